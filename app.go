@@ -3,106 +3,542 @@ package kratos
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/log/stdlog"
 	"github.com/go-kratos/kratos/v2/registry"
-	"golang.org/x/sync/errgroup"
+	"github.com/go-kratos/kratos/v2/transport"
 )
 
+// forceExitGrace is the grace period after the stop timeout before the forced exit.
+var forceExitGrace = 5 * time.Second
+
+// ErrStopTimeout is returned by Run once the servers are not stopped within the stop timeout.
+var ErrStopTimeout = errors.New("stop timeout")
+
 // App is an application components lifecycle manager
 type App struct {
-	opts   options
-	log    *log.Helper
+	opts options
+	log  *log.Helper
+	once sync.Once
+	// ctx is the root context of the hooks, the servers and the registrar, which is canceled
+	// first once the app stops.
+	ctx    context.Context
 	cancel func()
+	// stopping is closed once the app stops, which shuts down the servers.
+	stopping chan struct{}
+	health   *health.Registry
+
+	state int32
+
+	// reloadMu serializes the reloads and the shutdown of the servers.
+	reloadMu sync.Mutex
+
+	mu        sync.Mutex
+	sig       os.Signal
+	instance  *registry.ServiceInstance
+	endpoints []string
+	// the servers serving, whose contexts are srvCtx, and the errors of their starts, the
+	// first of which closes failed.
+	servers   []*server
+	srvCtx    context.Context
+	startErrs multiError
+	failed    chan struct{}
+	fail      sync.Once
+	// the background registration, which is stopped before the deregistration.
+	regCancel func()
+	regDone   chan struct{}
 }
 
 // New create an application lifecycle manager.
 func New(opts ...Option) *App {
 	options := options{
-		logger: stdlog.NewLogger(),
+		logger: log.GetLogger(),
 		ctx:    context.Background(),
-		sigs:   []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT},
+		sigs:   []os.Signal{syscall.SIGTERM, syscall.SIGINT},
+
+		registerPolicy:    RegisterPolicy{Deadline: 30 * time.Second, MaxBackoff: 5 * time.Second},
+		registerTimeout:   10 * time.Second,
+		deregisterTimeout: 3 * time.Second,
+		stopTimeout:       30 * time.Second,
+		exit:              os.Exit,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
-	return &App{
-		opts: options,
-		log:  log.NewHelper("app", options.logger),
+	if options.id == "" {
+		options.id = defaultID()
+	}
+	a := &App{
+		opts:     options,
+		log:      log.NewHelper("app", options.logger),
+		stopping: make(chan struct{}),
+		failed:   make(chan struct{}),
+		health:   health.NewRegistry(),
 	}
+	ctx, cancel := context.WithCancel(options.ctx)
+	a.ctx, a.cancel = NewContext(ctx, a), cancel
+	a.health.Register("servers", func(context.Context) error {
+		if state := a.State(); !state.Ready() {
+			return fmt.Errorf("app %s", strings.ToLower(state.String()))
+		}
+		return nil
+	})
+	return a
 }
 
-// Service returns registry service.
-func (a *App) Service() *registry.Service {
-	return &registry.Service{
+// ID returns the app id, the host name with a random suffix by default.
+func (a *App) ID() string { return a.opts.id }
+
+// Name returns the service name.
+func (a *App) Name() string { return a.opts.name }
+
+// Version returns the service version.
+func (a *App) Version() string { return a.opts.version }
+
+// Metadata returns the service metadata.
+func (a *App) Metadata() map[string]string { return a.opts.metadata }
+
+// Endpoint returns the endpoints of the instance, see Instance.
+func (a *App) Endpoint() []string { return a.Instance().Endpoints }
+
+// Health returns the readiness checks of the app, which include the check of the servers
+// started, named servers, and turn not ready once the app begins stopping.
+func (a *App) Health() *health.Registry {
+	return a.health
+}
+
+// State returns the current state of the app, the transitions are atomic.
+func (a *App) State() State {
+	return State(atomic.LoadInt32(&a.state))
+}
+
+// Endpoints returns the endpoints of the listening servers, nil before they are listening.
+func (a *App) Endpoints() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.endpoints...)
+}
+
+// Instance returns the registry service instance of the application, whose endpoints
+// are the endpoints of the listening servers if not specified, and whose zone is the
+// local zone of registry.LocalZone if not specified. The instance is built once the
+// servers are listening in Run, which is registered and deregistered.
+func (a *App) Instance() *registry.ServiceInstance {
+	a.mu.Lock()
+	ins := a.instance
+	a.mu.Unlock()
+	if ins != nil {
+		return ins
+	}
+	return a.buildInstance(a.opts.allServers())
+}
+
+// buildInstance builds the instance of the servers, the servers without the endpoints are skipped.
+func (a *App) buildInstance(servers []transport.Server) *registry.ServiceInstance {
+	metadata := a.opts.metadata
+	if zone := registry.LocalZone(); zone != "" && metadata[registry.ZoneKey] == "" {
+		metadata = make(map[string]string, len(a.opts.metadata)+1)
+		for k, v := range a.opts.metadata {
+			metadata[k] = v
+		}
+		metadata[registry.ZoneKey] = zone
+	}
+	endpoints := a.opts.endpoints
+	if len(endpoints) == 0 {
+		for _, srv := range servers {
+			if e, ok := srv.(transport.Endpointer); ok {
+				if endpoint, err := e.Endpoint(); err == nil {
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+		}
+	}
+	return &registry.ServiceInstance{
 		ID:        a.opts.id,
 		Name:      a.opts.name,
 		Version:   a.opts.version,
-		Metadata:  a.opts.metadata,
-		Endpoints: a.opts.endpoints,
+		Metadata:  metadata,
+		Endpoints: endpoints,
 	}
 }
 
-// Run executes all OnStart hooks registered with the application's Lifecycle.
+// Run starts the servers concurrently, and blocks until the app is stopped by Stop, the
+// first signal, see Signal, the done context or the first server failing, then it stops
+// all the servers within the stop timeout, and returns the errors of the servers and the
+// hooks if any.
+//
+// The BeforeStart hooks run before any server starts, and the AfterStart hooks once all
+// of them are listening, before the instance is registered. A failed start hook or server
+// aborts the launch, the servers already listening are stopped, and the AfterStop hooks
+// run, the error names the failed server and wraps its error, a panic of the server is
+// a failure as well, see CrashOnPanic. The BeforeStop hooks run
+// before the servers are stopped, and the AfterStop hooks once all of them are stopped,
+// the failed stop hooks never halt the shutdown. The contexts of the hooks and the
+// servers carry the app, see FromContext. The servers start in the order of the stages,
+// see Stage. Run is called at most once, see State.
 func (a *App) Run() error {
+	// the app stopped before Run stops the servers at once.
+	if !atomic.CompareAndSwapInt32(&a.state, int32(StateNew), int32(StateStarting)) && a.State() != StateStopping {
+		return errors.New("app already run")
+	}
+	defer atomic.StoreInt32(&a.state, int32(StateStopped))
+	ctx := a.ctx
+	defer a.cancel()
+	levels, err := a.opts.order()
+	if err != nil {
+		return err
+	}
+	if len(a.opts.sigs) > 0 {
+		defer a.trap()()
+	}
+	if err := runHooks(ctx, a.opts.beforeStart, true); err != nil {
+		return a.abort(err, nil)
+	}
+	// the servers keep serving until they are stopped, after the BeforeStop hooks.
+	srvCtx, srvCancel := context.WithCancel(valueContext{ctx})
+	defer srvCancel()
+	a.mu.Lock()
+	a.srvCtx = srvCtx
+	a.mu.Unlock()
+	go func() {
+		select {
+		case <-a.failed:
+			a.Stop()
+		case <-ctx.Done():
+			// the parent context is done.
+			a.Stop()
+		case <-a.stopping:
+		}
+	}()
+	// the stages start in order, the servers of a stage listen before serving, so that the
+	// instance is registered once all of them are listening.
 	var (
-		ctx context.Context
+		servers   []*server
+		endpoints []string
+		started   = make(map[*stage][]*server)
+		ready     = true
 	)
-	ctx, a.cancel = context.WithCancel(a.opts.ctx)
-	g, ctx := errgroup.WithContext(ctx)
-	for _, srv := range a.opts.servers {
-		srv := srv
-		g.Go(func() error {
-			<-ctx.Done() // wait for stop signal
-			stopCtx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			return srv.Stop(stopCtx)
-		})
-		g.Go(func() error {
-			startCtx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			return srv.Start(startCtx)
-		})
-	}
-	if a.opts.registry != nil {
-		g.Go(func() error {
-			time.Sleep(time.Second) // wait for server started
-			a.log.Infof("Registering %s service to the registry", a.opts.name)
-			return a.opts.registry.Register(a.Service())
-		})
-	}
-	c := make(chan os.Signal, 1)
+	for i, level := range levels {
+		if i > 0 && !a.await(levels[i-1], started) {
+			ready = false
+			break
+		}
+		var listening []*server
+		for _, st := range level {
+			srvs, eps, err := a.listen(st, i)
+			servers, endpoints = append(servers, srvs...), append(endpoints, eps...)
+			if err != nil {
+				return a.abort(err, servers)
+			}
+			started[st], listening = srvs, append(listening, srvs...)
+		}
+		a.mu.Lock()
+		a.servers = servers
+		a.mu.Unlock()
+		a.start(listening)
+	}
+	srvs := make([]transport.Server, 0, len(servers))
+	for _, s := range servers {
+		srvs = append(srvs, s.Server)
+	}
+	ins := a.buildInstance(srvs)
+	a.mu.Lock()
+	a.instance, a.endpoints = ins, endpoints
+	a.mu.Unlock()
+	var errs multiError
+	if !ready {
+		// stopped or failed while the stages started.
+	} else if err := runHooks(ctx, a.opts.afterStart, true); err != nil {
+		errs = append(errs, err)
+		// the instance is not registered, so it is not deregistered.
+		a.stop(false)
+	} else if a.opts.registrar != nil {
+		if a.opts.registerPolicy.Background {
+			regCtx, regCancel := context.WithCancel(ctx)
+			regDone := make(chan struct{})
+			a.mu.Lock()
+			a.regCancel, a.regDone = regCancel, regDone
+			a.mu.Unlock()
+			go func() {
+				defer close(regDone)
+				a.register(regCtx)
+			}()
+		} else {
+			regCtx, regCancel := context.WithTimeout(ctx, a.opts.registerPolicy.Deadline)
+			err := a.register(regCtx)
+			regCancel()
+			if err != nil {
+				errs = append(errs, err)
+				// the instance is not registered, so it is not deregistered.
+				a.stop(false)
+			}
+		}
+	}
+	atomic.CompareAndSwapInt32(&a.state, int32(StateStarting), int32(StateRunning))
+	<-a.stopping
+
+	a.log.Infof("Stopping the servers within %s", a.opts.stopTimeout)
+	var force *time.Timer
+	if a.opts.forceExit {
+		force = time.AfterFunc(a.opts.stopTimeout+forceExitGrace, a.forceExit)
+	}
+	stopCtx, stopCancel := context.WithTimeout(valueContext{ctx}, a.opts.stopTimeout)
+	defer stopCancel()
+	if err := runHooks(stopCtx, a.opts.beforeStop, false); err != nil {
+		errs = append(errs, err)
+	}
+	srvCancel()
+	// a reload in progress finishes before the shutdown.
+	a.reloadMu.Lock()
+	a.mu.Lock()
+	servers = a.servers
+	a.mu.Unlock()
+	stopErrs := a.stopServers(stopCtx, servers)
+	a.reloadMu.Unlock()
+	a.mu.Lock()
+	errs = append(append(a.startErrs, errs...), stopErrs...)
+	a.mu.Unlock()
+	if force != nil {
+		force.Reset(a.opts.stopTimeout + forceExitGrace)
+	}
+	afterCtx, afterCancel := context.WithTimeout(valueContext{ctx}, a.opts.stopTimeout)
+	defer afterCancel()
+	if err := runHooks(afterCtx, a.opts.afterStop, false); err != nil {
+		errs = append(errs, err)
+	}
+	if force != nil {
+		if errors.Is(errs, ErrStopTimeout) {
+			// the last resort once the caller hangs as well.
+			force.Reset(forceExitGrace)
+		} else {
+			force.Stop()
+		}
+	}
+	if err := errs.err(); err != nil {
+		if sig := a.Signal(); sig != nil {
+			return fmt.Errorf("stopped by signal %s: %w", sig, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// trap stops the app gracefully on the first signal, and exits immediately on the same
+// signal again, but SIGHUP reloads the servers if rebuilt, it returns the func which
+// stops trapping and waits for the goroutine.
+func (a *App) trap() func() {
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, a.opts.sigs...)
-	g.Go(func() error {
+	if a.opts.rebuild != nil {
+		signal.Notify(c, syscall.SIGHUP)
+	}
+	quit, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		var first os.Signal
 		for {
 			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-c:
-				a.Stop()
+			case <-quit:
+				return
+			case sig := <-c:
+				switch {
+				case sig == syscall.SIGHUP && a.opts.rebuild != nil:
+					a.log.Infof("Received signal %s, reloading the servers", sig)
+					go func() {
+						if err := a.Reload(valueContext{a.ctx}); err != nil {
+							a.log.Errorf("Failed to reload on signal %s: %v", sig, err)
+						}
+					}()
+				case first == nil:
+					first = sig
+					a.mu.Lock()
+					a.sig = sig
+					a.mu.Unlock()
+					a.log.Infof("Received signal %s, stopping gracefully", sig)
+					// the deregistration and the drain delay never block the next signal.
+					go a.Stop()
+				case sig == first:
+					a.log.Errorf("Received signal %s again, exiting immediately", sig)
+					a.opts.exit(1)
+					return
+				}
 			}
 		}
-	})
-	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		return err
+	}()
+	return func() {
+		signal.Stop(c)
+		close(quit)
+		<-done
 	}
-	return nil
 }
 
-// Stop gracefully stops the application.
+// Signal returns the signal which stopped the app, nil if none.
+func (a *App) Signal() os.Signal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sig
+}
+
+// runHooks runs the hooks in order, it returns the first error if failFast, otherwise
+// all the hooks run and their errors are aggregated.
+func runHooks(ctx context.Context, hooks []func(context.Context) error, failFast bool) error {
+	var errs multiError
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			if failFast {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs.err()
+}
+
+// Stop gracefully stops the application, it is safe to be called from any goroutine and
+// before Run. The context of the app is canceled first, so that the components started by
+// the hooks stop, then the instance is deregistered, the BeforeStop hooks run, and the
+// servers are stopped, even if the deregistration fails. The servers keep serving until
+// then, their contexts are canceled after the BeforeStop hooks.
 func (a *App) Stop() {
-	if a.opts.registry != nil {
-		a.log.Infof("Unregistering in the registry service: %s", a.opts.name)
-		if err := a.opts.registry.Deregister(a.Service()); err != nil {
+	a.stop(true)
+}
+
+// stop cancels the context of the app, and deregisters the instance if registered.
+func (a *App) stop(deregister bool) {
+	a.once.Do(func() {
+		atomic.StoreInt32(&a.state, int32(StateStopping))
+		a.health.Shutdown()
+		a.cancel()
+		a.mu.Lock()
+		regCancel, regDone := a.regCancel, a.regDone
+		a.mu.Unlock()
+		if regCancel != nil {
+			regCancel()
+			<-regDone
+		}
+		if deregister && a.opts.registrar != nil {
+			a.deregister()
+			if a.opts.drainDelay > 0 {
+				a.log.Infof("Draining for %s before stopping the servers", a.opts.drainDelay)
+				time.Sleep(a.opts.drainDelay)
+			}
+		}
+		close(a.stopping)
+	})
+}
+
+// abort stops the servers listening and runs the AfterStop hooks once the launch failed
+// before serving, and returns the errors.
+func (a *App) abort(err error, listening []*server) error {
+	a.stop(false)
+	errs := multiError{err}
+	ctx, cancel := context.WithTimeout(valueContext{a.ctx}, a.opts.stopTimeout)
+	defer cancel()
+	errs = append(errs, a.stopServers(ctx, listening)...)
+	if err := runHooks(ctx, a.opts.afterStop, false); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.err()
+}
+
+// forceExit exits the process once the shutdown hangs beyond the stop timeout and the grace.
+func (a *App) forceExit() {
+	a.log.Errorf("Shutdown not finished within the stop timeout of %s and the grace of %s, exiting", a.opts.stopTimeout, forceExitGrace)
+	a.opts.exit(1)
+}
+
+// register registers the instance, the failed attempts are retried with backoff until ctx is done.
+func (a *App) register(ctx context.Context) error {
+	mode := "strict"
+	if a.opts.registerPolicy.Background {
+		mode = "background"
+	}
+	a.log.Infof("Registering %s service to the registry in %s mode", a.opts.name, mode)
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, a.opts.registerTimeout)
+		err := a.opts.registrar.Register(attemptCtx, a.Instance())
+		cancel()
+		if err == nil {
+			a.log.Infof("Registered %s service to the registry after %d attempts", a.opts.name, attempt)
+			return nil
+		}
+		a.log.Warnf("Failed to register %s service to the registry at attempt %d: %v", a.opts.name, attempt, err)
+		select {
+		case <-ctx.Done():
+			a.log.Errorf("Gave up registering %s service to the registry after %d attempts: %v", a.opts.name, attempt, err)
+			return err
+		case <-time.After(a.backoff(attempt)):
+		}
+	}
+}
+
+// backoff returns the jittered exponential delay after the failed attempt.
+func (a *App) backoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << uint(attempt-1)
+	if max := a.opts.registerPolicy.MaxBackoff; d <= 0 || d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// deregister deregisters the instance, it returns once the deregister timeout is exceeded
+// even if the registrar ignores the context.
+func (a *App) deregister() {
+	a.log.Infof("Unregistering in the registry service: %s", a.opts.name)
+	ctx, cancel := context.WithTimeout(valueContext{a.ctx}, a.opts.deregisterTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.opts.registrar.Deregister(ctx, a.Instance())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
 			a.log.Errorf("Failed to deregister registry: %v", err)
 		}
+	case <-ctx.Done():
+		a.log.Errorf("Failed to deregister registry: %v", ctx.Err())
 	}
-	if a.cancel != nil {
-		a.cancel()
+}
+
+// multiError is the errors of the lifecycle, in the order they occurs.
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches the target.
+func (e multiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// err returns nil if there is no error, and the only error as is.
+func (e multiError) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
 	}
+	return e
 }
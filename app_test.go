@@ -0,0 +1,1051 @@
+package kratos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestAppRegistrar(t *testing.T) {
+	r := memory.New()
+	app := New(
+		ID("1"),
+		Name("helloworld"),
+		Version("v1.0.0"),
+		Endpoint("grpc://127.0.0.1:9000"),
+		Registrar(r),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	w, _ := r.Watch(context.Background(), "helloworld")
+	defer w.Stop()
+	for {
+		ins, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ins) == 1 {
+			if ins[0].ID != "1" || ins[0].Version != "v1.0.0" {
+				t.Fatalf("unexpected instance %+v", ins[0])
+			}
+			break
+		}
+	}
+	app.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the app stopped")
+	}
+	if ins, _ := r.GetService(context.Background(), "helloworld"); len(ins) != 0 {
+		t.Errorf("expected the instance deregistered, but got %v", ins)
+	}
+}
+
+// recorder records the lifecycle events of the fake server and registrar.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+	hang   bool
+	listen sync.Once
+	// delay delays the registrations.
+	delay time.Duration
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) Endpoint() (string, error) {
+	r.listen.Do(func() { r.record("listen") })
+	return "grpc://127.0.0.1:9000", nil
+}
+
+func (r *recorder) Start(ctx context.Context) error { return nil }
+
+func (r *recorder) Stop(ctx context.Context) error {
+	r.record("stop")
+	return nil
+}
+
+func (r *recorder) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	delay := r.delay
+	r.mu.Unlock()
+	time.Sleep(delay)
+	r.record("register " + strings.Join(service.Endpoints, ","))
+	return nil
+}
+
+func (r *recorder) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.record("deregister")
+	if r.hang {
+		select {}
+	}
+	return nil
+}
+
+func TestAppLifecycle(t *testing.T) {
+	for _, hang := range []bool{false, true} {
+		r := &recorder{hang: hang}
+		app := New(Name("helloworld"), Server(r), Registrar(r), DeregisterTimeout(50*time.Millisecond))
+		done := make(chan error, 1)
+		go func() {
+			done <- app.Run()
+		}()
+		time.Sleep(50 * time.Millisecond)
+		app.Stop()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the app stopped")
+		}
+		r.mu.Lock()
+		if got := strings.Join(r.events, ";"); !strings.HasPrefix(got, "listen;register grpc://127.0.0.1:9000;") ||
+			!strings.HasSuffix(got, "deregister;stop") {
+			t.Errorf("unexpected lifecycle %s", got)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// flakyRegistrar fails the registrations until the registry recovers.
+type flakyRegistrar struct {
+	mu         sync.Mutex
+	attempts   int
+	recoverAt  int
+	registered bool
+}
+
+func (r *flakyRegistrar) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.recoverAt == 0 || r.attempts < r.recoverAt {
+		return errors.New("registry unavailable")
+	}
+	r.registered = true
+	return nil
+}
+
+func (r *flakyRegistrar) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	return nil
+}
+
+func (r *flakyRegistrar) state() (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts, r.registered
+}
+
+func TestAppRegisterPolicy(t *testing.T) {
+	// the strict mode fails once the deadline is exceeded.
+	r := &flakyRegistrar{}
+	app := New(Name("helloworld"), Registrar(r), Registration(RegisterPolicy{Deadline: 100 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}))
+	if err := app.Run(); err == nil {
+		t.Fatal("expected the strict registration failed")
+	}
+	if attempts, _ := r.state(); attempts < 2 {
+		t.Errorf("expected the registration retried, but got %d attempts", attempts)
+	}
+
+	// the strict mode succeeds once the registry recovers within the deadline.
+	r = &flakyRegistrar{recoverAt: 3}
+	app = New(Name("helloworld"), Registrar(r), Registration(RegisterPolicy{MaxBackoff: 10 * time.Millisecond}))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if attempts, registered := r.state(); attempts != 3 || !registered {
+		t.Errorf("expected registered at the 3rd attempt, but got %d %v", attempts, registered)
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+
+	// the background mode serves before the registration.
+	r = &flakyRegistrar{}
+	app = New(Name("helloworld"), Registrar(r), Registration(RegisterPolicy{Background: true, MaxBackoff: 10 * time.Millisecond}))
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	app.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the app stopped")
+	}
+	attempts, _ := r.state()
+	time.Sleep(50 * time.Millisecond)
+	if a, _ := r.state(); attempts < 2 || a != attempts {
+		t.Errorf("expected the registration retried until stop, but got %d then %d attempts", attempts, a)
+	}
+}
+
+// testServer serves until stopped, unless it fails to start.
+type testServer struct {
+	name     string
+	rec      *recorder
+	startErr error
+	stopErr  error
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func newTestServer(name string, rec *recorder, startErr, stopErr error) *testServer {
+	return &testServer{name: name, rec: rec, startErr: startErr, stopErr: stopErr, stopped: make(chan struct{})}
+}
+
+func (s *testServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	if s.startErr != nil {
+		return s.startErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *testServer) Stop(ctx context.Context) error {
+	s.rec.record("stop " + s.name)
+	s.once.Do(func() { close(s.stopped) })
+	return s.stopErr
+}
+
+func (r *recorder) has(events ...string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, want := range events {
+		found := false
+		for _, e := range r.events {
+			found = found || e == want
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAppRun(t *testing.T) {
+	// the first failure stops all the servers, and the errors are aggregated.
+	r := &recorder{}
+	errStart, errStop := errors.New("address in use"), errors.New("stop failed")
+	app := New(Server(newTestServer("a", r, nil, errStop), newTestServer("b", r, errStart, nil)))
+	err := app.Run()
+	if err == nil || !strings.Contains(err.Error(), errStart.Error()) || !strings.Contains(err.Error(), errStop.Error()) {
+		t.Errorf("expected the start and the stop errors, but got %v", err)
+	}
+	if !r.has("start a", "start b", "stop a", "stop b") {
+		t.Errorf("expected all the servers started and stopped, but got %v", r.events)
+	}
+
+	// Stop from another goroutine stops the servers gracefully.
+	r = &recorder{}
+	app = New(Server(newTestServer("a", r, nil, nil), newTestServer("b", r, nil, nil)))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for !r.has("start a", "start b") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.has("stop a") || r.has("stop b") {
+		t.Errorf("expected the servers serving until stop, but got %v", r.events)
+	}
+	app.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the app stopped")
+	}
+	if !r.has("stop a", "stop b") {
+		t.Errorf("expected all the servers stopped, but got %v", r.events)
+	}
+
+	// a server ignoring the stop is bounded by the stop timeout.
+	r = &recorder{}
+	hang := newTestServer("hang", r, nil, nil)
+	hang.once.Do(func() {})
+	app = New(Server(hang), StopTimeout(50*time.Millisecond))
+	app.Stop()
+	if err := app.Run(); err == nil || !strings.Contains(err.Error(), "not stopped") {
+		t.Errorf("expected the stop timeout, but got %v", err)
+	}
+}
+
+func TestAppHooks(t *testing.T) {
+	r := &recorder{}
+	hook := func(event string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			r.record(event)
+			return err
+		}
+	}
+	errFlush := errors.New("flush failed")
+	app := New(
+		Server(newTestServer("a", r, nil, nil)),
+		BeforeStart(hook("before start 1", nil)),
+		BeforeStart(hook("before start 2", nil)),
+		AfterStart(hook("after start", nil)),
+		BeforeStop(hook("before stop 1", errFlush)),
+		BeforeStop(hook("before stop 2", nil)),
+		AfterStop(hook("after stop", nil)),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for !r.has("after start") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	app.Stop()
+	if err := <-done; !errors.Is(err, errFlush) {
+		t.Errorf("expected the stop hook error, but got %v", err)
+	}
+	r.mu.Lock()
+	got := strings.Join(r.events, ";")
+	r.mu.Unlock()
+	want := "before start 1;before start 2;start a;after start;before stop 1;before stop 2;stop a;after stop"
+	if !strings.HasPrefix(got, "before start 1;before start 2;") || !strings.HasSuffix(got, "before stop 1;before stop 2;stop a;after stop") ||
+		!r.has("start a", "after start") || len(got) != len(want) {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+
+	// a failed start hook aborts the launch, and the started servers are stopped.
+	r = &recorder{}
+	errWarm := errors.New("warm failed")
+	app = New(
+		Server(newTestServer("a", r, nil, nil)),
+		AfterStart(hook("after start", errWarm)),
+		AfterStart(hook("never", nil)),
+		AfterStop(hook("after stop", nil)),
+	)
+	if err := app.Run(); !errors.Is(err, errWarm) {
+		t.Errorf("expected the start hook error, but got %v", err)
+	}
+	if r.has("never") || !r.has("stop a", "after stop") {
+		t.Errorf("expected the launch aborted, but got %v", r.events)
+	}
+	r = &recorder{}
+	app = New(Server(newTestServer("a", r, nil, nil)), BeforeStart(hook("before start", errWarm)))
+	if err := app.Run(); !errors.Is(err, errWarm) || r.has("start a") {
+		t.Errorf("expected no server started, but got %v %v", err, r.events)
+	}
+}
+
+func TestAppSignal(t *testing.T) {
+	exited := make(chan int, 1)
+	raise := func() {
+		p, _ := os.FindProcess(os.Getpid())
+		if err := p.Signal(syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &recorder{}
+	stopping, release := make(chan struct{}), make(chan struct{})
+	app := New(Server(newTestServer("a", r, nil, nil)), Signal(syscall.SIGHUP), Exit(func(code int) { exited <- code }), BeforeStop(func(context.Context) error {
+		close(stopping)
+		<-release
+		return errors.New("flush failed")
+	}))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for !r.has("start a") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	raise()
+	<-stopping
+	// the same signal during the graceful shutdown exits immediately.
+	raise()
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("expected exit code 1, but got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the hard exit")
+	}
+	close(release)
+	err := <-done
+	if err == nil || !strings.Contains(err.Error(), "hangup") || app.Signal() != syscall.SIGHUP {
+		t.Errorf("expected the signal in the error, but got %v", err)
+	}
+}
+
+// infoServer records the app info of the start context.
+type infoServer struct {
+	info    chan AppInfo
+	stopped chan struct{}
+}
+
+func (s *infoServer) Start(ctx context.Context) error {
+	info, _ := FromContext(ctx)
+	s.info <- info
+	<-s.stopped
+	return nil
+}
+
+func (s *infoServer) Stop(ctx context.Context) error {
+	close(s.stopped)
+	return nil
+}
+
+func TestAppInfo(t *testing.T) {
+	host, _ := os.Hostname()
+	if id := New().ID(); !strings.HasPrefix(id, host+"-") || id == New().ID() {
+		t.Errorf("expected the host name with a random suffix, but got %s", id)
+	}
+	srv := &infoServer{info: make(chan AppInfo, 1), stopped: make(chan struct{})}
+	hooked := make(chan AppInfo, 1)
+	app := New(ID("1"), Name("helloworld"), Version("v1.0.0"), Metadata(map[string]string{"env": "test"}), Server(srv),
+		BeforeStart(func(ctx context.Context) error {
+			info, _ := FromContext(ctx)
+			hooked <- info
+			return nil
+		}))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for _, info := range []AppInfo{<-hooked, <-srv.info} {
+		if info == nil || info.ID() != "1" || info.Name() != "helloworld" || info.Version() != "v1.0.0" || info.Metadata()["env"] != "test" {
+			t.Errorf("expected the app info in the context, but got %v", info)
+		}
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// slowRegistrar blocks the registrations until the attempt times out.
+type slowRegistrar struct {
+	mu       sync.Mutex
+	attempts int
+	ins      *registry.ServiceInstance
+}
+
+func (r *slowRegistrar) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	r.attempts++
+	r.ins = service
+	r.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *slowRegistrar) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	return nil
+}
+
+func TestAppInstance(t *testing.T) {
+	r := &slowRegistrar{}
+	rec := &recorder{}
+	app := New(
+		Name("helloworld"),
+		Server(rec, newTestServer("plain", rec, nil, nil)),
+		Registrar(r),
+		RegisterTimeout(20*time.Millisecond),
+		Registration(RegisterPolicy{Deadline: 200 * time.Millisecond, MaxBackoff: time.Millisecond}),
+	)
+	if err := app.Run(); err == nil {
+		t.Fatal("expected the strict registration failed")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attempts < 2 {
+		t.Errorf("expected the attempts timed out and retried, but got %d attempts", r.attempts)
+	}
+	if ins := app.Instance(); ins != r.ins || !reflect.DeepEqual(ins.Endpoints, []string{"grpc://127.0.0.1:9000"}) {
+		t.Errorf("expected the registered instance of the endpoints, but got %+v", ins)
+	}
+}
+
+// delayedServer fails to start after the delay.
+type delayedServer struct {
+	*testServer
+	delay time.Duration
+}
+
+func (s *delayedServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	time.Sleep(s.delay)
+	return s.startErr
+}
+
+// busyServer fails to listen.
+type busyServer struct{ *testServer }
+
+func (s *busyServer) Endpoint() (string, error) { return "", s.startErr }
+
+func TestAppStartFailure(t *testing.T) {
+	errBusy := errors.New("address already in use")
+	for _, tc := range []struct {
+		name   string
+		failed func(r *recorder) transport.Server
+		err    string
+	}{
+		{"immediately", func(r *recorder) transport.Server {
+			return newTestServer("failed", r, errBusy, nil)
+		}, "failed to start server #1 *kratos.testServer"},
+		{"after a delay", func(r *recorder) transport.Server {
+			return &delayedServer{newTestServer("failed", r, errBusy, nil), 50 * time.Millisecond}
+		}, "failed to start server #1 *kratos.delayedServer"},
+		{"listening", func(r *recorder) transport.Server {
+			return &busyServer{newTestServer("failed", r, errBusy, nil)}
+		}, "failed to start server #1 *kratos.busyServer"},
+	} {
+		r := &recorder{}
+		app := New(Server(r, tc.failed(r)), AfterStop(func(context.Context) error {
+			r.record("after stop")
+			return nil
+		}))
+		err := app.Run()
+		if !errors.Is(err, errBusy) || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected the failed server named, but got %v", tc.name, err)
+		}
+		if !r.has("listen", "stop", "after stop") {
+			t.Errorf("%s: expected the started server stopped, but got %v", tc.name, r.events)
+		}
+	}
+}
+
+// ctxServer records whether the start context is done once stopped.
+type ctxServer struct {
+	rec *recorder
+	ctx chan context.Context
+}
+
+func (s *ctxServer) Start(ctx context.Context) error {
+	s.ctx <- ctx
+	<-ctx.Done()
+	return nil
+}
+
+func (s *ctxServer) Stop(ctx context.Context) error {
+	s.rec.record("stop")
+	return nil
+}
+
+func TestAppContext(t *testing.T) {
+	r := &recorder{}
+	poller := make(chan context.Context, 1)
+	srv := &ctxServer{rec: r, ctx: make(chan context.Context, 1)}
+	parent := context.WithValue(context.Background(), testKey{}, "value")
+	app := New(Context(parent), Server(srv),
+		BeforeStart(func(ctx context.Context) error {
+			poller <- ctx
+			return nil
+		}),
+		BeforeStop(func(ctx context.Context) error {
+			ctx = <-poller
+			if ctx.Err() == nil {
+				t.Error("expected the app context canceled before the BeforeStop hooks")
+			}
+			select {
+			case ctx := <-srv.ctx:
+				if ctx.Err() != nil || ctx.Value(testKey{}) != "value" {
+					t.Error("expected the servers serving during the BeforeStop hooks")
+				}
+				srv.ctx <- ctx
+			default:
+			}
+			return nil
+		}),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for len(srv.ctx) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ctx := <-srv.ctx; ctx.Err() == nil {
+		t.Error("expected the server context canceled once stopped")
+	}
+}
+
+type testKey struct{}
+
+func TestAppForceExit(t *testing.T) {
+	defer func(grace time.Duration) { forceExitGrace = grace }(forceExitGrace)
+	forceExitGrace = 50 * time.Millisecond
+	exited := make(chan int, 1)
+	r := &recorder{}
+	hang := newTestServer("hang", r, nil, nil)
+	hang.once.Do(func() {})
+	app := New(Server(newTestServer("ok", r, nil, nil), hang), StopTimeout(50*time.Millisecond),
+		ForceExit(true), Exit(func(code int) { exited <- code }))
+	app.Stop()
+	err := app.Run()
+	if !errors.Is(err, ErrStopTimeout) || !strings.Contains(err.Error(), "#1 *kratos.testServer") || strings.Contains(err.Error(), "#0") {
+		t.Errorf("expected the hanging server listed, but got %v", err)
+	}
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("expected exit code 1, but got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the forced exit")
+	}
+
+	// the forced exit is disarmed once the app stops in time.
+	app = New(Server(newTestServer("ok", r, nil, nil)), StopTimeout(50*time.Millisecond), ForceExit(true), Exit(func(code int) { exited <- code }))
+	app.Stop()
+	if err := app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-exited:
+		t.Error("expected no forced exit")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAppState(t *testing.T) {
+	var (
+		r      = &recorder{}
+		mu     sync.Mutex
+		states []State
+		app    *App
+	)
+	observe := func(context.Context) error {
+		mu.Lock()
+		states = append(states, app.State())
+		mu.Unlock()
+		return nil
+	}
+	app = New(Server(r), BeforeStart(observe), AfterStart(observe), BeforeStop(observe), AfterStop(observe),
+		BeforeStop(func(ctx context.Context) error {
+			if app.Health().Check(ctx).Ready {
+				t.Error("expected not ready once stopping")
+			}
+			return nil
+		}))
+	if app.State() != StateNew || app.Endpoints() != nil {
+		t.Errorf("unexpected state before run %s %v", app.State(), app.Endpoints())
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for !app.State().Ready() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res := app.Health().Check(context.Background()); !res.Ready {
+		t.Errorf("expected ready once running, but got %v", res.Errors)
+	}
+	if !reflect.DeepEqual(app.Endpoints(), []string{"grpc://127.0.0.1:9000"}) || app.Instance().Endpoints[0] != "grpc://127.0.0.1:9000" {
+		t.Errorf("unexpected endpoints %v", app.Endpoints())
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []State{StateStarting, StateStarting, StateStopping, StateStopping}
+	if !reflect.DeepEqual(states, want) || app.State() != StateStopped {
+		t.Errorf("expected %v then STOPPED, but got %v then %s", want, states, app.State())
+	}
+	if err := app.Run(); err == nil {
+		t.Error("expected the app run once")
+	}
+}
+
+// endpointServer listens on the endpoint.
+type endpointServer struct {
+	*testServer
+	endpoint string
+}
+
+func (s *endpointServer) Endpoint() (string, error) { return s.endpoint, nil }
+
+func TestAppReload(t *testing.T) {
+	r := &recorder{}
+	var next []transport.Server
+	app := New(
+		Server(&endpointServer{newTestServer("old", r, nil, nil), "grpc://127.0.0.1:9000"}),
+		Registrar(r),
+		Rebuild(func(context.Context) ([]transport.Server, error) { return next, nil }),
+	)
+	if err := app.Reload(context.Background()); err == nil {
+		t.Error("expected the reload failed before run")
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the new servers failing to listen are stopped, and the old ones keep serving.
+	errBusy := errors.New("address already in use")
+	next = []transport.Server{&busyServer{newTestServer("busy", r, errBusy, nil)}}
+	if err := app.Reload(context.Background()); !errors.Is(err, errBusy) {
+		t.Errorf("expected %v, but got %v", errBusy, err)
+	}
+	if r.has("stop old") || app.State() != StateRunning {
+		t.Errorf("expected the old server kept, but got %v", r.events)
+	}
+
+	// the new servers failing to start are stopped, and the old ones keep serving.
+	errStart := errors.New("bad certificate")
+	next = []transport.Server{&endpointServer{newTestServer("bad", r, errStart, nil), "grpc://127.0.0.1:9001"}}
+	if err := app.Reload(context.Background()); !errors.Is(err, errStart) {
+		t.Errorf("expected %v, but got %v", errStart, err)
+	}
+	if r.has("stop old") || r.has("register grpc://127.0.0.1:9001") || app.State() != StateRunning {
+		t.Errorf("expected the old server kept, but got %v", r.events)
+	}
+
+	next = []transport.Server{&endpointServer{newTestServer("new", r, nil, nil), "grpc://127.0.0.1:9001"}}
+	if err := app.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !r.has("start new", "register grpc://127.0.0.1:9001", "stop old") {
+		t.Errorf("expected the servers swapped, but got %v", r.events)
+	}
+	if e := app.Endpoints(); len(e) != 1 || e[0] != "grpc://127.0.0.1:9001" {
+		t.Errorf("expected the new endpoint, but got %v", e)
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !r.has("stop new") {
+		t.Errorf("expected the new server stopped, but got %v", r.events)
+	}
+}
+
+// lateServer fails to start after the delay.
+type lateServer struct {
+	*testServer
+	delay time.Duration
+}
+
+func (s *lateServer) Endpoint() (string, error) { return "grpc://127.0.0.1:9002", nil }
+
+func (s *lateServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	time.Sleep(s.delay)
+	return s.startErr
+}
+
+func TestAppReloadLateFailure(t *testing.T) {
+	defer func(d time.Duration) { reloadSettle = d }(reloadSettle)
+	reloadSettle = 50 * time.Millisecond
+	r := &recorder{}
+	var next []transport.Server
+	app := New(
+		Server(&endpointServer{newTestServer("old", r, nil, nil), "grpc://127.0.0.1:9000"}),
+		Registrar(r),
+		Rebuild(func(context.Context) ([]transport.Server, error) { return next, nil }),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the settle waits on all the new servers, not only the first one exiting at once.
+	errStart := errors.New("bad certificate")
+	next = []transport.Server{&recorder{}, &lateServer{newTestServer("late", r, errStart, nil), 10 * time.Millisecond}}
+	if err := app.Reload(context.Background()); !errors.Is(err, errStart) {
+		t.Errorf("expected %v, but got %v", errStart, err)
+	}
+	if r.has("register grpc://127.0.0.1:9000,grpc://127.0.0.1:9002") || r.has("stop old") {
+		t.Errorf("expected the reload aborted before the registration, but got %v", r.events)
+	}
+
+	// the old instance is registered back once a new server fails after the registration.
+	r.mu.Lock()
+	r.delay = 100 * time.Millisecond
+	r.mu.Unlock()
+	next = []transport.Server{&lateServer{newTestServer("later", r, errStart, nil), 80 * time.Millisecond}}
+	if err := app.Reload(context.Background()); !errors.Is(err, errStart) {
+		t.Errorf("expected %v, but got %v", errStart, err)
+	}
+	var registers []string
+	r.mu.Lock()
+	for _, e := range r.events {
+		if strings.HasPrefix(e, "register ") {
+			registers = append(registers, e)
+		}
+	}
+	r.mu.Unlock()
+	if n := len(registers); n < 2 || registers[n-2] != "register grpc://127.0.0.1:9002" || registers[n-1] != "register grpc://127.0.0.1:9000" {
+		t.Errorf("expected the old instance registered back, but got %v", registers)
+	}
+	if r.has("stop old") || app.State() != StateRunning {
+		t.Errorf("expected the old server kept, but got %v", r.events)
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listenerServer accepts the connections on the address, it inherits the listener of the
+// server it replaces.
+type listenerServer struct {
+	*testServer
+	address   string
+	lis       net.Listener
+	inherited []net.Listener
+}
+
+func (s *listenerServer) Endpoint() (string, error) {
+	if s.lis == nil {
+		lis, ok, err := transport.InheritListener("tcp", s.address, s.inherited)
+		if !ok && err == nil {
+			lis, err = net.Listen("tcp", s.address)
+		}
+		if err != nil {
+			return "", err
+		}
+		s.lis = lis
+	}
+	return "tcp://" + s.lis.Addr().String(), nil
+}
+
+func (s *listenerServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	for {
+		conn, err := s.lis.Accept()
+		if err != nil {
+			return nil
+		}
+		conn.Write([]byte(s.name))
+		conn.Close()
+	}
+}
+
+func (s *listenerServer) Stop(ctx context.Context) error {
+	s.rec.record("stop " + s.name)
+	return s.lis.Close()
+}
+
+func (s *listenerServer) Listener() net.Listener           { return s.lis }
+func (s *listenerServer) Inherit(listeners []net.Listener) { s.inherited = listeners }
+
+func TestAppReloadSameAddress(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := lis.Addr().String()
+	lis.Close()
+	r := &recorder{}
+	newServer := func(name string) *listenerServer {
+		return &listenerServer{testServer: newTestServer(name, r, nil, nil), address: address}
+	}
+	var next []transport.Server
+	app := New(Server(newServer("old")), Rebuild(func(context.Context) ([]transport.Server, error) { return next, nil }))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	dial := func() string {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		return string(b)
+	}
+	if got := dial(); got != "old" {
+		t.Fatalf("expected the old server, but got %q", got)
+	}
+	// the certificates are rotated on the same address.
+	next = []transport.Server{newServer("new")}
+	if err := app.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if got := dial(); got != "new" {
+			t.Fatalf("expected the new server on the inherited listener, but got %q", got)
+		}
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loaderServer warms up, and returns from Start.
+type loaderServer struct{ *testServer }
+
+func (s *loaderServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	time.Sleep(20 * time.Millisecond)
+	s.rec.record("warm " + s.name)
+	return nil
+}
+
+func (r *recorder) before(first, second string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, j := -1, -1
+	for k, e := range r.events {
+		if e == first && i < 0 {
+			i = k
+		}
+		if e == second && j < 0 {
+			j = k
+		}
+	}
+	return i >= 0 && j >= 0 && i < j
+}
+
+func TestAppStage(t *testing.T) {
+	r := &recorder{}
+	var warm int32
+	app := New(
+		Server(&loaderServer{newTestServer("cache", r, nil, nil)}),
+		Stage("api", []transport.Server{newTestServer("api", r, nil, nil)}, After(DefaultStage), ReadyCheck(func(context.Context) error {
+			if atomic.AddInt32(&warm, 1) < 3 {
+				return errors.New("warming up")
+			}
+			return nil
+		})),
+		Stage("gateway", []transport.Server{newTestServer("gateway", r, nil, nil)}, After("api")),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if r.has("start gateway") {
+		t.Errorf("expected the gateway waiting for the api, but got %v", r.events)
+	}
+	time.Sleep(300 * time.Millisecond)
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !r.before("warm cache", "start api") || !r.has("start gateway") {
+		t.Errorf("expected the stages started in order, but got %v", r.events)
+	}
+	if !r.before("stop gateway", "stop api") || !r.before("stop api", "stop cache") {
+		t.Errorf("expected the stages stopped in the reverse order, but got %v", r.events)
+	}
+
+	for _, tc := range []struct {
+		opts []Option
+		err  string
+	}{
+		{[]Option{Stage("api", nil, After("cache"))}, `stage "api" starts after the unknown stage "cache"`},
+		{[]Option{Stage("a", nil, After("b")), Stage("b", nil, After("a"))}, "stages in a cycle: a -> b -> a"},
+		{[]Option{Stage(DefaultStage, nil)}, `duplicate stage "default"`},
+	} {
+		if err := New(tc.opts...).Run(); err == nil || err.Error() != tc.err {
+			t.Errorf("expected %s, but got %v", tc.err, err)
+		}
+	}
+}
+
+// panicServer panics in Start.
+type panicServer struct{ *testServer }
+
+func (s *panicServer) Start(ctx context.Context) error {
+	s.rec.record("start " + s.name)
+	panic("boom")
+}
+
+func TestAppPanic(t *testing.T) {
+	r := &recorder{}
+	app := New(
+		Server(newTestServer("plain", r, nil, nil), &panicServer{newTestServer("panic", r, nil, nil)}),
+		CrashOnPanic(os.Getenv("KRATOS_TEST_CRASH") == "1"),
+		AfterStop(func(context.Context) error {
+			r.record("after stop")
+			return nil
+		}),
+	)
+	if os.Getenv("KRATOS_TEST_CRASH") == "1" {
+		app.Run()
+		return
+	}
+	err := app.Run()
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" || !strings.Contains(string(pe.Stack), "panicServer") {
+		t.Fatalf("expected the panic recovered with the stack, but got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to start server #1 *kratos.panicServer: panic: boom") {
+		t.Errorf("expected the panicking server named, but got %v", err)
+	}
+	if !r.has("stop plain", "after stop") {
+		t.Errorf("expected the app stopped gracefully, but got %v", r.events)
+	}
+
+	// the panic crashes the process without the graceful shutdown.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAppPanic$")
+	cmd.Env = append(os.Environ(), "KRATOS_TEST_CRASH=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "panic: boom") {
+		t.Errorf("expected the process crashed, but got %v: %s", err, out)
+	}
+}
+
+func TestAppGlobalLogger(t *testing.T) {
+	defer log.SetLogger(log.GetLogger())
+	r := log.NewRecorder()
+	log.SetLogger(r)
+	app := New(Name("helloworld"))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	for !app.State().Ready() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	app.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Entries()) == 0 {
+		t.Error("expected the app logged to the global logger")
+	}
+}
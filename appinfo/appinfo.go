@@ -0,0 +1,29 @@
+// Package appinfo carries the identity of the application in the contexts, it imports
+// nothing of kratos so that the transports and the middleware can read the identity
+// without depending on the kratos package, which is re-exported by kratos.AppInfo.
+package appinfo
+
+import "context"
+
+// Info is the identity of the application, which is injected into the contexts of the
+// hooks, the servers and the requests.
+type Info interface {
+	ID() string
+	Name() string
+	Version() string
+	Metadata() map[string]string
+	Endpoint() []string
+}
+
+type infoKey struct{}
+
+// NewContext returns a new Context that carries value.
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the Info value stored in ctx, if any.
+func FromContext(ctx context.Context) (info Info, ok bool) {
+	info, ok = ctx.Value(infoKey{}).(Info)
+	return
+}
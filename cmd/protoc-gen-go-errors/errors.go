@@ -1,6 +1,8 @@
 package main
 
 import (
+	"strings"
+
 	pb "github.com/go-kratos/kratos/cmd/protoc-gen-go-errors/proto"
 
 	"google.golang.org/protobuf/compiler/protogen"
@@ -42,17 +44,41 @@ func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.
 }
 
 func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, enum *protogen.Enum) {
-	err := proto.GetExtension(enum.Desc.Options(), pb.E_Errors)
-	if ok := err.(bool); !ok {
+	if ok := proto.GetExtension(enum.Desc.Options(), pb.E_Errors).(bool); !ok {
 		return
 	}
+	defaultCode := proto.GetExtension(enum.Desc.Options(), pb.E_DefaultCode).(int32)
+	if defaultCode == 0 {
+		defaultCode = 500
+	}
 	var ew errorWrapper
 	for _, v := range enum.Values {
+		code := proto.GetExtension(v.Desc.Options(), pb.E_Code).(int32)
+		if code == 0 {
+			code = defaultCode
+		}
 		err := &errorInfo{
-			Name:  string(enum.Desc.Name()),
-			Value: string(v.Desc.Name()),
+			Name:      string(enum.Desc.Name()),
+			Value:     string(v.Desc.Name()),
+			CamelName: camelCase(string(v.Desc.Name())),
+			Code:      int(code),
+			Errors:    g.QualifiedGoIdent(errorsPackage.Ident("Error")),
+			Newf:      g.QualifiedGoIdent(errorsPackage.Ident("Newf")),
+			FromError: g.QualifiedGoIdent(errorsPackage.Ident("FromError")),
 		}
 		ew.Errors = append(ew.Errors, err)
 	}
 	g.P(ew.execute())
 }
+
+// camelCase returns the CamelCase name of the enum value, i.e., UserNotFound for USER_NOT_FOUND.
+func camelCase(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(strings.ToLower(s), "_") {
+		if word == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	pb "github.com/go-kratos/kratos/cmd/protoc-gen-go-errors/proto"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func enumValue(name string, number, code int32) *descriptorpb.EnumValueDescriptorProto {
+	v := &descriptorpb.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)}
+	if code != 0 {
+		v.Options = &descriptorpb.EnumValueOptions{}
+		proto.SetExtension(v.Options, pb.E_Code, code)
+	}
+	return v
+}
+
+func TestGenerateFile(t *testing.T) {
+	opts := &descriptorpb.EnumOptions{}
+	proto.SetExtension(opts, pb.E_Errors, true)
+	proto.SetExtension(opts, pb.E_DefaultCode, int32(500))
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("user/v1/errors.proto"),
+		Package: proto.String("user.v1"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("github.com/go-kratos/kratos/examples/user/v1;v1")},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name:    proto.String("ErrorReason"),
+			Options: opts,
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				enumValue("UNKNOWN_ERROR", 0, 0),
+				enumValue("USER_NOT_FOUND", 1, 404),
+				enumValue("CONTENT_MISSING", 2, 400),
+			},
+		}},
+	}
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{fd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generateFile(gen, gen.Files[0])
+	res := gen.Response()
+	if res.Error != nil || len(res.File) != 1 {
+		t.Fatalf("unexpected response %v", res)
+	}
+	got := []byte(res.File[0].GetContent())
+	golden := "testdata/errors_errors.pb.go.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s, run go test -update:\n%s", golden, got)
+	}
+}
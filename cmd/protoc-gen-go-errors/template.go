@@ -5,24 +5,35 @@ import (
 	"text/template"
 )
 
-var errorsTemplate = `const (
-{{ range .Errors }}
-	Errors_{{.Value}} = "{{.Name}}_{{.Value}}"
-{{- end }}
-)
+var errorsTemplate = `
+{{- range .Errors }}
 
-{{ range .Errors }}
+// Is{{.CamelName}} reports whether err is the {{.Value}} error of {{.Name}}, by the reason and the code.
+func Is{{.CamelName}}(err error) bool {
+	if err == nil {
+		return false
+	}
+	e := {{.FromError}}(err)
+	return e.Reason == "{{.Value}}" && e.Code == {{.Code}}
+}
 
-func Is{{.Value}}(err error) bool {
-	return errors.Reason(err) == Errors_{{.Value}}
+// Error{{.CamelName}} returns the {{.Value}} error of {{.Name}} with the code {{.Code}}.
+func Error{{.CamelName}}(format string, args ...interface{}) *{{.Errors}} {
+	return {{.Newf}}({{.Code}}, "{{.Value}}", format, args...)
 }
 {{- end }}
 `
 
 type errorInfo struct {
-	Name  string
-	Value string
+	Name      string
+	Value     string
+	CamelName string
+	Code      int
+	Errors    string
+	Newf      string
+	FromError string
 }
+
 type errorWrapper struct {
 	Errors []*errorInfo
 }
@@ -0,0 +1,77 @@
+// Package binding applies the config values to the running components, at startup and
+// on every change of the values, i.e., the level of a log.Filter:
+//
+//	if err := binding.LogLevel(c, "log.level", filter); err != nil {
+//		panic(err)
+//	}
+package binding
+
+import (
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+)
+
+// Option is binding option.
+type Option func(*options)
+
+type options struct {
+	logger   log.Logger
+	failures metrics.Counter
+}
+
+// WithLogger with the logger of the failed applications, the global logger is used by default.
+func WithLogger(l log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithFailures with the counter of the failed applications of the changes, labeled by the key.
+func WithFailures(c metrics.Counter) Option {
+	return func(o *options) {
+		o.failures = c
+	}
+}
+
+// Bind applies the current value of the key, whose failure is returned, and re-applies
+// the value on every change, whose failures are logged and counted, so that the component
+// keeps the last value applied. The key is watched by the binding, which replaces the
+// observer of the key, since each key has at most one observer.
+func Bind(c config.Config, key string, apply func(config.Value) error, opts ...Option) error {
+	o := options{logger: log.GetLogger()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := apply(c.Value(key)); err != nil {
+		return fmt.Errorf("binding: failed to apply %s: %w", key, err)
+	}
+	h := log.NewHelper("config/binding", o.logger)
+	return c.Watch(key, func(key string, v config.Value) {
+		if err := apply(v); err != nil {
+			h.Errorf("Failed to apply the change of %s: %v", key, err)
+			if o.failures != nil {
+				o.failures.With(key).Inc()
+			}
+		}
+	})
+}
+
+// LogLevel binds the level of the filter to the key, i.e., log.level, whose value is a
+// level name in any case, i.e., debug, the unknown levels are rejected.
+func LogLevel(c config.Config, key string, f *log.Filter, opts ...Option) error {
+	return Bind(c, key, func(v config.Value) error {
+		s, err := v.String()
+		if err != nil {
+			return err
+		}
+		level, ok := log.LookupLevel(s)
+		if !ok {
+			return fmt.Errorf("unknown log level %q", s)
+		}
+		f.SetLevel(level)
+		return nil
+	}, opts...)
+}
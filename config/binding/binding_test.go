@@ -0,0 +1,99 @@
+package binding
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+)
+
+type testSource struct {
+	data    string
+	changes chan string
+	done    chan struct{}
+}
+
+func (s *testSource) Load() ([]*config.KeyValue, error) { return s.kvs(s.data), nil }
+func (s *testSource) Watch() (config.Watcher, error)    { return s, nil }
+func (s *testSource) Close() error                      { close(s.done); return nil }
+
+func (s *testSource) kvs(data string) []*config.KeyValue {
+	return []*config.KeyValue{{Key: "test", Value: []byte(data), Format: "json"}}
+}
+
+func (s *testSource) Next() ([]*config.KeyValue, error) {
+	select {
+	case data := <-s.changes:
+		return s.kvs(data), nil
+	case <-s.done:
+		return nil, errors.New("closed")
+	}
+}
+
+type testCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *testCounter) With(lvs ...string) metrics.Counter { return &labeled{c, lvs[0]} }
+func (c *testCounter) Inc()                               {}
+func (c *testCounter) Add(float64)                        {}
+
+type labeled struct {
+	*testCounter
+	name string
+}
+
+func (k *labeled) Inc() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.counts[k.name]++
+}
+
+func (c *testCounter) count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+func TestLogLevel(t *testing.T) {
+	s := &testSource{data: `{"log": {"level": "warn"}}`, changes: make(chan string), done: make(chan struct{})}
+	c := config.New(config.WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	f := log.NewFilter(log.NewRecorder())
+	failures := &testCounter{counts: make(map[string]int)}
+	if err := LogLevel(c, "log.level", f, WithFailures(failures), WithLogger(log.NewRecorder())); err != nil {
+		t.Fatal(err)
+	}
+	if f.Level() != log.LevelWarn {
+		t.Errorf("expected the level applied at startup, but got %s", f.Level())
+	}
+
+	s.changes <- `{"log": {"level": "DEBUG"}}`
+	waitFor(t, func() bool { return f.Level() == log.LevelDebug })
+	s.changes <- `{"log": {"level": "verbose"}}`
+	waitFor(t, func() bool { return failures.count("log.level") == 1 })
+	if f.Level() != log.LevelDebug {
+		t.Errorf("expected the last level kept, but got %s", f.Level())
+	}
+
+	if err := LogLevel(c, "log.format", f); err == nil {
+		t.Error("expected the missing key rejected at startup")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
@@ -3,12 +3,16 @@ package config
 import (
 	"encoding/json"
 	"errors"
-	"reflect"
+	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/encoding"
+	// the json codec of the env and the flag sources.
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/log/stdlog"
 )
 
 var (
@@ -16,127 +20,297 @@ var (
 	ErrNotFound = errors.New("key not found")
 	// ErrTypeAssert is type assert error.
 	ErrTypeAssert = errors.New("type assert error")
+	// ErrUnknownFormat is returned for the key-values of the formats without the codecs.
+	ErrUnknownFormat = errors.New("unknown config format")
+	// ErrInvalid is returned for the snapshots failing the validation.
+	ErrInvalid = errors.New("invalid config")
 
 	_ Config = (*config)(nil)
 )
 
-// Observer is config observer.
+// Observer is config observer, which is called with the key and the merged value of the key,
+// whose getters fail with ErrNotFound once the key is removed.
 type Observer func(string, Value)
 
-// Config is a config interface.
+// debounce is the window in which the changes of the sources are coalesced into a reload.
+const debounce = 100 * time.Millisecond
+
+// Config is a config interface, the key-values of the sources are merged into a tree in
+// order, the later sources win key by key, unless the priority is reversed by
+// WithReversePriority. The maps are merged deeply, while the scalars and the arrays are
+// replaced wholesale, and a map conflicts with a scalar or an array, which fails the load.
+// The values are read by the dot paths into the tree, i.e., server.http.addr. The references in the strings are expanded once merged, i.e.,
+// ${DB_HOST} and ${server.port:8000}, see expand for the syntax.
 type Config interface {
+	// Load loads and merges the sources, and watches them for changes.
 	Load() error
+	// Scan scans the merged tree into v, a struct or a proto message, through json, so
+	// that the struct fields are named by the json tags, and the proto fields by protojson,
+	// i.e., a Duration of "5s". The default tags of the struct fields are applied for the
+	// missing keys, i.e., `default:"8000"`, which are json literals other than the strings
+	// and the time.Duration, i.e., `default:"5s"` and `default:"[\"a\"]"`, and the unknown
+	// keys are rejected if strict, see WithStrict. The strings are parsed into the
+	// numbers, the booleans and the durations of the fields, i.e., the values of the env
+	// sources, and a single value is scanned into an array field as the only element.
 	Scan(v interface{}) error
+	// Value returns the value of the dot path in the current snapshot of the tree, whose
+	// getters fail with ErrNotFound if missing.
 	Value(key string) Value
+	// Watch watches the value of the dot path, the observer is called on a dedicated
+	// goroutine once the value changes after a reload, the changes within 100ms are
+	// coalesced. The keys missing are watched as well, whose observers are called once
+	// the keys are added. Each key has at most one observer, which is replaced by the next
+	// Watch, and a nil observer stops watching the key, the calls not started are dropped.
 	Watch(key string, o Observer) error
+	// Explain returns the origins of the value of the dot path, from the lowest priority
+	// to the highest, the last one wins unless they are maps, which are merged.
+	Explain(key string) ([]Origin, error)
+	// String returns the current snapshot in json with the secrets masked, see WithMask.
+	String() string
+	// Dump writes the current snapshot in indented json with the secrets masked, i.e.,
+	// the effective config in the startup log.
+	Dump(w io.Writer) error
+	// Close stops watching the sources, and waits for the pending call of the observers,
+	// so it must not be called by the observers.
 	Close() error
 }
 
 type config struct {
 	opts      options
-	reader    Reader
-	cached    sync.Map
-	observers sync.Map
+	reader    *reader
+	observers *observers
 	watchers  []Watcher
 	log       *log.Helper
+
+	mu      sync.Mutex
+	pending map[int][]*KeyValue
+	changed chan struct{}
+
+	wg   sync.WaitGroup
+	once sync.Once
+	done chan struct{}
+}
+
+// defaultDecoder decodes the value by the codec of its format registered in the encoding,
+// i.e., json, or the subtype of the content type, i.e., application/json. The value of the
+// empty format is raw, which is stored as a string under the dot path of its key.
+func defaultDecoder(kv *KeyValue, target map[string]interface{}) error {
+	if kv.Format == "" {
+		keys := strings.Split(kv.Key, ".")
+		for _, key := range keys[:len(keys)-1] {
+			next := make(map[string]interface{})
+			target[key] = next
+			target = next
+		}
+		target[keys[len(keys)-1]] = string(kv.Value)
+		return nil
+	}
+	codec := encoding.GetCodec(contentSubtype(kv.Format))
+	if codec == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, kv.Format)
+	}
+	var v map[string]interface{}
+	if err := codec.Unmarshal(kv.Value, &v); err != nil {
+		return err
+	}
+	for key, value := range v {
+		target[key] = value
+	}
+	return nil
+}
+
+// contentSubtype returns the subtype of the content type, i.e., json of application/json.
+func contentSubtype(format string) string {
+	if i := strings.IndexByte(format, ';'); i >= 0 {
+		format = format[:i]
+	}
+	if i := strings.IndexByte(format, '/'); i >= 0 {
+		format = format[i+1:]
+	}
+	return strings.TrimSpace(format)
 }
 
 // New new a config with options.
 func New(opts ...Option) Config {
 	options := options{
-		logger: stdlog.NewLogger(),
-		decoder: func(kv *KeyValue, v interface{}) error {
-			return json.Unmarshal(kv.Value, v)
-		},
+		logger:  log.GetLogger(),
+		decoder: defaultDecoder,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
-	return &config{
-		opts:   options,
-		reader: newReader(options),
-		log:    log.NewHelper("config", options.logger),
+	c := &config{
+		opts:      options,
+		reader:    newReader(options),
+		observers: newObservers(),
+		log:       log.NewHelper("config", options.logger),
+		changed:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
 	}
+	c.wg.Add(2)
+	go c.reload()
+	go func() {
+		defer c.wg.Done()
+		c.observers.dispatch(c.done, func(v interface{}) Value {
+			return newStrictValue(v, options.strict)
+		})
+	}()
+	return c
 }
 
-func (c *config) watch(w Watcher) {
+func (c *config) watch(i int, w Watcher) {
 	for {
 		kvs, err := w.Next()
 		if err != nil {
-			time.Sleep(time.Second)
+			select {
+			case <-c.done:
+				return
+			default:
+			}
 			c.log.Errorf("Failed to watch next config: %v", err)
+			select {
+			case <-c.done:
+				return
+			case <-time.After(time.Second):
+			}
 			continue
 		}
-		if err := c.reader.Merge(kvs...); err != nil {
-			c.log.Errorf("Failed to merge next config: %v", err)
-			continue
+		c.mu.Lock()
+		if c.pending == nil {
+			c.pending = make(map[int][]*KeyValue)
+		}
+		c.pending[i] = append(c.pending[i], kvs...)
+		c.mu.Unlock()
+		select {
+		case c.changed <- struct{}{}:
+		default:
 		}
-		c.cached.Range(func(key, value interface{}) bool {
-			k := key.(string)
-			v := value.(Value)
-			if n, ok := c.reader.Value(k); ok && !reflect.DeepEqual(n.Load(), v.Load()) {
-				v.Store(n.Load())
-				if o, ok := c.observers.Load(k); ok {
-					o.(Observer)(k, v)
+	}
+}
+
+// reload merges the pending changes once the sources are quiet for the debounce window,
+// and notifies the observers of the changed values.
+func (c *config) reload() {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.changed:
+		}
+		timer := time.NewTimer(debounce)
+	wait:
+		for {
+			select {
+			case <-c.done:
+				timer.Stop()
+				return
+			case <-c.changed:
+				if !timer.Stop() {
+					<-timer.C
 				}
+				timer.Reset(debounce)
+			case <-timer.C:
+				break wait
 			}
-			return true
-		})
+		}
+		c.mu.Lock()
+		updates := c.pending
+		c.pending = nil
+		c.mu.Unlock()
+		if err := c.reader.merge(updates); err != nil {
+			c.log.Errorf("Failed to merge next config: %v", err)
+			if c.opts.errorHandler != nil {
+				c.opts.errorHandler(err)
+			}
+			continue
+		}
+		c.observers.notify(c.reader.load())
 	}
 }
 
 func (c *config) Load() error {
-	for _, src := range c.opts.sources {
+	updates := make(map[int][]*KeyValue, len(c.opts.sources))
+	for i, src := range c.opts.sources {
 		kvs, err := src.Load()
 		if err != nil {
 			return err
 		}
-		if err := c.reader.Merge(kvs...); err != nil {
-			c.log.Errorf("Failed to merge config source: %v", err)
-			return err
-		}
+		updates[i] = kvs
+	}
+	// the sources are merged and validated as a whole.
+	if err := c.reader.merge(updates); err != nil {
+		c.log.Errorf("Failed to merge config source: %v", err)
+		return err
+	}
+	for i, src := range c.opts.sources {
 		w, err := src.Watch()
 		if err != nil {
 			c.log.Errorf("Failed to watch config source: %v", err)
 			return err
 		}
-		go c.watch(w)
+		c.watchers = append(c.watchers, w)
+		go c.watch(i, w)
 	}
 	return nil
 }
 
 func (c *config) Value(key string) Value {
-	if v, ok := c.cached.Load(key); ok {
-		return v.(Value)
-	}
 	if v, ok := c.reader.Value(key); ok {
-		c.cached.Store(key, v)
 		return v
 	}
 	return &errValue{err: ErrNotFound}
 }
 
 func (c *config) Scan(v interface{}) error {
-	data, err := c.reader.Source()
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(data, v)
+	return scan(c.reader.load(), v, c.opts.strict)
 }
 
 func (c *config) Watch(key string, o Observer) error {
-	if v := c.Value(key); v.Load() == nil {
-		return ErrNotFound
+	if o == nil {
+		c.observers.remove(key)
+		return nil
+	}
+	v, ok := lookup(c.reader.load(), key)
+	if !ok {
+		// the observer is called once the key is added.
+		v = removed{}
 	}
-	c.observers.Store(key, o)
+	c.observers.add(key, o, v)
 	return nil
 }
 
+func (c *config) Explain(key string) ([]Origin, error) {
+	return c.reader.Explain(key)
+}
+
+func (c *config) String() string {
+	data, err := json.Marshal(c.reader.redacted())
+	if err != nil {
+		return fmt.Sprintf("config: %v", err)
+	}
+	return string(data)
+}
+
+func (c *config) Dump(w io.Writer) error {
+	data, err := json.MarshalIndent(c.reader.redacted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
 func (c *config) Close() error {
-	for _, w := range c.watchers {
-		if err := w.Close(); err != nil {
-			return err
+	var err error
+	c.once.Do(func() {
+		close(c.done)
+		for _, w := range c.watchers {
+			if e := w.Close(); e != nil && err == nil {
+				err = e
+			}
 		}
-	}
-	return nil
+		c.wg.Wait()
+	})
+	return err
 }
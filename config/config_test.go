@@ -0,0 +1,354 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+)
+
+type testSource struct {
+	kvs     []*KeyValue
+	changes chan []*KeyValue
+	once    sync.Once
+	done    chan struct{}
+}
+
+func newTestSource(data string) *testSource {
+	return &testSource{
+		kvs:     []*KeyValue{{Key: "test", Value: []byte(data), Format: "json"}},
+		changes: make(chan []*KeyValue),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *testSource) Load() ([]*KeyValue, error) { return s.kvs, nil }
+func (s *testSource) Watch() (Watcher, error)    { return s, nil }
+
+func (s *testSource) Next() ([]*KeyValue, error) {
+	select {
+	case kvs := <-s.changes:
+		return kvs, nil
+	case <-s.done:
+		return nil, errors.New("closed")
+	}
+}
+
+func (s *testSource) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *testSource) set(data string) {
+	s.kvs = []*KeyValue{{Key: "test", Value: []byte(data), Format: "json"}}
+	s.changes <- s.kvs
+}
+
+func TestWatch(t *testing.T) {
+	s := newTestSource(`{"log": {"level": "info", "format": "json"}}`)
+	c := New(WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	calls := make(chan string, 10)
+	if err := c.Watch("log.level", func(key string, v Value) {
+		level, _ := v.String()
+		calls <- key + "=" + level
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// coalesced into one reload.
+	s.set(`{"log": {"level": "debug", "format": "json"}}`)
+	s.set(`{"log": {"level": "warn", "format": "json"}}`)
+	select {
+	case call := <-calls:
+		if call != "log.level=warn" {
+			t.Errorf("expected the merged value, but got %s", call)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the observer called")
+	}
+	// the unchanged value is not observed.
+	s.set(`{"log": {"level": "warn", "format": "text"}}`)
+	time.Sleep(3 * debounce)
+	if format, _ := c.Value("log.format").String(); format != "text" {
+		t.Errorf("expected the merged format, but got %s", format)
+	}
+	if err := c.Watch("log.level", nil); err != nil {
+		t.Fatal(err)
+	}
+	s.set(`{"log": {"level": "error", "format": "text"}}`)
+	time.Sleep(3 * debounce)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(calls)
+	for call := range calls {
+		t.Errorf("expected no calls, but got %s", call)
+	}
+}
+
+func TestWatchSlowObserver(t *testing.T) {
+	s := newTestSource(`{"a": 0, "b": 0}`)
+	c := New(WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	block := make(chan struct{})
+	c.Watch("a", func(string, Value) { <-block })
+	s.set(`{"a": 1, "b": 0}`)
+	time.Sleep(3 * debounce)
+	s.set(`{"a": 1, "b": 1}`)
+	time.Sleep(3 * debounce)
+	if b, _ := c.Value("b").Int(); b != 1 {
+		t.Errorf("expected the reload not blocked by the observer, but got %d", b)
+	}
+	close(block)
+	c.Close()
+}
+
+func TestReloadError(t *testing.T) {
+	s := newTestSource(`{"a": 1}`)
+	errs := make(chan error, 1)
+	c := New(WithSource(s), WithErrorHandler(func(err error) { errs <- err }))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// the first one is valid, but the reload is applied as a whole.
+	s.changes <- []*KeyValue{
+		{Key: "valid", Value: []byte(`{"a": 2}`), Format: "json"},
+		{Key: "invalid", Value: []byte(`{"a":`), Format: "json"},
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected the reload error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the error handler called")
+	}
+	if a, _ := c.Value("a").Int(); a != 1 {
+		t.Errorf("expected the previous snapshot kept, but got %d", a)
+	}
+}
+
+func TestSnapshotRace(t *testing.T) {
+	c := New(WithSource(newTestSource(`{"pair": {"a": 0, "b": 0}}`)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				pair, err := c.Value("pair").Map()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				a, _ := pair["a"].Int()
+				b, _ := pair["b"].Int()
+				if a != b {
+					t.Errorf("expected a consistent snapshot, but got a=%d b=%d", a, b)
+					return
+				}
+				var v struct {
+					Pair struct{ A, B int }
+				}
+				if err := c.Scan(&v); err != nil || v.Pair.A != v.Pair.B {
+					t.Errorf("expected a consistent scan, but got %+v %v", v, err)
+					return
+				}
+			}
+		}()
+	}
+	r := c.(*config).reader
+	for i := 1; i <= 200; i++ {
+		data := fmt.Sprintf(`{"pair": {"a": %d, "b": %d}}`, i, i)
+		if err := r.merge(map[int][]*KeyValue{0: {{Key: "a", Value: []byte(data), Format: "json"}}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.merge(map[int][]*KeyValue{0: {{Key: "b", Value: []byte(`{"pair": {"a": -1, `), Format: "json"}}}); err == nil {
+			t.Fatal("expected the merge error")
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestDecoder(t *testing.T) {
+	src := namedSource{newTestSource(`{}`), "remote"}
+	src.kvs = []*KeyValue{
+		{Key: "app.yaml", Value: []byte("server:\n  addr: :8000\n"), Format: "application/x-yaml; charset=utf-8"},
+		{Key: "secrets.db.password", Value: []byte("p@ss\n"), Format: ""},
+	}
+	c := New(WithSource(src))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if addr, _ := c.Value("server.addr").String(); addr != ":8000" {
+		t.Errorf("expected the codec of the content type, but got %s", addr)
+	}
+	if password, _ := c.Value("secrets.db.password").String(); password != "p@ss\n" {
+		t.Errorf("expected the raw value, but got %q", password)
+	}
+
+	unknown := namedSource{newTestSource(`{}`), "remote"}
+	unknown.kvs = []*KeyValue{{Key: "app.ini", Value: []byte("a=1"), Format: "ini"}}
+	err := New(WithSource(unknown)).Load()
+	if !errors.Is(err, ErrUnknownFormat) || !strings.Contains(err.Error(), "app.ini of remote") {
+		t.Errorf("expected the unknown format of the key, but got %v", err)
+	}
+	ini := New(WithSource(unknown), WithDecoder(func(kv *KeyValue, target map[string]interface{}) error {
+		for _, line := range strings.Split(string(kv.Value), "\n") {
+			if i := strings.IndexByte(line, '='); i > 0 {
+				target[line[:i]] = line[i+1:]
+			}
+		}
+		return nil
+	}))
+	if err := ini.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer ini.Close()
+	if a, _ := ini.Value("a").Int(); a != 1 {
+		t.Errorf("expected the custom decoder, but got %d", a)
+	}
+}
+
+type testBootstrap struct {
+	Addr    string `json:"addr"`
+	Timeout int    `json:"timeout"`
+}
+
+func (b *testBootstrap) Validate() error {
+	if b.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	s := newTestSource(`{"addr": ":8000"}`)
+	errs := make(chan error, 1)
+	c := New(
+		WithSource(s),
+		WithDefaults(&testBootstrap{Addr: ":80", Timeout: 1}),
+		WithValidator(func(v interface{}) error {
+			if v.(*testBootstrap).Addr == "" {
+				return errors.New("addr is required")
+			}
+			return nil
+		}),
+		WithErrorHandler(func(err error) { errs <- err }),
+	)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if addr, _ := c.Value("addr").String(); addr != ":8000" {
+		t.Errorf("expected the source over the defaults, but got %s", addr)
+	}
+	if timeout, _ := c.Value("timeout").Int(); timeout != 1 {
+		t.Errorf("expected the default timeout, but got %d", timeout)
+	}
+	for _, data := range []string{`{"addr": ":8000", "timeout": -1}`, `{"addr": ""}`} {
+		s.changes <- []*KeyValue{{Key: "test", Value: []byte(data), Format: "json"}}
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("expected ErrInvalid, but got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the error handler called")
+		}
+		if addr, _ := c.Value("addr").String(); addr != ":8000" {
+			t.Errorf("expected the previous snapshot kept, but got %s", addr)
+		}
+	}
+
+	c = New(WithSource(newTestSource(`{"timeout": 0}`)), WithDefaults(testBootstrap{Timeout: 1}))
+	if err := c.Load(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected the invalid config rejected at load, but got %v", err)
+	}
+}
+
+func TestWatchNullAndRemoved(t *testing.T) {
+	s := newTestSource(`{"a": 1}`)
+	c := New(WithSource(s))
+	defer c.Close()
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	calls := make(chan Value, 10)
+	if err := c.Watch("a", func(_ string, v Value) { calls <- v }); err != nil {
+		t.Fatal(err)
+	}
+	next := func() Value {
+		select {
+		case v := <-calls:
+			return v
+		case <-time.After(time.Second):
+			t.Fatal("expected the observer called")
+		}
+		return nil
+	}
+	s.set(`{"a": null}`)
+	if v := next(); v.Load() != nil {
+		t.Errorf("expected the null value, but got %v", v.Load())
+	}
+	s.set(`{}`)
+	if _, err := next().Int(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound of the removed key, but got %v", err)
+	}
+	s.set(`{"a": 2}`)
+	if n, _ := next().Int(); n != 2 {
+		t.Errorf("expected the key added back, but got %v", n)
+	}
+}
+
+func TestWatchAdded(t *testing.T) {
+	s := newTestSource(`{"a": 1}`)
+	c := New(WithSource(s))
+	defer c.Close()
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	calls := make(chan Value, 10)
+	if err := c.Watch("b", func(_ string, v Value) { calls <- v }); err != nil {
+		t.Fatal(err)
+	}
+	// the reloads without the key never call the observer.
+	s.set(`{"a": 2}`)
+	select {
+	case v := <-calls:
+		t.Errorf("expected no call while the key is missing, but got %v", v.Load())
+	case <-time.After(300 * time.Millisecond):
+	}
+	s.set(`{"a": 2, "b": "added"}`)
+	select {
+	case v := <-calls:
+		if b, _ := v.String(); b != "added" {
+			t.Errorf("expected the key added, but got %v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the observer called once the key is added")
+	}
+}
@@ -0,0 +1,141 @@
+// Package configtest provides the conformance tests of the config sources.
+package configtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+// Timeout is the max duration of waiting for a change of the key-values.
+var Timeout = 5 * time.Second
+
+// Backend writes the key-values of a source, the keys are relative to the path of the
+// source, i.e., app.json of /configs/app/app.json.
+type Backend interface {
+	Put(key, value string) error
+	Delete(key string) error
+}
+
+// Run runs the conformance tests of the source, which watches the prefix the backend
+// writes to, and the prefix must have no keys before.
+func Run(t *testing.T, src config.Source, b Backend) {
+	if err := b.Put("a.json", `{"name": "a", "a": 1}`); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := b.Put("b.yaml", "name: b\n"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	kvs, err := src.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(kvs) != 2 || !strings.HasSuffix(kvs[0].Key, "a.json") || !strings.HasSuffix(kvs[1].Key, "b.yaml") {
+		t.Fatalf("expected the key-values in the order of the keys, but got %v", keys(kvs))
+	}
+	if kvs[0].Format != "json" || kvs[1].Format != "yaml" {
+		t.Errorf("expected the formats of the key suffixes, but got %s %s", kvs[0].Format, kvs[1].Format)
+	}
+
+	c := config.New(config.WithSource(src))
+	if err = c.Load(); err != nil {
+		t.Fatalf("config load: %v", err)
+	}
+	defer c.Close()
+	if name, _ := c.Value("name").String(); name != "b" {
+		t.Errorf("expected the later key wins, but got %s", name)
+	}
+
+	w, err := src.Watch()
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if err = b.Put("a.json", `{"name": "a", "a": 2}`); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	expect(t, "the changed key", w, "a.json", `{"name": "a", "a": 2}`)
+	if err = b.Delete("b.yaml"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expect(t, "the deleted key", w, "b.yaml", "")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err = w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected Next of the closed watcher fails")
+		}
+	case <-time.After(Timeout):
+		t.Fatal("expected Close unblocks Next")
+	}
+
+	// the config follows the changes.
+	deadline := time.Now().Add(Timeout)
+	for {
+		a, _ := c.Value("a").Int()
+		name, _ := c.Value("name").String()
+		if a == 2 && name == "a" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the config follows the changes, but got a=%d name=%s", a, name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// expect waits for the change of the key, whose value is empty if it is deleted.
+func expect(t *testing.T, what string, w config.Watcher, key, value string) {
+	t.Helper()
+	type result struct {
+		kvs []*config.KeyValue
+		err error
+	}
+	deadline := time.After(Timeout)
+	for {
+		ch := make(chan result, 1)
+		go func() {
+			kvs, err := w.Next()
+			ch <- result{kvs: kvs, err: err}
+		}()
+		var res result
+		select {
+		case res = <-ch:
+		case <-deadline:
+			t.Fatalf("%s: timeout", what)
+		}
+		if res.err != nil {
+			t.Fatalf("%s: %v", what, res.err)
+		}
+		for _, kv := range res.kvs {
+			if !strings.HasSuffix(kv.Key, key) {
+				continue
+			}
+			if value == "" && kv.Value != nil {
+				t.Fatalf("%s: expected the nil value, but got %q", what, kv.Value)
+			}
+			if value != "" && string(kv.Value) != value {
+				t.Fatalf("%s: expected %q, but got %q", what, value, kv.Value)
+			}
+			return
+		}
+	}
+}
+
+func keys(kvs []*config.KeyValue) []string {
+	res := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		res = append(res, kv.Key)
+	}
+	return res
+}
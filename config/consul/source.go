@@ -0,0 +1,206 @@
+// Package consul is the config source of the consul KV.
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+
+	// the codecs of the key suffixes.
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/toml"
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// ErrValueTooLarge is returned by Load for the values larger than the max size.
+var ErrValueTooLarge = errors.New("consul: config value too large")
+
+var _ config.Source = (*source)(nil)
+
+// Option is consul source option.
+type Option func(o *options)
+
+type options struct {
+	path       string
+	prefix     bool
+	format     string
+	maxSize    int
+	waitTime   time.Duration
+	maxBackoff time.Duration
+	logger     log.Logger
+}
+
+// WithPath with the key of the config, or the prefix of the keys if WithPrefix.
+func WithPath(p string) Option {
+	return func(o *options) { o.path = p }
+}
+
+// WithPrefix with whether the keys under the path are loaded, in the order of the keys,
+// i.e., configs/app/a.json and configs/app/b.yaml of configs/app.
+func WithPrefix(prefix bool) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// WithFormat with the format of the values, which is inferred from the key suffixes by default.
+func WithFormat(format string) Option {
+	return func(o *options) { o.format = format }
+}
+
+// WithMaxSize with the max size of a value, 512KiB by default as the consul KV limit.
+// The larger configs should be split into the keys under a prefix.
+func WithMaxSize(n int) Option {
+	return func(o *options) { o.maxSize = n }
+}
+
+// WithWaitTime with the max duration of the blocking queries, 5m by default.
+func WithWaitTime(d time.Duration) Option {
+	return func(o *options) { o.waitTime = d }
+}
+
+// WithMaxBackoff with the max delay between the retries of the blocking queries after the outages, 10s by default.
+func WithMaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// WithLogger with source logger, the global logger is used by default.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+type source struct {
+	client *api.Client
+	opts   options
+	log    *log.Helper
+
+	mu     sync.Mutex
+	loaded map[string][]byte
+	index  uint64
+}
+
+// NewSource new a consul source of the key, or the keys of the prefix. The values are
+// decoded by the codecs of the key suffixes, i.e., json, yaml, yml and toml, unless the
+// format is given, and the keys of unknown formats are skipped, i.e., the binary values.
+// The source is watched by the blocking queries, which are retried with backoff during
+// the outages, keeping the last key-values.
+func NewSource(client *api.Client, opts ...Option) config.Source {
+	o := options{
+		maxSize:    512 * 1024,
+		waitTime:   5 * time.Minute,
+		maxBackoff: 10 * time.Second,
+		logger:     log.GetLogger(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.path = strings.TrimPrefix(o.path, "/")
+	if o.prefix && !strings.HasSuffix(o.path, "/") {
+		o.path += "/"
+	}
+	return &source{client: client, opts: o, log: log.NewHelper("config/consul", o.logger)}
+}
+
+func (s *source) String() string {
+	return "consul:" + s.opts.path
+}
+
+// format returns the format of the key, or empty if it is unknown.
+func (s *source) format(key string) string {
+	format := s.opts.format
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(path.Ext(key), "."))
+		if format == "yml" {
+			format = "yaml"
+		}
+	}
+	if encoding.GetCodec(format) == nil {
+		return ""
+	}
+	return format
+}
+
+// get returns the key-values and the index, it blocks until the index changes if it is
+// not zero.
+func (s *source) get(ctx context.Context, index uint64) ([]*config.KeyValue, uint64, error) {
+	q := (&api.QueryOptions{WaitIndex: index, WaitTime: s.opts.waitTime}).WithContext(ctx)
+	var (
+		pairs api.KVPairs
+		meta  *api.QueryMeta
+		err   error
+	)
+	if s.opts.prefix {
+		pairs, meta, err = s.client.KV().List(s.opts.path, q)
+	} else {
+		var pair *api.KVPair
+		pair, meta, err = s.client.KV().Get(s.opts.path, q)
+		if pair != nil {
+			pairs = api.KVPairs{pair}
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]*config.KeyValue, 0, len(pairs))
+	for _, pair := range pairs {
+		format := s.format(pair.Key)
+		if format == "" {
+			s.log.Debugf("skip config key of unknown format: %s", pair.Key)
+			continue
+		}
+		if len(pair.Value) > s.opts.maxSize {
+			return nil, 0, fmt.Errorf("%w: %s of %d bytes", ErrValueTooLarge, pair.Key, len(pair.Value))
+		}
+		value := pair.Value
+		if value == nil {
+			value = []byte{}
+		}
+		kvs = append(kvs, &config.KeyValue{Key: pair.Key, Value: value, Format: format})
+	}
+	return kvs, meta.LastIndex, nil
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	kvs, index, err := s.get(context.Background(), 0)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.loaded, s.index = snapshot(kvs), index
+	s.mu.Unlock()
+	return kvs, nil
+}
+
+// Watch watches the changes since the last Load.
+func (s *source) Watch() (config.Watcher, error) {
+	s.mu.Lock()
+	loaded, index, ok := make(map[string][]byte, len(s.loaded)), s.index, s.loaded != nil
+	for key, value := range s.loaded {
+		loaded[key] = value
+	}
+	s.mu.Unlock()
+	if !ok {
+		kvs, i, err := s.get(context.Background(), 0)
+		if err != nil {
+			return nil, err
+		}
+		loaded, index = snapshot(kvs), i
+	}
+	return newWatcher(s, loaded, index), nil
+}
+
+func snapshot(kvs []*config.KeyValue) map[string][]byte {
+	res := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
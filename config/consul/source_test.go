@@ -0,0 +1,150 @@
+package consul
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config/configtest"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// kv is a fake consul KV serving the keys by the blocking queries.
+type kv struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	index   uint64
+	pairs   map[string][]byte
+	down    bool
+}
+
+func newKV() *kv {
+	return &kv{changed: make(chan struct{}), index: 1, pairs: make(map[string][]byte)}
+}
+
+func (s *kv) change(fn func()) {
+	s.mu.Lock()
+	fn()
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
+
+func (s *kv) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	key := strings.TrimPrefix(req.URL.Path, "/v1/kv/")
+	switch req.Method {
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(req.Body)
+		s.change(func() { s.pairs[key] = data })
+		res.Write([]byte("true"))
+		return
+	case http.MethodDelete:
+		s.change(func() { delete(s.pairs, key) })
+		res.Write([]byte("true"))
+		return
+	}
+	index, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+	s.mu.Lock()
+	if s.down {
+		s.mu.Unlock()
+		http.Error(res, "No cluster leader", http.StatusInternalServerError)
+		return
+	}
+	if index == s.index {
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(100 * time.Millisecond):
+		case <-req.Context().Done():
+		}
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+	_, recurse := req.URL.Query()["recurse"]
+	var pairs api.KVPairs
+	for k, v := range s.pairs {
+		if k == key || (recurse && strings.HasPrefix(k, key)) {
+			pairs = append(pairs, &api.KVPair{Key: k, Value: v})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	res.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+	if len(pairs) == 0 {
+		http.NotFound(res, req)
+		return
+	}
+	json.NewEncoder(res).Encode(pairs)
+}
+
+type backend struct {
+	client *api.Client
+	prefix string
+}
+
+func (b backend) Put(key, value string) error {
+	_, err := b.client.KV().Put(&api.KVPair{Key: b.prefix + key, Value: []byte(value)}, nil)
+	return err
+}
+
+func (b backend) Delete(key string) error {
+	_, err := b.client.KV().Delete(b.prefix+key, nil)
+	return err
+}
+
+func newClient(t *testing.T, s *kv) *api.Client {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestConformance(t *testing.T) {
+	client := newClient(t, newKV())
+	configtest.Run(t, NewSource(client, WithPath("configs/app"), WithPrefix(true)), backend{client: client, prefix: "configs/app/"})
+}
+
+func TestOutage(t *testing.T) {
+	s := newKV()
+	client := newClient(t, s)
+	s.pairs["configs/app.yaml"] = []byte("name: a\n")
+	s.pairs["configs/app.bin"] = []byte{0xff, 0x00}
+	src := NewSource(client, WithPath("/configs/app.yaml"), WithMaxBackoff(100*time.Millisecond))
+	kvs, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "configs/app.yaml" || kvs[0].Format != "yaml" {
+		t.Fatalf("expected the single key, but got %v", kvs)
+	}
+	w, err := src.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	s.mu.Lock()
+	s.down = true
+	s.mu.Unlock()
+	time.Sleep(300 * time.Millisecond)
+	s.change(func() { s.down, s.pairs["configs/app.yaml"] = false, []byte("name: b\n") })
+	kvs, err = w.Next()
+	if err != nil || len(kvs) != 1 || string(kvs[0].Value) != "name: b\n" {
+		t.Fatalf("expected the change after the outage, but got %v %v", kvs, err)
+	}
+	if _, err := NewSource(client, WithPath("configs/app.yaml"), WithMaxSize(4)).Load(); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("expected ErrValueTooLarge, but got %v", err)
+	}
+}
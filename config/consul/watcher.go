@@ -0,0 +1,97 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+var errWatcherClosed = errors.New("consul: config watcher closed")
+
+// watcher converts the results of the blocking queries into the changed key-values, the
+// deleted keys are of the nil values.
+type watcher struct {
+	s      *source
+	ctx    context.Context
+	cancel context.CancelFunc
+	last   map[string][]byte
+	index  uint64
+}
+
+func newWatcher(s *source, last map[string][]byte, index uint64) *watcher {
+	w := &watcher{s: s, last: last, index: index}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	return w
+}
+
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	for attempt := 0; ; {
+		kvs, index, err := w.s.get(w.ctx, w.index)
+		if w.ctx.Err() != nil {
+			return nil, errWatcherClosed
+		}
+		if err != nil {
+			w.s.log.Errorf("Failed to watch config %s: %v", w.s.opts.path, err)
+			select {
+			case <-w.ctx.Done():
+				return nil, errWatcherClosed
+			case <-time.After(w.backoff(attempt)):
+			}
+			attempt++
+			continue
+		}
+		attempt = 0
+		// the index is reset once it goes backwards, i.e., the consul is restored.
+		if index < w.index {
+			index = 0
+		}
+		w.index = index
+		if res := diff(w.last, kvs); len(res) > 0 {
+			w.last = snapshot(kvs)
+			return res, nil
+		}
+	}
+}
+
+func (w *watcher) backoff(attempt int) time.Duration {
+	d := w.s.opts.maxBackoff
+	if attempt < 16 {
+		if b := 100 * time.Millisecond << uint(attempt); b < d {
+			d = b
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// diff returns the changed key-values, and the deleted ones of the nil values.
+func diff(last map[string][]byte, kvs []*config.KeyValue) []*config.KeyValue {
+	var res []*config.KeyValue
+	keys := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		keys[kv.Key] = true
+		if old, ok := last[kv.Key]; !ok || !bytes.Equal(old, kv.Value) {
+			res = append(res, kv)
+		}
+	}
+	var deleted []string
+	for key := range last {
+		if !keys[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+	for _, key := range deleted {
+		res = append(res, &config.KeyValue{Key: key})
+	}
+	return res
+}
+
+func (w *watcher) Close() error {
+	w.cancel()
+	return nil
+}
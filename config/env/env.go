@@ -0,0 +1,105 @@
+// Package env is the config source of the environment variables.
+//
+// The variables of the prefix are mapped into the key paths as follows:
+//
+//  1. the prefix and the underscore following it are stripped, i.e., APP_ of APP_SERVER__HTTP__ADDR.
+//  2. the name is lowercased, i.e., server__http__addr.
+//  3. each double underscore separates the keys, i.e., server.http.addr.
+//
+// A single underscore is kept in the key, so that APP_LOG_LEVEL is log_level rather than
+// log.level, which is set by APP_LOG__LEVEL. The variables of empty keys are skipped, i.e.,
+// APP__SERVER and APP_SERVER__, and a nested key wins over the value of its parent, i.e.,
+// APP_SERVER__ADDR over APP_SERVER. The values are strings, which the typed getters of
+// the config values parse, i.e., Int of APP_SERVER__PORT=8000.
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+// Separator is the separator of the keys in the variable names.
+const Separator = "__"
+
+var _ config.Source = (*env)(nil)
+
+type env struct {
+	prefix string
+}
+
+// NewSource new an env source of the variables of the prefix, i.e., APP or APP_.
+func NewSource(prefix string) config.Source {
+	return &env{prefix: strings.TrimSuffix(prefix, "_") + "_"}
+}
+
+func (e *env) Load() ([]*config.KeyValue, error) {
+	data, err := json.Marshal(e.values(os.Environ()))
+	if err != nil {
+		return nil, err
+	}
+	return []*config.KeyValue{{
+		Key:    e.prefix,
+		Value:  data,
+		Format: "json",
+	}}, nil
+}
+
+// values returns the tree of the variables of the prefix.
+func (e *env) values(environ []string) map[string]interface{} {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		name, value := kv, ""
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			name, value = kv[:i], kv[i+1:]
+		}
+		if !strings.HasPrefix(name, e.prefix) {
+			continue
+		}
+		vars[strings.ToLower(strings.TrimPrefix(name, e.prefix))] = value
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	// the parents go first, so that the nested keys win.
+	sort.Strings(names)
+	res := make(map[string]interface{})
+next:
+	for _, name := range names {
+		// the prefix is followed by a separator, i.e., APP__SERVER.
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		keys := strings.Split(name, Separator)
+		for _, key := range keys {
+			if key == "" {
+				continue next
+			}
+		}
+		node := res
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[key] = child
+			}
+			node = child
+		}
+		node[keys[len(keys)-1]] = vars[name]
+	}
+	return res
+}
+
+// Watch returns a watcher which never changes, since the variables are fixed once the
+// process starts.
+func (e *env) Watch() (config.Watcher, error) {
+	return &watcher{done: make(chan struct{})}, nil
+}
+
+func (e *env) String() string {
+	return "env:" + e.prefix
+}
@@ -0,0 +1,56 @@
+package env
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+func TestValues(t *testing.T) {
+	e := NewSource("APP").(*env)
+	got := e.values([]string{
+		"APP_SERVER__HTTP__ADDR=:8080",
+		"APP_SERVER__HTTP__TIMEOUT=1s",
+		"APP_LOG_LEVEL=debug",
+		"APP_LOG__LEVEL=info",
+		"APP_DSN=user=root password=",
+		"APP_CACHE=redis",
+		"APP_CACHE__ADDR=:6379",
+		"APP__EMPTY=x",
+		"APP_TRAILING__=x",
+		"APPLICATION=x",
+		"HOME=/root",
+	})
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"http": map[string]interface{}{"addr": ":8080", "timeout": "1s"},
+		},
+		"log_level": "debug",
+		"log":       map[string]interface{}{"level": "info"},
+		"dsn":       "user=root password=",
+		"cache":     map[string]interface{}{"addr": ":6379"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestSource(t *testing.T) {
+	os.Setenv("KRATOS_TEST_SERVER__HTTP__ADDR", ":8080")
+	os.Setenv("KRATOS_TEST_SERVER__HTTP__PORT", "8080")
+	defer os.Unsetenv("KRATOS_TEST_SERVER__HTTP__ADDR")
+	defer os.Unsetenv("KRATOS_TEST_SERVER__HTTP__PORT")
+	c := config.New(config.WithSource(NewSource("KRATOS_TEST_")))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if addr, err := c.Value("server.http.addr").String(); err != nil || addr != ":8080" {
+		t.Errorf("expected :8080, but got %s %v", addr, err)
+	}
+	if port, err := c.Value("server.http.port").Int(); err != nil || port != 8080 {
+		t.Errorf("expected 8080, but got %d %v", port, err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package etcd is the config source of the etcd keys.
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+
+	// the codecs of the key suffixes.
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/toml"
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrValueTooLarge is returned by Load for the values larger than the max size.
+var ErrValueTooLarge = errors.New("etcd: config value too large")
+
+var _ config.Source = (*source)(nil)
+
+// Option is etcd source option.
+type Option func(o *options)
+
+type options struct {
+	path       string
+	prefix     bool
+	format     string
+	maxSize    int
+	timeout    time.Duration
+	maxBackoff time.Duration
+	logger     log.Logger
+}
+
+// WithPath with the key of the config, or the prefix of the keys if WithPrefix.
+func WithPath(p string) Option {
+	return func(o *options) { o.path = p }
+}
+
+// WithPrefix with whether the keys under the path are loaded, in the order of the keys,
+// i.e., /configs/app/a.json and /configs/app/b.yaml of /configs/app.
+func WithPrefix(prefix bool) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// WithFormat with the format of the values, which is inferred from the key suffixes by default.
+func WithFormat(format string) Option {
+	return func(o *options) { o.format = format }
+}
+
+// WithMaxSize with the max size of a value, 1.5MiB by default as the etcd request limit.
+// The larger configs should be split into the keys under a prefix.
+func WithMaxSize(n int) Option {
+	return func(o *options) { o.maxSize = n }
+}
+
+// WithTimeout with the timeout of the reads, 5s by default.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithMaxBackoff with the max delay between the retries of the watch after the outages, 10s by default.
+func WithMaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// WithLogger with source logger, the global logger is used by default.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+type source struct {
+	client *clientv3.Client
+	opts   options
+	log    *log.Helper
+
+	mu     sync.Mutex
+	loaded map[string][]byte
+	rev    int64
+}
+
+// NewSource new an etcd source of the key, or the keys of the prefix. The values are
+// decoded by the codecs of the key suffixes, i.e., json, yaml, yml and toml, unless the
+// format is given, and the keys of unknown formats are skipped, i.e., the binary values.
+// The source is watched by the native watch, which is resumed from the last revision
+// after the outages, or resynchronized once the revision is compacted.
+func NewSource(client *clientv3.Client, opts ...Option) config.Source {
+	o := options{
+		maxSize:    1536 * 1024,
+		timeout:    5 * time.Second,
+		maxBackoff: 10 * time.Second,
+		logger:     log.GetLogger(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.prefix && !strings.HasSuffix(o.path, "/") {
+		o.path += "/"
+	}
+	return &source{client: client, opts: o, log: log.NewHelper("config/etcd", o.logger)}
+}
+
+func (s *source) String() string {
+	return "etcd:" + s.opts.path
+}
+
+// format returns the format of the key, or empty if it is unknown.
+func (s *source) format(key string) string {
+	format := s.opts.format
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(path.Ext(key), "."))
+		if format == "yml" {
+			format = "yaml"
+		}
+	}
+	if encoding.GetCodec(format) == nil {
+		return ""
+	}
+	return format
+}
+
+// keyValue returns the key-value of the key, or nil if it is skipped.
+func (s *source) keyValue(key string, value []byte) (*config.KeyValue, error) {
+	format := s.format(key)
+	if format == "" {
+		s.log.Debugf("skip config key of unknown format: %s", key)
+		return nil, nil
+	}
+	if len(value) > s.opts.maxSize {
+		return nil, fmt.Errorf("%w: %s of %d bytes", ErrValueTooLarge, key, len(value))
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return &config.KeyValue{Key: key, Value: value, Format: format}, nil
+}
+
+// get returns the key-values and the revision.
+func (s *source) get(ctx context.Context) ([]*config.KeyValue, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.timeout)
+	defer cancel()
+	var opts []clientv3.OpOption
+	if s.opts.prefix {
+		opts = append(opts, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	}
+	resp, err := s.client.Get(ctx, s.opts.path, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]*config.KeyValue, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		kv, err := s.keyValue(string(item.Key), item.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		if kv != nil {
+			kvs = append(kvs, kv)
+		}
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	kvs, rev, err := s.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.loaded, s.rev = snapshot(kvs), rev
+	s.mu.Unlock()
+	return kvs, nil
+}
+
+// Watch watches the changes since the last Load.
+func (s *source) Watch() (config.Watcher, error) {
+	s.mu.Lock()
+	loaded, rev, ok := make(map[string][]byte, len(s.loaded)), s.rev, s.loaded != nil
+	for key, value := range s.loaded {
+		loaded[key] = value
+	}
+	s.mu.Unlock()
+	if !ok {
+		kvs, r, err := s.get(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		loaded, rev = snapshot(kvs), r
+	}
+	return newWatcher(s, loaded, rev), nil
+}
+
+func snapshot(kvs []*config.KeyValue) map[string][]byte {
+	res := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+// +build integration
+
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config/configtest"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// The tests run against the etcd at 127.0.0.1:2379, i.e.,
+// docker run -p 2379:2379 quay.io/coreos/etcd etcd --listen-client-urls http://0.0.0.0:2379 --advertise-client-urls http://0.0.0.0:2379
+// go test -tags integration
+func newClient(t *testing.T) *clientv3.Client {
+	client, err := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:2379"}, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+type backend struct {
+	client *clientv3.Client
+	prefix string
+}
+
+func (b backend) Put(key, value string) error {
+	_, err := b.client.Put(context.Background(), b.prefix+key, value)
+	return err
+}
+
+func (b backend) Delete(key string) error {
+	_, err := b.client.Delete(context.Background(), b.prefix+key)
+	return err
+}
+
+func TestConformance(t *testing.T) {
+	client := newClient(t)
+	defer client.Close()
+	ctx := context.Background()
+	prefix := "/test/configs/app/"
+	if _, err := client.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(ctx, prefix, clientv3.WithPrefix())
+	configtest.Run(t, NewSource(client, WithPath("/test/configs/app"), WithPrefix(true)), backend{client: client, prefix: prefix})
+}
+
+func TestSingleKey(t *testing.T) {
+	client := newClient(t)
+	defer client.Close()
+	ctx := context.Background()
+	key := "/test/configs/app.yaml"
+	if _, err := client.Put(ctx, key, "name: app\n"); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(ctx, key)
+	if _, err := client.Put(ctx, key+".bak", "name: bak\n"); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Delete(ctx, key+".bak")
+	kvs, err := NewSource(client, WithPath(key)).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != key || kvs[0].Format != "yaml" {
+		t.Fatalf("expected the single key, but got %v", kvs)
+	}
+	if _, err = NewSource(client, WithPath(key), WithMaxSize(4)).Load(); err == nil {
+		t.Error("expected the value too large")
+	}
+}
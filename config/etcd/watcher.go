@@ -0,0 +1,154 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/config"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var errWatcherClosed = errors.New("etcd: config watcher closed")
+
+// watcher converts the events of the keys into the changed key-values, the deleted keys
+// are of the nil values.
+type watcher struct {
+	s      *source
+	ctx    context.Context
+	cancel context.CancelFunc
+	last   map[string][]byte
+	rev    int64
+	wch    clientv3.WatchChan
+}
+
+func newWatcher(s *source, last map[string][]byte, rev int64) *watcher {
+	w := &watcher{s: s, last: last, rev: rev}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	return w
+}
+
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	for {
+		if w.wch == nil {
+			var opts []clientv3.OpOption
+			if w.s.opts.prefix {
+				opts = append(opts, clientv3.WithPrefix())
+			}
+			opts = append(opts, clientv3.WithRev(w.rev+1))
+			w.wch = w.s.client.Watch(clientv3.WithRequireLeader(w.ctx), w.s.opts.path, opts...)
+		}
+		select {
+		case <-w.ctx.Done():
+			return nil, errWatcherClosed
+		case resp, ok := <-w.wch:
+			if !ok || resp.Canceled || resp.Err() != nil {
+				if w.ctx.Err() != nil {
+					return nil, errWatcherClosed
+				}
+				w.s.log.Errorf("Failed to watch config %s: %v", w.s.opts.path, resp.Err())
+				w.wch = nil
+				if kvs, err := w.resync(); err != nil || len(kvs) > 0 {
+					return kvs, err
+				}
+				continue
+			}
+			if kvs := w.apply(resp.Events); len(kvs) > 0 {
+				w.rev = resp.Header.Revision
+				return kvs, nil
+			}
+			w.rev = resp.Header.Revision
+		}
+	}
+}
+
+// apply returns the changed key-values of the events.
+func (w *watcher) apply(events []*clientv3.Event) []*config.KeyValue {
+	var res []*config.KeyValue
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		if ev.Type == clientv3.EventTypeDelete {
+			if _, ok := w.last[key]; ok {
+				delete(w.last, key)
+				res = append(res, &config.KeyValue{Key: key, Format: w.s.format(key)})
+			}
+			continue
+		}
+		kv, err := w.s.keyValue(key, ev.Kv.Value)
+		if err != nil {
+			// the previous value of the key is kept.
+			w.s.log.Errorf("Failed to watch config %s: %v", key, err)
+			continue
+		}
+		if kv == nil {
+			continue
+		}
+		if old, ok := w.last[key]; ok && bytes.Equal(old, kv.Value) {
+			continue
+		}
+		w.last[key] = kv.Value
+		res = append(res, kv)
+	}
+	return res
+}
+
+// resync reloads the keys with backoff until it succeeds or the watcher is closed, and
+// returns the differences from the last key-values, which are kept during the outages.
+func (w *watcher) resync() ([]*config.KeyValue, error) {
+	for attempt := 0; ; attempt++ {
+		kvs, rev, err := w.s.get(w.ctx)
+		if err == nil {
+			res := diff(w.last, kvs)
+			w.last, w.rev = snapshot(kvs), rev
+			return res, nil
+		}
+		w.s.log.Errorf("Failed to reload config %s: %v", w.s.opts.path, err)
+		select {
+		case <-w.ctx.Done():
+			return nil, errWatcherClosed
+		case <-time.After(w.backoff(attempt)):
+		}
+	}
+}
+
+func (w *watcher) backoff(attempt int) time.Duration {
+	d := w.s.opts.maxBackoff
+	if attempt < 16 {
+		if b := 100 * time.Millisecond << uint(attempt); b < d {
+			d = b
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// diff returns the changed key-values, and the deleted ones of the nil values.
+func diff(last map[string][]byte, kvs []*config.KeyValue) []*config.KeyValue {
+	var res []*config.KeyValue
+	keys := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		keys[kv.Key] = true
+		if old, ok := last[kv.Key]; !ok || !bytes.Equal(old, kv.Value) {
+			res = append(res, kv)
+		}
+	}
+	var deleted []string
+	for key := range last {
+		if !keys[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+	for _, key := range deleted {
+		res = append(res, &config.KeyValue{Key: key})
+	}
+	return res
+}
+
+func (w *watcher) Close() error {
+	w.cancel()
+	return nil
+}
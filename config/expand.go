@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxExpandDepth is the max depth of the references, which are cyclic mostly if exceeded.
+const maxExpandDepth = 10
+
+// expand returns a copy of the tree whose strings are expanded as follows:
+//
+//	${name}           the value of the dot path in the tree, or the env variable
+//	${name:fallback}  the fallback is used if neither exists, which is expanded as well
+//	$${literal}       the escape of ${literal}, which is not expanded
+//
+// The values of the dot paths are expanded recursively, up to 10 levels deep, and the
+// type of the value is kept if it is the whole string, i.e., port: ${server.port}.
+// An undefined reference without the fallback is an error.
+func expand(values map[string]interface{}, lookupEnv func(string) (string, bool)) (map[string]interface{}, error) {
+	e := &expander{root: values, lookupEnv: lookupEnv}
+	res, err := e.value("", values, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.(map[string]interface{}), nil
+}
+
+type expander struct {
+	root      map[string]interface{}
+	lookupEnv func(string) (string, bool)
+}
+
+func (e *expander) value(path string, v interface{}, depth int) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return e.string(path, v, depth)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			p := key
+			if path != "" {
+				p = path + "." + key
+			}
+			var err error
+			if res[key], err = e.value(p, val, depth); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			var err error
+			if res[i], err = e.value(fmt.Sprintf("%s[%d]", path, i), val, depth); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	return v, nil
+}
+
+func (e *expander) string(path, s string, depth int) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := closing(s, i+2)
+		if end < 0 {
+			return nil, fmt.Errorf("config: unclosed reference in %s: %q", path, s)
+		}
+		v, err := e.resolve(path, s[i+2:end], depth)
+		if err != nil {
+			return nil, err
+		}
+		// the whole string keeps the type of the value.
+		if i == 0 && end == len(s)-1 {
+			return v, nil
+		}
+		switch v := v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config: reference ${%s} in %s is not a scalar", s[i+2:end], path)
+		case string:
+			b.WriteString(v)
+		default:
+			fmt.Fprint(&b, v)
+		}
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// resolve returns the value of the reference, i.e., name:fallback.
+func (e *expander) resolve(path, ref string, depth int) (interface{}, error) {
+	if depth >= maxExpandDepth {
+		return nil, fmt.Errorf("config: reference ${%s} in %s exceeds the depth of %d", ref, path, maxExpandDepth)
+	}
+	name, fallback, hasFallback := ref, "", false
+	if i := strings.IndexByte(ref, ':'); i >= 0 {
+		name, fallback, hasFallback = ref[:i], ref[i+1:], true
+	}
+	if v, ok := lookup(e.root, name); ok {
+		return e.value(name, v, depth+1)
+	}
+	if v, ok := e.lookupEnv(name); ok {
+		return v, nil
+	}
+	if hasFallback {
+		return e.string(path, fallback, depth+1)
+	}
+	return nil, fmt.Errorf("config: undefined reference ${%s} in %s", name, path)
+}
+
+// closing returns the index of the brace closing the reference from i, counting the
+// nested references of the fallbacks, or -1 if it is unclosed.
+func closing(s string, i int) int {
+	open := 1
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			open++
+		case '}':
+			if open--; open == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{"DB_PASSWORD": "secret", "DB_HOST": "10.0.0.1"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	values := map[string]interface{}{
+		"db": map[string]interface{}{
+			"dsn":  "root:${DB_PASSWORD}@tcp(${DB_HOST}:${db.port})/${db.name:app}",
+			"port": float64(3306),
+			"addr": "${db.host:${DB_HOST}}",
+		},
+		"server": map[string]interface{}{
+			"port":    "${db.port}",
+			"name":    "${name}",
+			"literal": "$${DB_HOST} ${DB_HOST}",
+			"hosts":   []interface{}{"${DB_HOST}", "localhost"},
+		},
+		"name": "${app.name:kratos}",
+	}
+	got, err := expand(values, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"db": map[string]interface{}{
+			"dsn":  "root:secret@tcp(10.0.0.1:3306)/app",
+			"port": float64(3306),
+			"addr": "10.0.0.1",
+		},
+		"server": map[string]interface{}{
+			"port":    float64(3306),
+			"name":    "kratos",
+			"literal": "${DB_HOST} 10.0.0.1",
+			"hosts":   []interface{}{"10.0.0.1", "localhost"},
+		},
+		"name": "kratos",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+	if values["name"] != "${app.name:kratos}" {
+		t.Errorf("expected the raw tree kept, but got %v", values["name"])
+	}
+	for data, message := range map[string]string{
+		"${NOT_FOUND}":    "undefined reference ${NOT_FOUND} in a",
+		"${b}":            "exceeds the depth",
+		"${DB_HOST":       "unclosed reference",
+		"x${db}":          "not a scalar",
+		"${NOT_FOUND:${}": "unclosed reference",
+	} {
+		_, err := expand(map[string]interface{}{
+			"a":  data,
+			"b":  "${a}",
+			"db": map[string]interface{}{},
+		}, lookupEnv)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected the error of %q, but got %v", data, err)
+		}
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	s := newTestSource(`{"host": "127.0.0.1", "addr": "${host}:${port:8000}"}`)
+	c := New(WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	calls := make(chan string, 1)
+	c.Watch("addr", func(_ string, v Value) {
+		addr, _ := v.String()
+		calls <- addr
+	})
+	if addr, _ := c.Value("addr").String(); addr != "127.0.0.1:8000" {
+		t.Errorf("expected the expanded value, but got %s", addr)
+	}
+	var conf struct{ Addr string }
+	if err := c.Scan(&conf); err != nil || conf.Addr != "127.0.0.1:8000" {
+		t.Errorf("expected the expanded scan, but got %s %v", conf.Addr, err)
+	}
+	s.set(`{"host": "10.0.0.1", "addr": "${host}:${port:8000}"}`)
+	if addr := <-calls; addr != "10.0.0.1:8000" {
+		t.Errorf("expected the expanded observed value, but got %s", addr)
+	}
+}
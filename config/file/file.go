@@ -7,17 +7,47 @@ import (
 	"strings"
 
 	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+
+	// the codecs of the file extensions.
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/toml"
+	"github.com/go-kratos/kratos/v2/encoding/yaml"
 )
 
 var _ config.Source = (*file)(nil)
 
+// Option is file source option.
+type Option func(*options)
+
+type options struct {
+	logger log.Logger
+}
+
+// WithLogger with file source logger, the global logger is used by default.
+func WithLogger(l log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
 type file struct {
 	path string
+	log  *log.Helper
 }
 
-// NewSource new a file source.
-func NewSource(path string) config.Source {
-	return &file{path: path}
+// NewSource new a file source of a single file or a directory, whose files are loaded in
+// the order of their names, not recursively. The files are decoded by the codecs of their
+// extensions, i.e., json, yaml, yml and toml, the hidden files and the files of unknown
+// extensions are skipped. The symlinks are followed, so that the mounted Kubernetes
+// ConfigMaps are watched through their ..data swaps.
+func NewSource(path string, opts ...Option) config.Source {
+	o := options{logger: log.GetLogger()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &file{path: path, log: log.NewHelper("config/file", o.logger)}
 }
 
 func (f *file) loadFile(path string) (*config.KeyValue, error) {
@@ -35,23 +65,46 @@ func (f *file) loadFile(path string) (*config.KeyValue, error) {
 		return nil, err
 	}
 	return &config.KeyValue{
-		Key:       info.Name(),
+		Key:       filepath.Base(path),
 		Value:     data,
+		Format:    format(info.Name()),
 		Timestamp: info.ModTime(),
 	}, nil
 }
 
+// format returns the codec name of the file by its extension.
+func format(name string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext == "yml" {
+		return yaml.Name
+	}
+	return ext
+}
+
 func (f *file) loadDir(path string) (kvs []*config.KeyValue, err error) {
-	files, err := ioutil.ReadDir(f.path)
+	files, err := ioutil.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
 	for _, file := range files {
-		// ignore hidden files
-		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+		name := filepath.Join(path, file.Name())
+		// ignore hidden files, i.e., the ..data of the ConfigMaps
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			if file, err = os.Stat(name); err != nil {
+				return nil, err
+			}
+		}
+		if file.IsDir() {
 			continue
 		}
-		kv, err := f.loadFile(filepath.Join(f.path, file.Name()))
+		if encoding.GetCodec(format(file.Name())) == nil {
+			f.log.Debugf("skip config file of unknown format: %s", name)
+			continue
+		}
+		kv, err := f.loadFile(name)
 		if err != nil {
 			return nil, err
 		}
@@ -78,3 +131,7 @@ func (f *file) Load() (kvs []*config.KeyValue, err error) {
 func (f *file) Watch() (config.Watcher, error) {
 	return newWatcher(f)
 }
+
+func (f *file) String() string {
+	return "file:" + f.path
+}
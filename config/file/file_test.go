@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/config/configtest"
 )
 
 const (
@@ -140,3 +142,188 @@ func testConfig(t *testing.T, c config.Config) {
 	}
 
 }
+
+func TestYAMLConfig(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	file := filepath.Join(path, "test.yml")
+	if err := ioutil.WriteFile(file, []byte("server:\n  addr: 127.0.0.1\n  port: 8000\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	c := config.New(config.WithSource(NewSource(file)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if addr, err := c.Value("server.addr").String(); err != nil || addr != "127.0.0.1" {
+		t.Errorf("expected the yaml value, but got %s %v", addr, err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	for name, data := range map[string]string{
+		"b.yaml":      "b: 1\n",
+		"a.json":      `{"a": 1}`,
+		"c.toml":      "c = 1\n",
+		".hidden.yml": "hidden: 1\n",
+		"README.md":   "# readme\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(path, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kvs, err := NewSource(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key+":"+kv.Format)
+	}
+	if got := strings.Join(keys, ","); got != "a.json:json,b.yaml:yaml,c.toml:toml" {
+		t.Errorf("expected the sorted files of known formats, but got %s", got)
+	}
+}
+
+// writeConfigMap writes the files the way the kubelet updates a mounted ConfigMap, the
+// files are symlinks into ..data, which is swapped to a new timestamped directory.
+func writeConfigMap(t *testing.T, path, version, data string) {
+	dir := filepath.Join(path, "..."+version)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := os.Readlink(filepath.Join(path, "..data"))
+	if err := os.Symlink(filepath.Base(dir), filepath.Join(path, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(path, "..data_tmp"), filepath.Join(path, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if old != "" {
+		os.RemoveAll(filepath.Join(path, old))
+	}
+	link := filepath.Join(path, "config.yaml")
+	if _, err := os.Lstat(link); os.IsNotExist(err) {
+		if err := os.Symlink(filepath.Join("..data", "config.yaml"), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	current := "version: 1\n"
+	writeConfigMap(t, path, "1", current)
+	for _, source := range []string{path, filepath.Join(path, "config.yaml")} {
+		s := NewSource(source)
+		kvs, err := s.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != 1 || string(kvs[0].Value) != current {
+			t.Fatalf("expected %q, but got %v", current, kvs)
+		}
+		w, err := s.Watch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// twice, the watches of the files would be lost after the first swap.
+		for _, version := range []string{"2", "3"} {
+			data := "version: " + version + filepath.Base(source) + "\n"
+			writeConfigMap(t, path, version+filepath.Base(source), data)
+			current = data
+			kvs, err := nextTimeout(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(kvs) != 1 || kvs[0].Key != "config.yaml" || string(kvs[0].Value) != data {
+				t.Fatalf("expected %q, but got %v", data, kvs)
+			}
+		}
+		w.Close()
+	}
+}
+
+func TestWatchRename(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	file := filepath.Join(path, "config.json")
+	if err := ioutil.WriteFile(file, []byte(`{"version": 1}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewSource(file).Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, data := range []string{`{"version": 2}`, `{"version": 3}`} {
+		tmp := filepath.Join(path, ".config.json.swp")
+		if err := ioutil.WriteFile(tmp, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			t.Fatal(err)
+		}
+		kvs, err := nextTimeout(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(kvs[0].Value) != data {
+			t.Fatalf("expected %s, but got %s", data, kvs[0].Value)
+		}
+	}
+}
+
+func nextTimeout(w config.Watcher) ([]*config.KeyValue, error) {
+	type result struct {
+		kvs []*config.KeyValue
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		kvs, err := w.Next()
+		ch <- result{kvs: kvs, err: err}
+	}()
+	select {
+	case res := <-ch:
+		return res.kvs, res.err
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("timeout waiting for the config change")
+	}
+}
+
+type backend string
+
+func (b backend) Put(key, value string) error {
+	return ioutil.WriteFile(filepath.Join(string(b), key), []byte(value), 0666)
+}
+
+func (b backend) Delete(key string) error {
+	return os.Remove(filepath.Join(string(b), key))
+}
+
+func TestConformance(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	configtest.Run(t, NewSource(path), backend(path))
+}
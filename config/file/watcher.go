@@ -1,16 +1,27 @@
 package file
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/go-kratos/kratos/v2/config"
 )
 
+var errWatcherClosed = errors.New("config/file: watcher closed")
+
+// watcher watches the directory of the source rather than the files, since the files
+// are replaced by the atomic renames of the editors, and the symlinks of the mounted
+// ConfigMaps are swapped through ..data, neither of which is followed by the watches
+// of the files. The source is reloaded on any event, and the changed files are returned,
+// the deleted ones of the nil values.
 type watcher struct {
-	f  *file
-	fw *fsnotify.Watcher
+	f    *file
+	fw   *fsnotify.Watcher
+	last map[string][]byte
 }
 
 func newWatcher(f *file) (config.Watcher, error) {
@@ -18,36 +29,80 @@ func newWatcher(f *file) (config.Watcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	fw.Add(f.path)
-	return &watcher{f: f, fw: fw}, nil
+	dir := f.path
+	if fi, err := os.Stat(f.path); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(f.path)
+	}
+	if err := fw.Add(dir); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	w := &watcher{f: f, fw: fw, last: make(map[string][]byte)}
+	if kvs, err := f.Load(); err == nil {
+		w.last = snapshot(kvs)
+	}
+	return w, nil
 }
 
 func (w *watcher) Next() ([]*config.KeyValue, error) {
-	select {
-	case event := <-w.fw.Events:
-		if event.Op == fsnotify.Rename {
-			if _, err := os.Stat(event.Name); err == nil || os.IsExist(err) {
-				w.fw.Add(event.Name)
+	for {
+		select {
+		case _, ok := <-w.fw.Events:
+			if !ok {
+				return nil, errWatcherClosed
+			}
+			kvs, err := w.f.Load()
+			if err != nil {
+				// the files are missing in the middle of the swaps.
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			if res := diff(w.last, kvs); len(res) > 0 {
+				w.last = snapshot(kvs)
+				return res, nil
+			}
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return nil, errWatcherClosed
 			}
-		}
-		fi, err := os.Stat(w.f.path)
-		if err != nil {
-			return nil, err
-		}
-		path := w.f.path
-		if fi.IsDir() {
-			path = filepath.Join(w.f.path, event.Name)
-		}
-		kv, err := w.f.loadFile(path)
-		if err != nil {
 			return nil, err
 		}
-		return []*config.KeyValue{kv}, nil
-	case err := <-w.fw.Errors:
-		return nil, err
 	}
 }
 
 func (w *watcher) Close() error {
 	return w.fw.Close()
 }
+
+func snapshot(kvs []*config.KeyValue) map[string][]byte {
+	res := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+// diff returns the changed key-values, and the deleted ones of the nil values.
+func diff(last map[string][]byte, kvs []*config.KeyValue) []*config.KeyValue {
+	var res []*config.KeyValue
+	keys := make(map[string]bool, len(kvs))
+	for _, kv := range kvs {
+		keys[kv.Key] = true
+		if old, ok := last[kv.Key]; !ok || !bytes.Equal(old, kv.Value) {
+			res = append(res, kv)
+		}
+	}
+	var deleted []string
+	for key := range last {
+		if !keys[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+	for _, key := range deleted {
+		res = append(res, &config.KeyValue{Key: key, Format: format(key)})
+	}
+	return res
+}
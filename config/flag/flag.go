@@ -0,0 +1,178 @@
+// Package flag is the config source of the command-line flags, which should be the last
+// source, so that the flags win over the env variables and the files.
+//
+// The flags are named by the dot paths of the keys, i.e., --server.http.addr=:9090 for
+// server.http.addr, and a nested key wins over the value of its parent, i.e.,
+// --server.addr over --server.
+package flag
+
+import (
+	"encoding/json"
+	stdflag "flag"
+	"sort"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+var _ config.Source = (*source)(nil)
+
+// Strings is a flag.Value of the repeated flags, which is loaded as an array, i.e.,
+// --registry.endpoints=a --registry.endpoints=b.
+type Strings []string
+
+func (s *Strings) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set appends the value.
+func (s *Strings) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// Get returns the values.
+func (s *Strings) Get() interface{} {
+	return []string(*s)
+}
+
+type source struct {
+	name   string
+	values func() map[string]interface{}
+}
+
+// NewSource new a flag source of the flags set in the flag set, which must be parsed
+// before Load, so that the defaults of the unset flags never override the other sources.
+// The values are of the Get of the flag.Getter, i.e., the booleans of the bool flags and
+// the arrays of Strings, otherwise the strings.
+func NewSource(fs *stdflag.FlagSet) config.Source {
+	return &source{name: "flag:" + fs.Name(), values: func() map[string]interface{} {
+		values := make(map[string]interface{})
+		fs.Visit(func(f *stdflag.Flag) {
+			if g, ok := f.Value.(stdflag.Getter); ok {
+				values[f.Name] = g.Get()
+			} else {
+				values[f.Name] = f.Value.String()
+			}
+		})
+		return values
+	}}
+}
+
+// NewArgsSource new a flag source of the args, i.e., os.Args[1:], without declaring the
+// flags. The flags are given as --key=value or -key=value, a flag without a value is the
+// boolean true, i.e., --debug, and a repeated flag is an array of the values. The other
+// args are skipped, and the args after -- are never flags.
+func NewArgsSource(args []string) config.Source {
+	args = append([]string(nil), args...)
+	return &source{name: "args", values: func() map[string]interface{} {
+		return parseArgs(args)
+	}}
+}
+
+func parseArgs(args []string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value := strings.TrimLeft(arg, "-"), interface{}(true)
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		if name == "" {
+			continue
+		}
+		switch v := values[name].(type) {
+		case nil:
+			values[name] = value
+		case []interface{}:
+			values[name] = append(v, value)
+		default:
+			values[name] = []interface{}{v, value}
+		}
+	}
+	return values
+}
+
+// Bootstrap returns the value of the flag of the name in the args, i.e., the path of the
+// file source of --config=configs/, or the def if it is absent, and the args without it.
+// The value is given as --name=value or --name value.
+func Bootstrap(args []string, name, def string) (string, []string) {
+	value, rest := def, make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		switch trimmed := strings.TrimLeft(arg, "-"); {
+		case !strings.HasPrefix(arg, "-"):
+		case trimmed == name && i+1 < len(args):
+			value = args[i+1]
+			i++
+			continue
+		case strings.HasPrefix(trimmed, name+"="):
+			value = strings.TrimPrefix(trimmed, name+"=")
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return value, rest
+}
+
+func (s *source) String() string {
+	return s.name
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	data, err := json.Marshal(tree(s.values()))
+	if err != nil {
+		return nil, err
+	}
+	return []*config.KeyValue{{
+		Key:    s.name,
+		Value:  data,
+		Format: "json",
+	}}, nil
+}
+
+// tree returns the tree of the values of the dot paths.
+func tree(values map[string]interface{}) map[string]interface{} {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	// the parents go first, so that the nested keys win.
+	sort.Strings(names)
+	res := make(map[string]interface{})
+next:
+	for _, name := range names {
+		keys := strings.Split(name, ".")
+		for _, key := range keys {
+			if key == "" {
+				continue next
+			}
+		}
+		node := res
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[key] = child
+			}
+			node = child
+		}
+		node[keys[len(keys)-1]] = values[name]
+	}
+	return res
+}
+
+// Watch returns a watcher which never changes, since the flags are fixed once the
+// process starts.
+func (s *source) Watch() (config.Watcher, error) {
+	return &watcher{done: make(chan struct{})}, nil
+}
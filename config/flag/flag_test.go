@@ -0,0 +1,97 @@
+package flag
+
+import (
+	stdflag "flag"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+func TestParseArgs(t *testing.T) {
+	got := tree(parseArgs([]string{
+		"serve",
+		"--server.http.addr=:9090",
+		"-server.http.timeout=1s",
+		"--debug",
+		"--registry.endpoints=a",
+		"--registry.endpoints=b",
+		"--dsn=user=root",
+		"--",
+		"--ignored=true",
+	}))
+	want := map[string]interface{}{
+		"server":   map[string]interface{}{"http": map[string]interface{}{"addr": ":9090", "timeout": "1s"}},
+		"debug":    true,
+		"registry": map[string]interface{}{"endpoints": []interface{}{"a", "b"}},
+		"dsn":      "user=root",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	for _, args := range [][]string{
+		{"--config=configs/", "--debug"},
+		{"--config", "configs/", "--debug"},
+		{"-config=configs/", "--debug"},
+	} {
+		path, rest := Bootstrap(args, "config", "default/")
+		if path != "configs/" || !reflect.DeepEqual(rest, []string{"--debug"}) {
+			t.Errorf("expected the config path of %v, but got %s %v", args, path, rest)
+		}
+	}
+	if path, rest := Bootstrap([]string{"--debug", "--", "--config=x"}, "config", "default/"); path != "default/" || len(rest) != 3 {
+		t.Errorf("expected the default, but got %s %v", path, rest)
+	}
+}
+
+type testSource struct{ data string }
+
+func (s testSource) Load() ([]*config.KeyValue, error) {
+	return []*config.KeyValue{{Key: "file", Value: []byte(s.data), Format: "json"}}, nil
+}
+func (s testSource) Watch() (config.Watcher, error) { return &watcher{done: make(chan struct{})}, nil }
+
+func TestPriority(t *testing.T) {
+	fs := stdflag.NewFlagSet("test", stdflag.ContinueOnError)
+	fs.String("server.http.addr", ":8000", "")
+	fs.Int("server.http.port", 8000, "")
+	fs.Bool("debug", false, "")
+	var endpoints Strings
+	fs.Var(&endpoints, "registry.endpoints", "")
+	if err := fs.Parse([]string{"--server.http.addr=:9090", "--debug", "--registry.endpoints=a", "--registry.endpoints=b"}); err != nil {
+		t.Fatal(err)
+	}
+	file := testSource{`{"server": {"http": {"addr": ":8080", "port": 8080}}, "debug": false}`}
+	c := config.New(config.WithSource(file, NewSource(fs), NewArgsSource([]string{"--server.http.timeout=5s", "--hosts=a"})))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var conf struct {
+		Server struct {
+			HTTP struct {
+				Addr    string
+				Port    int
+				Timeout string
+			}
+		}
+		Debug    bool
+		Registry struct{ Endpoints []string }
+		Hosts    []string
+	}
+	if err := c.Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server.HTTP.Addr != ":9090" || conf.Server.HTTP.Port != 8080 || !conf.Debug {
+		t.Errorf("expected the set flags win, but got %+v", conf)
+	}
+	if conf.Server.HTTP.Timeout != "5s" || !reflect.DeepEqual(conf.Hosts, []string{"a"}) {
+		t.Errorf("expected the args, but got %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Registry.Endpoints, []string{"a", "b"}) {
+		t.Errorf("expected the repeated flags, but got %v", conf.Registry.Endpoints)
+	}
+}
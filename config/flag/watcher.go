@@ -0,0 +1,26 @@
+package flag
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/config"
+)
+
+var errWatcherClosed = errors.New("config/flag: watcher closed")
+
+type watcher struct {
+	once sync.Once
+	done chan struct{}
+}
+
+// Next blocks until the watcher is closed.
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	<-w.done
+	return nil, errWatcherClosed
+}
+
+func (w *watcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// masked replaces the values of the secret keys.
+const masked = "***"
+
+// defaultMask is the patterns of the secret keys masked by default.
+var defaultMask = []string{"*password*", "*secret*", "*token*"}
+
+// masker masks the values of the keys matching any of the patterns, which are the glob
+// patterns of path.Match or the exact keys, matched against the last segment of the dot
+// paths case-insensitively.
+type masker []string
+
+func newMasker(patterns []string) masker {
+	m := make(masker, 0, len(defaultMask)+len(patterns))
+	for _, p := range append(append([]string(nil), defaultMask...), patterns...) {
+		m = append(m, strings.ToLower(p))
+	}
+	return m
+}
+
+// match reports whether the last segment of the dot path is a secret key.
+func (m masker) match(key string) bool {
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.ToLower(key)
+	for _, p := range m {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mask returns a copy of the value whose secret keys are masked at any depth, including
+// the maps in the arrays, whatever the values of the secret keys are.
+func (m masker) mask(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(vv))
+		for key, value := range vv {
+			if m.match(key) {
+				res[key] = masked
+			} else {
+				res[key] = m.mask(value)
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, value := range vv {
+			res[i] = m.mask(value)
+		}
+		return res
+	}
+	return v
+}
+
+// maskPath masks the value of the dot path.
+func (m masker) maskPath(key string, v interface{}) interface{} {
+	if m.match(key) {
+		return masked
+	}
+	return m.mask(v)
+}
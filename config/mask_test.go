@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	c := New(WithSource(newTestSource(`{
+		"db": {"dsn": "root:pass@tcp/db", "Password": "p1", "pool": {"size": 10}},
+		"clients": [{"name": "a", "API_TOKEN": "t1"}, {"name": "b", "secrets": {"key": "k"}}],
+		"tokens": ["t2", "t3"],
+		"keys": {"secret_key": "s"}
+	}`)), WithMask("dsn"))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	want := map[string]interface{}{
+		"db": map[string]interface{}{"dsn": "***", "Password": "***", "pool": map[string]interface{}{"size": float64(10)}},
+		"clients": []interface{}{
+			map[string]interface{}{"name": "a", "API_TOKEN": "***"},
+			map[string]interface{}{"name": "b", "secrets": "***"},
+		},
+		"tokens": "***",
+		"keys":   map[string]interface{}{"secret_key": "***"},
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(c.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+	var buf bytes.Buffer
+	if err := c.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "p1") || !strings.Contains(buf.String(), `"dsn": "***"`) {
+		t.Errorf("expected the secrets masked in the dump, but got %s", buf.String())
+	}
+
+	if password, _ := c.Value("db.Password").String(); password != "p1" {
+		t.Errorf("expected the value intact, but got %s", password)
+	}
+	if origins, _ := c.Explain("db.Password"); len(origins) != 1 || origins[0].Value != "***" {
+		t.Errorf("expected the origin value masked, but got %v", origins)
+	}
+	origins, _ := c.Explain("db")
+	if db := origins[0].Value.(map[string]interface{}); db["dsn"] != "***" || db["pool"] == "***" {
+		t.Errorf("expected the nested secrets masked, but got %v", db)
+	}
+}
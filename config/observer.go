@@ -0,0 +1,110 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+)
+
+type observer struct {
+	fn   Observer
+	last interface{}
+}
+
+type event struct {
+	key     string
+	value   interface{}
+	removed bool
+	o       *observer
+}
+
+// removed is the last value of the observers of the keys removed.
+type removed struct{}
+
+// observers are the observers of the keys, which are called on the goroutine of dispatch
+// in the order of the changes, so that a slow observer never blocks the reloads.
+type observers struct {
+	mu     sync.Mutex
+	keys   map[string]*observer
+	queue  []event
+	signal chan struct{}
+}
+
+func newObservers() *observers {
+	return &observers{keys: make(map[string]*observer), signal: make(chan struct{}, 1)}
+}
+
+func (s *observers) add(key string, fn Observer, value interface{}) {
+	s.mu.Lock()
+	s.keys[key] = &observer{fn: fn, last: value}
+	s.mu.Unlock()
+}
+
+func (s *observers) remove(key string) {
+	s.mu.Lock()
+	delete(s.keys, key)
+	s.mu.Unlock()
+}
+
+// notify queues the calls of the observers whose values are changed in the snapshot.
+func (s *observers) notify(values map[string]interface{}) {
+	s.mu.Lock()
+	for key, o := range s.keys {
+		v, ok := lookup(values, key)
+		if !ok {
+			// the observer is notified once of the key removed.
+			v = removed{}
+		}
+		if reflect.DeepEqual(v, o.last) {
+			continue
+		}
+		o.last = v
+		s.queue = append(s.queue, event{key: key, value: v, removed: !ok, o: o})
+	}
+	s.mu.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next queued call whose observer is still watching.
+func (s *observers) next() (event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) > 0 {
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		if s.keys[e.key] == e.o {
+			return e, true
+		}
+	}
+	return event{}, false
+}
+
+// dispatch calls the observers with the values wrapped by value until done, the values of
+// the keys removed fail with ErrNotFound.
+func (s *observers) dispatch(done <-chan struct{}, value func(interface{}) Value) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.signal:
+		}
+		for {
+			e, ok := s.next()
+			if !ok {
+				break
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if e.removed {
+				e.o.fn(e.key, errValue{err: ErrNotFound})
+				continue
+			}
+			e.o.fn(e.key, value(e.value))
+		}
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"github.com/go-kratos/kratos/v2/log"
 )
 
-// Decoder is config decoder.
-type Decoder func(*KeyValue, interface{}) error
+// Decoder is config decoder, which decodes the value of the key-value into the target map.
+type Decoder func(*KeyValue, map[string]interface{}) error
 
 // Option is config option.
 type Option func(*options)
@@ -14,6 +14,13 @@ type options struct {
 	sources []Source
 	decoder Decoder
 	logger  log.Logger
+
+	errorHandler func(error)
+	strict       bool
+	reverse      bool
+	defaults     interface{}
+	validator    func(interface{}) error
+	mask         []string
 }
 
 // WithSource with config source.
@@ -23,16 +30,68 @@ func WithSource(s ...Source) Option {
 	}
 }
 
-// WithDecoder with config decoder.
+// WithDecoder with config decoder, which decodes the values by the codecs of their formats
+// by default, see defaultDecoder.
 func WithDecoder(d Decoder) Option {
 	return func(o *options) {
 		o.decoder = d
 	}
 }
 
-// WithLogger with config loogger.
+// WithLogger with config logger, by default the global logger, see log.SetLogger.
 func WithLogger(l log.Logger) Option {
 	return func(o *options) {
 		o.logger = l
 	}
 }
+
+// WithErrorHandler with the handler of the errors of the reloads, i.e., a file fails to
+// parse, in which case the previous snapshot is kept rather than applied partially.
+func WithErrorHandler(h func(error)) Option {
+	return func(o *options) {
+		o.errorHandler = h
+	}
+}
+
+// WithStrict with whether the scans reject the unknown keys, which are typos of the fields
+// mostly, ignored by default.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithReversePriority with whether the earlier sources win, i.e., the defaults go last.
+func WithReversePriority(reverse bool) Option {
+	return func(o *options) {
+		o.reverse = reverse
+	}
+}
+
+// WithDefaults with the defaults of the lowest priority, a map, a struct or a proto message,
+// i.e., the bootstrap message, whose type the snapshots are validated as, see WithValidator.
+func WithDefaults(v interface{}) Option {
+	return func(o *options) {
+		o.defaults = v
+	}
+}
+
+// WithValidator with the validator of the snapshots after every load and reload, which is
+// called with the snapshot scanned into a new value of the type of the defaults if they are
+// a struct, whose Validate is called as well, i.e., of the proto-gen-validate, otherwise
+// the merged tree. A snapshot failing the validation is never applied.
+func WithValidator(fn func(v interface{}) error) Option {
+	return func(o *options) {
+		o.validator = fn
+	}
+}
+
+// WithMask with the patterns of the secret keys in addition to *password*, *secret* and
+// *token*, the glob patterns or the exact keys, i.e., dsn, which are matched against the
+// last segment of the dot paths case-insensitively. The values of the secret keys are
+// masked in String, Dump and Explain, but never in Value and Scan.
+func WithMask(patterns ...string) Option {
+	return func(o *options) {
+		o.mask = append(o.mask, patterns...)
+	}
+}
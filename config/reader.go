@@ -3,51 +3,343 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/imdario/mergo"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 // Reader is config reader.
 type Reader interface {
-	Merge(...*KeyValue) error
 	Value(string) (Value, bool)
 	Source() ([]byte, error)
+	Explain(string) ([]Origin, error)
 }
 
+// Origin is the origin of a value, the source and the key of the key-value it is merged from.
+type Origin struct {
+	Source string
+	Key    string
+	// Value is the value of the dot path in the key-value, with the secrets masked.
+	Value interface{}
+}
+
+func (o Origin) String() string {
+	return o.Source + "/" + o.Key
+}
+
+// layer is the key-values of a source, the latest one of each key, sorted by the keys
+// so that the merge order of the overlapping keys never depends on when they are seen.
+type layer struct {
+	name  string
+	kvs   []*KeyValue
+	trees []map[string]interface{}
+}
+
+// with returns a copy of the layer with the key-values replaced by their keys, and the
+// ones of the nil values removed.
+func (l *layer) with(kvs []*KeyValue, trees []map[string]interface{}) *layer {
+	next := &layer{
+		name:  l.name,
+		kvs:   append([]*KeyValue(nil), l.kvs...),
+		trees: append([]map[string]interface{}(nil), l.trees...),
+	}
+next:
+	for i, kv := range kvs {
+		for j, old := range next.kvs {
+			if old.Key != kv.Key {
+				continue
+			}
+			if kv.Value == nil {
+				next.kvs = append(next.kvs[:j], next.kvs[j+1:]...)
+				next.trees = append(next.trees[:j], next.trees[j+1:]...)
+			} else {
+				next.kvs[j], next.trees[j] = kv, trees[i]
+			}
+			continue next
+		}
+		if kv.Value != nil {
+			next.kvs = append(next.kvs, kv)
+			next.trees = append(next.trees, trees[i])
+		}
+	}
+	sort.Stable(next)
+	return next
+}
+
+func (l *layer) Len() int           { return len(l.kvs) }
+func (l *layer) Less(i, j int) bool { return l.kvs[i].Key < l.kvs[j].Key }
+func (l *layer) Swap(i, j int) {
+	l.kvs[i], l.kvs[j] = l.kvs[j], l.kvs[i]
+	l.trees[i], l.trees[j] = l.trees[j], l.trees[i]
+}
+
+// reader holds the merged tree as an immutable snapshot, which is swapped atomically by
+// the merges, so that the reads never see a half-merged tree. The snapshot is rebuilt from
+// the layers of the sources in the order of the priority on each merge, and expanded.
 type reader struct {
 	opts   options
-	values map[string]interface{}
+	mask   masker
+	mu     sync.Mutex // serializes the merges
+	layers []*layer
+	values atomic.Value
+
+	// defaults is the tree of the defaults, of the lowest priority.
+	defaults    map[string]interface{}
+	defaultsErr error
+	// bootstrap is the struct type of the defaults, which the snapshots are validated as.
+	bootstrap reflect.Type
 }
 
-func newReader(opts options) Reader {
-	return &reader{
-		opts:   opts,
-		values: make(map[string]interface{}),
+func newReader(opts options) *reader {
+	r := &reader{opts: opts, mask: newMasker(opts.mask)}
+	for _, src := range opts.sources {
+		r.layers = append(r.layers, &layer{name: sourceName(src)})
+	}
+	if opts.defaults != nil {
+		r.defaults, r.defaultsErr = toMap(opts.defaults)
+		t := reflect.TypeOf(opts.defaults)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			r.bootstrap = t
+		}
 	}
+	r.values.Store(make(map[string]interface{}))
+	return r
 }
 
-func (r *reader) Merge(kvs ...*KeyValue) error {
-	merged, err := cloneMap(r.values)
+// toMap returns the tree of a map, a struct or a proto message.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return clone(m).(map[string]interface{}), nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if m, ok := v.(proto.Message); ok {
+		data, err = protojson.Marshal(m)
+	} else {
+		data, err = json.Marshal(v)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var res map[string]interface{}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("config: the defaults are not an object: %w", err)
 	}
-	for _, kv := range kvs {
-		var next map[string]interface{}
-		if err := r.opts.decoder(kv, &next); err != nil {
-			return err
+	return res, nil
+}
+
+// validate validates the snapshot scanned into the bootstrap type, by its Validate, i.e.,
+// of the proto-gen-validate, and the validator, or the tree unless the defaults are a struct.
+func (r *reader) validate(values map[string]interface{}) error {
+	if r.bootstrap == nil {
+		if r.opts.validator == nil {
+			return nil
 		}
-		if err := mergo.Map(&merged, convertMap(next), mergo.WithOverride); err != nil {
-			return err
+		return r.opts.validator(values)
+	}
+	v := reflect.New(r.bootstrap).Interface()
+	if err := scan(values, v, r.opts.strict); err != nil {
+		return fmt.Errorf("config: failed to scan %s: %w", r.bootstrap, err)
+	}
+	if vv, ok := v.(interface{ Validate() error }); ok {
+		if err := vv.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	if r.opts.validator != nil {
+		if err := r.opts.validator(v); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
 		}
 	}
-	r.values = merged
 	return nil
 }
 
+// sourceName returns the name of the source by its String, or its type.
+func sourceName(src Source) string {
+	if s, ok := src.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", src)
+}
+
+func (r *reader) load() map[string]interface{} {
+	return r.values.Load().(map[string]interface{})
+}
+
+// merge replaces the key-values of the sources by their indexes, and swaps in the new
+// snapshot only if all of them are decoded, merged, expanded and validated, otherwise the
+// previous snapshot is kept.
+func (r *reader) merge(updates map[int][]*KeyValue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	layers := append([]*layer(nil), r.layers...)
+	for i, kvs := range updates {
+		trees := make([]map[string]interface{}, 0, len(kvs))
+		for _, kv := range kvs {
+			if kv.Value == nil {
+				trees = append(trees, nil)
+				continue
+			}
+			tree := make(map[string]interface{})
+			if err := r.opts.decoder(kv, tree); err != nil {
+				return fmt.Errorf("config: failed to decode %s of %s: %w", kv.Key, layers[i].name, err)
+			}
+			trees = append(trees, convertMap(tree).(map[string]interface{}))
+		}
+		layers[i] = layers[i].with(kvs, trees)
+	}
+	if r.defaultsErr != nil {
+		return r.defaultsErr
+	}
+	m := &merger{values: make(map[string]interface{}), origins: make(map[string]Origin)}
+	if err := m.merge(m.values, r.defaults, "", Origin{Source: "defaults"}); err != nil {
+		return err
+	}
+	for _, l := range r.ordered(layers) {
+		for i, tree := range l.trees {
+			if err := m.merge(m.values, tree, "", Origin{Source: l.name, Key: l.kvs[i].Key}); err != nil {
+				return err
+			}
+		}
+	}
+	values, err := expand(m.values, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	if err = r.validate(values); err != nil {
+		return err
+	}
+	r.layers = layers
+	r.values.Store(values)
+	return nil
+}
+
+// ordered returns the layers from the lowest priority to the highest.
+func (r *reader) ordered(layers []*layer) []*layer {
+	if !r.opts.reverse {
+		return layers
+	}
+	res := make([]*layer, 0, len(layers))
+	for i := len(layers) - 1; i >= 0; i-- {
+		res = append(res, layers[i])
+	}
+	return res
+}
+
 func (r *reader) Value(path string) (Value, bool) {
+	v, ok := lookup(r.load(), path)
+	if !ok {
+		return nil, false
+	}
+	return newStrictValue(v, r.opts.strict), true
+}
+
+func (r *reader) Source() ([]byte, error) {
+	return json.Marshal(r.load())
+}
+
+// redacted returns the current snapshot with the secrets masked.
+func (r *reader) redacted() map[string]interface{} {
+	return r.mask.mask(r.load()).(map[string]interface{})
+}
+
+// Explain returns the origins of the dot path from the lowest priority to the highest,
+// the last one wins if it is a scalar or an array, otherwise the maps are merged. The
+// values of the origins are masked, see WithMask.
+func (r *reader) Explain(path string) ([]Origin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []Origin
+	if v, ok := lookup(r.defaults, path); ok {
+		res = append(res, Origin{Source: "defaults", Value: r.mask.maskPath(path, v)})
+	}
+	for _, l := range r.ordered(r.layers) {
+		for i, tree := range l.trees {
+			if v, ok := lookup(tree, path); ok {
+				res = append(res, Origin{Source: l.name, Key: l.kvs[i].Key, Value: r.mask.maskPath(path, v)})
+			}
+		}
+	}
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// merger merges the trees deeply, the maps are merged recursively, while the scalars and
+// the arrays are replaced wholesale. A map conflicts with a scalar or an array.
+type merger struct {
+	values  map[string]interface{}
+	origins map[string]Origin
+}
+
+func (m *merger) merge(dst, src map[string]interface{}, path string, origin Origin) error {
+	for key, value := range src {
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		old, ok := dst[key]
+		if !ok || old == nil || value == nil {
+			dst[key] = clone(value)
+			m.origins[p] = origin
+			continue
+		}
+		om, oldIsMap := old.(map[string]interface{})
+		vm, isMap := value.(map[string]interface{})
+		switch {
+		case oldIsMap && isMap:
+			if err := m.merge(om, vm, p, origin); err != nil {
+				return err
+			}
+		case oldIsMap != isMap:
+			return fmt.Errorf("config: type conflict at %s: %s in %s, but %s in %s",
+				p, kind(old), m.origins[p], kind(value), origin)
+		default:
+			dst[key] = clone(value)
+			m.origins[p] = origin
+		}
+	}
+	return nil
+}
+
+func kind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "array"
+	}
+	return "scalar"
+}
+
+// clone returns a deep copy of the maps, so that the trees of the layers are never changed.
+func clone(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		res := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			res[key] = clone(value)
+		}
+		return res
+	}
+	return v
+}
+
+// lookup returns the value of the dot path in the tree.
+func lookup(values map[string]interface{}, path string) (interface{}, bool) {
 	var (
-		next = r.values
+		next = values
 		keys = strings.Split(path, ".")
 		last = len(keys) - 1
 	)
@@ -57,9 +349,7 @@ func (r *reader) Value(path string) (Value, bool) {
 			return nil, false
 		}
 		if idx == last {
-			av := &atomicValue{}
-			av.Store(value)
-			return av, true
+			return value, true
 		}
 		switch vm := value.(type) {
 		case map[string]interface{}:
@@ -71,22 +361,6 @@ func (r *reader) Value(path string) (Value, bool) {
 	return nil, false
 }
 
-func (r *reader) Source() ([]byte, error) {
-	return json.Marshal(r.values)
-}
-
-func cloneMap(src map[string]interface{}) (map[string]interface{}, error) {
-	data, err := json.Marshal(src)
-	if err != nil {
-		return nil, err
-	}
-	dst := make(map[string]interface{})
-	if err = json.Unmarshal(data, &dst); err != nil {
-		return nil, err
-	}
-	return dst, nil
-}
-
 func convertMap(src interface{}) interface{} {
 	switch m := src.(type) {
 	case map[string]interface{}:
@@ -101,6 +375,12 @@ func convertMap(src interface{}) interface{} {
 			dst[fmt.Sprint(k)] = convertMap(v)
 		}
 		return dst
+	case []interface{}:
+		dst := make([]interface{}, len(m))
+		for i, v := range m {
+			dst[i] = convertMap(v)
+		}
+		return dst
 	default:
 		return src
 	}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type namedSource struct {
+	*testSource
+	name string
+}
+
+func (s namedSource) String() string { return s.name }
+
+func TestMergePriority(t *testing.T) {
+	defaults := namedSource{newTestSource(`{"server": {"addr": ":8000", "timeout": "1s"}, "hosts": ["a", "b"]}`), "defaults"}
+	file := namedSource{newTestSource(`{"server": {"addr": ":8080"}, "hosts": ["c"]}`), "file"}
+	c := New(WithSource(defaults, file))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var conf struct {
+		Server struct{ Addr, Timeout string }
+		Hosts  []string
+	}
+	if err := c.Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server.Addr != ":8080" || conf.Server.Timeout != "1s" || !reflect.DeepEqual(conf.Hosts, []string{"c"}) {
+		t.Errorf("expected the maps merged and the arrays replaced, but got %+v", conf)
+	}
+	origins, err := c.Explain("server.addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Origin{{Source: "defaults", Key: "test", Value: ":8000"}, {Source: "file", Key: "test", Value: ":8080"}}; !reflect.DeepEqual(origins, want) {
+		t.Errorf("expected %v, but got %v", want, origins)
+	}
+	if origins, _ := c.Explain("server.timeout"); len(origins) != 1 || origins[0].Source != "defaults" {
+		t.Errorf("expected the defaults, but got %v", origins)
+	}
+	if _, err := c.Explain("server.port"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, but got %v", err)
+	}
+
+	// a change of the lower source never overrides the higher one.
+	defaults.set(`{"server": {"addr": ":9000", "timeout": "2s"}}`)
+	ch := make(chan string, 1)
+	c.Watch("server.timeout", func(_ string, v Value) {
+		timeout, _ := v.String()
+		ch <- timeout
+	})
+	if timeout := <-ch; timeout != "2s" {
+		t.Errorf("expected the changed timeout, but got %s", timeout)
+	}
+	if addr, _ := c.Value("server.addr").String(); addr != ":8080" {
+		t.Errorf("expected the addr of the file kept, but got %s", addr)
+	}
+
+	reversed := New(WithSource(defaults, file), WithReversePriority(true))
+	if err := reversed.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer reversed.Close()
+	if addr, _ := reversed.Value("server.addr").String(); addr != ":9000" {
+		t.Errorf("expected the addr of the first source, but got %s", addr)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	c := New(WithSource(
+		namedSource{newTestSource(`{"server": {"addr": ":8000"}}`), "defaults"},
+		namedSource{newTestSource(`{"server": ":8080"}`), "env"},
+	))
+	err := c.Load()
+	if err == nil || !strings.Contains(err.Error(), "type conflict at server: map in defaults/test, but scalar in env/test") {
+		t.Errorf("expected the type conflict, but got %v", err)
+	}
+}
+
+func TestLayerOrder(t *testing.T) {
+	tree := func(v string) map[string]interface{} { return map[string]interface{}{"addr": v} }
+	l := (&layer{name: "file"}).with(
+		[]*KeyValue{{Key: "b.yaml", Value: []byte("b")}, {Key: "c.yaml", Value: []byte("c")}},
+		[]map[string]interface{}{tree("b"), tree("c")},
+	)
+	// the key first seen on the reload is merged by its order rather than last.
+	l = l.with([]*KeyValue{{Key: "a.yaml", Value: []byte("a")}, {Key: "c.yaml"}}, []map[string]interface{}{tree("a"), nil})
+	keys := make([]string, 0, len(l.kvs))
+	for i, kv := range l.kvs {
+		keys = append(keys, kv.Key)
+		if l.trees[i]["addr"] != kv.Key[:1] {
+			t.Errorf("expected the tree of %s, but got %v", kv.Key, l.trees[i])
+		}
+	}
+	if want := []string{"a.yaml", "b.yaml"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, but got %v", want, keys)
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// scan scans the value into v through json, applying the defaults of the struct fields.
+func scan(value interface{}, v interface{}, strict bool) error {
+	if m, ok := v.(proto.Message); ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return protojson.UnmarshalOptions{DiscardUnknown: !strict}.Unmarshal(data, m)
+	}
+	value, err := withDefaults(reflect.TypeOf(v), value)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// withDefaults returns a copy of the value with the default tags of the type applied for
+// the missing keys, the value is nil if it is missing, which is kept nil if no defaults.
+func withDefaults(t reflect.Type, value interface{}) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok && value != nil {
+			return value, nil
+		}
+		res := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			res[key] = val
+		}
+		if err := structDefaults(t, res); err != nil {
+			return nil, err
+		}
+		if value == nil && len(res) == 0 {
+			return nil, nil
+		}
+		return res, nil
+	case reflect.Slice, reflect.Array:
+		vals, ok := value.([]interface{})
+		if !ok {
+			if value == nil || t.Elem().Kind() == reflect.Uint8 {
+				return value, nil
+			}
+			// a single value of the repeated flags.
+			vals = []interface{}{value}
+		}
+		res := make([]interface{}, len(vals))
+		for i, val := range vals {
+			var err error
+			if res[i], err = withDefaults(t.Elem(), val); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		res := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			var err error
+			if res[key], err = withDefaults(t.Elem(), val); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	if s, ok := value.(string); ok {
+		return coerce(t, s), nil
+	}
+	return value, nil
+}
+
+// coerce parses the string into the kind of the type, i.e., the values of the env and the
+// flag sources, or returns it as is if it fails.
+func coerce(t reflect.Type, s string) interface{} {
+	if t == durationType {
+		if d, err := time.ParseDuration(s); err == nil {
+			return int64(d)
+		}
+	}
+	var (
+		v   interface{}
+		err error
+	)
+	switch t.Kind() {
+	case reflect.Bool:
+		v, err = strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err = strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		v, err = strconv.ParseFloat(s, 64)
+	default:
+		return s
+	}
+	if err != nil {
+		return s
+	}
+	return v
+}
+
+// structDefaults applies the defaults of the fields of the struct to m, whose keys are
+// matched by the json names of the fields case-insensitively, as encoding/json does.
+func structDefaults(t reflect.Type, m map[string]interface{}) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, named := jsonName(f)
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && !named {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := structDefaults(ft, m); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if key, ok := findKey(m, name); ok {
+			val, err := withDefaults(f.Type, m[key])
+			if err != nil {
+				return err
+			}
+			m[key] = val
+			continue
+		}
+		if def, ok := f.Tag.Lookup("default"); ok {
+			val, err := defaultValue(f.Type, def)
+			if err != nil {
+				return fmt.Errorf("config: invalid default of %s.%s: %w", t.Name(), f.Name, err)
+			}
+			m[name] = val
+			continue
+		}
+		val, err := withDefaults(f.Type, nil)
+		if err != nil {
+			return err
+		}
+		if val != nil {
+			m[name] = val
+		}
+	}
+	return nil
+}
+
+// defaultValue returns the json value of the default tag.
+func defaultValue(t reflect.Type, def string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d), nil
+	}
+	if t.Kind() == reflect.String {
+		return def, nil
+	}
+	if !json.Valid([]byte(def)) {
+		return nil, fmt.Errorf("not a json literal: %q", def)
+	}
+	return json.RawMessage(def), nil
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		return f.Name, false
+	}
+	return tag, true
+}
+
+func findKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
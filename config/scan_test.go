@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type httpConf struct {
+	Addr    string        `json:"addr" default:":8000"`
+	Timeout time.Duration `json:"timeout" default:"1s"`
+	Methods []string      `json:"methods" default:"[\"GET\"]"`
+}
+
+type testConf struct {
+	Server struct {
+		HTTP httpConf  `json:"http"`
+		GRPC *httpConf `json:"grpc"`
+	} `json:"server"`
+	Workers  int                 `json:"workers" default:"4"`
+	Debug    bool                `json:"debug" default:"true"`
+	Backends []httpConf          `json:"backends"`
+	Caches   map[string]httpConf `json:"caches"`
+}
+
+func newTestConfig(t *testing.T, data string, opts ...Option) Config {
+	c := New(append(opts, WithSource(newTestSource(data)))...)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestScanDefaults(t *testing.T) {
+	c := newTestConfig(t, `{
+		"server": {"http": {"addr": ":8080"}},
+		"workers": 0,
+		"backends": [{"addr": ":9000"}],
+		"caches": {"redis": {"timeout": 2000000000}}
+	}`)
+	defer c.Close()
+	var conf testConf
+	if err := c.Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if http := conf.Server.HTTP; http.Addr != ":8080" || http.Timeout != time.Second || len(http.Methods) != 1 {
+		t.Errorf("expected the defaults of the missing keys, but got %+v", http)
+	}
+	if conf.Server.GRPC == nil || conf.Server.GRPC.Addr != ":8000" {
+		t.Errorf("expected the defaults of the missing struct, but got %+v", conf.Server.GRPC)
+	}
+	if conf.Workers != 0 || !conf.Debug {
+		t.Errorf("expected the present keys kept, but got %d %v", conf.Workers, conf.Debug)
+	}
+	if conf.Backends[0].Addr != ":9000" || conf.Backends[0].Timeout != time.Second {
+		t.Errorf("expected the defaults of the elements, but got %+v", conf.Backends)
+	}
+	if redis := conf.Caches["redis"]; redis.Addr != ":8000" || redis.Timeout != 2*time.Second {
+		t.Errorf("expected the defaults of the map values, but got %+v", redis)
+	}
+	var http httpConf
+	if err := c.Value("server.http").Scan(&http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Addr != ":8080" || http.Timeout != time.Second {
+		t.Errorf("expected the partial scan, but got %+v", http)
+	}
+}
+
+func TestScanStrict(t *testing.T) {
+	data := `{"server": {"http": {"addrr": ":8080"}}, "timeout": "5s"}`
+	c := newTestConfig(t, data)
+	defer c.Close()
+	var conf testConf
+	if err := c.Scan(&conf); err != nil {
+		t.Errorf("expected the unknown keys ignored, but got %v", err)
+	}
+	strict := newTestConfig(t, data, WithStrict(true))
+	defer strict.Close()
+	if err := strict.Value("server.http").Scan(&httpConf{}); err == nil || !strings.Contains(err.Error(), "addrr") {
+		t.Errorf("expected the unknown key rejected, but got %v", err)
+	}
+	var d durationpb.Duration
+	if err := strict.Value("timeout").Scan(&d); err != nil || d.AsDuration() != 5*time.Second {
+		t.Errorf("expected the proto duration, but got %v %v", d.AsDuration(), err)
+	}
+}
+
+func TestScanInvalidDefault(t *testing.T) {
+	c := newTestConfig(t, `{}`)
+	defer c.Close()
+	var conf struct {
+		Port int `json:"port" default:"eighty"`
+	}
+	if err := c.Scan(&conf); err == nil {
+		t.Error("expected the invalid default rejected")
+	}
+}
+
+func TestScanStrings(t *testing.T) {
+	c := newTestConfig(t, `{"port": "8080", "debug": "true", "timeout": "5s", "ratio": "0.5", "hosts": "a", "name": "8080"}`)
+	defer c.Close()
+	var conf struct {
+		Port    int
+		Debug   bool
+		Timeout time.Duration
+		Ratio   float64
+		Hosts   []string
+		Name    string
+	}
+	if err := c.Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Port != 8080 || !conf.Debug || conf.Timeout != 5*time.Second || conf.Ratio != 0.5 || len(conf.Hosts) != 1 || conf.Name != "8080" {
+		t.Errorf("expected the strings parsed, but got %+v", conf)
+	}
+}
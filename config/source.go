@@ -4,8 +4,10 @@ import "time"
 
 // KeyValue is config key value.
 type KeyValue struct {
-	Key       string
-	Value     []byte
+	Key   string
+	Value []byte
+	// Format is the codec name of the value, i.e., json or yaml.
+	Format    string
 	Metadata  map[string]string
 	Timestamp time.Time
 }
@@ -18,6 +20,9 @@ type Source interface {
 
 // Watcher watches a source for changes.
 type Watcher interface {
+	// Next returns the changed key-values, which replace the previous ones of the same
+	// keys, the others of the source are kept. A key-value of the nil value removes the
+	// previous one of the key, i.e., the key is deleted from the backend.
 	Next() ([]*KeyValue, error)
 	Close() error
 }
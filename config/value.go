@@ -7,9 +7,6 @@ import (
 	"strconv"
 	"sync/atomic"
 	"time"
-
-	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/proto"
 )
 
 var (
@@ -17,78 +14,177 @@ var (
 	_ Value = (*errValue)(nil)
 )
 
-// Value is config value interface.
+// Value is config value interface, the typed getters coerce the value into the type, i.e.,
+// the strings of the env sources are parsed, and fail if it is not convertible.
 type Value interface {
 	Bool() (bool, error)
 	Int() (int64, error)
 	Float() (float64, error)
 	String() (string, error)
+	// Duration returns the duration of a string like 5s, or of an integer of nanoseconds.
 	Duration() (time.Duration, error)
+	// Slice returns the values of an array.
+	Slice() ([]Value, error)
+	// Map returns the values of an object by their keys.
+	Map() (map[string]Value, error)
 	Scan(interface{}) error
 	Load() interface{}
 	Store(interface{})
 }
 
+func newValue(v interface{}) Value {
+	return newStrictValue(v, false)
+}
+
+// newStrictValue returns a value whose Scan rejects the unknown keys if strict.
+func newStrictValue(v interface{}, strict bool) Value {
+	av := &atomicValue{strict: strict}
+	av.Store(v)
+	return av
+}
+
 type atomicValue struct {
-	atomic.Value
+	v      atomic.Value
+	strict bool
+}
+
+// holder holds the value, so that the nil values, i.e., a null of json, are stored.
+type holder struct {
+	v interface{}
+}
+
+func (v *atomicValue) Load() interface{} {
+	h, _ := v.v.Load().(holder)
+	return h.v
+}
+
+func (v *atomicValue) Store(val interface{}) {
+	v.v.Store(holder{v: val})
+}
+
+func (v *atomicValue) typeError() error {
+	return fmt.Errorf("%w: %v", ErrTypeAssert, reflect.TypeOf(v.Load()))
 }
 
 func (v *atomicValue) Bool() (bool, error) {
 	switch val := v.Load().(type) {
 	case bool:
 		return val, nil
-	case int64, float64, string:
-		return strconv.ParseBool(fmt.Sprint(val))
+	case string:
+		return strconv.ParseBool(val)
+	}
+	if n, ok := number(v.Load()); ok {
+		return n != 0, nil
 	}
-	return false, fmt.Errorf("type assert to %v failed", reflect.TypeOf(v.Load()))
+	return false, v.typeError()
 }
 func (v *atomicValue) Int() (int64, error) {
-	switch val := v.Load().(type) {
-	case int64:
-		return int64(val), nil
-	case float64:
-		return int64(val), nil
-	case string:
-		return strconv.ParseInt(val, 10, 64)
+	if s, ok := v.Load().(string); ok {
+		return strconv.ParseInt(s, 10, 64)
 	}
-	return 0, fmt.Errorf("type assert to %v failed", reflect.TypeOf(v.Load()))
+	if n, ok := integer(v.Load()); ok {
+		return n, nil
+	}
+	return 0, v.typeError()
 }
 func (v *atomicValue) Float() (float64, error) {
-	switch val := v.Load().(type) {
-	case float64:
-		return float64(val), nil
-	case int64:
-		return float64(val), nil
-	case string:
-		return strconv.ParseFloat(val, 10)
+	if s, ok := v.Load().(string); ok {
+		return strconv.ParseFloat(s, 64)
+	}
+	if n, ok := number(v.Load()); ok {
+		return n, nil
 	}
-	return 0.0, fmt.Errorf("type assert to %v failed", reflect.TypeOf(v.Load()))
+	return 0.0, v.typeError()
 }
 func (v *atomicValue) String() (string, error) {
 	switch val := v.Load().(type) {
 	case string:
 		return val, nil
-	case bool, int64, float64:
-		return fmt.Sprint(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
 	}
-	return "", fmt.Errorf("type assert to %v failed", reflect.TypeOf(v.Load()))
+	if _, ok := number(v.Load()); ok {
+		return fmt.Sprint(v.Load()), nil
+	}
+	return "", v.typeError()
 }
 func (v *atomicValue) Duration() (time.Duration, error) {
+	if s, ok := v.Load().(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+	}
 	val, err := v.Int()
 	if err != nil {
 		return 0, err
 	}
 	return time.Duration(val), nil
 }
+func (v *atomicValue) Slice() ([]Value, error) {
+	vals, ok := v.Load().([]interface{})
+	if !ok {
+		return nil, v.typeError()
+	}
+	res := make([]Value, 0, len(vals))
+	for _, val := range vals {
+		res = append(res, newStrictValue(val, v.strict))
+	}
+	return res, nil
+}
+func (v *atomicValue) Map() (map[string]Value, error) {
+	vals, ok := v.Load().(map[string]interface{})
+	if !ok {
+		return nil, v.typeError()
+	}
+	res := make(map[string]Value, len(vals))
+	for key, val := range vals {
+		res[key] = newStrictValue(val, v.strict)
+	}
+	return res, nil
+}
 func (v *atomicValue) Scan(obj interface{}) error {
-	data, err := json.Marshal(v.Load())
-	if err != nil {
-		return err
+	return scan(v.Load(), obj, v.strict)
+}
+
+// number returns the float of the numeric value, i.e., the integers decoded by yaml.
+func number(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case json.Number:
+		n, err := val.Float64()
+		return n, err == nil
+	case nil, bool, string:
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// integer returns the integer of the numeric value, which is exact for the integer kinds.
+func integer(v interface{}) (int64, bool) {
+	if val, ok := v.(json.Number); ok {
+		if n, err := val.Int64(); err == nil {
+			return n, true
+		}
 	}
-	if pb, ok := obj.(proto.Message); ok {
-		return protojson.Unmarshal(data, pb)
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
 	}
-	return json.Unmarshal(data, obj)
+	n, ok := number(v)
+	return int64(n), ok
 }
 
 type errValue struct {
@@ -100,6 +196,8 @@ func (v errValue) Int() (int64, error)              { return 0, v.err }
 func (v errValue) Float() (float64, error)          { return 0.0, v.err }
 func (v errValue) Duration() (time.Duration, error) { return 0, v.err }
 func (v errValue) String() (string, error)          { return "", v.err }
+func (v errValue) Slice() ([]Value, error)          { return nil, v.err }
+func (v errValue) Map() (map[string]Value, error)   { return nil, v.err }
 func (v errValue) Scan(interface{}) error           { return v.err }
 func (v errValue) Load() interface{}                { return nil }
 func (v errValue) Store(interface{})                {}
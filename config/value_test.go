@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAtomicValue(t *testing.T) {
+	for _, v := range []interface{}{int(8000), int32(8000), uint64(8000), float64(8000), json.Number("8000"), "8000"} {
+		i, err := newValue(v).Int()
+		if err != nil || i != 8000 {
+			t.Errorf("Int(%T) = %v, %v", v, i, err)
+		}
+		s, err := newValue(v).String()
+		if err != nil || s != "8000" {
+			t.Errorf("String(%T) = %v, %v", v, s, err)
+		}
+	}
+	if d, err := newValue("5s").Duration(); err != nil || d != 5*time.Second {
+		t.Errorf("Duration(5s) = %v, %v", d, err)
+	}
+	if d, err := newValue(int64(time.Second)).Duration(); err != nil || d != time.Second {
+		t.Errorf("Duration(1e9) = %v, %v", d, err)
+	}
+	if _, err := newValue([]interface{}{1}).Int(); !errors.Is(err, ErrTypeAssert) {
+		t.Errorf("Int([]) error = %v", err)
+	}
+	vals, err := newValue([]interface{}{"a", 1}).Slice()
+	if err != nil || len(vals) != 2 {
+		t.Fatalf("Slice() = %v, %v", vals, err)
+	}
+	if s, _ := vals[0].String(); s != "a" {
+		t.Errorf("Slice()[0] = %v", s)
+	}
+	m, err := newValue(map[string]interface{}{"port": 80}).Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := m["port"].Int(); p != 80 {
+		t.Errorf("Map()[port] = %v", p)
+	}
+	// the integers above 2^53 are exact.
+	for _, v := range []interface{}{int64(1<<53 + 1), uint64(1<<53 + 1), json.Number("9007199254740993")} {
+		if i, err := newValue(v).Int(); err != nil || i != 1<<53+1 {
+			t.Errorf("Int(%T) = %v, %v", v, i, err)
+		}
+	}
+}
+
+func TestNullValue(t *testing.T) {
+	s := newTestSource(`{"a": null, "b": {"c": null}}`)
+	c := New(WithSource(s))
+	defer c.Close()
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	v := c.Value("a")
+	if v.Load() != nil {
+		t.Errorf("expected the null value, but got %v", v.Load())
+	}
+	if _, err := v.Int(); !errors.Is(err, ErrTypeAssert) {
+		t.Errorf("expected ErrTypeAssert of the null value, but got %v", err)
+	}
+	if m, err := c.Value("b").Map(); err != nil || m["c"].Load() != nil {
+		t.Errorf("expected the null value in the map, but got %v %v", m, err)
+	}
+}
@@ -0,0 +1,45 @@
+package kratos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/appinfo"
+)
+
+// AppInfo is the identity of the application, which is injected into the contexts of the
+// hooks, the servers and the requests.
+type AppInfo = appinfo.Info
+
+// NewContext returns a new Context that carries value.
+func NewContext(ctx context.Context, s AppInfo) context.Context {
+	return appinfo.NewContext(ctx, s)
+}
+
+// FromContext returns the AppInfo value stored in ctx, if any.
+func FromContext(ctx context.Context) (s AppInfo, ok bool) {
+	return appinfo.FromContext(ctx)
+}
+
+// defaultID returns the host name with a random suffix, so that the instances on a host
+// are distinguished.
+func defaultID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// valueContext carries the values of the parent but never its cancellation, i.e., the app
+// info in the contexts of the shutdown.
+type valueContext struct{ context.Context }
+
+func (valueContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valueContext) Done() <-chan struct{}       { return nil }
+func (valueContext) Err() error                  { return nil }
@@ -1,30 +1,75 @@
 package encoding
 
 import (
+	"errors"
+	"strings"
+	"sync"
+
 	"google.golang.org/grpc/encoding"
 )
 
+// ErrUnsupportedType is returned by the codecs for the values they cannot map, i.e., a proto message for xml.
+var ErrUnsupportedType = errors.New("encoding: unsupported type")
+
 // Codec defines the interface HTTP/gRPC uses to encode and decode messages.  Note
 // that implementations of this interface must be thread safe; a Codec's
 // methods can be called from concurrent goroutines.
-type Codec encoding.Codec
+type Codec interface {
+	// Marshal returns the wire format of v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal parses the wire format into v.
+	Unmarshal(data []byte, v interface{}) error
+	// Name returns the name of the Codec implementation, which is the
+	// content-subtype it is registered for, i.e., json.
+	Name() string
+}
 
 // Compressor is used for compressing and decompressing when sending or
 // receiving messages.
 type Compressor encoding.Compressor
 
+var (
+	mu           sync.RWMutex
+	codecs       = make(map[string]Codec)
+	defaultCodec = "json"
+)
+
+// RegisterCodec registers the provided Codec for use with all HTTP/gRPC clients and
+// servers, by the lowercase name of the codec as the content-subtype.
+func RegisterCodec(codec Codec) {
+	if codec == nil {
+		panic("cannot register a nil Codec")
+	}
+	if codec.Name() == "" {
+		panic("cannot register Codec with empty string result for Name()")
+	}
+	mu.Lock()
+	codecs[strings.ToLower(codec.Name())] = codec
+	mu.Unlock()
+	encoding.RegisterCodec(codec)
+}
+
 // GetCodec gets a registered Codec by content-subtype, or nil if no Codec is
 // registered for the content-subtype.
-//
-// The content-subtype is expected to be lowercase.
 func GetCodec(contentSubtype string) Codec {
-	return encoding.GetCodec(contentSubtype)
+	mu.RLock()
+	defer mu.RUnlock()
+	return codecs[strings.ToLower(contentSubtype)]
 }
 
-// RegisterCodec registers the provided Codec for use with all HTTP/gRPC clients and
-// servers.
-func RegisterCodec(codec Codec) {
-	encoding.RegisterCodec(codec)
+// SetDefaultCodec sets the content-subtype of the Codec that unknown content types
+// fall back to, json by default.
+func SetDefaultCodec(contentSubtype string) {
+	mu.Lock()
+	defaultCodec = strings.ToLower(contentSubtype)
+	mu.Unlock()
+}
+
+// DefaultCodec returns the Codec that unknown content types fall back to, or nil if it is not registered.
+func DefaultCodec() Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	return codecs[defaultCodec]
 }
 
 // RegisterCompressor registers the compressor with HTTP/gRPC by its name.
@@ -0,0 +1,38 @@
+package encoding
+
+import (
+	"sync"
+	"testing"
+)
+
+type testCodec struct {
+	name string
+}
+
+func (c testCodec) Marshal(v interface{}) ([]byte, error)      { return nil, nil }
+func (c testCodec) Unmarshal(data []byte, v interface{}) error { return nil }
+func (c testCodec) Name() string                               { return c.name }
+
+func TestRegisterCodec(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RegisterCodec(testCodec{"Test"})
+			GetCodec("test")
+		}()
+	}
+	wg.Wait()
+	if c := GetCodec("TEST"); c == nil || c.Name() != "Test" {
+		t.Errorf("expected the codec registered by the lowercase name, but got %v", c)
+	}
+	if GetCodec("unknown") != nil {
+		t.Errorf("expected no codec for unknown content-subtype")
+	}
+	defer SetDefaultCodec("json")
+	SetDefaultCodec("test")
+	if c := DefaultCodec(); c == nil || c.Name() != "Test" {
+		t.Errorf("expected the default codec, but got %v", c)
+	}
+}
@@ -0,0 +1,76 @@
+package form
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the name registered for the form codec, i.e., application/x-www-form-urlencoded.
+const Name = "x-www-form-urlencoded"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a Codec implementation with url encoded forms, see EncodeValues for the mapping.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	values, err := EncodeValues(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	return DecodeValues(v, values)
+}
+
+func (codec) Name() string {
+	return Name
+}
+
+// EncodeValues encodes a struct or a proto message into url values, the nested messages
+// are flattened with dotted keys and the slices are repeated values. The fields are named
+// by the json tags of the structs and the json names of the proto messages, and the
+// well-known types are their scalars, i.e., Timestamp as RFC3339.
+func EncodeValues(v interface{}) (url.Values, error) {
+	values := make(url.Values)
+	if m, ok := v.(proto.Message); ok {
+		return values, encodeMessage(values, "", m.ProtoReflect())
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return values, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form: cannot encode %T: %w", v, encoding.ErrUnsupportedType)
+	}
+	return values, encodeStruct(values, "", rv)
+}
+
+// DecodeValues decodes the url values into a struct or a proto message, both the json
+// names and the proto names of the fields are accepted, and the unknown fields are ignored.
+func DecodeValues(v interface{}, values url.Values) error {
+	if m, ok := v.(proto.Message); ok {
+		return decodeMessage(m.ProtoReflect(), values)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form: cannot decode into %T: %w", v, encoding.ErrUnsupportedType)
+	}
+	return decodeStruct(rv.Elem(), values)
+}
@@ -0,0 +1,121 @@
+package form
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+type Page struct {
+	Size  int    `json:"page_size"`
+	Token string `json:"page_token,omitempty"`
+}
+
+type filter struct {
+	Page
+	Name    string        `json:"name"`
+	Tags    []string      `json:"tags"`
+	Since   time.Time     `json:"since"`
+	Timeout time.Duration `json:"timeout"`
+	Owner   *struct {
+		ID int64 `json:"id"`
+	} `json:"owner"`
+	Ignored string `json:"-"`
+}
+
+func TestStruct(t *testing.T) {
+	in := &filter{
+		Page:    Page{Size: 10},
+		Name:    "kratos",
+		Tags:    []string{"go", "grpc"},
+		Since:   time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		Timeout: time.Second,
+	}
+	in.Owner = &struct {
+		ID int64 `json:"id"`
+	}{ID: 1}
+	values, err := EncodeValues(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"page_size":  {"10"},
+		"page_token": {""},
+		"name":       {"kratos"},
+		"tags":       {"go", "grpc"},
+		"since":      {"2021-02-01T00:00:00Z"},
+		"timeout":    {"1s"},
+		"owner.id":   {"1"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected %v, but got %v", want, values)
+	}
+	out := new(filter)
+	if err := DecodeValues(out, values); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %+v, but got %+v", in, out)
+	}
+}
+
+func TestProtoMessage(t *testing.T) {
+	tests := []struct {
+		in   proto.Message
+		out  proto.Message
+		want url.Values
+	}{
+		{
+			&apipb.Api{Name: "kratos", Syntax: typepb.Syntax_SYNTAX_PROTO3, SourceContext: &sourcecontextpb.SourceContext{FileName: "api.proto"}},
+			new(apipb.Api),
+			url.Values{"name": {"kratos"}, "syntax": {"SYNTAX_PROTO3"}, "sourceContext.fileName": {"api.proto"}},
+		},
+		{
+			&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)},
+			new(errdetails.RetryInfo),
+			url.Values{"retryDelay": {"1.5s"}},
+		},
+	}
+	for _, test := range tests {
+		values, err := EncodeValues(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, test.want) {
+			t.Errorf("expected %v, but got %v", test.want, values)
+		}
+		if err := DecodeValues(test.out, values); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(test.in, test.out) {
+			t.Errorf("expected %v, but got %v", test.in, test.out)
+		}
+	}
+	// the proto names are accepted as well.
+	out := new(apipb.Api)
+	if err := DecodeValues(out, url.Values{"source_context.file_name": {"api.proto"}}); err != nil || out.SourceContext.GetFileName() != "api.proto" {
+		t.Errorf("expected the proto names decoded, but got %v %v", out, err)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	data, err := codec{}.Marshal(&Page{Size: 20, Token: "next"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "page_size=20&page_token=next" {
+		t.Errorf("unexpected form %s", data)
+	}
+	var out Page
+	if err := (codec{}).Unmarshal(data, &out); err != nil || out.Size != 20 || out.Token != "next" {
+		t.Errorf("unexpected page %+v %v", out, err)
+	}
+}
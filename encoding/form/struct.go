@@ -0,0 +1,270 @@
+package form
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// fieldName returns the name of the struct field by its json tag, or an empty name if it is skipped.
+func fieldName(f reflect.StructField) string {
+	if f.PkgPath != "" && !f.Anonymous {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		return f.Name
+	}
+	return tag
+}
+
+func encodeStruct(values url.Values, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		fv := rv.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := encodeStruct(values, prefix, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		name := fieldName(f)
+		if name == "" {
+			continue
+		}
+		if err := encodeValue(values, prefix+name, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeValue(values url.Values, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if s, ok, err := formatScalar(v); ok || err != nil {
+		if err == nil {
+			values.Add(key, s)
+		}
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(values, key+".", v)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(key, string(v.Bytes()))
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(values, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("form: field %q of %v is not supported", key, v.Type())
+}
+
+// formatScalar formats the scalar values, it returns false for the composite values.
+func formatScalar(v reflect.Value) (string, bool, error) {
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	case durationType:
+		return time.Duration(v.Int()).String(), true, nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		data, err := m.MarshalText()
+		return string(data), true, err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), true, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true, nil
+	}
+	return "", false, nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func decodeStruct(rv reflect.Value, values url.Values) error {
+	for key, vs := range values {
+		if len(vs) == 0 {
+			continue
+		}
+		if err := decodePath(rv, strings.Split(key, "."), vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodePath sets the field of the dotted path, the unknown fields are ignored.
+func decodePath(rv reflect.Value, path []string, vs []string) error {
+	f, ok := lookupField(rv, path[0])
+	if !ok {
+		return nil
+	}
+	if len(path) == 1 {
+		return setValue(f, vs, path[0])
+	}
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		f = f.Elem()
+	}
+	if f.Kind() != reflect.Struct {
+		return fmt.Errorf("form: invalid path: %q is not a struct", path[0])
+	}
+	return decodePath(f, path[1:], vs)
+}
+
+func lookupField(rv reflect.Value, name string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			fv := rv.Field(i)
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					if !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(f.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if v, ok := lookupField(fv, name); ok {
+				return v, true
+			}
+			continue
+		}
+		if n := fieldName(f); n != "" && (n == name || strings.EqualFold(f.Name, name)) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func setValue(v reflect.Value, vs []string, key string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), []string{s}, key); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	if len(vs) > 1 {
+		return fmt.Errorf("form: too many values for field %q: %s", key, strings.Join(vs, ", "))
+	}
+	if err := parseScalar(v, vs[0]); err != nil {
+		return fmt.Errorf("form: parsing field %q: %w", key, err)
+	}
+	return nil
+}
+
+func parseScalar(v reflect.Value, s string) error {
+	switch v.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
 
 	"github.com/go-kratos/kratos/v2/encoding"
@@ -23,27 +24,85 @@ var (
 	}
 )
 
+// EmitUnpopulated with whether the unpopulated fields of the proto messages are emitted,
+// it should be called before the codec is used.
+func EmitUnpopulated(emit bool) {
+	MarshalOptions.EmitUnpopulated = emit
+}
+
+// UseProtoNames with whether the proto field names are used instead of the lowerCamelCase names,
+// it should be called before the codec is used.
+func UseProtoNames(use bool) {
+	MarshalOptions.UseProtoNames = use
+}
+
+// DiscardUnknown with whether the unknown fields of the proto messages are ignored,
+// it should be called before the codec is used.
+func DiscardUnknown(discard bool) {
+	UnmarshalOptions.DiscardUnknown = discard
+}
+
+// Options is the options of a json codec instance.
+type Options struct {
+	// EmitUnpopulated emits the unpopulated fields of the proto messages.
+	EmitUnpopulated bool
+	// UseProtoNames uses the proto field names instead of the lowerCamelCase names.
+	UseProtoNames bool
+	// DiscardUnknown ignores the unknown fields, otherwise they are rejected.
+	DiscardUnknown bool
+}
+
+// New returns a json codec instance with the options, which is independent of the
+// registered codec, i.e., pinned by the HTTP servers and clients with different settings.
+func New(opts Options) encoding.Codec {
+	return codec{
+		marshal: &protojson.MarshalOptions{
+			EmitUnpopulated: opts.EmitUnpopulated,
+			UseProtoNames:   opts.UseProtoNames,
+		},
+		unmarshal: &protojson.UnmarshalOptions{
+			DiscardUnknown: opts.DiscardUnknown,
+		},
+	}
+}
+
 func init() {
 	encoding.RegisterCodec(codec{})
 }
 
-// codec is a Codec implementation with json.
-type codec struct{}
+// codec is a Codec implementation with json, the proto messages are encoded by protojson,
+// with the package options unless it is created by New.
+type codec struct {
+	marshal   *protojson.MarshalOptions
+	unmarshal *protojson.UnmarshalOptions
+}
 
-func (codec) Marshal(v interface{}) ([]byte, error) {
+func (c codec) Marshal(v interface{}) ([]byte, error) {
 	m, ok := v.(proto.Message)
 	if ok {
+		if c.marshal != nil {
+			return c.marshal.Marshal(m)
+		}
 		return MarshalOptions.Marshal(m)
 	}
 	return json.Marshal(v)
 }
 
-func (codec) Unmarshal(data []byte, v interface{}) error {
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	opts := &UnmarshalOptions
+	if c.unmarshal != nil {
+		opts = c.unmarshal
+	}
 	m, ok := v.(proto.Message)
 	if ok {
-		return UnmarshalOptions.Unmarshal(data, m)
+		return opts.Unmarshal(data, m)
+	}
+	if opts.DiscardUnknown {
+		return json.Unmarshal(data, v)
 	}
-	return json.Unmarshal(data, v)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
 }
 
 func (codec) Name() string {
@@ -0,0 +1,88 @@
+package json
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestProtoMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		in   proto.Message
+		out  proto.Message
+		json string
+	}{
+		{"oneof", structpb.NewStringValue("hello"), new(structpb.Value), `"hello"`},
+		{"enum", &typepb.Field{Kind: typepb.Field_TYPE_STRING, Name: "name"}, new(typepb.Field), `"kind":"TYPE_STRING"`},
+		{"duration", durationpb.New(1500 * time.Millisecond), new(durationpb.Duration), `"1.500s"`},
+		{"timestamp", timestamppb.New(time.Date(2021, 2, 1, 15, 18, 37, 0, time.UTC)), new(timestamppb.Timestamp), `"2021-02-01T15:18:37Z"`},
+	}
+	for _, test := range tests {
+		data, err := codec{}.Marshal(test.in)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !strings.Contains(string(data), test.json) {
+			t.Errorf("%s: expected %s in %s", test.name, test.json, data)
+		}
+		if err := (codec{}).Unmarshal(data, test.out); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !proto.Equal(test.in, test.out) {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.in, test.out)
+		}
+	}
+}
+
+func TestProtoNames(t *testing.T) {
+	defer UseProtoNames(MarshalOptions.UseProtoNames)
+	UseProtoNames(true)
+	data, err := codec{}.Marshal(&typepb.Field{TypeUrl: "type.googleapis.com/test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type_url"`) {
+		t.Errorf("expected the proto field names, but got %s", data)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	data, err := codec{}.Marshal(&user{Name: "kratos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out user
+	if err := (codec{}).Unmarshal(data, &out); err != nil || out.Name != "kratos" {
+		t.Errorf("expected the struct round-tripped by encoding/json, but got %s %v", data, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	strict := New(Options{})
+	if err := strict.Unmarshal([]byte(`{"name":"name","unknown":1}`), new(typepb.Field)); err == nil {
+		t.Errorf("expected the unknown field rejected")
+	}
+	var out struct {
+		Name string `json:"name"`
+	}
+	if err := strict.Unmarshal([]byte(`{"name":"name","unknown":1}`), &out); err == nil {
+		t.Errorf("expected the unknown struct field rejected")
+	}
+	data, err := New(Options{UseProtoNames: true}).Marshal(&typepb.Field{TypeUrl: "type.googleapis.com/test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type_url"`) || MarshalOptions.UseProtoNames {
+		t.Errorf("expected the proto field names of the instance only, but got %s", data)
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/go-kratos/kratos/encoding/msgpack
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	github.com/vmihailenco/msgpack/v5 v5.2.0
+	google.golang.org/protobuf v1.25.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
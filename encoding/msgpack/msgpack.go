@@ -0,0 +1,74 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Name is the name registered for the msgpack codec, i.e., application/msgpack.
+	Name = "msgpack"
+	// AliasName is the alternative name registered for the msgpack codec, i.e., application/x-msgpack.
+	AliasName = "x-msgpack"
+)
+
+func init() {
+	encoding.RegisterCodec(codec{name: Name})
+	encoding.RegisterCodec(codec{name: AliasName})
+}
+
+// codec is a Codec implementation with msgpack, the struct fields are named by the
+// msgpack tags, or the json tags if they are absent, and the proto messages are mapped
+// by their json names.
+type codec struct {
+	name string
+}
+
+func (c codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		data, err := protojson.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var obj interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		v = obj
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	if m, ok := v.(proto.Message); ok {
+		var obj interface{}
+		if err := dec.Decode(&obj); err != nil {
+			return err
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(data, m)
+	}
+	return dec.Decode(v)
+}
+
+func (c codec) Name() string {
+	return c.name
+}
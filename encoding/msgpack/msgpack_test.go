@@ -0,0 +1,93 @@
+package msgpack
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+type item struct {
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Price float64  `msgpack:"p" json:"price"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type order struct {
+	ID    string  `json:"id"`
+	Items []*item `json:"items"`
+}
+
+func newOrder() *order {
+	o := &order{ID: "order-1"}
+	for i := 0; i < 50; i++ {
+		o.Items = append(o.Items, &item{ID: int64(i), Name: fmt.Sprintf("item-%d", i), Price: 9.9, Tags: []string{"a", "b"}})
+	}
+	return o
+}
+
+func TestCodec(t *testing.T) {
+	in := newOrder()
+	data, err := codec{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := (codec{}).Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	first := raw["items"].([]interface{})[0].(map[string]interface{})
+	if _, ok := first["name"]; !ok {
+		t.Errorf("expected the json tags used, but got %v", first)
+	}
+	if _, ok := first["p"]; !ok {
+		t.Errorf("expected the msgpack tags preferred, but got %v", first)
+	}
+	out := new(order)
+	if err := (codec{}).Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %+v, but got %+v", in, out)
+	}
+	js, _ := json.Marshal(in)
+	t.Logf("msgpack %d bytes, json %d bytes", len(data), len(js))
+}
+
+func TestProtoMessage(t *testing.T) {
+	in := &typepb.Field{Name: "name", Kind: typepb.Field_TYPE_STRING, Number: 1}
+	data, err := codec{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(typepb.Field)
+	if err := (codec{}).Unmarshal(data, out); err != nil || !proto.Equal(in, out) {
+		t.Errorf("expected %v, but got %v %v", in, out, err)
+	}
+}
+
+func BenchmarkMsgpack(b *testing.B) {
+	o := newOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ := codec{}.Marshal(o)
+		if err := (codec{}).Unmarshal(data, new(order)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	o := newOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ := json.Marshal(o)
+		if err := json.Unmarshal(data, new(order)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
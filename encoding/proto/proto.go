@@ -1,29 +1,46 @@
 package proto
 
 import (
+	"fmt"
+
 	"github.com/go-kratos/kratos/v2/encoding"
 
 	"google.golang.org/protobuf/proto"
 )
 
-// Name is the name registered for the proto codec.
-const Name = "proto"
+const (
+	// Name is the name registered for the proto codec.
+	Name = "proto"
+	// AliasName is the alternative name registered for the proto codec, i.e., application/x-protobuf.
+	AliasName = "x-protobuf"
+)
 
 func init() {
-	encoding.RegisterCodec(codec{})
+	encoding.RegisterCodec(codec{name: Name})
+	encoding.RegisterCodec(codec{name: AliasName})
 }
 
 // codec is a Codec implementation with protobuf.
-type codec struct{}
+type codec struct {
+	name string
+}
 
-func (codec) Marshal(v interface{}) ([]byte, error) {
-	return proto.Marshal(v.(proto.Message))
+func (c codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot marshal %T, which is not a proto message", c.name, v)
+	}
+	return proto.Marshal(m)
 }
 
-func (codec) Unmarshal(data []byte, v interface{}) error {
-	return proto.Unmarshal(data, v.(proto.Message))
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%s: cannot unmarshal into %T, which is not a proto message", c.name, v)
+	}
+	return proto.Unmarshal(data, m)
 }
 
-func (codec) Name() string {
-	return Name
+func (c codec) Name() string {
+	return c.name
 }
@@ -0,0 +1,65 @@
+package proto
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestCodec(t *testing.T) {
+	for _, name := range []string{Name, AliasName} {
+		c := encoding.GetCodec(name)
+		if c == nil {
+			t.Fatalf("expected the codec registered for %s", name)
+		}
+		in := &typepb.Field{Name: "name", Number: 1}
+		data, err := c.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(typepb.Field)
+		if err := c.Unmarshal(data, out); err != nil || !proto.Equal(in, out) {
+			t.Errorf("expected %v, but got %v %v", in, out, err)
+		}
+		if _, err := c.Marshal(struct{}{}); err == nil {
+			t.Errorf("expected an error for non-proto values")
+		}
+	}
+}
+
+// message returns a mid-sized message with 20 fields.
+func message() *typepb.Type {
+	m := &typepb.Type{Name: "kratos.Message", Syntax: typepb.Syntax_SYNTAX_PROTO3}
+	for i := 0; i < 20; i++ {
+		m.Fields = append(m.Fields, &typepb.Field{
+			Kind:     typepb.Field_TYPE_STRING,
+			Number:   int32(i + 1),
+			Name:     fmt.Sprintf("field_%d", i),
+			JsonName: fmt.Sprintf("field%d", i),
+		})
+	}
+	return m
+}
+
+func benchmarkCodec(b *testing.B, name string) {
+	c := encoding.GetCodec(name)
+	m := message()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, err := c.Marshal(m)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := c.Unmarshal(data, new(typepb.Type)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSON(b *testing.B)  { benchmarkCodec(b, "json") }
+func BenchmarkProto(b *testing.B) { benchmarkCodec(b, Name) }
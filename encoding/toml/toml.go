@@ -0,0 +1,60 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/BurntSushi/toml"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the name registered for the toml codec, i.e., application/toml.
+const Name = "toml"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a Codec implementation with toml, the proto messages are mapped by their json names.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		data, err := protojson.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		v = obj
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		var obj map[string]interface{}
+		if err := toml.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(data, m)
+	}
+	return toml.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return Name
+}
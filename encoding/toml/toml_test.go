@@ -0,0 +1,33 @@
+package toml
+
+import (
+	"testing"
+)
+
+const document = `
+[server]
+addr = "0.0.0.0:8000"
+retries = 3
+`
+
+func TestCodec(t *testing.T) {
+	var v map[string]interface{}
+	if err := (codec{}).Unmarshal([]byte(document), &v); err != nil {
+		t.Fatal(err)
+	}
+	server := v["server"].(map[string]interface{})
+	if server["addr"] != "0.0.0.0:8000" || server["retries"] != int64(3) {
+		t.Errorf("expected the server table, but got %v", server)
+	}
+	data, err := (codec{}).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var next map[string]interface{}
+	if err := (codec{}).Unmarshal(data, &next); err != nil {
+		t.Fatal(err)
+	}
+	if next["server"].(map[string]interface{})["addr"] != "0.0.0.0:8000" {
+		t.Errorf("expected the round trip, but got %s", data)
+	}
+}
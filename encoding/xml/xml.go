@@ -0,0 +1,62 @@
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the name registered for the xml codec, i.e., application/xml and text/xml.
+const Name = "xml"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a Codec implementation with xml, the structs are mapped by the encoding/xml tags.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if err := mappable(v); err != nil {
+		return nil, err
+	}
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if err := mappable(v); err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return Name
+}
+
+// mappable returns ErrUnsupportedType for the values encoding/xml has no mapping of.
+func mappable(v interface{}) error {
+	if _, ok := v.(proto.Message); ok {
+		return fmt.Errorf("xml: %T is a proto message: %w", v, encoding.ErrUnsupportedType)
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return fmt.Errorf("xml: nil value: %w", encoding.ErrUnsupportedType)
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Errorf("xml: %v has no xml mapping: %w", t, encoding.ErrUnsupportedType)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package xml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type address struct {
+	City   string `xml:"city"`
+	Street string `xml:"street,attr"`
+}
+
+type bio struct {
+	Text string `xml:",cdata"`
+}
+
+type user struct {
+	ID      int      `xml:"id,attr"`
+	Name    string   `xml:"name"`
+	Bio     bio      `xml:"bio"`
+	Address *address `xml:"address"`
+	Tags    []string `xml:"tags>tag"`
+}
+
+func TestCodec(t *testing.T) {
+	in := &user{
+		ID:      1,
+		Name:    "kratos",
+		Bio:     bio{Text: "<b>go</b> & microservices"},
+		Address: &address{City: "Shanghai", Street: "Nanjing Road"},
+		Tags:    []string{"go", "grpc"},
+	}
+	data, err := codec{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if !strings.HasPrefix(s, `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("expected the xml header, but got %s", s)
+	}
+	if !strings.Contains(s, `<user id="1">`) || !strings.Contains(s, `<![CDATA[<b>go</b> & microservices]]>`) {
+		t.Errorf("expected the attributes and CDATA, but got %s", s)
+	}
+	out := new(user)
+	if err := (codec{}).Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 1 || out.Bio != in.Bio || out.Address == nil || out.Address.Street != "Nanjing Road" || len(out.Tags) != 2 {
+		t.Errorf("expected %+v, but got %+v", in, out)
+	}
+}
+
+func TestUnsupportedType(t *testing.T) {
+	data := []byte(`<value>1</value>`)
+	for _, v := range []interface{}{new(map[string]string), new(structpb.Value)} {
+		if err := (codec{}).Unmarshal(data, v); !errors.Is(err, encoding.ErrUnsupportedType) {
+			t.Errorf("expected ErrUnsupportedType for %T, but got %v", v, err)
+		}
+	}
+}
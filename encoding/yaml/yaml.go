@@ -0,0 +1,124 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Name is the name registered for the yaml codec, i.e., text/yaml.
+	Name = "yaml"
+	// AliasName is the alternative name registered for the yaml codec, i.e., application/x-yaml.
+	AliasName = "x-yaml"
+)
+
+// ErrMultiDocument is returned when the data has more than one document.
+var ErrMultiDocument = errors.New("yaml: multi-document streams are not supported")
+
+func init() {
+	encoding.RegisterCodec(codec{name: Name})
+	encoding.RegisterCodec(codec{name: AliasName})
+}
+
+// codec is a Codec implementation with yaml, the aliases are expanded and only a single
+// document is accepted. The maps are decoded with string keys so that they are compatible
+// with json, and the proto messages are mapped by their json names.
+type codec struct {
+	name string
+}
+
+func (c codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		data, err := protojson.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var obj interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		v = obj
+	}
+	return yaml.Marshal(v)
+}
+
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var node yaml.Node
+	if err := dec.Decode(&node); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ErrMultiDocument
+	}
+	switch v := v.(type) {
+	case proto.Message:
+		var obj interface{}
+		if err := node.Decode(&obj); err != nil {
+			return err
+		}
+		data, err := json.Marshal(stringKeys(obj))
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(data, v)
+	case *interface{}:
+		if err := node.Decode(v); err != nil {
+			return err
+		}
+		*v = stringKeys(*v)
+		return nil
+	case *map[string]interface{}:
+		if err := node.Decode(v); err != nil {
+			return err
+		}
+		for key, value := range *v {
+			(*v)[key] = stringKeys(value)
+		}
+		return nil
+	}
+	return node.Decode(v)
+}
+
+func (c codec) Name() string {
+	return c.name
+}
+
+// stringKeys converts the maps with non-string keys into the maps with string keys.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+		return v
+	}
+	return v
+}
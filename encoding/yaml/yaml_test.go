@@ -0,0 +1,56 @@
+package yaml
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+const document = `
+defaults: &defaults
+  timeout: 1s
+  retries: 3
+server:
+  <<: *defaults
+  addr: 0.0.0.0:8000
+codes:
+  404: not found
+`
+
+func TestUnmarshal(t *testing.T) {
+	var v map[string]interface{}
+	if err := (codec{}).Unmarshal([]byte(document), &v); err != nil {
+		t.Fatal(err)
+	}
+	server := v["server"].(map[string]interface{})
+	if server["timeout"] != "1s" || server["retries"] != 3 || server["addr"] != "0.0.0.0:8000" {
+		t.Errorf("expected the aliases expanded, but got %v", server)
+	}
+	if _, err := json.Marshal(v); err != nil {
+		t.Errorf("expected the maps compatible with json, but got %v", err)
+	}
+	if v["codes"].(map[string]interface{})["404"] != "not found" {
+		t.Errorf("expected the string keys, but got %v", v["codes"])
+	}
+}
+
+func TestMultiDocument(t *testing.T) {
+	var v interface{}
+	if err := (codec{}).Unmarshal([]byte("a: 1\n---\nb: 2\n"), &v); err != ErrMultiDocument {
+		t.Errorf("expected ErrMultiDocument, but got %v", err)
+	}
+}
+
+func TestProtoMessage(t *testing.T) {
+	in := &typepb.Field{Name: "name", Kind: typepb.Field_TYPE_STRING, JsonName: "name"}
+	data, err := codec{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(typepb.Field)
+	if err := (codec{}).Unmarshal(data, out); err != nil || !proto.Equal(in, out) {
+		t.Errorf("expected %v, but got %v %v", in, out, err)
+	}
+}
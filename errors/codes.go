@@ -1,216 +1,147 @@
 package errors
 
+// The constructors of the canonical gRPC codes, whose codes are the HTTP mapping of them.
+// The gRPC code is kept by the errors, so that the codes sharing an HTTP mapping, i.e.,
+// AlreadyExists and Aborted, are told apart by the predicates and on the gRPC wire.
+
 import (
 	"errors"
 	"fmt"
+
+	"google.golang.org/grpc/codes"
 )
 
-// Cancelled The operation was cancelled, typically by the caller.
-// HTTP Mapping: 499 Client Closed Request
-func Cancelled(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    1,
+// canonical returns an error object of the canonical gRPC code and its HTTP mapping.
+func canonical(code codes.Code, reason, format string, a ...interface{}) error {
+	return &Error{
+		Code:    int32(fromGRPCCode(code)),
 		Reason:  reason,
 		Message: fmt.Sprintf(format, a...),
+		grpc:    code,
 	}
 }
 
-func IsCancelled(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 1
+// is reports whether the error in the chain of err has the canonical gRPC code, which is
+// the code of its constructor, or else the gRPC mapping of its HTTP code.
+func is(err error, code codes.Code) bool {
+	if se := new(Error); errors.As(err, &se) {
+		return se.grpcCode() == code
 	}
 	return false
 }
 
+// Cancelled The operation was cancelled, typically by the caller.
+// HTTP Mapping: 499 Client Closed Request
+func Cancelled(reason, format string, a ...interface{}) error {
+	return canonical(codes.Canceled, reason, format, a...)
+}
+
+func IsCancelled(err error) bool {
+	return is(err, codes.Canceled)
+}
+
 // Unknown error.
 // HTTP Mapping: 500 Internal Server Error
 func Unknown(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    2,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.Unknown, reason, format, a...)
 }
 
 func IsUnknown(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 2
-	}
-	return false
+	return is(err, codes.Unknown)
 }
 
 // InvalidArgument The client specified an invalid argument.
 // HTTP Mapping: 400 Bad Request
 func InvalidArgument(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    3,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.InvalidArgument, reason, format, a...)
 }
 
 func IsInvalidArgument(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 3
-	}
-	return false
+	return is(err, codes.InvalidArgument)
 }
 
 // DeadlineExceeded The deadline expired before the operation could complete.
 // HTTP Mapping: 504 Gateway Timeout
 func DeadlineExceeded(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    4,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.DeadlineExceeded, reason, format, a...)
 }
 
 func IsDeadlineExceeded(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 4
-	}
-	return false
-}
-
-// NotFound Some requested entity (e.g., file or directory) was not found.
-// HTTP Mapping: 404 Not Found
-func NotFound(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    5,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
-}
-
-func IsNotFound(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 5
-	}
-	return false
+	return is(err, codes.DeadlineExceeded)
 }
 
 // AlreadyExists The entity that a client attempted to create (e.g., file or directory) already exists.
 // HTTP Mapping: 409 Conflict
 func AlreadyExists(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    6,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.AlreadyExists, reason, format, a...)
 }
 
 func IsAlreadyExists(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 6
-	}
-	return false
+	return is(err, codes.AlreadyExists)
 }
 
 // PermissionDenied The caller does not have permission to execute the specified operation.
 // HTTP Mapping: 403 Forbidden
 func PermissionDenied(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    7,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.PermissionDenied, reason, format, a...)
 }
 
 func IsPermissionDenied(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 7
-	}
-	return false
+	return is(err, codes.PermissionDenied)
 }
 
 // ResourceExhausted Some resource has been exhausted, perhaps a per-user quota, or
 // perhaps the entire file system is out of space.
 // HTTP Mapping: 429 Too Many Requests
 func ResourceExhausted(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    8,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.ResourceExhausted, reason, format, a...)
 }
 
 func IsResourceExhausted(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 8
-	}
-	return false
+	return is(err, codes.ResourceExhausted)
 }
 
 // FailedPrecondition The operation was rejected because the system is not in a state
 // required for the operation's execution.
-// HTTP Mapping: 400 Bad Request
+// HTTP Mapping: 412 Precondition Failed
 func FailedPrecondition(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    9,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.FailedPrecondition, reason, format, a...)
 }
 
 func IsFailedPrecondition(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 9
-	}
-	return false
+	return is(err, codes.FailedPrecondition)
 }
 
 // Aborted The operation was aborted, typically due to a concurrency issue such as
 // a sequencer check failure or transaction abort.
 // HTTP Mapping: 409 Conflict
 func Aborted(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    10,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.Aborted, reason, format, a...)
 }
 
 func IsAborted(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 10
-	}
-	return false
+	return is(err, codes.Aborted)
 }
 
 // OutOfRange The operation was attempted past the valid range.  E.g., seeking or
 // reading past end-of-file.
 // HTTP Mapping: 400 Bad Request
 func OutOfRange(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    11,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.OutOfRange, reason, format, a...)
 }
 
 func IsOutOfRange(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 11
-	}
-	return false
+	return is(err, codes.OutOfRange)
 }
 
 // Unimplemented The operation is not implemented or is not supported/enabled in this service.
 // HTTP Mapping: 501 Not Implemented
 func Unimplemented(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    12,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.Unimplemented, reason, format, a...)
 }
 
 func IsUnimplemented(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 12
-	}
-	return false
+	return is(err, codes.Unimplemented)
 }
 
 // Internal This means that some invariants expected by the
@@ -219,67 +150,29 @@ func IsUnimplemented(err error) bool {
 //
 // HTTP Mapping: 500 Internal Server Error
 func Internal(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    13,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.Internal, reason, format, a...)
 }
 
 func IsInternal(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 13
-	}
-	return false
+	return is(err, codes.Internal)
 }
 
 // Unavailable The service is currently unavailable.
 // HTTP Mapping: 503 Service Unavailable
 func Unavailable(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    14,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.Unavailable, reason, format, a...)
 }
 
 func IsUnavailable(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 14
-	}
-	return false
+	return is(err, codes.Unavailable)
 }
 
 // DataLoss Unrecoverable data loss or corruption.
 // HTTP Mapping: 500 Internal Server Error
 func DataLoss(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    15,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
+	return canonical(codes.DataLoss, reason, format, a...)
 }
 
 func IsDataLoss(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 15
-	}
-	return false
-}
-
-// Unauthorized The request does not have valid authentication credentials for the operation.
-// HTTP Mapping: 401 Unauthorized
-func Unauthorized(reason, format string, a ...interface{}) error {
-	return &StatusError{
-		Code:    16,
-		Reason:  reason,
-		Message: fmt.Sprintf(format, a...),
-	}
-}
-
-func IsUnauthorized(err error) bool {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code == 16
-	}
-	return false
+	return is(err, codes.DataLoss)
 }
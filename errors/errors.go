@@ -1,73 +1,168 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	// UnknownReason is unknown reason for error info.
 	UnknownReason = ""
+	// UnknownCode is the code of the errors which are not an *Error.
+	UnknownCode = 500
 	// SupportPackageIsVersion1 this constant should not be referenced by any other code.
 	SupportPackageIsVersion1 = true
 )
 
-var _ error = (*StatusError)(nil)
+var _ error = (*Error)(nil)
+
+// Error is a status error with the code, which is a HTTP status code, the reason
+// that identifies the error, the message for humans, and the metadata for details.
+type Error struct {
+	Code     int32             `json:"code"`
+	Reason   string            `json:"reason"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty" xml:"-"`
 
-// StatusError contains an error response from the server.
-type StatusError Status
+	cause     error
+	stack     []uintptr
+	retryable retryable
+	grpc      codes.Code
+}
 
-// Is matches each error in the chain with the target value.
-func (e *StatusError) Is(target error) bool {
-	err, ok := target.(*StatusError)
-	if ok {
-		return e.Code == err.Code
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v cause = %v", e.Code, e.Reason, e.Message, e.Metadata, e.cause)
 	}
-	return false
+	return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v", e.Code, e.Reason, e.Message, e.Metadata)
 }
 
-func (e *StatusError) Error() string {
-	return fmt.Sprintf("error: code = %d reason = %s message = %s details = %+v", e.Code, e.Reason, e.Message, e.Details)
+// WithCause returns a copy of the error with the underlying cause, which is reachable
+// by errors.Is and errors.As, and is never serialized to the clients.
+func (e *Error) WithCause(cause error) *Error {
+	err := *e
+	err.cause = cause
+	if stack := callers(); stack != nil {
+		err.stack = stack
+	}
+	return &err
 }
 
-// Error returns a Status representing c and msg.
-func Error(code int32, reason, message string) error {
-	return &StatusError{
-		Code:    code,
-		Reason:  reason,
-		Message: message,
+// WithMetadata returns a copy of the error with the metadata merged into its
+// metadata, the original error is never mutated.
+func (e *Error) WithMetadata(md map[string]string) *Error {
+	err := *e
+	err.Metadata = make(map[string]string, len(e.Metadata)+len(md))
+	for k, v := range e.Metadata {
+		err.Metadata[k] = v
+	}
+	for k, v := range md {
+		err.Metadata[k] = v
 	}
+	return &err
 }
 
-// Errorf returns New(c, fmt.Sprintf(format, a...)).
-func Errorf(code int32, reason, format string, a ...interface{}) error {
-	return Error(code, reason, fmt.Sprintf(format, a...))
+// grpcCode returns the canonical gRPC code of the error, which is kept by the canonical
+// constructors and FromGRPCStatus, or else mapped from the HTTP code.
+func (e *Error) grpcCode() codes.Code {
+	if e.grpc != codes.OK {
+		return e.grpc
+	}
+	return toGRPCCode(int(e.Code))
 }
 
-// Code returns the status code.
-func Code(err error) int32 {
-	if err == nil {
-		return 0 // ok
+// Unwrap returns the underlying cause.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches each error in the chain with the target value by the code and the reason,
+// the message and the metadata are ignored.
+func (e *Error) Is(target error) bool {
+	if err, ok := target.(*Error); ok {
+		return e.Code == err.Code && e.Reason == err.Reason
 	}
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Code
+	return false
+}
+
+// New returns an error object for the code, reason and message.
+func New(code int, reason, message string) *Error {
+	err := newError(code, reason, message)
+	err.stack = callers()
+	return err
+}
+
+// Newf New(code, reason, fmt.Sprintf(format, a...))
+func Newf(code int, reason, format string, a ...interface{}) *Error {
+	return New(code, reason, fmt.Sprintf(format, a...))
+}
+
+// Errorf returns an error object for the code, reason and message format.
+func Errorf(code int, reason, format string, a ...interface{}) error {
+	return New(code, reason, fmt.Sprintf(format, a...))
+}
+
+// Code returns the code of the error normalized by FromError, 200 for nil.
+// It supports wrapped errors.
+func Code(err error) int {
+	if err == nil {
+		return 200 // ok
 	}
-	return 2 // unknown
+	return int(FromError(err).Code)
 }
 
-// Reason returns the status for a particular error.
+// Reason returns the reason for a particular error.
 // It supports wrapped errors.
 func Reason(err error) string {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se.Reason
+	if err == nil {
+		return UnknownReason
+	}
+	return FromError(err).Reason
+}
+
+// Metadata returns the metadata of the error, nil if there is none.
+// It supports wrapped errors.
+func Metadata(err error) map[string]string {
+	if err == nil {
+		return nil
 	}
-	return UnknownReason
+	return FromError(err).Metadata
 }
 
-// FromError returns status error.
-func FromError(err error) (*StatusError, bool) {
-	if se := new(StatusError); errors.As(err, &se) {
-		return se, true
+// FromError normalizes the error to an *Error, nil for nil. It returns the *Error in
+// the chain of the error, the error of a gRPC status, 504 and 499 for the context
+// deadline and cancellation, and otherwise a 500 error with the original message.
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if se := new(Error); errors.As(err, &se) {
+		return se
+	}
+	var gs interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &gs) {
+		return FromGRPCStatus(gs.GRPCStatus())
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return newError(http.StatusGatewayTimeout, UnknownReason, err.Error())
+	case errors.Is(err, context.Canceled):
+		return newError(499, UnknownReason, err.Error())
+	}
+	return newError(UnknownCode, UnknownReason, err.Error())
+}
+
+// newError returns an error object without the stack trace, for the errors converted
+// from other errors, whose stack trace would be the conversion site.
+func newError(code int, reason, message string) *Error {
+	return &Error{
+		Code:    int32(code),
+		Reason:  reason,
+		Message: message,
 	}
-	return nil, false
 }
@@ -1,14 +1,18 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestErrorsMatch(t *testing.T) {
-	s := &StatusError{Code: 1}
-	st := &StatusError{Code: 2}
+	s := &Error{Code: 1}
+	st := &Error{Code: 2}
 
 	if errors.Is(s, st) {
 		t.Errorf("error is not match: %+v -> %+v", s, st)
@@ -21,8 +25,12 @@ func TestErrorsMatch(t *testing.T) {
 	}
 
 	s.Reason = "test_reason"
-	s.Reason = "test_reason"
+	if errors.Is(s, st) {
+		t.Errorf("error is not match: %+v -> %+v", s, st)
+	}
 
+	st.Reason = "test_reason"
+	st.Message = "other message"
 	if !errors.Is(s, st) {
 		t.Errorf("error is not match: %+v -> %+v", s, st)
 	}
@@ -33,7 +41,7 @@ func TestErrorsMatch(t *testing.T) {
 }
 
 func TestErrorIs(t *testing.T) {
-	err1 := &StatusError{Code: 1}
+	err1 := New(404, "USER_NOT_FOUND", "user not found")
 	t.Log(err1)
 	err2 := fmt.Errorf("wrap : %w", err1)
 	t.Log(err2)
@@ -41,14 +49,190 @@ func TestErrorIs(t *testing.T) {
 	if !(errors.Is(err2, err1)) {
 		t.Errorf("error is not match: a: %v b: %v ", err2, err1)
 	}
+	if !errors.Is(err2, New(404, "USER_NOT_FOUND", "")) {
+		t.Errorf("expected the wrapped error matched by code and reason: %v", err2)
+	}
+	if Code(err2) != 404 || Reason(err2) != "USER_NOT_FOUND" {
+		t.Errorf("expected the code and reason of the wrapped error, but got %d %s", Code(err2), Reason(err2))
+	}
 }
 
 func TestErrorAs(t *testing.T) {
-	err1 := &StatusError{Code: 1}
+	err1 := Newf(400, "INVALID", "invalid %s", "name")
 	err2 := fmt.Errorf("wrap : %w", err1)
 
-	err3 := new(StatusError)
-	if !errors.As(err2, &err3) {
+	err3 := new(Error)
+	if !errors.As(err2, &err3) || err3.Message != "invalid name" {
 		t.Errorf("error is not match: %v", err2)
 	}
 }
+
+func TestCode(t *testing.T) {
+	if Code(nil) != 200 || Reason(nil) != UnknownReason {
+		t.Errorf("expected ok for nil, but got %d", Code(nil))
+	}
+	if Code(errors.New("boom")) != UnknownCode {
+		t.Errorf("expected unknown for the foreign errors, but got %d", Code(errors.New("boom")))
+	}
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    int32
+		reason  string
+		message string
+	}{
+		{fmt.Errorf("wrap: %w", NotFound("USER_NOT_FOUND", "user %d", 1)), 404, "USER_NOT_FOUND", "user 1"},
+		{status.Error(codes.Unavailable, "connection refused"), 503, UnknownReason, "connection refused"},
+		{fmt.Errorf("wrap: %w", BadRequest("INVALID", "invalid").GRPCStatus().Err()), 400, "INVALID", "invalid"},
+		{fmt.Errorf("call: %w", context.DeadlineExceeded), 504, UnknownReason, "call: context deadline exceeded"},
+		{context.Canceled, 499, UnknownReason, "context canceled"},
+		{errors.New("boom"), 500, UnknownReason, "boom"},
+	}
+	for _, test := range tests {
+		se := FromError(test.err)
+		if se.Code != test.code || se.Reason != test.reason || se.Message != test.message {
+			t.Errorf("expected %d %s %s, but got %v", test.code, test.reason, test.message, se)
+		}
+	}
+	if FromError(nil) != nil {
+		t.Errorf("expected nil for nil")
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+		is   func(error) bool
+	}{
+		{BadRequest("reason", "message"), 400, IsBadRequest},
+		{Unauthorized("reason", "message"), 401, IsUnauthorized},
+		{Forbidden("reason", "message"), 403, IsForbidden},
+		{NotFound("reason", "message"), 404, IsNotFound},
+		{Conflict("reason", "message"), 409, IsConflict},
+		{TooManyRequests("reason", "message"), 429, IsTooManyRequests},
+		{ClientClosed("reason", "message"), 499, IsClientClosed},
+		{InternalServer("reason", "message"), 500, IsInternalServer},
+		{ServiceUnavailable("reason", "message"), 503, IsServiceUnavailable},
+		{GatewayTimeout("reason", "message"), 504, IsGatewayTimeout},
+	}
+	for _, test := range tests {
+		err := fmt.Errorf("wrap: %w", test.err)
+		if Code(err) != test.code || !test.is(err) {
+			t.Errorf("expected %d, but got %d", test.code, Code(err))
+		}
+		if test.is(errors.New("boom")) && test.code != UnknownCode {
+			t.Errorf("expected the foreign error not matched by %d", test.code)
+		}
+	}
+}
+
+func TestCanonicalPredicates(t *testing.T) {
+	tests := []struct {
+		err  error
+		is   func(error) bool
+		isnt []func(error) bool
+	}{
+		{Unknown("reason", "message"), IsUnknown, []func(error) bool{IsInternal, IsDataLoss}},
+		{Internal("reason", "message"), IsInternal, []func(error) bool{IsUnknown, IsDataLoss}},
+		{DataLoss("reason", "message"), IsDataLoss, []func(error) bool{IsUnknown, IsInternal}},
+		{AlreadyExists("reason", "message"), IsAlreadyExists, []func(error) bool{IsAborted}},
+		{Aborted("reason", "message"), IsAborted, []func(error) bool{IsAlreadyExists}},
+		{InvalidArgument("reason", "message"), IsInvalidArgument, []func(error) bool{IsOutOfRange}},
+		{OutOfRange("reason", "message"), IsOutOfRange, []func(error) bool{IsInvalidArgument}},
+		{New(409, "reason", "message"), IsAlreadyExists, []func(error) bool{IsAborted}},
+		{New(500, "reason", "message"), IsInternal, []func(error) bool{IsUnknown, IsDataLoss}},
+		{FromGRPCStatus(status.New(codes.Aborted, "message")), IsAborted, []func(error) bool{IsAlreadyExists}},
+	}
+	for i, test := range tests {
+		err := fmt.Errorf("wrap: %w", test.err)
+		if !test.is(err) {
+			t.Errorf("%d: expected %v matched", i, test.err)
+		}
+		for _, isnt := range test.isnt {
+			if isnt(err) {
+				t.Errorf("%d: expected %v not matched by the aliased predicate", i, test.err)
+			}
+		}
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	public := NotFound("USER_NOT_FOUND", "user not found")
+	err := fmt.Errorf("wrap: %w", public.WithCause(cause))
+	tests := []struct {
+		target error
+		want   bool
+	}{
+		{cause, true},
+		{public, true},
+		{New(404, "USER_NOT_FOUND", "other message"), true},
+		{&Error{Code: 404, Reason: "USER_NOT_FOUND", Metadata: map[string]string{"id": "1"}}, true},
+		{New(404, "ORDER_NOT_FOUND", "user not found"), false},
+		{New(500, "USER_NOT_FOUND", "user not found"), false},
+		{errors.New("sql: no rows in result set"), false},
+	}
+	for _, test := range tests {
+		if got := errors.Is(err, test.target); got != test.want {
+			t.Errorf("errors.Is(%v): expected %v, but got %v", test.target, test.want, got)
+		}
+	}
+	if public.Unwrap() != nil {
+		t.Errorf("expected the original error unchanged")
+	}
+	if se := FromError(err); se.Unwrap() != cause || se.Message != "user not found" {
+		t.Errorf("expected the public error with the cause, but got %v", se)
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	base := TooManyRequests("RATE_LIMITED", "rate limited").WithMetadata(map[string]string{"limit": "10"})
+	err := base.WithMetadata(map[string]string{"retry_after": "5"})
+	if len(base.Metadata) != 1 {
+		t.Errorf("expected the original metadata untouched, but got %v", base.Metadata)
+	}
+	md := Metadata(fmt.Errorf("wrap: %w", err))
+	if md["limit"] != "10" || md["retry_after"] != "5" {
+		t.Errorf("expected the merged metadata, but got %v", md)
+	}
+	if Metadata(nil) != nil || Metadata(errors.New("boom")) != nil {
+		t.Errorf("expected no metadata")
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{ServiceUnavailable("UNAVAILABLE", "try again"), true},
+		{TooManyRequests("QUOTA", "quota exceeded"), true},
+		{fmt.Errorf("call: %w", GatewayTimeout("TIMEOUT", "upstream timeout")), true},
+		{InternalServer("DB", "database error"), false},
+		{BadRequest("INVALID", "invalid name"), false},
+		{TooManyRequests("QUOTA", "quota exhausted").WithRetryable(false), false},
+		{BadRequest("CONFLICT", "try later").WithRetryable(true), true},
+		{InternalServer("DIAL", "dial failed").WithCause(timeoutError{}), true},
+		{TooManyRequests("QUOTA", "quota exhausted").WithRetryable(false).WithMetadata(map[string]string{"quota": "daily"}), false},
+		// the marker is never carried to the clients.
+		{FromGRPCStatus(TooManyRequests("QUOTA", "quota exhausted").WithRetryable(false).GRPCStatus()), true},
+	}
+	for _, test := range tests {
+		if IsRetryable(test.err) != test.retryable {
+			t.Errorf("%v: expected retryable %v", test.err, test.retryable)
+		}
+	}
+	if md := BadRequest("CONFLICT", "try later").WithRetryable(true).Metadata; len(md) != 0 {
+		t.Errorf("expected no retryable marker in the metadata, but got %v", md)
+	}
+}
@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toGRPCCode returns the gRPC code of the HTTP status code.
+func toGRPCCode(code int) codes.Code {
+	switch code {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests, http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// fromGRPCCode returns the HTTP status code of the gRPC code.
+func fromGRPCCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus returns the gRPC status of the error with the Status detail, the code is
+// the canonical gRPC code of the error, and the foreign details of the gRPC status in the
+// cause chain are kept.
+func (e *Error) GRPCStatus() *status.Status {
+	gs := status.New(e.grpcCode(), e.Message)
+	details := []proto.Message{&Status{
+		Code:     e.Code,
+		Reason:   e.Reason,
+		Message:  e.Message,
+		Metadata: e.Metadata,
+	}}
+	var cause interface{ GRPCStatus() *status.Status }
+	if e.cause != nil && errors.As(e.cause, &cause) {
+		for _, detail := range cause.GRPCStatus().Details() {
+			if d, ok := detail.(proto.Message); ok {
+				if _, ours := d.(*Status); !ours {
+					details = append(details, d)
+				}
+			}
+		}
+	}
+	if ds, err := gs.WithDetails(details...); err == nil {
+		return ds
+	}
+	return gs
+}
+
+// FromGRPCStatus returns the error of the gRPC status restored from its Status detail,
+// or mapped from the canonical gRPC code without it, and the gRPC code is kept. The status
+// is kept as the cause, so that its foreign details are reachable.
+func FromGRPCStatus(gs *status.Status) *Error {
+	se := newError(fromGRPCCode(gs.Code()), UnknownReason, gs.Message())
+	for _, detail := range gs.Details() {
+		if d, ok := detail.(*Status); ok {
+			se = newError(int(d.Code), d.Reason, d.Message)
+			se.Metadata = d.Metadata
+			break
+		}
+	}
+	se.grpc = gs.Code()
+	se.cause = gs.Err()
+	return se
+}
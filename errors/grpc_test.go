@@ -0,0 +1,116 @@
+package errors
+
+import (
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestGRPCCodes(t *testing.T) {
+	to := map[int]codes.Code{
+		200:   codes.OK,
+		400:   codes.InvalidArgument,
+		401:   codes.Unauthenticated,
+		403:   codes.PermissionDenied,
+		404:   codes.NotFound,
+		405:   codes.Unimplemented,
+		408:   codes.DeadlineExceeded,
+		409:   codes.AlreadyExists,
+		412:   codes.FailedPrecondition,
+		413:   codes.ResourceExhausted,
+		415:   codes.InvalidArgument,
+		422:   codes.InvalidArgument,
+		429:   codes.ResourceExhausted,
+		499:   codes.Canceled,
+		500:   codes.Internal,
+		501:   codes.Unimplemented,
+		502:   codes.Unavailable,
+		503:   codes.Unavailable,
+		504:   codes.DeadlineExceeded,
+		10001: codes.Unknown,
+	}
+	for code, want := range to {
+		if got := toGRPCCode(code); got != want {
+			t.Errorf("%d: expected %s, but got %s", code, want, got)
+		}
+	}
+	from := map[codes.Code]int{
+		codes.OK:                 200,
+		codes.Canceled:           499,
+		codes.Unknown:            500,
+		codes.InvalidArgument:    400,
+		codes.DeadlineExceeded:   504,
+		codes.NotFound:           404,
+		codes.AlreadyExists:      409,
+		codes.PermissionDenied:   403,
+		codes.ResourceExhausted:  429,
+		codes.FailedPrecondition: 412,
+		codes.Aborted:            409,
+		codes.OutOfRange:         400,
+		codes.Unimplemented:      501,
+		codes.Internal:           500,
+		codes.Unavailable:        503,
+		codes.DataLoss:           500,
+		codes.Unauthenticated:    401,
+	}
+	for code, want := range from {
+		if got := fromGRPCCode(code); got != want {
+			t.Errorf("%s: expected %d, but got %d", code, want, got)
+		}
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	err := New(10001, "BALANCE_NOT_ENOUGH", "balance not enough")
+	err.Metadata = map[string]string{"balance": "1"}
+	gs := err.GRPCStatus()
+	if gs.Code() != codes.Unknown || gs.Message() != "balance not enough" {
+		t.Errorf("unexpected status %v", gs)
+	}
+	se := FromGRPCStatus(gs)
+	if se.Code != 10001 || se.Reason != "BALANCE_NOT_ENOUGH" || se.Metadata["balance"] != "1" {
+		t.Errorf("expected the error restored, but got %v", se)
+	}
+
+	foreign, _ := status.New(codes.Unavailable, "overloaded").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1)})
+	se = FromGRPCStatus(foreign)
+	if se.Code != 503 || se.Reason != UnknownReason || se.Message != "overloaded" {
+		t.Errorf("expected the error mapped from the code, but got %v", se)
+	}
+	details := se.GRPCStatus().Details()
+	if len(details) != 2 {
+		t.Fatalf("expected the foreign detail kept, but got %v", details)
+	}
+	if _, ok := details[1].(*errdetails.RetryInfo); !ok {
+		t.Errorf("expected the retry info, but got %T", details[1])
+	}
+}
+
+func TestGRPCStatusCanonical(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{AlreadyExists("reason", "message"), codes.AlreadyExists},
+		{Aborted("reason", "message"), codes.Aborted},
+		{InvalidArgument("reason", "message"), codes.InvalidArgument},
+		{OutOfRange("reason", "message"), codes.OutOfRange},
+		{Unknown("reason", "message"), codes.Unknown},
+		{Internal("reason", "message"), codes.Internal},
+		{DataLoss("reason", "message"), codes.DataLoss},
+		{New(409, "reason", "message"), codes.AlreadyExists},
+	}
+	for _, test := range tests {
+		gs := FromError(test.err).GRPCStatus()
+		if gs.Code() != test.code {
+			t.Errorf("expected %s, but got %s", test.code, gs.Code())
+		}
+		// the restored error keeps the code to the next hop.
+		if se := FromGRPCStatus(gs); se.GRPCStatus().Code() != test.code || se.Code != int32(Code(test.err)) {
+			t.Errorf("expected %s restored, but got %s of %d", test.code, se.GRPCStatus().Code(), se.Code)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"errors"
+	"net"
+)
+
+// retryable is the retryable marker set by WithRetryable.
+type retryable int8
+
+const (
+	retryableUnset retryable = iota
+	retryableTrue
+	retryableFalse
+)
+
+// WithRetryable returns a copy of the error marked as retryable or not, which overrides
+// the classification by the code, i.e., a 429 of a quota permanently exhausted. The marker
+// is local to the process, it is never carried to the clients.
+func (e *Error) WithRetryable(ok bool) *Error {
+	err := *e
+	err.retryable = retryableFalse
+	if ok {
+		err.retryable = retryableTrue
+	}
+	return &err
+}
+
+// IsRetryable reports whether the request failed by the error is safe to retry. The marker
+// set by WithRetryable wins, then the timeouts of net.Error in the chain are retryable, and
+// then the 503, 429 and 504 errors.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if se := new(Error); errors.As(err, &se) && se.retryable != retryableUnset {
+		return se.retryable == retryableTrue
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	switch Code(err) {
+	case 503, 429, 504:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// errorsPackage is the function prefix of the errors package, whose frames are skipped.
+	errorsPackage = "github.com/go-kratos/kratos/v2/errors."
+	// maxStackDepth is the max number of the frames captured.
+	maxStackDepth = 32
+)
+
+var stackEnabled int32
+
+// EnableStack enables or disables capturing the stack trace when an error is created by
+// New, Newf, Errorf and the typed constructors, or wrapped by WithCause, disabled by default.
+// The stack trace is never serialized to the clients.
+func EnableStack(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&stackEnabled, v)
+}
+
+// Frame is a frame of the stack trace.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f Frame) String() string {
+	return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// callers returns the program counters of the call site, nil if the capture is disabled.
+// Only the program counters are captured, the frames are resolved by StackTrace lazily.
+func callers() []uintptr {
+	if atomic.LoadInt32(&stackEnabled) == 0 {
+		return nil
+	}
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(3, pcs[:])
+	stack := make([]uintptr, n)
+	copy(stack, pcs[:n])
+	return stack
+}
+
+// StackTrace returns the stack trace of the first error in the chain which has one,
+// nil if the capture was disabled. The frames in the errors package are skipped.
+func StackTrace(err error) []Frame {
+	for ; err != nil; err = errors.Unwrap(err) {
+		se, ok := err.(*Error)
+		if !ok || len(se.stack) == 0 {
+			continue
+		}
+		var res []Frame
+		frames := runtime.CallersFrames(se.stack)
+		for {
+			frame, more := frames.Next()
+			if len(res) > 0 || !isErrorsFrame(frame) {
+				res = append(res, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+			}
+			if !more {
+				break
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// isErrorsFrame reports whether the frame is in the errors package, its tests are callers as the user code.
+func isErrorsFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, errorsPackage) && !strings.HasSuffix(frame.File, "_test.go")
+}
@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStackTrace(t *testing.T) {
+	if StackTrace(NotFound("USER_NOT_FOUND", "user not found")) != nil {
+		t.Fatal("expected no stack trace when disabled")
+	}
+	EnableStack(true)
+	defer EnableStack(false)
+
+	err := InternalServer("DB", "database error")
+	frames := StackTrace(fmt.Errorf("wrap: %w", err))
+	if len(frames) == 0 || !strings.HasSuffix(frames[0].Function, "errors.TestStackTrace") {
+		t.Fatalf("expected the stack trace from the test, but got %v", frames)
+	}
+	if StackTrace(FromError(errors.New("boom"))) != nil {
+		t.Error("expected no stack trace for the converted errors")
+	}
+	data, _ := json.Marshal(err)
+	if strings.Contains(string(data), "TestStackTrace") {
+		t.Errorf("expected the stack trace never serialized, but got %s", data)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New(500, "DB", "database error")
+	}
+}
+
+func BenchmarkNewWithStack(b *testing.B) {
+	EnableStack(true)
+	defer EnableStack(false)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New(500, "DB", "database error")
+	}
+}
@@ -0,0 +1,121 @@
+package errors
+
+// BadRequest The request is malformed or invalid.
+// HTTP Mapping: 400 Bad Request
+func BadRequest(reason, format string, a ...interface{}) *Error {
+	return Newf(400, reason, format, a...)
+}
+
+// IsBadRequest determines if err is an error which indicates a 400 error.
+// It supports wrapped errors.
+func IsBadRequest(err error) bool {
+	return Code(err) == 400
+}
+
+// Unauthorized The request does not have valid authentication credentials for the operation.
+// HTTP Mapping: 401 Unauthorized
+func Unauthorized(reason, format string, a ...interface{}) *Error {
+	return Newf(401, reason, format, a...)
+}
+
+// IsUnauthorized determines if err is an error which indicates a 401 error.
+// It supports wrapped errors.
+func IsUnauthorized(err error) bool {
+	return Code(err) == 401
+}
+
+// Forbidden The caller does not have permission to execute the specified operation.
+// HTTP Mapping: 403 Forbidden
+func Forbidden(reason, format string, a ...interface{}) *Error {
+	return Newf(403, reason, format, a...)
+}
+
+// IsForbidden determines if err is an error which indicates a 403 error.
+// It supports wrapped errors.
+func IsForbidden(err error) bool {
+	return Code(err) == 403
+}
+
+// NotFound Some requested entity (e.g., file or directory) was not found.
+// HTTP Mapping: 404 Not Found
+func NotFound(reason, format string, a ...interface{}) *Error {
+	return Newf(404, reason, format, a...)
+}
+
+// IsNotFound determines if err is an error which indicates a 404 error.
+// It supports wrapped errors.
+func IsNotFound(err error) bool {
+	return Code(err) == 404
+}
+
+// Conflict The request conflicts with the current state of the target resource, i.e., it already exists.
+// HTTP Mapping: 409 Conflict
+func Conflict(reason, format string, a ...interface{}) *Error {
+	return Newf(409, reason, format, a...)
+}
+
+// IsConflict determines if err is an error which indicates a 409 error.
+// It supports wrapped errors.
+func IsConflict(err error) bool {
+	return Code(err) == 409
+}
+
+// TooManyRequests Some resource has been exhausted, perhaps a rate limit or a per-user quota.
+// HTTP Mapping: 429 Too Many Requests
+func TooManyRequests(reason, format string, a ...interface{}) *Error {
+	return Newf(429, reason, format, a...)
+}
+
+// IsTooManyRequests determines if err is an error which indicates a 429 error.
+// It supports wrapped errors.
+func IsTooManyRequests(err error) bool {
+	return Code(err) == 429
+}
+
+// ClientClosed The request was cancelled, typically by the caller.
+// HTTP Mapping: 499 Client Closed Request
+func ClientClosed(reason, format string, a ...interface{}) *Error {
+	return Newf(499, reason, format, a...)
+}
+
+// IsClientClosed determines if err is an error which indicates a 499 error.
+// It supports wrapped errors.
+func IsClientClosed(err error) bool {
+	return Code(err) == 499
+}
+
+// InternalServer Some invariants expected by the underlying system have been broken.
+// HTTP Mapping: 500 Internal Server Error
+func InternalServer(reason, format string, a ...interface{}) *Error {
+	return Newf(500, reason, format, a...)
+}
+
+// IsInternalServer determines if err is an error which indicates a 500 error.
+// It supports wrapped errors.
+func IsInternalServer(err error) bool {
+	return Code(err) == 500
+}
+
+// ServiceUnavailable The service is currently unavailable.
+// HTTP Mapping: 503 Service Unavailable
+func ServiceUnavailable(reason, format string, a ...interface{}) *Error {
+	return Newf(503, reason, format, a...)
+}
+
+// IsServiceUnavailable determines if err is an error which indicates a 503 error.
+// It supports wrapped errors.
+func IsServiceUnavailable(err error) bool {
+	return Code(err) == 503
+}
+
+// GatewayTimeout The deadline expired before the operation could complete.
+// HTTP Mapping: 504 Gateway Timeout
+func GatewayTimeout(reason, format string, a ...interface{}) *Error {
+	return Newf(504, reason, format, a...)
+}
+
+// IsGatewayTimeout determines if err is an error which indicates a 504 error.
+// It supports wrapped errors.
+func IsGatewayTimeout(err error) bool {
+	return Code(err) == 504
+}
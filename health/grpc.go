@@ -0,0 +1,37 @@
+package health
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type grpcService struct {
+	grpc_health_v1.UnimplementedHealthServer
+	r *Registry
+}
+
+// NewGRPCService returns the gRPC health service of the registry, whose empty service is
+// the overall readiness, and whose other services are the checks of the names.
+func NewGRPCService(r *Registry) grpc_health_v1.HealthServer {
+	return &grpcService{r: r}
+}
+
+func (s *grpcService) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	res := s.r.Check(ctx)
+	ready := res.Ready
+	if name := req.GetService(); name != "" {
+		if !s.r.has(name) {
+			return nil, status.Errorf(codes.NotFound, "unknown service %s", name)
+		}
+		_, failed := res.Errors[name]
+		_, shutdown := res.Errors["shutdown"]
+		ready = !failed && !shutdown
+	}
+	if ready {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+}
@@ -0,0 +1,200 @@
+// Package health aggregates the readiness checks of the components into a single
+// readiness, which the HTTP readiness handler and the gRPC health service read from:
+//
+//	app := kratos.New(kratos.Server(hs, gs))
+//	app.Health().Register("db", func(ctx context.Context) error { return db.PingContext(ctx) })
+//	hs.Handle("/readyz", app.Health())
+//	grpc_health_v1.RegisterHealthServer(gs, health.NewGRPCService(app.Health()))
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrShutdown is the error of the checks once the registry is shut down.
+var ErrShutdown = errors.New("health: shutting down")
+
+// Check is a readiness check, which returns nil if ready.
+type Check func(ctx context.Context) error
+
+// Option is registry option.
+type Option func(*Registry)
+
+// WithTimeout with the timeout of each check, the checks exceeding it fail, 1s by default.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+// WithTTL with the duration the result is cached for, so that the probe storms never
+// overload the dependencies, 1s by default.
+func WithTTL(d time.Duration) Option {
+	return func(r *Registry) {
+		r.ttl = d
+	}
+}
+
+// Result is the aggregated result of the checks.
+type Result struct {
+	// Ready reports whether all the checks passed.
+	Ready bool
+	// Errors is the errors of the failed checks by the names.
+	Errors map[string]error
+}
+
+// Registry is the registry of the named readiness checks.
+type Registry struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	checks   map[string]Check
+	shutdown bool
+
+	// refresh serializes running the checks, so that the concurrent probes share a run.
+	refresh sync.Mutex
+	result  *Result
+	expires time.Time
+	// gen is bumped once the checks change, so that the result of the previous ones is dropped.
+	gen int
+}
+
+// NewRegistry new a registry of the readiness checks.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		timeout: time.Second,
+		ttl:     time.Second,
+		checks:  make(map[string]Check),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Register registers the check of the name, which replaces the check of the same name,
+// and a nil check removes it.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if check == nil {
+		delete(r.checks, name)
+	} else {
+		r.checks[name] = check
+	}
+	r.expire()
+}
+
+// Shutdown turns the registry not ready at once and for good, i.e., once the app begins stopping.
+func (r *Registry) Shutdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shutdown = true
+	r.expire()
+}
+
+func (r *Registry) expire() {
+	r.result = nil
+	r.gen++
+}
+
+// Check runs the checks concurrently, each within the timeout, and returns the result,
+// which is cached for the TTL.
+func (r *Registry) Check(ctx context.Context) Result {
+	r.refresh.Lock()
+	defer r.refresh.Unlock()
+	r.mu.Lock()
+	if r.shutdown {
+		r.mu.Unlock()
+		return Result{Errors: map[string]error{"shutdown": ErrShutdown}}
+	}
+	if r.result != nil && time.Now().Before(r.expires) {
+		res := *r.result
+		r.mu.Unlock()
+		return res
+	}
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	gen := r.gen
+	r.mu.Unlock()
+
+	res := Result{Ready: true, Errors: make(map[string]error)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			if err := r.run(ctx, check); err != nil {
+				mu.Lock()
+				res.Ready = false
+				res.Errors[name] = err
+				mu.Unlock()
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.shutdown {
+		return Result{Errors: map[string]error{"shutdown": ErrShutdown}}
+	}
+	if gen == r.gen {
+		r.result, r.expires = &res, time.Now().Add(r.ttl)
+	}
+	return res
+}
+
+// run runs the check within the timeout, even if the check ignores the context.
+func (r *Registry) run(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health: check timed out: %w", ctx.Err())
+	}
+}
+
+// ServeHTTP serves the readiness probe, which replies 200 if ready, otherwise 503, with
+// the errors of the failed checks, i.e., {"status": "not ready", "errors": {"db": "..."}}.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	res := r.Check(req.Context())
+	body := struct {
+		Status string            `json:"status"`
+		Errors map[string]string `json:"errors,omitempty"`
+	}{Status: "ready"}
+	code := http.StatusOK
+	if !res.Ready {
+		body.Status, code = "not ready", http.StatusServiceUnavailable
+		body.Errors = make(map[string]string, len(res.Errors))
+		for name, err := range res.Errors {
+			body.Errors[name] = err.Error()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (r *Registry) has(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.checks[name]
+	return ok
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRegistry(t *testing.T) {
+	var calls int32
+	r := NewRegistry(WithTimeout(50*time.Millisecond), WithTTL(time.Hour))
+	r.Register("db", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	if res := r.Check(context.Background()); !res.Ready {
+		t.Fatalf("expected ready, but got %v", res.Errors)
+	}
+	r.Check(context.Background())
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected the result cached, but got %d calls", n)
+	}
+
+	// a hung check fails by the timeout, without blocking the others.
+	hang := make(chan struct{})
+	defer close(hang)
+	r.Register("cache", func(ctx context.Context) error {
+		<-hang
+		return nil
+	})
+	r.Register("mq", func(ctx context.Context) error { return errors.New("unreachable") })
+	start := time.Now()
+	res := r.Check(context.Background())
+	if res.Ready || len(res.Errors) != 2 || res.Errors["db"] != nil || time.Since(start) > time.Second {
+		t.Errorf("expected the cache and the mq failed, but got %v", res.Errors)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, but got %d %s", w.Code, w.Body)
+	}
+	svc := NewGRPCService(r)
+	for name, want := range map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+		"":   grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		"db": grpc_health_v1.HealthCheckResponse_SERVING,
+		"mq": grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+	} {
+		reply, err := svc.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: name})
+		if err != nil || reply.Status != want {
+			t.Errorf("%q: expected %s, but got %v %v", name, want, reply, err)
+		}
+	}
+	if _, err := svc.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Error("expected the unknown service rejected")
+	}
+
+	r.Register("cache", nil)
+	r.Register("mq", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, but got %d %s", w.Code, w.Body)
+	}
+	// the shutdown turns not ready at once, regardless of the cache.
+	r.Shutdown()
+	if res := r.Check(context.Background()); res.Ready || !errors.Is(res.Errors["shutdown"], ErrShutdown) {
+		t.Errorf("expected not ready once shut down, but got %v", res.Errors)
+	}
+}
@@ -0,0 +1,144 @@
+package cpu
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procCgroup lists the cgroups of the process, one "$ID:$CONTROLLERS:$PATH" per line,
+// the only line of the cgroup v2 is "0::$PATH".
+const procCgroup = "/proc/self/cgroup"
+
+// cgroup reads the CPU accounting of the cgroup of the process, the files are read
+// by readFile, so that the tests fake them.
+type cgroup struct {
+	// root is the mount point of the cgroup file system, i.e., /sys/fs/cgroup.
+	root     string
+	readFile func(name string) ([]byte, error)
+}
+
+// source returns the usage reader and the cores of the cgroup of the process, the v2
+// accounting is preferred over the v1 one, false if there is none.
+func (c cgroup) source() (func() (time.Duration, error), float64, bool) {
+	v2, v1 := c.paths()
+	if dir, ok := c.dir(v2, "", "cpu.stat"); ok {
+		stat := path.Join(dir, "cpu.stat")
+		return func() (time.Duration, error) { return c.readStat(stat) }, c.v2Cores(path.Join(dir, "cpu.max")), true
+	}
+	if dir, ok := c.dir(v1["cpuacct"], "cpuacct", "cpuacct.usage"); ok {
+		usage := path.Join(dir, "cpuacct.usage")
+		var cores float64
+		if cpu, ok := c.dir(v1["cpu"], "cpu", "cpu.cfs_quota_us"); ok {
+			cores = c.v1Cores(path.Join(cpu, "cpu.cfs_quota_us"), path.Join(cpu, "cpu.cfs_period_us"))
+		}
+		return func() (time.Duration, error) {
+			ns, err := c.readInt(usage)
+			return time.Duration(ns), err
+		}, cores, true
+	}
+	return nil, 0, false
+}
+
+// paths returns the path of the cgroup v2, and the paths of the cgroup v1 by controller.
+func (c cgroup) paths() (string, map[string]string) {
+	var v2 string
+	v1 := make(map[string]string)
+	data, err := c.readFile(procCgroup)
+	if err != nil {
+		return v2, v1
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			v2 = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			v1[controller] = fields[2]
+		}
+	}
+	return v2, v1
+}
+
+// dir returns the directory of the cgroup under the mount of the controller which has the
+// file, the mount itself is the cgroup of the process in a cgroup namespace, i.e., in the
+// containers, whose path is relative to the root of the host.
+func (c cgroup) dir(cgroupPath, controller, file string) (string, bool) {
+	mount := path.Join(c.root, controller)
+	dirs := []string{mount}
+	if cgroupPath != "" && cgroupPath != "/" {
+		dirs = []string{path.Join(mount, cgroupPath), mount}
+	}
+	for _, dir := range dirs {
+		if _, err := c.readFile(path.Join(dir, file)); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+func (c cgroup) readStat(name string) (time.Duration, error) {
+	data, err := c.readFile(name)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(usec) * time.Microsecond, nil
+		}
+	}
+	return 0, ErrNotSupported
+}
+
+// v2Cores parses "$MAX $PERIOD", where $MAX is "max" without a quota.
+func (c cgroup) v2Cores(name string) float64 {
+	data, err := c.readFile(name)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || period <= 0 {
+		return 0
+	}
+	return quota / period
+}
+
+// v1Cores returns the quota over the period, the quota is -1 without a limit.
+func (c cgroup) v1Cores(quotaName, periodName string) float64 {
+	quota, err := c.readInt(quotaName)
+	if err != nil || quota <= 0 {
+		return 0
+	}
+	period, err := c.readInt(periodName)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return float64(quota) / float64(period)
+}
+
+func (c cgroup) readInt(name string) (int64, error) {
+	data, err := c.readFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,86 @@
+package cpu
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// files fakes the cgroup and the proc file systems.
+type files map[string]string
+
+func (f files) ReadFile(name string) ([]byte, error) {
+	if data, ok := f[name]; ok {
+		return []byte(data), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func TestCgroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		files files
+		usage time.Duration
+		cores float64
+	}{
+		{
+			name: "v2",
+			files: files{
+				procCgroup:                              "0::/kubepods/pod1\n",
+				"/sys/fs/cgroup/kubepods/pod1/cpu.stat": "usage_usec 1500\nuser_usec 1000\n",
+				"/sys/fs/cgroup/kubepods/pod1/cpu.max":  "200000 100000\n",
+				"/sys/fs/cgroup/cpu.stat":               "usage_usec 9999\n",
+			},
+			usage: 1500 * time.Microsecond,
+			cores: 2,
+		},
+		{
+			name: "v2 namespace",
+			files: files{
+				procCgroup:                "0::/kubepods/pod1\n",
+				"/sys/fs/cgroup/cpu.stat": "usage_usec 1500\n",
+				"/sys/fs/cgroup/cpu.max":  "max 100000\n",
+			},
+			usage: 1500 * time.Microsecond,
+		},
+		{
+			name: "v1",
+			files: files{
+				procCgroup: "4:memory:/docker/1\n3:cpu,cpuacct:/docker/1\n0::/\n",
+				"/sys/fs/cgroup/cpuacct/docker/1/cpuacct.usage": "2000\n",
+				"/sys/fs/cgroup/cpu/docker/1/cpu.cfs_quota_us":  "50000\n",
+				"/sys/fs/cgroup/cpu/docker/1/cpu.cfs_period_us": "100000\n",
+			},
+			usage: 2000,
+			cores: 0.5,
+		},
+		{
+			name: "v1 unlimited",
+			files: files{
+				procCgroup:                             "3:cpuacct:/\n2:cpu:/\n",
+				"/sys/fs/cgroup/cpuacct/cpuacct.usage": "2000\n",
+				"/sys/fs/cgroup/cpu/cpu.cfs_quota_us":  "-1\n",
+				"/sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n",
+			},
+			usage: 2000,
+		},
+	}
+	for _, test := range tests {
+		read, cores, ok := cgroup{root: "/sys/fs/cgroup", readFile: test.files.ReadFile}.source()
+		if !ok {
+			t.Errorf("%s: expected the cgroup found", test.name)
+			continue
+		}
+		if usage, err := read(); err != nil || usage != test.usage || cores != test.cores {
+			t.Errorf("%s: expected %s of %v cores, but got %s of %v cores %v", test.name, test.usage, test.cores, usage, cores, err)
+		}
+	}
+	if _, _, ok := (cgroup{root: "/sys/fs/cgroup", readFile: files{procCgroup: "0::/\n"}.ReadFile}).source(); ok {
+		t.Error("expected no cgroup without the accounting")
+	}
+	stat := files{procCgroup: "0::/\n", "/sys/fs/cgroup/cpu.stat": "user_usec 1000\n"}
+	read, _, _ := cgroup{root: "/sys/fs/cgroup", readFile: stat.ReadFile}.source()
+	if _, err := read(); err != ErrNotSupported {
+		t.Errorf("expected %v without the usage, but got %v", ErrNotSupported, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package cpu samples the CPU usage of the process relative to its CPU limit,
+// which is the cgroup quota if set, or the number of CPUs otherwise.
+package cpu
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrNotSupported is returned when no CPU usage source is available.
+var ErrNotSupported = errors.New("cpu: usage is not supported on this platform")
+
+// Sampler samples the CPU usage.
+type Sampler struct {
+	mu    sync.Mutex
+	read  func() (time.Duration, error)
+	now   func() time.Time
+	cores float64
+	last  time.Duration
+	at    time.Time
+}
+
+// NewSampler new a CPU usage sampler, the cgroup v2 and v1 accounting of the cgroup of
+// the process is preferred over the usage of the process. It returns ErrNotSupported on
+// the platforms other than Linux.
+func NewSampler() (*Sampler, error) {
+	read, cores, err := source()
+	if err != nil {
+		return nil, err
+	}
+	return newSampler(read, cores, time.Now)
+}
+
+// newSampler new a sampler of the usage reader, the cores are the number of CPUs if unlimited.
+func newSampler(read func() (time.Duration, error), cores float64, now func() time.Time) (*Sampler, error) {
+	if cores <= 0 {
+		cores = float64(runtime.NumCPU())
+	}
+	usage, err := read()
+	if err != nil {
+		return nil, err
+	}
+	return &Sampler{read: read, now: now, cores: cores, last: usage, at: now()}, nil
+}
+
+// Usage returns the fraction of the CPU limit used since the last call, in [0, 1].
+func (s *Sampler) Usage() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	elapsed := now.Sub(s.at)
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	u := float64(usage-s.last) / (float64(elapsed) * s.cores)
+	s.last, s.at = usage, now
+	if u < 0 {
+		u = 0
+	}
+	if u > 1 {
+		u = 1
+	}
+	return u, nil
+}
@@ -0,0 +1,22 @@
+package cpu
+
+import (
+	"io/ioutil"
+	"syscall"
+	"time"
+)
+
+func source() (func() (time.Duration, error), float64, error) {
+	if read, cores, ok := (cgroup{root: "/sys/fs/cgroup", readFile: ioutil.ReadFile}).source(); ok {
+		return read, cores, nil
+	}
+	return readProcess, 0, nil
+}
+
+func readProcess() (time.Duration, error) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, err
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package cpu
+
+import "time"
+
+func source() (func() (time.Duration, error), float64, error) {
+	return nil, 0, ErrNotSupported
+}
@@ -0,0 +1,35 @@
+package cpu
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	var (
+		usage time.Duration
+		err   error
+		now   = time.Unix(0, 0)
+	)
+	s, _ := newSampler(func() (time.Duration, error) { return usage, err }, 2, func() time.Time { return now })
+	for _, test := range []struct {
+		used, elapsed time.Duration
+		want          float64
+	}{
+		{used: time.Second, elapsed: time.Second, want: 0.5},
+		{used: 4 * time.Second, elapsed: time.Second, want: 1},
+		{used: 0, elapsed: time.Second, want: 0},
+		{used: time.Second, elapsed: 0, want: 0},
+	} {
+		usage += test.used
+		now = now.Add(test.elapsed)
+		if u, err := s.Usage(); err != nil || u != test.want {
+			t.Errorf("expected %v of %s used in %s, but got %v %v", test.want, test.used, test.elapsed, u, err)
+		}
+	}
+	err = errors.New("read failed")
+	if _, got := s.Usage(); got != err {
+		t.Errorf("expected %v, but got %v", err, got)
+	}
+}
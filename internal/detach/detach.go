@@ -0,0 +1,32 @@
+// Package detach provides the contexts detached from the cancellation of their parents,
+// which keep the values of the parents, i.e., for the work outliving the request.
+package detach
+
+import (
+	"context"
+	"time"
+)
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// Context returns a context with the values of parent, which is never canceled.
+func Context(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+// WithDeadline returns a context detached from the cancellation of parent,
+// the deadline of parent still applies so that the work is bounded.
+func WithDeadline(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx := Context(parent)
+	if deadline, ok := parent.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithCancel(ctx)
+}
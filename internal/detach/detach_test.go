@@ -0,0 +1,29 @@
+package detach
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type key struct{}
+
+func TestWithDeadline(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Minute)
+	ctx, stop := WithDeadline(parent)
+	defer stop()
+	cancel()
+	if ctx.Err() != nil {
+		t.Errorf("expected the context not canceled with parent, got %v", ctx.Err())
+	}
+	if ctx.Value(key{}) != "value" {
+		t.Errorf("expected the values of parent")
+	}
+	want, _ := parent.Deadline()
+	if got, ok := ctx.Deadline(); !ok || !got.Equal(want) {
+		t.Errorf("expected the deadline %v, got %v", want, got)
+	}
+	if _, ok := Context(parent).Deadline(); ok {
+		t.Errorf("expected no deadline")
+	}
+}
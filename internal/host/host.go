@@ -0,0 +1,66 @@
+// Package host resolves the addresses of the listeners which the clients can dial.
+package host
+
+import (
+	"net"
+)
+
+// interfaceAddrs returns the addresses of the interfaces which are up, except the loopback ones.
+var interfaceAddrs = func() ([]net.Addr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var addrs []net.Addr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		as, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, as...)
+	}
+	return addrs, nil
+}
+
+// Extract returns the host:port of the listen address, whose unspecified host, i.e.,
+// [::] or 0.0.0.0, is replaced by the IP of an interface, IPv4 preferred. The loopback
+// IP is used if there is no interface.
+func Extract(hostPort string) (string, error) {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return hostPort, nil
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	var v6 net.IP
+	for _, addr := range addrs {
+		var ip net.IP
+		switch a := addr.(type) {
+		case *net.IPNet:
+			ip = a.IP
+		case *net.IPAddr:
+			ip = a.IP
+		}
+		if ip == nil || !ip.IsGlobalUnicast() {
+			continue
+		}
+		if ip.To4() != nil {
+			return net.JoinHostPort(ip.String(), port), nil
+		}
+		if v6 == nil {
+			v6 = ip
+		}
+	}
+	if v6 != nil {
+		return net.JoinHostPort(v6.String(), port), nil
+	}
+	return net.JoinHostPort("127.0.0.1", port), nil
+}
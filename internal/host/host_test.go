@@ -0,0 +1,49 @@
+package host
+
+import (
+	"net"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	defer func(fn func() ([]net.Addr, error)) { interfaceAddrs = fn }(interfaceAddrs)
+	addrs := func(cidrs ...string) func() ([]net.Addr, error) {
+		return func() ([]net.Addr, error) {
+			var as []net.Addr
+			for _, cidr := range cidrs {
+				ip, ipnet, err := net.ParseCIDR(cidr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ipnet.IP = ip
+				as = append(as, ipnet)
+			}
+			return as, nil
+		}
+	}
+	tests := []struct {
+		hostPort string
+		addrs    []string
+		want     string
+	}{
+		{"10.0.0.1:8000", []string{"192.168.1.2/24"}, "10.0.0.1:8000"},
+		{"localhost:8000", []string{"192.168.1.2/24"}, "localhost:8000"},
+		{"[::]:8000", []string{"fe80::1/64", "2001:db8::1/64", "192.168.1.2/24"}, "192.168.1.2:8000"},
+		{"0.0.0.0:8000", []string{"fe80::1/64", "2001:db8::1/64"}, "[2001:db8::1]:8000"},
+		{":8000", []string{"169.254.0.1/16"}, "127.0.0.1:8000"},
+		{":8000", nil, "127.0.0.1:8000"},
+	}
+	for _, test := range tests {
+		interfaceAddrs = addrs(test.addrs...)
+		got, err := Extract(test.hostPort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("Extract(%q) with %v: expected %s, but got %s", test.hostPort, test.addrs, test.want, got)
+		}
+	}
+	if _, err := Extract("8000"); err == nil {
+		t.Errorf("expected the error of the address without port")
+	}
+}
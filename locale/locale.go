@@ -0,0 +1,23 @@
+// Package locale carries the locale of the request in the contexts, and the translator of
+// the error messages consulted by the error encoders of the transports. It is negotiated
+// by the i18n middleware, and it imports neither the transports nor the middleware.
+package locale
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+type localeKey struct{}
+
+// NewContext returns a new Context that carries the locale.
+func NewContext(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey{}, tag)
+}
+
+// FromContext returns the locale stored in ctx, if any.
+func FromContext(ctx context.Context) (tag language.Tag, ok bool) {
+	tag, ok = ctx.Value(localeKey{}).(language.Tag)
+	return
+}
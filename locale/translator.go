@@ -0,0 +1,98 @@
+package locale
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync/atomic"
+
+	"golang.org/x/text/language"
+)
+
+// Translator translates the message of the error reason into the locale.
+type Translator interface {
+	Translate(tag language.Tag, reason string) (string, bool)
+}
+
+// translatorAppliance keeps the concrete type stored in the atomic value consistent.
+type translatorAppliance struct {
+	Translator
+}
+
+var global atomic.Value
+
+func init() {
+	global.Store(translatorAppliance{})
+}
+
+// RegisterTranslator registers the translator of the error messages, which is
+// consulted by the error encoders of the transports, nil to unregister it.
+func RegisterTranslator(t Translator) {
+	global.Store(translatorAppliance{t})
+}
+
+// GetTranslator returns the registered translator, nil if there is none.
+func GetTranslator() Translator {
+	return global.Load().(translatorAppliance).Translator
+}
+
+// Messages is the messages keyed by the locale and then by the error reason.
+type Messages map[string]map[string]string
+
+type translator struct {
+	fallback language.Tag
+	messages map[language.Tag]map[string]string
+}
+
+// NewTranslator returns a translator of the messages. A locale falls back to its
+// parents, i.e., zh-CN to zh, and then to the fallback locale.
+func NewTranslator(fallback language.Tag, messages Messages) Translator {
+	t := &translator{
+		fallback: fallback,
+		messages: make(map[language.Tag]map[string]string, len(messages)),
+	}
+	for locale, m := range messages {
+		tag := language.Make(locale)
+		if t.messages[tag] == nil {
+			t.messages[tag] = make(map[string]string, len(m))
+		}
+		for reason, msg := range m {
+			t.messages[tag][reason] = msg
+		}
+	}
+	return t
+}
+
+// LoadTranslator returns a translator of the messages in the json files, which are
+// objects keyed by the locale and then by the error reason. The later files win.
+func LoadTranslator(fallback language.Tag, paths ...string) (Translator, error) {
+	messages := make(Messages)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var m Messages
+		if err = json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		for locale, msgs := range m {
+			if messages[locale] == nil {
+				messages[locale] = make(map[string]string, len(msgs))
+			}
+			for reason, msg := range msgs {
+				messages[locale][reason] = msg
+			}
+		}
+	}
+	return NewTranslator(fallback, messages), nil
+}
+
+func (t *translator) Translate(tag language.Tag, reason string) (string, bool) {
+	for ; tag != language.Und; tag = tag.Parent() {
+		if msg, ok := t.messages[tag][reason]; ok {
+			return msg, true
+		}
+	}
+	msg, ok := t.messages[t.fallback][reason]
+	return msg, ok
+}
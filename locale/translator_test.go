@@ -0,0 +1,61 @@
+package locale
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestTranslator(t *testing.T) {
+	tr := NewTranslator(language.English, Messages{
+		"en":    {"USER_NOT_FOUND": "user not found", "LOCKED": "account locked"},
+		"zh":    {"USER_NOT_FOUND": "用户不存在"},
+		"zh-CN": {"LOCKED": "账户已锁定"},
+	})
+	tests := []struct {
+		locale string
+		reason string
+		msg    string
+		ok     bool
+	}{
+		{"zh-CN", "LOCKED", "账户已锁定", true},
+		{"zh-CN", "USER_NOT_FOUND", "用户不存在", true},
+		{"zh-TW", "USER_NOT_FOUND", "user not found", true},
+		{"ja", "LOCKED", "account locked", true},
+		{"und", "LOCKED", "account locked", true},
+		{"zh-CN", "UNKNOWN", "", false},
+	}
+	for _, test := range tests {
+		msg, ok := tr.Translate(language.Make(test.locale), test.reason)
+		if msg != test.msg || ok != test.ok {
+			t.Errorf("%s %s: expected %q %v, but got %q %v", test.locale, test.reason, test.msg, test.ok, msg, ok)
+		}
+	}
+}
+
+func TestLoadTranslator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base, override := filepath.Join(dir, "base.json"), filepath.Join(dir, "override.json")
+	ioutil.WriteFile(base, []byte(`{"en":{"A":"a"},"zh":{"A":"甲","B":"乙"}}`), 0644)
+	ioutil.WriteFile(override, []byte(`{"zh":{"B":"已覆盖"}}`), 0644)
+	tr, err := LoadTranslator(language.English, base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := tr.Translate(language.Make("zh-CN"), "A"); msg != "甲" {
+		t.Errorf("expected 甲, but got %q", msg)
+	}
+	if msg, _ := tr.Translate(language.Chinese, "B"); msg != "已覆盖" {
+		t.Errorf("expected the later file wins, but got %q", msg)
+	}
+	if _, err = LoadTranslator(language.English, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for the missing file")
+	}
+}
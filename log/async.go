@@ -0,0 +1,149 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var _ Logger = (*Async)(nil)
+
+// ErrAsyncClosed is returned when logging to a closed async logger.
+var ErrAsyncClosed = errors.New("log: async logger closed")
+
+// AsyncOption is async logger option.
+type AsyncOption func(*Async)
+
+// AsyncBuffer with the number of the entries buffered.
+func AsyncBuffer(n int) AsyncOption {
+	return func(a *Async) {
+		a.size = n
+	}
+}
+
+// AsyncDrop with the entries dropped instead of blocking when the buffer is full.
+func AsyncDrop() AsyncOption {
+	return func(a *Async) {
+		a.drop = true
+	}
+}
+
+type entry struct {
+	level  Level
+	kvpair []interface{}
+}
+
+// Async is a logger that writes the entries in a background goroutine.
+type Async struct {
+	logger  Logger
+	size    int
+	drop    bool
+	dropped uint64
+
+	// inflight is the number of the Log calls in progress, closing is closed by Close
+	// so that the producers blocked on the full queue give up without any lock.
+	inflight int64
+	once     sync.Once
+	closing  chan struct{}
+	queue    chan entry
+	done     chan struct{}
+}
+
+// NewAsync new an async logger, by default 1024 entries are buffered and
+// the callers block when the buffer is full.
+func NewAsync(logger Logger, opts ...AsyncOption) *Async {
+	a := &Async{
+		logger:  logger,
+		size:    1024,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	a.queue = make(chan entry, a.size)
+	go a.run()
+	return a
+}
+
+// Log enqueues the entry, the Valuers are evaluated before it is enqueued.
+func (a *Async) Log(level Level, kvpair ...interface{}) error {
+	e := entry{level: level}
+	if l, ok := a.logger.(*logger); ok {
+		e.kvpair = l.merge(kvpair)
+	} else {
+		e.kvpair = append([]interface{}(nil), kvpair...)
+		if containsValuer(e.kvpair) {
+			bindValues(context.Background(), e.kvpair)
+		}
+	}
+	atomic.AddInt64(&a.inflight, 1)
+	defer atomic.AddInt64(&a.inflight, -1)
+	select {
+	case <-a.closing:
+		return ErrAsyncClosed
+	default:
+	}
+	if !a.drop {
+		select {
+		case a.queue <- e:
+			return nil
+		case <-a.closing:
+			return ErrAsyncClosed
+		}
+	}
+	select {
+	case a.queue <- e:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return nil
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	next := a.logger
+	if l, ok := next.(*logger); ok {
+		next = l.log
+	}
+	for {
+		select {
+		case e := <-a.queue:
+			next.Log(e.level, e.kvpair...)
+		case <-a.closing:
+			// the Log calls started before closing may still enqueue, drain until they return.
+			for {
+				select {
+				case e := <-a.queue:
+					next.Log(e.level, e.kvpair...)
+				default:
+					if atomic.LoadInt64(&a.inflight) == 0 && len(a.queue) == 0 {
+						return
+					}
+					runtime.Gosched()
+				}
+			}
+		}
+	}
+}
+
+// Dropped returns the number of the entries dropped since the buffer was full.
+func (a *Async) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Close stops accepting entries, and waits until the buffered ones are written
+// or ctx is done. The callers blocked on the full buffer get ErrAsyncClosed.
+func (a *Async) Close(ctx context.Context) error {
+	a.once.Do(func() {
+		close(a.closing)
+	})
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
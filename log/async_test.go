@@ -0,0 +1,145 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type slowLogger struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	entries [][]interface{}
+}
+
+func (l *slowLogger) Log(level Level, kvpair ...interface{}) error {
+	for start := time.Now(); time.Since(start) < l.delay; {
+	}
+	l.mu.Lock()
+	l.entries = append(l.entries, kvpair)
+	l.mu.Unlock()
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	sink := &slowLogger{delay: time.Millisecond}
+	var calls int
+	counter := Valuer(func(context.Context) interface{} {
+		calls++
+		return calls
+	})
+	logger := NewAsync(With(sink, "n", counter), AsyncBuffer(16))
+	for i := 0; i < 10; i++ {
+		logger.Log(LevelInfo, "msg", "test")
+	}
+	// the valuers are evaluated when the entries are enqueued.
+	if calls != 10 {
+		t.Errorf("expected 10 valuer calls, but got %d", calls)
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.entries) != 10 || sink.entries[9][1] != 10 {
+		t.Errorf("expected the buffered entries written on close, but got %v", sink.entries)
+	}
+	if err := logger.Log(LevelInfo, "msg", "test"); err != ErrAsyncClosed {
+		t.Errorf("expected ErrAsyncClosed, but got %v", err)
+	}
+}
+
+func TestAsyncDrop(t *testing.T) {
+	sink := &slowLogger{delay: 10 * time.Millisecond}
+	logger := NewAsync(sink, AsyncBuffer(1), AsyncDrop())
+	for i := 0; i < 10; i++ {
+		logger.Log(LevelInfo, "msg", "test")
+	}
+	if logger.Dropped() == 0 {
+		t.Errorf("expected the entries dropped")
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.entries)+int(logger.Dropped()) != 10 {
+		t.Errorf("expected %d entries written, but got %d", 10-logger.Dropped(), len(sink.entries))
+	}
+}
+
+type blockingLogger struct {
+	release chan struct{}
+	written chan struct{}
+}
+
+func (l *blockingLogger) Log(level Level, kvpair ...interface{}) error {
+	<-l.release
+	l.written <- struct{}{}
+	return nil
+}
+
+func TestAsyncCloseBlocked(t *testing.T) {
+	sink := &blockingLogger{release: make(chan struct{}), written: make(chan struct{}, 3)}
+	logger := NewAsync(sink, AsyncBuffer(1))
+	logger.Log(LevelInfo, "msg", "first")
+	// wait until the first entry is taken by the writer, which blocks in the sink.
+	for len(logger.queue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	logger.Log(LevelInfo, "msg", "buffered")
+	errc := make(chan error)
+	go func() {
+		errc <- logger.Log(LevelInfo, "msg", "blocked")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := logger.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the close timed out, but got %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err != ErrAsyncClosed {
+			t.Errorf("expected ErrAsyncClosed, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the blocked producer released by close")
+	}
+	close(sink.release)
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.written) != 2 {
+		t.Errorf("expected the buffered entries written, but got %d", len(sink.written))
+	}
+}
+
+func TestMultiLoggerCloseAsync(t *testing.T) {
+	sink := &slowLogger{delay: time.Millisecond}
+	logger := MultiLogger(NewAsync(sink))
+	for i := 0; i < 5; i++ {
+		logger.Log(LevelInfo, "msg", "test")
+	}
+	if err := logger.(interface{ Close() error }).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.entries) != 5 {
+		t.Errorf("expected the entries written on close, but got %d", len(sink.entries))
+	}
+}
+
+func BenchmarkSlowSync(b *testing.B) {
+	logger := &slowLogger{delay: 10 * time.Microsecond}
+	for i := 0; i < b.N; i++ {
+		logger.Log(LevelInfo, "msg", "test", "ts", DefaultTimestamp)
+	}
+}
+
+func BenchmarkSlowAsync(b *testing.B) {
+	logger := NewAsync(&slowLogger{delay: 10 * time.Microsecond}, AsyncDrop())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Log(LevelInfo, "msg", "test", "ts", DefaultTimestamp)
+	}
+	b.StopTimer()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	logger.Close(ctx)
+}
@@ -0,0 +1,189 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/jsonlog"
+)
+
+var _ log.Logger = (*Logger)(nil)
+
+const (
+	// FormatEnv is the environment variable selecting the logger of Env, i.e., LOG_FORMAT=console.
+	FormatEnv = "LOG_FORMAT"
+	// NoColorEnv is the environment variable disabling the colors, see https://no-color.org.
+	NoColorEnv = "NO_COLOR"
+)
+
+const (
+	reset = "\x1b[0m"
+	dim   = "\x1b[2m"
+)
+
+var colors = map[log.Level]string{
+	log.LevelDebug: "\x1b[36m",
+	log.LevelInfo:  "\x1b[32m",
+	log.LevelWarn:  "\x1b[33m",
+	log.LevelError: "\x1b[31m",
+	log.LevelFatal: "\x1b[35m",
+}
+
+// Option is console logger option.
+type Option func(*options)
+
+type options struct {
+	color        bool
+	timeLayout   string
+	messageKey   string
+	messageWidth int
+}
+
+// Color with whether the output is colorized, by default only a terminal without NO_COLOR is.
+func Color(color bool) Option {
+	return func(o *options) {
+		o.color = color
+	}
+}
+
+// TimeLayout with the layout of the entry timestamp.
+func TimeLayout(layout string) Option {
+	return func(o *options) {
+		o.timeLayout = layout
+	}
+}
+
+// MessageKey with the key whose value is printed as the message.
+func MessageKey(key string) Option {
+	return func(o *options) {
+		o.messageKey = key
+	}
+}
+
+// MessageWidth with the width the messages are padded to, so that the pairs are aligned.
+func MessageWidth(width int) Option {
+	return func(o *options) {
+		o.messageWidth = width
+	}
+}
+
+// Logger is a logger writing human-friendly lines for development.
+type Logger struct {
+	out  io.Writer
+	opts options
+	mu   sync.Mutex
+	pool *sync.Pool
+}
+
+// NewLogger new a console logger writing to w.
+func NewLogger(w io.Writer, opts ...Option) *Logger {
+	options := options{
+		color:        isTerminal(w) && os.Getenv(NoColorEnv) == "",
+		timeLayout:   "15:04:05.000",
+		messageKey:   "msg",
+		messageWidth: 40,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Logger{
+		out:  w,
+		opts: options,
+		pool: &sync.Pool{
+			New: func() interface{} {
+				return new(bytes.Buffer)
+			},
+		},
+	}
+}
+
+// Env returns a console logger if LOG_FORMAT is console or text, otherwise a JSON logger,
+// so that the binary logs JSON in production and human-friendly lines locally.
+func Env(w io.Writer, opts ...Option) log.Logger {
+	switch strings.ToLower(os.Getenv(FormatEnv)) {
+	case "console", "text":
+		return NewLogger(w, opts...)
+	}
+	return jsonlog.NewLogger(jsonlog.Writer(w))
+}
+
+// Log writes the entry as a line of the timestamp, the level, the message, and the pairs.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	buf := l.pool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		l.pool.Put(buf)
+	}()
+	l.colorize(buf, dim, time.Now().Format(l.opts.timeLayout))
+	buf.WriteByte(' ')
+	l.colorize(buf, colors[level], fmt.Sprintf("%-5s", level.String()))
+	buf.WriteByte(' ')
+	var msg string
+	pairs := make([]interface{}, 0, len(kvpair))
+	for i := 0; i < len(kvpair); i += 2 {
+		var v interface{}
+		if i+1 < len(kvpair) {
+			v = log.Value(context.Background(), kvpair[i+1])
+		}
+		if s, ok := v.(string); ok && msg == "" && kvpair[i] == l.opts.messageKey {
+			msg = s
+			continue
+		}
+		pairs = append(pairs, kvpair[i], v)
+	}
+	buf.WriteString(msg)
+	if len(pairs) > 0 {
+		if pad := l.opts.messageWidth - len(msg); pad > 0 {
+			buf.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		buf.WriteByte(' ')
+		l.colorize(buf, dim, fmt.Sprint(pairs[i])+"=")
+		buf.WriteString(formatValue(pairs[i+1]))
+	}
+	buf.WriteByte('\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.out.Write(buf.Bytes())
+	return err
+}
+
+func (l *Logger) colorize(buf *bytes.Buffer, color, s string) {
+	if !l.opts.color || color == "" {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(reset)
+}
+
+// formatValue quotes the values which would be ambiguous in the key=value pairs.
+func formatValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,42 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/jsonlog"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := NewLogger(buf, TimeLayout("15:04"), MessageWidth(8))
+	logger.Log(log.LevelWarn, "msg", "hello", "user", "kratos", "error", errors.New("not found"))
+	line := buf.String()
+	if strings.Contains(line, "\x1b[") {
+		t.Errorf("expected no colors if the writer is not a terminal, but got %q", line)
+	}
+	if !strings.HasSuffix(line, ` WARN  hello    user=kratos error="not found"`+"\n") {
+		t.Errorf("unexpected line %q", line)
+	}
+
+	buf.Reset()
+	NewLogger(buf, Color(true)).Log(log.LevelError, "msg", "failed")
+	if !strings.Contains(buf.String(), colors[log.LevelError]+"ERROR"+reset) {
+		t.Errorf("expected the colored level, but got %q", buf.String())
+	}
+}
+
+func TestEnv(t *testing.T) {
+	defer os.Unsetenv(FormatEnv)
+	if _, ok := Env(os.Stdout).(*jsonlog.Logger); !ok {
+		t.Errorf("expected the JSON logger by default")
+	}
+	os.Setenv(FormatEnv, "console")
+	if _, ok := Env(os.Stdout).(*Logger); !ok {
+		t.Errorf("expected the console logger if %s=console", FormatEnv)
+	}
+}
@@ -0,0 +1,33 @@
+package file
+
+import (
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/jsonlog"
+)
+
+var _ log.Logger = (*Logger)(nil)
+
+// Logger is a logger writing JSON lines to a rotating file.
+type Logger struct {
+	*jsonlog.Logger
+	w *Writer
+}
+
+// NewLogger new a file logger appending to the file of path.
+func NewLogger(path string, opts ...Option) (*Logger, error) {
+	w, err := NewWriter(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{Logger: jsonlog.NewLogger(jsonlog.Writer(w)), w: w}, nil
+}
+
+// Rotate rotates the file immediately.
+func (l *Logger) Rotate() error {
+	return l.w.Rotate()
+}
+
+// Close flushes the buffered entries and closes the file.
+func (l *Logger) Close() error {
+	return l.w.Close()
+}
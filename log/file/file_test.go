@@ -0,0 +1,166 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func readLines(t *testing.T, name string) []string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestLoggerRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kratos-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	logger, err := NewLogger(path, MaxSize(4096), Compress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				logger.Log(log.LevelInfo, "msg", fmt.Sprintf("%d-%d", i, j), "padding", strings.Repeat("x", 64))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(names) < 10 {
+		t.Fatalf("expected rotated files, but got %v", names)
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name != path && !strings.HasSuffix(name, ".gz") {
+			t.Errorf("expected compressed backup, but got %s", name)
+		}
+		for _, line := range readLines(t, name) {
+			var entry map[string]string
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("interleaved line in %s: %s", name, line)
+			}
+			seen[entry["msg"]] = true
+		}
+	}
+	if len(seen) != 1000 {
+		t.Errorf("expected 1000 entries, but got %d", len(seen))
+	}
+}
+
+func TestWriterMaxBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kratos-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(filepath.Join(dir, "app.log"), MaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	w.millBackups()
+	if backups := w.backups(); len(backups) != 2 {
+		t.Errorf("expected 2 backups, but got %v", backups)
+	}
+}
+
+func TestWriterRotateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kratos-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	w, err := NewWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	fmt.Fprintln(w, "before")
+	rename = func(string, string) error { return fmt.Errorf("rename failed") }
+	err = w.Rotate()
+	rename = os.Rename
+	if err == nil {
+		t.Fatal("expected the rotation failed")
+	}
+	// the writer keeps appending to the original path.
+	if _, err := fmt.Fprintln(w, "after"); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if lines := readLines(t, path); len(lines) != 2 || lines[0] != "before" || lines[1] != "after" {
+		t.Errorf("expected both lines in the file, but got %v", lines)
+	}
+	if backups := w.backups(); len(backups) != 0 {
+		t.Errorf("expected no backups, but got %v", backups)
+	}
+}
+
+func TestWriterIntervalIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kratos-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(filepath.Join(dir, "app.log"), Interval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fmt.Fprintln(w, "first")
+	if backups := w.backups(); len(backups) != 0 {
+		t.Errorf("expected no empty backups, but got %v", backups)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fmt.Fprintln(w, "second")
+	w.Close()
+	if backups := w.backups(); len(backups) != 1 {
+		t.Errorf("expected 1 backup, but got %v", backups)
+	}
+}
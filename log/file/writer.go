@@ -0,0 +1,422 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+var _ io.WriteCloser = (*Writer)(nil)
+
+// rename moves the rotated files, it is replaced in tests.
+var rename = os.Rename
+
+// Option is file writer option.
+type Option func(*options)
+
+type options struct {
+	maxSize       int64
+	interval      time.Duration
+	maxBackups    int
+	maxAge        time.Duration
+	compress      bool
+	reopen        bool
+	flushInterval time.Duration
+}
+
+// MaxSize with the size in bytes beyond which the file is rotated.
+func MaxSize(n int64) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// Interval with the interval of the time-based rotation, i.e., 24 * time.Hour.
+func Interval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// MaxBackups with the max rotated files retained.
+func MaxBackups(n int) Option {
+	return func(o *options) {
+		o.maxBackups = n
+	}
+}
+
+// MaxAge with the max age of the rotated files retained.
+func MaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
+
+// Compress compresses the rotated files by gzip.
+func Compress() Option {
+	return func(o *options) {
+		o.compress = true
+	}
+}
+
+// ReopenOnSIGHUP reopens the file on SIGHUP, i.e., after it is moved by logrotate.
+func ReopenOnSIGHUP() Option {
+	return func(o *options) {
+		o.reopen = true
+	}
+}
+
+// FlushInterval with the interval of flushing the buffered data.
+func FlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = d
+	}
+}
+
+// Writer is a buffered file writer with rotation, it is safe for concurrent use.
+type Writer struct {
+	opts options
+	path string
+
+	mu         sync.Mutex
+	file       *os.File
+	buf        *bufio.Writer
+	size       int64
+	nextRotate time.Time
+	closed     bool
+
+	mill    chan struct{}
+	signals chan os.Signal
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWriter new a file writer appending to the file of path.
+func NewWriter(path string, opts ...Option) (*Writer, error) {
+	options := options{
+		flushInterval: time.Second,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	w := &Writer{
+		opts: options,
+		path: path,
+		mill: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.wg.Add(2)
+	go w.runMill()
+	go w.runFlush()
+	if options.reopen {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+		w.wg.Add(1)
+		go w.runReopen()
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	if w.buf == nil {
+		w.buf = bufio.NewWriterSize(f, 32*1024)
+	} else {
+		w.buf.Reset(f)
+	}
+	if w.opts.interval > 0 {
+		w.nextRotate = time.Now().Truncate(w.opts.interval).Add(w.opts.interval)
+	}
+	return nil
+}
+
+// Write writes p to the file, rotating it first if p would exceed the max size
+// or the rotation interval has elapsed, so a single write never spans two files.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		// the former rotation failed to reopen the file, retry it.
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.buf.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.opts.maxSize > 0 && w.size > 0 && w.size+n > w.opts.maxSize {
+		return true
+	}
+	if w.opts.interval <= 0 || time.Now().Before(w.nextRotate) {
+		return false
+	}
+	if w.size == 0 {
+		// nothing to rotate, wait for the next interval rather than leaving an empty backup.
+		w.nextRotate = time.Now().Truncate(w.opts.interval).Add(w.opts.interval)
+		return false
+	}
+	return true
+}
+
+// Rotate rotates the file immediately.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate()
+}
+
+// rotate moves the file to a backup and opens a new one, on failures it keeps
+// writing to the original path so that the entries are never lost.
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	backup := w.backupName(time.Now())
+	if err := rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		if oerr := w.open(); oerr != nil {
+			w.file = nil
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		// move the backup back, and append to it as before.
+		if rerr := rename(backup, w.path); rerr != nil || w.open() != nil {
+			w.file = nil
+		}
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backupName returns the unused name of the file rotated at t.
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	for {
+		name := strings.TrimSuffix(w.path, ext) + "-" + t.Format(backupTimeFormat) + ext
+		_, err1 := os.Stat(name)
+		_, err2 := os.Stat(name + ".gz")
+		if os.IsNotExist(err1) && os.IsNotExist(err2) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// Reopen reopens the file of the path, i.e., after it is moved by logrotate.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	return w.open()
+}
+
+// Flush flushes the buffered data to the file.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.file == nil {
+		return nil
+	}
+	return w.buf.Flush()
+}
+
+// Close flushes the buffered data, and closes the file once the rotated files are compressed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFile()
+	w.mu.Unlock()
+	if w.signals != nil {
+		signal.Stop(w.signals)
+	}
+	close(w.done)
+	w.wg.Wait()
+	return err
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+func (w *Writer) runFlush() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.Flush()
+		}
+	}
+}
+
+func (w *Writer) runReopen() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.signals:
+			w.Reopen()
+		}
+	}
+}
+
+func (w *Writer) runMill() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.done:
+			// compress the files rotated right before closing.
+			select {
+			case <-w.mill:
+				w.millBackups()
+			default:
+			}
+			return
+		case <-w.mill:
+			w.millBackups()
+		}
+	}
+}
+
+// millBackups compresses the rotated files, and removes the ones beyond the retention.
+func (w *Writer) millBackups() {
+	backups := w.backups()
+	if w.opts.compress {
+		for i, name := range backups {
+			if strings.HasSuffix(name, ".gz") {
+				continue
+			}
+			if err := compress(name); err == nil {
+				backups[i] = name + ".gz"
+			}
+		}
+	}
+	// the backups are sorted by time, the newest first.
+	for i, name := range backups {
+		expired := false
+		if w.opts.maxBackups > 0 && i >= w.opts.maxBackups {
+			expired = true
+		}
+		if w.opts.maxAge > 0 {
+			if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > w.opts.maxAge {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(name)
+		}
+	}
+}
+
+func (w *Writer) backups() []string {
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(w.path, ext) + "-"
+	names, _ := filepath.Glob(prefix + "*")
+	backups := names[:0]
+	for _, name := range names {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
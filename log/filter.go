@@ -0,0 +1,156 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var _ Logger = (*Filter)(nil)
+
+// LevelEnv is the environment variable of the initial filter level.
+const LevelEnv = "LOG_LEVEL"
+
+// fuzzyStr is the replacement of the filtered values.
+const fuzzyStr = "***"
+
+// FilterOption is filter option.
+type FilterOption func(*Filter)
+
+// FilterLevel with the lowest level of the entries passed through.
+func FilterLevel(level Level) FilterOption {
+	return func(f *Filter) {
+		atomic.StoreInt32(f.level, int32(level))
+	}
+}
+
+// FilterKey with the keys whose values are replaced, the keys are case-insensitive.
+func FilterKey(keys ...string) FilterOption {
+	return func(f *Filter) {
+		for _, key := range keys {
+			f.keys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+// FilterValue with the sensitive values which are replaced.
+func FilterValue(values ...string) FilterOption {
+	return func(f *Filter) {
+		for _, v := range values {
+			f.values[v] = struct{}{}
+		}
+	}
+}
+
+// FilterFunc with the func which drops the entries it returns true for.
+func FilterFunc(fn func(level Level, kvpair []interface{}) bool) FilterOption {
+	return func(f *Filter) {
+		f.filter = fn
+	}
+}
+
+// Filter is a logger that drops the entries below the level, and replaces the sensitive values.
+type Filter struct {
+	logger Logger
+	level  *int32
+	keys   map[string]struct{}
+	values map[string]struct{}
+	filter func(level Level, kvpair []interface{}) bool
+}
+
+// NewFilter new a logger filter, the level is read from LOG_LEVEL unless FilterLevel is given.
+// An unknown level of LOG_LEVEL is warned to the logger, and the default level is kept.
+func NewFilter(logger Logger, opts ...FilterOption) *Filter {
+	f := &Filter{
+		logger: logger,
+		level:  new(int32),
+		keys:   make(map[string]struct{}),
+		values: make(map[string]struct{}),
+	}
+	if env := os.Getenv(LevelEnv); env != "" {
+		if level, ok := LookupLevel(env); ok {
+			*f.level = int32(level)
+		} else {
+			logger.Log(LevelWarn, DefaultMessageKey, fmt.Sprintf("log: unknown level %q of %s, the level %s is kept", env, LevelEnv, Level(*f.level)))
+		}
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+func (f *Filter) withContext(ctx context.Context) Logger {
+	c := *f
+	c.logger = bindContext(ctx, f.logger)
+	return &c
+}
+
+// Level returns the lowest level of the entries passed through.
+func (f *Filter) Level() Level {
+	return Level(atomic.LoadInt32(f.level))
+}
+
+// SetLevel changes the lowest level of the entries passed through, it takes effect immediately.
+func (f *Filter) SetLevel(level Level) {
+	atomic.StoreInt32(f.level, int32(level))
+}
+
+// WithCallerSkip returns a filter sharing the level and the options, which
+// passes the entries to the logger reporting the caller skip more frames above.
+func (f *Filter) WithCallerSkip(skip int) Logger {
+	c := *f
+	c.logger = WithCallerSkip(f.logger, skip)
+	return &c
+}
+
+// Log passes the entry through if its level is enabled, the pairs prepended by
+// the wrapped With logger are filtered as well.
+func (f *Filter) Log(level Level, kvpair ...interface{}) error {
+	if !f.Level().Enabled(level) {
+		return nil
+	}
+	next := f.logger
+	if l, ok := next.(*logger); ok && (len(f.keys) > 0 || len(f.values) > 0 || f.filter != nil) {
+		next, kvpair = l.log, l.merge(kvpair)
+	}
+	if f.filter != nil && f.filter(level, kvpair) {
+		return nil
+	}
+	return next.Log(level, f.redact(kvpair)...)
+}
+
+// redact replaces the sensitive values, kvpair is copied before the first replacement.
+func (f *Filter) redact(kvpair []interface{}) []interface{} {
+	if len(f.keys) == 0 && len(f.values) == 0 {
+		return kvpair
+	}
+	copied := false
+	for i := 0; i+1 < len(kvpair); i += 2 {
+		if !f.sensitive(kvpair[i], kvpair[i+1]) {
+			continue
+		}
+		if !copied {
+			kvpair = append([]interface{}(nil), kvpair...)
+			copied = true
+		}
+		kvpair[i+1] = fuzzyStr
+	}
+	return kvpair
+}
+
+func (f *Filter) sensitive(key, value interface{}) bool {
+	if k, ok := key.(string); ok && len(f.keys) > 0 {
+		if _, ok := f.keys[strings.ToLower(k)]; ok {
+			return true
+		}
+	}
+	if v, ok := value.(string); ok && len(f.values) > 0 {
+		if _, ok := f.values[v]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package fluent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/internal/queue"
+)
+
+var _ log.Logger = (*Logger)(nil)
+
+// Option is fluent logger option.
+type Option func(*options)
+
+type options struct {
+	tag          string
+	bufferSize   int
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	retryWait    time.Duration
+	maxRetryWait time.Duration
+}
+
+// Tag with the fluent tag of the entries.
+func Tag(tag string) Option {
+	return func(o *options) {
+		o.tag = tag
+	}
+}
+
+// BufferSize with the max entries buffered in memory, the oldest entries are dropped beyond it.
+func BufferSize(n int) Option {
+	return func(o *options) {
+		o.bufferSize = n
+	}
+}
+
+// DialTimeout with the timeout of connecting to fluentd.
+func DialTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = d
+	}
+}
+
+// WriteTimeout with the timeout of writing an entry.
+func WriteTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = d
+	}
+}
+
+// RetryWait with the initial and the max wait before reconnecting.
+func RetryWait(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.retryWait = initial
+		o.maxRetryWait = max
+	}
+}
+
+// Logger is a logger sending the entries to fluentd by the forward protocol.
+type Logger struct {
+	opts    options
+	network string
+	address string
+	queue   *queue.Queue
+	conn    net.Conn
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewLogger new a fluent logger, addr is tcp://host:port or unix:///path/to/socket.
+// The entries are sent in the background, so the logging never blocks.
+func NewLogger(addr string, opts ...Option) (*Logger, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	l := &Logger{
+		opts: options{
+			tag:          "kratos",
+			bufferSize:   8192,
+			dialTimeout:  3 * time.Second,
+			writeTimeout: 3 * time.Second,
+			retryWait:    100 * time.Millisecond,
+			maxRetryWait: 10 * time.Second,
+		},
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	switch u.Scheme {
+	case "tcp":
+		l.network, l.address = "tcp", u.Host
+	case "unix":
+		l.network, l.address = "unix", u.Path
+	default:
+		return nil, fmt.Errorf("fluent: unsupported address %s", addr)
+	}
+	for _, o := range opts {
+		o(&l.opts)
+	}
+	l.queue = queue.New(l.opts.bufferSize)
+	go l.run()
+	return l, nil
+}
+
+// Log buffers the entry, it returns an error if the logger has been closed.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	n := len(kvpair)/2 + len(kvpair)%2 + 1
+	b := make([]byte, 0, 64+16*len(kvpair))
+	b = appendArrayHeader(b, 3)
+	b = appendString(b, l.opts.tag)
+	b = appendInt(b, time.Now().Unix())
+	b = appendMapHeader(b, n)
+	b = appendString(b, log.LevelKey)
+	b = appendString(b, level.String())
+	for i := 0; i < len(kvpair); i += 2 {
+		b = appendString(b, fmt.Sprint(kvpair[i]))
+		if i+1 < len(kvpair) {
+			b = appendValue(b, log.Value(context.Background(), kvpair[i+1]))
+		} else {
+			b = appendValue(b, nil)
+		}
+	}
+	if !l.queue.Push(b) {
+		return fmt.Errorf("fluent: logger closed")
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped by the full buffer.
+func (l *Logger) Dropped() uint64 {
+	return l.queue.Dropped()
+}
+
+// Close flushes the buffered entries and closes the connection,
+// the entries are given up once fluentd is unreachable.
+func (l *Logger) Close() error {
+	l.once.Do(func() {
+		close(l.closing)
+		l.queue.Close()
+	})
+	<-l.done
+	return nil
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	wait := l.opts.retryWait
+	for {
+		item, ok := l.queue.Pop()
+		if !ok {
+			break
+		}
+		for {
+			err := l.write(item.([]byte))
+			if err == nil {
+				wait = l.opts.retryWait
+				break
+			}
+			select {
+			case <-l.closing:
+				// unreachable while closing, give up the rest.
+				l.drain()
+				return
+			case <-time.After(wait):
+			}
+			if wait *= 2; wait > l.opts.maxRetryWait {
+				wait = l.opts.maxRetryWait
+			}
+		}
+	}
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+func (l *Logger) drain() {
+	for {
+		if _, ok := l.queue.Pop(); !ok {
+			break
+		}
+	}
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+func (l *Logger) write(data []byte) error {
+	if l.conn == nil {
+		conn, err := net.DialTimeout(l.network, l.address, l.opts.dialTimeout)
+		if err != nil {
+			return err
+		}
+		l.conn = conn
+	}
+	l.conn.SetWriteDeadline(time.Now().Add(l.opts.writeTimeout))
+	if _, err := l.conn.Write(data); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return err
+	}
+	return nil
+}
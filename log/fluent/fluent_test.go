@@ -0,0 +1,69 @@
+package fluent
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestLogger(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	received := make(chan []byte)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := ioutil.ReadAll(conn)
+		received <- data
+	}()
+	logger, err := NewLogger("tcp://"+lis.Addr().String(), Tag("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(log.LevelInfo, "msg", "hello", "n", 1)
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := <-received
+	// [tag, time, {level, msg, n}]
+	if data[0] != 0x93 || !bytes.Contains(data, appendString(nil, "app")) || !bytes.Contains(data, appendString(nil, "hello")) {
+		t.Errorf("unexpected entry %x", data)
+	}
+	if err := logger.Log(log.LevelInfo, "msg", "closed"); err == nil {
+		t.Errorf("expected error after close")
+	}
+}
+
+func TestLoggerUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	logger, err := NewLogger("tcp://"+addr, BufferSize(10), DialTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		logger.Log(log.LevelInfo, "msg", "dropped")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected non-blocking logging, but took %s", d)
+	}
+	if logger.Dropped() == 0 {
+		t.Errorf("expected dropped entries")
+	}
+	logger.Close()
+}
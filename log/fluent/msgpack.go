@@ -0,0 +1,116 @@
+package fluent
+
+import (
+	"fmt"
+	"math"
+)
+
+// The minimal msgpack encoding of the forward protocol entries.
+
+func appendArrayHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x90|byte(n))
+	}
+	return append(b, 0xdc, byte(n>>8), byte(n))
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdf)
+		return appendUint32(b, uint32(n))
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendInt(b []byte, i int64) []byte {
+	if i >= 0 && i < 128 {
+		return append(b, byte(i))
+	}
+	b = append(b, 0xd3)
+	return appendUint64(b, uint64(i))
+}
+
+func appendUint(b []byte, i uint64) []byte {
+	if i < 128 {
+		return append(b, byte(i))
+	}
+	b = append(b, 0xcf)
+	return appendUint64(b, i)
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	b = append(b, 0xcb)
+	return appendUint64(b, math.Float64bits(f))
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case string:
+		return appendString(b, v)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case error:
+		return appendString(b, v.Error())
+	case fmt.Stringer:
+		return appendString(b, v.String())
+	default:
+		return appendString(b, fmt.Sprintf("%+v", v))
+	}
+}
+
+func appendUint32(b []byte, i uint32) []byte {
+	return append(b, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+func appendUint64(b []byte, i uint64) []byte {
+	return append(b, byte(i>>56), byte(i>>48), byte(i>>40), byte(i>>32), byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
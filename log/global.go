@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdlog "log"
+	"os"
+	"sync/atomic"
+)
+
+// loggerAppliance keeps the concrete type stored in the atomic value consistent.
+type loggerAppliance struct {
+	Logger
+}
+
+var global atomic.Value
+
+func init() {
+	global.Store(loggerAppliance{newStdLogger()})
+}
+
+// SetLogger sets the global logger used by the package-level helpers
+// and by the components that are not given a logger.
+func SetLogger(logger Logger) {
+	global.Store(loggerAppliance{logger})
+}
+
+// GetLogger returns the global logger.
+func GetLogger() Logger {
+	return global.Load().(loggerAppliance).Logger
+}
+
+// stdLogger is the default global logger, which prints the kv pairs to stdout.
+type stdLogger struct {
+	log *stdlog.Logger
+}
+
+func newStdLogger() *stdLogger {
+	return &stdLogger{log: stdlog.New(os.Stdout, "", stdlog.LstdFlags)}
+}
+
+func (l *stdLogger) Log(level Level, kvpair ...interface{}) error {
+	if len(kvpair) == 0 {
+		return nil
+	}
+	if len(kvpair)%2 != 0 {
+		kvpair = append(kvpair, missingValue)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "source=%v %s=%s", DefaultCaller(context.Background()), LevelKey, level)
+	for i := 0; i < len(kvpair); i += 2 {
+		fmt.Fprintf(&buf, " %s=%v", kvpair[i], Value(context.Background(), kvpair[i+1]))
+	}
+	return l.log.Output(0, buf.String())
+}
+
+// Debug logs a message at debug level with the global logger.
+func Debug(a ...interface{}) {
+	GetLogger().Log(LevelDebug, "msg", fmt.Sprint(a...))
+}
+
+// Debugf logs a message at debug level with the global logger.
+func Debugf(format string, a ...interface{}) {
+	GetLogger().Log(LevelDebug, "msg", fmt.Sprintf(format, a...))
+}
+
+// Debugw logs the kv pairs at debug level with the global logger.
+func Debugw(kvpair ...interface{}) {
+	GetLogger().Log(LevelDebug, kvpair...)
+}
+
+// Info logs a message at info level with the global logger.
+func Info(a ...interface{}) {
+	GetLogger().Log(LevelInfo, "msg", fmt.Sprint(a...))
+}
+
+// Infof logs a message at info level with the global logger.
+func Infof(format string, a ...interface{}) {
+	GetLogger().Log(LevelInfo, "msg", fmt.Sprintf(format, a...))
+}
+
+// Infow logs the kv pairs at info level with the global logger.
+func Infow(kvpair ...interface{}) {
+	GetLogger().Log(LevelInfo, kvpair...)
+}
+
+// Warn logs a message at warn level with the global logger.
+func Warn(a ...interface{}) {
+	GetLogger().Log(LevelWarn, "msg", fmt.Sprint(a...))
+}
+
+// Warnf logs a message at warn level with the global logger.
+func Warnf(format string, a ...interface{}) {
+	GetLogger().Log(LevelWarn, "msg", fmt.Sprintf(format, a...))
+}
+
+// Warnw logs the kv pairs at warn level with the global logger.
+func Warnw(kvpair ...interface{}) {
+	GetLogger().Log(LevelWarn, kvpair...)
+}
+
+// Error logs a message at error level with the global logger.
+func Error(a ...interface{}) {
+	GetLogger().Log(LevelError, "msg", fmt.Sprint(a...))
+}
+
+// Errorf logs a message at error level with the global logger.
+func Errorf(format string, a ...interface{}) {
+	GetLogger().Log(LevelError, "msg", fmt.Sprintf(format, a...))
+}
+
+// Errorw logs the kv pairs at error level with the global logger.
+func Errorw(kvpair ...interface{}) {
+	GetLogger().Log(LevelError, kvpair...)
+}
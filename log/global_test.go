@@ -0,0 +1,22 @@
+package log
+
+import "testing"
+
+func TestGlobalLogger(t *testing.T) {
+	prev := GetLogger()
+	defer SetLogger(prev)
+
+	logger := NewRecorder()
+	SetLogger(logger)
+	if GetLogger() != logger {
+		t.Fatalf("expected the global logger replaced")
+	}
+	Infof("hello %s", "kratos")
+	Errorw("key", "value")
+	if len(logger.FilterByLevel(LevelInfo)) != 1 || !logger.Contains("msg", "hello kratos") {
+		t.Errorf("unexpected entries: %v", logger.Entries())
+	}
+	if len(logger.FilterByLevel(LevelError)) != 1 || !logger.Contains("key", "value") {
+		t.Errorf("unexpected entries: %v", logger.Entries())
+	}
+}
@@ -1,86 +1,132 @@
 package log
 
 import (
+	"context"
 	"fmt"
+	"os"
 )
 
 var nop Logger = new(nopLogger)
 
+// DefaultMessageKey is the default message key of the helper.
+const DefaultMessageKey = "msg"
+
+// HelperOption is helper option.
+type HelperOption func(*Helper)
+
+// MessageKey with the key of the formatted messages.
+func MessageKey(key string) HelperOption {
+	return func(h *Helper) {
+		h.msgKey = key
+	}
+}
+
+// ExitFunc with the function called after the fatal messages, os.Exit by default.
+func ExitFunc(fn func(code int)) HelperOption {
+	return func(h *Helper) {
+		h.exit = fn
+	}
+}
+
 // Helper is a logger helper.
 type Helper struct {
-	debug Logger
-	info  Logger
-	warn  Logger
-	err   Logger
+	log    Logger
+	msgKey string
+	exit   func(code int)
 }
 
 // NewHelper new a logger helper.
-func NewHelper(name string, logger Logger) *Helper {
-	log := With(logger, "module", name)
-	return &Helper{
-		debug: Debug(log),
-		info:  Info(log),
-		warn:  Warn(log),
-		err:   Error(log),
+func NewHelper(name string, logger Logger, opts ...HelperOption) *Helper {
+	h := &Helper{
+		log:    With(logger, "module", name),
+		msgKey: DefaultMessageKey,
+		exit:   os.Exit,
+	}
+	for _, o := range opts {
+		o(h)
 	}
+	return h
+}
+
+// WithContext returns a helper that evaluates the Valuers with ctx.
+func (h *Helper) WithContext(ctx context.Context) *Helper {
+	return &Helper{log: WithContext(ctx, h.log), msgKey: h.msgKey, exit: h.exit}
 }
 
 // Debug logs a message at debug level.
 func (h *Helper) Debug(a ...interface{}) {
-	h.debug.Print("message", fmt.Sprint(a...))
+	h.log.Log(LevelDebug, h.msgKey, fmt.Sprint(a...))
 }
 
 // Debugf logs a message at debug level.
 func (h *Helper) Debugf(format string, a ...interface{}) {
-	h.debug.Print("message", fmt.Sprintf(format, a...))
+	h.log.Log(LevelDebug, h.msgKey, fmt.Sprintf(format, a...))
 }
 
 // Debugw logs a message at debug level.
 func (h *Helper) Debugw(kvpair ...interface{}) {
-	h.debug.Print(kvpair...)
+	h.log.Log(LevelDebug, kvpair...)
 }
 
 // Info logs a message at info level.
 func (h *Helper) Info(a ...interface{}) {
-	h.info.Print("message", fmt.Sprint(a...))
+	h.log.Log(LevelInfo, h.msgKey, fmt.Sprint(a...))
 }
 
 // Infof logs a message at info level.
 func (h *Helper) Infof(format string, a ...interface{}) {
-	h.info.Print("message", fmt.Sprintf(format, a...))
+	h.log.Log(LevelInfo, h.msgKey, fmt.Sprintf(format, a...))
 }
 
 // Infow logs a message at info level.
 func (h *Helper) Infow(kvpair ...interface{}) {
-	h.info.Print(kvpair...)
+	h.log.Log(LevelInfo, kvpair...)
 }
 
 // Warn logs a message at warn level.
 func (h *Helper) Warn(a ...interface{}) {
-	h.warn.Print("message", fmt.Sprint(a...))
+	h.log.Log(LevelWarn, h.msgKey, fmt.Sprint(a...))
 }
 
-// Warnf logs a message at warnf level.
+// Warnf logs a message at warn level.
 func (h *Helper) Warnf(format string, a ...interface{}) {
-	h.warn.Print("message", fmt.Sprintf(format, a...))
+	h.log.Log(LevelWarn, h.msgKey, fmt.Sprintf(format, a...))
 }
 
-// Warnw logs a message at warnf level.
+// Warnw logs a message at warn level.
 func (h *Helper) Warnw(kvpair ...interface{}) {
-	h.warn.Print(kvpair...)
+	h.log.Log(LevelWarn, kvpair...)
 }
 
 // Error logs a message at error level.
 func (h *Helper) Error(a ...interface{}) {
-	h.err.Print("message", fmt.Sprint(a...))
+	h.log.Log(LevelError, h.msgKey, fmt.Sprint(a...))
 }
 
 // Errorf logs a message at error level.
 func (h *Helper) Errorf(format string, a ...interface{}) {
-	h.err.Print("message", fmt.Sprintf(format, a...))
+	h.log.Log(LevelError, h.msgKey, fmt.Sprintf(format, a...))
 }
 
 // Errorw logs a message at error level.
 func (h *Helper) Errorw(kvpair ...interface{}) {
-	h.err.Print(kvpair...)
+	h.log.Log(LevelError, kvpair...)
+}
+
+// Fatal logs a message at fatal level, then calls the exit function.
+func (h *Helper) Fatal(a ...interface{}) {
+	h.log.Log(LevelFatal, h.msgKey, fmt.Sprint(a...))
+	h.exit(1)
+}
+
+// Fatalf logs a message at fatal level, then calls the exit function.
+func (h *Helper) Fatalf(format string, a ...interface{}) {
+	h.log.Log(LevelFatal, h.msgKey, fmt.Sprintf(format, a...))
+	h.exit(1)
+}
+
+// Fatalw logs a message at fatal level, then calls the exit function.
+func (h *Helper) Fatalw(kvpair ...interface{}) {
+	h.log.Log(LevelFatal, kvpair...)
+	h.exit(1)
 }
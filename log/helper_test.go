@@ -18,3 +18,16 @@ func TestHelperLevel(t *testing.T) {
 	log.Warn("test warn")
 	log.Error("test error")
 }
+
+func TestHelperFatal(t *testing.T) {
+	logger := NewRecorder()
+	var code int
+	log := NewHelper("test", logger, MessageKey("message"), ExitFunc(func(c int) { code = c }))
+	log.Fatalf("test %s", "fatal")
+	if code != 1 {
+		t.Errorf("expected exit code 1, but got %d", code)
+	}
+	if len(logger.FilterByLevel(LevelFatal)) != 1 || !logger.Contains("message", "test fatal") {
+		t.Errorf("unexpected entries: %v", logger.Entries())
+	}
+}
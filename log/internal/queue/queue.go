@@ -0,0 +1,82 @@
+// Package queue provides the bounded queue of the asynchronous log outputs.
+package queue
+
+import "sync"
+
+// Queue is a bounded FIFO queue which drops the oldest items when it is full,
+// so that the producers never block.
+type Queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []interface{}
+	size    int
+	closed  bool
+	dropped uint64
+}
+
+// New new a queue of size items.
+func New(size int) *Queue {
+	if size <= 0 {
+		size = 1
+	}
+	q := &Queue{size: size}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push pushes the item, the oldest item is dropped if the queue is full.
+// It returns false if the queue has been closed.
+func (q *Queue) Push(item interface{}) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if len(q.items) >= q.size {
+		q.items[0] = nil
+		q.items = q.items[1:]
+		q.dropped++
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+	return true
+}
+
+// Pop pops the oldest item, it blocks until an item is available,
+// and returns false once the queue is closed and drained.
+func (q *Queue) Pop() (interface{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	item := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	return item, true
+}
+
+// Len returns the number of the queued items.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Dropped returns the number of the dropped items.
+func (q *Queue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Close closes the queue, the queued items are still popped.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,28 @@
+package queue
+
+import "testing"
+
+func TestQueueDropOldest(t *testing.T) {
+	q := New(2)
+	q.Push(1)
+	q.Push(2)
+	q.Push(3)
+	if q.Dropped() != 1 {
+		t.Errorf("expected 1 dropped, but got %d", q.Dropped())
+	}
+	q.Close()
+	if q.Push(4) {
+		t.Errorf("expected push rejected after close")
+	}
+	var got []interface{}
+	for {
+		item, ok := q.Pop()
+		if !ok {
+			break
+		}
+		got = append(got, item)
+	}
+	if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("expected [2 3], but got %v", got)
+	}
+}
@@ -0,0 +1,218 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var _ log.Logger = (*Logger)(nil)
+
+// Option is JSON logger option.
+type Option func(*options)
+
+type options struct {
+	out        io.Writer
+	timeKey    string
+	timeLayout string
+}
+
+// Writer with logger writer.
+func Writer(out io.Writer) Option {
+	return func(o *options) {
+		o.out = out
+	}
+}
+
+// TimeKey with the key of the entry timestamp, an empty key disables the timestamp.
+func TimeKey(key string) Option {
+	return func(o *options) {
+		o.timeKey = key
+	}
+}
+
+// TimeLayout with the layout of the entry timestamp.
+func TimeLayout(layout string) Option {
+	return func(o *options) {
+		o.timeLayout = layout
+	}
+}
+
+// Logger is a logger writing one JSON object per line.
+type Logger struct {
+	opts options
+	mu   sync.Mutex
+	pool *sync.Pool
+}
+
+// NewLogger new a JSON logger with options, it is safe for concurrent use
+// since every entry is written by a single Write call.
+func NewLogger(opts ...Option) *Logger {
+	options := options{
+		out:        os.Stdout,
+		timeKey:    "ts",
+		timeLayout: time.RFC3339Nano,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Logger{
+		opts: options,
+		pool: &sync.Pool{
+			New: func() interface{} {
+				return new(bytes.Buffer)
+			},
+		},
+	}
+}
+
+// Log writes the entry as a JSON object.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	buf := l.pool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		l.pool.Put(buf)
+	}()
+	buf.WriteString(`{"` + log.LevelKey + `":"` + level.String() + `"`)
+	if l.opts.timeKey != "" {
+		buf.WriteByte(',')
+		writeString(buf, l.opts.timeKey)
+		buf.WriteByte(':')
+		writeString(buf, time.Now().Format(l.opts.timeLayout))
+	}
+	for i := 0; i < len(kvpair); i += 2 {
+		buf.WriteByte(',')
+		if key, ok := kvpair[i].(string); ok {
+			writeString(buf, key)
+		} else {
+			writeString(buf, fmt.Sprint(kvpair[i]))
+		}
+		buf.WriteByte(':')
+		if i+1 < len(kvpair) {
+			writeValue(buf, log.Value(context.Background(), kvpair[i+1]))
+		} else {
+			buf.WriteString(`null`)
+		}
+	}
+	buf.WriteString("}\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.opts.out.Write(buf.Bytes())
+	return err
+}
+
+// writeValue writes the common types without reflection, and falls back to encoding/json.
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	var b [64]byte
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeString(buf, v)
+	case bool:
+		buf.Write(strconv.AppendBool(b[:0], v))
+	case int:
+		buf.Write(strconv.AppendInt(b[:0], int64(v), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(b[:0], int64(v), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(b[:0], int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(b[:0], int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(b[:0], v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(b[:0], uint64(v), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(b[:0], uint64(v), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(b[:0], uint64(v), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(b[:0], uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(b[:0], v, 10))
+	case float32:
+		writeFloat(buf, float64(v), 32)
+	case float64:
+		writeFloat(buf, v, 64)
+	case time.Time:
+		writeString(buf, v.Format(time.RFC3339Nano))
+	case error:
+		writeString(buf, v.Error())
+	case fmt.Stringer:
+		writeString(buf, v.String())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			writeString(buf, fmt.Sprintf("%+v", v))
+			return
+		}
+		buf.Write(data)
+	}
+}
+
+func writeFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// not representable in JSON.
+		writeString(buf, strconv.FormatFloat(f, 'g', -1, bits))
+		return
+	}
+	var b [64]byte
+	buf.Write(strconv.AppendFloat(b[:0], f, 'g', -1, bits))
+}
+
+const hex = "0123456789abcdef"
+
+// writeString writes s as a JSON string, the invalid UTF-8 is replaced by U+FFFD.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
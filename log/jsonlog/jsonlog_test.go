@@ -0,0 +1,92 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func TestLogger(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := NewLogger(Writer(buf))
+	logger.Log(log.LevelInfo,
+		"msg", "quote \" backslash \\ newline \n control \x01 invalid \xff",
+		"int", 42,
+		"float", 1.5,
+		"bool", true,
+		"error", errors.New("failed"),
+		"duration", time.Second,
+		"map", map[string]int{"a": 1},
+		"unpaired",
+	)
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":    "INFO",
+		"msg":      "quote \" backslash \\ newline \n control \x01 invalid �",
+		"int":      float64(42),
+		"float":    1.5,
+		"bool":     true,
+		"error":    "failed",
+		"duration": "1s",
+		"map":      map[string]interface{}{"a": float64(1)},
+		"unpaired": nil,
+	}
+	for k, v := range want {
+		if got, _ := json.Marshal(entry[k]); string(got) != mustMarshal(v) {
+			t.Errorf("%s: expected %s, but got %s", k, mustMarshal(v), got)
+		}
+	}
+	if _, ok := entry["ts"]; !ok {
+		t.Errorf("expected timestamp in %v", entry)
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestConcurrentLines(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := NewLogger(Writer(buf))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Log(log.LevelInfo, "msg", strings.Repeat("x", 100))
+			}
+		}()
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSpace(buf.buf.String()), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("expected 800 lines, but got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("invalid line %s", line)
+		}
+	}
+}
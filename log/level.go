@@ -1,5 +1,7 @@
 package log
 
+import "strings"
+
 // Level is a logger level.
 type Level int8
 
@@ -12,6 +14,8 @@ const (
 	LevelWarn
 	// LevelError is logger error level.
 	LevelError
+	// LevelFatal is logger fatal level.
+	LevelFatal
 )
 
 const (
@@ -34,7 +38,33 @@ func (l Level) String() string {
 		return "WARN"
 	case LevelError:
 		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
 	default:
 		return ""
 	}
 }
+
+// ParseLevel parses a level string into a logger Level value, unknown levels are LevelInfo.
+func ParseLevel(s string) Level {
+	level, _ := LookupLevel(s)
+	return level
+}
+
+// LookupLevel parses a level string in any case into a logger Level value, and reports
+// whether the level is known, unknown levels are LevelInfo.
+func LookupLevel(s string) (Level, bool) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return LevelDebug, true
+	case "INFO":
+		return LevelInfo, true
+	case "WARN":
+		return LevelWarn, true
+	case "ERROR":
+		return LevelError, true
+	case "FATAL":
+		return LevelFatal, true
+	}
+	return LevelInfo, false
+}
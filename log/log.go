@@ -1,40 +1,106 @@
 package log
 
+import "context"
+
 // Logger is a logger interface.
 type Logger interface {
-	Print(kvpair ...interface{})
+	Log(level Level, kvpair ...interface{}) error
 }
 
+// missingValue is the placeholder value of the unpaired key.
+const missingValue = "KVPAIR UNPAIRED"
+
 type logger struct {
-	log    Logger
-	kvpair []interface{}
+	log       Logger
+	prefix    []interface{}
+	hasValuer bool
+	ctx       context.Context
+	skip      int
+}
+
+func (l *logger) Log(level Level, kvpair ...interface{}) error {
+	return l.log.Log(level, l.merge(kvpair)...)
 }
 
-func (l *logger) Print(kvpair ...interface{}) {
-	l.log.Print(append(kvpair, l.kvpair...)...)
+// merge returns the prefix followed by kvpair, with the Valuers evaluated.
+func (l *logger) merge(kvpair []interface{}) []interface{} {
+	kvs := make([]interface{}, 0, len(l.prefix)+len(kvpair))
+	kvs = append(kvs, l.prefix...)
+	kvs = append(kvs, kvpair...)
+	if l.hasValuer || containsValuer(kvpair) {
+		ctx := l.ctx
+		if l.skip > 0 {
+			ctx = context.WithValue(ctx, callerSkipKey{}, l.skip)
+		}
+		bindValues(ctx, kvs)
+	}
+	return kvs
 }
 
-// With with logger kv pairs.
+// With returns a logger that prepends the kv pairs to every entry,
+// the nested loggers share a single wrapper with the pairs of both.
+// The Valuers of the pairs are evaluated when the entries are written.
 func With(log Logger, kvpair ...interface{}) Logger {
-	return &logger{log: log, kvpair: kvpair}
+	var (
+		prefix []interface{}
+		skip   int
+	)
+	ctx := context.Background()
+	if l, ok := log.(*logger); ok {
+		log, prefix, ctx, skip = l.log, l.prefix, l.ctx, l.skip
+	}
+	kvs := make([]interface{}, 0, len(prefix)+len(kvpair)+1)
+	kvs = append(kvs, prefix...)
+	kvs = append(kvs, kvpair...)
+	if len(kvpair)%2 != 0 {
+		kvs = append(kvs, missingValue)
+	}
+	return &logger{log: log, prefix: kvs, hasValuer: containsValuer(kvs), ctx: ctx, skip: skip}
+}
+
+// WithContext returns a logger that evaluates the Valuers with ctx, i.e., the request context,
+// including the Valuers of the loggers nested in the wrappers, i.e., a Filter of a logger With them.
+func WithContext(ctx context.Context, log Logger) Logger {
+	l, ok := log.(*logger)
+	if !ok {
+		l = &logger{log: log}
+	}
+	return l.withContext(ctx)
+}
+
+// contextLogger is implemented by the loggers which evaluate the Valuers or wrap such loggers.
+type contextLogger interface {
+	withContext(ctx context.Context) Logger
 }
 
-// Debug returns a debug logger.
-func Debug(log Logger) Logger {
-	return With(log, LevelKey, LevelDebug)
+// bindContext returns the logger evaluating the Valuers with ctx if it supports it.
+func bindContext(ctx context.Context, log Logger) Logger {
+	if l, ok := log.(contextLogger); ok {
+		return l.withContext(ctx)
+	}
+	return log
 }
 
-// Info returns a info logger.
-func Info(log Logger) Logger {
-	return With(log, LevelKey, LevelInfo)
+func (l *logger) withContext(ctx context.Context) Logger {
+	return &logger{log: bindContext(ctx, l.log), prefix: l.prefix, hasValuer: l.hasValuer, ctx: ctx, skip: l.skip}
 }
 
-// Warn return a warn logger.
-func Warn(log Logger) Logger {
-	return With(log, LevelKey, LevelWarn)
+// CallerSkipper is implemented by the loggers reporting the caller themselves, i.e., the adapters.
+type CallerSkipper interface {
+	// WithCallerSkip returns a logger reporting the caller skip more frames above.
+	WithCallerSkip(skip int) Logger
 }
 
-// Error returns a error logger.
-func Error(log Logger) Logger {
-	return With(log, LevelKey, LevelError)
+// WithCallerSkip returns a logger reporting the caller skip frames above the call site,
+// it is used by the wrappers outside the log packages to declare the frames they add.
+func WithCallerSkip(log Logger, skip int) Logger {
+	l, ok := log.(*logger)
+	if !ok {
+		l = &logger{log: log, ctx: context.Background()}
+	}
+	next := l.log
+	if s, ok := next.(CallerSkipper); ok {
+		next = s.WithCallerSkip(skip)
+	}
+	return &logger{log: next, prefix: l.prefix, hasValuer: l.hasValuer, ctx: l.ctx, skip: l.skip + skip}
 }
@@ -1,15 +1,24 @@
 package log
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type testLogger struct {
 	*testing.T
 }
 
-func (t *testLogger) Print(kvpiar ...interface{}) {
-	t.Log(kvpiar...)
+func (t *testLogger) Log(level Level, kvpiar ...interface{}) error {
+	t.T.Log(append([]interface{}{level}, kvpiar...)...)
+	return nil
 }
 
 func (t *testLogger) Close() error {
@@ -19,8 +28,259 @@ func (t *testLogger) Close() error {
 func TestLogger(t *testing.T) {
 	log := &testLogger{t}
 
-	Debug(log).Print("log", "test debug")
-	Info(log).Print("log", "test info")
-	Warn(log).Print("log", "test warn")
-	Error(log).Print("log", "test error")
+	log.Log(LevelDebug, "log", "test debug")
+	log.Log(LevelInfo, "log", "test info")
+	log.Log(LevelWarn, "log", "test warn")
+	log.Log(LevelError, "log", "test error")
+}
+
+func TestFilter(t *testing.T) {
+	logger := NewRecorder()
+	filter := NewFilter(logger, FilterLevel(LevelWarn))
+	filter.Log(LevelDebug, "log", "test debug")
+	filter.Log(LevelInfo, "log", "test info")
+	filter.Log(LevelWarn, "log", "test warn")
+	filter.Log(LevelError, "log", "test error")
+	if n := len(logger.Entries()); n != 2 {
+		t.Errorf("expected 2 entries, but got %d", n)
+	}
+	kvpair := []interface{}{"log", "test debug"}
+	allocs := testing.AllocsPerRun(100, func() {
+		filter.Log(LevelDebug, kvpair...)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations for filtered entries, but got %v", allocs)
+	}
+}
+
+func TestFilterSetLevel(t *testing.T) {
+	os.Setenv(LevelEnv, "error")
+	defer os.Unsetenv(LevelEnv)
+	logger := NewRecorder()
+	filter := NewFilter(logger)
+	filter.Log(LevelWarn, "log", "test warn")
+	if n := len(logger.Entries()); n != 0 {
+		t.Errorf("expected the level read from %s, but got %d entries", LevelEnv, n)
+	}
+	filter.SetLevel(LevelDebug)
+	filter.Log(LevelDebug, "log", "test debug")
+	if n := len(logger.Entries()); n != 1 || filter.Level() != LevelDebug {
+		t.Errorf("expected the level changed, but got %d entries", n)
+	}
+}
+
+func TestFilterInvalidEnv(t *testing.T) {
+	os.Setenv(LevelEnv, "verbose")
+	defer os.Unsetenv(LevelEnv)
+	r := NewRecorder()
+	f := NewFilter(r)
+	if f.Level() != LevelDebug {
+		t.Errorf("expected the default level kept on the unknown level of %s, but got %s", LevelEnv, f.Level())
+	}
+	if warns := r.FilterByLevel(LevelWarn); len(warns) != 1 || !strings.Contains(fmt.Sprint(warns[0].Value(DefaultMessageKey)), "verbose") {
+		t.Errorf("expected the unknown level warned, but got %v", r.Entries())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug": LevelDebug,
+		"INFO":  LevelInfo,
+		"Warn":  LevelWarn,
+		"error": LevelError,
+		"fatal": LevelFatal,
+		"":      LevelInfo,
+		"bogus": LevelInfo,
+	}
+	for s, want := range tests {
+		if got := ParseLevel(s); got != want {
+			t.Errorf("ParseLevel(%q): expected %v, but got %v", s, want, got)
+		}
+		if _, ok := LookupLevel(s); ok != (s != "" && s != "bogus") {
+			t.Errorf("LookupLevel(%q): unexpected ok %v", s, ok)
+		}
+	}
+}
+
+// keyvals returns the key-values of the entries recorded by r.
+func keyvals(r *Recorder) []interface{} {
+	var kvs []interface{}
+	for _, e := range r.Entries() {
+		kvs = append(kvs, e.KeyVals...)
+	}
+	return kvs
+}
+
+func TestWith(t *testing.T) {
+	logger := NewRecorder()
+	log := With(With(logger, "service.name", "demo"), "instance.id", "1", "unpaired")
+	log.Log(LevelInfo, "msg", "hello")
+	want := []interface{}{"service.name", "demo", "instance.id", "1", "unpaired", missingValue, "msg", "hello"}
+	if got := keyvals(logger); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestFilterRedact(t *testing.T) {
+	logger := NewRecorder()
+	filter := NewFilter(With(logger, "Authorization", "Bearer token"),
+		FilterKey("password", "authorization"),
+		FilterValue("secret-value"),
+		FilterFunc(func(level Level, kvpair []interface{}) bool {
+			return len(kvpair) > 3 && kvpair[3] == "drop"
+		}),
+	)
+	kvpair := []interface{}{"PassWord", "123", "user", "secret-value", "msg", "login"}
+	filter.Log(LevelInfo, kvpair...)
+	want := []interface{}{"Authorization", fuzzyStr, "PassWord", fuzzyStr, "user", fuzzyStr, "msg", "login"}
+	if got := keyvals(logger); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, but got %v", want, got)
+	}
+	if kvpair[1] != "123" || kvpair[3] != "secret-value" {
+		t.Errorf("expected the caller's pairs untouched, but got %v", kvpair)
+	}
+	logger.Reset()
+	filter.Log(LevelInfo, "msg", "drop")
+	if got := keyvals(logger); got != nil {
+		t.Errorf("expected the entry dropped, but got %v", got)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	logger := NewRecorder()
+	s := NewSampler(logger, SampleFirst(10), SampleThereafter(10), SampleInterval(time.Hour))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Log(LevelError, "msg", "hot path failed")
+			}
+		}()
+	}
+	wg.Wait()
+	s.Log(LevelInfo, "msg", "other")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 10 first, 99 thereafter of 1000, 1 other, and the summary.
+	entries := logger.Entries()
+	if len(entries) != 10+99+1+1 {
+		t.Fatalf("expected 111 entries, but got %d", len(entries))
+	}
+	if summary := entries[len(entries)-1]; summary.Value("suppressed") != uint64(891) {
+		t.Errorf("expected 891 suppressed, but got %v", summary.KeyVals)
+	}
+}
+
+func TestSamplerDisabled(t *testing.T) {
+	logger := NewRecorder()
+	s := NewSampler(logger, SampleFirst(1), SampleInterval(0), SampleSummary(0))
+	for i := 0; i < 3; i++ {
+		s.Log(LevelError, "msg", "hot path failed")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries := logger.Entries(); len(entries) != 3 {
+		t.Fatalf("expected 3 entries, but got %v", entries)
+	}
+
+	logger = NewRecorder()
+	s = NewSampler(logger, SampleFirst(1), SampleThereafter(0), SampleInterval(time.Hour), SampleSummary(0))
+	for i := 0; i < 3; i++ {
+		s.Log(LevelError, "msg", "hot path failed")
+	}
+	if entries := logger.Entries(); len(entries) != 1 {
+		t.Fatalf("expected 1 entry before closed, but got %v", entries)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries := logger.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected the summary once closed, but got %v", entries)
+	}
+	if summary := entries[1]; summary.Value("suppressed") != uint64(2) {
+		t.Errorf("expected 2 suppressed, but got %v", summary.KeyVals)
+	}
+}
+
+// reentrantLogger logs into the sampler again, which deadlocks if the sampler logs under its locks.
+type reentrantLogger struct {
+	*Recorder
+	sampler *Sampler
+}
+
+func (l *reentrantLogger) Log(level Level, kvpair ...interface{}) error {
+	if kvpair[1] == "log entries suppressed" {
+		l.sampler.Log(level, "message", "hot path failed")
+	}
+	return l.Recorder.Log(level, kvpair...)
+}
+
+func TestSamplerMessageKey(t *testing.T) {
+	logger := &reentrantLogger{Recorder: NewRecorder()}
+	s := NewSampler(logger, SampleFirst(1), SampleThereafter(0), SampleInterval(time.Hour), SampleMessageKey("message"))
+	logger.sampler = s
+	for i := 0; i < 3; i++ {
+		s.Log(LevelError, "message", "hot path failed")
+		s.Log(LevelError, "message", "other")
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the summaries logged without the sampler locks")
+	}
+	// the first of each message, and the summaries of the buckets keyed by the message key.
+	entries := logger.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, but got %v", entries)
+	}
+	for _, e := range entries[2:] {
+		if e.Value("message") != "log entries suppressed" || e.Value("suppressed") != uint64(2) {
+			t.Errorf("expected the summary by the message key, but got %v", e.KeyVals)
+		}
+	}
+}
+
+type failLogger struct {
+	closed bool
+}
+
+func (l *failLogger) Log(level Level, kvpair ...interface{}) error {
+	return errors.New("write failed")
+}
+
+func (l *failLogger) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestMultiLogger(t *testing.T) {
+	first, second, failed := NewRecorder(), NewRecorder(), &failLogger{}
+	var calls int
+	counter := Valuer(func(context.Context) interface{} {
+		calls++
+		return calls
+	})
+	logger := MultiLogger(first, failed, second)
+	if err := logger.Log(LevelInfo, "n", counter); err == nil {
+		t.Errorf("expected the error of the failed logger")
+	}
+	if calls != 1 || !first.Contains("n", 1) || !second.Contains("n", 1) {
+		t.Errorf("expected the valuer evaluated once, but got %d calls, %v and %v", calls, first.Entries(), second.Entries())
+	}
+	if err := logger.(interface{ Close() error }).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !failed.closed {
+		t.Errorf("expected close propagated")
+	}
 }
@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/log/logrus
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	github.com/sirupsen/logrus v1.7.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
@@ -0,0 +1,132 @@
+package logrus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	_ log.Logger        = (*Logger)(nil)
+	_ log.CallerSkipper = (*Logger)(nil)
+)
+
+// Option is logrus logger option.
+type Option func(*options)
+
+type options struct {
+	messageKey  string
+	exitOnFatal bool
+	callerKey   string
+	callerSkip  int
+}
+
+// MessageKey with the key whose value is the logrus entry message.
+func MessageKey(key string) Option {
+	return func(o *options) {
+		o.messageKey = key
+	}
+}
+
+// ExitOnFatal exits the process via logrus.Logger.Exit after the fatal entries,
+// by default the fatal entries are only logged.
+func ExitOnFatal() Option {
+	return func(o *options) {
+		o.exitOnFatal = true
+	}
+}
+
+// CallerKey with the field of the call site, since logrus.Logger.ReportCaller
+// can only report the adapter.
+func CallerKey(key string) Option {
+	return func(o *options) {
+		o.callerKey = key
+	}
+}
+
+// CallerSkip with the caller frames skipped beyond the log packages.
+func CallerSkip(skip int) Option {
+	return func(o *options) {
+		o.callerSkip = skip
+	}
+}
+
+// Logger is a logrus logger adapter, the entries are formatted by the formatter of the logrus logger.
+type Logger struct {
+	log  *logrus.Logger
+	opts options
+}
+
+// NewLogger new a logger writing the entries to the logrus logger.
+func NewLogger(logger *logrus.Logger, opts ...Option) *Logger {
+	options := options{
+		messageKey: "msg",
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Logger{log: logger, opts: options}
+}
+
+// Log writes the entry to the logrus logger.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	lv := logrusLevel(level)
+	if !l.log.IsLevelEnabled(lv) {
+		return nil
+	}
+	var msg string
+	fields := make(logrus.Fields, len(kvpair)/2)
+	for i := 0; i < len(kvpair); i += 2 {
+		key, ok := kvpair[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvpair[i])
+		}
+		var value interface{}
+		if i+1 < len(kvpair) {
+			value = log.Value(context.Background(), kvpair[i+1])
+		}
+		if key == l.opts.messageKey {
+			if s, ok := value.(string); ok {
+				msg = s
+				continue
+			}
+		}
+		fields[key] = value
+	}
+	if l.opts.callerKey != "" {
+		if frame, ok := log.CallerFrame(l.opts.callerSkip); ok {
+			fields[l.opts.callerKey] = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+	}
+	l.log.WithFields(fields).Log(lv, msg)
+	if level == log.LevelFatal && l.opts.exitOnFatal {
+		l.log.Exit(1)
+	}
+	return nil
+}
+
+// WithCallerSkip returns a logger reporting the caller skip more frames above.
+func (l *Logger) WithCallerSkip(skip int) log.Logger {
+	c := *l
+	c.opts.callerSkip += skip
+	return &c
+}
+
+func logrusLevel(level log.Level) logrus.Level {
+	switch level {
+	case log.LevelDebug:
+		return logrus.DebugLevel
+	case log.LevelInfo:
+		return logrus.InfoLevel
+	case log.LevelWarn:
+		return logrus.WarnLevel
+	case log.LevelError:
+		return logrus.ErrorLevel
+	case log.LevelFatal:
+		return logrus.FatalLevel
+	}
+	return logrus.InfoLevel
+}
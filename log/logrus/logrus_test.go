@@ -0,0 +1,51 @@
+package logrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := logrus.New()
+	l.Out = buf
+	l.Formatter = &logrus.JSONFormatter{}
+	var exited int
+	l.ExitFunc = func(code int) { exited = code }
+
+	logger := log.With(NewLogger(l), "service.name", "demo", "ts", log.Timestamp(time.RFC3339))
+	log.NewHelper("test", logger).Errorw("msg", "failed", "error", errors.New("boom"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid entry %s: %v", buf.String(), err)
+	}
+	if entry["msg"] != "failed" || entry["level"] != "error" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if entry["service.name"] != "demo" || entry["module"] != "test" || entry["error"] != "boom" {
+		t.Errorf("unexpected fields %v", entry)
+	}
+	if ts, ok := entry["ts"].(string); !ok {
+		t.Errorf("expected the timestamp field, but got %v", entry)
+	} else if _, err := time.Parse(time.RFC3339, ts); err != nil {
+		t.Errorf("expected the evaluated timestamp, but got %s", ts)
+	}
+
+	buf.Reset()
+	NewLogger(l).Log(log.LevelFatal, "msg", "fatal")
+	if exited != 0 {
+		t.Errorf("expected no exit by default")
+	}
+	NewLogger(l, ExitOnFatal()).Log(log.LevelFatal, "msg", "fatal")
+	if exited != 1 {
+		t.Errorf("expected exit with ExitOnFatal")
+	}
+}
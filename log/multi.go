@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"strings"
+)
+
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type multiLogger []Logger
+
+// MultiLogger returns a logger writing every entry to all the loggers in order,
+// the Valuers are evaluated once so that all the loggers get the same values.
+// The returned logger implements Close and Sync, which propagate to the loggers supporting them.
+func MultiLogger(loggers ...Logger) Logger {
+	return multiLogger(loggers)
+}
+
+func (m multiLogger) Log(level Level, kvpair ...interface{}) error {
+	if containsValuer(kvpair) {
+		kvpair = append([]interface{}(nil), kvpair...)
+		bindValues(context.Background(), kvpair)
+	}
+	var errs multiError
+	for _, l := range m {
+		if err := l.Log(level, kvpair...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m multiLogger) withContext(ctx context.Context) Logger {
+	loggers := make(multiLogger, len(m))
+	for i, l := range m {
+		loggers[i] = bindContext(ctx, l)
+	}
+	return loggers
+}
+
+// Close closes the loggers implementing io.Closer, and the ones closed with a context,
+// i.e., Async, which wait until the buffered entries are written.
+func (m multiLogger) Close() error {
+	var errs multiError
+	for _, l := range m {
+		var err error
+		switch c := l.(type) {
+		case interface{ Close() error }:
+			err = c.Close()
+		case interface{ Close(context.Context) error }:
+			err = c.Close(context.Background())
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Sync flushes the loggers implementing Sync.
+func (m multiLogger) Sync() error {
+	var errs multiError
+	for _, l := range m {
+		if s, ok := l.(interface{ Sync() error }); ok {
+			if err := s.Sync(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
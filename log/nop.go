@@ -2,4 +2,4 @@ package log
 
 type nopLogger struct{}
 
-func (l *nopLogger) Print(kvpair ...interface{}) {}
+func (l *nopLogger) Log(level Level, kvpair ...interface{}) error { return nil }
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+var _ Logger = (*Recorder)(nil)
+
+// Entry is a log entry recorded by the Recorder.
+type Entry struct {
+	Level   Level
+	KeyVals []interface{}
+}
+
+// Value returns the value of key in the entry, or nil if the entry hasn't it.
+func (e Entry) Value(key interface{}) interface{} {
+	for i := 0; i+1 < len(e.KeyVals); i += 2 {
+		if e.KeyVals[i] == key {
+			return e.KeyVals[i+1]
+		}
+	}
+	return nil
+}
+
+// Recorder is a logger that keeps the entries in memory, i.e., for assertions in tests.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder new a logger recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Log records the entry with the Valuers evaluated.
+func (r *Recorder) Log(level Level, kvpair ...interface{}) error {
+	kvs := append([]interface{}(nil), kvpair...)
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, missingValue)
+	}
+	bindValues(context.Background(), kvs)
+	r.mu.Lock()
+	r.entries = append(r.entries, Entry{Level: level, KeyVals: kvs})
+	r.mu.Unlock()
+	return nil
+}
+
+// Entries returns the recorded entries.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// FilterByLevel returns the recorded entries at level.
+func (r *Recorder) FilterByLevel(level Level) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []Entry
+	for _, e := range r.entries {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Contains reports whether any recorded entry has the key with the value.
+func (r *Recorder) Contains(key, value interface{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		for i := 0; i+1 < len(e.KeyVals); i += 2 {
+			if e.KeyVals[i] == key && reflect.DeepEqual(e.KeyVals[i+1], value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reset removes the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
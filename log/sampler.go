@@ -0,0 +1,220 @@
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Logger = (*Sampler)(nil)
+
+const samplerShards = 16
+
+// SamplerOption is sampler option.
+type SamplerOption func(*Sampler)
+
+// SampleFirst with the entries of a bucket passed through per interval.
+func SampleFirst(n uint64) SamplerOption {
+	return func(s *Sampler) {
+		s.first = n
+	}
+}
+
+// SampleThereafter with 1 in m entries of a bucket passed through beyond the first ones, 0 drops them all.
+func SampleThereafter(m uint64) SamplerOption {
+	return func(s *Sampler) {
+		s.thereafter = m
+	}
+}
+
+// SampleInterval with the sampling interval of the buckets, a non-positive interval disables
+// the sampling, all the entries pass through.
+func SampleInterval(d time.Duration) SamplerOption {
+	return func(s *Sampler) {
+		s.interval = d
+	}
+}
+
+// SampleSummary with the interval of the summary of the suppressed entries, a non-positive
+// interval disables the periodic summaries, the suppressed entries are summarized once closed.
+func SampleSummary(d time.Duration) SamplerOption {
+	return func(s *Sampler) {
+		s.summary = d
+	}
+}
+
+// SampleMessageKey with the key of the entry messages, which is used by the default bucket key
+// and the summary entries, DefaultMessageKey by default.
+func SampleMessageKey(key string) SamplerOption {
+	return func(s *Sampler) {
+		s.msgKey = key
+	}
+}
+
+// SampleKey with the bucket key of the entries, by default the level and the message value.
+func SampleKey(fn func(level Level, kvpair []interface{}) string) SamplerOption {
+	return func(s *Sampler) {
+		s.key = fn
+	}
+}
+
+type bucket struct {
+	level      Level
+	count      uint64
+	suppressed uint64
+	window     int64
+}
+
+type samplerShard struct {
+	mu      sync.RWMutex
+	buckets map[string]*bucket
+}
+
+// Sampler is a logger that rate-limits the entries per bucket, and
+// periodically logs the number of the suppressed entries.
+type Sampler struct {
+	logger     Logger
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+	summary    time.Duration
+	msgKey     string
+	key        func(level Level, kvpair []interface{}) string
+	shards     [samplerShards]samplerShard
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSampler new a logger sampler, by default the first 100 entries of a bucket pass per second and 1 in 100 thereafter.
+func NewSampler(logger Logger, opts ...SamplerOption) *Sampler {
+	s := &Sampler{
+		logger:     logger,
+		first:      100,
+		thereafter: 100,
+		interval:   time.Second,
+		summary:    10 * time.Second,
+		msgKey:     DefaultMessageKey,
+		done:       make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.key == nil {
+		s.key = s.defaultKey
+	}
+	for i := range s.shards {
+		s.shards[i].buckets = make(map[string]*bucket)
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *Sampler) defaultKey(level Level, kvpair []interface{}) string {
+	for i := 0; i+1 < len(kvpair); i += 2 {
+		if kvpair[i] == s.msgKey {
+			return level.String() + "|" + fmt.Sprint(kvpair[i+1])
+		}
+	}
+	return level.String()
+}
+
+// Log passes the entry through unless its bucket exceeds the rate.
+func (s *Sampler) Log(level Level, kvpair ...interface{}) error {
+	if s.interval <= 0 {
+		return s.logger.Log(level, kvpair...)
+	}
+	b := s.bucket(level, s.key(level, kvpair))
+	window := time.Now().UnixNano() / int64(s.interval)
+	if w := atomic.LoadInt64(&b.window); w != window && atomic.CompareAndSwapInt64(&b.window, w, window) {
+		atomic.StoreUint64(&b.count, 0)
+	}
+	n := atomic.AddUint64(&b.count, 1)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return s.logger.Log(level, kvpair...)
+	}
+	atomic.AddUint64(&b.suppressed, 1)
+	return nil
+}
+
+func (s *Sampler) bucket(level Level, key string) *bucket {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%samplerShards]
+	shard.mu.RLock()
+	b, ok := shard.buckets[key]
+	shard.mu.RUnlock()
+	if ok {
+		return b
+	}
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if b, ok = shard.buckets[key]; !ok {
+		b = &bucket{level: level}
+		shard.buckets[key] = b
+	}
+	return b
+}
+
+func (s *Sampler) run() {
+	defer s.wg.Done()
+	var tick <-chan time.Time
+	if s.summary > 0 {
+		ticker := time.NewTicker(s.summary)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-s.done:
+			s.flush()
+			return
+		case <-tick:
+			s.flush()
+		}
+	}
+}
+
+type summary struct {
+	level      Level
+	key        string
+	suppressed uint64
+}
+
+// flush logs the suppressed counts, and removes the buckets idle for a summary interval.
+// The summaries are logged after the shard is unlocked, so that a slow logger doesn't block Log.
+func (s *Sampler) flush() {
+	if s.interval <= 0 {
+		return
+	}
+	idle := (time.Now().UnixNano() - int64(s.summary)) / int64(s.interval)
+	var summaries []summary
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, b := range shard.buckets {
+			if n := atomic.SwapUint64(&b.suppressed, 0); n > 0 {
+				summaries = append(summaries, summary{level: b.level, key: key, suppressed: n})
+			} else if atomic.LoadInt64(&b.window) < idle {
+				delete(shard.buckets, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	for _, e := range summaries {
+		s.logger.Log(e.level, s.msgKey, "log entries suppressed", "sample_key", e.key, "suppressed", e.suppressed)
+	}
+}
+
+// Close stops the sampler after logging the suppressed counts.
+func (s *Sampler) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return nil
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"sync"
+)
+
+// NewStdlibAdapter returns a standard library logger that writes every line to logger as an entry at level,
+// i.e., http.Server.ErrorLog.
+func NewStdlibAdapter(logger Logger, level Level) *stdlog.Logger {
+	return stdlog.New(&stdlibWriter{logger: logger, level: level}, "", 0)
+}
+
+// stdlibWriter buffers the partial lines until they are complete.
+type stdlibWriter struct {
+	mu     sync.Mutex
+	logger Logger
+	level  Level
+	buf    []byte
+}
+
+func (w *stdlibWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimRight(w.buf[:i], "\r")
+		if len(line) > 0 {
+			w.logger.Log(w.level, "msg", string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
@@ -0,0 +1,23 @@
+package log
+
+import "testing"
+
+func TestStdlibAdapter(t *testing.T) {
+	logger := NewRecorder()
+	std := NewStdlibAdapter(logger, LevelError)
+	std.Print("first\nsecond")
+	w := std.Writer()
+	w.Write([]byte("par"))
+	w.Write([]byte("tial\r\nrest"))
+	w.Write([]byte("\n"))
+	want := []string{"first", "second", "partial", "rest"}
+	entries := logger.FilterByLevel(LevelError)
+	if len(entries) != len(want) {
+		t.Fatalf("expected %v, but got %v", want, entries)
+	}
+	for i, msg := range want {
+		if entries[i].Value("msg") != msg {
+			t.Errorf("expected %q, but got %q", msg, entries[i].Value("msg"))
+		}
+	}
+}
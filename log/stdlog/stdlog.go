@@ -2,12 +2,11 @@ package stdlog
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	stdlog "log"
 	"os"
-	"runtime"
-	"strings"
 	"sync"
 
 	"github.com/go-kratos/kratos/v2/log"
@@ -39,7 +38,7 @@ func Flag(flag int) Option {
 	}
 }
 
-// Skip with caller skip.
+// Skip with the caller frames skipped beyond the log packages.
 func Skip(skip int) Option {
 	return func(o *options) {
 		o.skip = skip
@@ -55,24 +54,25 @@ func Writer(out io.WriteCloser) Option {
 
 // Logger is std logger.
 type Logger struct {
-	opts options
-	log  *stdlog.Logger
-	pool *sync.Pool
+	opts   options
+	log    *stdlog.Logger
+	pool   *sync.Pool
+	caller log.Valuer
 }
 
 // NewLogger new a std logger with options.
 func NewLogger(opts ...Option) *Logger {
 	options := options{
 		flag: stdlog.LstdFlags,
-		skip: 4,
 		out:  os.Stdout,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
 	return &Logger{
-		opts: options,
-		log:  stdlog.New(options.out, options.prefix, options.flag),
+		opts:   options,
+		caller: log.Caller(options.skip),
+		log:    stdlog.New(options.out, options.prefix, options.flag),
 		pool: &sync.Pool{
 			New: func() interface{} {
 				return new(bytes.Buffer)
@@ -81,36 +81,32 @@ func NewLogger(opts ...Option) *Logger {
 	}
 }
 
-func (s *Logger) stackTrace(path string) string {
-	idx := strings.LastIndexByte(path, '/')
-	if idx == -1 {
-		return path
-	}
-	idx = strings.LastIndexByte(path[:idx], '/')
-	if idx == -1 {
-		return path
-	}
-	return path[idx+1:]
-}
-
-// Print print the kv pairs log.
-func (s *Logger) Print(kvpair ...interface{}) {
+// Log print the kv pairs log.
+func (s *Logger) Log(level log.Level, kvpair ...interface{}) error {
 	if len(kvpair) == 0 {
-		return
+		return nil
 	}
 	if len(kvpair)%2 != 0 {
 		kvpair = append(kvpair, "")
 	}
 	buf := s.pool.Get().(*bytes.Buffer)
-	if _, file, line, ok := runtime.Caller(s.opts.skip); ok {
-		buf.WriteString(fmt.Sprintf("source=%s:%d ", s.stackTrace(file), line))
-	}
+	buf.WriteString("source=" + fmt.Sprint(s.caller(context.Background())) + " ")
+	buf.WriteString(log.LevelKey + "=" + level.String() + " ")
 	for i := 0; i < len(kvpair); i += 2 {
-		fmt.Fprintf(buf, "%s=%v ", kvpair[i], kvpair[i+1])
+		fmt.Fprintf(buf, "%s=%v ", kvpair[i], log.Value(context.Background(), kvpair[i+1]))
 	}
 	s.log.Println(buf.String())
 	buf.Reset()
 	s.pool.Put(buf)
+	return nil
+}
+
+// WithCallerSkip returns a logger reporting the caller skip more frames above.
+func (s *Logger) WithCallerSkip(skip int) log.Logger {
+	c := *s
+	c.opts.skip += skip
+	c.caller = log.Caller(c.opts.skip)
+	return &c
 }
 
 // Close close the logger.
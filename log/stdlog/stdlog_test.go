@@ -16,10 +16,10 @@ func TestLogger(t *testing.T) {
 	logger := NewLogger(Writer(os.Stdout))
 	defer logger.Close()
 
-	log.Debug(logger).Print("log", "test debug")
-	log.Info(logger).Print("log", "test info")
-	log.Warn(logger).Print("log", "test warn")
-	log.Error(logger).Print("log", "test error")
+	logger.Log(log.LevelDebug, "log", "test debug")
+	logger.Log(log.LevelInfo, "log", "test info")
+	logger.Log(log.LevelWarn, "log", "test warn")
+	logger.Log(log.LevelError, "log", "test error")
 }
 
 func BenchmarkLoggerPrint(b *testing.B) {
@@ -28,7 +28,7 @@ func BenchmarkLoggerPrint(b *testing.B) {
 	defer logger.Close()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			logger.Print("log", "test")
+			logger.Log(log.LevelInfo, "log", "test")
 		}
 	})
 }
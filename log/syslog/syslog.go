@@ -0,0 +1,150 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdsyslog "log/syslog"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/internal/queue"
+)
+
+var _ log.Logger = (*Logger)(nil)
+
+// Option is syslog logger option.
+type Option func(*options)
+
+type options struct {
+	network    string
+	address    string
+	tag        string
+	facility   stdsyslog.Priority
+	bufferSize int
+}
+
+// Network with the network and address of the syslog server, by default the local syslog server.
+func Network(network, address string) Option {
+	return func(o *options) {
+		o.network = network
+		o.address = address
+	}
+}
+
+// Tag with the syslog tag, by default the program name.
+func Tag(tag string) Option {
+	return func(o *options) {
+		o.tag = tag
+	}
+}
+
+// Facility with the syslog facility.
+func Facility(facility stdsyslog.Priority) Option {
+	return func(o *options) {
+		o.facility = facility
+	}
+}
+
+// BufferSize with the max entries buffered in memory, the oldest entries are dropped beyond it.
+func BufferSize(n int) Option {
+	return func(o *options) {
+		o.bufferSize = n
+	}
+}
+
+type entry struct {
+	level log.Level
+	msg   string
+}
+
+// Logger is a logger writing the entries to syslog, the levels are mapped to the severities.
+type Logger struct {
+	w     *stdsyslog.Writer
+	queue *queue.Queue
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewLogger new a syslog logger, the entries are written in the background,
+// so the logging never blocks.
+func NewLogger(opts ...Option) (*Logger, error) {
+	options := options{
+		facility:   stdsyslog.LOG_USER,
+		bufferSize: 8192,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	w, err := stdsyslog.Dial(options.network, options.address, options.facility|stdsyslog.LOG_INFO, options.tag)
+	if err != nil {
+		return nil, err
+	}
+	l := &Logger{
+		w:     w,
+		queue: queue.New(options.bufferSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Log buffers the entry, it returns an error if the logger has been closed.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	buf := new(bytes.Buffer)
+	for i := 0; i < len(kvpair); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		var value interface{}
+		if i+1 < len(kvpair) {
+			value = log.Value(context.Background(), kvpair[i+1])
+		}
+		fmt.Fprintf(buf, "%v=%v", kvpair[i], value)
+	}
+	if !l.queue.Push(entry{level: level, msg: buf.String()}) {
+		return fmt.Errorf("syslog: logger closed")
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped by the full buffer.
+func (l *Logger) Dropped() uint64 {
+	return l.queue.Dropped()
+}
+
+// Close flushes the buffered entries and closes the syslog connection.
+func (l *Logger) Close() error {
+	l.once.Do(l.queue.Close)
+	<-l.done
+	return l.w.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for {
+		item, ok := l.queue.Pop()
+		if !ok {
+			return
+		}
+		e := item.(entry)
+		// the writer reconnects on failures, the failed entries are dropped.
+		switch e.level {
+		case log.LevelDebug:
+			l.w.Debug(e.msg)
+		case log.LevelInfo:
+			l.w.Info(e.msg)
+		case log.LevelWarn:
+			l.w.Warning(e.msg)
+		case log.LevelError:
+			l.w.Err(e.msg)
+		case log.LevelFatal:
+			l.w.Crit(e.msg)
+		default:
+			l.w.Info(e.msg)
+		}
+	}
+}
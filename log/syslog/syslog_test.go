@@ -0,0 +1,37 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package syslog
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestLogger(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	logger, err := NewLogger(Network("udp", conn.LocalAddr().String()), Tag("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(log.LevelError, "msg", "failed", "code", 500)
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LOG_USER|LOG_ERR is <11>.
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<11>") || !strings.Contains(msg, "msg=failed code=500") {
+		t.Errorf("unexpected message %s", msg)
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultCaller is a Valuer that returns the file and line of the call site.
+	DefaultCaller = Caller(0)
+
+	// DefaultTimestamp is a Valuer that returns the current timestamp in RFC3339.
+	DefaultTimestamp = Timestamp(time.RFC3339)
+)
+
+// Valuer is returns a log value, which is evaluated when the entry is written.
+type Valuer func(ctx context.Context) interface{}
+
+// Value returns the value of v, evaluating it if it is a Valuer.
+func Value(ctx context.Context, v interface{}) interface{} {
+	if v, ok := v.(Valuer); ok {
+		return v(ctx)
+	}
+	return v
+}
+
+// Timestamp returns a Valuer that returns the current timestamp formatted by layout.
+func Timestamp(layout string) Valuer {
+	return func(context.Context) interface{} {
+		return time.Now().Format(layout)
+	}
+}
+
+const (
+	// logPackage is the import path of the log package, whose frames are never reported as callers.
+	logPackage = "github.com/go-kratos/kratos/v2/log"
+	// logModules is the import path prefix of the adapter modules, i.e., log/zap.
+	logModules = "github.com/go-kratos/kratos/log/"
+)
+
+// Caller returns a Valuer that returns the file and line of the call site,
+// which is the first frame outside the log packages, then skipped by skip frames
+// and the frames declared by WithCallerSkip.
+func Caller(skip int) Valuer {
+	return func(ctx context.Context) interface{} {
+		frame, ok := CallerFrame(skip + callerSkip(ctx))
+		if !ok {
+			return ""
+		}
+		return trimPath(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+}
+
+// CallerFrame returns the first frame outside the log packages skipped by skip frames,
+// it is used by the adapters reporting the caller themselves.
+func CallerFrame(skip int) (frame runtime.Frame, ok bool) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	outside := false
+	for {
+		frame, more := frames.Next()
+		if !outside && !isLogFrame(frame) {
+			outside = true
+		}
+		if outside {
+			if skip == 0 {
+				return frame, true
+			}
+			skip--
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+type callerSkipKey struct{}
+
+func callerSkip(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	skip, _ := ctx.Value(callerSkipKey{}).(int)
+	return skip
+}
+
+// isLogFrame reports whether the frame is in the log packages, their tests are callers as the user code.
+func isLogFrame(frame runtime.Frame) bool {
+	function := frame.Function
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	if strings.HasPrefix(function, logModules) {
+		return true
+	}
+	if !strings.HasPrefix(function, logPackage) {
+		return false
+	}
+	rest := function[len(logPackage):]
+	return strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "/")
+}
+
+// trimPath returns the last directory and the file name of the path.
+func trimPath(path string) string {
+	idx := strings.LastIndexByte(path, '/')
+	if idx == -1 {
+		return path
+	}
+	idx = strings.LastIndexByte(path[:idx], '/')
+	if idx == -1 {
+		return path
+	}
+	return path[idx+1:]
+}
+
+func containsValuer(kvpair []interface{}) bool {
+	for i := 1; i < len(kvpair); i += 2 {
+		if _, ok := kvpair[i].(Valuer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bindValues evaluates the Valuers of kvpair in place.
+func bindValues(ctx context.Context, kvpair []interface{}) {
+	for i := 1; i < len(kvpair); i += 2 {
+		if v, ok := kvpair[i].(Valuer); ok {
+			kvpair[i] = v(ctx)
+		}
+	}
+}
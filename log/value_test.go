@@ -0,0 +1,161 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/stdlog"
+)
+
+// value returns the value of key in the last entry recorded by r.
+func value(r *log.Recorder, key string) interface{} {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[len(entries)-1].Value(key)
+}
+
+// next returns the line following the call site.
+func next() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line + 1
+}
+
+// wrapped is a wrapper outside the log packages, which adds a frame.
+func wrapped(logger log.Logger) {
+	logger.Log(log.LevelInfo, "msg", "test")
+}
+
+func TestCaller(t *testing.T) {
+	r := log.NewRecorder()
+	caller := log.With(r, "caller", log.DefaultCaller)
+	tests := []struct {
+		name string
+		log  func() int
+	}{
+		{"With", func() int {
+			line := next()
+			caller.Log(log.LevelInfo, "msg", "test")
+			return line
+		}},
+		{"Filter(With)", func() int {
+			line := next()
+			log.NewFilter(caller).Log(log.LevelInfo, "msg", "test")
+			return line
+		}},
+		{"With(Filter)", func() int {
+			line := next()
+			log.With(log.NewFilter(r), "caller", log.DefaultCaller).Log(log.LevelInfo, "msg", "test")
+			return line
+		}},
+		{"Helper(With)", func() int {
+			line := next()
+			log.NewHelper("test", caller).Info("test")
+			return line
+		}},
+		{"Helper(Filter(With))", func() int {
+			line := next()
+			log.NewHelper("test", log.NewFilter(caller)).Infof("%s", "test")
+			return line
+		}},
+		{"Helper(With(Filter(With)))", func() int {
+			line := next()
+			log.NewHelper("test", log.With(log.NewFilter(caller), "k", "v")).Infow("msg", "test")
+			return line
+		}},
+		{"WithCallerSkip(With)", func() int {
+			line := next()
+			wrapped(log.WithCallerSkip(caller, 1))
+			return line
+		}},
+		{"With(WithCallerSkip(Filter(With)))", func() int {
+			line := next()
+			wrapped(log.With(log.WithCallerSkip(log.NewFilter(caller), 1), "k", "v"))
+			return line
+		}},
+		{"Helper(WithCallerSkip(With))", func() int {
+			h := log.NewHelper("test", log.WithCallerSkip(caller, 1))
+			line := next()
+			func() { h.WithContext(context.Background()).Info("test") }()
+			return line
+		}},
+		{"Log(Valuer)", func() int {
+			line := next()
+			log.With(r).Log(log.LevelInfo, "caller", log.DefaultCaller)
+			return line
+		}},
+	}
+	for _, test := range tests {
+		line := test.log()
+		want := fmt.Sprintf("log/value_test.go:%d", line)
+		if got := value(r, "caller"); got != want {
+			t.Errorf("%s: expected caller %s, but got %v", test.name, want, got)
+		}
+	}
+}
+
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) Close() error { return nil }
+
+func TestStdCaller(t *testing.T) {
+	buf := new(buffer)
+	logger := stdlog.NewLogger(stdlog.Writer(buf))
+	line := next()
+	log.NewHelper("test", log.NewFilter(logger)).Info("test")
+	if want := fmt.Sprintf("source=log/value_test.go:%d ", line); !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %s, but got %s", want, buf.String())
+	}
+}
+
+func TestStdCallerSkip(t *testing.T) {
+	buf := new(buffer)
+	logger := log.WithCallerSkip(log.NewFilter(stdlog.NewLogger(stdlog.Writer(buf))), 1)
+	line := next()
+	wrapped(logger)
+	if want := fmt.Sprintf("source=log/value_test.go:%d ", line); !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %s, but got %s", want, buf.String())
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	r := log.NewRecorder()
+	log.With(r, "ts", log.Timestamp("2006")).Log(log.LevelInfo, "msg", "test")
+	if ts, ok := value(r, "ts").(string); !ok || len(ts) != 4 {
+		t.Errorf("expected evaluated timestamp, but got %v", value(r, "ts"))
+	}
+}
+
+type ctxKey struct{}
+
+func TestWithContext(t *testing.T) {
+	r := log.NewRecorder()
+	logger := log.With(r, "id", log.Valuer(func(ctx context.Context) interface{} {
+		return ctx.Value(ctxKey{})
+	}))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	log.NewHelper("test", logger).WithContext(ctx).Info("test")
+	if got := value(r, "id"); got != "request-1" {
+		t.Errorf("expected id request-1, but got %v", got)
+	}
+	// the loggers nested in the wrappers evaluate the Valuers with ctx too.
+	nested := log.NewFilter(log.MultiLogger(log.With(r, "trace_id", log.Valuer(func(ctx context.Context) interface{} {
+		return ctx.Value(ctxKey{})
+	}))))
+	log.NewHelper("test", nested).WithContext(ctx).Info("test")
+	if got := value(r, "trace_id"); got != "request-1" {
+		t.Errorf("expected trace_id request-1, but got %v", got)
+	}
+	log.NewHelper("test", logger).Info("test")
+	if got := value(r, "id"); got != nil {
+		t.Errorf("expected no id without context, but got %v", got)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/log/zap
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	go.uber.org/zap v1.16.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
@@ -0,0 +1,151 @@
+package zap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	_ log.Logger        = (*Logger)(nil)
+	_ log.CallerSkipper = (*Logger)(nil)
+)
+
+// Option is zap logger option.
+type Option func(*options)
+
+type options struct {
+	messageKey  string
+	callerSkip  int
+	exitOnFatal bool
+}
+
+// MessageKey with the key whose value is the zap entry message.
+func MessageKey(key string) Option {
+	return func(o *options) {
+		o.messageKey = key
+	}
+}
+
+// ExitOnFatal exits the process after the fatal entries as zap does,
+// by default the fatal entries are only logged.
+func ExitOnFatal() Option {
+	return func(o *options) {
+		o.exitOnFatal = true
+	}
+}
+
+// CallerSkip with the caller frames skipped beyond the log packages.
+func CallerSkip(skip int) Option {
+	return func(o *options) {
+		o.callerSkip = skip
+	}
+}
+
+// Logger is a zap logger adapter.
+type Logger struct {
+	log  *zap.Logger
+	opts options
+}
+
+// NewLogger new a logger writing the entries to the zap logger.
+func NewLogger(logger *zap.Logger, opts ...Option) *Logger {
+	options := options{
+		messageKey: "msg",
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Logger{
+		log:  logger,
+		opts: options,
+	}
+}
+
+// Log writes the entry to the zap logger, the fatal entries never exit the process
+// without ExitOnFatal, so that the Helper decides.
+func (l *Logger) Log(level log.Level, kvpair ...interface{}) error {
+	var msg string
+	fields := make([]zap.Field, 0, len(kvpair)/2)
+	for i := 0; i < len(kvpair); i += 2 {
+		key, ok := kvpair[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvpair[i])
+		}
+		var value interface{}
+		if i+1 < len(kvpair) {
+			value = log.Value(context.Background(), kvpair[i+1])
+		}
+		if key == l.opts.messageKey {
+			if s, ok := value.(string); ok {
+				msg = s
+				continue
+			}
+		}
+		fields = append(fields, field(key, value))
+	}
+	if ce := l.log.Check(zapLevel(level), msg); ce != nil {
+		// the call site is reported instead of the adapter, if zap adds the caller.
+		if ce.Entry.Caller.Defined {
+			if frame, ok := log.CallerFrame(l.opts.callerSkip); ok {
+				ce.Entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+			}
+		}
+		if level == log.LevelFatal && !l.opts.exitOnFatal {
+			// zap exits after writing the fatal entries, write the entry to the core instead.
+			errOut := ce.ErrorOutput
+			if ce = l.log.Core().Check(ce.Entry, nil); ce == nil {
+				return nil
+			}
+			ce.ErrorOutput = errOut
+		}
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// WithCallerSkip returns a logger reporting the caller skip more frames above.
+func (l *Logger) WithCallerSkip(skip int) log.Logger {
+	c := *l
+	c.opts.callerSkip += skip
+	return &c
+}
+
+// Sync flushes the buffered entries.
+func (l *Logger) Sync() error {
+	return l.log.Sync()
+}
+
+func field(key string, value interface{}) zap.Field {
+	switch v := value.(type) {
+	case error:
+		return zap.NamedError(key, v)
+	case time.Duration:
+		return zap.Duration(key, v)
+	case fmt.Stringer:
+		return zap.Stringer(key, v)
+	default:
+		return zap.Any(key, v)
+	}
+}
+
+func zapLevel(level log.Level) zapcore.Level {
+	switch level {
+	case log.LevelDebug:
+		return zapcore.DebugLevel
+	case log.LevelInfo:
+		return zapcore.InfoLevel
+	case log.LevelWarn:
+		return zapcore.WarnLevel
+	case log.LevelError:
+		return zapcore.ErrorLevel
+	case log.LevelFatal:
+		return zapcore.FatalLevel
+	}
+	return zapcore.InfoLevel
+}
@@ -0,0 +1,96 @@
+package zap
+
+import (
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/stdlog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := NewLogger(zap.New(core, zap.AddCaller()))
+	h := log.NewHelper("test", logger)
+	h.Infow("msg", "hello", "error", errors.New("failed"), "latency", time.Second)
+	h.Debugf("debug %d", 1)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, but got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Message != "hello" || e.Level != zapcore.InfoLevel {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	fields := e.ContextMap()
+	if fields["module"] != "test" || fields["error"] != "failed" || fields["latency"] != time.Second {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if !strings.HasSuffix(e.Caller.File, "log/zap/zap_test.go") {
+		t.Errorf("expected caller in the test, but got %s", e.Caller.File)
+	}
+	if entries[1].Message != "debug 1" || entries[1].Level != zapcore.DebugLevel {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+}
+
+func TestCallerSkip(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := log.WithCallerSkip(log.NewFilter(log.With(NewLogger(zap.New(core, zap.AddCaller())), "k", "v")), 1)
+	wrapped := func() {
+		log.NewHelper("test", logger).Info("test")
+	}
+	_, _, line, _ := runtime.Caller(0)
+	wrapped()
+	e := logs.AllUntimed()[0]
+	if !strings.HasSuffix(e.Caller.File, "log/zap/zap_test.go") || e.Caller.Line != line+1 {
+		t.Errorf("expected caller zap_test.go:%d, but got %s:%d", line+1, e.Caller.File, e.Caller.Line)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	var exited bool
+	h := log.NewHelper("test", NewLogger(zap.New(core)), log.ExitFunc(func(int) { exited = true }))
+	h.Fatal("fatal")
+	entries := logs.AllUntimed()
+	if len(entries) != 1 || entries[0].Message != "fatal" || entries[0].Level != zapcore.FatalLevel {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+	if !exited {
+		t.Errorf("expected the helper to exit")
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+func (discard) Close() error                { return nil }
+
+func BenchmarkStdLogger(b *testing.B) {
+	h := log.NewHelper("bench", stdlog.NewLogger(stdlog.Writer(discard{})))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Infow("msg", "hello", "key", "value", "n", i)
+	}
+}
+
+func BenchmarkZapLogger(b *testing.B) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(ioutil.Discard), zapcore.DebugLevel)
+	h := log.NewHelper("bench", NewLogger(zap.New(core)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Infow("msg", "hello", "key", "value", "n", i)
+	}
+}
@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// GlobalPrefix is the key prefix of the metadata propagated to all the downstream hops.
+	GlobalPrefix = "x-md-global-"
+	// LocalPrefix is the key prefix of the metadata sent to the next hop only.
+	LocalPrefix = "x-md-local-"
+)
+
+// DefaultPrefixes returns the key prefixes extracted by the servers by default.
+func DefaultPrefixes() []string {
+	return []string{GlobalPrefix, LocalPrefix}
+}
+
+// Extract returns the metadata of the HTTP headers or the gRPC metadata whose keys have any
+// of the prefixes, which are case-insensitive. The keys are kept with the prefixes, and the
+// values are percent-decoded, the values failing to decode are kept as is.
+func Extract(header map[string][]string, prefixes []string) Metadata {
+	md := Metadata{}
+	for k, vs := range header {
+		key := strings.ToLower(k)
+		if !hasPrefix(key, prefixes) {
+			continue
+		}
+		for _, v := range vs {
+			md.Add(key, decodeValue(v))
+		}
+	}
+	return md
+}
+
+func hasPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeValue(v string) string {
+	if !strings.Contains(v, "%") {
+		return v
+	}
+	if s, err := url.PathUnescape(v); err == nil {
+		return s
+	}
+	return v
+}
+
+// Outgoing returns the metadata the clients send with ctx: the global metadata of the server
+// context, which the server extracted from the upstream, and the metadata of the client context
+// whose keys have the global or the local prefixes, which replace the former ones of the same
+// keys. So that the global metadata propagates to all the downstream hops, and the local
+// metadata to the next hop only, which its server never propagates again.
+func Outgoing(ctx context.Context) Metadata {
+	md := Metadata{}
+	if s, ok := FromServerContext(ctx); ok {
+		for k, v := range s {
+			if strings.HasPrefix(k, GlobalPrefix) {
+				md[k] = v
+			}
+		}
+	}
+	if c, ok := FromClientContext(ctx); ok {
+		for k, v := range c {
+			if hasPrefix(k, DefaultPrefixes()) {
+				md[k] = v
+			}
+		}
+	}
+	return md
+}
+
+// Encode returns the HTTP headers or the gRPC metadata of the metadata, whose values are
+// percent-encoded if not printable ASCII, which Extract decodes.
+func Encode(md Metadata) map[string][]string {
+	header := make(map[string][]string, len(md))
+	for k, vs := range md {
+		for _, v := range vs {
+			header[k] = append(header[k], encodeValue(v))
+		}
+	}
+	return header
+}
+
+func encodeValue(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c < 0x20 || c >= 0x7f || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// TrimPrefix returns the key without the global or the local prefix, i.e., tenant for x-md-global-tenant.
+func TrimPrefix(key string) string {
+	key = strings.ToLower(key)
+	for _, p := range DefaultPrefixes() {
+		if strings.HasPrefix(key, p) {
+			return key[len(p):]
+		}
+	}
+	return key
+}
@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// The well-known keys of the metadata.
+const (
+	// CallerKey is the name of the calling app, which is sent to the next hop only.
+	CallerKey = LocalPrefix + "caller"
+	// CallerVersionKey is the version of the calling app, which is sent to the next hop only.
+	CallerVersionKey = LocalPrefix + "caller-version"
+	// TenantKey is the tenant ID of the request.
+	TenantKey = GlobalPrefix + "tenant"
+	// PriorityKey is the priority of the request, the greater the higher, 0 by default.
+	PriorityKey = GlobalPrefix + "priority"
+	// ShadowKey is the flag of the shadow traffic, which must cause no side effects.
+	ShadowKey = GlobalPrefix + "shadow"
+)
+
+// WellKnownKeys returns the well-known keys.
+func WellKnownKeys() []string {
+	return []string{CallerKey, TenantKey, PriorityKey, ShadowKey}
+}
+
+// Value returns the value of the key in effect for ctx, the outgoing one wins over the
+// incoming one, empty if none.
+func Value(ctx context.Context, key string) string {
+	if md, ok := ctx.Value(clientMetadataKey{}).(Metadata); ok {
+		if v := md.Get(key); v != "" {
+			return v
+		}
+	}
+	if md, ok := ctx.Value(serverMetadataKey{}).(Metadata); ok {
+		return md.Get(key)
+	}
+	return ""
+}
+
+// Bool reports whether the value of the key is truthy, which is case-insensitive one of
+// 1, t, true, y, yes and on, otherwise false.
+func Bool(ctx context.Context, key string) bool {
+	switch strings.ToLower(Value(ctx, key)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// Caller returns the name of the calling app.
+func Caller(ctx context.Context) string {
+	return Value(ctx, CallerKey)
+}
+
+// Tenant returns the tenant ID of the request.
+func Tenant(ctx context.Context) string {
+	return Value(ctx, TenantKey)
+}
+
+// WithTenant returns a new Context whose outgoing metadata carries the tenant ID.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return withValue(ctx, TenantKey, id)
+}
+
+// Priority returns the priority of the request, 0 if none or invalid.
+func Priority(ctx context.Context) int {
+	p, _ := strconv.Atoi(Value(ctx, PriorityKey))
+	return p
+}
+
+// WithPriority returns a new Context whose outgoing metadata carries the priority.
+func WithPriority(ctx context.Context, p int) context.Context {
+	return withValue(ctx, PriorityKey, strconv.Itoa(p))
+}
+
+// Shadow reports whether the request is the shadow traffic.
+func Shadow(ctx context.Context) bool {
+	return Bool(ctx, ShadowKey)
+}
+
+// WithShadow returns a new Context whose outgoing metadata carries the shadow traffic flag.
+func WithShadow(ctx context.Context, shadow bool) context.Context {
+	return withValue(ctx, ShadowKey, strconv.FormatBool(shadow))
+}
+
+func withValue(ctx context.Context, key, value string) context.Context {
+	md, ok := FromClientContext(ctx)
+	if !ok {
+		md = Metadata{}
+	}
+	md.Set(key, value)
+	return context.WithValue(ctx, clientMetadataKey{}, md)
+}
@@ -0,0 +1,140 @@
+// Package metadata carries the request metadata across the services, i.e., the tenant, the
+// caller and the flags, which the servers extract from the incoming requests into the server
+// context, and the clients inject from the client context into the outgoing requests.
+//
+// The keys are case-insensitive, which are normalized to lowercase. A key has one or more
+// values in order, Get returns the first one, and Set replaces all of them. The Metadata
+// stored in and retrieved from a context are copies, so that mutating a retrieved Metadata
+// never affects the stored one.
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Metadata is the request metadata, whose keys are lowercase.
+type Metadata map[string][]string
+
+// New returns the metadata of the maps, the later maps win on the same keys.
+func New(mds ...map[string]string) Metadata {
+	md := Metadata{}
+	for _, m := range mds {
+		for k, v := range m {
+			md.Set(k, v)
+		}
+	}
+	return md
+}
+
+// Pairs returns the metadata of the key value pairs, the values of the same keys are
+// appended in order. It panics if the number of kv is odd.
+func Pairs(kv ...string) Metadata {
+	if len(kv)%2 == 1 {
+		panic(fmt.Sprintf("metadata: Pairs got the odd number of kv: %d", len(kv)))
+	}
+	md := Metadata{}
+	for i := 0; i < len(kv); i += 2 {
+		md.Add(kv[i], kv[i+1])
+	}
+	return md
+}
+
+// Join returns the metadata of all the metadata, the values of the same keys are appended in order.
+func Join(mds ...Metadata) Metadata {
+	md := Metadata{}
+	for _, m := range mds {
+		for k, v := range m {
+			md[strings.ToLower(k)] = append(md[strings.ToLower(k)], v...)
+		}
+	}
+	return md
+}
+
+// Get returns the first value of the key, empty if none.
+func (m Metadata) Get(key string) string {
+	if v := m[strings.ToLower(key)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Values returns the values of the key.
+func (m Metadata) Values(key string) []string {
+	return m[strings.ToLower(key)]
+}
+
+// Set replaces the values of the key with the value.
+func (m Metadata) Set(key string, value string) {
+	m[strings.ToLower(key)] = []string{value}
+}
+
+// Add appends the value to the values of the key.
+func (m Metadata) Add(key string, value string) {
+	key = strings.ToLower(key)
+	m[key] = append(m[key], value)
+}
+
+// Delete deletes the values of the key.
+func (m Metadata) Delete(key string) {
+	delete(m, strings.ToLower(key))
+}
+
+// Range calls f for each key and its values until f returns false.
+func (m Metadata) Range(f func(k string, v []string) bool) {
+	for k, v := range m {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// Clone returns a deep copy of the metadata.
+func (m Metadata) Clone() Metadata {
+	md := make(Metadata, len(m))
+	for k, v := range m {
+		md[k] = append([]string(nil), v...)
+	}
+	return md
+}
+
+type serverMetadataKey struct{}
+
+// NewServerContext returns a new Context that carries a copy of the incoming metadata.
+func NewServerContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, serverMetadataKey{}, md.Clone())
+}
+
+// FromServerContext returns a copy of the incoming metadata stored in ctx, if any.
+func FromServerContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(serverMetadataKey{}).(Metadata)
+	if !ok {
+		return nil, false
+	}
+	return md.Clone(), true
+}
+
+type clientMetadataKey struct{}
+
+// NewClientContext returns a new Context that carries a copy of the outgoing metadata,
+// which replaces the outgoing metadata of ctx if any.
+func NewClientContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, clientMetadataKey{}, md.Clone())
+}
+
+// FromClientContext returns a copy of the outgoing metadata stored in ctx, if any.
+func FromClientContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(clientMetadataKey{}).(Metadata)
+	if !ok {
+		return nil, false
+	}
+	return md.Clone(), true
+}
+
+// MergeToClientContext returns a new Context that carries the outgoing metadata of ctx with
+// the key value pairs appended, the existing values are kept. It panics if the number of kv is odd.
+func MergeToClientContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromClientContext(ctx)
+	return context.WithValue(ctx, clientMetadataKey{}, Join(md, Pairs(kv...)))
+}
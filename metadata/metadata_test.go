@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	md := New(map[string]string{"X-Tenant": "a"}, map[string]string{"x-tenant": "b"})
+	if got := md.Get("X-TENANT"); got != "b" {
+		t.Errorf("expected the later map wins with the case-insensitive key, but got %s", got)
+	}
+	md = Pairs("Flag", "a", "flag", "b")
+	if got := md.Values("flag"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected the values appended, but got %v", got)
+	}
+	md.Set("flag", "c")
+	if got := md.Values("FLAG"); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("expected the values replaced, but got %v", got)
+	}
+}
+
+func TestContextCopy(t *testing.T) {
+	md := New(map[string]string{"tenant": "a"})
+	ctx := NewServerContext(context.Background(), md)
+	// mutating the stored metadata or the retrieved one never affects the context.
+	md.Set("tenant", "b")
+	got, ok := FromServerContext(ctx)
+	if !ok || got.Get("tenant") != "a" {
+		t.Fatalf("expected the stored copy, but got %v", got)
+	}
+	got.Set("tenant", "c")
+	got.Add("caller", "x")
+	if got, _ := FromServerContext(ctx); got.Get("tenant") != "a" || got.Get("caller") != "" {
+		t.Errorf("expected the retrieved copy, but got %v", got)
+	}
+	if _, ok := FromClientContext(ctx); ok {
+		t.Error("expected the server metadata not outgoing")
+	}
+}
+
+func TestMergeToClientContext(t *testing.T) {
+	ctx := NewClientContext(context.Background(), Pairs("flag", "a"))
+	merged := MergeToClientContext(ctx, "Flag", "b", "tenant", "t")
+	md, _ := FromClientContext(merged)
+	if !reflect.DeepEqual(md, Metadata{"flag": {"a", "b"}, "tenant": {"t"}}) {
+		t.Errorf("expected the outgoing metadata appended, but got %v", md)
+	}
+	if md, _ := FromClientContext(ctx); !reflect.DeepEqual(md, Metadata{"flag": {"a"}}) {
+		t.Errorf("expected the parent context untouched, but got %v", md)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	header := map[string][]string{
+		"X-Md-Global-Tenant": {"a"},
+		"X-Md-Local-Caller":  {"%E7%94%A8%E6%88%B7", "100%"},
+		"Authorization":      {"secret"},
+	}
+	md := Extract(header, DefaultPrefixes())
+	want := Metadata{"x-md-global-tenant": {"a"}, "x-md-local-caller": {"用户", "100%"}}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("expected %v, but got %v", want, md)
+	}
+	if md := Extract(header, nil); len(md) != 0 {
+		t.Errorf("expected none extracted, but got %v", md)
+	}
+}
+
+func TestWellKnown(t *testing.T) {
+	ctx := NewServerContext(context.Background(), Pairs(TenantKey, "a", CallerKey, "gateway", ShadowKey, "Yes", PriorityKey, "x"))
+	if Tenant(ctx) != "a" || Caller(ctx) != "gateway" || !Shadow(ctx) || Priority(ctx) != 0 {
+		t.Errorf("unexpected incoming values of %v", ctx)
+	}
+	ctx = WithPriority(WithShadow(WithTenant(ctx, "b"), false), 5)
+	if Tenant(ctx) != "b" || Shadow(ctx) || Priority(ctx) != 5 {
+		t.Errorf("expected the outgoing values win, but got %s %v %d", Tenant(ctx), Shadow(ctx), Priority(ctx))
+	}
+	if md := Outgoing(ctx); md.Get(TenantKey) != "b" || md.Get(PriorityKey) != "5" || md.Get(CallerKey) != "" {
+		t.Errorf("unexpected outgoing metadata %v", md)
+	}
+	for v, want := range map[string]bool{"1": true, "TRUE": true, "on": true, "0": false, "no": false, "": false} {
+		if got := Bool(NewServerContext(context.Background(), Pairs("k", v)), "k"); got != want {
+			t.Errorf("expected %q %v, but got %v", v, want, got)
+		}
+	}
+}
@@ -20,3 +20,10 @@ type Observer interface {
 	With(lvs ...string) Observer
 	Observe(float64)
 }
+
+// ExemplarObserver is an observer supporting exemplars, i.e., OpenMetrics histograms,
+// the observers returned by With may implement it.
+type ExemplarObserver interface {
+	Observer
+	ObserveWithExemplar(value float64, exemplar map[string]string)
+}
@@ -0,0 +1,121 @@
+package acl
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+// ReasonIPForbidden is the error reason of the rejected requests.
+const ReasonIPForbidden = "IP_FORBIDDEN"
+
+type lists struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// List is the allow and deny lists, which can be swapped at runtime.
+type List struct {
+	v atomic.Value
+}
+
+// NewList new an access list with allow and deny CIDRs.
+func NewList(allow, deny []string) (*List, error) {
+	l := &List{}
+	if err := l.Set(allow, deny); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Set atomically replaces the allow and deny CIDRs,
+// the previous lists are kept if any CIDR is invalid.
+func (l *List) Set(allow, deny []string) error {
+	a, err := ParseCIDRs(allow...)
+	if err != nil {
+		return err
+	}
+	d, err := ParseCIDRs(deny...)
+	if err != nil {
+		return err
+	}
+	l.v.Store(&lists{allow: a, deny: d})
+	return nil
+}
+
+func (l *List) load() *lists {
+	v, _ := l.v.Load().(*lists)
+	if v == nil {
+		return &lists{}
+	}
+	return v
+}
+
+// Option is acl option.
+type Option func(*options)
+
+type options struct {
+	list         *List
+	trusted      []*net.IPNet
+	allowIfEmpty bool
+}
+
+// WithList with access list.
+func WithList(l *List) Option {
+	return func(o *options) {
+		o.list = l
+	}
+}
+
+// TrustedProxies with the proxies whose X-Forwarded-For header is honored,
+// it panics if any CIDR is invalid.
+func TrustedProxies(cidrs ...string) Option {
+	nets, err := ParseCIDRs(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+	return func(o *options) {
+		o.trusted = nets
+	}
+}
+
+// AllowIfEmpty with whether requests are allowed when no lists are configured.
+func AllowIfEmpty(allow bool) Option {
+	return func(o *options) {
+		o.allowIfEmpty = allow
+	}
+}
+
+// Server is a server middleware that checks the client IP against the access list,
+// the deny list is evaluated before the allow list.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		list: &List{},
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			ip := ClientIP(ctx, options.trusted)
+			if !options.allowed(ip) {
+				return nil, errors.PermissionDenied(ReasonIPForbidden, "ip %s is forbidden", ip)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+func (o *options) allowed(ip net.IP) bool {
+	l := o.list.load()
+	if len(l.allow) == 0 && len(l.deny) == 0 {
+		return o.allowIfEmpty
+	}
+	if ip == nil || contains(l.deny, ip) {
+		return false
+	}
+	return len(l.allow) == 0 || contains(l.allow, ip)
+}
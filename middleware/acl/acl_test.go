@@ -0,0 +1,108 @@
+package acl
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func httpContext(remote string, forwarded ...string) context.Context {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remote
+	for _, f := range forwarded {
+		req.Header.Add("X-Forwarded-For", f)
+	}
+	return http.NewContext(context.Background(), http.ServerInfo{Request: req})
+}
+
+func TestServer(t *testing.T) {
+	list, err := NewList([]string{"10.0.0.0/8", "192.168.1.1"}, []string{"10.0.0.13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Server(WithList(list))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	tests := []struct {
+		remote string
+		ok     bool
+	}{
+		{"10.1.2.3:1234", true},
+		{"192.168.1.1:1234", true},
+		{"10.0.0.13:1234", false},
+		{"172.16.0.1:1234", false},
+		{"garbage", false},
+	}
+	for _, test := range tests {
+		_, err := h(httpContext(test.remote), nil)
+		if test.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", test.remote, err)
+		}
+		if !test.ok && (!errors.IsPermissionDenied(err) || errors.Reason(err) != ReasonIPForbidden) {
+			t.Errorf("%s: expected forbidden, got %v", test.remote, err)
+		}
+	}
+
+	// the lists are swapped at runtime, and kept on invalid CIDRs.
+	if err := list.Set(nil, []string{"10.1.0.0/16"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Set([]string{"bogus"}, nil); err == nil {
+		t.Errorf("expected the invalid CIDR rejected")
+	}
+	if _, err := h(httpContext("10.1.2.3:1234"), nil); !errors.IsPermissionDenied(err) {
+		t.Errorf("expected the denied ip forbidden, got %v", err)
+	}
+	if _, err := h(httpContext("172.16.0.1:1234"), nil); err != nil {
+		t.Errorf("expected the ip allowed by the deny list only, got %v", err)
+	}
+}
+
+func TestAllowIfEmpty(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "reply", nil }
+	if _, err := Server()(handler)(httpContext("10.0.0.1:1234"), nil); !errors.IsPermissionDenied(err) {
+		t.Errorf("expected forbidden without lists, got %v", err)
+	}
+	if _, err := Server(AllowIfEmpty(true))(handler)(httpContext("10.0.0.1:1234"), nil); err != nil {
+		t.Errorf("expected allowed without lists, got %v", err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := ParseCIDRs("10.0.0.0/8")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"direct", httpContext("1.2.3.4:1234", "5.6.7.8"), "1.2.3.4"},
+		{"trusted proxy", httpContext("10.0.0.1:1234", "5.6.7.8"), "5.6.7.8"},
+		{"proxy chain", httpContext("10.0.0.1:1234", "9.9.9.9, 5.6.7.8", "10.0.0.2"), "5.6.7.8"},
+		{"spoofed", httpContext("10.0.0.1:1234", "garbage, 5.6.7.8"), "5.6.7.8"},
+		{"grpc", metadata.NewIncomingContext(
+			peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}}),
+			metadata.Pairs("x-forwarded-for", "5.6.7.8"),
+		), "5.6.7.8"},
+	}
+	for _, test := range tests {
+		if ip := ClientIP(test.ctx, trusted); ip.String() != test.want {
+			t.Errorf("%s: expected %s, got %v", test.name, test.want, ip)
+		}
+	}
+}
+
+func TestTrustedProxiesInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on the invalid CIDR")
+		}
+	}()
+	TrustedProxies("10.0.0.0/33")
+}
@@ -0,0 +1,87 @@
+package acl
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ParseCIDRs parses CIDRs or plain IP addresses into networks.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: cidr}
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP resolves the client IP of the request, the X-Forwarded-For chain
+// is only honored when the direct peer is one of the trusted proxies,
+// in which case the rightmost untrusted address is the client.
+func ClientIP(ctx context.Context, trusted []*net.IPNet) net.IP {
+	var (
+		remote    string
+		forwarded []string
+	)
+	if info, ok := http.FromContext(ctx); ok {
+		remote = info.Request.RemoteAddr
+		forwarded = info.Request.Header.Values("X-Forwarded-For")
+	} else if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			forwarded = md.Get("x-forwarded-for")
+		}
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !contains(trusted, ip) {
+		return ip
+	}
+	var chain []string
+	for _, f := range forwarded {
+		chain = append(chain, strings.Split(f, ",")...)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		next := net.ParseIP(strings.TrimSpace(chain[i]))
+		if next == nil {
+			break
+		}
+		ip = next
+		if !contains(trusted, ip) {
+			break
+		}
+	}
+	return ip
+}
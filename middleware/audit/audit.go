@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/acl"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+// Event is an audit record of an operation.
+type Event struct {
+	// Principal is the authenticated principal, if any.
+	Principal interface{}
+	// Operation is the audited operation.
+	Operation string
+	// Fields is the request fields selected by the operation extractor.
+	Fields map[string]interface{}
+	// Time is when the operation started.
+	Time time.Time
+	// Duration is how long the operation took.
+	Duration time.Duration
+	// ClientIP is the resolved client address.
+	ClientIP net.IP
+	// Code is the status code of the outcome, 0 means success.
+	Code int32
+	// Reason is the error reason of the outcome, if any.
+	Reason string
+}
+
+// Sink receives audit events.
+type Sink interface {
+	Write(ctx context.Context, e *Event) error
+}
+
+// Extractor selects the audited fields from the request,
+// sensitive payloads should be reduced to identifiers.
+type Extractor func(req interface{}) map[string]interface{}
+
+type logSink struct {
+	log *log.Helper
+}
+
+// NewLogSink new a sink writing events to the logger.
+func NewLogSink(logger log.Logger) Sink {
+	return &logSink{log: log.NewHelper("audit", logger)}
+}
+
+func (s *logSink) Write(ctx context.Context, e *Event) error {
+	s.log.WithContext(ctx).Infow(
+		"principal", e.Principal,
+		"operation", e.Operation,
+		"fields", e.Fields,
+		"time", e.Time.Format(time.RFC3339Nano),
+		"duration", e.Duration,
+		"client_ip", e.ClientIP,
+		"code", e.Code,
+		"reason", e.Reason,
+	)
+	return nil
+}
+
+// Option is audit option.
+type Option func(*options)
+
+type options struct {
+	sink       Sink
+	extractors map[string]Extractor
+	failures   metrics.Counter
+	trusted    []*net.IPNet
+}
+
+// WithSink with event sink, by default the events are written to the global logger.
+func WithSink(s Sink) Option {
+	return func(o *options) {
+		o.sink = s
+	}
+}
+
+// Extract with the request field extractor of the operation,
+// operations without an extractor are audited without fields.
+func Extract(operation string, fn Extractor) Option {
+	return func(o *options) {
+		o.extractors[operation] = fn
+	}
+}
+
+// Failures with the counter of sink failures.
+func Failures(c metrics.Counter) Option {
+	return func(o *options) {
+		o.failures = c
+	}
+}
+
+// TrustedProxies with the proxies whose X-Forwarded-For header is honored,
+// it panics if any CIDR is invalid.
+func TrustedProxies(cidrs ...string) Option {
+	nets, err := acl.ParseCIDRs(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+	return func(o *options) {
+		o.trusted = nets
+	}
+}
+
+// Server is a server middleware that hands an audit event of every mutating
+// operation to the sink, HTTP requests with safe methods are not audited.
+// Sink failures never fail the request, they are counted instead. The principal is
+// recorded whether the middleware is placed outside or inside the auth middleware.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		sink:       NewLogSink(log.GetLogger()),
+		extractors: make(map[string]Extractor),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if info, ok := http.FromContext(ctx); ok {
+				switch info.Request.Method {
+				case "GET", "HEAD", "OPTIONS":
+					return handler(ctx, req)
+				}
+			}
+			e := &Event{
+				Time:     time.Now(),
+				ClientIP: acl.ClientIP(ctx, options.trusted),
+			}
+			if tr, ok := transport.FromContext(ctx); ok {
+				e.Operation = tr.Operation
+			}
+			if fn, ok := options.extractors[e.Operation]; ok {
+				e.Fields = fn(req)
+			}
+			ctx = auth.WithPrincipal(ctx)
+			reply, err := handler(ctx, req)
+			e.Duration = time.Since(e.Time)
+			e.Principal, _ = auth.FromContext(ctx)
+			if err != nil {
+				e.Code = int32(errors.Code(err))
+				e.Reason = errors.Reason(err)
+			}
+			if serr := options.sink.Write(ctx, e); serr != nil && options.failures != nil {
+				options.failures.With(e.Operation).Inc()
+			}
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type sink struct {
+	events []*Event
+	err    error
+}
+
+func (s *sink) Write(ctx context.Context, e *Event) error {
+	s.events = append(s.events, e)
+	return s.err
+}
+
+type counter struct {
+	lvs []string
+	n   int
+}
+
+func (c *counter) With(lvs ...string) metrics.Counter {
+	c.lvs = lvs
+	return c
+}
+
+func (c *counter) Inc()              { c.n++ }
+func (c *counter) Add(delta float64) {}
+
+// authenticate is the auth middleware placed inside the audit one.
+func authenticate(handler middleware.Handler) middleware.Handler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return handler(auth.NewContext(ctx, "alice"), req)
+	}
+}
+
+func newContext(method string) context.Context {
+	req := httptest.NewRequest(method, "/v1/users/1", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: "/test.Users/Delete"})
+	return http.NewContext(ctx, http.ServerInfo{Request: req})
+}
+
+func TestServer(t *testing.T) {
+	s := &sink{}
+	var fail error
+	h := middleware.Chain(
+		Server(WithSink(s), Extract("/test.Users/Delete", func(req interface{}) map[string]interface{} {
+			return map[string]interface{}{"id": req}
+		})),
+		authenticate,
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", fail
+	})
+
+	if _, err := h(newContext("GET"), "1"); err != nil || len(s.events) != 0 {
+		t.Fatalf("expected the safe methods not audited, got %v, error %v", s.events, err)
+	}
+	if _, err := h(newContext("DELETE"), "1"); err != nil {
+		t.Fatal(err)
+	}
+	fail = errors.NotFound("USER_NOT_FOUND", "user not found")
+	if _, err := h(newContext("DELETE"), "2"); err != fail {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+	if len(s.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(s.events))
+	}
+	e := s.events[0]
+	if e.Principal != "alice" || e.Operation != "/test.Users/Delete" || e.Fields["id"] != "1" || e.ClientIP.String() != "1.2.3.4" || e.Code != 0 {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.Time.IsZero() || e.Duration <= 0 {
+		t.Errorf("expected the timing recorded, got %+v", e)
+	}
+	if e = s.events[1]; e.Code != 404 || e.Reason != "USER_NOT_FOUND" {
+		t.Errorf("expected the outcome recorded, got %+v", e)
+	}
+}
+
+func TestSinkFailure(t *testing.T) {
+	failures := &counter{}
+	h := Server(WithSink(&sink{err: fmt.Errorf("sink unavailable")}), Failures(failures))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	if reply, err := h(newContext("POST"), nil); err != nil || reply != "reply" {
+		t.Fatalf("expected the sink failure never fails the request, got %v, error %v", reply, err)
+	}
+	if failures.n != 1 || len(failures.lvs) != 1 || failures.lvs[0] != "/test.Users/Delete" {
+		t.Errorf("expected the failure counted, got %d %v", failures.n, failures.lvs)
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	r := log.NewRecorder()
+	h := Server(WithSink(NewLogSink(r)))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	h(newContext("POST"), nil)
+	if !r.Contains("operation", "/test.Users/Delete") || !r.Contains("module", "audit") {
+		t.Errorf("unexpected entries %v", r.Entries())
+	}
+}
+
+func TestDefaultSink(t *testing.T) {
+	defer log.SetLogger(log.GetLogger())
+	r := log.NewRecorder()
+	log.SetLogger(r)
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	h(newContext("POST"), nil)
+	if !r.Contains("operation", "/test.Users/Delete") {
+		t.Errorf("expected the events written to the global logger, got %v", r.Entries())
+	}
+}
@@ -0,0 +1,128 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+const (
+	// ReasonKeyMissing is the error reason of requests without API keys.
+	ReasonKeyMissing = "API_KEY_MISSING"
+	// ReasonKeyInvalid is the error reason of requests with unknown API keys.
+	ReasonKeyInvalid = "API_KEY_INVALID"
+	// ReasonScopeDenied is the error reason of API keys lacking the scopes of the operation.
+	ReasonScopeDenied = "API_KEY_SCOPE_DENIED"
+)
+
+// Extractor extracts the API key from the request.
+type Extractor func(ctx context.Context) string
+
+// Header returns an extractor of the HTTP header or gRPC metadata key.
+func Header(name string) Extractor {
+	return func(ctx context.Context) string {
+		if tr, ok := transport.FromContext(ctx); ok && tr.Header != nil {
+			return tr.Header.Get(name)
+		}
+		return ""
+	}
+}
+
+// Query returns an extractor of the HTTP query parameter. The keys in the query end up in
+// the access logs and the proxy logs along with the URLs, so that it isn't a default extractor.
+func Query(name string) Extractor {
+	return func(ctx context.Context) string {
+		if info, ok := http.FromContext(ctx); ok {
+			return info.Request.URL.Query().Get(name)
+		}
+		return ""
+	}
+}
+
+type keyKey struct{}
+
+// NewContext returns a new Context that carries the resolved API key.
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// FromContext returns the resolved API key stored in ctx, if any.
+func FromContext(ctx context.Context) (key *Key, ok bool) {
+	key, ok = ctx.Value(keyKey{}).(*Key)
+	return
+}
+
+// Option is API key auth option.
+type Option func(*options)
+
+type options struct {
+	extractors []Extractor
+	store      KeyStore
+	scopes     map[string][]string
+}
+
+// Extract with the API key extractors, which are tried in order.
+func Extract(extractors ...Extractor) Option {
+	return func(o *options) {
+		o.extractors = extractors
+	}
+}
+
+// WithStore with API key store.
+func WithStore(s KeyStore) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// Scopes with the scopes required by the operation.
+func Scopes(operation string, scopes ...string) Option {
+	return func(o *options) {
+		o.scopes[operation] = scopes
+	}
+}
+
+// Server is a server middleware that authenticates requests by API keys, which
+// come from the X-API-Key header or metadata by default.
+// The principal of the key is stored in the context via auth.NewContext.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		extractors: []Extractor{Header("X-API-Key")},
+		store:      NewStaticStore(nil),
+		scopes:     make(map[string][]string),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var key string
+			for _, extract := range options.extractors {
+				if key = extract(ctx); key != "" {
+					break
+				}
+			}
+			if key == "" {
+				return nil, errors.Unauthorized(ReasonKeyMissing, "missing api key")
+			}
+			k, err := options.store.Lookup(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if k == nil {
+				return nil, ErrKeyNotFound
+			}
+			if tr, ok := transport.FromContext(ctx); ok {
+				if scopes := options.scopes[tr.Operation]; !k.HasScopes(scopes...) {
+					return nil, errors.PermissionDenied(ReasonScopeDenied, "api key lacks the scopes of %s", tr.Operation)
+				}
+			}
+			ctx = NewContext(ctx, k)
+			return handler(auth.NewContext(ctx, k.Principal), req)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package apikey
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+)
+
+func TestAPIKey(t *testing.T) {
+	m := Server(
+		WithStore(NewStaticStore(map[string]*Key{
+			"reader-key": {Principal: "reader", Scopes: []string{"read"}},
+			"writer-key": {Principal: "writer", Scopes: []string{"read", "write"}},
+		})),
+		Scopes("/v1/items/write", "write"),
+	)
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ := auth.FromContext(ctx)
+		return principal, nil
+	})
+	tests := []struct {
+		operation string
+		key       string
+		principal interface{}
+		check     func(error) bool
+	}{
+		{"/v1/items/read", "reader-key", "reader", nil},
+		{"/v1/items/write", "writer-key", "writer", nil},
+		{"/v1/items/write", "reader-key", nil, errors.IsPermissionDenied},
+		{"/v1/items/read", "unknown-key", nil, errors.IsUnauthorized},
+		{"/v1/items/read", "", nil, errors.IsUnauthorized},
+	}
+	for _, test := range tests {
+		header := transporttest.Header{}
+		if test.key != "" {
+			header.Set("X-API-Key", test.key)
+		}
+		ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "GRPC", Operation: test.operation, Header: header})
+		principal, err := h(ctx, nil)
+		if test.check != nil {
+			if !test.check(err) {
+				t.Errorf("%s with %q: unexpected error %v", test.operation, test.key, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if principal != test.principal {
+			t.Errorf("%s with %q: expected principal %v, but got %v", test.operation, test.key, test.principal, principal)
+		}
+	}
+}
+
+func TestCachingStore(t *testing.T) {
+	lookups := map[string]int{}
+	s := NewCachingStore(func(ctx context.Context, key string) (*Key, error) {
+		lookups[key]++
+		switch key {
+		case "valid":
+			return &Key{Principal: "valid"}, nil
+		case "failing":
+			return nil, errors.ServiceUnavailable("Unavailable", "store unavailable")
+		case "nil":
+			return nil, nil
+		}
+		return nil, ErrKeyNotFound
+	}, time.Minute, CacheSize(2))
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if k, err := s.Lookup(ctx, "valid"); err != nil || k.Principal != "valid" {
+			t.Fatalf("unexpected key %v, error %v", k, err)
+		}
+		if _, err := s.Lookup(ctx, "unknown"); err != ErrKeyNotFound {
+			t.Fatalf("expected ErrKeyNotFound, got %v", err)
+		}
+		if _, err := s.Lookup(ctx, "failing"); !errors.IsServiceUnavailable(err) {
+			t.Fatalf("expected the store error, got %v", err)
+		}
+	}
+	if lookups["valid"] != 1 || lookups["unknown"] != 1 || lookups["failing"] != 2 {
+		t.Errorf("unexpected lookups %v", lookups)
+	}
+	// the nil key is not found, and evicts the least recently used "valid".
+	if k, err := s.Lookup(ctx, "nil"); k != nil || err != ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound, got %v, %v", k, err)
+	}
+	if len(s.cache) != 2 {
+		t.Errorf("expected 2 cached keys, got %d", len(s.cache))
+	}
+	s.Lookup(ctx, "valid")
+	if lookups["valid"] != 2 {
+		t.Errorf("expected the evicted key to be looked up again, got %d", lookups["valid"])
+	}
+}
+
+func TestQueryExtractor(t *testing.T) {
+	store := WithStore(NewStaticStore(map[string]*Key{"reader-key": {Principal: "reader"}}))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ := auth.FromContext(ctx)
+		return principal, nil
+	}
+	req := httptest.NewRequest("GET", "/v1/items?api_key=reader-key", nil)
+	ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "HTTP", Operation: "/v1/items", Header: transporttest.Header{}})
+	ctx = http.NewContext(ctx, http.ServerInfo{Request: req})
+	// the keys in the query are leaked into the logs, so that they are opt-in.
+	if _, err := Server(store)(handler)(ctx, nil); !errors.IsUnauthorized(err) {
+		t.Errorf("expected the query key ignored by default, but got %v", err)
+	}
+	principal, err := Server(store, Extract(Query("api_key")))(handler)(ctx, nil)
+	if err != nil || principal != "reader" {
+		t.Errorf("expected the principal of the query key, but got %v %v", principal, err)
+	}
+}
@@ -0,0 +1,193 @@
+package apikey
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	_ KeyStore = (*StaticStore)(nil)
+	_ KeyStore = (*CachingStore)(nil)
+)
+
+// ErrKeyNotFound is returned by the key stores for unknown keys.
+var ErrKeyNotFound = errors.Unauthorized(ReasonKeyInvalid, "invalid api key")
+
+// Key is the resolved API key, which never holds the key itself.
+type Key struct {
+	// Principal is the owner of the key.
+	Principal interface{}
+	// Scopes is the scopes granted to the key.
+	Scopes []string
+}
+
+// HasScopes reports whether the key is granted all the scopes.
+func (k *Key) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		var granted bool
+		for _, s := range k.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyStore resolves the API keys, it returns ErrKeyNotFound for unknown keys.
+// A nil key without error is taken as unknown as well.
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (*Key, error)
+}
+
+type staticKey struct {
+	digest [sha256.Size]byte
+	key    *Key
+}
+
+// StaticStore is a key store of static keys.
+type StaticStore struct {
+	keys []staticKey
+}
+
+// NewStaticStore new a static key store, the keys are compared in constant time.
+func NewStaticStore(keys map[string]*Key) *StaticStore {
+	s := &StaticStore{keys: make([]staticKey, 0, len(keys))}
+	for k, v := range keys {
+		s.keys = append(s.keys, staticKey{digest: sha256.Sum256([]byte(k)), key: v})
+	}
+	return s
+}
+
+// Lookup compares the key with all the static keys, so the timing leaks neither the keys nor the matched one.
+func (s *StaticStore) Lookup(ctx context.Context, key string) (*Key, error) {
+	digest := sha256.Sum256([]byte(key))
+	var found *Key
+	for _, k := range s.keys {
+		if subtle.ConstantTimeCompare(digest[:], k.digest[:]) == 1 {
+			found = k.key
+		}
+	}
+	if found == nil {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// LookupFunc resolves the API key remotely.
+type LookupFunc func(ctx context.Context, key string) (*Key, error)
+
+// DefaultCacheSize is the default max number of the keys cached by the CachingStore.
+const DefaultCacheSize = 10000
+
+// CacheOption is caching store option.
+type CacheOption func(*CachingStore)
+
+// CacheSize with the max number of the cached keys, the least recently used
+// keys are evicted beyond it, which bounds the cache filled with unknown keys.
+func CacheSize(n int) CacheOption {
+	if n < 1 {
+		panic("apikey: the cache size must be positive")
+	}
+	return func(s *CachingStore) {
+		s.size = n
+	}
+}
+
+type cachedKey struct {
+	digest   [sha256.Size]byte
+	key      *Key
+	err      error
+	expireAt time.Time
+}
+
+// CachingStore is a key store caching the results of the remote lookup,
+// the cache is indexed by the key digests rather than the keys.
+type CachingStore struct {
+	lookup LookupFunc
+	ttl    time.Duration
+	size   int
+
+	mu    sync.Mutex
+	ll    *list.List
+	cache map[[sha256.Size]byte]*list.Element
+}
+
+// NewCachingStore new a caching key store, both the resolved and the unknown keys are cached for ttl.
+// The lookup returning a nil key without error is taken as ErrKeyNotFound.
+func NewCachingStore(lookup LookupFunc, ttl time.Duration, opts ...CacheOption) *CachingStore {
+	s := &CachingStore{
+		lookup: lookup,
+		ttl:    ttl,
+		size:   DefaultCacheSize,
+		ll:     list.New(),
+		cache:  make(map[[sha256.Size]byte]*list.Element),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Lookup resolves the key from the cache, or the remote lookup on misses.
+func (s *CachingStore) Lookup(ctx context.Context, key string) (*Key, error) {
+	digest := sha256.Sum256([]byte(key))
+	if c, ok := s.get(digest); ok {
+		return c.key, c.err
+	}
+	k, err := s.lookup(ctx, key)
+	if err == nil && k == nil {
+		err = ErrKeyNotFound
+	}
+	if err != nil && err != ErrKeyNotFound {
+		// the lookup failed, do not cache the transient errors.
+		return nil, err
+	}
+	s.set(cachedKey{digest: digest, key: k, err: err, expireAt: time.Now().Add(s.ttl)})
+	return k, err
+}
+
+func (s *CachingStore) get(digest [sha256.Size]byte) (*cachedKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.cache[digest]
+	if !ok {
+		return nil, false
+	}
+	c := el.Value.(*cachedKey)
+	if time.Now().After(c.expireAt) {
+		s.remove(el)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return c, true
+}
+
+func (s *CachingStore) set(c cachedKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.cache[c.digest]; ok {
+		*el.Value.(*cachedKey) = c
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.cache[c.digest] = s.ll.PushFront(&c)
+	for s.ll.Len() > s.size {
+		s.remove(s.ll.Back())
+	}
+}
+
+func (s *CachingStore) remove(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.cache, el.Value.(*cachedKey).digest)
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"sync"
+)
+
+type principalKey struct{}
+
+// NewContext returns a new Context that carries the authenticated principal,
+// which is recorded in the holder of WithPrincipal as well, if any.
+func NewContext(ctx context.Context, principal interface{}) context.Context {
+	if h, ok := ctx.Value(holderKey{}).(*holder); ok {
+		h.store(principal)
+	}
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated principal stored in ctx, or recorded in the
+// holder of WithPrincipal, if any.
+func FromContext(ctx context.Context) (principal interface{}, ok bool) {
+	if principal = ctx.Value(principalKey{}); principal != nil {
+		return principal, true
+	}
+	if h, ok := ctx.Value(holderKey{}).(*holder); ok {
+		principal = h.load()
+	}
+	return principal, principal != nil
+}
+
+type holderKey struct{}
+
+type holder struct {
+	mu        sync.Mutex
+	principal interface{}
+}
+
+func (h *holder) store(principal interface{}) {
+	h.mu.Lock()
+	h.principal = principal
+	h.mu.Unlock()
+}
+
+func (h *holder) load() interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.principal
+}
+
+// WithPrincipal returns a context that records the principal authenticated by the inner
+// middleware, it is used by the middlewares placed outside the auth middleware, i.e., audit.
+func WithPrincipal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, holderKey{}, new(holder))
+}
@@ -0,0 +1,116 @@
+package basic
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ReasonUnauthorized is the error reason of the rejected requests.
+const ReasonUnauthorized = "Unauthorized"
+
+// Validator verifies the credentials and returns the authenticated principal.
+type Validator func(ctx context.Context, user, pass string) (interface{}, error)
+
+// Static returns a validator of the static users and passwords,
+// the passwords are compared in constant time, and the principal is the user name.
+func Static(users map[string]string) Validator {
+	return func(ctx context.Context, user, pass string) (interface{}, error) {
+		expected, ok := users[user]
+		if !ok {
+			// compare anyway to not leak the existence of users by timing.
+			expected = pass + "-"
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(pass)) != 1 || !ok {
+			return nil, errors.Unauthorized(ReasonUnauthorized, "invalid user or password")
+		}
+		return user, nil
+	}
+}
+
+// Option is basic auth option.
+type Option func(*options)
+
+type options struct {
+	realm     string
+	validator Validator
+}
+
+// Realm with the realm of WWW-Authenticate challenges.
+func Realm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithValidator with credentials validator.
+func WithValidator(v Validator) Option {
+	return func(o *options) {
+		o.validator = v
+	}
+}
+
+// Server is a server middleware that authenticates requests by HTTP Basic auth.
+// The credentials come from the Authorization header of HTTP or the authorization
+// metadata of gRPC alike, and the authenticated principal is stored in the context
+// via auth.NewContext.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		realm: "Restricted",
+		validator: func(context.Context, string, string) (interface{}, error) {
+			return nil, errors.Unauthorized(ReasonUnauthorized, "no users configured")
+		},
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	challenge := fmt.Sprintf("Basic realm=%q", options.realm)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			user, pass, ok := credentials(ctx)
+			if !ok {
+				return nil, reject(ctx, challenge, errors.Unauthorized(ReasonUnauthorized, "missing basic credentials"))
+			}
+			principal, err := options.validator(ctx, user, pass)
+			if err != nil {
+				return nil, reject(ctx, challenge, err)
+			}
+			return handler(auth.NewContext(ctx, principal), req)
+		}
+	}
+}
+
+func credentials(ctx context.Context) (user, pass string, ok bool) {
+	var authorization string
+	if tr, ok := transport.FromContext(ctx); ok && tr.Header != nil {
+		authorization = tr.Header.Get("Authorization")
+	}
+	const prefix = "Basic "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(authorization[len(prefix):])
+	if err != nil {
+		return
+	}
+	cs := string(data)
+	i := strings.IndexByte(cs, ':')
+	if i < 0 {
+		return
+	}
+	return cs[:i], cs[i+1:], true
+}
+
+func reject(ctx context.Context, challenge string, err error) error {
+	if tr, ok := transport.FromContext(ctx); ok && tr.ReplyHeader != nil {
+		tr.ReplyHeader.Set("WWW-Authenticate", challenge)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+)
+
+func basicAuth(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestBasic(t *testing.T) {
+	m := Server(
+		Realm("test"),
+		WithValidator(Static(map[string]string{"admin": "secret"})),
+	)
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ := auth.FromContext(ctx)
+		return principal, nil
+	})
+	tests := []struct {
+		name          string
+		authorization string
+		ok            bool
+	}{
+		{"valid", basicAuth("admin", "secret"), true},
+		{"invalid password", basicAuth("admin", "wrong"), false},
+		{"unknown user", basicAuth("guest", "secret"), false},
+		{"missing", "", false},
+		{"malformed", "Basic !!!", false},
+		{"other scheme", "Bearer token", false},
+	}
+	// the credentials are read the same way whichever the transport is.
+	for _, kind := range []string{"HTTP", "GRPC"} {
+		for _, test := range tests {
+			t.Run(kind+" "+test.name, func(t *testing.T) {
+				header, reply := transporttest.Header{}, transporttest.Header{}
+				if test.authorization != "" {
+					header.Set("Authorization", test.authorization)
+				}
+				ctx := transport.NewContext(context.Background(), transport.Transport{
+					Kind:        kind,
+					Header:      header,
+					ReplyHeader: reply,
+				})
+				principal, err := h(ctx, nil)
+				if test.ok {
+					if err != nil {
+						t.Fatalf("expected no error, but got: %v", err)
+					}
+					if principal != "admin" {
+						t.Errorf("expected principal admin, but got: %v", principal)
+					}
+					return
+				}
+				if !errors.IsUnauthorized(err) {
+					t.Errorf("expected unauthorized error, but got: %v", err)
+				}
+				if reply.Get("WWW-Authenticate") != `Basic realm="test"` {
+					t.Errorf("expected WWW-Authenticate challenge, but got: %q", reply.Get("WWW-Authenticate"))
+				}
+			})
+		}
+	}
+}
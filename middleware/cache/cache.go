@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/internal/detach"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotCacheable is returned by the key function if the request cannot be cached.
+var ErrNotCacheable = errors.New("cache: request is not cacheable")
+
+// KeyFunc returns the cache key of the request.
+type KeyFunc func(ctx context.Context, operation string, req interface{}) (string, error)
+
+// Option is cache option.
+type Option func(*options)
+
+type options struct {
+	cache      Cache
+	keyFunc    KeyFunc
+	operations map[string]time.Duration
+}
+
+// WithCache with reply cache.
+func WithCache(c Cache) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
+
+// WithKey with cache key function.
+func WithKey(fn KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = fn
+	}
+}
+
+// Operations with the cached operations and their ttl, other operations are never cached.
+func Operations(ttls map[string]time.Duration) Option {
+	return func(o *options) {
+		for operation, ttl := range ttls {
+			o.operations[operation] = ttl
+		}
+	}
+}
+
+// Key returns the default cache key of the request,
+// which is the operation with the deterministic proto encoding of the request.
+func Key(operation string, req interface{}) (string, error) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return "", ErrNotCacheable
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return operation + ":" + string(data), nil
+}
+
+type cacheKey struct{}
+
+// scope is the cache and the key function of the middleware, in the request context.
+type scope struct {
+	cache   Cache
+	keyFunc KeyFunc
+}
+
+// Evict deletes the key from the cache of the request context,
+// it is used by mutating handlers to invalidate the stale replies.
+func Evict(ctx context.Context, key string) {
+	if s, ok := ctx.Value(cacheKey{}).(*scope); ok {
+		s.cache.Delete(ctx, key)
+	}
+}
+
+// EvictRequest deletes the reply of the request of the cached operation from the cache of
+// the request context, whose key is built by the key function of the middleware, i.e.,
+// EvictRequest(ctx, "/api.Users/Get", &pb.GetUserRequest{Id: id}) by the user update.
+func EvictRequest(ctx context.Context, operation string, req interface{}) error {
+	s, ok := ctx.Value(cacheKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+	key, err := s.keyFunc(ctx, operation, req)
+	if err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, key)
+	return nil
+}
+
+type hitKey struct{}
+
+// the states recorded by WithHit.
+const (
+	stateSkipped int32 = iota
+	stateMiss
+	stateHit
+)
+
+// WithHit returns a context that records whether the reply is served from cache,
+// it is used by the middlewares placed outside the cache middleware, i.e., logging.
+func WithHit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hitKey{}, new(int32))
+}
+
+// IsHit reports whether the reply is served from cache, the context must be returned by WithHit.
+func IsHit(ctx context.Context) bool {
+	hit, _ := Hit(ctx)
+	return hit
+}
+
+// Hit reports whether the reply is served from cache, and whether the request is of a
+// cached operation, the context must be returned by WithHit.
+func Hit(ctx context.Context) (hit bool, ok bool) {
+	state, found := ctx.Value(hitKey{}).(*int32)
+	if !found {
+		return false, false
+	}
+	switch atomic.LoadInt32(state) {
+	case stateHit:
+		return true, true
+	case stateMiss:
+		return false, true
+	}
+	return false, false
+}
+
+// record records the state in the holder of WithHit, if any.
+func record(ctx context.Context, state int32) {
+	if holder, ok := ctx.Value(hitKey{}).(*int32); ok {
+		atomic.StoreInt32(holder, state)
+	}
+}
+
+// Server is a server middleware that caches the replies of the configured operations,
+// concurrent misses of the same key are coalesced into a single handler execution,
+// which is detached from the cancellation of the waiters. Every caller gets its own
+// copy of the proto message replies, so that mutating a reply never alters the cached one.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		cache: NewLRU(10000),
+		keyFunc: func(ctx context.Context, operation string, req interface{}) (string, error) {
+			return Key(operation, req)
+		},
+		operations: make(map[string]time.Duration),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	var group singleflight.Group
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			ctx = context.WithValue(ctx, cacheKey{}, &scope{cache: options.cache, keyFunc: options.keyFunc})
+			tr, ok := transport.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			ttl, ok := options.operations[tr.Operation]
+			if !ok {
+				return handler(ctx, req)
+			}
+			key, err := options.keyFunc(ctx, tr.Operation, req)
+			if err != nil {
+				return handler(ctx, req)
+			}
+			if reply, ok := options.cache.Get(ctx, key); ok {
+				record(ctx, stateHit)
+				return clone(reply), nil
+			}
+			record(ctx, stateMiss)
+			ch := group.DoChan(key, func() (interface{}, error) {
+				ctx, cancel := detach.WithDeadline(ctx)
+				defer cancel()
+				reply, err := handler(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				options.cache.Set(ctx, key, reply, ttl)
+				return reply, nil
+			})
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case res := <-ch:
+				if res.Err != nil {
+					return nil, res.Err
+				}
+				return clone(res.Val), nil
+			}
+		}
+	}
+}
+
+// clone copies the proto message replies, other replies are shared as is.
+func clone(reply interface{}) interface{} {
+	if m, ok := reply.(proto.Message); ok {
+		return proto.Clone(m)
+	}
+	return reply
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const operation = "/test.Users/Get"
+
+func newContext(ctx context.Context) context.Context {
+	return WithHit(transport.NewContext(ctx, transport.Transport{Operation: operation}))
+}
+
+func TestServer(t *testing.T) {
+	var executions int32
+	h := Server(Operations(map[string]time.Duration{operation: time.Minute}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		return structpb.NewStringValue("reply"), nil
+	})
+	req := structpb.NewStringValue("request")
+	ctx := newContext(context.Background())
+	reply, err := h(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsHit(ctx) {
+		t.Errorf("expected a miss")
+	}
+	// mutating the reply never alters the cached one.
+	reply.(*structpb.Value).Kind = &structpb.Value_StringValue{StringValue: "mutated"}
+	ctx = newContext(context.Background())
+	if reply, err = h(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if !IsHit(ctx) {
+		t.Errorf("expected a hit")
+	}
+	if !proto.Equal(reply.(proto.Message), structpb.NewStringValue("reply")) {
+		t.Errorf("expected the cached reply, got %v", reply)
+	}
+	if executions != 1 {
+		t.Errorf("expected 1 execution, got %d", executions)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var executions int32
+	h := Server(Operations(map[string]time.Duration{operation: time.Minute}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return structpb.NewStringValue("reply"), nil
+	})
+	req := structpb.NewStringValue("request")
+	leader, cancel := context.WithCancel(newContext(context.Background()))
+	errc := make(chan error, 1)
+	go func() {
+		_, err := h(leader, req)
+		errc <- err
+	}()
+	<-started
+	const followers = 4
+	var wg sync.WaitGroup
+	replies := make([]interface{}, followers)
+	for i := 0; i < followers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], _ = h(newContext(context.Background()), req)
+		}(i)
+	}
+	// the leader giving up never fails the followers.
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected the leader canceled, got %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i, reply := range replies {
+		if !proto.Equal(reply.(proto.Message), structpb.NewStringValue("reply")) {
+			t.Fatalf("unexpected reply %v", reply)
+		}
+		for _, other := range replies[:i] {
+			if reply == other {
+				t.Errorf("expected every follower to get its own copy")
+			}
+		}
+	}
+	if executions != 1 {
+		t.Errorf("expected 1 execution, got %d", executions)
+	}
+}
+
+func TestEvictRequest(t *testing.T) {
+	const update = "/test.Users/Update"
+	var executions int32
+	h := Server(Operations(map[string]time.Duration{operation: time.Minute}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if tr, _ := transport.FromContext(ctx); tr.Operation == update {
+			// the update evicts the reply of the get of the same user.
+			return nil, EvictRequest(ctx, operation, req)
+		}
+		atomic.AddInt32(&executions, 1)
+		return structpb.NewStringValue("reply"), nil
+	})
+	req := structpb.NewStringValue("user")
+	for _, op := range []string{operation, operation, update, operation} {
+		ctx := WithHit(transport.NewContext(context.Background(), transport.Transport{Operation: op}))
+		if _, err := h(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := Hit(ctx); ok != (op == operation) {
+			t.Errorf("expected the cache ran only for the cached operation, got %v of %s", ok, op)
+		}
+	}
+	if executions != 2 {
+		t.Errorf("expected the reply evicted by the update, got %d executions", executions)
+	}
+	if err := EvictRequest(context.Background(), operation, req); err != nil {
+		t.Errorf("expected no error outside the middleware, got %v", err)
+	}
+}
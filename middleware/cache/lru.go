@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Cache = (*lru)(nil)
+
+// Cache is the reply cache.
+type Cache interface {
+	// Get returns the value of the key.
+	Get(ctx context.Context, key string) (interface{}, bool)
+	// Set sets the value of the key with ttl.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration)
+	// Delete deletes the key.
+	Delete(ctx context.Context, key string)
+}
+
+type element struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewLRU new an in-memory LRU cache bounded by size entries.
+func NewLRU(size int) Cache {
+	return &lru{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) Get(ctx context.Context, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*element)
+	if time.Now().After(e.expireAt) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *lru) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*element)
+		e.value, e.expireAt = value, time.Now().Add(ttl)
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&element{key: key, value: value, expireAt: time.Now().Add(ttl)})
+	for c.size > 0 && c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *lru) Delete(ctx context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *lru) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*element).key)
+}
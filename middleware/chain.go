@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Option is chain registration option.
+type Option func(*entry)
+
+// Before orders the middleware outside the named middleware.
+func Before(name string) Option {
+	return func(e *entry) {
+		e.before = append(e.before, name)
+	}
+}
+
+// After orders the middleware inside the named middleware.
+func After(name string) Option {
+	return func(e *entry) {
+		e.after = append(e.after, name)
+	}
+}
+
+type entry struct {
+	name   string
+	m      Middleware
+	before []string
+	after  []string
+}
+
+// Builder registers named middlewares with ordering constraints.
+type Builder struct {
+	entries []*entry
+}
+
+// NewChain new a middleware chain builder.
+func NewChain() *Builder {
+	return &Builder{}
+}
+
+// Use registers the named middleware, without constraints the middlewares
+// are ordered by registration, the first one being the outermost.
+func (b *Builder) Use(name string, m Middleware, opts ...Option) *Builder {
+	e := &entry{name: name, m: m}
+	for _, o := range opts {
+		o(e)
+	}
+	b.entries = append(b.entries, e)
+	return b
+}
+
+// Build resolves the ordering constraints topologically and returns the chained middleware,
+// it returns an error on duplicated names, unknown references, or conflicting constraints.
+func (b *Builder) Build() (Middleware, error) {
+	index := make(map[string]int, len(b.entries))
+	for i, e := range b.entries {
+		if _, ok := index[e.name]; ok {
+			return nil, fmt.Errorf("middleware: duplicated middleware %q", e.name)
+		}
+		index[e.name] = i
+	}
+	// edges[i] holds the middlewares which must be inside of i.
+	var (
+		edges       = make([][]int, len(b.entries))
+		indegree    = make([]int, len(b.entries))
+		constraints = make(map[[2]int]string)
+	)
+	edge := func(outer, inner int, constraint string) {
+		key := [2]int{outer, inner}
+		if _, ok := constraints[key]; ok {
+			return
+		}
+		constraints[key] = constraint
+		edges[outer] = append(edges[outer], inner)
+		indegree[inner]++
+	}
+	for i, e := range b.entries {
+		for _, name := range e.before {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("middleware: %q is ordered before unknown middleware %q", e.name, name)
+			}
+			edge(i, j, fmt.Sprintf("%q before %q", e.name, name))
+		}
+		for _, name := range e.after {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("middleware: %q is ordered after unknown middleware %q", e.name, name)
+			}
+			edge(j, i, fmt.Sprintf("%q after %q", e.name, name))
+		}
+	}
+	var (
+		ordered = make([]Middleware, 0, len(b.entries))
+		done    = make([]bool, len(b.entries))
+	)
+	for len(ordered) < len(b.entries) {
+		// pick the earliest registered middleware without pending constraints.
+		next := -1
+		for i := range b.entries {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var conflicts []string
+			for key, constraint := range constraints {
+				if !done[key[0]] && !done[key[1]] {
+					conflicts = append(conflicts, constraint)
+				}
+			}
+			sort.Strings(conflicts)
+			return nil, fmt.Errorf("middleware: conflicting ordering constraints: %s", strings.Join(conflicts, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, b.entries[next].m)
+		for _, inner := range edges[next] {
+			indegree[inner]--
+		}
+	}
+	if len(ordered) == 0 {
+		return func(h Handler) Handler { return h }, nil
+	}
+	return Chain(ordered[0], ordered[1:]...), nil
+}
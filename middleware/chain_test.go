@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func named(name string, trace *[]string) Middleware {
+	return func(handler Handler) Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			*trace = append(*trace, name)
+			return handler(ctx, req)
+		}
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	m, err := NewChain().
+		Use("auth", named("auth", &trace), After("metrics")).
+		Use("metrics", named("metrics", &trace)).
+		Use("tracing", named("tracing", &trace), Before("metrics"), After("recovery")).
+		Use("recovery", named("recovery", &trace)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if _, err := h(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"recovery", "tracing", "metrics", "auth"}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("expected %v, but got %v", expected, trace)
+	}
+}
+
+func TestChainConflict(t *testing.T) {
+	var trace []string
+	_, err := NewChain().
+		Use("recovery", named("recovery", &trace)).
+		Use("tracing", named("tracing", &trace), After("auth")).
+		Use("auth", named("auth", &trace), After("tracing")).
+		Build()
+	if err == nil {
+		t.Fatal("expected conflicting constraints error")
+	}
+	for _, constraint := range []string{`"tracing" after "auth"`, `"auth" after "tracing"`} {
+		if !strings.Contains(err.Error(), constraint) {
+			t.Errorf("expected error to list %s, but got: %v", constraint, err)
+		}
+	}
+	if strings.Contains(err.Error(), "recovery") {
+		t.Errorf("expected error to list only the conflicting constraints, but got: %v", err)
+	}
+}
+
+func TestChainUnknown(t *testing.T) {
+	var trace []string
+	_, err := NewChain().
+		Use("auth", named("auth", &trace), After("tracing")).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `unknown middleware "tracing"`) {
+		t.Errorf("expected unknown middleware error, but got: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package coalesce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/internal/detach"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/middleware/cache"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// files is the registry of the method descriptors of the operations.
+var files interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+} = protoregistry.GlobalFiles
+
+// KeyFunc returns the coalescing key of the request.
+type KeyFunc func(ctx context.Context, operation string, req interface{}) (string, error)
+
+// Option is coalesce option.
+type Option func(*options)
+
+type options struct {
+	keyFunc    KeyFunc
+	operations map[string]struct{}
+}
+
+// WithKey with coalescing key function.
+func WithKey(fn KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = fn
+	}
+}
+
+// Operations with the coalesced operations, other operations are never coalesced.
+func Operations(operations ...string) Option {
+	return func(o *options) {
+		for _, operation := range operations {
+			o.operations[operation] = struct{}{}
+		}
+	}
+}
+
+// Server is a server middleware that coalesces concurrent identical requests of the
+// same principal into a single handler execution, all waiters share the same error,
+// and get their own copy of the proto message replies. The shared execution is detached
+// from the cancellation of the waiters, so that a waiter giving up never cancels the others.
+// Only the safe requests are coalesced, which are the GET and HEAD requests of HTTP, and
+// for the other transports, the methods with the NO_SIDE_EFFECTS idempotency level if
+// their descriptors are registered.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		keyFunc: func(ctx context.Context, operation string, req interface{}) (string, error) {
+			return cache.Key(operation, req)
+		},
+		operations: make(map[string]struct{}),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	var group singleflight.Group
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			if _, ok := options.operations[tr.Operation]; !ok {
+				return handler(ctx, req)
+			}
+			if !isSafe(ctx, tr.Operation) {
+				return handler(ctx, req)
+			}
+			key, err := options.keyFunc(ctx, tr.Operation, req)
+			if err != nil {
+				return handler(ctx, req)
+			}
+			if principal, ok := auth.FromContext(ctx); ok {
+				key = fmt.Sprintf("%v\x00%s", principal, key)
+			}
+			ch := group.DoChan(key, func() (interface{}, error) {
+				ctx, cancel := detach.WithDeadline(ctx)
+				defer cancel()
+				return handler(ctx, req)
+			})
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case res := <-ch:
+				if res.Err != nil || !res.Shared {
+					return res.Val, res.Err
+				}
+				return clone(res.Val), nil
+			}
+		}
+	}
+}
+
+// isSafe reports whether the request of the operation has no side effects.
+func isSafe(ctx context.Context, operation string) bool {
+	if info, ok := http.FromContext(ctx); ok {
+		m := info.Request.Method
+		return m == "GET" || m == "HEAD"
+	}
+	// the gRPC operation is /package.Service/Method.
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(operation, "/"), "/", ".", 1))
+	if !name.IsValid() {
+		return true
+	}
+	d, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return true
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return true
+	}
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	return ok && opts.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+}
+
+// clone copies the proto message replies, other replies are shared as is.
+func clone(reply interface{}) interface{} {
+	if m, ok := reply.(proto.Message); ok {
+		return proto.Clone(m)
+	}
+	return reply
+}
@@ -0,0 +1,208 @@
+package coalesce
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const operation = "/test.Users/Get"
+
+func TestServer(t *testing.T) {
+	release := make(chan struct{})
+	var executions int32
+	h := Server(Operations(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		<-release
+		return "reply", nil
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation})
+	req := structpb.NewStringValue("request")
+	const waiters = 5
+	var wg sync.WaitGroup
+	replies := make([]interface{}, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], _ = h(ctx, req)
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if executions != 1 {
+		t.Errorf("expected 1 execution, got %d", executions)
+	}
+	for _, reply := range replies {
+		if reply != "reply" {
+			t.Errorf("expected the shared reply, got %v", reply)
+		}
+	}
+}
+
+func TestWaiterCanceled(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Server(Operations(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "reply", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	base := transport.NewContext(context.Background(), transport.Transport{Operation: operation})
+	req := structpb.NewStringValue("request")
+	first, cancel := context.WithCancel(base)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := h(first, req)
+		errc <- err
+	}()
+	<-started
+	replyc := make(chan interface{}, 1)
+	go func() {
+		reply, _ := h(base, req)
+		replyc <- reply
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// the first waiter giving up never cancels the shared execution.
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected the waiter canceled, got %v", err)
+	}
+	close(release)
+	if reply := <-replyc; reply != "reply" {
+		t.Errorf("expected the shared reply, got %v", reply)
+	}
+}
+
+func TestBypass(t *testing.T) {
+	var executions int32
+	h := Server(Operations(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		return "reply", nil
+	})
+	req := structpb.NewStringValue("request")
+	tr := transport.Transport{Operation: operation}
+	// the mutating HTTP methods, the other operations, and the requests without a key are never coalesced.
+	ctxs := []context.Context{
+		http.NewContext(transport.NewContext(context.Background(), tr), http.ServerInfo{Request: httptest.NewRequest("POST", "/", nil)}),
+		transport.NewContext(context.Background(), transport.Transport{Operation: "/test.Users/Update"}),
+		context.Background(),
+	}
+	for _, ctx := range ctxs {
+		if reply, err := h(ctx, req); err != nil || reply != "reply" {
+			t.Errorf("unexpected reply %v, error %v", reply, err)
+		}
+	}
+	if _, err := h(transport.NewContext(context.Background(), tr), "not a proto message"); err != nil {
+		t.Fatal(err)
+	}
+	if executions != 4 {
+		t.Errorf("expected 4 executions, got %d", executions)
+	}
+}
+
+// coalesce runs the waiters of the contexts concurrently, and returns their replies.
+func coalesce(h middleware.Handler, release chan struct{}, req interface{}, ctxs ...context.Context) []interface{} {
+	var wg sync.WaitGroup
+	replies := make([]interface{}, len(ctxs))
+	for i, ctx := range ctxs {
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			replies[i], _ = h(ctx, req)
+		}(i, ctx)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return replies
+}
+
+func TestCloneAndPrincipal(t *testing.T) {
+	release := make(chan struct{})
+	var executions int32
+	h := Server(Operations(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&executions, 1)
+		<-release
+		principal, _ := auth.FromContext(ctx)
+		return structpb.NewStringValue(principal.(string)), nil
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation})
+	alice, bob := auth.NewContext(ctx, "alice"), auth.NewContext(ctx, "bob")
+	replies := coalesce(h, release, structpb.NewStringValue("request"), alice, alice, bob)
+	if executions != 2 {
+		t.Errorf("expected 1 execution per principal, got %d", executions)
+	}
+	for i, want := range []string{"alice", "alice", "bob"} {
+		if m, ok := replies[i].(*structpb.Value); !ok || m.GetStringValue() != want {
+			t.Errorf("expected the reply of %s, got %v", want, replies[i])
+		}
+	}
+	if replies[0] == replies[1] {
+		t.Errorf("expected a copy of the reply per waiter")
+	}
+}
+
+func TestSideEffects(t *testing.T) {
+	level := descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("coalesce_test.proto"),
+		Package:    proto.String("coalesce"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Users"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Get"), InputType: proto.String(".google.protobuf.Value"), OutputType: proto.String(".google.protobuf.Value"),
+					Options: &descriptorpb.MethodOptions{IdempotencyLevel: &level}},
+				{Name: proto.String("Charge"), InputType: proto.String(".google.protobuf.Value"), OutputType: proto.String(".google.protobuf.Value")},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := new(protoregistry.Files)
+	if err := registry.RegisterFile(fd); err != nil {
+		t.Fatal(err)
+	}
+	defer func(f interface {
+		FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+	}) {
+		files = f
+	}(files)
+	files = registry
+
+	for operation, want := range map[string]int32{"/coalesce.Users/Get": 1, "/coalesce.Users/Charge": 3} {
+		release := make(chan struct{})
+		var executions int32
+		h := Server(Operations(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+			atomic.AddInt32(&executions, 1)
+			<-release
+			return "reply", nil
+		})
+		ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "GRPC", Operation: operation})
+		coalesce(h, release, structpb.NewStringValue("request"), ctx, ctx, ctx)
+		if executions != want {
+			t.Errorf("%s: expected %d executions, got %d", operation, want, executions)
+		}
+	}
+}
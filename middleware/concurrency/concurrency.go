@@ -0,0 +1,80 @@
+package concurrency
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Option is concurrency option.
+type Option func(*options)
+
+type options struct {
+	limiters map[string]*Limiter
+	inflight metrics.Gauge
+	queued   metrics.Gauge
+}
+
+// Operation with the limiter of the operation, the operations without limiters are not limited.
+func Operation(operation string, l *Limiter) Option {
+	return func(o *options) {
+		o.limiters[operation] = l
+	}
+}
+
+// WithInFlight with the gauge of the executions in flight, labeled by operation.
+func WithInFlight(g metrics.Gauge) Option {
+	return func(o *options) {
+		o.inflight = g
+	}
+}
+
+// WithQueued with the gauge of the waiting requests, labeled by operation.
+func WithQueued(g metrics.Gauge) Option {
+	return func(o *options) {
+		o.queued = g
+	}
+}
+
+// Server is a server middleware that limits the concurrent executions per operation.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		limiters: make(map[string]*Limiter),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	for operation, l := range options.limiters {
+		operation := operation
+		l.mu.Lock()
+		l.observe = func(inflight, queued int) {
+			if options.inflight != nil {
+				options.inflight.With(operation).Set(float64(inflight))
+			}
+			if options.queued != nil {
+				options.queued.With(operation).Set(float64(queued))
+			}
+		}
+		l.mu.Unlock()
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			l, ok := options.limiters[tr.Operation]
+			if !ok {
+				return handler(ctx, req)
+			}
+			release, err := l.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			return handler(ctx, req)
+		}
+	}
+}
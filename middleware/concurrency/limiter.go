@@ -0,0 +1,132 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ReasonConcurrencyExceeded is the error reason of rejected requests.
+const ReasonConcurrencyExceeded = "CONCURRENCY_EXCEEDED"
+
+// Limiter limits the concurrent executions, the requests beyond the limit wait
+// in a bounded FIFO queue until a slot is released or the wait times out.
+type Limiter struct {
+	mu       sync.Mutex
+	limit    int
+	maxWait  int
+	timeout  time.Duration
+	inflight int
+	waiters  *list.List
+	observe  func(inflight, queued int)
+}
+
+// NewLimiter new a limiter of limit concurrent executions, at most maxWait requests
+// wait up to timeout for a slot, a zero maxWait fails fast beyond the limit.
+func NewLimiter(limit, maxWait int, timeout time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		maxWait: maxWait,
+		timeout: timeout,
+		waiters: list.New(),
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// SetLimit sets the concurrency limit at runtime, the waiters are granted
+// immediately if the limit is raised.
+func (l *Limiter) SetLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.grant()
+	l.notify()
+}
+
+// InFlight returns the number of executions in flight.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// Queued returns the number of waiting requests.
+func (l *Limiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiters.Len()
+}
+
+// Acquire acquires a slot, the returned release func must be called once the execution is done.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	l.mu.Lock()
+	if l.inflight < l.limit && l.waiters.Len() == 0 {
+		l.inflight++
+		l.notify()
+		l.mu.Unlock()
+		return l.release, nil
+	}
+	if l.waiters.Len() >= l.maxWait {
+		l.mu.Unlock()
+		return nil, errors.ResourceExhausted(ReasonConcurrencyExceeded, "concurrency limit %d exceeded", l.limit)
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.notify()
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-timer.C:
+		err = errors.ResourceExhausted(ReasonConcurrencyExceeded, "concurrency limit wait timeout %s", l.timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready:
+		// the slot has been granted concurrently, hand it over to the next waiter.
+		l.inflight--
+		l.grant()
+	default:
+		l.waiters.Remove(elem)
+	}
+	l.notify()
+	return nil, err
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.grant()
+	l.notify()
+}
+
+// grant grants the free slots to the waiters in FIFO order.
+func (l *Limiter) grant() {
+	for l.inflight < l.limit && l.waiters.Len() > 0 {
+		ready := l.waiters.Remove(l.waiters.Front()).(chan struct{})
+		l.inflight++
+		close(ready)
+	}
+}
+
+func (l *Limiter) notify() {
+	if l.observe != nil {
+		l.observe(l.inflight, l.waiters.Len())
+	}
+}
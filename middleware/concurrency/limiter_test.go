@@ -0,0 +1,90 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestLimiterFIFO(t *testing.T) {
+	l := NewLimiter(1, 10, time.Second)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		go func(i int) {
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		// wait until the waiter is queued to fix the arrival order.
+		for l.Queued() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	release()
+	for i := 0; i < 5; i++ {
+		if got := <-order; got != i {
+			t.Fatalf("expected waiter %d, but got %d", i, got)
+		}
+	}
+	if l.InFlight() != 0 || l.Queued() != 0 {
+		t.Errorf("expected idle limiter, but got inflight %d queued %d", l.InFlight(), l.Queued())
+	}
+}
+
+func TestLimiterRejects(t *testing.T) {
+	l := NewLimiter(1, 1, 10*time.Millisecond)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	done := make(chan error)
+	go func() {
+		_, err := l.Acquire(context.Background())
+		done <- err
+	}()
+	for l.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := l.Acquire(context.Background()); !errors.IsResourceExhausted(err) {
+		t.Errorf("expected fail fast with full queue, but got %v", err)
+	}
+	if err := <-done; !errors.IsResourceExhausted(err) {
+		t.Errorf("expected wait timeout, but got %v", err)
+	}
+	if l.Queued() != 0 {
+		t.Errorf("expected empty queue, but got %d", l.Queued())
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l := NewLimiter(1, 1, time.Second)
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := l.Acquire(context.Background())
+		done <- err
+	}()
+	for l.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	l.SetLimit(2)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if l.InFlight() != 2 {
+		t.Errorf("expected 2 in flight, but got %d", l.InFlight())
+	}
+}
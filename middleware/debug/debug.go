@@ -0,0 +1,258 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/stdlog"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/timeout"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultHeader is the default header carrying the debug token.
+const DefaultHeader = "X-Debug-Token"
+
+// DefaultRedactHeaders are the headers carrying the credentials, whose values are masked in the dumps.
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-API-Key",
+	"X-Auth-Token",
+	"X-CSRF-Token",
+}
+
+// DefaultRedactFields are the fields carrying the credentials, whose values are masked in the
+// dumped bodies. The names are matched regardless of the case, the dashes and the underscores,
+// i.e., api_key matches the apiKey of the proto JSON.
+var DefaultRedactFields = []string{
+	"password",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"api_key",
+	"authorization",
+}
+
+const redacted = "***"
+
+// Option is debug option.
+type Option func(*options)
+
+type options struct {
+	header    string
+	requestID string
+	limit     int
+	redact    []string
+	fields    []string
+	logger    log.Logger
+}
+
+// Header with the header carrying the debug token.
+func Header(name string) Option {
+	return func(o *options) {
+		o.header = name
+	}
+}
+
+// RequestID with the header carrying the request id, which tags the dumps.
+func RequestID(name string) Option {
+	return func(o *options) {
+		o.requestID = name
+	}
+}
+
+// Limit with the max bytes of the dumped request and reply bodies.
+func Limit(n int) Option {
+	return func(o *options) {
+		o.limit = n
+	}
+}
+
+// RedactHeaders with the headers whose values are masked in the dumps,
+// which replace DefaultRedactHeaders.
+func RedactHeaders(names ...string) Option {
+	return func(o *options) {
+		o.redact = names
+	}
+}
+
+// RedactFields with the fields of the requests and the replies whose values are masked in
+// the dumps, which replace DefaultRedactFields.
+func RedactFields(names ...string) Option {
+	return func(o *options) {
+		o.fields = names
+	}
+}
+
+// Logger with the dump logger, which should not filter the debug level
+// since the dumps are requested explicitly.
+func Logger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Server is a server middleware that dumps the requests carrying the debug token,
+// other requests only cost one header lookup. An empty token disables the dumps.
+// The values of the credential headers and body fields are masked in the dumps.
+func Server(token string, opts ...Option) middleware.Middleware {
+	options := options{
+		header:    DefaultHeader,
+		requestID: "X-Request-ID",
+		limit:     4096,
+		redact:    DefaultRedactHeaders,
+		fields:    DefaultRedactFields,
+		logger:    stdlog.NewLogger(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	logger := log.With(options.logger, "module", "debug")
+	redact := names(append(options.redact, options.header))
+	fields := names(options.fields)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok || tr.Header == nil || token == "" {
+				return handler(ctx, req)
+			}
+			if subtle.ConstantTimeCompare([]byte(tr.Header.Get(options.header)), []byte(token)) != 1 {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			reply, err := handler(ctx, req)
+			kvpair := []interface{}{
+				"request_id", tr.Header.Get(options.requestID),
+				"kind", tr.Kind,
+				"operation", tr.Operation,
+				"metadata", dumpHeader(tr.Header, redact),
+				"request", dump(req, options.limit, fields),
+				"latency", time.Since(start),
+			}
+			if b, ok := timeout.FromContext(ctx); ok {
+				// the budget consumed before the handler, i.e., by decoding and the outer middleware.
+				kvpair = append(kvpair, "before_handler", start.Sub(b.Start), "timeout", b.Timeout)
+			}
+			if err != nil {
+				kvpair = append(kvpair, "code", errors.Code(err), "error", err.Error())
+			} else {
+				kvpair = append(kvpair, "reply", dump(reply, options.limit, fields))
+			}
+			if tr.ReplyHeader != nil {
+				kvpair = append(kvpair, "reply_metadata", dumpHeader(tr.ReplyHeader, redact))
+			}
+			logger.Log(log.LevelDebug, kvpair...)
+			return reply, err
+		}
+	}
+}
+
+// names returns the set of the normalized names.
+func names(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, name := range list {
+		set[normalize(name)] = struct{}{}
+	}
+	return set
+}
+
+// normalize folds the case, the dashes and the underscores of the names of the headers and
+// the fields, i.e., X-API-Key, api_key and apiKey.
+func normalize(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// dumpHeader dumps the header with the values of the redact names masked.
+func dumpHeader(h transport.Header, redact map[string]struct{}) string {
+	keys := h.Keys()
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := h.Get(k)
+		if _, ok := redact[normalize(k)]; ok {
+			v = redacted
+		}
+		pairs = append(pairs, k+": "+v)
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// dump dumps the value with the values of the redact fields masked, the proto messages as
+// the proto JSON, the other values of the kinds other than the scalars as the JSON, and the
+// values failing to marshal by the type name only, since they can't be redacted.
+func dump(v interface{}, limit int, fields map[string]struct{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil, string, bool, int, int32, int64, uint, uint32, uint64, float32, float64, error:
+		s = fmt.Sprintf("%+v", v)
+	default:
+		var (
+			data []byte
+			err  error
+		)
+		if msg, ok := v.(proto.Message); ok {
+			data, err = protojson.Marshal(msg)
+		} else {
+			data, err = json.Marshal(v)
+		}
+		if err == nil {
+			data, err = redactJSON(data, fields)
+		}
+		if err != nil {
+			s = fmt.Sprintf("%T", v)
+		} else {
+			s = string(data)
+		}
+	}
+	if limit > 0 && len(s) > limit {
+		return fmt.Sprintf("%s...(%d bytes truncated)", s[:limit], len(s)-limit)
+	}
+	return s
+}
+
+// redactJSON masks the values of the redact fields in the JSON.
+func redactJSON(data []byte, fields map[string]struct{}) ([]byte, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(v, fields))
+}
+
+func redactValue(v interface{}, fields map[string]struct{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if _, ok := fields[normalize(k)]; ok {
+				v[k] = redacted
+			} else {
+				v[k] = redactValue(e, fields)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redactValue(e, fields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,128 @@
+package debug
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestServer(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		tr, _ := transport.FromContext(ctx)
+		tr.ReplyHeader.Set("Set-Cookie", "session=secret")
+		return "reply", nil
+	}
+	tests := []struct {
+		name  string
+		token string
+		opts  []Option
+		dump  bool
+		shown []string
+		hide  []string
+	}{
+		{
+			name:  "redact",
+			token: "token",
+			dump:  true,
+			shown: []string{"X-Request-Id: request", "Set-Cookie: ***", "Authorization: ***", "X-Debug-Token: ***"},
+			hide:  []string{"Bearer secret", "session=secret", "token"},
+		},
+		{
+			name:  "custom",
+			token: "token",
+			opts:  []Option{RedactHeaders("X-Request-ID")},
+			dump:  true,
+			shown: []string{"X-Request-Id: ***", "Authorization: Bearer secret", "X-Debug-Token: ***"},
+		},
+		{
+			name:  "mismatch",
+			token: "other",
+		},
+		{
+			name: "disabled",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := log.NewRecorder()
+			header := transporttest.Header{}
+			header.Set("X-Request-ID", "request")
+			header.Set("Authorization", "Bearer secret")
+			header.Set(DefaultHeader, "token")
+			ctx := transport.NewContext(context.Background(), transport.Transport{
+				Header:      header,
+				ReplyHeader: transporttest.Header{},
+			})
+			opts := append([]Option{Logger(r)}, test.opts...)
+			reply, err := Server(test.token, opts...)(handler)(ctx, "request")
+			if err != nil || reply != "reply" {
+				t.Fatalf("unexpected reply %v, error %v", reply, err)
+			}
+			entries := r.Entries()
+			if !test.dump {
+				if len(entries) != 0 {
+					t.Fatalf("expected no dump, got %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected one dump, got %d", len(entries))
+			}
+			e := entries[0]
+			if e.Value("request_id") != "request" || e.Value("request") != "request" || e.Value("reply") != "reply" {
+				t.Errorf("unexpected dump %v", e.KeyVals)
+			}
+			dumped := e.Value("metadata").(string) + e.Value("reply_metadata").(string)
+			for _, s := range test.shown {
+				if !strings.Contains(dumped, s) {
+					t.Errorf("expected %q in %s", s, dumped)
+				}
+			}
+			for _, s := range test.hide {
+				if strings.Contains(dumped, s) {
+					t.Errorf("unexpected %q in %s", s, dumped)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpLimit(t *testing.T) {
+	if s := dump(strings.Repeat("a", 10), 4, nil); s != "aaaa...(6 bytes truncated)" {
+		t.Errorf("unexpected dump %s", s)
+	}
+	if s := dump("short", 10, nil); s != "short" {
+		t.Errorf("unexpected dump %s", s)
+	}
+}
+
+func TestDumpRedact(t *testing.T) {
+	fields := names(DefaultRedactFields)
+	msg, _ := structpb.NewStruct(map[string]interface{}{
+		"name":  "alice",
+		"login": map[string]interface{}{"Password": "secret", "apiKey": "secret"},
+		"keys":  []interface{}{map[string]interface{}{"api_key": "secret"}},
+	})
+	type login struct {
+		Name  string
+		Token string `json:"access-token"`
+	}
+	for _, v := range []interface{}{msg, login{Name: "alice", Token: "secret"}, &login{Name: "alice", Token: "secret"}} {
+		s := dump(v, 0, fields)
+		if strings.Contains(s, "secret") || !strings.Contains(s, "alice") || !strings.Contains(s, redacted) {
+			t.Errorf("expected the fields redacted, got %s", s)
+		}
+	}
+	if s := dump(make(chan string), 0, fields); s != "chan string" {
+		t.Errorf("expected the type of the value failing to marshal, got %s", s)
+	}
+	if s := dump(login{Token: "secret"}, 0, names(nil)); !strings.Contains(s, "secret") {
+		t.Errorf("expected the fields kept without the redact fields, got %s", s)
+	}
+}
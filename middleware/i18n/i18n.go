@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/locale"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"golang.org/x/text/language"
+)
+
+// NewContext returns a new Context that carries the locale, see locale.NewContext.
+func NewContext(ctx context.Context, tag language.Tag) context.Context {
+	return locale.NewContext(ctx, tag)
+}
+
+// FromContext returns the locale stored in ctx, if any, see locale.FromContext.
+func FromContext(ctx context.Context) (language.Tag, bool) {
+	return locale.FromContext(ctx)
+}
+
+// Translator translates the message of the error reason into the locale.
+type Translator = locale.Translator
+
+// Messages is the messages keyed by the locale and then by the error reason.
+type Messages = locale.Messages
+
+// RegisterTranslator registers the translator of the error messages, see locale.RegisterTranslator.
+func RegisterTranslator(t Translator) {
+	locale.RegisterTranslator(t)
+}
+
+// GetTranslator returns the registered translator, nil if there is none.
+func GetTranslator() Translator {
+	return locale.GetTranslator()
+}
+
+// NewTranslator returns a translator of the messages, see locale.NewTranslator.
+func NewTranslator(fallback language.Tag, messages Messages) Translator {
+	return locale.NewTranslator(fallback, messages)
+}
+
+// LoadTranslator returns a translator of the messages in the json files, see locale.LoadTranslator.
+func LoadTranslator(fallback language.Tag, paths ...string) (Translator, error) {
+	return locale.LoadTranslator(fallback, paths...)
+}
+
+// Option is i18n option.
+type Option func(*options)
+
+type options struct {
+	supported []language.Tag
+	fallback  language.Tag
+}
+
+// Supported with the supported locales.
+func Supported(tags ...language.Tag) Option {
+	return func(o *options) {
+		o.supported = tags
+	}
+}
+
+// Default with the default locale when nothing matches.
+func Default(tag language.Tag) Option {
+	return func(o *options) {
+		o.fallback = tag
+	}
+}
+
+// Server is a server middleware that negotiates the locale from the Accept-Language
+// header of HTTP or the accept-language metadata of gRPC, and stores it in the context.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		fallback: language.English,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	supported := append([]language.Tag{options.fallback}, options.supported...)
+	matcher := language.NewMatcher(supported)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tag := options.fallback
+			if accept := acceptLanguage(ctx); accept != "" {
+				// garbage values are ignored, the valid prefix is still honored.
+				tags, _, _ := language.ParseAcceptLanguage(accept)
+				if len(tags) > 0 {
+					if _, index, conf := matcher.Match(tags...); conf != language.No {
+						tag = supported[index]
+					}
+				}
+			}
+			return handler(NewContext(ctx, tag), req)
+		}
+	}
+}
+
+func acceptLanguage(ctx context.Context) string {
+	if tr, ok := transport.FromContext(ctx); ok && tr.Header != nil {
+		return tr.Header.Get("Accept-Language")
+	}
+	return ""
+}
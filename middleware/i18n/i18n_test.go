@@ -0,0 +1,42 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+
+	"golang.org/x/text/language"
+)
+
+func TestLocale(t *testing.T) {
+	m := Server(
+		Default(language.English),
+		Supported(language.SimplifiedChinese, language.Japanese),
+	)
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		tag, _ := FromContext(ctx)
+		return tag, nil
+	})
+	tests := []struct {
+		accept string
+		tag    language.Tag
+	}{
+		{"", language.English},
+		{"ja", language.Japanese},
+		{"zh-CN,zh;q=0.9,en;q=0.8", language.SimplifiedChinese},
+		{"fr;q=0.9,ja;q=0.5", language.Japanese},
+		{"fr-FR", language.English},
+		{"!!!garbage;;q=x", language.English},
+	}
+	for _, test := range tests {
+		header := transporttest.Header{}
+		header.Set("Accept-Language", test.accept)
+		ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "HTTP", Header: header})
+		reply, _ := h(ctx, nil)
+		if reply.(language.Tag) != test.tag {
+			t.Errorf("accept %q: expected %s, but got %s", test.accept, test.tag, reply)
+		}
+	}
+}
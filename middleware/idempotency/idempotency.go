@@ -0,0 +1,192 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// DefaultHeader is the default idempotency key header.
+const DefaultHeader = "Idempotency-Key"
+
+// Option is idempotency option.
+type Option func(*options)
+
+type options struct {
+	header      string
+	ttl         time.Duration
+	lockTTL     time.Duration
+	cacheErrors bool
+	store       Store
+	logger      log.Logger
+}
+
+// Header with the header carrying the idempotency key.
+func Header(name string) Option {
+	return func(o *options) {
+		o.header = name
+	}
+}
+
+// TTL with the retention of completed results.
+func TTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// LockTTL with the retention of in-flight markers, which bounds how long
+// a crashed execution blocks retries.
+func LockTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.lockTTL = d
+	}
+}
+
+// CacheErrors with whether failed results are stored and replayed,
+// otherwise the key is released so that the client can retry.
+func CacheErrors(cache bool) Option {
+	return func(o *options) {
+		o.cacheErrors = cache
+	}
+}
+
+// WithStore with result store.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// Logger with the logger of the results failed to store.
+func Logger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// record is the stored state of an idempotency key.
+type record struct {
+	Done    bool   `json:"done"`
+	Reply   []byte `json:"reply,omitempty"`
+	Code    int32  `json:"code,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Server is a server middleware that executes the requests carrying
+// the same idempotency key at most once, and replays the stored result.
+// Only the proto message replies are replayable, the key of other replies is
+// released after the execution. The store errors fail the request with Unavailable,
+// except storing the result once executed, which is logged and the result returned,
+// so the key stays in flight until the lock ttl rather than the request executed again.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		header:  DefaultHeader,
+		ttl:     24 * time.Hour,
+		lockTTL: time.Minute,
+		store:   NewMemoryStore(),
+		logger:  log.GetLogger(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	log := log.NewHelper("idempotency", options.logger)
+	pending, _ := json.Marshal(&record{})
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok || tr.Header == nil {
+				return handler(ctx, req)
+			}
+			key := tr.Header.Get(options.header)
+			if key == "" {
+				return handler(ctx, req)
+			}
+			key = tr.Operation + ":" + key
+			ok, err := options.store.SetNX(ctx, key, pending, options.lockTTL)
+			if err != nil {
+				return nil, errors.Unavailable("IdempotencyStore", "idempotency store: %v", err)
+			}
+			if !ok {
+				return replay(ctx, options.store, key)
+			}
+			reply, err := handler(ctx, req)
+			if err != nil && !options.cacheErrors {
+				if derr := options.store.Delete(ctx, key); derr != nil {
+					return nil, errors.Unavailable("IdempotencyStore", "idempotency store: %v", derr)
+				}
+				return nil, err
+			}
+			data, merr := marshal(reply, err)
+			if merr != nil {
+				// the reply is not replayable, release the key.
+				if derr := options.store.Delete(ctx, key); derr != nil {
+					return nil, errors.Unavailable("IdempotencyStore", "idempotency store: %v", derr)
+				}
+				return reply, err
+			}
+			if serr := options.store.Set(ctx, key, data, options.ttl); serr != nil {
+				log.Errorf("failed to store the result of %s: %v", key, serr)
+			}
+			return reply, err
+		}
+	}
+}
+
+func replay(ctx context.Context, store Store, key string) (interface{}, error) {
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, errors.Unavailable("IdempotencyStore", "idempotency store: %v", err)
+	}
+	var r record
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, errors.Internal("IdempotencyStore", "idempotency record: %v", err)
+		}
+	}
+	if !r.Done {
+		return nil, errors.Aborted("IdempotencyConflict", "request with the same idempotency key is in flight")
+	}
+	if r.Code != 0 {
+		return nil, errors.New(int(r.Code), r.Reason, r.Message)
+	}
+	any := new(anypb.Any)
+	if err := proto.Unmarshal(r.Reply, any); err != nil {
+		return nil, errors.Internal("IdempotencyStore", "idempotency reply: %v", err)
+	}
+	reply, err := any.UnmarshalNew()
+	if err != nil {
+		return nil, errors.Internal("IdempotencyStore", "idempotency reply: %v", err)
+	}
+	return reply, nil
+}
+
+func marshal(reply interface{}, err error) ([]byte, error) {
+	r := record{Done: true}
+	if err != nil {
+		se := errors.FromError(err)
+		r.Code, r.Reason, r.Message = se.Code, se.Reason, se.Message
+		return json.Marshal(&r)
+	}
+	m, ok := reply.(proto.Message)
+	if !ok {
+		return nil, errors.Internal("IdempotencyStore", "reply is not a proto message: %T", reply)
+	}
+	any, err := anypb.New(m)
+	if err != nil {
+		return nil, err
+	}
+	if r.Reply, err = proto.Marshal(any); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&r)
+}
@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newContext(key string) context.Context {
+	header := transporttest.Header{}
+	header.Set(DefaultHeader, key)
+	return transport.NewContext(context.Background(), transport.Transport{Operation: "/test.Payment/Charge", Header: header})
+}
+
+func TestReplay(t *testing.T) {
+	var executions int
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		executions++
+		return structpb.NewStringValue(fmt.Sprintf("reply-%d", executions)), nil
+	})
+	for i := 0; i < 3; i++ {
+		reply, err := h(newContext("key"), "request")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(reply.(proto.Message), structpb.NewStringValue("reply-1")) {
+			t.Errorf("expected the replayed reply, got %v", reply)
+		}
+	}
+	if _, err := h(newContext("other"), "request"); err != nil || executions != 2 {
+		t.Errorf("expected the other key to execute, got %d executions, error %v", executions, err)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return structpb.NewStringValue("reply"), nil
+	})
+	done := make(chan error)
+	go func() {
+		_, err := h(newContext("key"), "request")
+		done <- err
+	}()
+	<-started
+	_, err := h(newContext("key"), "request")
+	if !errors.IsAborted(err) || errors.Code(err) != 409 {
+		t.Errorf("expected 409 conflict, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		var executions int
+		h := Server(CacheErrors(cache))(func(ctx context.Context, req interface{}) (interface{}, error) {
+			executions++
+			return nil, errors.Conflict("Declined", "card declined %d", executions)
+		})
+		var err error
+		for i := 0; i < 2; i++ {
+			_, err = h(newContext("key"), "request")
+		}
+		want := 2
+		if cache {
+			want = 1
+		}
+		if executions != want {
+			t.Errorf("cache %v: expected %d executions, got %d", cache, want, executions)
+		}
+		if se := errors.FromError(err); se.Code != 409 || se.Reason != "Declined" || se.Message != fmt.Sprintf("card declined %d", want) {
+			t.Errorf("cache %v: unexpected error %v", cache, err)
+		}
+	}
+}
+
+func TestNonProtoReply(t *testing.T) {
+	var executions int
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		executions++
+		return "reply", nil
+	})
+	for i := 0; i < 2; i++ {
+		if reply, err := h(newContext("key"), "request"); err != nil || reply != "reply" {
+			t.Fatalf("unexpected reply %v, error %v", reply, err)
+		}
+	}
+	if executions != 2 {
+		t.Errorf("expected the key to be released, got %d executions", executions)
+	}
+}
+
+type failingStore struct {
+	Store
+}
+
+func (s failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return fmt.Errorf("store unavailable")
+}
+
+func TestStoreError(t *testing.T) {
+	logger := log.NewRecorder()
+	var executions int
+	h := Server(WithStore(failingStore{NewMemoryStore()}), Logger(logger))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		executions++
+		return structpb.NewStringValue("reply"), nil
+	})
+	reply, err := h(newContext("key"), "request")
+	if err != nil || reply.(*structpb.Value).GetStringValue() != "reply" {
+		t.Errorf("expected the result of the execution, got %v %v", reply, err)
+	}
+	if errs := logger.FilterByLevel(log.LevelError); len(errs) != 1 || !strings.Contains(fmt.Sprint(errs[0].Value(log.DefaultMessageKey)), "store unavailable") {
+		t.Errorf("expected the store error logged, got %v", logger.Entries())
+	}
+	// the key stays in flight rather than the request executed again.
+	if _, err := h(newContext("key"), "request"); !errors.IsAborted(err) || executions != 1 {
+		t.Errorf("expected the key kept in flight, got %v after %d executions", err, executions)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	ctx := context.Background()
+	s.Set(ctx, "expired", []byte("value"), time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	s.nextSweep = time.Time{}
+	s.Set(ctx, "key", []byte("value"), time.Minute)
+	if _, ok := s.entries["expired"]; ok || len(s.entries) != 1 {
+		t.Errorf("expected the expired entry to be swept, got %v", s.entries)
+	}
+}
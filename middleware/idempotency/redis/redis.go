@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware/idempotency"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+// Store is a redis idempotency store.
+type Store struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewStore new a redis idempotency store, the keys are prefixed with prefix.
+func NewStore(client redis.UniversalClient, prefix string) *Store {
+	return &Store{client: client, prefix: prefix}
+}
+
+// Get returns the value of the key, or nil if the key does not exist.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return value, err
+}
+
+// SetNX sets the value of the key only if the key does not exist.
+func (s *Store) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, value, ttl).Result()
+}
+
+// Set sets the value of the key.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// Delete deletes the key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,76 @@
+// +build integration
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The tests run against the redis at 127.0.0.1:6379, i.e.,
+// docker run -p 6379:6379 redis
+// go test -tags integration
+func newStore(t *testing.T) *Store {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(client, "test:idempotency:"+t.Name()+":")
+}
+
+func TestStore(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	defer s.Delete(ctx, "key")
+	if v, err := s.Get(ctx, "key"); err != nil || v != nil {
+		t.Fatalf("expected nil for the absent key, got %q %v", v, err)
+	}
+	if ok, err := s.SetNX(ctx, "key", []byte("pending"), time.Minute); err != nil || !ok {
+		t.Fatalf("expected the key locked, got %v %v", ok, err)
+	}
+	if ok, err := s.SetNX(ctx, "key", []byte("other"), time.Minute); err != nil || ok {
+		t.Fatalf("expected the locked key kept, got %v %v", ok, err)
+	}
+	if err := s.Set(ctx, "key", []byte("done"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(ctx, "key"); err != nil || string(v) != "done" {
+		t.Fatalf("expected done, got %q %v", v, err)
+	}
+	if err := s.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.SetNX(ctx, "key", []byte("pending"), time.Minute); err != nil || !ok {
+		t.Fatalf("expected the deleted key locked again, got %v %v", ok, err)
+	}
+}
+
+func TestStoreTTL(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	if ok, err := s.SetNX(ctx, "key", []byte("pending"), 100*time.Millisecond); err != nil || !ok {
+		t.Fatalf("expected the key locked, got %v %v", ok, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if v, err := s.Get(ctx, "key"); err != nil || v != nil {
+		t.Fatalf("expected the lock expired, got %q %v", v, err)
+	}
+}
+
+func TestStorePrefix(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+	ctx := context.Background()
+	a, b := NewStore(client, "test:a:"), NewStore(client, "test:b:")
+	defer a.Delete(ctx, "key")
+	if err := a.Set(ctx, "key", []byte("a"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.Get(ctx, "key"); err != nil || v != nil {
+		t.Errorf("expected the keys isolated by the prefix, got %q %v", v, err)
+	}
+}
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Store = (*memoryStore)(nil)
+
+// Store is the result store of idempotent requests.
+type Store interface {
+	// Get returns the value of the key, or nil if the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// SetNX sets the value of the key only if the key does not exist.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Set sets the value of the key.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete deletes the key.
+	Delete(ctx context.Context, key string) error
+}
+
+type entry struct {
+	value    []byte
+	expireAt time.Time
+}
+
+// sweepInterval is the min interval between the sweeps of the expired entries.
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	nextSweep time.Time
+}
+
+// NewMemoryStore new an in-memory store, which is only suitable for a single instance.
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]entry)}
+}
+
+// set sets the value of the key, and sweeps the expired entries once per sweepInterval,
+// so that the keys never read again do not pile up.
+func (s *memoryStore) set(key string, value []byte, ttl time.Duration) {
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expireAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+	s.entries[key] = entry{value: value, expireAt: now.Add(ttl)}
+}
+
+func (s *memoryStore) get(key string) ([]byte, bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expireAt) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, _ := s.get(key)
+	return value, nil
+}
+
+func (s *memoryStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(key); ok {
+		return false, nil
+	}
+	s.set(key, value, ttl)
+	return true, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value, ttl)
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
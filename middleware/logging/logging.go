@@ -3,16 +3,48 @@ package logging
 import (
 	"context"
 	"path"
+	"strings"
 
+	"github.com/go-kratos/kratos/v2/appinfo"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metadata"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/cache"
+	"github.com/go-kratos/kratos/v2/middleware/timeout"
 	"github.com/go-kratos/kratos/v2/transport/grpc"
 	"github.com/go-kratos/kratos/v2/transport/http"
 )
 
+// Option is logging option.
+type Option func(*options)
+
+type options struct {
+	mdKeys []string
+}
+
+// WithMetadata with the metadata keys logged as the fields, i.e., md.tenant for the key
+// x-md-global-tenant, the well-known keys by default, see metadata.WellKnownKeys, none if
+// empty. The keys beyond the well-known ones bloat the logs with the caller-controlled values.
+func WithMetadata(keys ...string) Option {
+	return func(o *options) {
+		o.mdKeys = keys
+	}
+}
+
+func newOptions(opts []Option) options {
+	options := options{
+		mdKeys: metadata.WellKnownKeys(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
 // GRPCServer is a gRPC logging middleware.
-func GRPCServer(logger log.Logger) middleware.Middleware {
+func GRPCServer(logger log.Logger, opts ...Option) middleware.Middleware {
+	options := newOptions(opts)
 	log := log.NewHelper("grpc", logger)
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -25,30 +57,32 @@ func GRPCServer(logger log.Logger) middleware.Middleware {
 				service = path.Dir(info.FullMethod)[1:]
 				method = path.Base(info.FullMethod)
 			}
+			ctx = timeout.WithBudget(cache.WithHit(ctx))
 			reply, err := handler(ctx, req)
 			if err != nil {
-				log.Errorw(
+				log.WithContext(ctx).Errorw(withStack(err, withFields(ctx, options.mdKeys,
 					"kind", "server",
 					"grpc.service", service,
 					"grpc.method", method,
 					"grpc.code", errors.Code(err),
 					"grpc.error", err.Error(),
-				)
+				))...)
 				return nil, err
 			}
-			log.Infow(
+			log.WithContext(ctx).Infow(withFields(ctx, options.mdKeys,
 				"kind", "server",
 				"grpc.service", service,
 				"grpc.method", method,
 				"grpc.code", 0,
-			)
+			)...)
 			return reply, nil
 		}
 	}
 }
 
 // HTTPServer is a gRPC logging middleware.
-func HTTPServer(logger log.Logger) middleware.Middleware {
+func HTTPServer(logger log.Logger, opts ...Option) middleware.Middleware {
+	options := newOptions(opts)
 	log := log.NewHelper("http", logger)
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -61,24 +95,61 @@ func HTTPServer(logger log.Logger) middleware.Middleware {
 				path = info.Request.RequestURI
 				method = info.Request.Method
 			}
+			ctx = timeout.WithBudget(cache.WithHit(ctx))
 			reply, err := handler(ctx, req)
 			if err != nil {
-				log.Errorw(
+				log.WithContext(ctx).Errorw(withStack(err, withFields(ctx, options.mdKeys,
 					"kind", "server",
 					"http.path", path,
 					"http.method", method,
 					"http.code", errors.Code(err),
 					"http.error", err.Error(),
-				)
+				))...)
 				return nil, err
 			}
-			log.Infow(
+			log.WithContext(ctx).Infow(withFields(ctx, options.mdKeys,
 				"kind", "server",
 				"http.path", path,
 				"http.method", method,
 				"http.code", 0,
-			)
+			)...)
 			return reply, nil
 		}
 	}
 }
+
+// withFields appends the identity of the app, the metadata of the keys, whether the reply
+// is served from cache if the operation is cached, and the deadline budget recorded by the timeout middleware, if any.
+func withFields(ctx context.Context, mdKeys []string, kvpair ...interface{}) []interface{} {
+	if info, ok := appinfo.FromContext(ctx); ok {
+		kvpair = append(kvpair, "service.id", info.ID(), "service.name", info.Name(), "service.version", info.Version())
+	}
+	for _, key := range mdKeys {
+		if v := metadata.Value(ctx, key); v != "" {
+			kvpair = append(kvpair, "md."+metadata.TrimPrefix(key), v)
+		}
+	}
+	if hit, ok := cache.Hit(ctx); ok {
+		kvpair = append(kvpair, "cache_hit", hit)
+	}
+	if b, ok := timeout.FromContext(ctx); ok {
+		kvpair = append(kvpair, "timeout", b.Timeout, "consumed", b.Consumed())
+	}
+	return kvpair
+}
+
+// withStack appends the stack trace of the server errors, if captured by errors.EnableStack.
+func withStack(err error, kvpair []interface{}) []interface{} {
+	if errors.Code(err) < 500 {
+		return kvpair
+	}
+	frames := errors.StackTrace(err)
+	if len(frames) == 0 {
+		return kvpair
+	}
+	stack := make([]string, 0, len(frames))
+	for _, f := range frames {
+		stack = append(stack, f.String())
+	}
+	return append(kvpair, "stack", strings.Join(stack, "\n"))
+}
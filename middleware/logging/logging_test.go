@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/cache"
+	"github.com/go-kratos/kratos/v2/middleware/timeout"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestHTTPServer(t *testing.T) {
+	logger := log.NewRecorder()
+	var fail error
+	h := HTTPServer(logger)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", fail
+	})
+	app := kratos.New(kratos.ID("1"), kratos.Name("helloworld"), kratos.Version("v1.0.0"))
+	ctx := http.NewContext(kratos.NewContext(context.Background(), app), http.ServerInfo{
+		Request: httptest.NewRequest("GET", "/v1/users/1", nil),
+	})
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries := logger.FilterByLevel(log.LevelInfo)
+	if len(entries) != 1 || entries[0].Value("http.path") != "/v1/users/1" || entries[0].Value("http.method") != "GET" {
+		t.Errorf("unexpected access log: %v", logger.Entries())
+	}
+	if !logger.Contains("service.name", "helloworld") || !logger.Contains("service.version", "v1.0.0") {
+		t.Errorf("expected the app identity logged, but got %v", logger.Entries())
+	}
+
+	logger.Reset()
+	if _, err := h(metadata.WithTenant(ctx, "t1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.Contains("md.tenant", "t1") {
+		t.Errorf("expected the well-known metadata logged, but got %v", logger.Entries())
+	}
+	logger.Reset()
+	HTTPServer(logger, WithMetadata())(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})(metadata.WithTenant(ctx, "t1"), nil)
+	if logger.Contains("md.tenant", "t1") {
+		t.Errorf("expected no metadata logged, but got %v", logger.Entries())
+	}
+
+	logger.Reset()
+	fail = errors.NotFound("USER_NOT_FOUND", "user not found").WithCause(stderrors.New("sql: no rows in result set"))
+	if _, err := h(ctx, nil); err != fail {
+		t.Fatalf("expected the handler error, but got %v", err)
+	}
+	entries = logger.FilterByLevel(log.LevelError)
+	if len(entries) != 1 || !logger.Contains("http.code", errors.Code(fail)) {
+		t.Errorf("unexpected error log: %v", logger.Entries())
+	}
+	if s, _ := entries[0].Value("http.error").(string); !strings.Contains(s, "sql: no rows") {
+		t.Errorf("expected the cause logged, but got %s", s)
+	}
+}
+
+func TestStack(t *testing.T) {
+	errors.EnableStack(true)
+	defer errors.EnableStack(false)
+	logger := log.NewRecorder()
+	var fail error
+	h := HTTPServer(logger)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, fail
+	})
+	fail = errors.InternalServer("DB", "database error")
+	h(context.Background(), nil)
+	if s, _ := logger.Entries()[0].Value("stack").(string); !strings.Contains(s, "logging.TestStack") {
+		t.Errorf("expected the stack logged, but got %q", s)
+	}
+
+	logger.Reset()
+	fail = errors.NotFound("USER_NOT_FOUND", "user not found")
+	h(context.Background(), nil)
+	if logger.Entries()[0].Value("stack") != nil {
+		t.Errorf("expected no stack for the client errors, but got %v", logger.Entries())
+	}
+}
+
+func TestCacheHit(t *testing.T) {
+	const operation = "/test.Users/Get"
+	logger := log.NewRecorder()
+	h := middleware.Chain(
+		HTTPServer(logger),
+		cache.Server(cache.Operations(map[string]time.Duration{operation: time.Minute})),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return structpb.NewStringValue("reply"), nil
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation})
+	for _, hit := range []bool{false, true} {
+		logger.Reset()
+		if _, err := h(ctx, structpb.NewStringValue("request")); err != nil {
+			t.Fatal(err)
+		}
+		if !logger.Contains("cache_hit", hit) {
+			t.Errorf("expected cache_hit %v, but got %v", hit, logger.Entries())
+		}
+	}
+	// the operations not cached have no cache_hit.
+	logger.Reset()
+	if _, err := h(transport.NewContext(context.Background(), transport.Transport{Operation: "/test.Users/Update"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := logger.Entries()[0].Value("cache_hit"); v != nil {
+		t.Errorf("expected no cache_hit without the cache, but got %v", v)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	logger := log.NewRecorder()
+	h := middleware.Chain(HTTPServer(logger), timeout.Server(timeout.Default(time.Minute)))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	})
+	if _, err := h(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	e := logger.Entries()[0]
+	if e.Value("timeout") != time.Minute {
+		t.Errorf("expected the timeout logged, but got %v", e.KeyVals)
+	}
+	if consumed, ok := e.Value("consumed").(time.Duration); !ok || consumed <= 0 {
+		t.Errorf("expected the consumed time logged, but got %v", e.KeyVals)
+	}
+}
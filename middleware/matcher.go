@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Matcher reports whether the operation matches.
+type Matcher func(ctx context.Context, operation string) bool
+
+// Exact matches the operations exactly.
+func Exact(operations ...string) Matcher {
+	set := make(map[string]struct{}, len(operations))
+	for _, operation := range operations {
+		set[operation] = struct{}{}
+	}
+	return func(ctx context.Context, operation string) bool {
+		_, ok := set[operation]
+		return ok
+	}
+}
+
+// Prefix matches the operations with any of the prefixes.
+func Prefix(prefixes ...string) Matcher {
+	return func(ctx context.Context, operation string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(operation, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Glob matches the operations with any of the shell patterns, see path.Match for the syntax,
+// i.e., /helloworld.Greeter/* or /v1/users/*/posts.
+func Glob(patterns ...string) Matcher {
+	return func(ctx context.Context, operation string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, operation); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Any matches the operations matched by any of the matchers.
+func Any(matchers ...Matcher) Matcher {
+	return func(ctx context.Context, operation string) bool {
+		for _, m := range matchers {
+			if m(ctx, operation) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Skip returns a middleware that bypasses m for the operations matched by matcher.
+func Skip(m Middleware, matcher Matcher) Middleware {
+	return func(handler Handler) Handler {
+		next := m(handler)
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromContext(ctx); ok && matcher(ctx, tr.Operation) {
+				return handler(ctx, req)
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// WithSkip returns a decorator that bypasses the middleware for the operations,
+// the operations containing any of *?[ are glob patterns, others match exactly.
+func WithSkip(operations ...string) func(Middleware) Middleware {
+	var exact, patterns []string
+	for _, operation := range operations {
+		if strings.ContainsAny(operation, "*?[") {
+			patterns = append(patterns, operation)
+		} else {
+			exact = append(exact, operation)
+		}
+	}
+	matcher := Any(Exact(exact...), Glob(patterns...))
+	return func(m Middleware) Middleware {
+		return Skip(m, matcher)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestWithSkip(t *testing.T) {
+	var trace []string
+	h := WithSkip("/healthz", "/helloworld.Greeter/*")(named("auth", &trace))(
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil },
+	)
+	tests := []struct {
+		operation string
+		skipped   bool
+	}{
+		{"/healthz", true},
+		{"/healthz/live", false},
+		{"/helloworld.Greeter/SayHello", true},
+		{"/helloworld.Admin/SayHello", false},
+	}
+	for _, test := range tests {
+		trace = nil
+		ctx := transport.NewContext(context.Background(), transport.Transport{Operation: test.operation})
+		if _, err := h(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		if skipped := len(trace) == 0; skipped != test.skipped {
+			t.Errorf("operation %s: expected skipped %v, but got %v", test.operation, test.skipped, skipped)
+		}
+	}
+}
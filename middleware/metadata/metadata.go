@@ -0,0 +1,78 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/appinfo"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+// Option is metadata option.
+type Option func(*options)
+
+type options struct {
+	constants metadata.Metadata
+}
+
+// WithConstants with the metadata sent with every request, i.e., the environment and the
+// region, the keys must have the global or the local prefixes, see metadata.GlobalPrefix,
+// it panics if any key is invalid.
+func WithConstants(md map[string]string) Option {
+	constants := metadata.New(md)
+	for k := range constants {
+		if err := validKey(k); err != nil {
+			panic(err)
+		}
+	}
+	return func(o *options) {
+		for k, v := range constants {
+			o.constants[k] = v
+		}
+	}
+}
+
+func validKey(key string) error {
+	for _, p := range metadata.DefaultPrefixes() {
+		if strings.HasPrefix(key, p) && len(key) > len(p) {
+			return nil
+		}
+	}
+	return fmt.Errorf("metadata: invalid constant key %q, which must have the prefix %s or %s", key, metadata.GlobalPrefix, metadata.LocalPrefix)
+}
+
+// Client is a client middleware that merges the constant metadata into the outgoing metadata,
+// with the name and the version of the app in the context as the caller, see appinfo.FromContext.
+// The values of the request win, i.e., set by the call site or propagated from the upstream.
+func Client(opts ...Option) middleware.Middleware {
+	options := options{
+		constants: metadata.Metadata{},
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			constants := options.constants
+			if info, ok := appinfo.FromContext(ctx); ok {
+				constants = metadata.New(map[string]string{metadata.CallerKey: info.Name(), metadata.CallerVersionKey: info.Version()})
+				for k, v := range options.constants {
+					constants[k] = v
+				}
+			}
+			out := metadata.Outgoing(ctx)
+			md, _ := metadata.FromClientContext(ctx)
+			if md == nil {
+				md = metadata.Metadata{}
+			}
+			for k, v := range constants {
+				if _, ok := out[k]; !ok && v[0] != "" {
+					md[k] = v
+				}
+			}
+			return handler(metadata.NewClientContext(ctx, md), req)
+		}
+	}
+}
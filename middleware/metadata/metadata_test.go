@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/metadata"
+)
+
+func TestClient(t *testing.T) {
+	m := Client(WithConstants(map[string]string{
+		"x-md-global-env":    "prod",
+		"X-Md-Local-Region":  "sh",
+		"x-md-global-tenant": "default",
+	}))
+	var got metadata.Metadata
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = metadata.Outgoing(ctx)
+		return nil, nil
+	})
+	ctx := kratos.NewContext(context.Background(), kratos.New(kratos.Name("helloworld"), kratos.Version("v1.0.0")))
+	// the propagated and the explicit values win.
+	ctx = metadata.NewServerContext(ctx, metadata.Pairs("x-md-global-env", "staging"))
+	ctx = metadata.NewClientContext(ctx, metadata.Pairs("x-md-global-tenant", "t"))
+	h(ctx, nil)
+	want := metadata.Metadata{
+		"x-md-global-env":         {"staging"},
+		"x-md-local-region":       {"sh"},
+		"x-md-global-tenant":      {"t"},
+		metadata.CallerKey:        {"helloworld"},
+		metadata.CallerVersionKey: {"v1.0.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, but got %v", want, got)
+	}
+}
+
+func TestWithConstantsInvalid(t *testing.T) {
+	for _, key := range []string{"", "env", "x-md-global-"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected the key %q invalid", key)
+				}
+			}()
+			WithConstants(map[string]string{key: "v"})
+		}()
+	}
+}
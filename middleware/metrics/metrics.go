@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/stdlog"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LabelsFunc returns the extra labels of the request.
+type LabelsFunc func(ctx context.Context, req interface{}) map[string]string
+
+// Option is metrics option.
+type Option func(*options)
+
+type options struct {
+	requests   metrics.Counter
+	seconds    metrics.Observer
+	operations map[string]metrics.Observer
+	keys       []string
+	labels     LabelsFunc
+	mdKeys     []string
+	logger     log.Logger
+	rejected   sync.Map
+}
+
+// WithRequests with the requests counter,
+// labeled by kind, operation, code, reason, and the extra label keys.
+func WithRequests(c metrics.Counter) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+// WithSeconds with the latency histogram,
+// labeled by kind, operation, and the extra label keys.
+func WithSeconds(c metrics.Observer) Option {
+	return func(o *options) {
+		o.seconds = c
+	}
+}
+
+// WithOperationSeconds with the latency histogram of the operation,
+// which overrides the buckets of endpoints with very different latency profiles.
+func WithOperationSeconds(operation string, c metrics.Observer) Option {
+	return func(o *options) {
+		o.operations[operation] = c
+	}
+}
+
+// WithLabels with the extra labels of the declared keys, the missing ones are empty. The
+// undeclared keys are rejected to protect the cardinality, which is logged once per key.
+func WithLabels(keys []string, fn LabelsFunc) Option {
+	return func(o *options) {
+		o.keys = keys
+		o.labels = fn
+	}
+}
+
+// Logger with the logger of the rejected labels.
+func Logger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// maxPriority is the max priority label, the greater priorities are labeled by it.
+const maxPriority = 10
+
+// WithMetadataLabels with the extra labels of the metadata keys of bounded values, following
+// the keys of WithLabels, none by default. The keys are metadata.CallerKey, metadata.ShadowKey
+// labeled by true or false, and metadata.PriorityKey labeled by the priority clamped to
+// [0, 10]. It panics on the other keys, i.e., metadata.TenantKey, whose caller-controlled
+// values explode the cardinality.
+func WithMetadataLabels(keys ...string) Option {
+	for _, key := range keys {
+		switch key {
+		case metadata.CallerKey, metadata.ShadowKey, metadata.PriorityKey:
+		default:
+			panic(fmt.Sprintf("metrics: the metadata key %q is not bounded", key))
+		}
+	}
+	return func(o *options) {
+		o.mdKeys = keys
+	}
+}
+
+// mdLabel returns the label value of the metadata key, normalized to a bounded set.
+func mdLabel(ctx context.Context, key string) string {
+	switch key {
+	case metadata.ShadowKey:
+		return strconv.FormatBool(metadata.Shadow(ctx))
+	case metadata.PriorityKey:
+		p := metadata.Priority(ctx)
+		if p < 0 {
+			p = 0
+		} else if p > maxPriority {
+			p = maxPriority
+		}
+		return strconv.Itoa(p)
+	}
+	return metadata.Value(ctx, key)
+}
+
+// Server is a server middleware that records the requests and latency,
+// the trace id of the active span is attached as an exemplar when supported.
+func Server(opts ...Option) middleware.Middleware {
+	options := &options{
+		operations: make(map[string]metrics.Observer),
+		logger:     stdlog.NewLogger(),
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	log := log.NewHelper("metrics", options.logger)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var (
+				kind      string
+				operation string
+			)
+			if tr, ok := transport.FromContext(ctx); ok {
+				kind = tr.Kind
+				operation = tr.Operation
+			}
+			extra := options.extra(ctx, req, log)
+			startTime := time.Now()
+			reply, err := handler(ctx, req)
+			if options.requests != nil {
+				lvs := append([]string{kind, operation, strconv.Itoa(errors.Code(err)), errors.Reason(err)}, extra...)
+				options.requests.With(lvs...).Inc()
+			}
+			seconds, ok := options.operations[operation]
+			if !ok {
+				seconds = options.seconds
+			}
+			if seconds != nil {
+				observe(ctx, seconds.With(append([]string{kind, operation}, extra...)...), time.Since(startTime).Seconds())
+			}
+			return reply, err
+		}
+	}
+}
+
+func (o *options) extra(ctx context.Context, req interface{}, log *log.Helper) []string {
+	lvs := make([]string, 0, len(o.keys)+len(o.mdKeys))
+	if o.labels != nil {
+		labels := o.labels(ctx, req)
+		for _, key := range o.keys {
+			lvs = append(lvs, labels[key])
+		}
+		o.reject(labels, log)
+	}
+	for _, key := range o.mdKeys {
+		lvs = append(lvs, mdLabel(ctx, key))
+	}
+	return lvs
+}
+
+func observe(ctx context.Context, o metrics.Observer, value float64) {
+	if eo, ok := o.(metrics.ExemplarObserver); ok {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			eo.ObserveWithExemplar(value, map[string]string{"trace_id": sc.TraceID.String()})
+			return
+		}
+	}
+	o.Observe(value)
+}
+
+func (o *options) isKey(key string) bool {
+	for _, k := range o.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// reject logs the undeclared keys of labels, once per key.
+func (o *options) reject(labels map[string]string, log *log.Helper) {
+	for key := range labels {
+		if o.isKey(key) {
+			continue
+		}
+		if _, loaded := o.rejected.LoadOrStore(key, struct{}{}); !loaded {
+			log.Errorf("the undeclared label %q is rejected, declare it by WithLabels", key)
+		}
+	}
+}
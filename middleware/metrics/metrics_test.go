@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type counter struct {
+	lvs [][]string
+}
+
+func (c *counter) With(lvs ...string) metrics.Counter {
+	c.lvs = append(c.lvs, lvs)
+	return c
+}
+
+func (c *counter) Inc()              {}
+func (c *counter) Add(delta float64) {}
+
+func TestServer(t *testing.T) {
+	requests := &counter{}
+	h := Server(
+		WithRequests(requests),
+		WithLabels([]string{"region"}, func(ctx context.Context, req interface{}) map[string]string {
+			return map[string]string{"region": "eu"}
+		}),
+		WithMetadataLabels(metadata.ShadowKey, metadata.PriorityKey),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.NotFound("NotFound", "not found")
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "GRPC", Operation: "/test.Users/Get"})
+	tests := []struct {
+		shadow   string
+		priority string
+		want     []string
+	}{
+		{"yes", "100", []string{"true", "10"}},
+		{"", "", []string{"false", "0"}},
+		{"no", "-3", []string{"false", "0"}},
+		{"", "5", []string{"false", "5"}},
+	}
+	for _, test := range tests {
+		requests.lvs = nil
+		md := metadata.Metadata{}
+		md.Set(metadata.ShadowKey, test.shadow)
+		md.Set(metadata.PriorityKey, test.priority)
+		h(metadata.NewServerContext(ctx, md), nil)
+		want := append([]string{"GRPC", "/test.Users/Get", "404", "NotFound", "eu"}, test.want...)
+		if len(requests.lvs) != 1 || !reflect.DeepEqual(requests.lvs[0], want) {
+			t.Errorf("expected the labels %v, but got %v", want, requests.lvs)
+		}
+	}
+}
+
+func TestMetadataLabelsUnbounded(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on the tenant key")
+		}
+	}()
+	WithMetadataLabels(metadata.TenantKey)
+}
+
+func TestLabelsUndeclared(t *testing.T) {
+	requests := &counter{}
+	logger := log.NewRecorder()
+	h := Server(
+		Logger(logger),
+		WithRequests(requests),
+		WithLabels([]string{"region"}, func(ctx context.Context, req interface{}) map[string]string {
+			return map[string]string{"region": "eu", "user": "alice"}
+		}),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "HTTP", Operation: "/users"})
+	h(ctx, nil)
+	h(ctx, nil)
+	want := []string{"HTTP", "/users", "200", "", "eu"}
+	if len(requests.lvs) != 2 || !reflect.DeepEqual(requests.lvs[0], want) {
+		t.Errorf("expected the labels %v, but got %v", want, requests.lvs)
+	}
+	if errs := logger.FilterByLevel(log.LevelError); len(errs) != 1 {
+		t.Errorf("expected the undeclared label logged once, but got %v", errs)
+	}
+}
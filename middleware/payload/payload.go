@@ -0,0 +1,71 @@
+package payload
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+// Option is payload option.
+type Option func(*options)
+
+type options struct {
+	requestBytes  metrics.Observer
+	responseBytes metrics.Observer
+}
+
+// WithRequestBytes with the request size histogram, labeled by kind and operation.
+func WithRequestBytes(o metrics.Observer) Option {
+	return func(opts *options) {
+		opts.requestBytes = o
+	}
+}
+
+// WithResponseBytes with the response size histogram, labeled by kind and operation.
+func WithResponseBytes(o metrics.Observer) Option {
+	return func(opts *options) {
+		opts.responseBytes = o
+	}
+}
+
+// Server is a server middleware that records the request and response sizes
+// once the transport has written the response, including failed requests.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			if stats := statsFromContext(ctx); stats != nil {
+				stats.OnFinish(func(s *transport.Stats) {
+					if options.requestBytes != nil {
+						options.requestBytes.With(tr.Kind, tr.Operation).Observe(float64(s.RequestBytes()))
+					}
+					if options.responseBytes != nil {
+						options.responseBytes.With(tr.Kind, tr.Operation).Observe(float64(s.ResponseBytes()))
+					}
+				})
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+func statsFromContext(ctx context.Context) *transport.Stats {
+	if info, ok := http.FromContext(ctx); ok {
+		return info.Stats
+	}
+	if info, ok := grpc.FromContext(ctx); ok {
+		return info.Stats
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package payload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type observation struct {
+	lvs   []string
+	value float64
+}
+
+type observer struct {
+	lvs          []string
+	observations *[]observation
+}
+
+func newObserver() *observer {
+	return &observer{observations: new([]observation)}
+}
+
+func (o *observer) With(lvs ...string) metrics.Observer {
+	return &observer{lvs: lvs, observations: o.observations}
+}
+
+func (o *observer) Observe(value float64) {
+	*o.observations = append(*o.observations, observation{lvs: o.lvs, value: value})
+}
+
+func TestServer(t *testing.T) {
+	requests, responses := newObserver(), newObserver()
+	h := Server(WithRequestBytes(requests), WithResponseBytes(responses))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.InternalServer("Internal", "internal")
+	})
+	tests := []struct {
+		kind string
+		ctx  func(ctx context.Context, stats *transport.Stats) context.Context
+	}{
+		{"HTTP", func(ctx context.Context, stats *transport.Stats) context.Context {
+			return http.NewContext(ctx, http.ServerInfo{Stats: stats})
+		}},
+		{"GRPC", func(ctx context.Context, stats *transport.Stats) context.Context {
+			return grpc.NewContext(ctx, grpc.ServerInfo{Stats: stats})
+		}},
+	}
+	for _, test := range tests {
+		*requests.observations, *responses.observations = nil, nil
+		stats := new(transport.Stats)
+		ctx := transport.NewContext(context.Background(), transport.Transport{Kind: test.kind, Operation: "/users"})
+		if _, err := h(test.ctx(ctx, stats), nil); err == nil {
+			t.Fatalf("expected the handler error")
+		}
+		if len(*requests.observations) != 0 {
+			t.Fatalf("expected no observation before the response is written")
+		}
+		stats.AddRequestBytes(10)
+		stats.AddResponseBytes(32)
+		stats.Finish()
+		lvs := []string{test.kind, "/users"}
+		if want := []observation{{lvs, 10}}; !reflect.DeepEqual(*requests.observations, want) {
+			t.Errorf("expected the request bytes %v, but got %v", want, *requests.observations)
+		}
+		if want := []observation{{lvs, 32}}; !reflect.DeepEqual(*responses.observations, want) {
+			t.Errorf("expected the response bytes %v, but got %v", want, *responses.observations)
+		}
+	}
+}
+
+func TestServerWithoutStats(t *testing.T) {
+	requests := newObserver()
+	h := Server(WithRequestBytes(requests))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "HTTP", Operation: "/users"})
+	if reply, err := h(ctx, nil); err != nil || reply != "ok" {
+		t.Fatalf("expected the reply, but got %v %v", reply, err)
+	}
+	if reply, err := h(context.Background(), nil); err != nil || reply != "ok" {
+		t.Fatalf("expected the reply, but got %v %v", reply, err)
+	}
+	if len(*requests.observations) != 0 {
+		t.Errorf("expected no observation without stats, but got %v", *requests.observations)
+	}
+}
@@ -0,0 +1,174 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/acl"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const (
+	// ReasonQuotaExceeded is the error reason of exhausted quotas.
+	ReasonQuotaExceeded = "QUOTA_EXCEEDED"
+	// ReasonUnknownCaller is the error reason of rejected unknown callers.
+	ReasonUnknownCaller = "QUOTA_UNKNOWN_CALLER"
+	// ReasonStoreUnavailable is the error reason of the failed quota stores.
+	ReasonStoreUnavailable = "QUOTA_STORE_UNAVAILABLE"
+
+	// AnonymousKey is the prefix of the buckets of unknown callers, which are keyed by
+	// the peer address, the callers without a peer address share the bucket of the prefix.
+	AnonymousKey = "anonymous"
+)
+
+// KeyFunc extracts the caller key from the context, ok is false for unknown callers.
+type KeyFunc func(ctx context.Context) (key string, ok bool)
+
+// Metadata returns a KeyFunc that extracts the caller from the request metadata key, i.e., x-md-global-app-id.
+func Metadata(key string) KeyFunc {
+	return func(ctx context.Context) (string, bool) {
+		tr, ok := transport.FromContext(ctx)
+		if !ok || tr.Header == nil {
+			return "", false
+		}
+		value := tr.Header.Get(key)
+		return value, value != ""
+	}
+}
+
+// Claim returns a KeyFunc that extracts the caller from a claim of the authenticated
+// principal, the principal must be a map keyed by claim names, i.e., jwt.MapClaims.
+func Claim(name string) KeyFunc {
+	return func(ctx context.Context) (string, bool) {
+		principal, ok := auth.FromContext(ctx)
+		if !ok {
+			return "", false
+		}
+		v := reflect.ValueOf(principal)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return "", false
+		}
+		claim := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !claim.IsValid() {
+			return "", false
+		}
+		value := fmt.Sprint(claim.Interface())
+		return value, value != ""
+	}
+}
+
+// IP returns a KeyFunc that extracts the caller from the client IP, X-Forwarded-For
+// is only honored for requests from the trusted proxies.
+func IP(trusted ...*net.IPNet) KeyFunc {
+	return func(ctx context.Context) (string, bool) {
+		ip := acl.ClientIP(ctx, trusted)
+		if ip == nil {
+			return "", false
+		}
+		return ip.String(), true
+	}
+}
+
+// Option is quota option.
+type Option func(*options)
+
+type options struct {
+	store         Store
+	key           KeyFunc
+	limit         int64
+	window        time.Duration
+	rejectUnknown bool
+}
+
+// WithStore with quota store.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithKey with caller key extractor.
+func WithKey(fn KeyFunc) Option {
+	return func(o *options) {
+		o.key = fn
+	}
+}
+
+// Limit with n requests allowed per window for every caller.
+func Limit(n int64, window time.Duration) Option {
+	return func(o *options) {
+		o.limit = n
+		o.window = window
+	}
+}
+
+// RejectUnknown rejects the unknown callers, by default they have an anonymous bucket per
+// peer address, see AnonymousKey.
+func RejectUnknown() Option {
+	return func(o *options) {
+		o.rejectUnknown = true
+	}
+}
+
+// Server is a server middleware that enforces a sliding window quota per caller.
+// The store errors fail the request with ServiceUnavailable.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		store:  NewMemoryStore(),
+		key:    Metadata("x-md-global-app-id"),
+		limit:  100,
+		window: time.Minute,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			key, ok := options.key(ctx)
+			if !ok {
+				if options.rejectUnknown {
+					return nil, errors.Unauthorized(ReasonUnknownCaller, "unknown caller")
+				}
+				key = anonymous(ctx)
+			}
+			res, err := options.store.Allow(ctx, key, options.limit, options.window)
+			if err != nil {
+				return nil, errors.ServiceUnavailable(ReasonStoreUnavailable, "quota store: %v", err).WithCause(err)
+			}
+			md := map[string]string{
+				"X-RateLimit-Limit":     strconv.FormatInt(res.Limit, 10),
+				"X-RateLimit-Remaining": strconv.FormatInt(res.Remaining, 10),
+				"X-RateLimit-Reset":     strconv.FormatInt(int64((res.Reset+time.Second-1)/time.Second), 10),
+			}
+			if tr, ok := transport.FromContext(ctx); ok && tr.ReplyHeader != nil {
+				for k, v := range md {
+					tr.ReplyHeader.Set(k, v)
+				}
+			}
+			if !res.Allowed {
+				return nil, exceeded(key, md)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// anonymous returns the key of the unknown caller by the peer address, the X-Forwarded-For
+// is never honored, since an unknown caller could spoof it to get a fresh bucket.
+func anonymous(ctx context.Context) string {
+	if ip := acl.ClientIP(ctx, nil); ip != nil {
+		return AnonymousKey + ":" + ip.String()
+	}
+	return AnonymousKey
+}
+
+func exceeded(key string, md map[string]string) error {
+	return errors.TooManyRequests(ReasonQuotaExceeded, "quota exceeded for caller %s", key).WithMetadata(md)
+}
@@ -0,0 +1,163 @@
+package quota
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func newContext(caller string) (context.Context, transporttest.Header) {
+	header, reply := transporttest.Header{}, transporttest.Header{}
+	if caller != "" {
+		header.Set("x-md-global-app-id", caller)
+	}
+	return transport.NewContext(context.Background(), transport.Transport{Header: header, ReplyHeader: reply}), reply
+}
+
+func TestServer(t *testing.T) {
+	h := Server(Limit(2, time.Hour))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	for i := 0; i < 2; i++ {
+		ctx, reply := newContext("app")
+		if _, err := h(ctx, nil); err != nil {
+			t.Fatalf("expected the request %d allowed, but got %v", i, err)
+		}
+		if reply.Get("X-RateLimit-Limit") != "2" || reply.Get("X-RateLimit-Remaining") == "" {
+			t.Errorf("expected the rate limit headers, but got %v", reply)
+		}
+	}
+	ctx, reply := newContext("app")
+	_, err := h(ctx, nil)
+	if errors.Code(err) != 429 || errors.Reason(err) != ReasonQuotaExceeded {
+		t.Fatalf("expected the quota exceeded, but got %v", err)
+	}
+	if md := errors.Metadata(err); md["X-RateLimit-Remaining"] != "0" {
+		t.Errorf("expected the rate limit metadata, but got %v", md)
+	}
+	if reply.Get("X-RateLimit-Remaining") != "0" || reply.Get("X-RateLimit-Reset") == "" {
+		t.Errorf("expected the rate limit headers, but got %v", reply)
+	}
+	// the other callers have their own quota.
+	ctx, _ = newContext("other")
+	if _, err := h(ctx, nil); err != nil {
+		t.Errorf("expected the other caller allowed, but got %v", err)
+	}
+}
+
+func TestServerUnknown(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	h := Server(Limit(1, time.Hour))(handler)
+	ctx, _ := newContext("")
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the unknown callers share the anonymous bucket.
+	if _, err := h(context.Background(), nil); errors.Code(err) != 429 {
+		t.Errorf("expected the anonymous quota exceeded, but got %v", err)
+	}
+
+	h = Server(RejectUnknown())(handler)
+	if _, err := h(ctx, nil); errors.Code(err) != 401 || errors.Reason(err) != ReasonUnknownCaller {
+		t.Errorf("expected the unknown caller rejected, but got %v", err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	key := Claim("sub")
+	if _, ok := key(context.Background()); ok {
+		t.Errorf("expected no caller without principal")
+	}
+	if k, ok := key(auth.NewContext(context.Background(), map[string]interface{}{"sub": "alice"})); !ok || k != "alice" {
+		t.Errorf("expected the caller alice, but got %q", k)
+	}
+	if _, ok := key(auth.NewContext(context.Background(), map[string]interface{}{"iss": "kratos"})); ok {
+		t.Errorf("expected no caller without the claim")
+	}
+	if _, ok := key(auth.NewContext(context.Background(), "alice")); ok {
+		t.Errorf("expected no caller with the non map principal")
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		prev, cur int64
+		elapsed   time.Duration
+		want      int64
+	}{
+		{10, 0, 0, 10},
+		{10, 2, 30 * time.Second, 7},
+		{10, 5, time.Minute, 5},
+	}
+	for _, test := range tests {
+		if n := Estimate(test.prev, test.cur, time.Minute, test.elapsed); n != test.want {
+			t.Errorf("Estimate(%d, %d, %v) = %d, want %d", test.prev, test.cur, test.elapsed, n, test.want)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	s.counters["stale"] = &counter{index: 0}
+	for i := 0; i < 1024; i++ {
+		if _, err := s.Allow(context.Background(), "app", 1<<20, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := s.counters["stale"]; ok {
+		t.Errorf("expected the stale counter swept")
+	}
+	if _, ok := s.counters["app"]; !ok {
+		t.Errorf("expected the active counter kept")
+	}
+}
+
+func TestServerAnonymous(t *testing.T) {
+	h := Server(Limit(1, time.Hour))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	newPeer := func(addr, forwarded string) context.Context {
+		ctx, _ := newContext("")
+		md := metadata.Pairs("x-forwarded-for", forwarded)
+		return peer.NewContext(metadata.NewIncomingContext(ctx, md), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1234}})
+	}
+	if _, err := h(newPeer("10.0.0.1", "1.1.1.1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	// a spoofed X-Forwarded-For never gets a fresh bucket.
+	if _, err := h(newPeer("10.0.0.1", "2.2.2.2"), nil); errors.Code(err) != 429 {
+		t.Errorf("expected the quota of the peer exceeded, but got %v", err)
+	}
+	// the other peers have their own bucket.
+	if _, err := h(newPeer("10.0.0.2", "1.1.1.1"), nil); err != nil {
+		t.Errorf("expected the other peer allowed, but got %v", err)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error) {
+	return nil, stderrors.New("connection refused")
+}
+
+func TestStoreError(t *testing.T) {
+	h := Server(WithStore(failingStore{}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	ctx, _ := newContext("app")
+	if _, err := h(ctx, nil); !errors.IsServiceUnavailable(err) || errors.Reason(err) != ReasonStoreUnavailable {
+		t.Errorf("expected the store unavailable, but got %v", err)
+	}
+}
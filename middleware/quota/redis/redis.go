@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware/quota"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ quota.Store = (*Store)(nil)
+
+// script reads the previous and current window counters, and increments the
+// current one only if the weighted estimate is below the limit.
+var script = redis.NewScript(`
+local prev = tonumber(redis.call('GET', KEYS[1]) or '0')
+local cur = tonumber(redis.call('GET', KEYS[2]) or '0')
+local estimate = math.floor(prev * tonumber(ARGV[1])) + cur
+if estimate >= tonumber(ARGV[2]) then
+	return {0, prev, cur}
+end
+cur = redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return {1, prev, cur}
+`)
+
+// Store is a redis sliding window quota store.
+type Store struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewStore new a redis quota store, the keys are prefixed with prefix.
+func NewStore(client redis.UniversalClient, prefix string) *Store {
+	return &Store{client: client, prefix: prefix}
+}
+
+// Allow consumes one request from the quota of key if it is not exhausted.
+func (s *Store) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*quota.Result, error) {
+	now := time.Now().UnixNano()
+	index := now / int64(window)
+	elapsed := time.Duration(now - index*int64(window))
+	weight := 1 - float64(elapsed)/float64(window)
+	keys := []string{
+		s.prefix + key + ":" + strconv.FormatInt(index-1, 10),
+		s.prefix + key + ":" + strconv.FormatInt(index, 10),
+	}
+	res, err := script.Run(ctx, s.client, keys,
+		strconv.FormatFloat(weight, 'f', 6, 64), limit, int64(2*window/time.Millisecond),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 3 {
+		return nil, fmt.Errorf("quota: unexpected script result %v", res)
+	}
+	allowed, _ := values[0].(int64)
+	prev, _ := values[1].(int64)
+	cur, _ := values[2].(int64)
+	result := &quota.Result{Limit: limit, Reset: window - elapsed}
+	if allowed == 1 {
+		result.Allowed = true
+		if result.Remaining = limit - quota.Estimate(prev, cur, window, elapsed); result.Remaining < 0 {
+			result.Remaining = 0
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+// +build integration
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The tests run against the redis at 127.0.0.1:6379, i.e.,
+// docker run -p 6379:6379 redis
+// go test -tags integration
+func newStore(t *testing.T) (*Store, func()) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	prefix := "test:quota:" + t.Name() + ":"
+	return NewStore(client, prefix), func() {
+		ctx := context.Background()
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+		client.Close()
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+	ctx := context.Background()
+	for i := int64(0); i < 3; i++ {
+		res, err := s.Allow(ctx, "app", 3, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Limit != 3 || res.Remaining != 2-i || res.Reset <= 0 || res.Reset > time.Hour {
+			t.Fatalf("expected the request %d allowed, got %+v", i, res)
+		}
+	}
+	res, err := s.Allow(ctx, "app", 3, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected the quota exceeded, got %+v", res)
+	}
+	// the other callers have their own quota.
+	if res, err := s.Allow(ctx, "other", 3, time.Hour); err != nil || !res.Allowed {
+		t.Errorf("expected the other caller allowed, got %+v %v", res, err)
+	}
+}
+
+func TestStoreWindow(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+	ctx := context.Background()
+	const window = 500 * time.Millisecond
+	if res, err := s.Allow(ctx, "app", 1, window); err != nil || !res.Allowed {
+		t.Fatalf("expected the request allowed, got %+v %v", res, err)
+	}
+	// the counter of the previous window weighs less as the window slides.
+	time.Sleep(2 * window)
+	if res, err := s.Allow(ctx, "app", 1, window); err != nil || !res.Allowed {
+		t.Errorf("expected the request allowed in the next windows, got %+v %v", res, err)
+	}
+}
@@ -0,0 +1,95 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Store = (*memoryStore)(nil)
+
+// Result is the result of a quota check.
+type Result struct {
+	// Allowed reports whether the request is allowed.
+	Allowed bool
+	// Limit is the number of requests allowed per window.
+	Limit int64
+	// Remaining is the number of requests remaining in the window.
+	Remaining int64
+	// Reset is the time until the current window resets.
+	Reset time.Duration
+}
+
+// Store is the quota counter store.
+type Store interface {
+	// Allow consumes one request from the quota of key if it is not exhausted.
+	Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error)
+}
+
+// Estimate returns the requests in the sliding window, weighting the previous
+// fixed window by how much of it still overlaps the sliding window.
+func Estimate(prev, cur int64, window, elapsed time.Duration) int64 {
+	weight := 1 - float64(elapsed)/float64(window)
+	return int64(float64(prev)*weight) + cur
+}
+
+type counter struct {
+	index int64
+	prev  int64
+	cur   int64
+}
+
+type memoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	calls    int
+}
+
+// NewMemoryStore new an in-memory sliding window store, which is only suitable for a single instance.
+func NewMemoryStore() Store {
+	return &memoryStore{counters: make(map[string]*counter)}
+}
+
+func (s *memoryStore) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error) {
+	now := time.Now().UnixNano()
+	index := now / int64(window)
+	elapsed := time.Duration(now - index*int64(window))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(index)
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{index: index}
+		s.counters[key] = c
+	}
+	if c.index != index {
+		if c.index == index-1 {
+			c.prev = c.cur
+		} else {
+			c.prev = 0
+		}
+		c.cur = 0
+		c.index = index
+	}
+	res := &Result{Limit: limit, Reset: window - elapsed}
+	if n := Estimate(c.prev, c.cur, window, elapsed); n < limit {
+		c.cur++
+		res.Allowed = true
+		res.Remaining = limit - n - 1
+	}
+	return res, nil
+}
+
+// sweep removes the counters that are older than the previous window every once in a while.
+func (s *memoryStore) sweep(index int64) {
+	if s.calls++; s.calls < 1024 {
+		return
+	}
+	s.calls = 0
+	for key, c := range s.counters {
+		if c.index < index-1 {
+			delete(s.counters, key)
+		}
+	}
+}
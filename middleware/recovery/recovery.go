@@ -4,19 +4,55 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/detach"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // HandlerFunc is recovery handler func.
 type HandlerFunc func(ctx context.Context, req, err interface{}) error
 
+// PanicMapper maps known panic values to errors, it returns nil for unexpected panics.
+type PanicMapper func(v interface{}) error
+
+// PanicReport is the report of an unexpected panic.
+type PanicReport struct {
+	// Operation is the operation which panicked.
+	Operation string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+	// Request is the redacted snapshot of the request.
+	Request string
+	// Time is when the panic was recovered.
+	Time time.Time
+}
+
+// ReporterFunc reports unexpected panics, it is called asynchronously.
+type ReporterFunc func(ctx context.Context, report PanicReport)
+
 // Option is recovery option.
 type Option func(*options)
 
 type options struct {
-	handler HandlerFunc
+	handler    HandlerFunc
+	mapper     PanicMapper
+	reporter   ReporterFunc
+	redactor   func(req interface{}) string
+	queueSize  int
+	reports    chan reportTask
+	reporting  int32
+	stackLimit int
 }
 
 // Handler with recovery handler.
@@ -26,6 +62,43 @@ func Handler(h HandlerFunc) Option {
 	}
 }
 
+// WithPanicMapper with the mapper of known panic values to client-visible errors,
+// the mapped panics are not reported.
+func WithPanicMapper(m PanicMapper) Option {
+	return func(o *options) {
+		o.mapper = m
+	}
+}
+
+// WithReporter with the reporter of unexpected panics, which is called by a goroutine
+// running only while there are pending reports.
+func WithReporter(r ReporterFunc) Option {
+	return func(o *options) {
+		o.reporter = r
+	}
+}
+
+// WithRedactor with the snapshot function of requests in panic reports,
+// by default proto fields named like passwords, secrets, and tokens are cleared.
+func WithRedactor(fn func(req interface{}) string) Option {
+	return func(o *options) {
+		o.redactor = fn
+	}
+}
+
+// WithReportQueue with the size of the pending reports queue,
+// the reports beyond the queue are dropped so that a slow reporter never stalls requests.
+func WithReportQueue(size int) Option {
+	return func(o *options) {
+		o.queueSize = size
+	}
+}
+
+type reportTask struct {
+	ctx    context.Context
+	report PanicReport
+}
+
 // Recovery is a server middleware that recovers from any panics.
 func Recovery(opts ...Option) middleware.Middleware {
 	options := options{
@@ -36,14 +109,26 @@ func Recovery(opts ...Option) middleware.Middleware {
 			fmt.Printf("%v: %+v\n%s\n", err, req, buf)
 			return errors.Unknown("Unknown", "panic triggered: %v", err)
 		},
+		redactor:   redact,
+		queueSize:  64,
+		stackLimit: 64 << 10,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.reporter != nil {
+		options.reports = make(chan reportTask, options.queueSize)
+	}
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			defer func() {
 				if rerr := recover(); rerr != nil {
+					if options.mapper != nil {
+						if err = options.mapper(rerr); err != nil {
+							return
+						}
+					}
+					options.report(ctx, req, rerr)
 					err = options.handler(ctx, req, rerr)
 				}
 			}()
@@ -51,3 +136,112 @@ func Recovery(opts ...Option) middleware.Middleware {
 		}
 	}
 }
+
+func (o *options) report(ctx context.Context, req, v interface{}) {
+	if o.reports == nil {
+		return
+	}
+	buf := make([]byte, o.stackLimit)
+	buf = buf[:runtime.Stack(buf, false)]
+	r := PanicReport{
+		Value:   v,
+		Stack:   buf,
+		Request: o.redactor(req),
+		Time:    time.Now(),
+	}
+	if tr, ok := transport.FromContext(ctx); ok {
+		r.Operation = tr.Operation
+	}
+	select {
+	case o.reports <- reportTask{ctx: detach.Context(ctx), report: r}:
+	default:
+		// the reporter is too slow, drop the report.
+	}
+	if atomic.CompareAndSwapInt32(&o.reporting, 0, 1) {
+		go o.deliver()
+	}
+}
+
+// deliver reports the pending reports, and returns once there is none, so that
+// no goroutine outlives the reports.
+func (o *options) deliver() {
+	for {
+		select {
+		case t := <-o.reports:
+			o.reporter(t.ctx, t.report)
+		default:
+			atomic.StoreInt32(&o.reporting, 0)
+			// a report enqueued right before the flag is cleared is delivered by this goroutine.
+			if len(o.reports) == 0 || !atomic.CompareAndSwapInt32(&o.reporting, 0, 1) {
+				return
+			}
+		}
+	}
+}
+
+var sensitive = []string{"password", "secret", "token", "credential"}
+
+func redact(req interface{}) string {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return fmt.Sprintf("%T", req)
+	}
+	m = proto.Clone(m)
+	redactMessage(m.ProtoReflect())
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("%T", req)
+	}
+	return string(data)
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitive {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactMessage clears the sensitive fields, and the map entries of the sensitive string keys,
+// i.e., of google.protobuf.Struct, in the nested, repeated and map messages as well.
+func redactMessage(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isSensitive(string(fd.Name())) {
+			m.Clear(fd)
+			return true
+		}
+		switch {
+		case fd.IsList():
+			if fd.Message() != nil {
+				for l, i := v.List(), 0; i < l.Len(); i++ {
+					redactMessage(l.Get(i).Message())
+				}
+			}
+		case fd.IsMap():
+			redactMap(fd, v.Map())
+		case fd.Message() != nil:
+			redactMessage(v.Message())
+		}
+		return true
+	})
+}
+
+func redactMap(fd protoreflect.FieldDescriptor, mp protoreflect.Map) {
+	var cleared []protoreflect.MapKey
+	mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		if fd.MapKey().Kind() == protoreflect.StringKind && isSensitive(k.String()) {
+			cleared = append(cleared, k)
+			return true
+		}
+		if fd.MapValue().Message() != nil {
+			redactMessage(v.Message())
+		}
+		return true
+	})
+	for _, k := range cleared {
+		mp.Clear(k)
+	}
+}
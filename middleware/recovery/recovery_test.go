@@ -0,0 +1,111 @@
+package recovery
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRecovery(t *testing.T) {
+	h := Recovery(Handler(func(ctx context.Context, req, err interface{}) error {
+		return errors.InternalServer("PANIC", "%v", err)
+	}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if _, err := h(context.Background(), nil); !errors.IsInternalServer(err) || errors.FromError(err).Message != "boom" {
+		t.Errorf("expected the panic recovered, got %v", err)
+	}
+}
+
+func TestPanicMapper(t *testing.T) {
+	reports := make(chan PanicReport, 1)
+	h := Recovery(
+		WithPanicMapper(func(v interface{}) error {
+			if v == "known" {
+				return errors.BadRequest("KNOWN", "known panic")
+			}
+			return nil
+		}),
+		WithReporter(func(ctx context.Context, r PanicReport) { reports <- r }),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic(req)
+	})
+	if _, err := h(context.Background(), "known"); errors.Reason(err) != "KNOWN" {
+		t.Errorf("expected the mapped error, got %v", err)
+	}
+	if _, err := h(context.Background(), "unknown"); !errors.IsUnknown(err) {
+		t.Errorf("expected the unknown error, got %v", err)
+	}
+	select {
+	case r := <-reports:
+		if r.Value != "unknown" {
+			t.Errorf("expected only the unexpected panic reported, got %v", r.Value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the unexpected panic reported")
+	}
+}
+
+type ctxKey struct{}
+
+func TestReporter(t *testing.T) {
+	reports := make(chan PanicReport)
+	errc := make(chan error, 1)
+	h := Recovery(WithReporter(func(ctx context.Context, r PanicReport) {
+		errc <- ctx.Err()
+		if ctx.Value(ctxKey{}) != "value" {
+			t.Errorf("expected the values of the request")
+		}
+		reports <- r
+	}))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	// the reports are delivered after the requests are done, and the later ones as well.
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+		ctx = transport.NewContext(ctx, transport.Transport{Operation: "/test.Users/Get"})
+		h(ctx, nil)
+		cancel()
+		r := <-reports
+		if err := <-errc; err != nil {
+			t.Errorf("expected the report detached from the request, got %v", err)
+		}
+		if r.Operation != "/test.Users/Get" || r.Value != "boom" || !strings.Contains(string(r.Stack), "recovery") || r.Time.IsZero() {
+			t.Errorf("unexpected report %+v", r)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	req, err := structpb.NewStruct(map[string]interface{}{
+		"name":     "alice",
+		"password": "secret",
+		"profile":  map[string]interface{}{"api_token": "token", "city": "paris"},
+		"devices":  []interface{}{map[string]interface{}{"id": "d1", "secret_key": "key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(redact(req)), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"devices":[{"id":"d1"}],"name":"alice","profile":{"city":"paris"}}`
+	if data, _ := json.Marshal(got); string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+	if req.Fields["password"] == nil {
+		t.Errorf("expected the request never changed")
+	}
+	if s := redact("plain"); s != "string" {
+		t.Errorf("expected the type of the non-proto requests, got %s", s)
+	}
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/middleware/debug"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Event is a reported error event.
+type Event struct {
+	// Kind is the transport kind.
+	Kind string
+	// Endpoint is the server endpoint.
+	Endpoint string
+	// Operation is the failed operation.
+	Operation string
+	// Error is the error returned by the handler.
+	Error error
+	// Chain is the messages of the error chain, from the outermost to the root cause.
+	Chain []string
+	// Code is the status code of the error.
+	Code int32
+	// Reason is the reason of the error.
+	Reason string
+	// Metadata is the request metadata, the headers of debug.DefaultRedactHeaders are excluded.
+	Metadata map[string]string
+	// Principal is the authenticated principal, if any.
+	Principal interface{}
+	// Time is when the error was returned.
+	Time time.Time
+}
+
+// Option is report option.
+type Option func(*options)
+
+type options struct {
+	reporter  Reporter
+	filter    func(err error) bool
+	queueSize int
+	timeout   time.Duration
+	dropped   metrics.Counter
+	failures  metrics.Counter
+}
+
+// WithReporter with error reporter.
+func WithReporter(r Reporter) Option {
+	return func(o *options) {
+		o.reporter = r
+	}
+}
+
+// WithFilter with the filter of reported errors, by default server errors are reported.
+func WithFilter(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.filter = fn
+	}
+}
+
+// WithQueue with the size of the pending events queue.
+func WithQueue(size int) Option {
+	return func(o *options) {
+		o.queueSize = size
+	}
+}
+
+// WithTimeout with the timeout of every report.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithDropped with the counter of events dropped because the queue is full.
+func WithDropped(c metrics.Counter) Option {
+	return func(o *options) {
+		o.dropped = c
+	}
+}
+
+// WithFailures with the counter of failed reports.
+func WithFailures(c metrics.Counter) Option {
+	return func(o *options) {
+		o.failures = c
+	}
+}
+
+// queued is an event queued for the reporter, or a flush marker.
+type queued struct {
+	event   *Event
+	flushed chan struct{}
+}
+
+// Sender forwards the events to the reporter asynchronously in the background, events
+// beyond the bounded queue are dropped.
+type Sender struct {
+	options options
+	events  chan queued
+
+	once sync.Once
+	done chan struct{}
+	// stopped is closed once the queued events are reported after the close.
+	stopped chan struct{}
+}
+
+// NewSender new a sender, which reports the queued events until it is closed.
+func NewSender(opts ...Option) *Sender {
+	options := options{
+		reporter:  NopReporter{},
+		filter:    isServerError,
+		queueSize: 256,
+		timeout:   5 * time.Second,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	s := &Sender{
+		options: options,
+		events:  make(chan queued, options.queueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *Sender) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case q := <-s.events:
+			s.report(q)
+		case <-s.done:
+			// the events queued before the close are still reported.
+			for {
+				select {
+				case q := <-s.events:
+					s.report(q)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Sender) report(q queued) {
+	if q.flushed != nil {
+		close(q.flushed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.timeout)
+	defer cancel()
+	if err := s.options.reporter.Report(ctx, q.event); err != nil && s.options.failures != nil {
+		s.options.failures.With(q.event.Operation).Inc()
+	}
+}
+
+// Flush blocks until the events queued before are reported, or ctx is done.
+func (s *Sender) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case s.events <- queued{flushed: flushed}:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close reports the queued events and stops the sender, the events of the requests
+// afterwards are dropped.
+func (s *Sender) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	<-s.stopped
+	return nil
+}
+
+// Server returns the server middleware of the sender.
+func (s *Sender) Server() middleware.Middleware {
+	options := s.options
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			// the principal authenticated by the inner auth middleware is recorded.
+			ctx = auth.WithPrincipal(ctx)
+			reply, err := handler(ctx, req)
+			if err == nil || !options.filter(err) {
+				return reply, err
+			}
+			e := newEvent(ctx, err)
+			select {
+			case <-s.done:
+				s.drop(e)
+			default:
+				select {
+				case s.events <- queued{event: e}:
+				default:
+					s.drop(e)
+				}
+			}
+			return reply, err
+		}
+	}
+}
+
+func (s *Sender) drop(e *Event) {
+	if s.options.dropped != nil {
+		s.options.dropped.With(e.Operation).Inc()
+	}
+}
+
+// Server is a server middleware that forwards server errors to the reporter
+// asynchronously, events beyond the bounded queue are dropped. The sender runs
+// for the lifetime of the process, use NewSender to flush and stop it.
+func Server(opts ...Option) middleware.Middleware {
+	return NewSender(opts...).Server()
+}
+
+// redact is the lowercased headers excluded from the metadata.
+var redact = func() map[string]struct{} {
+	m := make(map[string]struct{}, len(debug.DefaultRedactHeaders))
+	for _, name := range debug.DefaultRedactHeaders {
+		m[strings.ToLower(name)] = struct{}{}
+	}
+	return m
+}()
+
+func newEvent(ctx context.Context, err error) *Event {
+	e := &Event{
+		Error:  err,
+		Code:   int32(errors.Code(err)),
+		Reason: errors.Reason(err),
+		Time:   time.Now(),
+	}
+	for cause := err; cause != nil; cause = stderrors.Unwrap(cause) {
+		e.Chain = append(e.Chain, cause.Error())
+	}
+	if tr, ok := transport.FromContext(ctx); ok {
+		e.Kind = tr.Kind
+		e.Endpoint = tr.Endpoint
+		e.Operation = tr.Operation
+		if tr.Header != nil {
+			e.Metadata = make(map[string]string)
+			for _, key := range tr.Header.Keys() {
+				if _, ok := redact[strings.ToLower(key)]; !ok {
+					e.Metadata[key] = tr.Header.Get(key)
+				}
+			}
+		}
+	}
+	e.Principal, _ = auth.FromContext(ctx)
+	return e
+}
+
+// isServerError reports whether the error is a server error, whose HTTP mapping is 5xx.
+func isServerError(err error) bool {
+	return errors.Code(err) >= 500
+}
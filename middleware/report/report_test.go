@@ -0,0 +1,233 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware/auth"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+)
+
+type recorder struct {
+	events chan *Event
+	err    error
+}
+
+func (r *recorder) Report(ctx context.Context, e *Event) error {
+	r.events <- e
+	return r.err
+}
+
+type counter struct {
+	mu  sync.Mutex
+	lvs [][]string
+}
+
+func (c *counter) With(lvs ...string) metrics.Counter {
+	c.mu.Lock()
+	c.lvs = append(c.lvs, lvs)
+	c.mu.Unlock()
+	return c
+}
+
+func (c *counter) Inc()              {}
+func (c *counter) Add(delta float64) {}
+
+func (c *counter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.lvs)
+}
+
+func TestServer(t *testing.T) {
+	r := &recorder{events: make(chan *Event, 1)}
+	h := Server(WithReporter(r))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if req == "bad" {
+			return nil, errors.BadRequest("Invalid", "invalid")
+		}
+		return nil, fmt.Errorf("query: %w", errors.InternalServer("Database", "database down"))
+	})
+	header := transporttest.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("X-Request-Id", "1")
+	ctx := transport.NewContext(context.Background(), transport.Transport{
+		Kind:      "HTTP",
+		Endpoint:  "http://127.0.0.1:8000",
+		Operation: "/users",
+		Header:    header,
+	})
+	ctx = auth.NewContext(ctx, "alice")
+
+	if _, err := h(ctx, "bad"); errors.Code(err) != 400 {
+		t.Fatalf("expected the handler error, but got %v", err)
+	}
+	if _, err := h(ctx, "ok"); errors.Code(err) != 500 {
+		t.Fatalf("expected the handler error, but got %v", err)
+	}
+	var e *Event
+	select {
+	case e = <-r.events:
+	case <-time.After(time.Second):
+		t.Fatal("expected the server error reported")
+	}
+	if e.Kind != "HTTP" || e.Operation != "/users" || e.Code != 500 || e.Reason != "Database" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if len(e.Chain) != 2 || e.Chain[0] != e.Error.Error() {
+		t.Errorf("expected the error chain, but got %v", e.Chain)
+	}
+	if want := map[string]string{"X-Request-Id": "1"}; !reflect.DeepEqual(e.Metadata, want) {
+		t.Errorf("expected the metadata %v, but got %v", want, e.Metadata)
+	}
+	if e.Principal != "alice" {
+		t.Errorf("expected the principal alice, but got %v", e.Principal)
+	}
+	select {
+	case e = <-r.events:
+		t.Errorf("expected the client error not reported, but got %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServerDropped(t *testing.T) {
+	r := &recorder{events: make(chan *Event)}
+	dropped, failures := &counter{}, &counter{}
+	h := Server(
+		WithReporter(r),
+		WithQueue(1),
+		WithDropped(dropped),
+		WithFailures(failures),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.InternalServer("Internal", "internal")
+	})
+	r.err = fmt.Errorf("report failed")
+	// the first is blocked in the reporter, the second is queued, the third is dropped.
+	h(context.Background(), nil)
+	time.Sleep(50 * time.Millisecond)
+	h(context.Background(), nil)
+	h(context.Background(), nil)
+	if n := dropped.count(); n != 1 {
+		t.Errorf("expected 1 dropped event, but got %d", n)
+	}
+	<-r.events
+	<-r.events
+	deadline := time.Now().Add(time.Second)
+	for failures.count() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := failures.count(); n < 1 {
+		t.Errorf("expected the failed reports counted, but got %d", n)
+	}
+}
+
+func TestWebhookReporter(t *testing.T) {
+	var got webhookEvent
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected the JSON content type, but got %q", ct)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	r := NewWebhookReporter(srv.URL, nil)
+	e := &Event{
+		Operation: "/users",
+		Error:     errors.InternalServer("Database", "database down"),
+		Code:      500,
+		Reason:    "Database",
+		Principal: "alice",
+	}
+	if err := r.Report(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if got.Operation != "/users" || got.Code != 500 || got.Reason != "Database" || got.Principal != "alice" || got.Message != e.Error.Error() {
+		t.Errorf("unexpected webhook event %+v", got)
+	}
+	status = http.StatusBadGateway
+	if err := r.Report(context.Background(), e); err == nil {
+		t.Errorf("expected the error on status %d", status)
+	}
+}
+
+func TestSender(t *testing.T) {
+	var mu sync.Mutex
+	var reported []string
+	r := reporterFunc(func(ctx context.Context, e *Event) error {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		reported = append(reported, e.Reason)
+		mu.Unlock()
+		return nil
+	})
+	dropped := &counter{}
+	s := NewSender(WithReporter(r), WithDropped(dropped))
+	h := s.Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.InternalServer(req.(string), "internal")
+	})
+	ctx := context.Background()
+	h(ctx, "first")
+	h(ctx, "second")
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if !reflect.DeepEqual(reported, []string{"first", "second"}) {
+		t.Errorf("expected the queued events reported once flushed, but got %v", reported)
+	}
+	mu.Unlock()
+
+	h(ctx, "third")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(reported) != 3 {
+		t.Errorf("expected the queued events reported once closed, but got %v", reported)
+	}
+	mu.Unlock()
+	h(ctx, "fourth")
+	if n := dropped.count(); n != 1 {
+		t.Errorf("expected the events dropped after the close, but got %d", n)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Errorf("expected the flush of a closed sender done, but got %v", err)
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	r := &recorder{events: make(chan *Event, 1)}
+	h := Server(WithReporter(r))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		ctx = auth.NewContext(ctx, "alice")
+		return nil, errors.InternalServer("Internal", "internal")
+	})
+	header := transporttest.Header{}
+	header.Set("X-Auth-Token", "secret")
+	header.Set("X-Request-Id", "1")
+	h(transport.NewContext(context.Background(), transport.Transport{Header: header}), nil)
+	e := <-r.events
+	if e.Principal != "alice" {
+		t.Errorf("expected the principal of the inner auth middleware, but got %v", e.Principal)
+	}
+	if want := map[string]string{"X-Request-Id": "1"}; !reflect.DeepEqual(e.Metadata, want) {
+		t.Errorf("expected the metadata %v, but got %v", want, e.Metadata)
+	}
+}
+
+type reporterFunc func(ctx context.Context, e *Event) error
+
+func (f reporterFunc) Report(ctx context.Context, e *Event) error { return f(ctx, e) }
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	_ Reporter = NopReporter{}
+	_ Reporter = (*WebhookReporter)(nil)
+)
+
+// Reporter receives the reported error events.
+type Reporter interface {
+	Report(ctx context.Context, e *Event) error
+}
+
+// NopReporter is a reporter that discards all events.
+type NopReporter struct{}
+
+// Report discards the event.
+func (NopReporter) Report(context.Context, *Event) error { return nil }
+
+// WebhookReporter posts the events as JSON to a webhook.
+type WebhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookReporter new a webhook reporter posting to url, a nil client means http.DefaultClient.
+func NewWebhookReporter(url string, client *http.Client) *WebhookReporter {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookReporter{url: url, client: client}
+}
+
+type webhookEvent struct {
+	Kind      string            `json:"kind"`
+	Endpoint  string            `json:"endpoint"`
+	Operation string            `json:"operation"`
+	Code      int32             `json:"code"`
+	Reason    string            `json:"reason"`
+	Message   string            `json:"message"`
+	Chain     []string          `json:"chain"`
+	Metadata  map[string]string `json:"metadata"`
+	Principal string            `json:"principal,omitempty"`
+	Time      time.Time         `json:"time"`
+}
+
+// Report posts the event to the webhook.
+func (r *WebhookReporter) Report(ctx context.Context, e *Event) error {
+	we := webhookEvent{
+		Kind:      e.Kind,
+		Endpoint:  e.Endpoint,
+		Operation: e.Operation,
+		Code:      e.Code,
+		Reason:    e.Reason,
+		Message:   e.Error.Error(),
+		Chain:     e.Chain,
+		Metadata:  e.Metadata,
+		Time:      e.Time,
+	}
+	if e.Principal != nil {
+		we.Principal = fmt.Sprint(e.Principal)
+	}
+	data, err := json.Marshal(&we)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("report: webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
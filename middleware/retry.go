@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// RetryAttemptKey is the metadata key of the retry attempt stamped by the client retry middleware.
+const RetryAttemptKey = "x-md-retry-attempt"
+
+// RetryAttempt returns the retry attempt of the request, which is 0 for the first attempt.
+func RetryAttempt(ctx context.Context) int {
+	tr, ok := transport.FromContext(ctx)
+	if !ok || tr.Header == nil {
+		return 0
+	}
+	attempt, err := strconv.Atoi(tr.Header.Get(RetryAttemptKey))
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// IsRetry reports whether the request is a retried attempt, so the execution may be a replay.
+func IsRetry(ctx context.Context) bool {
+	return RetryAttempt(ctx) > 0
+}
@@ -0,0 +1,128 @@
+package retry
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/idempotency"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ReasonRetryWithoutKey is the error reason of retried non-idempotent requests without idempotency keys.
+const ReasonRetryWithoutKey = "RETRY_WITHOUT_IDEMPOTENCY_KEY"
+
+// Option is retry option.
+type Option func(*options)
+
+type options struct {
+	attempts  int
+	backoff   func(attempt int) time.Duration
+	retryable func(err error) bool
+
+	operations map[string]struct{}
+	header     string
+}
+
+// Attempts with the max attempts of the client, including the first one.
+func Attempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+// Backoff with the delay before the retry attempt.
+func Backoff(fn func(attempt int) time.Duration) Option {
+	return func(o *options) {
+		o.backoff = fn
+	}
+}
+
+// Retryable with the predicate of retryable errors, errors.IsRetryable by default.
+func Retryable(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.retryable = fn
+	}
+}
+
+// NonIdempotent with the non-idempotent operations, whose retried attempts must carry idempotency keys.
+func NonIdempotent(operations ...string) Option {
+	return func(o *options) {
+		for _, operation := range operations {
+			o.operations[operation] = struct{}{}
+		}
+	}
+}
+
+// Header with the header carrying the idempotency key.
+func Header(name string) Option {
+	return func(o *options) {
+		o.header = name
+	}
+}
+
+func newOptions(opts []Option) options {
+	options := options{
+		attempts: 3,
+		backoff: func(attempt int) time.Duration {
+			return time.Duration(attempt) * 100 * time.Millisecond
+		},
+		retryable:  errors.IsRetryable,
+		operations: make(map[string]struct{}),
+		header:     idempotency.DefaultHeader,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// Client is a client middleware that retries the failed requests, every retried
+// attempt is stamped with the middleware.RetryAttemptKey metadata. The transports send
+// every attempt as a fresh request with the header, i.e., the HTTP client rewinds the body.
+func Client(opts ...Option) middleware.Middleware {
+	options := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			tr, _ := transport.FromContext(ctx)
+			for attempt := 0; attempt < options.attempts; attempt++ {
+				if attempt > 0 {
+					timer := time.NewTimer(options.backoff(attempt))
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						return nil, err
+					case <-timer.C:
+					}
+					if tr.Header != nil {
+						tr.Header.Set(middleware.RetryAttemptKey, strconv.Itoa(attempt))
+					}
+				}
+				if reply, err = handler(ctx, req); err == nil || !options.retryable(err) {
+					return reply, err
+				}
+			}
+			return reply, err
+		}
+	}
+}
+
+// Server is a server middleware that rejects the retried attempts of the
+// non-idempotent operations which lack idempotency keys.
+func Server(opts ...Option) middleware.Middleware {
+	options := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if !middleware.IsRetry(ctx) {
+				return handler(ctx, req)
+			}
+			tr, _ := transport.FromContext(ctx)
+			if _, ok := options.operations[tr.Operation]; ok && tr.Header.Get(options.header) == "" {
+				return nil, errors.FailedPrecondition(ReasonRetryWithoutKey, "retried %s requires an idempotency key", tr.Operation)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
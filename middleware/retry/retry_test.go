@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+)
+
+func TestRetryHandshake(t *testing.T) {
+	const operation = "/test.Payment/Charge"
+	var executions int
+	server := Server(NonIdempotent(operation))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if executions++; executions == 1 {
+			return nil, errors.Unavailable("Unavailable", "try again")
+		}
+		return "reply", nil
+	})
+	// the client header is sent as the server header, as the transports do.
+	header := transporttest.Header{}
+	invoke := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return server(transport.NewContext(ctx, transport.Transport{Operation: operation, Header: header}), req)
+	}
+	client := Client(Backoff(func(int) time.Duration { return 0 }))(invoke)
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation, Header: header})
+
+	if _, err := client(ctx, nil); !errors.IsFailedPrecondition(err) {
+		t.Fatalf("expected retry without key rejected, but got %v", err)
+	}
+	if executions != 1 {
+		t.Errorf("expected 1 execution, but got %d", executions)
+	}
+
+	executions = 0
+	header = transporttest.Header{}
+	header.Set("Idempotency-Key", "key")
+	ctx = transport.NewContext(context.Background(), transport.Transport{Operation: operation, Header: header})
+	reply, err := client(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "reply" || executions != 2 {
+		t.Errorf("expected reply after 2 executions, but got %v after %d", reply, executions)
+	}
+}
+
+func TestRetryHTTPClient(t *testing.T) {
+	type attempt struct {
+		header string
+		body   string
+	}
+	attempts := make(chan attempt, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		attempts <- attempt{header: r.Header.Get(middleware.RetryAttemptKey), body: string(body)}
+		if len(attempts) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	client, err := khttp.NewClient(khttp.WithMiddleware(Client(Backoff(func(int) time.Duration { return 0 }))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("PUT", srv.URL, strings.NewReader(`{"name":"kratos"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	close(attempts)
+	var got []attempt
+	for a := range attempts {
+		got = append(got, a)
+	}
+	if len(got) != 2 || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 2 attempts, but got %v with %d", got, res.StatusCode)
+	}
+	if got[0].header != "" || got[1].header != "1" {
+		t.Errorf("expected the retry attempt header on the second attempt, but got %v", got)
+	}
+	if got[1].body != `{"name":"kratos"}` {
+		t.Errorf("expected the body resent, but got %q", got[1].body)
+	}
+	if req.Header.Get(middleware.RetryAttemptKey) != "" {
+		t.Errorf("expected the request of the caller untouched")
+	}
+}
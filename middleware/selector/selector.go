@@ -0,0 +1,77 @@
+package selector
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// MatchFunc is selector match function.
+type MatchFunc = middleware.Matcher
+
+// Option is selector option.
+type Option func(*options)
+
+type options struct {
+	matchers []middleware.Matcher
+}
+
+// Path with exact operations, i.e., /package.service/method or /v1/users/{id}.
+func Path(paths ...string) Option {
+	return Match(middleware.Exact(paths...))
+}
+
+// Prefix with operation prefixes, i.e., /v1/admin/.
+func Prefix(prefixes ...string) Option {
+	return Match(middleware.Prefix(prefixes...))
+}
+
+// Glob with operation shell patterns, i.e., /helloworld.Greeter/*.
+func Glob(patterns ...string) Option {
+	return Match(middleware.Glob(patterns...))
+}
+
+// Regex with operation regular expressions, it panics if an expression cannot be parsed.
+func Regex(exprs ...string) Option {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		regexps = append(regexps, regexp.MustCompile(expr))
+	}
+	return Match(func(ctx context.Context, operation string) bool {
+		for _, re := range regexps {
+			if re.MatchString(operation) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Match with custom match function.
+func Match(fn MatchFunc) Option {
+	return func(o *options) {
+		o.matchers = append(o.matchers, fn)
+	}
+}
+
+// Server returns a middleware that applies m only to the operations
+// matched by any of the options, other operations pass straight through.
+func Server(m middleware.Middleware, opts ...Option) middleware.Middleware {
+	options := options{}
+	for _, o := range opts {
+		o(&options)
+	}
+	match := middleware.Any(options.matchers...)
+	return func(handler middleware.Handler) middleware.Handler {
+		next := m(handler)
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromContext(ctx)
+			if !ok || !match(ctx, tr.Operation) {
+				return handler(ctx, req)
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package selector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestSelector(t *testing.T) {
+	var applied bool
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			applied = true
+			return handler(ctx, req)
+		}
+	}
+	h := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", nil
+	}
+	s := Server(m,
+		Path("/healthz"),
+		Prefix("/v1/admin/"),
+		Regex(`^/helloworld\.Greeter/.+$`),
+		Match(func(ctx context.Context, operation string) bool {
+			return strings.HasSuffix(operation, "/custom")
+		}),
+	)(h)
+	tests := []struct {
+		operation string
+		applied   bool
+	}{
+		{"/healthz", true},
+		{"/healthz/live", false},
+		{"/v1/admin/users/{id}", true},
+		{"/v1/users/{id}", false},
+		{"/helloworld.Greeter/SayHello", true},
+		{"/v1/custom", true},
+	}
+	for _, test := range tests {
+		applied = false
+		ctx := transport.NewContext(context.Background(), transport.Transport{Kind: "HTTP", Operation: test.operation})
+		if _, err := s(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		if applied != test.applied {
+			t.Errorf("operation %s: expected applied %v, but got %v", test.operation, test.applied, applied)
+		}
+	}
+	applied = false
+	if _, err := s(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if applied {
+		t.Errorf("expected pass through without transport context")
+	}
+}
@@ -0,0 +1,255 @@
+package shedding
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/cpu"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ReasonLoadShedding is the error reason of shed requests.
+const ReasonLoadShedding = "LOAD_SHEDDING"
+
+// Sampler samples the fraction of the CPU limit in use, in [0, 1].
+type Sampler interface {
+	Usage() (float64, error)
+}
+
+// newSampler is the default sampler, which is faked in the tests.
+var newSampler = func() (Sampler, error) {
+	return cpu.NewSampler()
+}
+
+// nopSampler is the sampler of the platforms without the CPU usage, which never sheds.
+type nopSampler struct{}
+
+func (nopSampler) Usage() (float64, error) { return 0, nil }
+
+// Option is shedding option.
+type Option func(*options)
+
+type options struct {
+	sampler    Sampler
+	threshold  float64
+	recovery   float64
+	window     time.Duration
+	interval   time.Duration
+	fraction   float64
+	retryAfter time.Duration
+	critical   map[string]struct{}
+	shed       metrics.Counter
+}
+
+// WithSampler with CPU usage sampler, by default the cgroup aware sampler of the process.
+func WithSampler(s Sampler) Option {
+	return func(o *options) {
+		o.sampler = s
+	}
+}
+
+// Threshold with the CPU usage that starts shedding once sustained for the window,
+// the shedding stops once the usage stays below the recovery threshold for the window.
+func Threshold(threshold, recovery float64) Option {
+	return func(o *options) {
+		o.threshold = threshold
+		o.recovery = recovery
+	}
+}
+
+// Window with the duration the CPU usage must be sustained to switch the state.
+func Window(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// Interval with the CPU sampling interval.
+func Interval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// Fraction with the fraction of the non-critical requests rejected while shedding.
+func Fraction(f float64) Option {
+	return func(o *options) {
+		o.fraction = f
+	}
+}
+
+// RetryAfter with the Retry-After hint of the rejected requests.
+func RetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.retryAfter = d
+	}
+}
+
+// Critical with the critical operations, which are always admitted.
+func Critical(operations ...string) Option {
+	return func(o *options) {
+		for _, operation := range operations {
+			o.critical[operation] = struct{}{}
+		}
+	}
+}
+
+// WithShed with the counter of shed requests, labeled by operation.
+func WithShed(c metrics.Counter) Option {
+	return func(o *options) {
+		o.shed = c
+	}
+}
+
+// shedder is the hysteresis state machine of the CPU usage.
+type shedder struct {
+	threshold float64
+	recovery  float64
+	window    time.Duration
+
+	mu       sync.Mutex
+	since    time.Time // when the usage crossed into the opposite state
+	shedding int32
+}
+
+// observe records a CPU usage sample, the state switches only after the
+// usage stays across the threshold of the opposite state for the window.
+func (s *shedder) observe(usage float64, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shedding := atomic.LoadInt32(&s.shedding) == 1
+	crossed := (!shedding && usage > s.threshold) || (shedding && usage < s.recovery)
+	if !crossed {
+		s.since = time.Time{}
+		return
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	if now.Sub(s.since) >= s.window {
+		s.since = time.Time{}
+		if shedding {
+			atomic.StoreInt32(&s.shedding, 0)
+		} else {
+			atomic.StoreInt32(&s.shedding, 1)
+		}
+	}
+}
+
+func (s *shedder) isShedding() bool {
+	return atomic.LoadInt32(&s.shedding) == 1
+}
+
+// Shedder samples the CPU usage in the background, and rejects a fraction of the
+// non-critical requests while the usage is sustained above the threshold.
+type Shedder struct {
+	options options
+	state   *shedder
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewShedder new a shedder, which samples the CPU usage until it is closed. Without a
+// sampler, the shedder never sheds if the CPU usage is not supported on this platform.
+func NewShedder(opts ...Option) *Shedder {
+	options := options{
+		threshold:  0.8,
+		recovery:   0.7,
+		window:     5 * time.Second,
+		interval:   500 * time.Millisecond,
+		fraction:   0.5,
+		retryAfter: time.Second,
+		critical:   make(map[string]struct{}),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.sampler == nil {
+		sampler, err := newSampler()
+		if err != nil {
+			sampler = nopSampler{}
+		}
+		options.sampler = sampler
+	}
+	s := &Shedder{
+		options: options,
+		state: &shedder{
+			threshold: options.threshold,
+			recovery:  options.recovery,
+			window:    options.window,
+		},
+		done: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *Shedder) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.options.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			if usage, err := s.options.sampler.Usage(); err == nil {
+				s.state.observe(usage, now)
+			}
+		}
+	}
+}
+
+// Close stops sampling the CPU usage, the requests are admitted afterwards.
+func (s *Shedder) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	atomic.StoreInt32(&s.state.shedding, 0)
+	return nil
+}
+
+// Server returns the server middleware of the shedder.
+func (s *Shedder) Server() middleware.Middleware {
+	options := s.options
+	retryAfter := strconv.Itoa(int((options.retryAfter + time.Second - 1) / time.Second))
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if !s.state.isShedding() {
+				return handler(ctx, req)
+			}
+			tr, ok := transport.FromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			if _, ok := options.critical[tr.Operation]; ok || rand.Float64() >= options.fraction {
+				return handler(ctx, req)
+			}
+			if options.shed != nil {
+				options.shed.With(tr.Operation).Inc()
+			}
+			if tr.ReplyHeader != nil {
+				tr.ReplyHeader.Set("Retry-After", retryAfter)
+			}
+			return nil, errors.Unavailable(ReasonLoadShedding, "server overloaded, retry after %ss", retryAfter)
+		}
+	}
+}
+
+// Server is a server middleware that rejects a fraction of the non-critical
+// requests while the CPU usage is sustained above the threshold. The sampling
+// runs for the lifetime of the process, use NewShedder to stop it.
+func Server(opts ...Option) middleware.Middleware {
+	return NewShedder(opts...).Server()
+}
@@ -0,0 +1,114 @@
+package shedding
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/cpu"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/transporttest"
+)
+
+type sampler struct {
+	samples int64
+}
+
+func (s *sampler) Usage() (float64, error) {
+	atomic.AddInt64(&s.samples, 1)
+	return 1, nil
+}
+
+func TestShedderHysteresis(t *testing.T) {
+	s := &shedder{threshold: 0.8, recovery: 0.6, window: time.Second}
+	now := time.Now()
+	steps := []struct {
+		usage    float64
+		after    time.Duration
+		shedding bool
+	}{
+		{0.9, 0, false},
+		{0.9, 500 * time.Millisecond, false},
+		// a dip resets the window.
+		{0.5, 600 * time.Millisecond, false},
+		{0.9, 700 * time.Millisecond, false},
+		{0.9, 1700 * time.Millisecond, true},
+		// between the thresholds keeps shedding.
+		{0.7, 3 * time.Second, true},
+		{0.5, 4 * time.Second, true},
+		{0.5, 5 * time.Second, false},
+	}
+	for i, step := range steps {
+		s.observe(step.usage, now.Add(step.after))
+		if s.isShedding() != step.shedding {
+			t.Fatalf("step %d: expected shedding %v, but got %v", i, step.shedding, s.isShedding())
+		}
+	}
+}
+
+func TestShedder(t *testing.T) {
+	cpu := &sampler{}
+	s := NewShedder(
+		WithSampler(cpu),
+		Window(0),
+		Interval(time.Millisecond),
+		Fraction(1),
+		RetryAfter(1500*time.Millisecond),
+		Critical("/critical"),
+	)
+	h := s.Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	call := func(operation string) (transporttest.Header, error) {
+		reply := transporttest.Header{}
+		ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation, ReplyHeader: reply})
+		_, err := h(ctx, nil)
+		return reply, err
+	}
+	deadline := time.Now().Add(time.Second)
+	for !s.state.isShedding() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	reply, err := call("/users")
+	if errors.Code(err) != 503 || errors.Reason(err) != ReasonLoadShedding {
+		t.Fatalf("expected the request shed, but got %v", err)
+	}
+	if reply.Get("Retry-After") != "2" {
+		t.Errorf("expected the Retry-After 2, but got %q", reply.Get("Retry-After"))
+	}
+	if _, err := call("/critical"); err != nil {
+		t.Errorf("expected the critical operation admitted, but got %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	samples := atomic.LoadInt64(&cpu.samples)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt64(&cpu.samples); n != samples {
+		t.Errorf("expected no samples after close, but got %d more", n-samples)
+	}
+	if _, err := call("/users"); err != nil {
+		t.Errorf("expected the request admitted after close, but got %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("expected the close idempotent, but got %v", err)
+	}
+}
+
+func TestShedderUnsupported(t *testing.T) {
+	defer func(fn func() (Sampler, error)) { newSampler = fn }(newSampler)
+	newSampler = func() (Sampler, error) { return nil, cpu.ErrNotSupported }
+	s := NewShedder(Window(0), Interval(time.Millisecond), Fraction(1))
+	defer s.Close()
+	h := s.Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: "/test"})
+	if _, err := h(ctx, nil); err != nil {
+		t.Errorf("expected the requests admitted without the CPU usage, but got %v", err)
+	}
+}
@@ -5,56 +5,14 @@ import (
 
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/middleware"
-	"github.com/golang/protobuf/proto"
-	"github.com/golang/protobuf/ptypes"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func encode(err error) error {
-	se, ok := errors.FromError(err)
-	if !ok {
-		se = &errors.StatusError{
-			Code: 2,
-		}
-	}
-	gs := status.Newf(codes.Code(se.Code), "%s: %s", se.Reason, se.Message)
-	details := []proto.Message{
-		&errdetails.ErrorInfo{
-			Reason:   se.Reason,
-			Metadata: map[string]string{"message": se.Message},
-		},
-	}
-	for _, any := range se.Details {
-		detail := &ptypes.DynamicAny{}
-		if err := ptypes.UnmarshalAny(any, detail); err != nil {
-			continue
-		}
-		details = append(details, detail.Message)
-	}
-	gs, err = gs.WithDetails(details...)
-	if err != nil {
-		return err
-	}
-	return gs.Err()
+	return errors.FromError(err).GRPCStatus().Err()
 }
 
 func decode(err error) error {
-	gs := status.Convert(err)
-	se := &errors.StatusError{
-		Code:    int32(gs.Code()),
-		Details: gs.Proto().Details,
-	}
-	for _, detail := range gs.Details() {
-		switch d := detail.(type) {
-		case *errdetails.ErrorInfo:
-			se.Reason = d.Reason
-			se.Message = d.Metadata["message"]
-			break
-		}
-	}
-	return se
+	return errors.FromError(err)
 }
 
 // HandlerFunc is middleware error handler.
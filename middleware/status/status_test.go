@@ -0,0 +1,38 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodes(t *testing.T) {
+	tests := []struct {
+		err  *errors.Error
+		code codes.Code
+	}{
+		{errors.BadRequest("reason", "message"), codes.InvalidArgument},
+		{errors.Unauthorized("reason", "message"), codes.Unauthenticated},
+		{errors.Forbidden("reason", "message"), codes.PermissionDenied},
+		{errors.NotFound("reason", "message"), codes.NotFound},
+		{errors.Conflict("reason", "message"), codes.AlreadyExists},
+		{errors.TooManyRequests("reason", "message"), codes.ResourceExhausted},
+		{errors.ClientClosed("reason", "message"), codes.Canceled},
+		{errors.InternalServer("reason", "message"), codes.Internal},
+		{errors.ServiceUnavailable("reason", "message"), codes.Unavailable},
+		{errors.GatewayTimeout("reason", "message"), codes.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		gerr := encode(test.err)
+		if code := status.Code(gerr); code != test.code {
+			t.Errorf("%d: expected %s, but got %s", test.err.Code, test.code, code)
+		}
+		se := errors.FromError(decode(gerr))
+		if se.Code != test.err.Code || se.Reason != test.err.Reason || se.Message != test.err.Message {
+			t.Errorf("expected %v, but got %v", test.err, se)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package timeout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Option is timeout option.
+type Option func(*options)
+
+type options struct {
+	timeout    time.Duration
+	operations map[string]time.Duration
+}
+
+// Default with the default timeout of operations.
+func Default(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// Operations with per-operation timeouts, i.e., "/v1.ReportService/Generate": 30s.
+func Operations(timeouts map[string]time.Duration) Option {
+	return func(o *options) {
+		for operation, d := range timeouts {
+			o.operations[operation] = d
+		}
+	}
+}
+
+// Budget is the deadline budget of an operation.
+type Budget struct {
+	// Timeout is the configured timeout of the operation.
+	Timeout time.Duration
+	// Start is the time when the operation started.
+	Start time.Time
+}
+
+// Consumed returns the time consumed since the operation started.
+func (b Budget) Consumed() time.Duration {
+	return time.Since(b.Start)
+}
+
+type budgetKey struct{}
+
+// NewContext returns a new Context that carries budget.
+func NewContext(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// FromContext returns the Budget value stored in ctx, or recorded in the holder of WithBudget, if any.
+func FromContext(ctx context.Context) (b Budget, ok bool) {
+	if b, ok = ctx.Value(budgetKey{}).(Budget); ok {
+		return
+	}
+	if h, ok := ctx.Value(holderKey{}).(*holder); ok {
+		return h.load()
+	}
+	return
+}
+
+type holderKey struct{}
+
+type holder struct {
+	mu     sync.Mutex
+	budget Budget
+	ok     bool
+}
+
+func (h *holder) store(b Budget) {
+	h.mu.Lock()
+	h.budget, h.ok = b, true
+	h.mu.Unlock()
+}
+
+func (h *holder) load() (Budget, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.budget, h.ok
+}
+
+// WithBudget returns a context that records the budget of the timeout middleware,
+// it is used by the middlewares placed outside the timeout middleware, i.e., logging.
+func WithBudget(ctx context.Context) context.Context {
+	return context.WithValue(ctx, holderKey{}, new(holder))
+}
+
+// Server is a server middleware that enforces per-operation deadlines,
+// the tighter of the existing deadline and the configured one wins.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		timeout:    2 * time.Second,
+		operations: make(map[string]time.Duration),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var operation string
+			if tr, ok := transport.FromContext(ctx); ok {
+				operation = tr.Operation
+			}
+			d, ok := options.operations[operation]
+			if !ok {
+				d = options.timeout
+			}
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			b := Budget{Timeout: d, Start: time.Now()}
+			if h, ok := ctx.Value(holderKey{}).(*holder); ok {
+				h.store(b)
+			}
+			ctx = NewContext(ctx, b)
+			reply, err := handler(ctx, req)
+			if err != nil {
+				if ctx.Err() == context.DeadlineExceeded && !errors.IsDeadlineExceeded(err) {
+					return nil, errors.DeadlineExceeded("Timeout", "operation %s exceeded timeout: %s", operation, d)
+				}
+				return nil, err
+			}
+			return reply, nil
+		}
+	}
+}
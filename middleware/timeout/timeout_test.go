@@ -0,0 +1,70 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestServer(t *testing.T) {
+	const operation = "/test.Reports/Generate"
+	m := Server(Default(time.Second), Operations(map[string]time.Duration{operation: 10 * time.Millisecond}))
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	ctx := transport.NewContext(context.Background(), transport.Transport{Operation: operation})
+	start := time.Now()
+	if _, err := h(ctx, nil); !errors.IsDeadlineExceeded(err) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the operation timeout, took %v", elapsed)
+	}
+
+	// the tighter existing deadline wins.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := h(ctx, nil); !errors.IsDeadlineExceeded(err) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the existing deadline, took %v", elapsed)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	fail := errors.NotFound("NotFound", "not found")
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, fail
+	})
+	if _, err := h(context.Background(), nil); err != fail {
+		t.Errorf("expected the handler error, got %v", err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	var inner Budget
+	h := Server(Default(time.Minute))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		inner, _ = FromContext(ctx)
+		return "reply", nil
+	})
+	ctx := WithBudget(context.Background())
+	if _, ok := FromContext(ctx); ok {
+		t.Errorf("expected no budget before the timeout middleware")
+	}
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	b, ok := FromContext(ctx)
+	if !ok || b != inner || b.Timeout != time.Minute {
+		t.Errorf("expected the budget %v recorded for the outer middleware, got %v", inner, b)
+	}
+	if b.Consumed() <= 0 {
+		t.Errorf("expected the consumed time, got %v", b.Consumed())
+	}
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceID returns a log Valuer of the trace id in the context, or an empty string.
+func TraceID() log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			return sc.TraceID.String()
+		}
+		return ""
+	}
+}
+
+// SpanID returns a log Valuer of the span id in the context, or an empty string.
+func SpanID() log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+			return sc.SpanID.String()
+		}
+		return ""
+	}
+}
+
+// RequestID returns a log Valuer of the request id carried by the header, i.e., X-Request-ID.
+func RequestID(header string) log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if tr, ok := transport.FromContext(ctx); ok && tr.Header != nil {
+			return tr.Header.Get(header)
+		}
+		return ""
+	}
+}
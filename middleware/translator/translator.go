@@ -0,0 +1,115 @@
+package translator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/log/stdlog"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// MatchFunc reports whether an error matches.
+type MatchFunc func(err error) bool
+
+// Option is translator option.
+type Option func(*options)
+
+type rule struct {
+	match MatchFunc
+	to    error
+}
+
+type options struct {
+	rules         []rule
+	correlationID func(ctx context.Context) string
+	logger        log.Logger
+}
+
+// Is maps the errors matching target by errors.Is to the public error.
+func Is(target error, to error) Option {
+	return Match(func(err error) bool { return stderrors.Is(err, target) }, to)
+}
+
+// Reason maps the kratos errors with the reason to the public error.
+func Reason(reason string, to error) Option {
+	return Match(func(err error) bool { return errors.Reason(err) == reason }, to)
+}
+
+// Match maps the errors matching fn to the public error.
+func Match(fn MatchFunc, to error) Option {
+	return func(o *options) {
+		o.rules = append(o.rules, rule{match: fn, to: to})
+	}
+}
+
+// CorrelationID with the correlation id generator of unknown errors.
+func CorrelationID(fn func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.correlationID = fn
+	}
+}
+
+// Logger with translator logger.
+func Logger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Server is a server middleware that translates the internal errors into public errors,
+// rules are evaluated in order, kratos errors without a matching rule pass through,
+// and any other error is converted to a generic internal error.
+func Server(opts ...Option) middleware.Middleware {
+	options := options{
+		correlationID: randomID,
+		logger:        stdlog.NewLogger(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	log := log.NewHelper("translator", options.logger)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			reply, err := handler(ctx, req)
+			if err == nil {
+				return reply, nil
+			}
+			for _, r := range options.rules {
+				if r.match(err) {
+					return nil, translate(r.to, err)
+				}
+			}
+			if se := new(errors.Error); stderrors.As(err, &se) {
+				return nil, err
+			}
+			var operation string
+			if tr, ok := transport.FromContext(ctx); ok {
+				operation = tr.Operation
+			}
+			id := options.correlationID(ctx)
+			log.WithContext(ctx).Errorw(
+				"operation", operation,
+				"correlation_id", id,
+				"error", err.Error(),
+			)
+			return nil, translate(errors.Internal("Internal", "internal error, correlation id: %s", id), err)
+		}
+	}
+}
+
+func translate(to error, cause error) error {
+	return errors.FromError(to).WithCause(cause)
+}
+
+func randomID(ctx context.Context) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,43 @@
+package translator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var errNoRows = stderrors.New("sql: no rows in result set")
+
+func TestTranslator(t *testing.T) {
+	m := Server(
+		CorrelationID(func(context.Context) string { return "test_id" }),
+		Is(errNoRows, errors.NotFound("UserNotFound", "user not found")),
+		Reason("Downstream", errors.Unavailable("Unavailable", "service unavailable")),
+	)
+	tests := []struct {
+		err    error
+		code   int32
+		reason string
+	}{
+		{fmt.Errorf("find user: %w", errNoRows), 404, "UserNotFound"},
+		{errors.Internal("Downstream", "connection refused"), 503, "Unavailable"},
+		{errors.InvalidArgument("InvalidName", "invalid name"), 400, "InvalidName"},
+		{stderrors.New("unknown"), 500, "Internal"},
+	}
+	for _, test := range tests {
+		h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, test.err
+		})
+		_, err := h(context.Background(), nil)
+		se := errors.FromError(err)
+		if se.Code != test.code || se.Reason != test.reason {
+			t.Errorf("expected %d %s, but got: %d %s", test.code, test.reason, se.Code, se.Reason)
+		}
+		if !stderrors.Is(err, test.err) {
+			t.Errorf("expected the original error to be reachable: %v", err)
+		}
+	}
+}
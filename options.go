@@ -3,6 +3,7 @@ package kratos
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/registry"
@@ -23,12 +24,41 @@ type options struct {
 	ctx  context.Context
 	sigs []os.Signal
 
-	logger   log.Logger
-	registry registry.Registry
-	servers  []transport.Server
+	logger    log.Logger
+	registrar registry.Registrar
+	servers   []transport.Server
+	stages    []*stage
+
+	registerPolicy    RegisterPolicy
+	registerTimeout   time.Duration
+	deregisterTimeout time.Duration
+	drainDelay        time.Duration
+	stopTimeout       time.Duration
+	forceExit         bool
+	crashOnPanic      bool
+	rebuild           func(context.Context) ([]transport.Server, error)
+	exit              func(code int)
+
+	beforeStart []func(context.Context) error
+	afterStart  []func(context.Context) error
+	beforeStop  []func(context.Context) error
+	afterStop   []func(context.Context) error
 }
 
-// ID with service id.
+// RegisterPolicy is the policy of registering the instance at startup, the registration
+// is retried with backoff, so that a brief outage of the registry never fails the app.
+type RegisterPolicy struct {
+	// Background serves without waiting for the registration, which is retried in the
+	// background until it succeeds or the app stops. Otherwise Run fails once the
+	// registration is not done within the deadline.
+	Background bool
+	// Deadline is the max duration of retrying the registration if not Background, 30s by default.
+	Deadline time.Duration
+	// MaxBackoff is the max delay between the attempts, 5s by default.
+	MaxBackoff time.Duration
+}
+
+// ID with service id, the host name with a random suffix by default.
 func ID(id string) Option {
 	return func(o *options) { o.id = id }
 }
@@ -53,27 +83,110 @@ func Endpoint(endpoints ...string) Option {
 	return func(o *options) { o.endpoints = endpoints }
 }
 
-// Context with service context.
+// Context with service context, whose values the contexts of the hooks, the servers and
+// the registrar carry, and whose cancellation stops the app.
 func Context(ctx context.Context) Option {
 	return func(o *options) { o.ctx = ctx }
 }
 
-// Signal with exit signals.
+// Signal with the signals stopping the app gracefully, SIGTERM and SIGINT by default,
+// and none are trapped if empty. The same signal again exits the process immediately.
+// SIGHUP is trapped as well to reload the servers if Rebuild is set, see App.Reload.
 func Signal(sigs ...os.Signal) Option {
 	return func(o *options) { o.sigs = sigs }
 }
 
-// Logger with service logger.
+// Logger with service logger, by default the global logger, see log.SetLogger.
 func Logger(logger log.Logger) Option {
 	return func(o *options) { o.logger = logger }
 }
 
-// Registry with service registry.
-func Registry(r registry.Registry) Option {
-	return func(o *options) { o.registry = r }
+// Registrar with service registrar.
+func Registrar(r registry.Registrar) Option {
+	return func(o *options) { o.registrar = r }
+}
+
+// Registration with the policy of registering the instance at startup, which retries the
+// registration for up to 30s and fails Run then by default.
+func Registration(p RegisterPolicy) Option {
+	return func(o *options) {
+		if p.Deadline > 0 {
+			o.registerPolicy.Deadline = p.Deadline
+		}
+		if p.MaxBackoff > 0 {
+			o.registerPolicy.MaxBackoff = p.MaxBackoff
+		}
+		o.registerPolicy.Background = p.Background
+	}
+}
+
+// RegisterTimeout with the timeout of each attempt of registering the instance, 10s by default.
+func RegisterTimeout(d time.Duration) Option {
+	return func(o *options) { o.registerTimeout = d }
+}
+
+// DeregisterTimeout with the timeout of deregistering the instance on stop, 3s by default.
+func DeregisterTimeout(d time.Duration) Option {
+	return func(o *options) { o.deregisterTimeout = d }
+}
+
+// DrainDelay with the delay between deregistering the instance and stopping the servers
+// on stop, so that the clients notice the instance is gone, none by default.
+func DrainDelay(d time.Duration) Option {
+	return func(o *options) { o.drainDelay = d }
+}
+
+// StopTimeout with the timeout of stopping the servers gracefully, and of the BeforeStop
+// and the AfterStop hooks each, 30s by default.
+func StopTimeout(d time.Duration) Option {
+	return func(o *options) { o.stopTimeout = d }
+}
+
+// ForceExit with whether to exit the process as the last resort once the shutdown hangs,
+// the exit function is called with 1 after a grace period of 5s beyond the stop timeout.
+func ForceExit(force bool) Option {
+	return func(o *options) { o.forceExit = force }
+}
+
+// Exit with the exit function of the forced exit and the repeated signal, os.Exit by default.
+func Exit(fn func(code int)) Option {
+	return func(o *options) { o.exit = fn }
+}
+
+// CrashOnPanic with crashing the process once the Start or the Stop of a server panics.
+// Otherwise the panic is recovered into a PanicError with the stack, which stops the app
+// gracefully as a failure, and Run returns it.
+func CrashOnPanic(crash bool) Option {
+	return func(o *options) { o.crashOnPanic = crash }
+}
+
+// Rebuild with the function building the fresh servers, which App.Reload swaps for the
+// servers serving, i.e., with the rotated certificates.
+func Rebuild(fn func(context.Context) ([]transport.Server, error)) Option {
+	return func(o *options) { o.rebuild = fn }
 }
 
 // Server with transport servers.
 func Server(srv ...transport.Server) Option {
 	return func(o *options) { o.servers = srv }
 }
+
+// BeforeStart with a hook run before any server starts, the hooks run in order.
+func BeforeStart(fn func(context.Context) error) Option {
+	return func(o *options) { o.beforeStart = append(o.beforeStart, fn) }
+}
+
+// AfterStart with a hook run once all the servers are listening, the hooks run in order.
+func AfterStart(fn func(context.Context) error) Option {
+	return func(o *options) { o.afterStart = append(o.afterStart, fn) }
+}
+
+// BeforeStop with a hook run before the servers are stopped, the hooks run in order.
+func BeforeStop(fn func(context.Context) error) Option {
+	return func(o *options) { o.beforeStop = append(o.beforeStop, fn) }
+}
+
+// AfterStop with a hook run once all the servers are stopped, the hooks run in order.
+func AfterStop(fn func(context.Context) error) Option {
+	return func(o *options) { o.afterStop = append(o.afterStop, fn) }
+}
@@ -0,0 +1,324 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/supervisor"
+
+	"github.com/hashicorp/consul/api"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// versionKey is the service meta key of the instance version.
+const versionKey = "version"
+
+// Option is consul registry option.
+type Option func(o *options)
+
+type options struct {
+	ttl             time.Duration
+	deregisterAfter time.Duration
+	healthCheck     bool
+	includeWarning  bool
+	waitTime        time.Duration
+	maxBackoff      time.Duration
+	threshold       time.Duration
+	onFailure       supervisor.FailureFunc
+	logger          log.Logger
+}
+
+// TTL with the TTL of the check passed by the registry, 10s by default.
+func TTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// DeregisterCriticalAfter with the duration after which the critical instances are
+// deregistered by consul, 1m by default.
+func DeregisterCriticalAfter(d time.Duration) Option {
+	return func(o *options) { o.deregisterAfter = d }
+}
+
+// HealthCheck with the gRPC and HTTP checks of the endpoints run by consul, in addition
+// to the TTL check. The gRPC endpoints are checked by the gRPC health checking protocol.
+func HealthCheck(enable bool) Option {
+	return func(o *options) { o.healthCheck = enable }
+}
+
+// IncludeWarning with the instances in warning state returned by the discovery,
+// only the passing instances are returned by default.
+func IncludeWarning(include bool) Option {
+	return func(o *options) { o.includeWarning = include }
+}
+
+// WaitTime with the max duration of the blocking queries of the watchers, 55s by default.
+func WaitTime(d time.Duration) Option {
+	return func(o *options) { o.waitTime = d }
+}
+
+// MaxBackoff with the max delay between the re-register attempts after the TTL check
+// fails to pass, i.e., the agent restarted, 30s by default.
+func MaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// OnFailure with the func called on the failed re-register attempts once the registration
+// has been lost longer than the threshold.
+func OnFailure(threshold time.Duration, fn supervisor.FailureFunc) Option {
+	return func(o *options) { o.threshold, o.onFailure = threshold, fn }
+}
+
+// Logger with registry logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Registry is consul registry, the metadata is kept in the service meta with the
+// version under the version key, and the endpoints are the tagged addresses keyed
+// by the schemes, i.e., grpc and https.
+type Registry struct {
+	opts   *options
+	client *api.Client
+	log    *log.Helper
+
+	mu          sync.Mutex
+	supervisors map[string]*supervisor.Supervisor
+}
+
+// New creates consul registry.
+func New(client *api.Client, opts ...Option) *Registry {
+	options := &options{
+		ttl:             10 * time.Second,
+		deregisterAfter: time.Minute,
+		waitTime:        55 * time.Second,
+		maxBackoff:      30 * time.Second,
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	return &Registry{
+		opts:        options,
+		client:      client,
+		log:         log.NewHelper("registry/consul", options.logger),
+		supervisors: make(map[string]*supervisor.Supervisor),
+	}
+}
+
+func checkID(id string) string {
+	return "service:" + id
+}
+
+// registration is a registered instance, whose TTL check is passed by its supervisor.
+type registration struct {
+	r   *Registry
+	reg *api.AgentServiceRegistration
+}
+
+// Register registers the service and passes its TTL check.
+func (reg *registration) Register(ctx context.Context) error {
+	agent := reg.r.client.Agent()
+	if err := agent.ServiceRegisterOpts(reg.reg, api.ServiceRegisterOpts{ReplaceExistingChecks: true}); err != nil {
+		return err
+	}
+	return agent.UpdateTTL(checkID(reg.reg.ID), "pass", api.HealthPassing)
+}
+
+// KeepAlive passes the TTL check until it fails.
+func (reg *registration) KeepAlive(ctx context.Context) error {
+	ticker := time.NewTicker(reg.r.opts.ttl / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := reg.r.client.Agent().UpdateTTL(checkID(reg.reg.ID), "pass", api.HealthPassing); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Register the registration, its TTL check is passed in the background until it is
+// deregistered, and it is re-registered once the check fails to pass.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	sr, err := r.registration(service)
+	if err != nil {
+		return err
+	}
+	reg := &registration{r: r, reg: sr}
+	if err = reg.Register(ctx); err != nil {
+		return err
+	}
+	s := supervisor.New(context.Background(), service.Name+"/"+service.ID, reg,
+		supervisor.MaxBackoff(r.opts.maxBackoff),
+		supervisor.OnFailure(r.opts.threshold, r.opts.onFailure),
+		supervisor.Logger(r.opts.logger),
+	)
+	r.mu.Lock()
+	prev, ok := r.supervisors[service.ID]
+	r.supervisors[service.ID] = s
+	r.mu.Unlock()
+	if ok {
+		prev.Stop()
+	}
+	return nil
+}
+
+// Deregister the registration.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	s, ok := r.supervisors[service.ID]
+	delete(r.supervisors, service.ID)
+	r.mu.Unlock()
+	if ok {
+		s.Stop()
+	}
+	return r.client.Agent().ServiceDeregister(service.ID)
+}
+
+// GetService return the service instances in memory according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	ins, _, err := r.service(ctx, name, 0)
+	return ins, err
+}
+
+// Watch creates a watcher according to the service name, the changes are pushed by the
+// blocking queries.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return registry.NewWatcherAdapter(newWatcher(ctx, r, name)), nil
+}
+
+// service returns the healthy instances by the blocking query waiting for a change of index.
+func (r *Registry) service(ctx context.Context, name string, index uint64) ([]*registry.ServiceInstance, uint64, error) {
+	opts := &api.QueryOptions{WaitIndex: index, WaitTime: r.opts.waitTime}
+	entries, meta, err := r.client.Health().Service(name, "", false, opts.WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	ins := make([]*registry.ServiceInstance, 0, len(entries))
+	for _, entry := range entries {
+		switch entry.Checks.AggregatedStatus() {
+		case api.HealthPassing:
+		case api.HealthWarning:
+			if !r.opts.includeWarning {
+				continue
+			}
+		default:
+			continue
+		}
+		ins = append(ins, instance(entry.Service))
+	}
+	return ins, meta.LastIndex, nil
+}
+
+func (r *Registry) registration(service *registry.ServiceInstance) (*api.AgentServiceRegistration, error) {
+	reg := &api.AgentServiceRegistration{
+		ID:              service.ID,
+		Name:            service.Name,
+		Meta:            make(map[string]string, len(service.Metadata)+1),
+		TaggedAddresses: make(map[string]api.ServiceAddress, len(service.Endpoints)),
+	}
+	for k, v := range service.Metadata {
+		reg.Meta[k] = v
+	}
+	reg.Meta[versionKey] = service.Version
+	// the TTL check is passing initially, so that a re-registration never flaps the instance.
+	checks := api.AgentServiceChecks{{
+		CheckID:                        checkID(service.ID),
+		TTL:                            r.opts.ttl.String(),
+		Status:                         api.HealthPassing,
+		DeregisterCriticalServiceAfter: r.opts.deregisterAfter.String(),
+	}}
+	for _, e := range service.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			return nil, err
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, err
+		}
+		secure := registry.IsSecure(u)
+		scheme := schemeOf(u.Scheme, secure)
+		reg.TaggedAddresses[scheme] = api.ServiceAddress{Address: host, Port: p}
+		if reg.Address == "" {
+			reg.Address, reg.Port = host, p
+		}
+		if !r.opts.healthCheck {
+			continue
+		}
+		check := &api.AgentServiceCheck{
+			Interval:                       r.opts.ttl.String(),
+			DeregisterCriticalServiceAfter: r.opts.deregisterAfter.String(),
+		}
+		switch scheme {
+		case "grpc", "grpcs":
+			check.GRPC, check.GRPCUseTLS = u.Host, secure
+		case "http", "https":
+			check.HTTP = scheme + "://" + u.Host
+		default:
+			continue
+		}
+		checks = append(checks, check)
+	}
+	reg.Checks = checks
+	return reg, nil
+}
+
+func instance(s *api.AgentService) *registry.ServiceInstance {
+	in := &registry.ServiceInstance{
+		ID:       s.ID,
+		Name:     s.Service,
+		Version:  s.Meta[versionKey],
+		Metadata: make(map[string]string, len(s.Meta)),
+	}
+	for k, v := range s.Meta {
+		if k != versionKey {
+			in.Metadata[k] = v
+		}
+	}
+	for scheme, addr := range s.TaggedAddresses {
+		switch scheme {
+		// the addresses tagged by consul itself.
+		case "lan", "wan", "lan_ipv4", "wan_ipv4", "lan_ipv6", "wan_ipv6":
+			continue
+		}
+		in.Endpoints = append(in.Endpoints, fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(addr.Address, strconv.Itoa(addr.Port))))
+	}
+	sort.Strings(in.Endpoints)
+	return in
+}
+
+// schemeOf returns the scheme of the endpoint with the security, i.e., grpcs for the secure grpc.
+func schemeOf(scheme string, secure bool) string {
+	if !secure {
+		return scheme
+	}
+	switch scheme {
+	case "http":
+		return "https"
+	case "grpc":
+		return "grpcs"
+	}
+	return scheme
+}
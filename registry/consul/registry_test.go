@@ -0,0 +1,197 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/registrytest"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// agent is a fake consul agent serving the registrations by the blocking queries.
+type agent struct {
+	mu       sync.Mutex
+	changed  chan struct{}
+	index    uint64
+	services map[string]*api.AgentServiceRegistration
+	status   map[string]string
+}
+
+func newAgent() *agent {
+	return &agent{
+		changed:  make(chan struct{}),
+		index:    1,
+		services: make(map[string]*api.AgentServiceRegistration),
+		status:   make(map[string]string),
+	}
+}
+
+func (a *agent) change(fn func()) {
+	a.mu.Lock()
+	fn()
+	a.index++
+	close(a.changed)
+	a.changed = make(chan struct{})
+	a.mu.Unlock()
+}
+
+func (a *agent) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	switch path := req.URL.Path; {
+	case path == "/v1/agent/service/register":
+		reg := new(api.AgentServiceRegistration)
+		json.NewDecoder(req.Body).Decode(reg)
+		status := api.HealthCritical
+		if len(reg.Checks) > 0 && reg.Checks[0].Status != "" {
+			status = reg.Checks[0].Status
+		}
+		a.change(func() { a.services[reg.ID], a.status[reg.ID] = reg, status })
+	case strings.HasPrefix(path, "/v1/agent/check/update/service:"):
+		var body struct{ Status string }
+		json.NewDecoder(req.Body).Decode(&body)
+		id := strings.TrimPrefix(path, "/v1/agent/check/update/service:")
+		a.mu.Lock()
+		_, ok := a.services[id]
+		a.mu.Unlock()
+		if !ok {
+			http.Error(res, "CheckID does not have associated TTL", http.StatusInternalServerError)
+			return
+		}
+		a.change(func() { a.status[id] = body.Status })
+	case strings.HasPrefix(path, "/v1/agent/service/deregister/"):
+		id := strings.TrimPrefix(path, "/v1/agent/service/deregister/")
+		a.change(func() { delete(a.services, id) })
+	case strings.HasPrefix(path, "/v1/health/service/"):
+		index, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+		a.mu.Lock()
+		if index == a.index {
+			changed := a.changed
+			a.mu.Unlock()
+			select {
+			case <-changed:
+			case <-time.After(100 * time.Millisecond):
+			}
+			a.mu.Lock()
+		}
+		var entries []*api.ServiceEntry
+		for id, reg := range a.services {
+			entries = append(entries, &api.ServiceEntry{
+				Service: &api.AgentService{ID: id, Service: reg.Name, Meta: reg.Meta, TaggedAddresses: reg.TaggedAddresses},
+				Checks:  api.HealthChecks{{Status: a.status[id]}},
+			})
+		}
+		res.Header().Set("X-Consul-Index", strconv.FormatUint(a.index, 10))
+		a.mu.Unlock()
+		json.NewEncoder(res).Encode(entries)
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	a := newAgent()
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(client, WaitTime(time.Second))
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{
+		ID:        "1",
+		Name:      "helloworld",
+		Version:   "v1.0.0",
+		Metadata:  map[string]string{"zone": "a"},
+		Endpoints: []string{"http://127.0.0.1:8000", "grpc://127.0.0.1:9000?isSecure=true"},
+	}
+	w, err := r.Watch(ctx, svc.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if ins, err := w.Next(); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	if err = r.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	var ins []*registry.ServiceInstance
+	for len(ins) == 0 {
+		// the critical instance is skipped until its TTL check passes.
+		if ins, err = w.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := ins[0]
+	if in.Version != "v1.0.0" || in.Metadata["zone"] != "a" || len(in.Metadata) != 1 ||
+		strings.Join(in.Endpoints, ",") != "grpcs://127.0.0.1:9000,http://127.0.0.1:8000" {
+		t.Errorf("unexpected instance %+v", in)
+	}
+
+	a.change(func() { a.status["1"] = api.HealthWarning })
+	if ins, _ = w.Next(); len(ins) != 0 {
+		t.Errorf("expected the warning instance skipped, but got %v", ins)
+	}
+	if ins, _ = New(client, IncludeWarning(true)).GetService(ctx, svc.Name); len(ins) != 1 {
+		t.Errorf("expected the warning instance included, but got %v", ins)
+	}
+
+	if err = r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if ins, err = r.GetService(ctx, svc.Name); err != nil || len(ins) != 0 {
+		t.Errorf("unexpected instances %v %v", ins, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	srv := httptest.NewServer(newAgent())
+	defer srv.Close()
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registrytest.Run(t, New(client, WaitTime(time.Second)), "conformance")
+}
+
+func TestReregister(t *testing.T) {
+	a := newAgent()
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(client, TTL(100*time.Millisecond), MaxBackoff(20*time.Millisecond))
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+	if err = r.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	// the agent restarted without the registrations.
+	a.change(func() { delete(a.services, "1") })
+	var ins []*registry.ServiceInstance
+	for deadline := time.Now().Add(time.Second); len(ins) == 0 && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		ins, _ = r.GetService(ctx, svc.Name)
+	}
+	if len(ins) != 1 {
+		t.Fatalf("expected the instance re-registered, but got %v", ins)
+	}
+	if err = r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ins, _ = r.GetService(ctx, svc.Name); len(ins) != 0 {
+		t.Errorf("expected no re-registration after deregister, but got %v", ins)
+	}
+}
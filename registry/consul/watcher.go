@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// watcher pushes the changes of the instances by the blocking queries with the index.
+type watcher struct {
+	r      *Registry
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	index  uint64
+	first  bool
+}
+
+func newWatcher(ctx context.Context, r *Registry, name string) *watcher {
+	w := &watcher{r: r, name: name, first: true}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	for {
+		index := w.index
+		if w.first {
+			index = 0
+		}
+		ins, last, err := w.r.service(w.ctx, w.name, index)
+		if err != nil {
+			if w.ctx.Err() != nil {
+				return nil, w.ctx.Err()
+			}
+			return nil, err
+		}
+		if last < w.index {
+			// the index went backwards, i.e., the consul servers were restored.
+			w.index = 0
+		}
+		if !w.first && last == w.index {
+			// the query timed out without changes.
+			continue
+		}
+		w.first, w.index = false, last
+		return ins, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
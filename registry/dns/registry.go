@@ -0,0 +1,251 @@
+// Package dns implements the discovery of the services by the DNS records, for the
+// environments with neither a registry nor the Kubernetes API.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"github.com/miekg/dns"
+)
+
+var _ registry.Discovery = (*Registry)(nil)
+
+// Option is dns discovery option.
+type Option func(o *options)
+
+type options struct {
+	resolver    string
+	scheme      string
+	defaultPort int
+	refresh     time.Duration
+	grace       time.Duration
+	timeout     time.Duration
+	logger      log.Logger
+}
+
+// Resolver with the address of the DNS server, the first nameserver of /etc/resolv.conf by default.
+func Resolver(addr string) Option {
+	return func(o *options) { o.resolver = addr }
+}
+
+// Scheme with the scheme of the endpoints, grpc by default.
+func Scheme(scheme string) Option {
+	return func(o *options) { o.scheme = scheme }
+}
+
+// DefaultPort with the port of the A and AAAA records, which are skipped without it.
+func DefaultPort(port int) Option {
+	return func(o *options) { o.defaultPort = port }
+}
+
+// RefreshInterval with the max interval of re-resolving, the records are re-resolved
+// once their min TTL expires within it, 30s by default.
+func RefreshInterval(d time.Duration) Option {
+	return func(o *options) { o.refresh = d }
+}
+
+// GracePeriod with the duration of keeping the last known good records once the DNS
+// returns no records or fails, 1m by default.
+func GracePeriod(d time.Duration) Option {
+	return func(o *options) { o.grace = d }
+}
+
+// Timeout with the timeout of the queries, 2s by default.
+func Timeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// Logger with discovery logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// minRefresh is the min interval of re-resolving, so that the records of the zero TTL
+// never flood the DNS server.
+const minRefresh = time.Second
+
+// errNoRecords is returned if the name has neither SRV nor A and AAAA records.
+var errNoRecords = errors.New("dns: no records")
+
+// Registry is dns discovery. The service is resolved by the SRV records of
+// _<scheme>._tcp.<name>, or of the name itself if it starts with an underscore, which
+// carry the ports and the weights, and only the records of the lowest priority are
+// used. Otherwise it is resolved by the A and AAAA records of the name with the
+// default port. The ID of an instance is its host and port.
+type Registry struct {
+	opts   options
+	log    *log.Helper
+	client *dns.Client
+	tcp    *dns.Client
+}
+
+// New creates dns discovery.
+func New(opts ...Option) *Registry {
+	o := options{
+		scheme:  "grpc",
+		refresh: 30 * time.Second,
+		grace:   time.Minute,
+		timeout: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.resolver == "" {
+		o.resolver = "127.0.0.1:53"
+		if conf, err := dns.ClientConfigFromFile("/etc/resolv.conf"); err == nil && len(conf.Servers) > 0 {
+			o.resolver = net.JoinHostPort(conf.Servers[0], conf.Port)
+		}
+	}
+	if o.logger == nil {
+		o.logger = log.GetLogger()
+	}
+	return &Registry{
+		opts:   o,
+		log:    log.NewHelper("registry/dns", o.logger),
+		client: &dns.Client{Timeout: o.timeout},
+		tcp:    &dns.Client{Net: "tcp", Timeout: o.timeout},
+	}
+}
+
+// GetService return the service instances resolved according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	ins, _, err := r.resolve(ctx, name)
+	if errors.Is(err, errNoRecords) {
+		return []*registry.ServiceInstance{}, nil
+	}
+	return ins, err
+}
+
+// Watch creates a watcher according to the service name, which re-resolves the records
+// once their TTL expires.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return registry.NewWatcherAdapter(newWatcher(ctx, r, name)), nil
+}
+
+// resolve returns the instances and the min TTL of the records.
+func (r *Registry) resolve(ctx context.Context, name string) ([]*registry.ServiceInstance, time.Duration, error) {
+	srv := name
+	if !strings.HasPrefix(name, "_") {
+		srv = "_" + r.opts.scheme + "._tcp." + name
+	}
+	answers, err := r.query(ctx, srv, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	var records []*dns.SRV
+	for _, rr := range answers {
+		if rec, ok := rr.(*dns.SRV); ok {
+			records = append(records, rec)
+		}
+	}
+	if len(records) > 0 {
+		return r.srvInstances(name, records), minTTL(answers), nil
+	}
+	if r.opts.defaultPort == 0 {
+		return nil, 0, errNoRecords
+	}
+	var all []dns.RR
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answers, err := r.query(ctx, name, qtype)
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, answers...)
+	}
+	var ins []*registry.ServiceInstance
+	for _, rr := range all {
+		var ip net.IP
+		switch rec := rr.(type) {
+		case *dns.A:
+			ip = rec.A
+		case *dns.AAAA:
+			ip = rec.AAAA
+		default:
+			continue
+		}
+		ins = append(ins, r.instance(name, ip.String(), r.opts.defaultPort, nil))
+	}
+	if len(ins) == 0 {
+		return nil, 0, errNoRecords
+	}
+	return sortInstances(ins), minTTL(all), nil
+}
+
+func (r *Registry) srvInstances(name string, records []*dns.SRV) []*registry.ServiceInstance {
+	priority := records[0].Priority
+	for _, rec := range records {
+		if rec.Priority < priority {
+			priority = rec.Priority
+		}
+	}
+	var ins []*registry.ServiceInstance
+	for _, rec := range records {
+		if rec.Priority != priority {
+			continue
+		}
+		var md map[string]string
+		if rec.Weight > 0 {
+			md = map[string]string{registry.WeightKey: strconv.Itoa(int(rec.Weight))}
+		}
+		ins = append(ins, r.instance(name, strings.TrimSuffix(rec.Target, "."), int(rec.Port), md))
+	}
+	return sortInstances(ins)
+}
+
+func (r *Registry) instance(name, host string, port int, md map[string]string) *registry.ServiceInstance {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	return &registry.ServiceInstance{
+		ID:        addr,
+		Name:      name,
+		Metadata:  md,
+		Endpoints: []string{r.opts.scheme + "://" + addr},
+	}
+}
+
+// query returns the answers of the question, the name error is no answers.
+// The truncated replies over UDP are queried again over TCP, so that the large
+// services never lose the instances beyond the UDP message size.
+func (r *Registry) query(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	res, _, err := r.client.ExchangeContext(ctx, m, r.opts.resolver)
+	if err != nil {
+		return nil, err
+	}
+	if res.Truncated {
+		if res, _, err = r.tcp.ExchangeContext(ctx, m, r.opts.resolver); err != nil {
+			return nil, err
+		}
+	}
+	switch res.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+		return res.Answer, nil
+	}
+	return nil, fmt.Errorf("dns: query %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[res.Rcode])
+}
+
+// minTTL returns the min TTL of the records.
+func minTTL(records []dns.RR) time.Duration {
+	var ttl uint32
+	for i, rr := range records {
+		if h := rr.Header(); i == 0 || h.Ttl < ttl {
+			ttl = h.Ttl
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func sortInstances(ins []*registry.ServiceInstance) []*registry.ServiceInstance {
+	sort.Slice(ins, func(i, j int) bool { return ins[i].ID < ins[j].ID })
+	return ins
+}
@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"github.com/miekg/dns"
+)
+
+// server is a fake DNS server of the records.
+type server struct {
+	mu       sync.Mutex
+	records  map[string][]string
+	truncate bool
+}
+
+func (s *server) set(name string, records ...string) {
+	s.mu.Lock()
+	s.records[name] = records
+	s.mu.Unlock()
+}
+
+func (s *server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	q := req.Question[0]
+	s.mu.Lock()
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp && s.truncate {
+		s.mu.Unlock()
+		m.Truncated = true
+		w.WriteMsg(m)
+		return
+	}
+	for _, rec := range s.records[q.Name] {
+		rr, _ := dns.NewRR(rec)
+		if rr.Header().Rrtype == q.Qtype {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	s.mu.Unlock()
+	w.WriteMsg(m)
+}
+
+func newServer(t *testing.T) (*server, string, func()) {
+	s := &server{records: make(map[string][]string)}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: conn, Handler: s}
+	tcp := &dns.Server{Listener: l, Handler: s}
+	go srv.ActivateAndServe()
+	go tcp.ActivateAndServe()
+	return s, conn.LocalAddr().String(), func() {
+		srv.Shutdown()
+		tcp.Shutdown()
+	}
+}
+
+func endpoints(ins []*registry.ServiceInstance) string {
+	var res []string
+	for _, in := range ins {
+		res = append(res, in.Endpoints...)
+	}
+	return strings.Join(res, ",")
+}
+
+func TestSRV(t *testing.T) {
+	s, addr, shutdown := newServer(t)
+	defer shutdown()
+	s.set("_grpc._tcp.helloworld.local.",
+		"_grpc._tcp.helloworld.local. 1 IN SRV 10 200 9000 a.helloworld.local.",
+		"_grpc._tcp.helloworld.local. 1 IN SRV 10 100 9001 b.helloworld.local.",
+		"_grpc._tcp.helloworld.local. 1 IN SRV 20 100 9002 backup.helloworld.local.",
+	)
+	r := New(Resolver(addr), GracePeriod(1500*time.Millisecond))
+	ctx := context.Background()
+	ins, err := r.GetService(ctx, "helloworld.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the records of the lowest priority are used.
+	if got := endpoints(ins); got != "grpc://a.helloworld.local:9000,grpc://b.helloworld.local:9001" || ins[0].Metadata[registry.WeightKey] != "200" {
+		t.Fatalf("unexpected instances %s %v", got, ins[0].Metadata)
+	}
+
+	w, _ := r.Watch(ctx, "helloworld.local")
+	defer w.Stop()
+	if ins, _ = w.Next(); len(ins) != 2 {
+		t.Fatalf("unexpected instances %v", ins)
+	}
+	s.set("_grpc._tcp.helloworld.local.", "_grpc._tcp.helloworld.local. 1 IN SRV 10 100 9001 b.helloworld.local.")
+	if ins, _ = w.Next(); endpoints(ins) != "grpc://b.helloworld.local:9001" {
+		t.Fatalf("unexpected instances %s", endpoints(ins))
+	}
+	// the last known good instances are kept within the grace period.
+	s.set("_grpc._tcp.helloworld.local.")
+	start := time.Now()
+	if ins, _ = w.Next(); len(ins) != 0 || time.Since(start) < time.Second {
+		t.Fatalf("expected the instances kept within the grace period, but got %v after %s", ins, time.Since(start))
+	}
+}
+
+func TestA(t *testing.T) {
+	s, addr, shutdown := newServer(t)
+	defer shutdown()
+	s.set("helloworld.local.", "helloworld.local. 30 IN A 10.0.0.1", "helloworld.local. 30 IN AAAA ::1")
+	ctx := context.Background()
+	if ins, err := New(Resolver(addr)).GetService(ctx, "helloworld.local"); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the A records skipped without the default port, but got %v %v", ins, err)
+	}
+	ins, err := New(Resolver(addr), DefaultPort(8000), Scheme("http")).GetService(ctx, "helloworld.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := endpoints(ins); got != "http://10.0.0.1:8000,http://[::1]:8000" {
+		t.Errorf("unexpected instances %s", got)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	s, addr, shutdown := newServer(t)
+	defer shutdown()
+	s.truncate = true
+	s.set("_grpc._tcp.helloworld.local.", "_grpc._tcp.helloworld.local. 1 IN SRV 10 100 9000 a.helloworld.local.")
+	ins, err := New(Resolver(addr)).GetService(context.Background(), "helloworld.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := endpoints(ins); got != "grpc://a.helloworld.local:9000" {
+		t.Errorf("expected the instances queried over tcp, but got %s", got)
+	}
+}
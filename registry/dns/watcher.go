@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// watcher re-resolves the records once their TTL expires, the last known good instances
+// are kept within the grace period once the DNS returns no records or fails.
+type watcher struct {
+	r      *Registry
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	started bool
+	next    time.Time
+	last    []*registry.ServiceInstance
+	// lost is the time since when the last known good instances are kept.
+	lost time.Time
+}
+
+func newWatcher(ctx context.Context, r *Registry, name string) *watcher {
+	w := &watcher{r: r, name: name}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	if w.started {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case <-time.After(time.Until(w.next)):
+		}
+	}
+	w.started = true
+	ins, ttl, err := w.r.resolve(w.ctx, w.name)
+	if w.ctx.Err() != nil {
+		return nil, w.ctx.Err()
+	}
+	if err == nil {
+		w.next = time.Now().Add(refresh(ttl, w.r.opts.refresh))
+		w.last, w.lost = ins, time.Time{}
+		return ins, nil
+	}
+	if !errors.Is(err, errNoRecords) {
+		w.r.log.Errorf("failed to resolve %s: %v", w.name, err)
+	}
+	if w.lost.IsZero() {
+		w.lost = time.Now()
+	}
+	if remaining := w.r.opts.grace - time.Since(w.lost); len(w.last) > 0 && remaining > 0 {
+		// retry before the grace period expires.
+		w.next = time.Now().Add(refresh(remaining, w.r.opts.refresh))
+		return w.last, nil
+	}
+	w.next = time.Now().Add(refresh(0, w.r.opts.refresh))
+	w.last = nil
+	return []*registry.ServiceInstance{}, nil
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+// refresh returns the interval of re-resolving by the TTL, within the refresh interval.
+func refresh(ttl, max time.Duration) time.Duration {
+	if ttl <= 0 || ttl > max {
+		ttl = max
+	}
+	if ttl < minRefresh {
+		ttl = minRefresh
+	}
+	return ttl
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NewEndpoint returns the endpoint URL of the scheme and the host, the secure scheme
+// is used if isSecure, i.e., grpcs for grpc. The host is with or without port, and
+// an IPv6 literal is enclosed in brackets.
+func NewEndpoint(scheme, host string, isSecure bool) string {
+	if isSecure {
+		scheme = secureScheme(scheme)
+	}
+	return (&url.URL{Scheme: scheme, Host: joinHost(host)}).String()
+}
+
+// ParseEndpoint returns the host of the first endpoint of the scheme, which is secure
+// if and only if isSecure. It returns an empty host without error if there is none, so
+// that the instance is skipped rather than failing the whole resolution. The malformed
+// endpoints and the ones of the unspecified hosts, i.e., [::]:8000, are skipped, the
+// error of a malformed endpoint is returned only if no other endpoint matches.
+func ParseEndpoint(endpoints []string, scheme string, isSecure bool) (string, error) {
+	var perr error
+	for _, e := range endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			if perr == nil {
+				perr = err
+			}
+			continue
+		}
+		if u.Host == "" || isUnspecified(u.Hostname()) {
+			continue
+		}
+		s, secure := strings.ToLower(u.Scheme), IsSecure(u)
+		if s == secureScheme(scheme) {
+			s = scheme
+		}
+		if s == scheme && secure == isSecure {
+			return u.Host, nil
+		}
+	}
+	return "", perr
+}
+
+// isUnspecified reports whether the host is unspecified, which no client can dial.
+func isUnspecified(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// IsSecure reports whether the endpoint is secure, by the scheme or the isSecure parameter.
+func IsSecure(u *url.URL) bool {
+	switch strings.ToLower(u.Scheme) {
+	case "https", "grpcs":
+		return true
+	}
+	ok, _ := strconv.ParseBool(u.Query().Get("isSecure"))
+	return ok
+}
+
+func secureScheme(scheme string) string {
+	switch scheme {
+	case "http":
+		return "https"
+	case "grpc":
+		return "grpcs"
+	}
+	return scheme
+}
+
+// joinHost encloses the IPv6 literal of the host in brackets.
+func joinHost(host string) string {
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(h, port)
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil && ip.To4() == nil {
+		return "[" + ip.String() + "]"
+	}
+	return host
+}
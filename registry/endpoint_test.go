@@ -0,0 +1,60 @@
+package registry
+
+import "testing"
+
+func TestNewEndpoint(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		host     string
+		isSecure bool
+		endpoint string
+	}{
+		{"http", "127.0.0.1:8000", false, "http://127.0.0.1:8000"},
+		{"grpc", "127.0.0.1:9000", true, "grpcs://127.0.0.1:9000"},
+		{"grpc", "[::1]:9000", false, "grpc://[::1]:9000"},
+		{"http", "::1", false, "http://[::1]"},
+		{"http", "example.com", true, "https://example.com"},
+	}
+	for _, test := range tests {
+		if e := NewEndpoint(test.scheme, test.host, test.isSecure); e != test.endpoint {
+			t.Errorf("expected %s, but got %s", test.endpoint, e)
+		}
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	endpoints := []string{
+		"http://127.0.0.1:8000",
+		"grpc://[::1]:9000",
+		"grpc://127.0.0.1:9443?isSecure=true",
+		"https://example.com",
+	}
+	tests := []struct {
+		scheme   string
+		isSecure bool
+		host     string
+	}{
+		{"http", false, "127.0.0.1:8000"},
+		{"http", true, "example.com"},
+		{"grpc", false, "[::1]:9000"},
+		{"grpc", true, "127.0.0.1:9443"},
+		{"ws", false, ""},
+	}
+	for _, test := range tests {
+		host, err := ParseEndpoint(endpoints, test.scheme, test.isSecure)
+		if err != nil || host != test.host {
+			t.Errorf("%s %v: expected %s, but got %s %v", test.scheme, test.isSecure, test.host, host, err)
+		}
+	}
+	if _, err := ParseEndpoint([]string{"http://%zz"}, "http", false); err == nil {
+		t.Error("expected error for the malformed endpoint")
+	}
+	// the malformed and the unspecified endpoints are skipped.
+	host, err := ParseEndpoint([]string{"http://%zz", "http://[::]:8000", "http://0.0.0.0:8000", "http://10.0.0.1:8000"}, "http", false)
+	if err != nil || host != "10.0.0.1:8000" {
+		t.Errorf("expected the valid endpoint, but got %s %v", host, err)
+	}
+	if host, err := ParseEndpoint([]string{"http://[::]:8000"}, "http", false); err != nil || host != "" {
+		t.Errorf("expected no endpoint, but got %s %v", host, err)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/registry/etcd
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	go.etcd.io/etcd/client/v3 v3.5.0
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
@@ -0,0 +1,231 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/supervisor"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// Option is etcd registry option.
+type Option func(o *options)
+
+type options struct {
+	ctx        context.Context
+	namespace  string
+	ttl        time.Duration
+	maxBackoff time.Duration
+	threshold  time.Duration
+	onFailure  supervisor.FailureFunc
+	logger     log.Logger
+}
+
+// Context with registry context, the registrations are kept alive until it is done.
+func Context(ctx context.Context) Option {
+	return func(o *options) { o.ctx = ctx }
+}
+
+// Namespace with registry namespace, /microservices by default.
+func Namespace(ns string) Option {
+	return func(o *options) { o.namespace = ns }
+}
+
+// RegisterTTL with the TTL of the leases of the registrations, 15s by default.
+func RegisterTTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// MaxBackoff with the max delay between the re-register attempts after the lease is lost, 30s by default.
+func MaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// OnFailure with the func called on the failed re-register attempts once the lease has
+// been lost longer than the threshold.
+func OnFailure(threshold time.Duration, fn supervisor.FailureFunc) Option {
+	return func(o *options) { o.threshold, o.onFailure = threshold, fn }
+}
+
+// Logger with registry logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Registry is etcd registry, an instance is registered as the json of the
+// ServiceInstance under /<namespace>/<service>/<id>, bound to a lease.
+type Registry struct {
+	opts   *options
+	client *clientv3.Client
+	log    *log.Helper
+
+	mu            sync.Mutex
+	registrations map[string]*registration
+}
+
+// errLeaseLost is returned by the keepalive of a lost lease.
+var errLeaseLost = errors.New("etcd: lease lost")
+
+// registration is a registered instance, whose lease is kept alive by its supervisor.
+type registration struct {
+	r          *Registry
+	key, value string
+	supervisor *supervisor.Supervisor
+
+	mu    sync.Mutex
+	lease clientv3.LeaseID
+}
+
+func (reg *registration) leaseID() clientv3.LeaseID {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.lease
+}
+
+// Register grants a new lease and puts the key bound to it.
+func (reg *registration) Register(ctx context.Context) error {
+	lease, err := reg.r.register(ctx, reg.key, reg.value)
+	if err != nil {
+		return err
+	}
+	reg.mu.Lock()
+	reg.lease = lease
+	reg.mu.Unlock()
+	return nil
+}
+
+// KeepAlive keeps the lease alive until it is lost.
+func (reg *registration) KeepAlive(ctx context.Context) error {
+	kac, err := reg.r.client.KeepAlive(ctx, reg.leaseID())
+	if err != nil {
+		return err
+	}
+	for range kac {
+		// drain the keepalive responses until the lease is lost.
+	}
+	return errLeaseLost
+}
+
+// New creates etcd registry.
+func New(client *clientv3.Client, opts ...Option) *Registry {
+	options := &options{
+		ctx:        context.Background(),
+		namespace:  "/microservices",
+		ttl:        15 * time.Second,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	return &Registry{
+		opts:          options,
+		client:        client,
+		log:           log.NewHelper("registry/etcd", options.logger),
+		registrations: make(map[string]*registration),
+	}
+}
+
+func (r *Registry) prefix(name string) string {
+	return fmt.Sprintf("%s/%s/", r.opts.namespace, name)
+}
+
+// Register the registration, the lease is re-granted and the instance is re-registered
+// once the lease is lost, i.e., by an etcd restart or a network partition.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	key := r.prefix(service.Name) + service.ID
+	value, err := json.Marshal(service)
+	if err != nil {
+		return err
+	}
+	reg := &registration{r: r, key: key, value: string(value)}
+	if err = reg.Register(ctx); err != nil {
+		return err
+	}
+	reg.supervisor = supervisor.New(r.opts.ctx, key, reg,
+		supervisor.MaxBackoff(r.opts.maxBackoff),
+		supervisor.OnFailure(r.opts.threshold, r.opts.onFailure),
+		supervisor.Logger(r.opts.logger),
+	)
+	r.mu.Lock()
+	prev, ok := r.registrations[key]
+	r.registrations[key] = reg
+	r.mu.Unlock()
+	if ok {
+		prev.supervisor.Stop()
+	}
+	return nil
+}
+
+// Deregister the registration, its lease is revoked.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	key := r.prefix(service.Name) + service.ID
+	r.mu.Lock()
+	reg, ok := r.registrations[key]
+	delete(r.registrations, key)
+	r.mu.Unlock()
+	if ok {
+		reg.supervisor.Stop()
+	}
+	if _, err := r.client.Delete(ctx, key); err != nil {
+		return err
+	}
+	if ok {
+		if _, err := r.client.Revoke(ctx, reg.leaseID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetService return the service instances in memory according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	return getService(ctx, r.client, r.prefix(name))
+}
+
+// Watch creates a watcher according to the service name.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return registry.NewWatcherAdapter(newWatcher(ctx, r.client, r.prefix(name))), nil
+}
+
+// register grants a lease and puts the key bound to it.
+func (r *Registry) register(ctx context.Context, key, value string) (clientv3.LeaseID, error) {
+	grant, err := r.client.Grant(ctx, int64(r.opts.ttl/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	if _, err = r.client.Put(ctx, key, value, clientv3.WithLease(grant.ID)); err != nil {
+		return 0, err
+	}
+	return grant.ID, nil
+}
+
+func getService(ctx context.Context, kv clientv3.KV, prefix string) ([]*registry.ServiceInstance, error) {
+	resp, err := kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*registry.ServiceInstance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		si := new(registry.ServiceInstance)
+		if err := json.Unmarshal(kv.Value, si); err != nil {
+			return nil, err
+		}
+		items = append(items, si)
+	}
+	return items, nil
+}
@@ -0,0 +1,81 @@
+// +build integration
+
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/registrytest"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// The tests run against the etcd at 127.0.0.1:2379, i.e.,
+// docker run -p 2379:2379 quay.io/coreos/etcd etcd --listen-client-urls http://0.0.0.0:2379 --advertise-client-urls http://0.0.0.0:2379
+// go test -tags integration
+func newClient(t *testing.T) *clientv3.Client {
+	client, err := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:2379"}, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRegistry(t *testing.T) {
+	client := newClient(t)
+	defer client.Close()
+	ctx := context.Background()
+	r := New(client, Namespace("/test"), RegisterTTL(2*time.Second))
+	svc := &registry.ServiceInstance{
+		ID:        "1",
+		Name:      "helloworld",
+		Version:   "v1.0.0",
+		Metadata:  map[string]string{"zone": "a"},
+		Endpoints: []string{"grpc://127.0.0.1:9000"},
+	}
+	w, err := r.Watch(ctx, svc.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if ins, err := w.Next(); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	if err = r.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	ins, err := w.Next()
+	if err != nil || len(ins) != 1 || ins[0].Version != "v1.0.0" || ins[0].Metadata["zone"] != "a" {
+		t.Fatalf("unexpected instances %v %v", ins, err)
+	}
+
+	// the instance is re-registered once its lease is revoked.
+	if _, err = client.Revoke(ctx, r.registrations["/test/helloworld/1"].leaseID()); err != nil {
+		t.Fatal(err)
+	}
+	if ins, _ = w.Next(); len(ins) != 0 {
+		t.Fatalf("expected the instance removed, but got %v", ins)
+	}
+	if ins, _ = w.Next(); len(ins) != 1 {
+		t.Fatalf("expected the instance re-registered, but got %v", ins)
+	}
+
+	if err = r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if ins, _ = w.Next(); len(ins) != 0 {
+		t.Fatalf("expected the instance deregistered, but got %v", ins)
+	}
+	if ins, err = r.GetService(ctx, svc.Name); err != nil || len(ins) != 0 {
+		t.Errorf("unexpected instances %v %v", ins, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	client := newClient(t)
+	defer client.Close()
+	registrytest.Run(t, New(client, Namespace("/conformance")), "conformance")
+}
@@ -0,0 +1,56 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kratos/kratos/v2/registry"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// watcher converts the events of the prefix into the snapshots of the instances.
+type watcher struct {
+	prefix    string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	client    *clientv3.Client
+	watchChan clientv3.WatchChan
+	first     bool
+}
+
+func newWatcher(ctx context.Context, client *clientv3.Client, prefix string) *watcher {
+	w := &watcher{prefix: prefix, client: client, first: true}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.watchChan = client.Watch(w.ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(0))
+	return w
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	if w.first {
+		w.first = false
+		return getService(w.ctx, w.client, w.prefix)
+	}
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case resp, ok := <-w.watchChan:
+		if !ok {
+			if err := w.ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("etcd: watch channel closed")
+		}
+		if err := resp.Err(); err != nil {
+			return nil, err
+		}
+		return getService(w.ctx, w.client, w.prefix)
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
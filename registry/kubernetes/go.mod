@@ -0,0 +1,12 @@
+module github.com/go-kratos/kratos/registry/kubernetes
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	k8s.io/api v0.21.14
+	k8s.io/apimachinery v0.21.14
+	k8s.io/client-go v0.21.14
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
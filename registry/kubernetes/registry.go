@@ -0,0 +1,292 @@
+// Package kubernetes implements the discovery of the services in a Kubernetes cluster
+// by their EndpointSlices, the registration is left to the kubelet.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var _ registry.Discovery = (*Registry)(nil)
+
+// SchemesAnnotation is the annotation of the Service mapping the names of its ports to
+// the schemes of the endpoints, i.e., web=http,rpc=grpc. A port without a mapping is of
+// the scheme of its name if it is http, https, grpc or grpcs, or it is prefixed by one
+// of them, i.e., grpc-helloworld, and it is skipped otherwise.
+const SchemesAnnotation = "kratos.go-kratos.dev/schemes"
+
+// VersionLabel is the label of the pods of the instance version.
+const VersionLabel = "app.kubernetes.io/version"
+
+// ErrForbidden is returned by the discovery if the list and watch are forbidden by RBAC.
+var ErrForbidden = errors.New("kubernetes: forbidden")
+
+// Option is kubernetes discovery option.
+type Option func(o *options)
+
+type options struct {
+	namespace string
+	resync    time.Duration
+	logger    log.Logger
+}
+
+// Namespace with the namespace of the services, default by default.
+func Namespace(ns string) Option {
+	return func(o *options) { o.namespace = ns }
+}
+
+// ResyncPeriod with the resync period of the informers, none by default.
+func ResyncPeriod(d time.Duration) Option {
+	return func(o *options) { o.resync = d }
+}
+
+// Logger with discovery logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// NewClient returns the client of the kubeconfig, or of the in-cluster config if the
+// kubeconfig is empty.
+func NewClient(kubeconfig string) (kubernetes.Interface, error) {
+	var (
+		config *rest.Config
+		err    error
+	)
+	if kubeconfig == "" {
+		config, err = rest.InClusterConfig()
+	} else {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// Registry is kubernetes discovery, a kratos service is the Kubernetes Service of the
+// same name, whose instances are the ready pods of its EndpointSlices: the ID is the
+// pod name, the metadata is the pod labels, and the endpoints are the pod IP with the
+// ports of the Service.
+type Registry struct {
+	opts   options
+	log    *log.Helper
+	stop   chan struct{}
+	synced []cache.InformerSynced
+
+	services corelisters.ServiceLister
+	pods     corelisters.PodLister
+	slices   discoverylisters.EndpointSliceLister
+
+	mu       sync.Mutex
+	err      error
+	watchers map[*watcher]struct{}
+}
+
+// New creates kubernetes discovery, the informers run until Close.
+func New(client kubernetes.Interface, opts ...Option) *Registry {
+	o := options{namespace: corev1.NamespaceDefault}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = log.GetLogger()
+	}
+	r := &Registry{
+		opts:     o,
+		log:      log.NewHelper("registry/kubernetes", o.logger),
+		stop:     make(chan struct{}),
+		watchers: make(map[*watcher]struct{}),
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(client, o.resync, informers.WithNamespace(o.namespace))
+	services := factory.Core().V1().Services()
+	pods := factory.Core().V1().Pods()
+	slices := factory.Discovery().V1().EndpointSlices()
+	r.services, r.pods, r.slices = services.Lister(), pods.Lister(), slices.Lister()
+	for _, informer := range []cache.SharedIndexInformer{services.Informer(), pods.Informer(), slices.Informer()} {
+		informer.SetWatchErrorHandler(r.watchError)
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { r.notify() },
+			UpdateFunc: func(interface{}, interface{}) { r.notify() },
+			DeleteFunc: func(interface{}) { r.notify() },
+		})
+		r.synced = append(r.synced, informer.HasSynced)
+	}
+	factory.Start(r.stop)
+	return r
+}
+
+// Close stops the informers.
+func (r *Registry) Close() {
+	close(r.stop)
+}
+
+// watchError keeps the forbidden error of the list and watch, so that the discovery fails
+// clearly rather than hanging, the other errors are retried by the informers.
+func (r *Registry) watchError(_ *cache.Reflector, err error) {
+	r.log.Errorf("failed to watch: %v", err)
+	// the reflector wraps the status error by its message.
+	if apierrors.IsForbidden(err) || strings.Contains(err.Error(), "is forbidden") {
+		r.mu.Lock()
+		r.err = fmt.Errorf("%w to watch in namespace %s, check the RBAC of the service account: %v", ErrForbidden, r.opts.namespace, err)
+		r.mu.Unlock()
+	}
+}
+
+// ready waits for the informers synced, or fails if the list and watch are forbidden.
+func (r *Registry) ready(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		synced := true
+		for _, fn := range r.synced {
+			synced = synced && fn()
+		}
+		if synced {
+			return nil
+		}
+		r.mu.Lock()
+		err := r.err
+		r.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stop:
+			return errors.New("kubernetes: discovery closed")
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify notifies the watchers of any change, the identical snapshots are skipped by
+// the watcher adapter.
+func (r *Registry) notify() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for w := range r.watchers {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// GetService return the ready instances of the service according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	if err := r.ready(ctx); err != nil {
+		return nil, err
+	}
+	return r.instances(name)
+}
+
+// Watch creates a watcher according to the service name.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	if err := r.ready(ctx); err != nil {
+		return nil, err
+	}
+	w := &watcher{r: r, name: name, changed: make(chan struct{}, 1)}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.changed <- struct{}{}
+	r.mu.Lock()
+	r.watchers[w] = struct{}{}
+	r.mu.Unlock()
+	return registry.NewWatcherAdapter(w), nil
+}
+
+func (r *Registry) instances(name string) ([]*registry.ServiceInstance, error) {
+	svc, err := r.services.Services(r.opts.namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return []*registry.ServiceInstance{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	schemes := portSchemes(svc)
+	slices, err := r.slices.EndpointSlices(r.opts.namespace).List(labels.SelectorFromSet(labels.Set{
+		discoveryv1.LabelServiceName: name,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]*registry.ServiceInstance)
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			if ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" || len(ep.Addresses) == 0 {
+				continue
+			}
+			in, ok := index[ep.TargetRef.Name]
+			if !ok {
+				in = &registry.ServiceInstance{ID: ep.TargetRef.Name, Name: name, Metadata: map[string]string{}}
+				if pod, err := r.pods.Pods(r.opts.namespace).Get(ep.TargetRef.Name); err == nil {
+					for k, v := range pod.Labels {
+						in.Metadata[k] = v
+					}
+					in.Version = pod.Labels[VersionLabel]
+				}
+				index[ep.TargetRef.Name] = in
+			}
+			for _, port := range slice.Ports {
+				if port.Name == nil || port.Port == nil {
+					continue
+				}
+				if scheme, ok := schemes[*port.Name]; ok {
+					host := net.JoinHostPort(ep.Addresses[0], strconv.Itoa(int(*port.Port)))
+					in.Endpoints = append(in.Endpoints, scheme+"://"+host)
+				}
+			}
+		}
+	}
+	items := make([]*registry.ServiceInstance, 0, len(index))
+	for _, in := range index {
+		sort.Strings(in.Endpoints)
+		items = append(items, in)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+// portSchemes returns the schemes of the named ports of the service.
+func portSchemes(svc *corev1.Service) map[string]string {
+	schemes := make(map[string]string)
+	for _, port := range svc.Spec.Ports {
+		for _, scheme := range []string{"https", "http", "grpcs", "grpc"} {
+			if port.Name == scheme || strings.HasPrefix(port.Name, scheme+"-") {
+				schemes[port.Name] = scheme
+				break
+			}
+		}
+	}
+	for _, kv := range strings.Split(svc.Annotations[SchemesAnnotation], ",") {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			schemes[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+		}
+	}
+	return schemes
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func pod(name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		Labels:    map[string]string{"app": "helloworld", VersionLabel: "v1.0.0"},
+	}}
+}
+
+func endpoint(name, ip string, ready bool) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Addresses:  []string{ip},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: name},
+	}
+}
+
+func ports(kv ...interface{}) []discoveryv1.EndpointPort {
+	var res []discoveryv1.EndpointPort
+	for i := 0; i < len(kv); i += 2 {
+		name, port := kv[i].(string), int32(kv[i+1].(int))
+		res = append(res, discoveryv1.EndpointPort{Name: &name, Port: &port})
+	}
+	return res
+}
+
+func TestRegistry(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "helloworld",
+			Namespace:   "default",
+			Annotations: map[string]string{SchemesAnnotation: "rpc=grpc"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http"}, {Name: "rpc"}, {Name: "metrics"}}},
+	}
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helloworld-abc",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "helloworld"},
+		},
+		Endpoints: []discoveryv1.Endpoint{endpoint("helloworld-1", "10.0.0.1", true), endpoint("helloworld-2", "10.0.0.2", false)},
+		Ports:     ports("http", 8000, "rpc", 9000, "metrics", 9090),
+	}
+	client := fake.NewSimpleClientset(svc, slice, pod("helloworld-1"), pod("helloworld-2"))
+	r := New(client)
+	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := r.Watch(ctx, "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	ins, err := w.Next()
+	if err != nil || len(ins) != 1 {
+		t.Fatalf("unexpected instances %v %v", ins, err)
+	}
+	in := ins[0]
+	if in.ID != "helloworld-1" || in.Version != "v1.0.0" || in.Metadata["app"] != "helloworld" ||
+		strings.Join(in.Endpoints, ",") != "grpc://10.0.0.1:9000,http://10.0.0.1:8000" {
+		t.Errorf("unexpected instance %+v", in)
+	}
+
+	// the pod becomes ready.
+	slice.Endpoints[1] = endpoint("helloworld-2", "10.0.0.2", true)
+	if _, err = client.DiscoveryV1().EndpointSlices("default").Update(ctx, slice, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ins, err = w.Next(); err != nil || len(ins) != 2 || ins[1].ID != "helloworld-2" {
+		t.Fatalf("unexpected instances %v %v", ins, err)
+	}
+	if ins, err = r.GetService(ctx, "unknown"); err != nil || len(ins) != 0 {
+		t.Errorf("unexpected instances %v %v", ins, err)
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gr := schema.GroupResource{Group: action.GetResource().Group, Resource: action.GetResource().Resource}
+		return true, nil, apierrors.NewForbidden(gr, "", nil)
+	})
+	r := New(client)
+	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := r.GetService(ctx, "helloworld")
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected the forbidden error, but got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+type watcher struct {
+	r       *Registry
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	changed chan struct{}
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.changed:
+	}
+	return w.r.instances(w.name)
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.r.mu.Lock()
+	delete(w.r.watchers, w)
+	w.r.mu.Unlock()
+	return nil
+}
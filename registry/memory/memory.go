@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// Registry is an in-memory registry for the tests and the single-process setups,
+// it is both the registrar and the discovery.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]map[string]*registry.ServiceInstance
+	watchers map[string]map[*watcher]struct{}
+}
+
+// New creates in-memory registry.
+func New() *Registry {
+	return &Registry{
+		services: make(map[string]map[string]*registry.ServiceInstance),
+		watchers: make(map[string]map[*watcher]struct{}),
+	}
+}
+
+// Register the registration, the watchers of the service are notified.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances, ok := r.services[service.Name]
+	if !ok {
+		instances = make(map[string]*registry.ServiceInstance)
+		r.services[service.Name] = instances
+	}
+	instances[service.ID] = clone(service)
+	r.broadcast(service.Name)
+	return nil
+}
+
+// Deregister the registration, the watchers of the service are notified.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if instances, ok := r.services[service.Name]; ok {
+		delete(instances, service.ID)
+		if len(instances) == 0 {
+			delete(r.services, service.Name)
+		}
+	}
+	r.broadcast(service.Name)
+	return nil
+}
+
+// GetService return the service instances in memory according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot(name), nil
+}
+
+// Watch creates a watcher according to the service name, the first Next returns
+// the current instances.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w := &watcher{r: r, name: name, ch: make(chan []*registry.ServiceInstance, 1)}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watchers, ok := r.watchers[name]
+	if !ok {
+		watchers = make(map[*watcher]struct{})
+		r.watchers[name] = watchers
+	}
+	watchers[w] = struct{}{}
+	w.ch <- r.snapshot(name)
+	return registry.NewWatcherAdapter(w), nil
+}
+
+// snapshot returns the copies of the instances ordered by the ids.
+func (r *Registry) snapshot(name string) []*registry.ServiceInstance {
+	instances := r.services[name]
+	items := make([]*registry.ServiceInstance, 0, len(instances))
+	for _, in := range instances {
+		items = append(items, clone(in))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// broadcast replaces the pending snapshot of the watchers with the latest one, so that
+// a slow watcher never blocks the registrations.
+func (r *Registry) broadcast(name string) {
+	for w := range r.watchers[name] {
+		select {
+		case <-w.ch:
+		default:
+		}
+		w.ch <- r.snapshot(name)
+	}
+}
+
+func (r *Registry) removeWatcher(w *watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if watchers, ok := r.watchers[w.name]; ok {
+		delete(watchers, w)
+		if len(watchers) == 0 {
+			delete(r.watchers, w.name)
+		}
+	}
+}
+
+func clone(in *registry.ServiceInstance) *registry.ServiceInstance {
+	out := *in
+	if in.Metadata != nil {
+		out.Metadata = make(map[string]string, len(in.Metadata))
+		for k, v := range in.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	out.Endpoints = append([]string(nil), in.Endpoints...)
+	return &out
+}
+
+type watcher struct {
+	r      *Registry
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     chan []*registry.ServiceInstance
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case ins := <-w.ch:
+		return ins, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.r.removeWatcher(w)
+	return nil
+}
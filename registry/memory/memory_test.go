@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/registrytest"
+)
+
+func TestRegistry(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	w, _ := r.Watch(ctx, "helloworld")
+	if ins, err := w.Next(); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld", Metadata: map[string]string{"zone": "a"}}
+	r.Register(ctx, svc)
+	r.Register(ctx, &registry.ServiceInstance{ID: "2", Name: "helloworld"})
+	// the slow watcher gets the latest snapshot.
+	ins, _ := w.Next()
+	if len(ins) != 2 || ins[0].ID != "1" || ins[1].ID != "2" {
+		t.Fatalf("unexpected instances %v", ins)
+	}
+	ins[0].Metadata["zone"] = "b"
+	if got, _ := r.GetService(ctx, "helloworld"); got[0].Metadata["zone"] != "a" {
+		t.Errorf("expected the snapshots copied, but got %v", got[0].Metadata)
+	}
+	r.Deregister(ctx, svc)
+	if ins, _ = w.Next(); len(ins) != 1 || ins[0].ID != "2" {
+		t.Errorf("unexpected instances %v", ins)
+	}
+	w.Stop()
+	if _, err := w.Next(); err == nil {
+		t.Error("expected error after stop")
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			svc := &registry.ServiceInstance{ID: fmt.Sprint(i), Name: "helloworld"}
+			for j := 0; j < 100; j++ {
+				r.Register(ctx, svc)
+				r.Deregister(ctx, svc)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w, _ := r.Watch(ctx, "helloworld")
+				w.Next()
+				w.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+	if ins, _ := r.GetService(ctx, "helloworld"); len(ins) != 0 {
+		t.Errorf("expected no instances, but got %v", ins)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	registrytest.Run(t, New(), "conformance")
+}
@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"os"
+	"strconv"
+)
+
+// The reserved metadata keys of the instances, which are read by the client-side load balancing.
+const (
+	// WeightKey is the metadata key of the weight of the instance, DefaultWeight by default.
+	WeightKey = "weight"
+	// ZoneKey is the metadata key of the zone of the instance, i.e., us-east-1a.
+	ZoneKey = "zone"
+)
+
+// DefaultWeight is the weight of the instances without a valid weight.
+const DefaultWeight = 100
+
+// ZoneEnv is the environment variable of the zone of the local instance.
+const ZoneEnv = "KRATOS_ZONE"
+
+// Weight returns the weight of the instance, DefaultWeight if it is missing or not positive.
+func Weight(in *ServiceInstance) int {
+	if w, err := strconv.Atoi(in.Metadata[WeightKey]); err == nil && w > 0 {
+		return w
+	}
+	return DefaultWeight
+}
+
+// Zone returns the zone of the instance, empty if unknown.
+func Zone(in *ServiceInstance) string {
+	return in.Metadata[ZoneKey]
+}
+
+// LocalZone returns the zone of the local instance by ZoneEnv, which the clients prefer.
+func LocalZone() string {
+	return os.Getenv(ZoneEnv)
+}
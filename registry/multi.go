@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/internal/detach"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// MultiPolicy is the policy of the partial failures of the multi registrar.
+type MultiPolicy int
+
+const (
+	// RequireAll fails the registration if any of the registrars fails, the succeeded
+	// registrations are rolled back.
+	RequireAll MultiPolicy = iota
+	// RequireAny succeeds the registration if any of the registrars succeeds, the
+	// failures of the others are logged.
+	RequireAny
+)
+
+// MultiRegistrar registers the instances into all of the registrars, i.e., both consul
+// and etcd during a migration. The instance is registered as is, so that its ID is
+// identical across the registries. The discovery is not composed, the clients watch
+// one of the registries.
+type MultiRegistrar struct {
+	// Policy is the policy of the partial failures, RequireAll by default.
+	Policy MultiPolicy
+	// Logger is the logger of the failures, the global logger is used by default.
+	Logger log.Logger
+	// RollbackTimeout is the timeout of rolling back the registrations, 10s by default.
+	// The rollback has a fresh context, since the one of Register may be done already.
+	RollbackTimeout time.Duration
+
+	registrars []Registrar
+}
+
+var _ Registrar = (*MultiRegistrar)(nil)
+
+// NewMultiRegistrar returns the registrar of the registrars.
+func NewMultiRegistrar(rs ...Registrar) *MultiRegistrar {
+	return &MultiRegistrar{registrars: rs}
+}
+
+// Register the registration into all of the registrars concurrently.
+func (m *MultiRegistrar) Register(ctx context.Context, service *ServiceInstance) error {
+	errs := m.each(func(r Registrar) error { return r.Register(ctx, service) })
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		return nil
+	case m.Policy == RequireAny && failed < len(errs):
+		m.logger().Warnf("registered %s into %d of %d registries: %v", service.ID, len(errs)-failed, len(errs), multiError(errs))
+		return nil
+	}
+	if m.Policy == RequireAll {
+		m.rollback(ctx, service, errs)
+	}
+	return multiError(errs)
+}
+
+// rollback deregisters the succeeded registrations, with a fresh context bounded by RollbackTimeout.
+func (m *MultiRegistrar) rollback(ctx context.Context, service *ServiceInstance, errs []error) {
+	timeout := m.RollbackTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(detach.Context(ctx), timeout)
+	defer cancel()
+	for i, r := range m.registrars {
+		if errs[i] != nil {
+			continue
+		}
+		if err := r.Deregister(ctx, service); err != nil {
+			m.logger().Errorf("failed to roll back the registration of %s: %v", service.ID, err)
+		}
+	}
+}
+
+// Deregister the registration from all of the registrars, even if some of them fail.
+func (m *MultiRegistrar) Deregister(ctx context.Context, service *ServiceInstance) error {
+	return multiError(m.each(func(r Registrar) error { return r.Deregister(ctx, service) }))
+}
+
+// each calls fn with the registrars concurrently, and returns the errors in order.
+func (m *MultiRegistrar) each(fn func(r Registrar) error) []error {
+	errs := make([]error, len(m.registrars))
+	var wg sync.WaitGroup
+	for i, r := range m.registrars {
+		wg.Add(1)
+		go func(i int, r Registrar) {
+			defer wg.Done()
+			errs[i] = fn(r)
+		}(i, r)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (m *MultiRegistrar) logger() *log.Helper {
+	logger := m.Logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+	return log.NewHelper("registry", logger)
+}
+
+// multiError returns the error of the failed registrars, nil if none.
+func multiError(errs []error) error {
+	var msgs []string
+	for i, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("registrar %d: %v", i, err))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("registry: %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,114 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+)
+
+type failingRegistrar struct{}
+
+func (failingRegistrar) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	return errors.New("unavailable")
+}
+
+func (failingRegistrar) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	return errors.New("unavailable")
+}
+
+// blockingRegistrar fails the registration once the context is done.
+type blockingRegistrar struct{}
+
+func (blockingRegistrar) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingRegistrar) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	return nil
+}
+
+// rollbackRegistrar records the context of the deregistration.
+type rollbackRegistrar struct {
+	registry.Registrar
+	err      error
+	deadline time.Duration
+}
+
+func (r *rollbackRegistrar) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.err = ctx.Err()
+	if deadline, ok := ctx.Deadline(); ok {
+		r.deadline = time.Until(deadline)
+	}
+	return r.Registrar.Deregister(ctx, service)
+}
+
+func TestMultiRegistrarRollback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld"}
+	a := &rollbackRegistrar{Registrar: memory.New(), err: errors.New("not rolled back")}
+	m := registry.NewMultiRegistrar(a, blockingRegistrar{})
+	m.RollbackTimeout = time.Second
+	if err := m.Register(ctx, svc); err == nil {
+		t.Fatal("expected the registration failed")
+	}
+	if a.err != nil {
+		t.Fatalf("expected the rollback with a live context, but got %v", a.err)
+	}
+	if a.deadline <= 0 || a.deadline > time.Second {
+		t.Errorf("expected the rollback bounded by the timeout, but got %v", a.deadline)
+	}
+	if ins, _ := a.Registrar.(*memory.Registry).GetService(context.Background(), "helloworld"); len(ins) != 0 {
+		t.Errorf("expected the registration rolled back, but got %v", ins)
+	}
+}
+
+func TestMultiRegistrar(t *testing.T) {
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld"}
+	a, b := memory.New(), memory.New()
+	m := registry.NewMultiRegistrar(a, b)
+	if err := m.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*memory.Registry{a, b} {
+		if ins, _ := r.GetService(ctx, "helloworld"); len(ins) != 1 || ins[0].ID != "1" {
+			t.Errorf("expected the identical instance registered, but got %v", ins)
+		}
+	}
+	if err := m.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+
+	// the succeeded registrations are rolled back if any fails.
+	m = registry.NewMultiRegistrar(a, failingRegistrar{})
+	if err := m.Register(ctx, svc); err == nil {
+		t.Fatal("expected the registration failed")
+	}
+	if ins, _ := a.GetService(ctx, "helloworld"); len(ins) != 0 {
+		t.Errorf("expected the registration rolled back, but got %v", ins)
+	}
+
+	m.Policy = registry.RequireAny
+	if err := m.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if ins, _ := a.GetService(ctx, "helloworld"); len(ins) != 1 {
+		t.Errorf("expected the instance registered, but got %v", ins)
+	}
+	// the deregistration is done on all of the registrars.
+	if err := m.Deregister(ctx, svc); err == nil {
+		t.Error("expected the deregistration failure reported")
+	}
+	if ins, _ := a.GetService(ctx, "helloworld"); len(ins) != 0 {
+		t.Errorf("expected the instance deregistered, but got %v", ins)
+	}
+	if err := registry.NewMultiRegistrar(failingRegistrar{}, failingRegistrar{}).Register(ctx, svc); err == nil {
+		t.Error("expected the registration failed")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/registry/nacos
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	github.com/nacos-group/nacos-sdk-go v1.0.8
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
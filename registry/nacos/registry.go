@@ -0,0 +1,337 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/supervisor"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// The nacos instance metadata keys of the kratos instance fields, which are stripped from
+// the metadata of the discovered instances.
+const (
+	idKey      = "id"
+	versionKey = "version"
+	kindKey    = "kind"
+)
+
+// Option is nacos registry option.
+type Option func(o *options)
+
+type options struct {
+	group         string
+	cluster       string
+	weight        float64
+	checkInterval time.Duration
+	maxBackoff    time.Duration
+	threshold     time.Duration
+	onFailure     supervisor.FailureFunc
+	logger        log.Logger
+}
+
+// Group with the group name of the instances, DEFAULT_GROUP by default.
+func Group(name string) Option {
+	return func(o *options) { o.group = name }
+}
+
+// Cluster with the cluster name of the instances, DEFAULT by default.
+func Cluster(name string) Option {
+	return func(o *options) { o.cluster = name }
+}
+
+// Weight with the weight of the instances, 100 by default.
+func Weight(w float64) Option {
+	return func(o *options) { o.weight = w }
+}
+
+// CheckInterval with the interval of checking the registered instances are still healthy
+// on the server, the lost ones are re-registered, 10s by default.
+func CheckInterval(d time.Duration) Option {
+	return func(o *options) { o.checkInterval = d }
+}
+
+// MaxBackoff with the max delay between the re-register attempts, 30s by default.
+func MaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// OnFailure with the func called on the failed re-register attempts once the registration
+// has been lost longer than the threshold.
+func OnFailure(threshold time.Duration, fn supervisor.FailureFunc) Option {
+	return func(o *options) { o.threshold, o.onFailure = threshold, fn }
+}
+
+// Logger with registry logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Registry is nacos registry, an endpoint is registered as an ephemeral nacos instance
+// of the service, whose metadata carries the kratos instance id, the version and the
+// scheme of the endpoint besides the kratos metadata.
+type Registry struct {
+	opts   options
+	client naming_client.INamingClient
+
+	mu          sync.Mutex
+	supervisors map[string]*supervisor.Supervisor
+}
+
+// New creates nacos registry.
+func New(client naming_client.INamingClient, opts ...Option) *Registry {
+	options := options{
+		group:         "DEFAULT_GROUP",
+		cluster:       "DEFAULT",
+		weight:        100,
+		checkInterval: 10 * time.Second,
+		maxBackoff:    30 * time.Second,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	return &Registry{opts: options, client: client, supervisors: make(map[string]*supervisor.Supervisor)}
+}
+
+// endpoint is an endpoint of the instance as a nacos instance.
+type endpoint struct {
+	kind string
+	host string
+	port uint64
+}
+
+func endpoints(service *registry.ServiceInstance) ([]endpoint, error) {
+	res := make([]endpoint, 0, len(service.Endpoints))
+	for _, e := range service.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			return nil, err
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		kind := u.Scheme
+		if registry.IsSecure(u) {
+			switch kind {
+			case "http":
+				kind = "https"
+			case "grpc":
+				kind = "grpcs"
+			}
+		}
+		res = append(res, endpoint{kind: kind, host: host, port: p})
+	}
+	return res, nil
+}
+
+// registration is a registered instance, which is checked by its supervisor.
+type registration struct {
+	r       *Registry
+	service *registry.ServiceInstance
+	eps     []endpoint
+}
+
+// Register registers the ephemeral instances of the endpoints.
+func (reg *registration) Register(ctx context.Context) error {
+	return reg.r.register(reg.service, reg.eps)
+}
+
+// KeepAlive checks the instances of the endpoints until any of them is lost.
+func (reg *registration) KeepAlive(ctx context.Context) error {
+	ticker := time.NewTicker(reg.r.opts.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		res, err := reg.r.client.SelectInstances(vo.SelectInstancesParam{
+			ServiceName: reg.service.Name,
+			GroupName:   reg.r.opts.group,
+			Clusters:    []string{reg.r.opts.cluster},
+			HealthyOnly: true,
+		})
+		if err != nil {
+			return err
+		}
+		found := make(map[string]bool, len(res))
+		for _, in := range res {
+			if in.Metadata[idKey] == reg.service.ID {
+				found[net.JoinHostPort(in.Ip, strconv.FormatUint(in.Port, 10))] = true
+			}
+		}
+		for _, e := range reg.eps {
+			if addr := net.JoinHostPort(e.host, strconv.FormatUint(e.port, 10)); !found[addr] {
+				return fmt.Errorf("nacos: instance %s lost", addr)
+			}
+		}
+	}
+}
+
+// Register the registration as the ephemeral instances, one per endpoint, which are
+// re-registered once any of them is lost on the server.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	eps, err := endpoints(service)
+	if err != nil {
+		return err
+	}
+	reg := &registration{r: r, service: service, eps: eps}
+	if err = reg.Register(ctx); err != nil {
+		return err
+	}
+	s := supervisor.New(context.Background(), service.Name+"/"+service.ID, reg,
+		supervisor.MaxBackoff(r.opts.maxBackoff),
+		supervisor.OnFailure(r.opts.threshold, r.opts.onFailure),
+		supervisor.Logger(r.opts.logger),
+	)
+	r.mu.Lock()
+	prev, ok := r.supervisors[service.ID]
+	r.supervisors[service.ID] = s
+	r.mu.Unlock()
+	if ok {
+		prev.Stop()
+	}
+	return nil
+}
+
+func (r *Registry) register(service *registry.ServiceInstance, eps []endpoint) error {
+	for _, e := range eps {
+		md := make(map[string]string, len(service.Metadata)+3)
+		for k, v := range service.Metadata {
+			md[k] = v
+		}
+		md[idKey], md[versionKey], md[kindKey] = service.ID, service.Version, e.kind
+		if _, err := r.client.RegisterInstance(vo.RegisterInstanceParam{
+			Ip:          e.host,
+			Port:        e.port,
+			Weight:      r.opts.weight,
+			Enable:      true,
+			Healthy:     true,
+			Metadata:    md,
+			ClusterName: r.opts.cluster,
+			ServiceName: service.Name,
+			GroupName:   r.opts.group,
+			Ephemeral:   true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deregister the registration.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	s, ok := r.supervisors[service.ID]
+	delete(r.supervisors, service.ID)
+	r.mu.Unlock()
+	if ok {
+		s.Stop()
+	}
+	eps, err := endpoints(service)
+	if err != nil {
+		return err
+	}
+	for _, e := range eps {
+		if _, err = r.client.DeregisterInstance(vo.DeregisterInstanceParam{
+			Ip:          e.host,
+			Port:        e.port,
+			Cluster:     r.opts.cluster,
+			ServiceName: service.Name,
+			GroupName:   r.opts.group,
+			Ephemeral:   true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetService return the healthy service instances according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	res, err := r.client.SelectInstances(vo.SelectInstancesParam{
+		ServiceName: name,
+		GroupName:   r.opts.group,
+		Clusters:    []string{r.opts.cluster},
+		HealthyOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instances(name, res), nil
+}
+
+// Watch creates a watcher according to the service name.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w, err := newWatcher(ctx, r, name)
+	if err != nil {
+		return nil, err
+	}
+	return registry.NewWatcherAdapter(w), nil
+}
+
+// instances merges the nacos instances of the endpoints into the kratos instances.
+func instances(name string, res []model.Instance) []*registry.ServiceInstance {
+	var (
+		items []*registry.ServiceInstance
+		index = make(map[string]*registry.ServiceInstance)
+	)
+	for _, in := range res {
+		id := in.Metadata[idKey]
+		if id == "" {
+			id = in.InstanceId
+		}
+		si, ok := index[id]
+		if !ok {
+			si = &registry.ServiceInstance{
+				ID:       id,
+				Name:     name,
+				Version:  in.Metadata[versionKey],
+				Metadata: make(map[string]string, len(in.Metadata)),
+			}
+			for k, v := range in.Metadata {
+				switch k {
+				case idKey, versionKey, kindKey:
+				default:
+					si.Metadata[k] = v
+				}
+			}
+			index[id] = si
+			items = append(items, si)
+		}
+		kind := in.Metadata[kindKey]
+		if kind == "" {
+			kind = "http"
+		}
+		si.Endpoints = append(si.Endpoints, fmt.Sprintf("%s://%s", kind, net.JoinHostPort(in.Ip, strconv.FormatUint(in.Port, 10))))
+	}
+	for _, si := range items {
+		sort.Strings(si.Endpoints)
+	}
+	return items
+}
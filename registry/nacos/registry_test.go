@@ -0,0 +1,147 @@
+package nacos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/registrytest"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// namingClient is a fake naming client keeping the instances in memory.
+type namingClient struct {
+	naming_client.INamingClient
+
+	mu        sync.Mutex
+	instances map[string]model.Instance
+	callbacks []*vo.SubscribeParam
+}
+
+func (c *namingClient) RegisterInstance(p vo.RegisterInstanceParam) (bool, error) {
+	c.mu.Lock()
+	c.instances[fmt.Sprintf("%s:%d", p.Ip, p.Port)] = model.Instance{Ip: p.Ip, Port: p.Port, Weight: p.Weight, Metadata: p.Metadata, Healthy: true, Enable: true}
+	callbacks := c.callbacks
+	c.mu.Unlock()
+	for _, cb := range callbacks {
+		cb.SubscribeCallback(nil, nil)
+	}
+	return true, nil
+}
+
+func (c *namingClient) DeregisterInstance(p vo.DeregisterInstanceParam) (bool, error) {
+	c.mu.Lock()
+	delete(c.instances, fmt.Sprintf("%s:%d", p.Ip, p.Port))
+	callbacks := c.callbacks
+	c.mu.Unlock()
+	for _, cb := range callbacks {
+		cb.SubscribeCallback(nil, nil)
+	}
+	return true, nil
+}
+
+func (c *namingClient) SelectInstances(p vo.SelectInstancesParam) ([]model.Instance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]model.Instance, 0, len(c.instances))
+	for _, in := range c.instances {
+		res = append(res, in)
+	}
+	return res, nil
+}
+
+func (c *namingClient) Subscribe(p *vo.SubscribeParam) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, p)
+	return nil
+}
+
+func (c *namingClient) Unsubscribe(p *vo.SubscribeParam) error { return nil }
+
+func TestRegistry(t *testing.T) {
+	client := &namingClient{instances: make(map[string]model.Instance)}
+	r := New(client)
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{
+		ID:        "1",
+		Name:      "helloworld",
+		Version:   "v1.0.0",
+		Metadata:  map[string]string{"zone": "a"},
+		Endpoints: []string{"http://127.0.0.1:8000", "grpc://127.0.0.1:9000?isSecure=true"},
+	}
+	w, err := r.Watch(ctx, svc.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if ins, err := w.Next(); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	// the callbacks never block without a consumer.
+	done := make(chan struct{})
+	go func() {
+		r.Register(ctx, svc)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the callbacks not blocked")
+	}
+	ins, err := w.Next()
+	if err != nil || len(ins) != 1 {
+		t.Fatalf("unexpected instances %v %v", ins, err)
+	}
+	in := ins[0]
+	if in.ID != "1" || in.Version != "v1.0.0" || in.Metadata["zone"] != "a" || len(in.Metadata) != 1 ||
+		strings.Join(in.Endpoints, ",") != "grpcs://127.0.0.1:9000,http://127.0.0.1:8000" {
+		t.Errorf("unexpected instance %+v", in)
+	}
+	if err = r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if ins, _ = w.Next(); len(ins) != 0 {
+		t.Errorf("expected the instance deregistered, but got %v", ins)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	registrytest.Run(t, New(&namingClient{instances: make(map[string]model.Instance)}), "conformance")
+}
+
+func TestReregister(t *testing.T) {
+	client := &namingClient{instances: make(map[string]model.Instance)}
+	r := New(client, CheckInterval(20*time.Millisecond), MaxBackoff(20*time.Millisecond))
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+	if err := r.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	// the server lost the instance, i.e., by a restart.
+	client.mu.Lock()
+	delete(client.instances, "127.0.0.1:9000")
+	client.mu.Unlock()
+	var ins []*registry.ServiceInstance
+	for deadline := time.Now().Add(time.Second); len(ins) == 0 && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		ins, _ = r.GetService(ctx, svc.Name)
+	}
+	if len(ins) != 1 {
+		t.Fatalf("expected the instance re-registered, but got %v", ins)
+	}
+	if err := r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ins, _ = r.GetService(ctx, svc.Name); len(ins) != 0 {
+		t.Errorf("expected no re-registration after deregister, but got %v", ins)
+	}
+}
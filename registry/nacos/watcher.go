@@ -0,0 +1,68 @@
+package nacos
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// watcher translates the subscribe callbacks into the snapshots of the instances, the
+// callbacks never block on a slow consumer, which gets the latest snapshot.
+type watcher struct {
+	r      *Registry
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	param  *vo.SubscribeParam
+	notify chan struct{}
+	first  bool
+}
+
+func newWatcher(ctx context.Context, r *Registry, name string) (*watcher, error) {
+	w := &watcher{
+		r:      r,
+		name:   name,
+		notify: make(chan struct{}, 1),
+		first:  true,
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.param = &vo.SubscribeParam{
+		ServiceName: name,
+		GroupName:   r.opts.group,
+		Clusters:    []string{r.opts.cluster},
+		SubscribeCallback: func(services []model.SubscribeService, err error) {
+			select {
+			case w.notify <- struct{}{}:
+			default:
+			}
+		},
+	}
+	if err := r.client.Subscribe(w.param); err != nil {
+		w.cancel()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	if w.first {
+		w.first = false
+		return w.r.GetService(w.ctx, w.name)
+	}
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.notify:
+	}
+	return w.r.GetService(w.ctx, w.name)
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return w.r.client.Unsubscribe(w.param)
+}
@@ -1,41 +1,61 @@
+// Package registry defines the contracts of the service registration and discovery,
+// which are implemented by the registry backends, so that the app runtime and the
+// clients are not coupled to any of them.
+//
+// An instance advertises an endpoint URL per server, whose scheme is the protocol:
+//
+//	http://127.0.0.1:8000    the HTTP server, plaintext
+//	https://127.0.0.1:8443   the HTTP server, TLS
+//	grpc://127.0.0.1:9000    the gRPC server, plaintext
+//	grpcs://127.0.0.1:9443   the gRPC server, TLS
+//
+// The host is an IP or a host name, an IPv6 literal is enclosed in brackets, i.e.,
+// grpc://[::1]:9000, and the port is required. The query parameter isSecure=true is
+// equivalent to the secure scheme, i.e., http://127.0.0.1:8443?isSecure=true.
+// The clients pick the endpoint by the scheme, and skip the instances without one.
 package registry
 
-// Registry is service registry.
-type Registry interface {
+import "context"
+
+// Registrar is service registrar.
+type Registrar interface {
 	// Register the registration.
-	Register(service *Service) error
+	Register(ctx context.Context, service *ServiceInstance) error
 	// Deregister the registration.
-	Deregister(service *Service) error
+	Deregister(ctx context.Context, service *ServiceInstance) error
+}
+
+// Discovery is service discovery.
+type Discovery interface {
 	// GetService return the service instances in memory according to the service name.
-	GetService(name string) ([]*Service, error)
+	GetService(ctx context.Context, name string) ([]*ServiceInstance, error)
 	// Watch creates a watcher according to the service name.
-	Watch(name string) (Watcher, error)
+	Watch(ctx context.Context, name string) (Watcher, error)
 }
 
 // Watcher is service watcher.
 type Watcher interface {
-	// Watch returns services in the following two cases:
-	// 1.the first time to watch and the service instance list is not empty.
-	// 2.any service instance changes found.
-	// if the above two conditions are not met, it will block until context deadline exceeded or canceled
-	Next() ([]*Service, error)
-	// Close close the watcher.
-	Close() error
+	// Next returns the snapshot of the service instances in the following two cases:
+	// 1.the first time to watch, even if the service instance list is empty.
+	// 2.any service instance changes found, the identical snapshots are skipped.
+	// Otherwise it blocks until the watcher is stopped, which returns ErrWatcherStopped,
+	// or the context of the watch is done.
+	Next() ([]*ServiceInstance, error)
+	// Stop close the watcher, and unblocks the pending Next.
+	Stop() error
 }
 
-// Service is an instance of a service in a discovery system.
-type Service struct {
+// ServiceInstance is an instance of a service in a discovery system.
+type ServiceInstance struct {
 	// ID is the unique instance ID as registered.
-	ID string
+	ID string `json:"id"`
 	// Name is the service name as registered.
-	Name string
+	Name string `json:"name"`
 	// Version is the version of the compiled.
-	Version string
+	Version string `json:"version"`
 	// Metadata is the kv pair metadata associated with the service instance.
-	Metadata map[string]string
-	// Endpoints is endpoint addresses of the service instance.
-	// schema:
-	//   http://127.0.0.1:8000?isSecure=false
-	//   grpc://127.0.0.1:9000?isSecure=false
-	Endpoints []string
+	Metadata map[string]string `json:"metadata"`
+	// Endpoints is endpoint addresses of the service instance, see the package doc
+	// for the schemes, i.e., http://127.0.0.1:8000 and grpc://127.0.0.1:9000.
+	Endpoints []string `json:"endpoints"`
 }
@@ -0,0 +1,140 @@
+// Package registrytest provides the conformance tests of the registry backends.
+package registrytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+// Timeout is the max duration of waiting for a change of the instances.
+var Timeout = 5 * time.Second
+
+// Registry is a registry backend which is both the registrar and the discovery.
+type Registry interface {
+	registry.Registrar
+	registry.Discovery
+}
+
+// Run runs the conformance tests of the backend, the instances are registered under
+// the service name, which must have no instances before.
+func Run(t *testing.T, r Registry, name string) {
+	ctx := context.Background()
+	a := &registry.ServiceInstance{
+		ID:        "conformance-a",
+		Name:      name,
+		Version:   "v1.0.0",
+		Metadata:  map[string]string{"zone": "a"},
+		Endpoints: []string{"grpc://127.0.0.1:9000"},
+	}
+	b := &registry.ServiceInstance{
+		ID:        "conformance-b",
+		Name:      name,
+		Version:   "v1.0.1",
+		Metadata:  map[string]string{"zone": "b"},
+		Endpoints: []string{"grpc://127.0.0.1:9001"},
+	}
+	w, err := r.Watch(ctx, name)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Stop()
+
+	expect(t, "the initial snapshot", w)
+	if err = r.Register(ctx, a); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	expect(t, "the registered instance", w, a)
+
+	// the identical registration is skipped by the watcher.
+	if err = r.Register(ctx, a); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err = r.Register(ctx, b); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	expect(t, "the second instance", w, a, b)
+	ins, err := r.GetService(ctx, name)
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if err = equal(ins, a, b); err != nil {
+		t.Errorf("get service: %v", err)
+	}
+
+	if err = r.Deregister(ctx, a); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+	expect(t, "the deregistered instance", w, b)
+	if err = r.Deregister(ctx, b); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+	expect(t, "no instances", w)
+
+	// stop unblocks the pending next.
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	w.Stop()
+	select {
+	case err = <-done:
+		if !errors.Is(err, registry.ErrWatcherStopped) {
+			t.Errorf("expected ErrWatcherStopped, but got %v", err)
+		}
+	case <-time.After(Timeout):
+		t.Error("expected the pending next unblocked by stop")
+	}
+}
+
+// expect waits for the snapshot of the watcher.
+func expect(t *testing.T, step string, w registry.Watcher, want ...*registry.ServiceInstance) {
+	t.Helper()
+	done := make(chan struct{})
+	var (
+		ins []*registry.ServiceInstance
+		err error
+	)
+	go func() {
+		ins, err = w.Next()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatalf("%s: timed out", step)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", step, err)
+	}
+	if ins == nil {
+		t.Errorf("%s: expected an empty snapshot rather than nil", step)
+	}
+	if err = equal(ins, want...); err != nil {
+		t.Fatalf("%s: %v", step, err)
+	}
+}
+
+func equal(ins []*registry.ServiceInstance, want ...*registry.ServiceInstance) error {
+	got := append([]*registry.ServiceInstance(nil), ins...)
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	if len(got) != len(want) {
+		return fmt.Errorf("expected %d instances, but got %d", len(want), len(got))
+	}
+	for i, in := range got {
+		w := want[i]
+		if in.ID != w.ID || in.Name != w.Name || in.Version != w.Version ||
+			!reflect.DeepEqual(in.Metadata, w.Metadata) || !reflect.DeepEqual(in.Endpoints, w.Endpoints) {
+			return fmt.Errorf("expected %+v, but got %+v", w, in)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package supervisor keeps the registrations of the registry backends alive, the lost
+// registrations are re-registered with backoff until they are deregistered.
+package supervisor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// Registration is a registration kept alive by the supervisor.
+type Registration interface {
+	// Register registers the instance again after the registration is lost.
+	Register(ctx context.Context) error
+	// KeepAlive renews the registration until ctx is done, it returns once the
+	// renewal fails, i.e., the lease or the TTL check is lost.
+	KeepAlive(ctx context.Context) error
+}
+
+// FailureFunc is called on the failed re-register attempts once the registration has
+// been lost longer than the threshold, with the duration since it was lost.
+type FailureFunc func(key string, outage time.Duration, err error)
+
+// Option is supervisor option.
+type Option func(o *options)
+
+type options struct {
+	maxBackoff time.Duration
+	threshold  time.Duration
+	onFailure  FailureFunc
+	logger     log.Logger
+}
+
+// MaxBackoff with the max delay between the re-register attempts, 30s by default.
+func MaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// OnFailure with the func called on the failed re-register attempts once the registration
+// has been lost longer than the threshold, so that the alerts can fire.
+func OnFailure(threshold time.Duration, fn FailureFunc) Option {
+	return func(o *options) { o.threshold, o.onFailure = threshold, fn }
+}
+
+// Logger with supervisor logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Supervisor keeps a registration alive in the background.
+type Supervisor struct {
+	opts   options
+	key    string
+	reg    Registration
+	log    *log.Helper
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New starts the supervisor of the registered registration, the key identifies the
+// registration in the logs and the failure func. It stops when ctx is done or Stop is called.
+func New(ctx context.Context, key string, reg Registration, opts ...Option) *Supervisor {
+	options := options{maxBackoff: 30 * time.Second}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	s := &Supervisor{
+		opts: options,
+		key:  key,
+		reg:  reg,
+		log:  log.NewHelper("registry/supervisor", options.logger),
+		done: make(chan struct{}),
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.run(ctx)
+	return s
+}
+
+// Stop stops the supervisor and waits for the pending renewal or re-register attempt,
+// so that the registration is never re-registered after Stop returns.
+func (s *Supervisor) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *Supervisor) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		err := s.reg.KeepAlive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.log.Warnf("registration of %s lost, re-registering: %v", s.key, err)
+		lost := time.Now()
+		for attempt := 0; ; attempt++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.backoff(attempt)):
+			}
+			if err = s.reg.Register(ctx); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.log.Errorf("failed to re-register %s: %v", s.key, err)
+			if outage := time.Since(lost); s.opts.onFailure != nil && outage >= s.opts.threshold {
+				s.opts.onFailure(s.key, outage, err)
+			}
+		}
+		s.log.Infof("re-registered %s after %s", s.key, time.Since(lost))
+	}
+}
+
+// backoff returns the jittered exponential delay of the re-register attempt.
+func (s *Supervisor) backoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << uint(attempt)
+	if d <= 0 || d > s.opts.maxBackoff {
+		d = s.opts.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
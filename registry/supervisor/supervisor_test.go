@@ -0,0 +1,100 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// registration is a fake registration whose backend is down within the outage window.
+type registration struct {
+	mu        sync.Mutex
+	down      bool
+	lost      chan struct{}
+	registers int
+}
+
+func (r *registration) outage(down bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if down && !r.down {
+		close(r.lost)
+	}
+	r.down = down
+}
+
+func (r *registration) Register(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.down {
+		return errors.New("backend unavailable")
+	}
+	r.registers++
+	r.lost = make(chan struct{})
+	return nil
+}
+
+func (r *registration) KeepAlive(ctx context.Context) error {
+	r.mu.Lock()
+	lost := r.lost
+	r.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-lost:
+		return errors.New("lease lost")
+	}
+}
+
+func (r *registration) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registers
+}
+
+func TestSupervisor(t *testing.T) {
+	reg := &registration{lost: make(chan struct{})}
+	var (
+		mu       sync.Mutex
+		failures []time.Duration
+	)
+	s := New(context.Background(), "helloworld/1", reg,
+		MaxBackoff(20*time.Millisecond),
+		OnFailure(50*time.Millisecond, func(key string, outage time.Duration, err error) {
+			mu.Lock()
+			failures = append(failures, outage)
+			mu.Unlock()
+		}),
+	)
+
+	reg.outage(true)
+	time.Sleep(200 * time.Millisecond)
+	if reg.count() != 0 {
+		t.Fatalf("expected no registration within the outage")
+	}
+	mu.Lock()
+	if len(failures) == 0 || failures[0] < 50*time.Millisecond {
+		t.Errorf("expected the failures after the threshold, but got %v", failures)
+	}
+	mu.Unlock()
+
+	reg.outage(false)
+	deadline := time.Now().Add(time.Second)
+	for reg.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reg.count() != 1 {
+		t.Fatalf("expected re-registered once the backend recovered, but got %d", reg.count())
+	}
+
+	// no re-registration after stop.
+	s.Stop()
+	reg.outage(true)
+	reg.outage(false)
+	time.Sleep(100 * time.Millisecond)
+	if reg.count() != 1 {
+		t.Errorf("expected no re-registration after stop, but got %d", reg.count())
+	}
+}
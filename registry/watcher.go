@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrWatcherStopped is returned by Next of a stopped watcher.
+var ErrWatcherStopped = errors.New("registry: watcher stopped")
+
+// NewWatcherAdapter returns a watcher of the standard semantics over the watcher of a
+// backend, whose Next returns the snapshots of the instances, the first one being the
+// current instances: the first Next returns the current instances even if empty, the
+// later ones block until the instances change, i.e., the identical snapshots are skipped,
+// and Stop unblocks a pending Next with ErrWatcherStopped.
+func NewWatcherAdapter(w Watcher) Watcher {
+	return &watcherAdapter{w: w, stopped: make(chan struct{})}
+}
+
+type watcherAdapter struct {
+	w       Watcher
+	stopped chan struct{}
+	once    sync.Once
+	started bool
+	last    string
+}
+
+type nextResult struct {
+	ins []*ServiceInstance
+	err error
+}
+
+func (a *watcherAdapter) Next() ([]*ServiceInstance, error) {
+	for {
+		ch := make(chan nextResult, 1)
+		go func() {
+			ins, err := a.w.Next()
+			ch <- nextResult{ins: ins, err: err}
+		}()
+		var res nextResult
+		select {
+		case <-a.stopped:
+			return nil, ErrWatcherStopped
+		case res = <-ch:
+		}
+		select {
+		case <-a.stopped:
+			return nil, ErrWatcherStopped
+		default:
+		}
+		if res.err != nil {
+			return nil, res.err
+		}
+		key := snapshotKey(res.ins)
+		if a.started && key == a.last {
+			continue
+		}
+		a.started, a.last = true, key
+		if res.ins == nil {
+			res.ins = []*ServiceInstance{}
+		}
+		return res.ins, nil
+	}
+}
+
+func (a *watcherAdapter) Stop() error {
+	var err error
+	a.once.Do(func() {
+		close(a.stopped)
+		err = a.w.Stop()
+	})
+	return err
+}
+
+// snapshotKey returns the key of the instances ordered by the ids, which is identical for
+// the identical instances, compared by the ids, the versions, the endpoints and the metadata.
+func snapshotKey(ins []*ServiceInstance) string {
+	keys := make([]string, 0, len(ins))
+	for _, in := range ins {
+		endpoints := append([]string(nil), in.Endpoints...)
+		sort.Strings(endpoints)
+		md := make([]string, 0, len(in.Metadata))
+		for k, v := range in.Metadata {
+			md = append(md, k+"="+v)
+		}
+		sort.Strings(md)
+		keys = append(keys, strings.Join([]string{
+			in.ID, in.Version, strings.Join(endpoints, ","), strings.Join(md, "&"),
+		}, "\x00"))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\x01")
+}
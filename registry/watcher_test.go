@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+type chanWatcher chan []*ServiceInstance
+
+func (w chanWatcher) Next() ([]*ServiceInstance, error) { return <-w, nil }
+func (w chanWatcher) Stop() error                       { return nil }
+
+func TestWatcherAdapter(t *testing.T) {
+	ch := make(chanWatcher, 4)
+	w := NewWatcherAdapter(ch)
+	ch <- nil
+	if ins, err := w.Next(); err != nil || ins == nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	a := &ServiceInstance{ID: "1", Version: "v1", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+	b := &ServiceInstance{ID: "1", Version: "v1", Endpoints: []string{"grpc://127.0.0.1:9001"}}
+	ch <- []*ServiceInstance{}
+	ch <- []*ServiceInstance{a}
+	ch <- []*ServiceInstance{a}
+	ch <- []*ServiceInstance{b}
+	if ins, _ := w.Next(); len(ins) != 1 || ins[0] != a {
+		t.Fatalf("expected the identical snapshots skipped, but got %v", ins)
+	}
+	// an endpoint change of the same version is not skipped.
+	if ins, _ := w.Next(); len(ins) != 1 || ins[0] != b {
+		t.Fatalf("expected the changed endpoints, but got %v", ins)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	w.Stop()
+	if err := <-done; !errors.Is(err, ErrWatcherStopped) {
+		t.Errorf("expected ErrWatcherStopped, but got %v", err)
+	}
+}
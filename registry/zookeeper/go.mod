@@ -0,0 +1,10 @@
+module github.com/go-kratos/kratos/registry/zookeeper
+
+go 1.15
+
+require (
+	github.com/go-kratos/kratos/v2 v2.0.0-20210201151837-244c98e529c3
+	github.com/go-zookeeper/zk v1.0.2
+)
+
+replace github.com/go-kratos/kratos/v2 => ../../
@@ -0,0 +1,302 @@
+package zookeeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// conn is the zookeeper operations used by the registry.
+type conn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Delete(path string, version int32) error
+	Children(path string) ([]string, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	SessionID() int64
+	Close()
+}
+
+// Option is zookeeper registry option.
+type Option func(o *options)
+
+type options struct {
+	root           string
+	sessionTimeout time.Duration
+	maxBackoff     time.Duration
+	logger         log.Logger
+}
+
+// Root with the root path of the services, /microservices by default.
+func Root(path string) Option {
+	return func(o *options) { o.root = path }
+}
+
+// SessionTimeout with the session timeout of the connection, 10s by default.
+func SessionTimeout(d time.Duration) Option {
+	return func(o *options) { o.sessionTimeout = d }
+}
+
+// MaxBackoff with the max delay between the re-register attempts after a session expiry, 10s by default.
+func MaxBackoff(d time.Duration) Option {
+	return func(o *options) { o.maxBackoff = d }
+}
+
+// Logger with registry logger, the global logger is used by default.
+func Logger(logger log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Registry is zookeeper registry, an instance is registered as the ephemeral znode
+// /<root>/<service>/<id> with the json of the ServiceInstance.
+type Registry struct {
+	opts *options
+	conn conn
+	log  *log.Helper
+
+	mu            sync.Mutex
+	registrations map[string][]byte
+	session       int64
+	closed        chan struct{}
+}
+
+// New creates zookeeper registry connected to the servers.
+func New(servers []string, opts ...Option) (*Registry, error) {
+	options := newOptions(opts)
+	c, events, err := zk.Connect(servers, options.sessionTimeout, zk.WithLogger(zkLogger{log.NewHelper("registry/zookeeper", options.logger)}))
+	if err != nil {
+		return nil, err
+	}
+	return newRegistry(c, events, options), nil
+}
+
+func newOptions(opts []Option) *options {
+	options := &options{
+		root:           "/microservices",
+		sessionTimeout: 10 * time.Second,
+		maxBackoff:     10 * time.Second,
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	return options
+}
+
+func newRegistry(c conn, events <-chan zk.Event, options *options) *Registry {
+	r := &Registry{
+		opts:          options,
+		conn:          c,
+		log:           log.NewHelper("registry/zookeeper", options.logger),
+		registrations: make(map[string][]byte),
+		closed:        make(chan struct{}),
+	}
+	go r.watchSession(events)
+	return r
+}
+
+// Close closes the connection, the ephemeral znodes are removed by zookeeper.
+func (r *Registry) Close() error {
+	close(r.closed)
+	r.conn.Close()
+	return nil
+}
+
+func (r *Registry) servicePath(name string) string {
+	return path.Join(r.opts.root, name)
+}
+
+// Register the registration.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	data, err := json.Marshal(service)
+	if err != nil {
+		return err
+	}
+	p := path.Join(r.servicePath(service.Name), service.ID)
+	if err = r.register(p, data); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.registrations[p] = data
+	r.mu.Unlock()
+	return nil
+}
+
+// Deregister the registration.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	p := path.Join(r.servicePath(service.Name), service.ID)
+	r.mu.Lock()
+	delete(r.registrations, p)
+	r.mu.Unlock()
+	if err := r.conn.Delete(p, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return err
+	}
+	return nil
+}
+
+// GetService return the service instances in memory according to the service name.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	p := r.servicePath(name)
+	children, _, err := r.conn.Children(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.instances(p, children)
+}
+
+// Watch creates a watcher according to the service name.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return registry.NewWatcherAdapter(newWatcher(ctx, r, r.servicePath(name))), nil
+}
+
+// register creates the parents and the ephemeral znode of the instance.
+func (r *Registry) register(p string, data []byte) error {
+	if err := r.ensure(path.Dir(p)); err != nil {
+		return err
+	}
+	_, err := r.conn.Create(p, data, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+	if errors.Is(err, zk.ErrNodeExists) {
+		_, err = r.conn.Set(p, data, -1)
+	}
+	return err
+}
+
+// ensure creates the persistent znodes of the path.
+func (r *Registry) ensure(p string) error {
+	if p == "/" {
+		return nil
+	}
+	if err := r.ensure(path.Dir(p)); err != nil {
+		return err
+	}
+	if _, err := r.conn.Create(p, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) instances(p string, children []string) ([]*registry.ServiceInstance, error) {
+	items := make([]*registry.ServiceInstance, 0, len(children))
+	for _, child := range children {
+		data, _, err := r.conn.Get(path.Join(p, child))
+		if errors.Is(err, zk.ErrNoNode) {
+			// deregistered in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		si := new(registry.ServiceInstance)
+		if err := json.Unmarshal(data, si); err != nil {
+			return nil, err
+		}
+		items = append(items, si)
+	}
+	return items, nil
+}
+
+// watchSession re-registers the instances once a new session is established after the
+// previous one expired. A connection loss keeps the session, so the ephemeral znodes.
+func (r *Registry) watchSession(events <-chan zk.Event) {
+	for {
+		select {
+		case <-r.closed:
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != zk.EventSession {
+				continue
+			}
+			switch e.State {
+			case zk.StateDisconnected:
+				r.log.Warn("connection lost, the session is kept until it expires")
+			case zk.StateExpired:
+				r.log.Warn("session expired, the instances are re-registered in the next session")
+			case zk.StateHasSession:
+				session := r.conn.SessionID()
+				r.mu.Lock()
+				expired := r.session != 0 && r.session != session
+				r.session = session
+				r.mu.Unlock()
+				if expired {
+					go r.reregister(session)
+				}
+			}
+		}
+	}
+}
+
+// reregister recreates the znodes of the registrations in the session with jittered
+// backoff, so that the instances do not stampede a restarted zookeeper.
+func (r *Registry) reregister(session int64) {
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-r.closed:
+			return
+		case <-time.After(r.backoff(attempt)):
+		}
+		r.mu.Lock()
+		if r.session != session {
+			// a newer session re-registers them.
+			r.mu.Unlock()
+			return
+		}
+		registrations := make(map[string][]byte, len(r.registrations))
+		for p, data := range r.registrations {
+			registrations[p] = data
+		}
+		r.mu.Unlock()
+		var failed bool
+		for p, data := range registrations {
+			if err := r.register(p, data); err != nil {
+				r.log.Errorf("failed to re-register %s: %v", p, err)
+				failed = true
+			}
+		}
+		if !failed {
+			r.log.Infof("re-registered %d instances in the new session", len(registrations))
+			return
+		}
+	}
+}
+
+// backoff returns the full jittered exponential delay of the attempt.
+func (r *Registry) backoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << uint(attempt)
+	if d <= 0 || d > r.opts.maxBackoff {
+		d = r.opts.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// zkLogger logs the messages of the zookeeper connection.
+type zkLogger struct {
+	log *log.Helper
+}
+
+func (l zkLogger) Printf(format string, a ...interface{}) {
+	l.log.Infof(format, a...)
+}
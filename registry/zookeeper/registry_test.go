@@ -0,0 +1,229 @@
+package zookeeper
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/registrytest"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// fakeConn is an in-memory zookeeper whose ephemeral znodes are removed by the session expiry.
+type fakeConn struct {
+	mu        sync.Mutex
+	session   int64
+	nodes     map[string][]byte
+	ephemeral map[string]bool
+	watches   map[string][]chan zk.Event
+	creates   int
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		session:   1,
+		nodes:     map[string][]byte{"/": nil},
+		ephemeral: make(map[string]bool),
+		watches:   make(map[string][]chan zk.Event),
+	}
+}
+
+func (c *fakeConn) fire(p string) {
+	for _, ch := range c.watches[p] {
+		ch <- zk.Event{Type: zk.EventNodeChildrenChanged, Path: p}
+	}
+	delete(c.watches, p)
+}
+
+func (c *fakeConn) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	if _, ok := c.nodes[path.Dir(p)]; !ok {
+		return "", zk.ErrNoNode
+	}
+	c.creates++
+	c.nodes[p] = data
+	c.ephemeral[p] = flags&zk.FlagEphemeral != 0
+	c.fire(p)
+	c.fire(path.Dir(p))
+	return p, nil
+}
+
+func (c *fakeConn) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[p] = data
+	return &zk.Stat{}, nil
+}
+
+func (c *fakeConn) Get(p string) ([]byte, *zk.Stat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return data, &zk.Stat{}, nil
+}
+
+func (c *fakeConn) Delete(p string, version int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.nodes[p]; !ok {
+		return zk.ErrNoNode
+	}
+	delete(c.nodes, p)
+	c.fire(path.Dir(p))
+	return nil
+}
+
+func (c *fakeConn) children(p string) ([]string, error) {
+	if _, ok := c.nodes[p]; !ok {
+		return nil, zk.ErrNoNode
+	}
+	var children []string
+	for n := range c.nodes {
+		if n != "/" && path.Dir(n) == p {
+			children = append(children, path.Base(n))
+		}
+	}
+	return children, nil
+}
+
+func (c *fakeConn) Children(p string) ([]string, *zk.Stat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	children, err := c.children(p)
+	return children, &zk.Stat{}, err
+}
+
+func (c *fakeConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	children, err := c.children(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ch := make(chan zk.Event, 1)
+	c.watches[p] = append(c.watches[p], ch)
+	return children, &zk.Stat{}, ch, nil
+}
+
+func (c *fakeConn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.nodes[p]
+	ch := make(chan zk.Event, 1)
+	c.watches[p] = append(c.watches[p], ch)
+	return ok, &zk.Stat{}, ch, nil
+}
+
+func (c *fakeConn) SessionID() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+func (c *fakeConn) Close() {}
+
+// expire removes the ephemeral znodes, and starts a new session.
+func (c *fakeConn) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p, ephemeral := range c.ephemeral {
+		if ephemeral {
+			delete(c.nodes, p)
+			delete(c.ephemeral, p)
+			c.fire(path.Dir(p))
+		}
+	}
+	c.session++
+}
+
+func (c *fakeConn) createCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.creates
+}
+
+func TestRegistry(t *testing.T) {
+	c := newFakeConn()
+	events := make(chan zk.Event, 1)
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	r := newRegistry(c, events, newOptions([]Option{MaxBackoff(10 * time.Millisecond)}))
+	defer r.Close()
+	ctx := context.Background()
+	svc := &registry.ServiceInstance{ID: "1", Name: "helloworld", Version: "v1.0.0", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+
+	w, _ := r.Watch(ctx, svc.Name)
+	defer w.Stop()
+	if ins, err := w.Next(); err != nil || len(ins) != 0 {
+		t.Fatalf("expected the empty snapshot, but got %v %v", ins, err)
+	}
+	if err := r.Register(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	var ins []*registry.ServiceInstance
+	for len(ins) == 0 {
+		ins, _ = w.Next()
+	}
+	if ins[0].ID != "1" || ins[0].Version != "v1.0.0" {
+		t.Fatalf("unexpected instances %v", ins)
+	}
+
+	// a connection loss keeps the session and the znodes.
+	creates := c.createCount()
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateDisconnected}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	time.Sleep(50 * time.Millisecond)
+	if c.createCount() != creates {
+		t.Errorf("expected no re-registration after a connection loss")
+	}
+
+	// a session expiry re-registers the instance in the new session.
+	c.expire()
+	if ins, _ = w.Next(); len(ins) != 0 {
+		t.Fatalf("expected the znode removed by the expiry, but got %v", ins)
+	}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	if ins, _ = w.Next(); len(ins) != 1 {
+		t.Fatalf("expected the instance re-registered, but got %v", ins)
+	}
+
+	if err := r.Deregister(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if ins, _ = r.GetService(ctx, svc.Name); len(ins) != 0 {
+		t.Errorf("expected the instance deregistered, but got %v", ins)
+	}
+	if strings.Count(strings.Join(keys(c), ","), "helloworld") != 1 {
+		t.Errorf("expected the service znode kept, but got %v", keys(c))
+	}
+}
+
+func keys(c *fakeConn) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []string
+	for k := range c.nodes {
+		res = append(res, k)
+	}
+	return res
+}
+
+func TestConformance(t *testing.T) {
+	events := make(chan zk.Event, 1)
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	r := newRegistry(newFakeConn(), events, newOptions(nil))
+	defer r.Close()
+	registrytest.Run(t, r, "conformance")
+}
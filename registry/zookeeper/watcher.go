@@ -0,0 +1,71 @@
+package zookeeper
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// watcher re-arms the one-shot child watches of the service znode in a loop.
+type watcher struct {
+	r      *Registry
+	path   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	event  <-chan zk.Event
+}
+
+func newWatcher(ctx context.Context, r *Registry, path string) *watcher {
+	w := &watcher{r: r, path: path}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	if w.event != nil {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case <-w.event:
+		}
+	}
+	ins, event, err := w.watch()
+	// the next call re-arms the watch immediately after a failure.
+	w.event = event
+	return ins, err
+}
+
+// watch returns the instances of the service, and arms the watch of its children,
+// or of its creation if the service znode does not exist.
+func (w *watcher) watch() ([]*registry.ServiceInstance, <-chan zk.Event, error) {
+	for {
+		children, _, event, err := w.r.conn.ChildrenW(w.path)
+		if err == nil {
+			ins, err := w.r.instances(w.path, children)
+			if err != nil {
+				return nil, nil, err
+			}
+			return ins, event, nil
+		}
+		if !errors.Is(err, zk.ErrNoNode) {
+			return nil, nil, err
+		}
+		exists, _, event, err := w.r.conn.ExistsW(w.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			return nil, event, nil
+		}
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
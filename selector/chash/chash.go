@@ -0,0 +1,161 @@
+// Package chash implements the selector of the consistent hash, which routes the requests
+// of the same hash key to the same node, see selector.WithHashKey. The nodes are placed on
+// a ring by their virtual nodes, so that a node added or removed moves only the keys of its
+// own arcs. The requests without a key are routed by the weighted random.
+package chash
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/random"
+)
+
+// DefaultReplicas is the number of the virtual nodes of a node of the default weight.
+const DefaultReplicas = 160
+
+// Option is a selector option.
+type Option func(*options)
+
+// WithReplicas with the number of the virtual nodes of a node of the default weight, the
+// others are scaled by their weights, see registry.DefaultWeight.
+func WithReplicas(n int) Option {
+	return func(o *options) {
+		o.replicas = n
+	}
+}
+
+type options struct {
+	replicas int
+}
+
+// point is a virtual node on the ring.
+type point struct {
+	hash uint64
+	node selector.Node
+}
+
+type ring struct {
+	nodes  []selector.Node
+	points []point
+}
+
+func newRing(nodes []selector.Node, replicas int) *ring {
+	total := 0
+	for _, n := range nodes {
+		total += virtuals(n, replicas)
+	}
+	r := &ring{nodes: nodes, points: make([]point, 0, total)}
+	for _, n := range nodes {
+		h := hash(n.Scheme() + "://" + n.Address())
+		for i := 0; i < virtuals(n, replicas); i++ {
+			r.points = append(r.points, point{hash: mix(h + uint64(i)*0x9e3779b97f4a7c15), node: n})
+		}
+	}
+	sort.Sort(byHash(r.points))
+	return r
+}
+
+type byHash []point
+
+func (p byHash) Len() int           { return len(p) }
+func (p byHash) Less(i, j int) bool { return p[i].hash < p[j].hash }
+func (p byHash) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// virtuals returns the number of the virtual nodes of the node by its weight, at least one.
+func virtuals(n selector.Node, replicas int) int {
+	if v := replicas * n.Weight() / registry.DefaultWeight; v > 0 {
+		return v
+	}
+	return 1
+}
+
+// lookup returns the node of the first point clockwise from the key among the allowed
+// nodes, all of them if nil.
+func (r *ring) lookup(key string, allowed map[selector.Node]bool) (selector.Node, bool) {
+	h := hash(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	for i := 0; i < len(r.points); i++ {
+		p := r.points[(start+i)%len(r.points)]
+		if allowed == nil || allowed[p.node] {
+			return p.node, true
+		}
+	}
+	return nil, false
+}
+
+// hash returns the FNV-1a hash of the key, mixed so that the keys alike spread over the ring.
+func hash(key string) uint64 {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(key))
+	return mix(f.Sum64())
+}
+
+// mix is the finalizer of SplitMix64, the virtual nodes of a node are the mixed sequence
+// from the hash of its key.
+func mix(h uint64) uint64 {
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
+
+// Selector is the selector of the consistent hash.
+type Selector struct {
+	replicas int
+	ring     atomic.Value
+	fallback selector.Selector
+}
+
+// New returns a selector of the consistent hash.
+func New(opts ...Option) selector.Selector {
+	o := options{replicas: DefaultReplicas}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &Selector{replicas: o.replicas, fallback: random.New()}
+	s.ring.Store(&ring{})
+	return s
+}
+
+// NewBuilder returns the builder of the selectors of the consistent hash.
+func NewBuilder(opts ...Option) selector.Builder {
+	return selector.BuilderFunc(func() selector.Selector { return New(opts...) })
+}
+
+// Apply replaces the nodes, which rebuilds the ring.
+func (s *Selector) Apply(nodes []selector.Node) {
+	s.ring.Store(newRing(nodes, s.replicas))
+	s.fallback.Apply(nodes)
+}
+
+// Select returns the node of the hash key of the context, or a random node without one.
+// The filtered nodes are looked up on the same ring, so that a key keeps its node as long
+// as the node is allowed. The done func reports nothing.
+func (s *Selector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	key, ok := selector.HashKey(ctx)
+	if !ok {
+		return s.fallback.Select(ctx, opts...)
+	}
+	r := s.ring.Load().(*ring)
+	var allowed map[selector.Node]bool
+	if len(opts) > 0 {
+		allowed = make(map[selector.Node]bool)
+		for _, n := range selector.Filter(ctx, r.nodes, opts...) {
+			allowed[n] = true
+		}
+		if len(allowed) == 0 {
+			return nil, nil, selector.ErrNoAvailable
+		}
+	}
+	n, ok := r.lookup(key, allowed)
+	if !ok {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	return n, done, nil
+}
+
+func done(context.Context, selector.DoneInfo) {}
@@ -0,0 +1,132 @@
+package chash
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/selectortest"
+)
+
+func TestConformance(t *testing.T) {
+	selectortest.Run(t, NewBuilder())
+	ctx := selector.WithHashKey(context.Background(), "user")
+	s := New()
+	if _, _, err := s.Select(ctx); err != selector.ErrNoAvailable {
+		t.Errorf("expected ErrNoAvailable without nodes, but got %v", err)
+	}
+}
+
+// route returns the addresses of the nodes of the keys.
+func route(s selector.Selector, keys int, opts ...selector.SelectOption) []string {
+	addrs := make([]string, keys)
+	for i := range addrs {
+		n, _, _ := s.Select(selector.WithHashKey(context.Background(), "user"+strconv.Itoa(i)), opts...)
+		addrs[i] = n.Address()
+	}
+	return addrs
+}
+
+func TestRemap(t *testing.T) {
+	s := New()
+	nodes := selectortest.Nodes("n", 4)
+	s.Apply(nodes[:3])
+	before := route(s, 10000)
+	if again := route(s, 10000); again[42] != before[42] || again[4242] != before[4242] {
+		t.Fatalf("expected the same key routed to the same node")
+	}
+	s.Apply(nodes)
+	moved := 0
+	for i, addr := range route(s, 10000) {
+		if addr != before[i] {
+			moved++
+			if addr != "n3" {
+				t.Fatalf("expected the keys moved to the added node only, but got %s", addr)
+			}
+		}
+	}
+	// a quarter of the keys expected.
+	if moved < 1500 || moved > 3500 {
+		t.Errorf("expected about a quarter of the keys moved, but got %d of 10000", moved)
+	}
+	s.Apply(nodes[:3])
+	for i, addr := range route(s, 10000) {
+		if addr != before[i] {
+			t.Fatalf("expected the keys back once the node is removed, but got %s of %s", addr, before[i])
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := New()
+	s.Apply(selectortest.Nodes("n", 3))
+	before := route(s, 1000)
+	drop := selector.WithFilter(func(_ context.Context, nodes []selector.Node) []selector.Node {
+		var res []selector.Node
+		for _, n := range nodes {
+			if n.Address() != "n0" {
+				res = append(res, n)
+			}
+		}
+		return res
+	})
+	for i, addr := range route(s, 1000, drop) {
+		if addr == "n0" || (before[i] != "n0" && addr != before[i]) {
+			t.Fatalf("expected the keys of the allowed nodes kept, but got %s of %s", addr, before[i])
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	s := New()
+	s.Apply(selectortest.Nodes("n", 2))
+	picks := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		n, _, _ := s.Select(context.Background())
+		picks[n.Address()]++
+	}
+	if picks["n0"] < 400 || picks["n1"] < 400 {
+		t.Errorf("expected the requests without a key spread, but got %v", picks)
+	}
+}
+
+// BenchmarkApply measures the cost of rebuilding the ring of 100 nodes.
+func BenchmarkApply(b *testing.B) {
+	s := New()
+	nodes := selectortest.Nodes("n", 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Apply(nodes)
+	}
+}
+
+// BenchmarkSelect reports the relative standard deviation of the shares of the ring per
+// node as the uniformity of the distribution, in percent.
+func BenchmarkSelect(b *testing.B) {
+	s := New().(*Selector)
+	s.Apply(selectortest.Nodes("n", 20))
+	ctxs := make([]context.Context, 1024)
+	for i := range ctxs {
+		ctxs[i] = selector.WithHashKey(context.Background(), "user"+strconv.Itoa(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = s.Select(ctxs[i%len(ctxs)])
+	}
+	b.StopTimer()
+	points := s.ring.Load().(*ring).points
+	shares := make(map[selector.Node]float64)
+	for i, p := range points {
+		// the arc ending at the point belongs to its node.
+		prev := points[(i+len(points)-1)%len(points)].hash
+		shares[p.node] += float64(p.hash-prev) / math.MaxUint64
+	}
+	mean := 1.0 / float64(len(shares))
+	var variance float64
+	for _, share := range shares {
+		variance += (share - mean) * (share - mean) / float64(len(shares))
+	}
+	b.ReportMetric(100*math.Sqrt(variance)/mean, "%stddev")
+}
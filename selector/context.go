@@ -0,0 +1,32 @@
+package selector
+
+import "context"
+
+type hashKey struct{}
+
+// WithHashKey returns the context with the hash key of the request, i.e., the user ID, so
+// that the requests of the same key are routed to the same node, see selector/chash.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+// HashKey returns the hash key of the request, false if none.
+func HashKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(hashKey{}).(string)
+	return key, ok
+}
+
+type filtersKey struct{}
+
+// NewFilterContext returns the context with the filters of the requests, which the HTTP and
+// gRPC clients apply after the filters of their options, so that the balancers read them
+// from the context of the pick.
+func NewFilterContext(ctx context.Context, filters ...NodeFilter) context.Context {
+	return context.WithValue(ctx, filtersKey{}, filters)
+}
+
+// FiltersFromContext returns the filters of the requests in the context.
+func FiltersFromContext(ctx context.Context) []NodeFilter {
+	filters, _ := ctx.Value(filtersKey{}).([]NodeFilter)
+	return filters
+}
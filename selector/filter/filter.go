@@ -0,0 +1,71 @@
+// Package filter implements the node filters composed with any selector, see
+// selector.WithFilter. The filters are pure and applied in order, and each returns none
+// once it drops all the nodes, which fails the selection with selector.ErrNoAvailable,
+// unless it is wrapped by OrAll.
+package filter
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// OrAll returns the filter returning the unfiltered nodes once f drops all of them.
+func OrAll(f selector.NodeFilter) selector.NodeFilter {
+	return func(ctx context.Context, nodes []selector.Node) []selector.Node {
+		if res := f(ctx, nodes); len(res) > 0 {
+			return res
+		}
+		return nodes
+	}
+}
+
+// match returns the nodes matching fn.
+func match(nodes []selector.Node, fn func(n selector.Node) bool) []selector.Node {
+	res := make([]selector.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if fn(n) {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// Zone returns the filter of the nodes in the local zone, registry.LocalZone if empty. The
+// nodes of the other zones are returned once there is none in the local zone only if
+// allowCrossZone, and all the nodes are returned if the local zone is unknown.
+func Zone(localZone string, allowCrossZone bool) selector.NodeFilter {
+	if localZone == "" {
+		localZone = registry.LocalZone()
+	}
+	return func(_ context.Context, nodes []selector.Node) []selector.Node {
+		if localZone == "" {
+			return nodes
+		}
+		res := match(nodes, func(n selector.Node) bool { return n.Zone() == localZone })
+		if len(res) == 0 && allowCrossZone {
+			return nodes
+		}
+		return res
+	}
+}
+
+// Metadata returns the filter of the nodes whose metadata of the key is one of the values,
+// or is present if there is no value.
+func Metadata(key string, values ...string) selector.NodeFilter {
+	return func(_ context.Context, nodes []selector.Node) []selector.Node {
+		return match(nodes, func(n selector.Node) bool {
+			v, ok := n.Metadata()[key]
+			if !ok || len(values) == 0 {
+				return ok
+			}
+			for _, want := range values {
+				if v == want {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
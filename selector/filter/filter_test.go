@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/selectortest"
+)
+
+func newNode(addr, version string, md map[string]string) selector.Node {
+	return selector.NewNode("http", addr, &registry.ServiceInstance{Version: version, Metadata: md})
+}
+
+func addrs(nodes []selector.Node) string {
+	res := make([]string, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.Address()
+	}
+	return strings.Join(res, ",")
+}
+
+func TestVersion(t *testing.T) {
+	var nodes []selector.Node
+	for _, v := range []string{"v1.9.0", "v2.0.0", "v2.3.1", "2.3.5-rc.1", "v3.0.0", "dev"} {
+		nodes = append(nodes, newNode(v, v, nil))
+	}
+	for pattern, want := range map[string]string{
+		"v2.*":             "v2.0.0,v2.3.1",
+		"v2.0.0":           "v2.0.0",
+		">=2.0.0 <3":       "v2.0.0,v2.3.1,2.3.5-rc.1",
+		"^2.3":             "v2.3.1,2.3.5-rc.1",
+		"~2.3.2":           "2.3.5-rc.1",
+		">v2.3.1 <=v3.0.0": "2.3.5-rc.1,v3.0.0",
+		"^0.1":             "",
+	} {
+		if got := addrs(Version(pattern)(context.Background(), nodes)); got != want {
+			t.Errorf("expected %s matching %q, but got %s", want, pattern, got)
+		}
+	}
+	for _, pattern := range []string{"[v2", ">=x", "=>1", "^1.2.3.4"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic on the invalid pattern %q", pattern)
+				}
+			}()
+			Version(pattern)
+		}()
+	}
+}
+
+func TestZoneAndMetadata(t *testing.T) {
+	nodes := []selector.Node{
+		newNode("a", "", map[string]string{registry.ZoneKey: "a", "color": "red"}),
+		newNode("b", "", map[string]string{registry.ZoneKey: "b", "color": "blue"}),
+		newNode("c", "", map[string]string{registry.ZoneKey: "b"}),
+	}
+	ctx := context.Background()
+	for want, f := range map[string]selector.NodeFilter{
+		"a":     Zone("a", false),
+		"b,c":   Zone("b", false),
+		"":      Zone("c", false),
+		"a,b,c": Zone("c", true),
+		"b":     Metadata("color", "blue", "green"),
+		"a,b":   Metadata("color"),
+	} {
+		if got := addrs(f(ctx, nodes)); got != want {
+			t.Errorf("expected %s, but got %s", want, got)
+		}
+	}
+	// composed in order, the empty set falls back to the input of OrAll.
+	got := selector.Filter(ctx, nodes, selector.WithFilter(Zone("b", false), OrAll(Metadata("color", "red"))))
+	if addrs(got) != "b,c" {
+		t.Errorf("expected the nodes of the zone kept, but got %s", addrs(got))
+	}
+}
+
+func TestSubset(t *testing.T) {
+	nodes := selectortest.Nodes("n", 30)
+	ctx := context.Background()
+	load := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		f := Subset(5, WithClientID("client"+strconv.Itoa(i)))
+		subset := f(ctx, nodes)
+		if len(subset) != 5 || addrs(f(ctx, nodes)) != addrs(subset) {
+			t.Fatalf("expected a deterministic subset of 5, but got %s", addrs(subset))
+		}
+		contains := false
+		for _, n := range subset {
+			load[n.Address()]++
+			contains = contains || n == nodes[0]
+		}
+		// a node removed changes the subsets containing it only.
+		if removed := f(ctx, nodes[1:]); !contains && addrs(removed) != addrs(subset) {
+			t.Fatalf("expected the subset kept, but got %s of %s", addrs(removed), addrs(subset))
+		}
+	}
+	// 50 clients per node expected.
+	for addr, n := range load {
+		if n < 25 || n > 80 {
+			t.Errorf("expected the clients spread over the nodes, but got %d of %s", n, addr)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// SubsetOption is an option of Subset.
+type SubsetOption func(*subsetOptions)
+
+// WithClientID with the stable ID of the client, the host name by default, or a random ID
+// if the host name is unknown.
+func WithClientID(id string) SubsetOption {
+	return func(o *subsetOptions) {
+		o.clientID = id
+	}
+}
+
+type subsetOptions struct {
+	clientID string
+}
+
+// Subset returns the filter of the subset of at most n nodes, so that a client connects
+// to a few of many nodes. The subset is deterministic by the client ID, and is picked by
+// the rendezvous hashing, so that the clients spread evenly over the nodes, and a node
+// added or removed changes the subsets of its clients only. It panics if n is not positive.
+func Subset(n int, opts ...SubsetOption) selector.NodeFilter {
+	if n < 1 {
+		panic(fmt.Sprintf("filter: invalid subset size %d", n))
+	}
+	var o subsetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.clientID == "" {
+		if o.clientID, _ = os.Hostname(); o.clientID == "" {
+			o.clientID = strconv.FormatInt(rand.Int63(), 36)
+		}
+	}
+	seed := sum64(o.clientID, offset64)
+	return func(_ context.Context, nodes []selector.Node) []selector.Node {
+		if len(nodes) <= n {
+			return nodes
+		}
+		type scored struct {
+			node  selector.Node
+			score uint64
+		}
+		all := make([]scored, len(nodes))
+		for i, node := range nodes {
+			all[i] = scored{node: node, score: sum64(node.Scheme()+"://"+node.Address(), seed)}
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].score > all[j].score })
+		res := make([]selector.Node, 0, n)
+		for _, s := range all[:n] {
+			res = append(res, s.node)
+		}
+		return res
+	}
+}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// sum64 returns the FNV-1a hash of the key from the offset, mixed by the finalizer of
+// SplitMix64.
+func sum64(key string, offset uint64) uint64 {
+	h := offset
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// Version returns the filter of the nodes whose version matches the pattern, which is either
+// a glob, i.e., v2.*, or a semver range of the space separated comparisons, all of which must
+// hold, i.e., ">=1.2.0 <2.0.0", "^1.2" or "~1.2.3". The comparisons are =, >, >=, <, <=, the
+// caret allowing the changes not modifying the major version, and the tilde allowing the
+// patch changes. The leading v of the versions is optional, and the prereleases and the
+// builds are ignored. It panics on an invalid pattern.
+func Version(pattern string) selector.NodeFilter {
+	matches, err := parseVersion(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("filter: invalid version pattern %q: %v", pattern, err))
+	}
+	return func(_ context.Context, nodes []selector.Node) []selector.Node {
+		return match(nodes, func(n selector.Node) bool { return matches(n.Version()) })
+	}
+}
+
+func parseVersion(pattern string) (func(string) bool, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || !strings.ContainsAny(pattern[:1], "=<>^~") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(v string) bool {
+			ok, _ := path.Match(pattern, v)
+			return ok
+		}, nil
+	}
+	var cmps []func(semver) bool
+	for _, f := range strings.Fields(pattern) {
+		cmp, err := parseComparison(f)
+		if err != nil {
+			return nil, err
+		}
+		cmps = append(cmps, cmp)
+	}
+	return func(v string) bool {
+		sv, _, ok := parseSemver(v)
+		if !ok {
+			return false
+		}
+		for _, cmp := range cmps {
+			if !cmp(sv) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseComparison(s string) (func(semver) bool, error) {
+	op := s
+	if i := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune("=<>^~", r) }); i >= 0 {
+		op = s[:i]
+	}
+	want, parts, ok := parseSemver(s[len(op):])
+	if !ok {
+		return nil, fmt.Errorf("invalid version %q", s[len(op):])
+	}
+	switch op {
+	case "", "=":
+		return func(v semver) bool { return v.compare(want) == 0 }, nil
+	case ">":
+		return func(v semver) bool { return v.compare(want) > 0 }, nil
+	case ">=":
+		return func(v semver) bool { return v.compare(want) >= 0 }, nil
+	case "<":
+		return func(v semver) bool { return v.compare(want) < 0 }, nil
+	case "<=":
+		return func(v semver) bool { return v.compare(want) <= 0 }, nil
+	case "^":
+		// ^0.2.3 allows 0.2.x, and ^0.0.3 allows 0.0.3 only.
+		upper := semver{want[0] + 1, 0, 0}
+		if want[0] == 0 && parts > 1 {
+			upper = semver{0, want[1] + 1, 0}
+			if want[1] == 0 && parts > 2 {
+				upper = semver{0, 0, want[2] + 1}
+			}
+		}
+		return func(v semver) bool { return v.compare(want) >= 0 && v.compare(upper) < 0 }, nil
+	case "~":
+		// ~1.2.3 and ~1.2 allow 1.2.x, and ~1 allows 1.x.
+		upper := semver{want[0], want[1] + 1, 0}
+		if parts == 1 {
+			upper = semver{want[0] + 1, 0, 0}
+		}
+		return func(v semver) bool { return v.compare(want) >= 0 && v.compare(upper) < 0 }, nil
+	}
+	return nil, fmt.Errorf("invalid comparison %q", op)
+}
+
+// semver is the major, the minor and the patch versions.
+type semver [3]int
+
+func (v semver) compare(o semver) int {
+	for i := range v {
+		if v[i] != o[i] {
+			if v[i] < o[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseSemver parses the version with the optional leading v, whose missing parts are zero,
+// it returns the number of the parts present.
+func parseSemver(s string) (semver, int, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	var v semver
+	parts := strings.Split(s, ".")
+	if len(parts) > len(v) {
+		return v, 0, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, 0, false
+		}
+		v[i] = n
+	}
+	return v, len(parts), true
+}
@@ -0,0 +1,45 @@
+package selector
+
+import "github.com/go-kratos/kratos/v2/registry"
+
+// Node is a node of a service.
+type Node interface {
+	// Scheme is the scheme of the endpoint, i.e., http, https or grpc.
+	Scheme() string
+	// Address is the host of the endpoint, i.e., 127.0.0.1:8000.
+	Address() string
+	// Weight is the initial weight of the node, see registry.Weight.
+	Weight() int
+	// Zone is the zone of the node, empty if unknown.
+	Zone() string
+	// Version is the version of the service instance.
+	Version() string
+	// Metadata is the metadata of the service instance, which must not be modified.
+	Metadata() map[string]string
+}
+
+// NewNode returns the node of the endpoint of the service instance, the instance is nil if unknown.
+func NewNode(scheme, addr string, in *registry.ServiceInstance) Node {
+	n := &node{scheme: scheme, addr: addr, weight: registry.DefaultWeight}
+	if in != nil {
+		n.weight, n.zone = registry.Weight(in), registry.Zone(in)
+		n.version, n.metadata = in.Version, in.Metadata
+	}
+	return n
+}
+
+type node struct {
+	scheme   string
+	addr     string
+	weight   int
+	zone     string
+	version  string
+	metadata map[string]string
+}
+
+func (n *node) Scheme() string              { return n.scheme }
+func (n *node) Address() string             { return n.addr }
+func (n *node) Weight() int                 { return n.weight }
+func (n *node) Zone() string                { return n.zone }
+func (n *node) Version() string             { return n.version }
+func (n *node) Metadata() map[string]string { return n.metadata }
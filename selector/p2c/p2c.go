@@ -0,0 +1,183 @@
+// Package p2c implements the selector of the power of two choices, which picks the less
+// loaded of two random nodes, the load is estimated by the requests in flight and the
+// moving averages of the latency and the success rate observed via the done funcs, so that
+// the heterogeneous nodes are routed around once they degrade.
+package p2c
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+const (
+	// tau is the time constant of the moving averages, the older observations decay by
+	// e every tau.
+	tau = 600 * time.Millisecond
+	// staleAfter is the idle duration after which the latency of a node decays by e every
+	// staleDecay, so that a node degraded once is retried rather than starved.
+	staleAfter = time.Second
+	staleDecay = 5 * time.Second
+	// defaultLatency is the latency of the nodes before the first observation, and the
+	// least cold start penalty of the nodes added later.
+	defaultLatency = 10 * time.Millisecond
+)
+
+// stats is the load statistics of a node.
+type stats struct {
+	inflight int64
+
+	mu sync.Mutex
+	// latency is the moving average of the latency in nanoseconds.
+	latency float64
+	// success is the moving average of the success rate.
+	success float64
+	// updated is the time of the last observation.
+	updated time.Time
+}
+
+func newStats(latency float64, now time.Time) *stats {
+	return &stats{latency: latency, success: 1, updated: now}
+}
+
+// observe records the result of a request.
+func (s *stats) observe(now time.Time, di selector.DoneInfo) {
+	atomic.AddInt64(&s.inflight, -1)
+	success := 1.0
+	if di.Err != nil {
+		success = 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	beta := math.Exp(-float64(now.Sub(s.updated)) / float64(tau))
+	s.latency = s.latency*beta + float64(di.Latency)*(1-beta)
+	s.success = s.success*beta + success*(1-beta)
+	s.updated = now
+}
+
+// load returns the estimated cost of a request, the stale latency decays.
+func (s *stats) load(now time.Time, weight int) float64 {
+	s.mu.Lock()
+	latency, success, idle := s.latency, s.success, now.Sub(s.updated)
+	s.mu.Unlock()
+	if idle > staleAfter {
+		latency *= math.Exp(-float64(idle-staleAfter) / float64(staleDecay))
+	}
+	inflight := float64(atomic.LoadInt64(&s.inflight))
+	// the success rate never drops to zero, so that the failing nodes are still compared.
+	return (latency + 1) * (inflight + 1) / (math.Max(success, 0.01) * math.Max(float64(weight), 1))
+}
+
+func (s *stats) snapshot() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latency
+}
+
+type balancer struct {
+	nodes []selector.Node
+	stats map[selector.Node]*stats
+}
+
+// Selector is the selector of the power of two choices.
+type Selector struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	balancer atomic.Value
+	random   *rand.Rand
+}
+
+// New returns a selector of the power of two choices.
+func New() selector.Selector {
+	return newSelector(time.Now)
+}
+
+// NewBuilder returns the builder of the selectors of the power of two choices.
+func NewBuilder() selector.Builder {
+	return selector.BuilderFunc(New)
+}
+
+func newSelector(now func() time.Time) *Selector {
+	s := &Selector{now: now, random: rand.New(rand.NewSource(now().UnixNano()))}
+	s.balancer.Store(&balancer{stats: map[selector.Node]*stats{}})
+	return s
+}
+
+// Apply replaces the nodes, the nodes kept keep their statistics, and the nodes added
+// start with the highest latency of the nodes kept as the cold start penalty, so that
+// they are not flooded before the first observations.
+func (s *Selector) Apply(nodes []selector.Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	former := s.balancer.Load().(*balancer)
+	kept := make(map[string]*stats, len(former.nodes))
+	penalty := float64(defaultLatency)
+	for _, n := range former.nodes {
+		st := former.stats[n]
+		kept[key(n)] = st
+		if l := st.snapshot(); l > penalty {
+			penalty = l
+		}
+	}
+	b := &balancer{nodes: nodes, stats: make(map[selector.Node]*stats, len(nodes))}
+	now := s.now()
+	for _, n := range nodes {
+		st, ok := kept[key(n)]
+		if !ok {
+			st = newStats(penalty, now)
+		}
+		b.stats[n] = st
+	}
+	s.balancer.Store(b)
+}
+
+func key(n selector.Node) string {
+	return n.Scheme() + "://" + n.Address()
+}
+
+// Select returns the less loaded of two random nodes, or the only one, and the done func
+// which must be called once the request is done to feed back the load.
+func (s *Selector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	b := s.balancer.Load().(*balancer)
+	known := b.nodes
+	if len(opts) > 0 {
+		known = nil
+		for _, n := range selector.Filter(ctx, b.nodes, opts...) {
+			if _, ok := b.stats[n]; ok {
+				known = append(known, n)
+			}
+		}
+	}
+	if len(known) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	picked := known[0]
+	if len(known) > 1 {
+		s.mu.Lock()
+		i := s.random.Intn(len(known))
+		// the second choice is never the first one.
+		j := s.random.Intn(len(known) - 1)
+		s.mu.Unlock()
+		if j >= i {
+			j++
+		}
+		now := s.now()
+		a, c := known[i], known[j]
+		if b.stats[c].load(now, c.Weight()) < b.stats[a].load(now, a.Weight()) {
+			a = c
+		}
+		picked = a
+	}
+	st := b.stats[picked]
+	atomic.AddInt64(&st.inflight, 1)
+	var once sync.Once
+	return picked, func(ctx context.Context, di selector.DoneInfo) {
+		once.Do(func() { st.observe(s.now(), di) })
+	}, nil
+}
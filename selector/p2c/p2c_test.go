@@ -0,0 +1,76 @@
+package p2c
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/selectortest"
+)
+
+func TestConformance(t *testing.T) {
+	selectortest.Run(t, NewBuilder())
+}
+
+// clock is the fake clock of the simulations.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestTwoNodes(t *testing.T) {
+	c := &clock{now: time.Now()}
+	s := newSelector(c.Now)
+	nodes := selectortest.Nodes("n", 2)
+	s.Apply(nodes)
+	// the slow node is never picked twice as the both choices.
+	slow := nodes[1]
+	s.balancer.Load().(*balancer).stats[slow].latency = float64(time.Second)
+	for i := 0; i < 100; i++ {
+		n, done, _ := s.Select(context.Background())
+		if n == slow {
+			t.Fatalf("expected the fast node picked, but got the slow one at %d", i)
+		}
+		done(context.Background(), selector.DoneInfo{Latency: 10 * time.Millisecond})
+	}
+	s.Apply(nodes[:1])
+	if n, _, _ := s.Select(context.Background()); n != nodes[0] {
+		t.Errorf("expected the only node picked, but got %v", n)
+	}
+}
+
+func TestDegradedNode(t *testing.T) {
+	c := &clock{now: time.Now()}
+	s := newSelector(c.Now)
+	nodes := selectortest.Nodes("n", 3)
+	s.Apply(nodes)
+	degraded := nodes[2]
+	run := func(n int, slow time.Duration) map[selector.Node]int {
+		picks := make(map[selector.Node]int)
+		for i := 0; i < n; i++ {
+			node, done, _ := s.Select(context.Background())
+			picks[node]++
+			c.now = c.now.Add(5 * time.Millisecond)
+			latency := 10 * time.Millisecond
+			if node == degraded {
+				latency = slow
+			}
+			done(context.Background(), selector.DoneInfo{Latency: latency})
+		}
+		return picks
+	}
+	if picks := run(3000, 10*time.Millisecond); picks[degraded] < 700 {
+		t.Errorf("expected the healthy nodes picked evenly, but got %v", picks)
+	}
+	// the latency of the node degrades mid-run.
+	run(500, 200*time.Millisecond)
+	if picks := run(3000, 200*time.Millisecond); picks[degraded] > 150 {
+		t.Errorf("expected the degraded node routed around, but got %d of 3000 picks", picks[degraded])
+	}
+	// the added node is not flooded before its first observations.
+	added := selectortest.Nodes("added", 1)[0]
+	s.Apply(append(nodes, added))
+	if picks := run(100, 200*time.Millisecond); picks[added] > 50 {
+		t.Errorf("expected the added node warmed up, but got %d of 100 picks", picks[added])
+	}
+}
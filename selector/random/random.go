@@ -0,0 +1,92 @@
+// Package random implements the selector of the weighted random, which is cheap and good
+// enough once the nodes are alike.
+package random
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// nodes is the nodes with the cumulative weights.
+type nodes struct {
+	nodes []selector.Node
+	sums  []int64
+}
+
+// Selector is the selector of the weighted random.
+type Selector struct {
+	nodes atomic.Value
+}
+
+// New returns a selector of the weighted random.
+func New() selector.Selector {
+	s := &Selector{}
+	s.nodes.Store(&nodes{})
+	return s
+}
+
+// NewBuilder returns the builder of the selectors of the weighted random.
+func NewBuilder() selector.Builder {
+	return selector.BuilderFunc(New)
+}
+
+// Apply replaces the nodes.
+func (s *Selector) Apply(ns []selector.Node) {
+	sums := make([]int64, len(ns))
+	var sum int64
+	for i, n := range ns {
+		sum += weight(n)
+		sums[i] = sum
+	}
+	s.nodes.Store(&nodes{nodes: ns, sums: sums})
+}
+
+// Select returns a random node by the weights, the done func reports nothing.
+func (s *Selector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	ns := s.nodes.Load().(*nodes)
+	if len(opts) > 0 {
+		n, ok := Pick(selector.Filter(ctx, ns.nodes, opts...))
+		if !ok {
+			return nil, nil, selector.ErrNoAvailable
+		}
+		return n, done, nil
+	}
+	if len(ns.nodes) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	r := rand.Int63n(ns.sums[len(ns.sums)-1])
+	i := sort.Search(len(ns.sums), func(i int) bool { return ns.sums[i] > r })
+	return ns.nodes[i], done, nil
+}
+
+// Pick returns a random node of the nodes by the weights, false if none.
+func Pick(ns []selector.Node) (selector.Node, bool) {
+	if len(ns) == 0 {
+		return nil, false
+	}
+	var sum int64
+	for _, n := range ns {
+		sum += weight(n)
+	}
+	r := rand.Int63n(sum)
+	for _, n := range ns {
+		if r -= weight(n); r < 0 {
+			return n, true
+		}
+	}
+	return ns[len(ns)-1], true
+}
+
+// weight returns the weight of the node, the invalid weights count as one.
+func weight(n selector.Node) int64 {
+	if w := n.Weight(); w > 0 {
+		return int64(w)
+	}
+	return 1
+}
+
+func done(context.Context, selector.DoneInfo) {}
@@ -0,0 +1,34 @@
+package random
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/selectortest"
+)
+
+func TestConformance(t *testing.T) {
+	selectortest.Run(t, NewBuilder())
+}
+
+func TestWeight(t *testing.T) {
+	heavy := selector.NewNode("http", "heavy", &registry.ServiceInstance{Metadata: map[string]string{registry.WeightKey: "300"}})
+	light := selector.NewNode("http", "light", nil)
+	s := New()
+	s.Apply([]selector.Node{heavy, light})
+	only := selector.WithFilter(func(_ context.Context, nodes []selector.Node) []selector.Node { return nodes[1:] })
+	picks := make(map[selector.Node]int)
+	for i := 0; i < 4000; i++ {
+		n, _, _ := s.Select(context.Background())
+		picks[n]++
+		if n, _, _ := s.Select(context.Background(), only); n != light {
+			t.Fatalf("expected the filtered node picked, but got %v", n.Address())
+		}
+	}
+	// 3000 of the heavy node expected.
+	if picks[heavy] < 2700 || picks[heavy] > 3300 {
+		t.Errorf("expected the picks by the weights, but got %d of 4000 heavy ones", picks[heavy])
+	}
+}
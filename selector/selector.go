@@ -0,0 +1,83 @@
+// Package selector defines the client side load balancing shared by the HTTP and gRPC
+// clients: the discovery applies the nodes of a service to a Selector, which selects a
+// node per request among the nodes narrowed by the NodeFilters, and the result of the
+// request is fed back via the DoneFunc, so that a balancer written once works for both
+// transports.
+package selector
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ErrNoAvailable is returned by Select once there is no node, or the filters drop all of them.
+var ErrNoAvailable = errors.ServiceUnavailable("NODE_NOT_AVAILABLE", "no node available")
+
+// Selector selects a node among the nodes applied.
+type Selector interface {
+	// Select returns a node with the func reporting the result of the request, which must
+	// be called once the request is done, or ErrNoAvailable if none.
+	Select(ctx context.Context, opts ...SelectOption) (Node, DoneFunc, error)
+	// Apply replaces the nodes, which is safe to call concurrently with Select.
+	Apply(nodes []Node)
+}
+
+// Builder builds the selectors, one per service.
+type Builder interface {
+	Build() Selector
+}
+
+// BuilderFunc is an adapter to allow the use of an ordinary function as a Builder.
+type BuilderFunc func() Selector
+
+// Build calls f().
+func (f BuilderFunc) Build() Selector {
+	return f()
+}
+
+// DoneInfo is the result of a request to the selected node.
+type DoneInfo struct {
+	// Err is the error of the request, nil if succeeded.
+	Err error
+	// BytesSent reports whether any bytes were sent to the node.
+	BytesSent bool
+	// BytesReceived reports whether any bytes were received from the node.
+	BytesReceived bool
+	// Latency is the duration since the node was selected.
+	Latency time.Duration
+}
+
+// DoneFunc reports the result of the request to the selected node.
+type DoneFunc func(ctx context.Context, di DoneInfo)
+
+// NodeFilter narrows the nodes before the selection, it must not modify the nodes.
+type NodeFilter func(ctx context.Context, nodes []Node) []Node
+
+// SelectOptions is the options of a selection.
+type SelectOptions struct {
+	Filters []NodeFilter
+}
+
+// SelectOption is a selection option.
+type SelectOption func(*SelectOptions)
+
+// WithFilter with the filters applied in order before the selection.
+func WithFilter(filters ...NodeFilter) SelectOption {
+	return func(o *SelectOptions) {
+		o.Filters = append(o.Filters, filters...)
+	}
+}
+
+// Filter returns the nodes narrowed by the filters of the options in order.
+func Filter(ctx context.Context, nodes []Node, opts ...SelectOption) []Node {
+	var options SelectOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	for _, f := range options.Filters {
+		nodes = f(ctx, nodes)
+	}
+	return nodes
+}
@@ -0,0 +1,80 @@
+// Package selectortest implements the conformance tests of the selectors.
+package selectortest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// Run runs the conformance tests of the selectors of the builder, which are meant to run
+// with the race detector:
+//
+//   - Select fails with ErrNoAvailable without nodes, or once the filters drop all of them.
+//   - Select returns only the nodes applied, with a non-nil DoneFunc.
+//   - Apply is safe under the concurrent Select.
+func Run(t *testing.T, b selector.Builder) {
+	ctx := context.Background()
+	s := b.Build()
+	if _, _, err := s.Select(ctx); err != selector.ErrNoAvailable {
+		t.Errorf("expected ErrNoAvailable without nodes, but got %v", err)
+	}
+	nodes := Nodes("a", 3)
+	s.Apply(nodes)
+	applied := make(map[selector.Node]bool)
+	for _, n := range nodes {
+		applied[n] = true
+	}
+	for i := 0; i < 100; i++ {
+		n, done, err := s.Select(ctx)
+		if err != nil {
+			t.Fatalf("expected a node selected, but got %v", err)
+		}
+		if !applied[n] || done == nil {
+			t.Fatalf("expected an applied node with the done func, but got %v", n)
+		}
+		done(ctx, selector.DoneInfo{})
+	}
+	drop := selector.WithFilter(func(context.Context, []selector.Node) []selector.Node { return nil })
+	if _, _, err := s.Select(ctx, drop); err != selector.ErrNoAvailable {
+		t.Errorf("expected ErrNoAvailable once the filters drop all, but got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if n, done, err := s.Select(ctx); err == nil {
+					done(ctx, selector.DoneInfo{})
+					_ = n.Address()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		s.Apply(Nodes(strconv.Itoa(i), i%5))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// Nodes returns n nodes of the default weight, whose addresses have the prefix.
+func Nodes(prefix string, n int) []selector.Node {
+	nodes := make([]selector.Node, n)
+	for i := range nodes {
+		nodes[i] = selector.NewNode("http", prefix+strconv.Itoa(i), &registry.ServiceInstance{ID: prefix + strconv.Itoa(i)})
+	}
+	return nodes
+}
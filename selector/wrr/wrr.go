@@ -0,0 +1,238 @@
+// Package wrr implements the selector of the smooth weighted round robin with the zone
+// affinity, the weights and the zones are read from the metadata of the instances, and
+// the weight is registry.DefaultWeight if missing.
+package wrr
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// wrr is the smooth weighted round robin of nginx, which spreads the picks of a heavy
+// node rather than bursting them.
+type wrr struct {
+	weights []int
+	current []int
+	total   int
+}
+
+// newWRR returns the round robin of the weights, starting from the current weights if any.
+func newWRR(weights, current []int) *wrr {
+	w := &wrr{weights: weights, current: current}
+	if w.current == nil {
+		w.current = make([]int, len(weights))
+	}
+	for _, weight := range weights {
+		w.total += weight
+	}
+	return w
+}
+
+func (w *wrr) next() int {
+	best := 0
+	for i, weight := range w.weights {
+		w.current[i] += weight
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= w.total
+	return best
+}
+
+// nextOf returns the next of the indices, the others keep their current weights, so that
+// the picks among the same indices are as smooth as next.
+func (w *wrr) nextOf(indices []int) int {
+	best, total := indices[0], 0
+	for _, i := range indices {
+		w.current[i] += w.weights[i]
+		total += w.weights[i]
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= total
+	return best
+}
+
+func key(n selector.Node) string {
+	return n.Scheme() + "://" + n.Address()
+}
+
+// group is the nodes picked by their weights.
+type group struct {
+	nodes []selector.Node
+	wrr   *wrr
+}
+
+// newGroup returns the group of the nodes, the nodes kept since the former group keep their
+// current weights, so that the fairness survives a slight change of the nodes.
+func newGroup(nodes []selector.Node, former *group) *group {
+	var currents map[string]int
+	if former != nil {
+		currents = make(map[string]int, len(former.nodes))
+		for i, n := range former.nodes {
+			currents[key(n)] = former.wrr.current[i]
+		}
+	}
+	weights, current := make([]int, len(nodes)), make([]int, len(nodes))
+	for i, n := range nodes {
+		weights[i], current[i] = n.Weight(), currents[key(n)]
+	}
+	return &group{nodes: nodes, wrr: newWRR(weights, current)}
+}
+
+// spillScale is the total weight of the local and the spilled picks.
+const spillScale = 1000
+
+// picker picks the nodes by the weighted round robin, preferring the nodes in the local zone.
+//
+// The callers are considered evenly spread over the zones of the nodes, so the local zone
+// serves all of its callers if it has at least its fair share of the capacity, 1/zones of
+// the total weight. Otherwise the local zone serves the fraction of the callers matching
+// its capacity, and the rest spill over to the other zones, i.e., with the zones a of the
+// weight 100 and b of the weight 300, the callers in a pick a for 50% and b for 50%.
+//
+// The nodes narrowed by the filters are picked among all the nodes regardless of the zones.
+type picker struct {
+	mu     sync.Mutex
+	local  *group
+	remote *group
+	spill  *wrr
+	all    *group
+	index  map[selector.Node]int
+}
+
+// newPicker returns the picker of the nodes, the nodes of the local zone are preferred
+// if the zone is not empty. The picks continue from the former picker if any.
+func newPicker(zone string, nodes []selector.Node, former *picker) *picker {
+	var (
+		local, remote []selector.Node
+		localWeight   int
+		totalWeight   int
+		zones         = make(map[string]struct{})
+	)
+	for _, n := range nodes {
+		zones[n.Zone()] = struct{}{}
+		totalWeight += n.Weight()
+		if zone != "" && n.Zone() == zone {
+			local = append(local, n)
+			localWeight += n.Weight()
+		} else {
+			remote = append(remote, n)
+		}
+	}
+	p := &picker{index: make(map[selector.Node]int, len(nodes))}
+	for i, n := range nodes {
+		p.index[n] = i
+	}
+	var (
+		fAll, fLocal, fRemote *group
+		spillCurrent          []int
+	)
+	if former != nil {
+		former.mu.Lock()
+		defer former.mu.Unlock()
+		fAll, fLocal, fRemote = former.all, former.local, former.remote
+		if former.spill != nil {
+			spillCurrent = former.spill.current
+		}
+	}
+	p.all = newGroup(nodes, fAll)
+	switch {
+	case len(local) == 0:
+		p.remote = newGroup(remote, fRemote)
+	case len(remote) == 0 || localWeight*len(zones) >= totalWeight:
+		p.local = newGroup(local, fLocal)
+	default:
+		ratio := spillScale * localWeight * len(zones) / totalWeight
+		p.local, p.remote = newGroup(local, fLocal), newGroup(remote, fRemote)
+		p.spill = newWRR([]int{ratio, spillScale - ratio}, spillCurrent)
+	}
+	return p
+}
+
+// pick returns the next node, false if there are no nodes.
+func (p *picker) pick() (selector.Node, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	g := p.local
+	if g == nil || p.spill != nil && p.spill.next() == 1 {
+		g = p.remote
+	}
+	if len(g.nodes) == 0 {
+		return nil, false
+	}
+	return g.nodes[g.wrr.next()], true
+}
+
+// pickOf returns the next of the nodes, false if none of them are known.
+func (p *picker) pickOf(nodes []selector.Node) (selector.Node, bool) {
+	indices := make([]int, 0, len(nodes))
+	for _, n := range nodes {
+		if i, ok := p.index[n]; ok {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.all.nodes[p.all.wrr.nextOf(indices)], true
+}
+
+// Selector is the selector of the weighted round robin. Select only locks the picker for
+// advancing the current weights, and Apply swaps the picker without blocking Select.
+type Selector struct {
+	zone   string
+	mu     sync.Mutex
+	picker atomic.Value
+}
+
+// New returns a selector of the weighted round robin, preferring the nodes in the local
+// zone, see registry.LocalZone.
+func New() selector.Selector {
+	return newSelector(registry.LocalZone())
+}
+
+// NewBuilder returns the builder of the selectors of the weighted round robin.
+func NewBuilder() selector.Builder {
+	return selector.BuilderFunc(New)
+}
+
+func newSelector(zone string) *Selector {
+	s := &Selector{zone: zone}
+	s.picker.Store(newPicker(zone, nil, nil))
+	return s
+}
+
+// Apply replaces the nodes, the nodes kept keep their current weights.
+func (s *Selector) Apply(nodes []selector.Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.picker.Store(newPicker(s.zone, nodes, s.picker.Load().(*picker)))
+}
+
+// Select returns the next node, the nodes narrowed by the filters are picked in turn as
+// well. The done func reports nothing to the round robin, but it must be called as well.
+func (s *Selector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	p := s.picker.Load().(*picker)
+	pick := p.pick
+	if len(opts) > 0 {
+		if nodes := selector.Filter(ctx, p.all.nodes, opts...); len(nodes) != len(p.all.nodes) {
+			pick = func() (selector.Node, bool) { return p.pickOf(nodes) }
+		}
+	}
+	n, ok := pick()
+	if !ok {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	return n, done, nil
+}
+
+func done(context.Context, selector.DoneInfo) {}
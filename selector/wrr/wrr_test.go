@@ -0,0 +1,161 @@
+package wrr
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/selectortest"
+)
+
+func newNode(addr string, weight int, zone string) selector.Node {
+	return selector.NewNode("http", addr, &registry.ServiceInstance{Metadata: map[string]string{
+		registry.WeightKey: strconv.Itoa(weight),
+		registry.ZoneKey:   zone,
+	}})
+}
+
+func picks(s selector.Selector, n int) map[string]int {
+	res := make(map[string]int)
+	for i := 0; i < n; i++ {
+		node, done, err := s.Select(context.Background())
+		if err != nil {
+			return nil
+		}
+		done(context.Background(), selector.DoneInfo{})
+		res[node.Address()]++
+	}
+	return res
+}
+
+func TestConformance(t *testing.T) {
+	selectortest.Run(t, NewBuilder())
+}
+
+func TestWeight(t *testing.T) {
+	s := New()
+	s.Apply([]selector.Node{newNode("a", 200, ""), newNode("b", 100, ""), newNode("c", 100, "")})
+	if got := picks(s, 400); got["a"] != 200 || got["b"] != 100 || got["c"] != 100 {
+		t.Errorf("unexpected picks %v", got)
+	}
+	// the heavy node is spread rather than bursting.
+	var seq string
+	for i := 0; i < 4; i++ {
+		n, _, _ := s.Select(context.Background())
+		seq += n.Address()
+	}
+	if seq != "abca" {
+		t.Errorf("expected the smooth picks, but got %s", seq)
+	}
+}
+
+func TestZone(t *testing.T) {
+	tests := []struct {
+		zone  string
+		nodes []selector.Node
+		want  map[string]int
+	}{
+		// the local zone has its fair share of the capacity.
+		{"a", []selector.Node{newNode("a1", 100, "a"), newNode("a2", 100, "a"), newNode("b1", 100, "b"), newNode("b2", 100, "b")}, map[string]int{"a1": 500, "a2": 500}},
+		// the local zone has 25% of the capacity, half of its callers spill over.
+		{"a", []selector.Node{newNode("a1", 100, "a"), newNode("b1", 100, "b"), newNode("b2", 200, "b")}, map[string]int{"a1": 500, "b1": 167, "b2": 333}},
+		// the local zone has 20% of the capacity among the 3 zones, 60% are served locally.
+		{"a", []selector.Node{newNode("a1", 100, "a"), newNode("b1", 200, "b"), newNode("c1", 200, "c")}, map[string]int{"a1": 600, "b1": 200, "c1": 200}},
+		// no local nodes.
+		{"c", []selector.Node{newNode("a1", 100, "a"), newNode("b1", 100, "b")}, map[string]int{"a1": 500, "b1": 500}},
+		// no zone preferred.
+		{"", []selector.Node{newNode("a1", 100, "a"), newNode("b1", 100, "b")}, map[string]int{"a1": 500, "b1": 500}},
+	}
+	for i, test := range tests {
+		s := newSelector(test.zone)
+		s.Apply(test.nodes)
+		got := picks(s, 1000)
+		for k, v := range test.want {
+			if d := got[k] - v; d < -1 || d > 1 {
+				t.Errorf("%d: expected %v, but got %v", i, test.want, got)
+				break
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%d: expected %v, but got %v", i, test.want, got)
+		}
+	}
+}
+
+func seq(s selector.Selector, n int, opts ...selector.SelectOption) string {
+	var res string
+	for i := 0; i < n; i++ {
+		node, _, err := s.Select(context.Background(), opts...)
+		if err != nil {
+			return res
+		}
+		res += node.Address()
+	}
+	return res
+}
+
+func TestApply(t *testing.T) {
+	nodes := func() []selector.Node {
+		return []selector.Node{newNode("a", 200, ""), newNode("b", 100, ""), newNode("c", 100, "")}
+	}
+	s := New()
+	s.Apply(nodes())
+	want := seq(s, 8)
+
+	// the fairness survives the nodes applied again.
+	s = New()
+	s.Apply(nodes())
+	got := seq(s, 3)
+	s.Apply(nodes())
+	if got += seq(s, 5); got != want {
+		t.Errorf("expected %s, but got %s", want, got)
+	}
+	// the nodes kept continue the round robin once a node is added.
+	s.Apply(append(nodes(), newNode("d", 100, "")))
+	if got := picks(s, 500); got["a"] != 200 || got["b"] != 100 || got["c"] != 100 || got["d"] != 100 {
+		t.Errorf("unexpected picks %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := New()
+	s.Apply([]selector.Node{newNode("a", 200, ""), newNode("b", 100, ""), newNode("c", 100, "")})
+	noC := selector.WithFilter(func(ctx context.Context, nodes []selector.Node) []selector.Node {
+		var res []selector.Node
+		for _, n := range nodes {
+			if n.Address() != "c" {
+				res = append(res, n)
+			}
+		}
+		return res
+	})
+	// the narrowed nodes are picked smoothly by their weights.
+	if got := seq(s, 6, noC); got != "abaaba" {
+		t.Errorf("expected the smooth picks of the narrowed nodes, but got %s", got)
+	}
+}
+
+// BenchmarkSelect selects by 64 goroutines with 10k selections each.
+func BenchmarkSelect(b *testing.B) {
+	s := New()
+	s.Apply(selectortest.Nodes("n", 20))
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for g := 0; g < 64; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10000; j++ {
+					_, done, _ := s.Select(ctx)
+					done(ctx, selector.DoneInfo{})
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,313 @@
+package kratos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// server is a server of the app, which is named by its stage, index and type in the errors.
+type server struct {
+	transport.Server
+	name  string
+	level int
+	// exited is closed once Start returns, nil if not started.
+	exited chan struct{}
+	// pending reports whether the server is of a reload not committed yet, whose failure
+	// aborts the reload rather than stopping the app, see Reload. It is guarded by the mu
+	// of the app, as err.
+	pending bool
+	err     error
+}
+
+// reloadSettle is the duration the new servers of a reload run before they are registered,
+// so that the servers failing at once abort the reload.
+var reloadSettle = 100 * time.Millisecond
+
+// listen wraps the servers of the stage in the level of the start order, and listens the
+// servers implementing Endpointer, it returns the servers listening with the error of the
+// first one failing.
+func (a *App) listen(st *stage, level int) ([]*server, []string, error) {
+	var (
+		servers   = make([]*server, 0, len(st.servers))
+		endpoints []string
+		prefix    string
+	)
+	if st.name != DefaultStage {
+		prefix = st.name
+	}
+	for i, srv := range st.servers {
+		s := &server{Server: srv, name: fmt.Sprintf("%s#%d %T", prefix, i, srv), level: level}
+		if e, ok := srv.(transport.Endpointer); ok {
+			endpoint, err := e.Endpoint()
+			if err != nil {
+				return servers, nil, fmt.Errorf("failed to start server %s: %w", s.name, err)
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		servers = append(servers, s)
+	}
+	return servers, endpoints, nil
+}
+
+// start starts the servers concurrently, the first failure stops the app unless the server
+// is pending.
+func (a *App) start(servers []*server) {
+	a.mu.Lock()
+	ctx := a.srvCtx
+	a.mu.Unlock()
+	for _, s := range servers {
+		s.exited = make(chan struct{})
+		go func(s *server) {
+			defer close(s.exited)
+			if err := a.protect(func() error { return s.Start(ctx) }); err != nil && !errors.Is(err, context.Canceled) {
+				err = fmt.Errorf("failed to start server %s: %w", s.name, err)
+				a.mu.Lock()
+				s.err = err
+				pending := s.pending
+				if !pending {
+					a.startErrs = append(a.startErrs, err)
+				}
+				a.mu.Unlock()
+				if !pending {
+					a.fail.Do(func() { close(a.failed) })
+				}
+			}
+		}(s)
+	}
+}
+
+// PanicError is the error of a panic recovered from the Start or the Stop of a server.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// protect calls fn, and recovers its panic into a PanicError unless CrashOnPanic.
+func (a *App) protect(fn func() error) (err error) {
+	if a.opts.crashOnPanic {
+		return fn()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			stack := make([]byte, 64<<10)
+			stack = stack[:runtime.Stack(stack, false)]
+			a.log.Errorf("Recovered the panic of a server: %v\n%s", v, stack)
+			err = &PanicError{Value: v, Stack: stack}
+		}
+	}()
+	return fn()
+}
+
+// stopServers stops the servers in the reverse order of the stages, the servers of a level
+// concurrently, and returns the errors of the servers stopped until ctx is done, with
+// ErrStopTimeout listing the servers not finished.
+func (a *App) stopServers(ctx context.Context, servers []*server) []error {
+	var (
+		errs    []error
+		pending []string
+		top     int
+	)
+	for _, s := range servers {
+		if s.level > top {
+			top = s.level
+		}
+	}
+	for level := top; level >= 0; level-- {
+		var group []*server
+		for _, s := range servers {
+			if s.level == level {
+				group = append(group, s)
+			}
+		}
+		e, p := a.stopLevel(ctx, group)
+		errs, pending = append(errs, e...), append(pending, p...)
+	}
+	if len(pending) > 0 {
+		errs = append(errs, fmt.Errorf("%w: servers not stopped within %s: %s", ErrStopTimeout, a.opts.stopTimeout, strings.Join(pending, ", ")))
+	}
+	return errs
+}
+
+// stopLevel stops the servers concurrently, and returns the errors of the servers stopped
+// until ctx is done, and the names of the servers not finished. A server is finished once
+// its Stop returns, and its Start returns if started.
+func (a *App) stopLevel(ctx context.Context, servers []*server) ([]error, []string) {
+	var (
+		mu       sync.Mutex
+		errs     []error
+		finished = make(map[*server]bool, len(servers))
+		wg       sync.WaitGroup
+	)
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *server) {
+			defer wg.Done()
+			if err := a.protect(func() error { return s.Stop(ctx) }); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to stop server %s: %w", s.name, err))
+				mu.Unlock()
+			}
+			if s.exited != nil {
+				select {
+				case <-s.exited:
+				case <-ctx.Done():
+					return
+				}
+			}
+			mu.Lock()
+			finished[s] = true
+			mu.Unlock()
+		}(s)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var pending []string
+	for _, s := range servers {
+		if !finished[s] {
+			pending = append(pending, s.name)
+		}
+	}
+	return append([]error(nil), errs...), pending
+}
+
+// Reload swaps the servers for the ones of the rebuild function, see Rebuild, i.e., to
+// rotate the certificates or to change the addresses: the new servers listen and start,
+// the instance is registered with their endpoints, then the old servers are stopped
+// gracefully within the stop timeout. The new servers implementing transport.Inheritor
+// inherit the listeners of the old ones, so that a server of the same address takes over
+// the bound socket. Once a new server fails to listen or to start, or the registration
+// fails, the reload is aborted, the new servers are stopped and the old ones keep serving
+// untouched, with the old instance registered back if the new one was. The errors of stopping the old servers are returned after the swap. A new
+// server failing after the swap stops the app as the others.
+func (a *App) Reload(ctx context.Context) error {
+	if a.opts.rebuild == nil {
+		return errors.New("no rebuild function to reload the servers")
+	}
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	if state := a.State(); state != StateRunning {
+		return fmt.Errorf("failed to reload the servers of the app %s", strings.ToLower(state.String()))
+	}
+	srvs, err := a.opts.rebuild(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild the servers: %w", err)
+	}
+	a.log.Infof("Reloading %d servers", len(srvs))
+	a.mu.Lock()
+	var listeners []net.Listener
+	for _, s := range a.servers {
+		if i, ok := s.Server.(transport.Inheritor); ok && i.Listener() != nil {
+			listeners = append(listeners, i.Listener())
+		}
+	}
+	a.mu.Unlock()
+	for _, srv := range srvs {
+		if i, ok := srv.(transport.Inheritor); ok {
+			i.Inherit(listeners)
+		}
+	}
+	servers, endpoints, err := a.listen(&stage{name: DefaultStage, servers: srvs}, 0)
+	if err != nil {
+		return a.rollback(err, servers)
+	}
+	for _, s := range servers {
+		s.pending = true
+	}
+	a.start(servers)
+	settle := time.NewTimer(reloadSettle)
+	defer settle.Stop()
+wait:
+	for _, s := range servers {
+		select {
+		case <-s.exited:
+		case <-settle.C:
+			break wait
+		}
+	}
+	if err := a.reloadErr(servers); err != nil {
+		return a.rollback(err, servers)
+	}
+	ins := a.buildInstance(srvs)
+	if a.opts.registrar != nil {
+		regCtx, cancel := context.WithTimeout(ctx, a.opts.registerTimeout)
+		err := a.opts.registrar.Register(regCtx, ins)
+		cancel()
+		if err != nil {
+			return a.rollback(fmt.Errorf("failed to register the reloaded servers: %w", err), servers)
+		}
+	}
+	a.mu.Lock()
+	for _, s := range servers {
+		if s.err != nil {
+			restore := a.instance
+			a.mu.Unlock()
+			err := s.err
+			if a.opts.registrar != nil {
+				err = a.restore(ctx, err, restore)
+			}
+			return a.rollback(err, servers)
+		}
+		s.pending = false
+	}
+	old := a.servers
+	a.instance, a.endpoints, a.servers = ins, endpoints, servers
+	a.mu.Unlock()
+	stopCtx, cancel := context.WithTimeout(valueContext{ctx}, a.opts.stopTimeout)
+	defer cancel()
+	return multiError(a.stopServers(stopCtx, old)).err()
+}
+
+// reloadErr returns the first error of the new servers failing to start.
+func (a *App) reloadErr(servers []*server) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, s := range servers {
+		if s.err != nil {
+			return s.err
+		}
+	}
+	return nil
+}
+
+// restore registers the old instance back once a new server fails after the registration
+// of the reload, so that the registry is not left with the endpoints of the new servers.
+func (a *App) restore(ctx context.Context, err error, ins *registry.ServiceInstance) error {
+	regCtx, cancel := context.WithTimeout(ctx, a.opts.registerTimeout)
+	defer cancel()
+	if rerr := a.opts.registrar.Register(regCtx, ins); rerr != nil {
+		return multiError{err, fmt.Errorf("failed to register the servers back: %w", rerr)}
+	}
+	return err
+}
+
+// rollback stops the new servers of an aborted reload.
+func (a *App) rollback(err error, servers []*server) error {
+	a.log.Errorf("Failed to reload the servers: %v", err)
+	ctx, cancel := context.WithTimeout(valueContext{a.ctx}, a.opts.stopTimeout)
+	defer cancel()
+	return multiError(append([]error{err}, a.stopServers(ctx, servers)...)).err()
+}
@@ -0,0 +1,168 @@
+package kratos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// DefaultStage is the name of the stage of the servers of Server, which depends on none.
+const DefaultStage = "default"
+
+// stageReadyInterval is the interval of polling the readiness checks of the stages.
+var stageReadyInterval = 100 * time.Millisecond
+
+// stage is a named group of servers, which start once the stages they depend on are ready.
+type stage struct {
+	name    string
+	servers []transport.Server
+	after   []string
+	ready   func(context.Context) error
+	// needed reports whether any stage depends on the stage, so that Run waits for it.
+	needed bool
+}
+
+// StageOption is a stage option.
+type StageOption func(s *stage)
+
+// After with the names of the stages which are ready before the stage starts.
+func After(names ...string) StageOption {
+	return func(s *stage) { s.after = append(s.after, names...) }
+}
+
+// ReadyCheck with the readiness check of the stage, which is polled until it passes before
+// the stages depending on it start. Otherwise the stage is ready once the Starts of all its
+// servers return, i.e., a loader warming the cache, so that the stages of the servers
+// serving until stopped need a readiness check to be depended on.
+func ReadyCheck(fn func(context.Context) error) StageOption {
+	return func(s *stage) { s.ready = fn }
+}
+
+// Stage with the named stage of servers, which start once the stages they depend on are
+// ready, see After, otherwise along with DefaultStage, the stage of the servers of Server.
+// The servers listen once their stage starts, and the stages stop in the reverse order.
+func Stage(name string, srvs []transport.Server, opts ...StageOption) Option {
+	return func(o *options) {
+		s := &stage{name: name, servers: srvs}
+		for _, opt := range opts {
+			opt(s)
+		}
+		o.stages = append(o.stages, s)
+	}
+}
+
+// allServers returns the servers of all the stages.
+func (o *options) allServers() []transport.Server {
+	srvs := append([]transport.Server(nil), o.servers...)
+	for _, s := range o.stages {
+		srvs = append(srvs, s.servers...)
+	}
+	return srvs
+}
+
+// order returns the stages in the start order, the stages of a level depend on the stages
+// of the former levels only. It fails on the duplicate names, the unknown names and the
+// cycles of the dependencies.
+func (o *options) order() ([][]*stage, error) {
+	stages := append([]*stage{{name: DefaultStage, servers: o.servers}}, o.stages...)
+	byName := make(map[string]*stage, len(stages))
+	for _, s := range stages {
+		if _, ok := byName[s.name]; ok {
+			return nil, fmt.Errorf("duplicate stage %q", s.name)
+		}
+		byName[s.name] = s
+	}
+	for _, s := range stages {
+		for _, name := range s.after {
+			dep, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("stage %q starts after the unknown stage %q", s.name, name)
+			}
+			dep.needed = true
+		}
+	}
+	levels := make(map[*stage]int, len(stages))
+	var (
+		visiting = make(map[*stage]bool)
+		visit    func(s *stage, path []string) (int, error)
+	)
+	visit = func(s *stage, path []string) (int, error) {
+		if level, ok := levels[s]; ok {
+			return level, nil
+		}
+		if visiting[s] {
+			for i, name := range path {
+				if name == s.name {
+					path = path[i:]
+					break
+				}
+			}
+			return 0, fmt.Errorf("stages in a cycle: %s -> %s", strings.Join(path, " -> "), s.name)
+		}
+		path = append(path, s.name)
+		visiting[s] = true
+		level := 0
+		for _, name := range s.after {
+			dep, err := visit(byName[name], path)
+			if err != nil {
+				return 0, err
+			}
+			if dep+1 > level {
+				level = dep + 1
+			}
+		}
+		levels[s] = level
+		return level, nil
+	}
+	var res [][]*stage
+	for _, s := range stages {
+		level, err := visit(s, nil)
+		if err != nil {
+			return nil, err
+		}
+		for len(res) <= level {
+			res = append(res, nil)
+		}
+	}
+	// the stages of a level are in the declaration order.
+	for _, s := range stages {
+		res[levels[s]] = append(res[levels[s]], s)
+	}
+	return res, nil
+}
+
+// await waits for the needed stages until they are ready, it returns false once the app
+// stops or a server fails meanwhile.
+func (a *App) await(stages []*stage, servers map[*stage][]*server) bool {
+	for _, st := range stages {
+		if !st.needed {
+			continue
+		}
+		a.log.Infof("Waiting for the stage %s to be ready", st.name)
+		if st.ready == nil {
+			for _, s := range servers[st] {
+				select {
+				case <-s.exited:
+				case <-a.failed:
+					return false
+				case <-a.ctx.Done():
+					return false
+				}
+			}
+			continue
+		}
+		for st.ready(a.ctx) != nil {
+			select {
+			case <-time.After(stageReadyInterval):
+			case <-a.failed:
+				return false
+			case <-a.ctx.Done():
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package kratos
+
+// State is the runtime state of the app.
+type State int32
+
+const (
+	// StateNew is the state before Run.
+	StateNew State = iota
+	// StateStarting is the state of Run starting the servers and registering the instance.
+	StateStarting
+	// StateRunning is the state once the servers are started and the instance is registered.
+	StateRunning
+	// StateStopping is the state once Stop begins, before the deregistration.
+	StateStopping
+	// StateStopped is the state once Run returns.
+	StateStopped
+)
+
+// Ready reports whether the app serves the traffic, which the readiness probes bind to,
+// so that the app turns not ready once it begins stopping.
+func (s State) Ready() bool {
+	return s == StateRunning
+}
+
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "NEW"
+	case StateStarting:
+		return "STARTING"
+	case StateRunning:
+		return "RUNNING"
+	case StateStopping:
+		return "STOPPING"
+	case StateStopped:
+		return "STOPPED"
+	}
+	return ""
+}
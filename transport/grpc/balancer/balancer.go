@@ -0,0 +1,99 @@
+// Package balancer adapts the selectors to the gRPC balancers, so that the gRPC clients
+// select the ready sub connections by the selectors shared with the HTTP clients.
+package balancer
+
+import (
+	"time"
+
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
+
+	gbalancer "google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewBuilder returns the builder of the gRPC balancer of the name, whose pickers select the
+// ready sub connections by the selectors of b, the nodes are read from the service instances
+// resolved by the discovery, see discovery.Instance. A balancer, i.e., of a client connection,
+// keeps a single selector, which the ready sub connections are applied to, so that the state
+// of the selector, i.e., the load statistics, outlives the changes of the sub connections.
+func NewBuilder(name string, b selector.Builder) gbalancer.Builder {
+	return &builder{name: name, builder: b}
+}
+
+type builder struct {
+	name    string
+	builder selector.Builder
+}
+
+func (b *builder) Build(cc gbalancer.ClientConn, opts gbalancer.BuildOptions) gbalancer.Balancer {
+	pb := &pickerBuilder{selector: b.builder.Build()}
+	return base.NewBalancerBuilder(b.name, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+func (b *builder) Name() string {
+	return b.name
+}
+
+// Register registers the gRPC balancer of the name, which is used by the service config,
+// i.e., {"loadBalancingPolicy":"wrr"}.
+func Register(name string, b selector.Builder) {
+	gbalancer.Register(NewBuilder(name, b))
+}
+
+type pickerBuilder struct {
+	selector selector.Selector
+}
+
+// subConnNode is the node of a ready sub connection.
+type subConnNode struct {
+	selector.Node
+	sc gbalancer.SubConn
+}
+
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) gbalancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(gbalancer.ErrNoSubConnAvailable)
+	}
+	nodes := make([]selector.Node, 0, len(info.ReadySCs))
+	for sc, sci := range info.ReadySCs {
+		in, _ := discovery.Instance(sci.Address)
+		nodes = append(nodes, &subConnNode{Node: selector.NewNode("grpc", sci.Address.Addr, in), sc: sc})
+	}
+	b.selector.Apply(nodes)
+	return &picker{selector: b.selector}
+}
+
+type picker struct {
+	selector selector.Selector
+}
+
+func (p *picker) Pick(info gbalancer.PickInfo) (gbalancer.PickResult, error) {
+	var opts []selector.SelectOption
+	if info.Ctx != nil {
+		// the filters of the client options and the call, see transport/grpc.WithFilter.
+		if filters := selector.FiltersFromContext(info.Ctx); len(filters) > 0 {
+			opts = append(opts, selector.WithFilter(filters...))
+		}
+	}
+	n, done, err := p.selector.Select(info.Ctx, opts...)
+	if err != nil {
+		// fails the call at once, rather than waiting for the next picker, i.e., once the
+		// filters drop all the ready sub connections.
+		return gbalancer.PickResult{}, status.Error(codes.Unavailable, err.Error())
+	}
+	start := time.Now()
+	return gbalancer.PickResult{
+		SubConn: n.(*subConnNode).sc,
+		Done: func(di gbalancer.DoneInfo) {
+			done(info.Ctx, selector.DoneInfo{
+				Err:           di.Err,
+				BytesSent:     di.BytesSent,
+				BytesReceived: di.BytesReceived,
+				Latency:       time.Since(start),
+			})
+		},
+	}, nil
+}
@@ -0,0 +1,119 @@
+package balancer
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/filter"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
+
+	gbalancer "google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+type subConn struct {
+	gbalancer.SubConn
+	name string
+}
+
+func TestPicker(t *testing.T) {
+	os.Setenv(registry.ZoneEnv, "a")
+	defer os.Unsetenv(registry.ZoneEnv)
+	info := base.PickerBuildInfo{ReadySCs: make(map[gbalancer.SubConn]base.SubConnInfo)}
+	for name, md := range map[string]map[string]string{
+		"a1": {registry.ZoneKey: "a"},
+		"b1": {registry.ZoneKey: "b", registry.WeightKey: "100"},
+		"b2": {registry.ZoneKey: "b", registry.WeightKey: "200"},
+	} {
+		in := &registry.ServiceInstance{ID: name, Metadata: md}
+		info.ReadySCs[&subConn{name: name}] = base.SubConnInfo{Address: discovery.NewAddress(name, in)}
+	}
+	p := (&pickerBuilder{selector: wrr.New()}).Build(info)
+	got := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		res, err := p.Pick(gbalancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[res.SubConn.(*subConn).name]++
+	}
+	// the local zone has 25% of the capacity, half of its callers spill over.
+	if got["a1"] != 500 || got["b1"] < 166 || got["b1"] > 167 || got["b2"] < 333 || got["b2"] > 334 {
+		t.Errorf("unexpected picks %v", got)
+	}
+	ctx := selector.NewFilterContext(context.Background(), filter.Zone("b", false))
+	for i := 0; i < 10; i++ {
+		res, err := p.Pick(gbalancer.PickInfo{Ctx: ctx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := res.SubConn.(*subConn).name; name == "a1" {
+			t.Fatalf("expected the sub connections of the filters, but got %s", name)
+		}
+	}
+	none := selector.NewFilterContext(context.Background(), filter.Version("v9.*"))
+	if _, err := p.Pick(gbalancer.PickInfo{Ctx: none}); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable once the filters drop all, but got %v", err)
+	}
+	if _, err := (&pickerBuilder{selector: wrr.New()}).Build(base.PickerBuildInfo{}).Pick(gbalancer.PickInfo{}); err != gbalancer.ErrNoSubConnAvailable {
+		t.Errorf("expected ErrNoSubConnAvailable, but got %v", err)
+	}
+}
+
+// clientConn is the client connection of the balancer, which keeps the sub connections.
+type clientConn struct {
+	gbalancer.ClientConn
+	subConns []gbalancer.SubConn
+}
+
+func (cc *clientConn) NewSubConn(addrs []resolver.Address, _ gbalancer.NewSubConnOptions) (gbalancer.SubConn, error) {
+	sc := &subConn{name: addrs[0].Addr}
+	cc.subConns = append(cc.subConns, sc)
+	return sc, nil
+}
+
+func (cc *clientConn) UpdateState(gbalancer.State) {}
+
+func (sc *subConn) Connect() {}
+
+// countingSelector counts the applies of the selector.
+type countingSelector struct {
+	selector.Selector
+	applies int
+}
+
+func (s *countingSelector) Apply(nodes []selector.Node) {
+	s.applies++
+	s.Selector.Apply(nodes)
+}
+
+func TestBalancerSelector(t *testing.T) {
+	var built []*countingSelector
+	b := NewBuilder("test", selector.BuilderFunc(func() selector.Selector {
+		s := &countingSelector{Selector: wrr.New()}
+		built = append(built, s)
+		return s
+	}))
+	cc := &clientConn{}
+	bal := b.Build(cc, gbalancer.BuildOptions{})
+	defer bal.Close()
+	addrs := []resolver.Address{discovery.NewAddress("a", nil), discovery.NewAddress("b", nil)}
+	if err := bal.UpdateClientConnState(gbalancer.ClientConnState{ResolverState: resolver.State{Addresses: addrs}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range cc.subConns {
+		bal.UpdateSubConnState(sc, gbalancer.SubConnState{ConnectivityState: connectivity.Ready})
+	}
+	// the ready sub connections are applied to the selector of the balancer.
+	if len(built) != 1 || built[0].applies != 2 {
+		t.Fatalf("expected a single selector applied twice, but got %d selectors", len(built))
+	}
+}
@@ -0,0 +1,15 @@
+// Package chash registers the gRPC balancer of the consistent hash, see selector/chash, the
+// hash key of a call is set by the HashKey call option of the client.
+package chash
+
+import (
+	"github.com/go-kratos/kratos/v2/selector/chash"
+	"github.com/go-kratos/kratos/v2/transport/grpc/balancer"
+)
+
+// Name is the name of the balancer.
+const Name = "chash"
+
+func init() {
+	balancer.Register(Name, chash.NewBuilder())
+}
@@ -0,0 +1,15 @@
+// Package p2c registers the gRPC balancer of the power of two choices, see selector/p2c,
+// which is used by the service config of the client, i.e., {"loadBalancingPolicy":"p2c"}.
+package p2c
+
+import (
+	"github.com/go-kratos/kratos/v2/selector/p2c"
+	"github.com/go-kratos/kratos/v2/transport/grpc/balancer"
+)
+
+// Name is the name of the balancer.
+const Name = "p2c"
+
+func init() {
+	balancer.Register(Name, p2c.NewBuilder())
+}
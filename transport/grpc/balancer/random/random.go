@@ -0,0 +1,14 @@
+// Package random registers the gRPC balancer of the weighted random, see selector/random.
+package random
+
+import (
+	"github.com/go-kratos/kratos/v2/selector/random"
+	"github.com/go-kratos/kratos/v2/transport/grpc/balancer"
+)
+
+// Name is the name of the balancer.
+const Name = "random"
+
+func init() {
+	balancer.Register(Name, random.NewBuilder())
+}
@@ -0,0 +1,16 @@
+// Package wrr registers the gRPC balancer of the weighted round robin, see selector/wrr,
+// the weights and the zones are read from the metadata of the instances resolved by the
+// discovery, and the instances in the local zone are preferred, see registry.LocalZone.
+package wrr
+
+import (
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport/grpc/balancer"
+)
+
+// Name is the name of the balancer.
+const Name = "wrr"
+
+func init() {
+	balancer.Register(Name, wrr.NewBuilder())
+}
@@ -2,17 +2,29 @@ package grpc
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/appinfo"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metadata"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc/balancer/wrr"
+	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
 
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 // ClientOption is gRPC client option.
 type ClientOption func(o *clientOptions)
 
-// WithContext with client context.
+// WithContext with client context, the user agent is the name and the version of the app
+// in the context if any, i.e., helloworld/v1.0.0.
 func WithContext(ctx context.Context) ClientOption {
 	return func(c *clientOptions) {
 		c.ctx = ctx
@@ -54,8 +66,35 @@ func WithOptions(opts ...grpc.DialOption) ClientOption {
 	}
 }
 
+// WithDiscovery with the discovery of the discovery:///<service> targets, which are
+// balanced by the weighted round robin of the wrr balancer by default, see WithBalancer.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) {
+		o.discovery = d
+	}
+}
+
+// WithBalancer with the name of the registered balancer of the discovery:///<service>
+// targets, i.e., the p2c balancer once the transport/grpc/balancer/p2c package is imported.
+func WithBalancer(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.balancer = name
+	}
+}
+
+// WithFilter with the filters of the nodes of the discovery:///<service> targets, which are
+// applied in order before the filters of the call context, see selector.NewFilterContext.
+func WithFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *clientOptions) {
+		o.filters = filters
+	}
+}
+
 type clientOptions struct {
 	ctx         context.Context
+	discovery   registry.Discovery
+	balancer    string
+	filters     []selector.NodeFilter
 	insecure    bool
 	timeout     time.Duration
 	interceptor grpc.UnaryClientInterceptor
@@ -68,6 +107,7 @@ func NewClient(target string, opts ...ClientOption) (*grpc.ClientConn, error) {
 	options := clientOptions{
 		ctx:      context.Background(),
 		timeout:  500 * time.Millisecond,
+		balancer: wrr.Name,
 		insecure: false,
 	}
 	for _, o := range opts {
@@ -75,28 +115,89 @@ func NewClient(target string, opts ...ClientOption) (*grpc.ClientConn, error) {
 	}
 	var grpcOpts = []grpc.DialOption{
 		grpc.WithTimeout(options.timeout),
-		grpc.WithUnaryInterceptor(UnaryClientInterceptor(options.middleware)),
+		grpc.WithUnaryInterceptor(filterInterceptor(options.filters, UnaryClientInterceptor(options.middleware))),
 	}
 	if options.interceptor != nil {
 		grpcOpts = append(grpcOpts, grpc.WithChainUnaryInterceptor(
 			options.interceptor,
-			UnaryClientInterceptor(options.middleware),
+			filterInterceptor(options.filters, UnaryClientInterceptor(options.middleware)),
 		))
 	}
 	if options.insecure {
 		grpcOpts = append(grpcOpts, grpc.WithInsecure())
 	}
+	if options.discovery != nil {
+		grpcOpts = append(grpcOpts,
+			grpc.WithResolvers(discovery.NewBuilder(options.discovery, discovery.WithSecure(!options.insecure))),
+			grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy":%q}`, options.balancer)),
+		)
+	}
+	if info, ok := appinfo.FromContext(options.ctx); ok {
+		grpcOpts = append(grpcOpts, grpc.WithUserAgent(strings.TrimSuffix(info.Name()+"/"+info.Version(), "/")))
+	}
 	if len(options.grpcOpts) > 0 {
 		grpcOpts = append(grpcOpts, options.grpcOpts...)
 	}
 	return grpc.DialContext(options.ctx, target, grpcOpts...)
 }
 
-// UnaryClientInterceptor retruns a unary client interceptor.
+// filterInterceptor returns the interceptor passing the filters to the balancer via the
+// call context before the filters of the call, or next if there is none.
+func filterInterceptor(filters []selector.NodeFilter, next grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	if len(filters) == 0 {
+		return next
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = selector.NewFilterContext(ctx, append(filters[:len(filters):len(filters)], selector.FiltersFromContext(ctx)...)...)
+		return next(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+// hashKeyOption is the call option of the hash key.
+type hashKeyOption struct {
+	grpc.EmptyCallOption
+	key string
+}
+
+// HashKey returns the call option of the hash key of the call, i.e., the user ID, so that
+// the calls of the same key are routed to the same node by the chash balancer.
+func HashKey(key string) grpc.CallOption {
+	return hashKeyOption{key: key}
+}
+
+// UnaryClientInterceptor retruns a unary client interceptor, the errors are restored from the gRPC statuses.
 func UnaryClientInterceptor(m middleware.Middleware) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for _, o := range opts {
+			// the balancer reads the key from the context of the pick.
+			if o, ok := o.(hashKeyOption); ok {
+				ctx = selector.WithHashKey(ctx, o.key)
+			}
+		}
+		header := grpcmd.MD{}
+		ctx = transport.NewContext(ctx, transport.Transport{
+			Kind:      "GRPC",
+			Operation: method,
+			Header:    headerCarrier(header),
+		})
 		h := func(ctx context.Context, req interface{}) (interface{}, error) {
-			return reply, invoker(ctx, method, req, reply, cc, opts...)
+			md, _ := grpcmd.FromOutgoingContext(ctx)
+			out := grpcmd.MD{}
+			for k, vs := range metadata.Encode(metadata.Outgoing(ctx)) {
+				// the metadata set explicitly wins.
+				if _, ok := md[k]; !ok {
+					if _, ok := header[k]; !ok {
+						out[k] = vs
+					}
+				}
+			}
+			if len(header) > 0 || len(out) > 0 {
+				ctx = grpcmd.NewOutgoingContext(ctx, grpcmd.Join(md, header, out))
+			}
+			if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+				return nil, errors.FromError(err)
+			}
+			return reply, nil
 		}
 		if m != nil {
 			h = m(h)
@@ -1,6 +1,10 @@
 package grpc
 
-import "context"
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
 
 // ServerInfo is HTTP server infomation.
 type ServerInfo struct {
@@ -8,6 +12,8 @@ type ServerInfo struct {
 	Server interface{}
 	// FullMethod is the full RPC method string, i.e., /package.service/method.
 	FullMethod string
+	// Stats is the byte counts of the request and reply messages.
+	Stats *transport.Stats
 }
 
 type serverKey struct{}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	_ transport.Header = headerCarrier{}
+	_ transport.Header = (*replyHeaderCarrier)(nil)
+)
+
+type headerCarrier metadata.MD
+
+// Get returns the value associated with the passed key.
+func (mc headerCarrier) Get(key string) string {
+	if values := metadata.MD(mc).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set stores the key-value pair.
+func (mc headerCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+// Keys lists the keys stored in this carrier.
+func (mc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range metadata.MD(mc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// replyHeaderCarrier buffers the values, which are sent once as the gRPC header of the
+// reply, see flush.
+type replyHeaderCarrier struct {
+	ctx context.Context
+	md  metadata.MD
+}
+
+// Get returns the value associated with the passed key.
+func (rc *replyHeaderCarrier) Get(key string) string {
+	return headerCarrier(rc.md).Get(key)
+}
+
+// Set stores the key-value pair, which replaces the former values of the key.
+func (rc *replyHeaderCarrier) Set(key string, value string) {
+	rc.md.Set(key, value)
+}
+
+// flush sends the values with the reply header.
+func (rc *replyHeaderCarrier) flush() error {
+	if len(rc.md) == 0 {
+		return nil
+	}
+	return grpc.SetHeader(rc.ctx, rc.md)
+}
+
+// Keys lists the keys stored in this carrier.
+func (rc *replyHeaderCarrier) Keys() []string {
+	return headerCarrier(rc.md).Keys()
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type headerStream struct {
+	calls  int
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/test.Service/Call" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.calls++
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return nil }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestReplyHeader(t *testing.T) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	srv := NewServer()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		tr, _ := transport.FromContext(ctx)
+		tr.ReplyHeader.Set("x-md-request", "first")
+		tr.ReplyHeader.Set("x-md-request", "second")
+		tr.ReplyHeader.Set("x-md-trace", "trace")
+		return "reply", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: stream.Method()}
+	if _, err := srv.unaryServerInterceptor()(ctx, "request", info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if stream.calls != 1 {
+		t.Errorf("expected the header to be sent once, got %d", stream.calls)
+	}
+	if v := stream.header.Get("x-md-request"); !reflect.DeepEqual(v, []string{"second"}) {
+		t.Errorf("expected [second], got %v", v)
+	}
+	if v := stream.header.Get("x-md-trace"); !reflect.DeepEqual(v, []string{"trace"}) {
+		t.Errorf("expected [trace], got %v", v)
+	}
+}
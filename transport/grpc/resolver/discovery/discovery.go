@@ -0,0 +1,146 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the scheme of the discovery targets, i.e., discovery:///helloworld.
+const Scheme = "discovery"
+
+type instanceKey struct{}
+
+// NewAddress returns the resolved address of the host of the service instance.
+func NewAddress(host string, in *registry.ServiceInstance) resolver.Address {
+	return resolver.Address{Addr: host, Attributes: attributes.New(instanceKey{}, in)}
+}
+
+// Instance returns the service instance of the resolved address.
+func Instance(addr resolver.Address) (*registry.ServiceInstance, bool) {
+	if addr.Attributes == nil {
+		return nil, false
+	}
+	in, ok := addr.Attributes.Value(instanceKey{}).(*registry.ServiceInstance)
+	return in, ok
+}
+
+// Option is discovery builder option.
+type Option func(*builder)
+
+// WithLogger with the logger of the resolvers, the global logger is used by default.
+func WithLogger(logger log.Logger) Option {
+	return func(b *builder) {
+		b.logger = logger
+	}
+}
+
+// WithSecure with the grpcs endpoints resolved rather than the grpc ones.
+func WithSecure(secure bool) Option {
+	return func(b *builder) {
+		b.secure = secure
+	}
+}
+
+type builder struct {
+	discovery registry.Discovery
+	logger    log.Logger
+	secure    bool
+}
+
+// NewBuilder returns a resolver builder of the discovery targets, the endpoint of the
+// target is the service name, and the instances without a grpc endpoint are skipped.
+func NewBuilder(d registry.Discovery, opts ...Option) resolver.Builder {
+	b := &builder{discovery: d}
+	for _, o := range opts {
+		o(b)
+	}
+	if b.logger == nil {
+		b.logger = log.GetLogger()
+	}
+	return b
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := b.discovery.Watch(ctx, target.Endpoint)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	r := &discoveryResolver{
+		w:      w,
+		cc:     cc,
+		ctx:    ctx,
+		cancel: cancel,
+		secure: b.secure,
+		log:    log.NewHelper("grpc/resolver/discovery", b.logger),
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+type discoveryResolver struct {
+	w      registry.Watcher
+	cc     resolver.ClientConn
+	ctx    context.Context
+	cancel context.CancelFunc
+	secure bool
+	log    *log.Helper
+}
+
+func (r *discoveryResolver) watch() {
+	for {
+		ins, err := r.w.Next()
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return
+			}
+			r.log.Errorf("failed to watch discovery endpoint: %v", err)
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		r.update(ins)
+	}
+}
+
+func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
+	addrs := make([]resolver.Address, 0, len(ins))
+	for _, in := range ins {
+		host, err := registry.ParseEndpoint(in.Endpoints, "grpc", r.secure)
+		if err != nil {
+			r.log.Errorf("failed to parse discovery endpoint: %v", err)
+			continue
+		}
+		if host == "" {
+			continue
+		}
+		addrs = append(addrs, NewAddress(host, in))
+	}
+	if len(addrs) == 0 {
+		// keep the last addresses rather than failing all the calls.
+		r.log.Warnf("no grpc endpoint found in %d instances, the last addresses are kept", len(ins))
+		return
+	}
+	r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	r.w.Stop()
+}
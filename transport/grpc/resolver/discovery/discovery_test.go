@@ -0,0 +1,58 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+type testClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+}
+
+func (cc *testClientConn) UpdateState(s resolver.State) {
+	cc.states <- s
+}
+
+func (cc *testClientConn) ParseServiceConfig(string) *serviceconfig.ParseResult { return nil }
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	d := memory.New()
+	d.Register(ctx, &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{"http://127.0.0.1:8000", "grpc://127.0.0.1:9000"}})
+	d.Register(ctx, &registry.ServiceInstance{ID: "2", Name: "helloworld", Endpoints: []string{"grpc://[::1]:9000"}})
+	d.Register(ctx, &registry.ServiceInstance{ID: "3", Name: "helloworld", Endpoints: []string{"http://127.0.0.1:8001"}})
+	cc := &testClientConn{states: make(chan resolver.State, 1)}
+	r, err := NewBuilder(d).Build(resolver.Target{Scheme: Scheme, Endpoint: "helloworld"}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	s := <-cc.states
+	if len(s.Addresses) != 2 || s.Addresses[0].Addr != "127.0.0.1:9000" || s.Addresses[1].Addr != "[::1]:9000" {
+		t.Fatalf("unexpected addresses %v", s.Addresses)
+	}
+	if in, ok := Instance(s.Addresses[1]); !ok || in.ID != "2" {
+		t.Errorf("expected the instance 2, but got %v", in)
+	}
+
+	d.Deregister(ctx, &registry.ServiceInstance{ID: "1", Name: "helloworld"})
+	if s = <-cc.states; len(s.Addresses) != 1 || s.Addresses[0].Addr != "[::1]:9000" {
+		t.Fatalf("unexpected addresses %v", s.Addresses)
+	}
+	// the instances without grpc endpoints keep the last addresses.
+	d.Deregister(ctx, &registry.ServiceInstance{ID: "2", Name: "helloworld"})
+	select {
+	case s = <-cc.states:
+		t.Errorf("expected no update, but got %v", s.Addresses)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
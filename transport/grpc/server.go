@@ -3,17 +3,27 @@ package grpc
 import (
 	"context"
 	"net"
+	"sync"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/log/stdlog"
+	"github.com/go-kratos/kratos/v2/metadata"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-var _ transport.Server = (*Server)(nil)
+var (
+	_ transport.Server     = (*Server)(nil)
+	_ transport.Endpointer = (*Server)(nil)
+)
 
 // ServerOption is gRPC server option.
 type ServerOption func(o *serverOptions)
@@ -26,6 +36,7 @@ type serverOptions struct {
 	middleware  middleware.Middleware
 	grpcOpts    []grpc.ServerOption
 	logger      log.Logger
+	mdPrefixes  []string
 }
 
 // Network with server network.
@@ -63,13 +74,22 @@ func Middleware(m middleware.Middleware) ServerOption {
 	}
 }
 
-// Logger with server logger.
+// Logger with server logger, the global logger is used by default.
 func Logger(logger log.Logger) ServerOption {
 	return func(o *serverOptions) {
 		o.logger = logger
 	}
 }
 
+// MetadataPrefixes with the prefixes of the incoming metadata keys extracted into the server
+// metadata, see metadata.FromServerContext, x-md-global- and x-md-local- by default. None are
+// extracted if empty, i.e., the edge servers never trust the metadata of the external callers.
+func MetadataPrefixes(prefixes ...string) ServerOption {
+	return func(o *serverOptions) {
+		o.mdPrefixes = prefixes
+	}
+}
+
 // Options with grpc options.
 func Options(opts ...grpc.ServerOption) ServerOption {
 	return func(o *serverOptions) {
@@ -80,57 +100,104 @@ func Options(opts ...grpc.ServerOption) ServerOption {
 // Server is a gRPC server wrapper.
 type Server struct {
 	*grpc.Server
-	opts serverOptions
-	log  *log.Helper
+	opts     serverOptions
+	log      *log.Helper
+	endpoint string
+
+	once      sync.Once
+	lis       net.Listener
+	err       error
+	inherited []net.Listener
+	// baseCtx is the context of Start, whose values the request contexts carry.
+	baseCtx context.Context
 }
 
 // NewServer creates a gRPC server by options.
 func NewServer(opts ...ServerOption) *Server {
 	options := serverOptions{
-		network: "tcp",
-		address: ":9000",
-		timeout: time.Second,
-		logger:  stdlog.NewLogger(),
+		network:    "tcp",
+		address:    ":9000",
+		timeout:    time.Second,
+		mdPrefixes: metadata.DefaultPrefixes(),
 	}
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
+	srv := &Server{
+		opts: options,
+		log:  log.NewHelper("grpc", options.logger),
+	}
 	var grpcOpts = []grpc.ServerOption{
 		grpc.ChainUnaryInterceptor(
-			UnaryServerInterceptor(options.middleware),
+			srv.unaryServerInterceptor(),
 			UnaryTimeoutInterceptor(options.timeout),
 		),
 	}
 	if options.interceptor != nil {
 		grpcOpts = append(grpcOpts, grpc.ChainUnaryInterceptor(
 			options.interceptor,
-			UnaryServerInterceptor(options.middleware),
+			srv.unaryServerInterceptor(),
 			UnaryTimeoutInterceptor(options.timeout),
 		))
 	}
 	if len(options.grpcOpts) > 0 {
 		grpcOpts = append(grpcOpts, options.grpcOpts...)
 	}
-	return &Server{
-		opts:   options,
-		Server: grpc.NewServer(grpcOpts...),
-		log:    log.NewHelper("grpc", options.logger),
-	}
+	srv.Server = grpc.NewServer(grpcOpts...)
+	return srv
+}
+
+// Listener returns the listener of the server, nil if not listening.
+func (s *Server) Listener() net.Listener {
+	return s.lis
+}
+
+// Inherit with the listeners of the servers replaced on a reload, the server listens on a
+// duplicate of the listener of its address if any.
+func (s *Server) Inherit(listeners []net.Listener) {
+	s.inherited = listeners
+}
+
+// Endpoint listens if not yet, and returns the endpoint of the gRPC server.
+func (s *Server) Endpoint() (string, error) {
+	s.once.Do(func() {
+		var ok bool
+		if s.lis, ok, s.err = transport.InheritListener(s.opts.network, s.opts.address, s.inherited); !ok && s.err == nil {
+			s.lis, s.err = net.Listen(s.opts.network, s.opts.address)
+		}
+		if s.err != nil {
+			return
+		}
+		var addr string
+		if addr, s.err = host.Extract(s.lis.Addr().String()); s.err != nil {
+			s.lis.Close()
+			return
+		}
+		s.endpoint = registry.NewEndpoint("grpc", addr, false)
+		s.log.Infof("[gRPC] server listening on: %s", s.lis.Addr().String())
+	})
+	return s.endpoint, s.err
 }
 
-// Start start the gRPC server.
+// Start start the gRPC server, the request contexts carry the values of ctx, i.e., the app info.
 func (s *Server) Start(ctx context.Context) error {
-	lis, err := net.Listen(s.opts.network, s.opts.address)
-	if err != nil {
+	if _, err := s.Endpoint(); err != nil {
 		return err
 	}
-	s.log.Infof("[gRPC] server listening on: %s", s.opts.address)
-	return s.Serve(lis)
+	s.baseCtx = ctx
+	return s.Serve(s.lis)
 }
 
 // Stop stop the gRPC server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.GracefulStop()
+	if s.lis != nil {
+		// the listener is closed by GracefulStop only if served.
+		s.lis.Close()
+	}
 	s.log.Info("[gRPC] server stopping")
 	return nil
 }
@@ -146,19 +213,63 @@ func UnaryTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor
 
 // UnaryServerInterceptor returns a unary server interceptor.
 func UnaryServerInterceptor(m middleware.Middleware) grpc.UnaryServerInterceptor {
+	srv := &Server{opts: serverOptions{middleware: m, mdPrefixes: metadata.DefaultPrefixes()}}
+	return srv.unaryServerInterceptor()
+}
+
+func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		ctx = transport.NewContext(ctx, transport.Transport{Kind: "GRPC"})
-		ctx = NewContext(ctx, ServerInfo{Server: info.Server, FullMethod: info.FullMethod})
+		if s.baseCtx != nil {
+			ctx = valueContext{Context: ctx, values: s.baseCtx}
+		}
+		md, _ := grpcmd.FromIncomingContext(ctx)
+		replyHeader := &replyHeaderCarrier{ctx: ctx, md: grpcmd.MD{}}
+		ctx = transport.NewContext(ctx, transport.Transport{
+			Kind:        "GRPC",
+			Endpoint:    s.endpoint,
+			Operation:   info.FullMethod,
+			Header:      headerCarrier(md.Copy()),
+			ReplyHeader: replyHeader,
+		})
+		stats := new(transport.Stats)
+		if msg, ok := req.(proto.Message); ok {
+			stats.AddRequestBytes(int64(proto.Size(msg)))
+		}
+		ctx = metadata.NewServerContext(ctx, metadata.Extract(md, s.opts.mdPrefixes))
+		ctx = NewContext(ctx, ServerInfo{Server: info.Server, FullMethod: info.FullMethod, Stats: stats})
 		h := func(ctx context.Context, req interface{}) (interface{}, error) {
 			return handler(ctx, req)
 		}
-		if m != nil {
-			h = m(h)
+		if s.opts.middleware != nil {
+			h = s.opts.middleware(h)
 		}
 		reply, err := h(ctx, req)
+		if ferr := replyHeader.flush(); ferr != nil {
+			s.log.Errorf("[gRPC] failed to send the reply header: %v", ferr)
+		}
+		if msg, ok := reply.(proto.Message); ok && err == nil {
+			stats.AddResponseBytes(int64(proto.Size(msg)))
+		}
+		stats.Finish()
 		if err != nil {
+			if _, ok := status.FromError(err); !ok {
+				err = errors.FromError(err).GRPCStatus().Err()
+			}
 			return nil, err
 		}
 		return reply, nil
 	}
 }
+
+// valueContext is the request context, which falls back to the values of the base context.
+type valueContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valueContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.values.Value(key)
+}
@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestServerEndpointUnspecified(t *testing.T) {
+	srv := NewServer(Address(":0"), Logger(log.NewRecorder()))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.lis.Close()
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "grpc" {
+		t.Errorf("expected the grpc scheme, but got %s", endpoint)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip == nil || ip.IsUnspecified() || u.Port() == "0" {
+		t.Errorf("expected the endpoint of a dialable host, but got %s", endpoint)
+	}
+}
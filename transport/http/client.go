@@ -1,20 +1,33 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/textproto"
+	"strings"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/appinfo"
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http/httpstatus"
 )
 
 // ClientOption is HTTP client option.
 type ClientOption func(*Client)
 
-// WithTimeout with client request timeout.
+// WithTimeout with client request timeout, which bounds the whole exchange including the
+// read of the response body, and the response headers only of the streaming responses.
 func WithTimeout(d time.Duration) ClientOption {
 	return func(o *Client) {
 		o.timeout = d
@@ -35,13 +48,85 @@ func WithMaxIdleConns(n int) ClientOption {
 	}
 }
 
-// WithUserAgent with client user agent.
+// WithUserAgent with client user agent, the name and the version of the app in the
+// request context by default, i.e., helloworld/v1.0.0.
 func WithUserAgent(ua string) ClientOption {
 	return func(o *Client) {
 		o.userAgent = ua
 	}
 }
 
+// WithContentType with the codec name of the requests and the accepted responses, i.e., msgpack,
+// the codec must be registered by importing its package.
+func WithContentType(name string) ClientOption {
+	return func(o *Client) {
+		o.contentType = name
+	}
+}
+
+// WithCodec with the codec instance of the requests and the accepted responses as WithContentType,
+// i.e., a json codec with options, the responses are decoded by it via DecodeResponse.
+func WithCodec(c encoding.Codec) ClientOption {
+	return func(o *Client) {
+		o.contentType = c.Name()
+		o.codec = c
+	}
+}
+
+// WithDiscovery with the discovery of the discovery://<service> URLs, the instances are
+// selected by the selectors, see WithSelector, and those without a http endpoint are skipped.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(o *Client) {
+		o.discovery = d
+	}
+}
+
+// WithSelector with the builder of the selectors of the discovered services, the weighted
+// round robin preferring the local zone by default, see selector/wrr.
+func WithSelector(b selector.Builder) ClientOption {
+	return func(o *Client) {
+		o.selector = b
+	}
+}
+
+// WithFilter with the filters of the nodes of the discovered services, which are applied in
+// order before the filters of the request context, see selector.NewFilterContext.
+func WithFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *Client) {
+		o.filters = filters
+	}
+}
+
+// WithMiddleware with the client middleware, i.e., the constant metadata, whose requests are
+// the *http.Request, and the replies are the *http.Response.
+func WithMiddleware(m middleware.Middleware) ClientOption {
+	return func(o *Client) {
+		o.middleware = m
+	}
+}
+
+// CallOption is an option of a request of the client, see WithCallOptions.
+type CallOption func(ctx context.Context) context.Context
+
+// HashKey with the hash key of the request, i.e., the user ID, so that the requests of the
+// same key are routed to the same node by the consistent hash selector, see selector/chash.
+func HashKey(key string) CallOption {
+	return func(ctx context.Context) context.Context {
+		return selector.WithHashKey(ctx, key)
+	}
+}
+
+// WithCallOptions returns the request with the call options applied to its context.
+func WithCallOptions(req *http.Request, opts ...CallOption) *http.Request {
+	ctx := req.Context()
+	for _, o := range opts {
+		ctx = o(ctx)
+	}
+	return req.WithContext(ctx)
+}
+
+type codecKey struct{}
+
 // Client is a HTTP transport client.
 type Client struct {
 	base         http.RoundTripper
@@ -49,6 +134,13 @@ type Client struct {
 	keepAlive    time.Duration
 	maxIdleConns int
 	userAgent    string
+	contentType  string
+	codec        encoding.Codec
+	discovery    registry.Discovery
+	selector     selector.Builder
+	resolver     *resolver
+	filters      []selector.NodeFilter
+	middleware   middleware.Middleware
 }
 
 // NewClient new a HTTP transport client.
@@ -61,6 +153,12 @@ func NewClient(opts ...ClientOption) (*http.Client, error) {
 	for _, o := range opts {
 		o(client)
 	}
+	if client.discovery != nil {
+		if client.selector == nil {
+			client.selector = wrr.NewBuilder()
+		}
+		client.resolver = newResolver(client.discovery, client.selector)
+	}
 	client.base = &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout:   client.timeout,
@@ -77,22 +175,187 @@ func NewClient(opts ...ClientOption) (*http.Client, error) {
 	return &http.Client{Transport: client}, nil
 }
 
-// RoundTrip is transport round trip.
+// RoundTrip is transport round trip, through the client middleware if any. Every attempt
+// of the middleware sends a fresh request with the body rewound, and the responses of
+// status 429 and 5xx are surfaced to the middleware as errors, i.e., for the retries, while
+// the response of the last attempt is still returned to the caller.
 func (c *Client) RoundTrip(req *http.Request) (*http.Response, error) {
-	if c.userAgent != "" && req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", c.userAgent)
+	if c.middleware == nil {
+		return c.roundTrip(req)
 	}
-	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
-	defer cancel()
-	res, err := c.base.RoundTrip(req.WithContext(ctx))
+	// the request of the caller must not be modified, the attempts are cloned from it.
+	req, err := rewindable(req.Clone(req.Context()))
 	if err != nil {
 		return nil, err
 	}
+	ctx := transport.NewContext(req.Context(), transport.Transport{
+		Kind:      "HTTP",
+		Operation: req.URL.Path,
+		Header:    headerCarrier(req.Header),
+	})
+	h := func(ctx context.Context, in interface{}) (interface{}, error) {
+		attempt := in.(*http.Request).Clone(ctx)
+		if attempt.GetBody != nil {
+			body, err := attempt.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+		res, err := c.roundTrip(attempt)
+		if err != nil {
+			return nil, err
+		}
+		return res, statusError(res)
+	}
+	reply, err := c.middleware(h)(ctx, req)
+	if res, ok := reply.(*http.Response); ok && res != nil {
+		return res, nil
+	}
+	if err == nil {
+		err = errors.Unknown("Unknown", "no response of %s", req.URL)
+	}
+	return nil, err
+}
+
+// rewindable returns the request with GetBody for the attempts, the body is buffered if it
+// can't be rewound, and the original body is closed.
+func rewindable(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody != nil {
+		// the attempts send the bodies of GetBody.
+		return req, req.Body.Close()
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body = http.NoBody
+	req.ContentLength = int64(len(data))
+	return req, nil
+}
+
+// statusError returns the error of the responses of status 429 and 5xx, the body is
+// buffered so that the response is still readable.
+func statusError(res *http.Response) error {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+		return nil
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return CheckResponse(&http.Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       ioutil.NopCloser(bytes.NewReader(data)),
+		Request:    res.Request,
+	})
+}
+
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	if ua := c.userAgent; req.Header.Get("User-Agent") == "" {
+		if info, ok := appinfo.FromContext(req.Context()); ok && ua == "" {
+			ua = strings.TrimSuffix(info.Name()+"/"+info.Version(), "/")
+		}
+		if ua != "" {
+			req.Header.Set("User-Agent", ua)
+		}
+	}
+	if c.contentType != "" {
+		contentType := baseContentType + "/" + c.contentType
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", contentType)
+		}
+		if req.Body != nil && req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+	}
+	for k, vs := range metadata.Encode(metadata.Outgoing(req.Context())) {
+		// the headers set explicitly win.
+		if _, ok := req.Header[textproto.CanonicalMIMEHeaderKey(k)]; !ok {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	// the context lives until the response body is closed, see timeoutBody.
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(c.timeout, cancel)
+	res, err := c.send(req.WithContext(ctx))
+	if err != nil {
+		if !timer.Stop() {
+			err = context.DeadlineExceeded
+		}
+		cancel()
+		return nil, err
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), StreamContentType) {
+		timer.Stop()
+	}
+	res.Body = &timeoutBody{ReadCloser: res.Body, timer: timer, cancel: cancel}
 	return res, nil
 }
 
+// timeoutBody is the response body canceling the request context once closed.
+type timeoutBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (b *timeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.timer.Stop()
+	b.cancel()
+	return err
+}
+
+// send sends the request to the host of the URL, or the node selected for the discovery scheme.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if c.codec != nil {
+		ctx = context.WithValue(ctx, codecKey{}, c.codec)
+	}
+	req = req.WithContext(ctx)
+	if req.URL.Scheme == DiscoveryScheme {
+		if c.resolver == nil {
+			return nil, errors.InvalidArgument("Discovery", "no discovery for %s", req.URL)
+		}
+		var opts []selector.SelectOption
+		if filters := append(c.filters[:len(c.filters):len(c.filters)], selector.FiltersFromContext(ctx)...); len(filters) > 0 {
+			opts = append(opts, selector.WithFilter(filters...))
+		}
+		n, done, err := c.resolver.resolve(ctx, req.URL.Host, opts...)
+		if err != nil {
+			return nil, err
+		}
+		u := *req.URL
+		u.Scheme, u.Host = n.Scheme(), n.Address()
+		req.URL, req.Host = &u, n.Address()
+		start := time.Now()
+		res, err := c.base.RoundTrip(req)
+		latency, derr := time.Since(start), err
+		if err == nil && res.StatusCode >= 500 {
+			// the node failing with a 5xx is reported to the selector with the decoded error.
+			derr = statusError(res)
+		}
+		done(ctx, selector.DoneInfo{Err: derr, BytesSent: err == nil, BytesReceived: err == nil, Latency: latency})
+		return res, err
+	}
+	return c.base.RoundTrip(req)
+}
+
 // CheckResponse returns an error (of type *Error) if the response
-// status code is not 2xx.
+// status code is not 2xx, the code is mapped by httpstatus.ToCode without an error body.
 func CheckResponse(res *http.Response) error {
 	if res.StatusCode >= 200 && res.StatusCode <= 299 {
 		return nil
@@ -102,18 +365,54 @@ func CheckResponse(res *http.Response) error {
 	if err != nil {
 		return err
 	}
-	contentType := res.Header.Get("content-type")
-	codec := encoding.GetCodec(contentSubtype(contentType))
-	if codec == nil {
-		return errors.Unknown("Unknown", "unknown contentType: %s", contentType)
+	codec, err := responseBodyCodec(res)
+	if err != nil {
+		return err
 	}
-	se := &errors.StatusError{}
+	se := &errors.Error{}
 	if err := codec.Unmarshal(data, se); err != nil {
-		return err
+		// the proto codecs decode the status proto of the error.
+		var st errors.Status
+		if codec.Unmarshal(data, &st) == nil {
+			se = &errors.Error{Code: st.Code, Reason: st.Reason, Message: st.Message, Metadata: st.Metadata}
+		}
+	}
+	if se.Code == 0 {
+		// not an error body, i.e., from a proxy.
+		return errors.New(httpstatus.ToCode(res.StatusCode), errors.UnknownReason, http.StatusText(res.StatusCode))
 	}
 	return se
 }
 
+// NewRequest new a request of method to target with the args v, which are encoded into
+// the query by the form codec for GET, HEAD and DELETE requests, and into the body by the
+// default codec otherwise. A nil v has no args.
+func NewRequest(ctx context.Context, method, target string, v interface{}) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil || v == nil {
+		return req, err
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return req, EncodeQuery(req, v)
+	}
+	codec := encoding.DefaultCodec()
+	if codec == nil {
+		return nil, errors.Unknown("Codec", "no default codec")
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Header.Set("Content-Type", contentType(codec))
+	return req, nil
+}
+
 // DecodeResponse decodes the body of res into target. If there is no body, target is unchanged.
 func DecodeResponse(res *http.Response, v interface{}) error {
 	defer res.Body.Close()
@@ -121,10 +420,37 @@ func DecodeResponse(res *http.Response, v interface{}) error {
 	if err != nil {
 		return err
 	}
-	contentType := res.Header.Get("content-type")
-	codec := encoding.GetCodec(contentSubtype(contentType))
-	if codec == nil {
-		return errors.Unknown("Unknown", "unknown contentType: %s", contentType)
+	codec, err := responseBodyCodec(res)
+	if err != nil {
+		return err
 	}
 	return codec.Unmarshal(data, v)
 }
+
+// responseBodyCodec returns the codec of the response body, the instance of WithCodec if it matches,
+// and if the content type is unknown, the codec accepted by the request or the default codec.
+func responseBodyCodec(res *http.Response) (encoding.Codec, error) {
+	contentType := res.Header.Get("content-type")
+	if err := checkCharset(contentType); err != nil {
+		return nil, err
+	}
+	subtype := ContentSubtype(contentType)
+	if res.Request != nil {
+		if codec, ok := res.Request.Context().Value(codecKey{}).(encoding.Codec); ok && (subtype == "" || subtype == codec.Name()) {
+			return codec, nil
+		}
+	}
+	if codec := encoding.GetCodec(subtype); codec != nil {
+		return codec, nil
+	}
+	if res.Request != nil {
+		accept := strings.Split(res.Request.Header.Get("accept"), ",")[0]
+		if codec := encoding.GetCodec(ContentSubtype(accept)); codec != nil {
+			return codec, nil
+		}
+	}
+	if codec := encoding.DefaultCodec(); codec != nil {
+		return codec, nil
+	}
+	return nil, errors.Unknown("Unknown", "unknown contentType: %s", contentType)
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metadata"
+	mmd "github.com/go-kratos/kratos/v2/middleware/metadata"
+	"github.com/go-kratos/kratos/v2/middleware/retry"
+)
+
+func startServer(t *testing.T, h http.HandlerFunc) string {
+	srv := NewServer(Address("127.0.0.1:0"), Logger(log.NewRecorder()))
+	srv.HandleFunc("/", h)
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start(context.Background())
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+	return endpoint
+}
+
+func TestClientMetadataPropagation(t *testing.T) {
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(ctx context.Context, endpoint string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+	var (
+		mu       sync.Mutex
+		received = make(map[string]metadata.Metadata)
+	)
+	record := func(hop string, r *http.Request) {
+		md, _ := metadata.FromServerContext(r.Context())
+		mu.Lock()
+		received[hop] = md
+		mu.Unlock()
+	}
+	c := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		record("c", r)
+	})
+	b := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		record("b", r)
+		if err := call(r.Context(), c); err != nil {
+			t.Error(err)
+		}
+	})
+	ctx := metadata.NewClientContext(context.Background(), metadata.Pairs(
+		"x-md-global-tenant", "租户",
+		"x-md-local-caller", "a",
+		"caller", "dropped",
+	))
+	if err := call(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if md := received["b"]; md.Get("x-md-global-tenant") != "租户" || md.Get("x-md-local-caller") != "a" || len(md) != 2 {
+		t.Errorf("expected the global and the local metadata at the first hop, but got %v", md)
+	}
+	if md := received["c"]; md.Get("x-md-global-tenant") != "租户" || len(md) != 1 {
+		t.Errorf("expected the global metadata only at the second hop, but got %v", md)
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	header := make(chan http.Header, 1)
+	endpoint := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header <- r.Header
+	})
+	client, err := NewClient(WithMiddleware(mmd.Client(mmd.WithConstants(map[string]string{"x-md-global-region": "上海"}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Get(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got := (<-header).Get("X-Md-Global-Region"); got != "%E4%B8%8A%E6%B5%B7" {
+		t.Errorf("expected the encoded constant, but got %s", got)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		bodies   []string
+		failures = 1
+	)
+	endpoint := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		if len(bodies) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	client, err := NewClient(WithMiddleware(retry.Client(retry.Backoff(func(int) time.Duration { return 0 }))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the body without GetBody is buffered for the attempts.
+	res, err := client.Post(endpoint, "text/plain", ioutil.NopCloser(strings.NewReader("payload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(data) != "ok" {
+		t.Errorf("expected the retried response, but got %d %q", res.StatusCode, data)
+	}
+	if want := []string{"payload", "payload"}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("expected the bodies %v, but got %v", want, bodies)
+	}
+
+	// the response of the last failed attempt is returned.
+	bodies, failures = nil, 3
+	res, err = client.Post(endpoint, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || len(bodies) != 3 {
+		t.Errorf("expected 3 attempts failed by 503, but got %d after %d", res.StatusCode, len(bodies))
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	endpoint := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("kratos"))
+	})
+	client, _ := NewClient(WithTimeout(100 * time.Millisecond))
+	res, err := client.Get(endpoint + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the body is read once the round trip returns.
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || string(body) != "kratos" {
+		t.Errorf("expected the body read, but got %q %v", body, err)
+	}
+	if _, err := client.Get(endpoint + "/?slow=1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline exceeded, but got %v", err)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	type args struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	endpoint := startServer(t, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&args{Name: req.URL.RawQuery + "|" + req.Header.Get("Content-Type") + "|" + string(body)})
+	})
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, "name=kratos&page=1&tags=a&tags=b||"},
+		{http.MethodPost, `page=1|application/json|{"name":"kratos","tags":["a","b"]}`},
+	}
+	for _, test := range tests {
+		req, err := NewRequest(context.Background(), test.method, endpoint+"/?page=1", &args{Name: "kratos", Tags: []string{"a", "b"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply args
+		if err := DecodeResponse(res, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Name != test.want {
+			t.Errorf("%s: expected %q, but got %q", test.method, test.want, reply.Name)
+		}
+	}
+}
@@ -2,56 +2,165 @@ package http
 
 import (
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/errors"
 )
 
-const (
-	baseContentType    = "application"
-	defaultContentType = "application/json"
-)
+const baseContentType = "application"
 
-func contentSubtype(contentType string) string {
-	if contentType == baseContentType {
-		return ""
+// ContentSubtype returns the lowercase subtype of the content type without parameters,
+// the structured suffixes are resolved to the underlying codec, i.e., json for
+// application/json; charset=utf-8 and application/vnd.myapp.v2+json.
+func ContentSubtype(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
 	}
-	if !strings.HasPrefix(contentType, baseContentType) {
+	i := strings.IndexByte(contentType, '/')
+	if i < 0 {
 		return ""
 	}
-	// guaranteed since != baseContentType and has baseContentType prefix
-	switch contentType[len(baseContentType)] {
-	case '/', ';':
-		// this will return true for "application/grpc+" or "application/grpc;"
-		// which the previous validContentType function tested to be valid, so we
-		// just say that no content-subtype is specified in this case
-		return contentType[len(baseContentType)+1:]
-	default:
-		return ""
+	subtype := strings.ToLower(strings.TrimSpace(contentType[i+1:]))
+	if i := strings.LastIndexByte(subtype, '+'); i >= 0 {
+		subtype = subtype[i+1:]
+	}
+	return subtype
+}
+
+// checkCharset returns an unsupported media type error if the charset of the content type is not utf-8.
+func checkCharset(contentType string) error {
+	for _, param := range strings.Split(contentType, ";")[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(kv[0], "charset") {
+			continue
+		}
+		switch strings.ToLower(strings.Trim(kv[1], `"`)) {
+		case "utf-8", "utf8":
+		default:
+			return errors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "unsupported charset: %s", kv[1])
+		}
+	}
+	return nil
+}
+
+// contentType returns the content type of the codec.
+func contentType(codec encoding.Codec) string {
+	return baseContentType + "/" + codec.Name()
+}
+
+// defaultCodec returns the codec that unknown content types fall back to.
+func defaultCodec(contentType string) (encoding.Codec, error) {
+	if codec := encoding.DefaultCodec(); codec != nil {
+		return codec, nil
+	}
+	return nil, errors.InvalidArgument("Codec", "not found codec: "+contentType)
+}
+
+// negotiator selects the codecs of the requests and the responses,
+// a pinned codec is used for the responses regardless of the accept header.
+// The internal details of the errors are encoded in debug mode.
+type negotiator struct {
+	codec    string
+	instance encoding.Codec
+	strict   bool
+	debug    bool
+}
+
+// fallback returns the pinned codec, the default codec if not pinned.
+func (n negotiator) fallback(contentType string) (encoding.Codec, error) {
+	if n.instance != nil {
+		return n.instance, nil
+	}
+	if n.codec == "" {
+		return defaultCodec(contentType)
+	}
+	if codec := encoding.GetCodec(n.codec); codec != nil {
+		return codec, nil
 	}
+	return nil, errors.InvalidArgument("Codec", "not found codec: "+n.codec)
 }
 
-// requestCodec returns request codec.
-func requestCodec(req *http.Request) (encoding.Codec, error) {
+// requestCodec returns request codec, the fallback codec if the content type is unknown.
+// In strict mode, the content type must match the pinned codec, or any registered codec
+// if not pinned, and a request body without content type is rejected.
+func (n negotiator) requestCodec(req *http.Request) (encoding.Codec, error) {
 	contentType := req.Header.Get("content-type")
-	codec := encoding.GetCodec(contentSubtype(contentType))
-	if codec == nil {
-		return nil, errors.InvalidArgument("Codec", "not found codec: "+contentType)
+	if err := checkCharset(contentType); err != nil {
+		return nil, err
+	}
+	subtype := ContentSubtype(contentType)
+	if n.instance != nil && subtype == n.codec {
+		return n.instance, nil
+	}
+	if n.strict {
+		if contentType == "" && req.ContentLength == 0 {
+			return n.fallback(contentType)
+		}
+		if n.codec != "" && subtype != n.codec {
+			return nil, errors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "unsupported media type: %q", contentType)
+		}
 	}
-	return codec, nil
+	if codec := encoding.GetCodec(subtype); codec != nil {
+		return codec, nil
+	}
+	if n.strict {
+		return nil, errors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "unsupported media type: %q", contentType)
+	}
+	return n.fallback(contentType)
 }
 
-// responseCodec returns response codec.
-func responseCodec(req *http.Request) (string, encoding.Codec, error) {
+// responseCodec returns response codec negotiated by the accept header, the fallback codec
+// if none is acceptable or the codec is pinned.
+func (n negotiator) responseCodec(req *http.Request) (string, encoding.Codec, error) {
 	accepts := req.Header.Values("accept")
-	for _, contentType := range accepts {
-		if codec := encoding.GetCodec(contentSubtype(contentType)); codec != nil {
-			return contentType, codec, nil
+	if n.codec == "" {
+		for _, subtype := range acceptSubtypes(accepts) {
+			if codec := encoding.GetCodec(subtype); codec != nil {
+				return contentType(codec), codec, nil
+			}
+		}
+	}
+	codec, err := n.fallback(strings.Join(accepts, ", "))
+	if err != nil {
+		return "", nil, err
+	}
+	return contentType(codec), codec, nil
+}
+
+// acceptSubtypes returns the subtypes of the accept headers by the descending q-values,
+// the media types of q=0 or an invalid q-value are not acceptable.
+func acceptSubtypes(accepts []string) []string {
+	type mediaRange struct {
+		subtype string
+		q       float64
+	}
+	var ranges []mediaRange
+	for _, accept := range accepts {
+		for _, mediaType := range strings.Split(accept, ",") {
+			q := 1.0
+			for _, param := range strings.Split(mediaType, ";")[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "q") {
+					continue
+				}
+				v, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+			if q > 0 {
+				ranges = append(ranges, mediaRange{subtype: ContentSubtype(mediaType), q: q})
+			}
 		}
 	}
-	if codec := encoding.GetCodec("json"); codec != nil {
-		return defaultContentType, codec, nil
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	subtypes := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		subtypes = append(subtypes, r.subtype)
 	}
-	return "", nil, errors.InvalidArgument("Codec", "not found codec: "+strings.Join(accepts, "; "))
+	return subtypes
 }
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding/json"
+
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+type user struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestXMLNegotiation(t *testing.T) {
+	for _, contentType := range []string{"application/xml", "text/xml; charset=utf-8"} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`<user><name>kratos</name></user>`))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "text/html, "+contentType)
+		var u user
+		if err := DefaultRequestDecoder(req, &u); err != nil || u.Name != "kratos" {
+			t.Fatalf("%s: expected the xml body decoded, but got %+v %v", contentType, u, err)
+		}
+		res := httptest.NewRecorder()
+		if err := DefaultResponseEncoder(res, req, &u); err != nil {
+			t.Fatal(err)
+		}
+		if res.Header().Get("Content-Type") != "application/xml" || !strings.Contains(res.Body.String(), "<name>kratos</name>") {
+			t.Errorf("%s: expected the xml response, but got %s %s", contentType, res.Header().Get("Content-Type"), res.Body.String())
+		}
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	for accept, want := range map[string]string{
+		"application/json;q=0.5, application/xml":          "application/xml",
+		"application/xml;q=0.2, application/json;q=0.8":    "application/json",
+		"application/xml;q=0, text/html":                   "application/json",
+		"application/xml;q=invalid":                        "application/json",
+		"application/json;q=0.5, application/xml;q=0.5":    "application/json",
+		"text/html, application/XML; charset=utf-8; q=0.9": "application/xml",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		res := httptest.NewRecorder()
+		if err := DefaultResponseEncoder(res, req, &user{Name: "kratos"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Header().Get("Content-Type"); got != want {
+			t.Errorf("%s: expected %s, but got %s", accept, want, got)
+		}
+	}
+}
+
+func TestDefaultCodecName(t *testing.T) {
+	srv := NewServer(DefaultCodec("XML"))
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`<user><name>kratos</name></user>`))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Accept", "application/json")
+	var u user
+	if err := srv.Decode(req, &u); err != nil || u.Name != "kratos" {
+		t.Fatalf("expected the xml body decoded, but got %+v %v", u, err)
+	}
+	res := httptest.NewRecorder()
+	srv.Encode(res, req, &u)
+	if got := res.Header().Get("Content-Type"); got != "application/xml" {
+		t.Errorf("expected the pinned xml response, but got %s", got)
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`<user><name>kratos</name></user>`))
+	req.Header.Set("Content-Type", "application/xml")
+	err := DefaultRequestDecoder(req, &map[string]string{})
+	if code, _ := StatusError(err); code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, but got %d %v", code, err)
+	}
+}
+
+func TestContentSubtype(t *testing.T) {
+	tests := map[string]string{
+		"application/json":                     "json",
+		"application/json; charset=utf-8":      "json",
+		"Application/JSON":                     "json",
+		"application/vnd.myapp.v2+json":        "json",
+		"application/grpc+proto; charset=utf8": "proto",
+		"text/xml":                             "xml",
+		"application/x-www-form-urlencoded":    "x-www-form-urlencoded",
+		"":                                     "",
+	}
+	for contentType, want := range tests {
+		if got := ContentSubtype(contentType); got != want {
+			t.Errorf("%q: expected %q, but got %q", contentType, want, got)
+		}
+	}
+}
+
+func TestCharset(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"kratos"}`))
+	req.Header.Set("Content-Type", "application/vnd.kratos+json; charset=ISO-8859-1")
+	var u user
+	if code, _ := StatusError(DefaultRequestDecoder(req, &u)); code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for non utf-8 charset, but got %d", code)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kratos+json; charset=UTF-8")
+	if err := DefaultRequestDecoder(req, &u); err != nil || u.Name != "kratos" {
+		t.Errorf("expected the vendor type decoded as json, but got %+v %v", u, err)
+	}
+}
+
+func TestStrictContentType(t *testing.T) {
+	srv := NewServer(DefaultCodec("xml"), StrictContentType(true))
+	tests := []struct {
+		contentType string
+		body        string
+		code        int
+	}{
+		{"application/xml", `<user><name>kratos</name></user>`, http.StatusOK},
+		{"application/json", `{"name":"kratos"}`, http.StatusUnsupportedMediaType},
+		{"application/unknown", `{"name":"kratos"}`, http.StatusUnsupportedMediaType},
+		{"", `<user><name>kratos</name></user>`, http.StatusUnsupportedMediaType},
+		{"multipart/form-data; boundary=x", "--x--\r\n", http.StatusUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		var u user
+		code := http.StatusOK
+		if err := srv.opts.requestDecoder(req, &u); err != nil {
+			code, _ = StatusError(err)
+		}
+		if code != test.code {
+			t.Errorf("%q: expected %d, but got %d", test.contentType, test.code, code)
+		}
+	}
+
+	// empty content type is decoded by the pinned codec unless strict.
+	srv = NewServer(DefaultCodec("xml"))
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`<user><name>kratos</name></user>`))
+	req.Header.Set("Accept", "application/json")
+	var u user
+	if err := srv.opts.requestDecoder(req, &u); err != nil || u.Name != "kratos" {
+		t.Fatalf("expected the body decoded by the pinned codec, but got %+v %v", u, err)
+	}
+	res := httptest.NewRecorder()
+	if err := srv.opts.responseEncoder(res, req, &u); err != nil {
+		t.Fatal(err)
+	}
+	if res.Header().Get("Content-Type") != "application/xml" {
+		t.Errorf("expected the pinned codec regardless of accept, but got %s", res.Header().Get("Content-Type"))
+	}
+}
+
+func TestCodecInstances(t *testing.T) {
+	public := NewServer(Codec(json.New(json.Options{})))
+	replay := NewServer(Codec(json.New(json.Options{EmitUnpopulated: true, UseProtoNames: true})))
+	tests := []struct {
+		srv  *Server
+		want []string
+		not  string
+	}{
+		{public, []string{`"typeUrl"`}, `"number"`},
+		{replay, []string{`"type_url"`, `"number"`}, `"typeUrl"`},
+	}
+	var wg sync.WaitGroup
+	for _, test := range tests {
+		test := test
+		ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			test.srv.Encode(res, req, &typepb.Field{TypeUrl: "type.googleapis.com/test"})
+		}))
+		defer ts.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := http.Get(ts.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer res.Body.Close()
+			data, _ := ioutil.ReadAll(res.Body)
+			for _, want := range test.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("expected %s in %s", want, data)
+				}
+			}
+			if strings.Contains(string(data), test.not) {
+				t.Errorf("unexpected %s in %s", test.not, data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		res.Write([]byte(`{"typeUrl":"test","unknown":1}`))
+	}))
+	defer ts.Close()
+	client, err := NewClient(WithCodec(json.New(json.Options{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeResponse(res, new(typepb.Field)); err == nil {
+		t.Errorf("expected the unknown field rejected by the client codec")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/go-kratos/kratos/v2/transport"
+
 	"github.com/gorilla/mux"
 )
 
@@ -11,6 +13,8 @@ import (
 type ServerInfo struct {
 	Request  *http.Request
 	Response http.ResponseWriter
+	// Stats is the byte counts of the request and response payloads.
+	Stats *transport.Stats
 }
 
 type serverKey struct{}
@@ -1,13 +1,41 @@
 package http
 
 import (
+	"errors"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	kerrors "github.com/go-kratos/kratos/v2/errors"
 )
 
-// DefaultRequestDecoder default request decoder.
+// DefaultRequestDecoder default request decoder, a multipart request is bound into a struct
+// with the uploaded files.
 func DefaultRequestDecoder(req *http.Request, v interface{}) error {
-	codec, err := requestCodec(req)
+	return negotiator{}.decodeRequest(req, v)
+}
+
+// DefaultResponseEncoder is default response encoder, a receive channel or a StreamFunc is
+// streamed as newline-delimited JSON.
+func DefaultResponseEncoder(res http.ResponseWriter, req *http.Request, v interface{}) error {
+	return negotiator{}.encodeResponse(res, req, v)
+}
+
+// DefaultErrorEncoder is default errors encoder, the message is translated into the locale
+// of the request by the translator registered by i18n.RegisterTranslator. The message of
+// the errors which are not an *errors.Error is replaced by the status text.
+func DefaultErrorEncoder(res http.ResponseWriter, req *http.Request, err error) {
+	negotiator{}.encodeError(res, req, err)
+}
+
+func (n negotiator) decodeRequest(req *http.Request, v interface{}) error {
+	if isMultipart(req.Header.Get("content-type")) {
+		if n.strict {
+			return kerrors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "multipart bodies are not accepted in strict mode")
+		}
+		return decodeMultipart(req, v)
+	}
+	codec, err := n.requestCodec(req)
 	if err != nil {
 		return err
 	}
@@ -17,14 +45,19 @@ func DefaultRequestDecoder(req *http.Request, v interface{}) error {
 	}
 	defer req.Body.Close()
 	if err = codec.Unmarshal(data, v); err != nil {
+		if errors.Is(err, encoding.ErrUnsupportedType) {
+			return kerrors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "unsupported media type: %v", err)
+		}
 		return err
 	}
 	return nil
 }
 
-// DefaultResponseEncoder is default response encoder.
-func DefaultResponseEncoder(res http.ResponseWriter, req *http.Request, v interface{}) error {
-	contentType, codec, err := responseCodec(req)
+func (n negotiator) encodeResponse(res http.ResponseWriter, req *http.Request, v interface{}) error {
+	if isStream(v) {
+		return encodeStream(res, req, v)
+	}
+	contentType, codec, err := n.responseCodec(req)
 	if err != nil {
 		return err
 	}
@@ -37,20 +70,37 @@ func DefaultResponseEncoder(res http.ResponseWriter, req *http.Request, v interf
 	return nil
 }
 
-// DefaultErrorEncoder is default errors encoder.
-func DefaultErrorEncoder(res http.ResponseWriter, req *http.Request, err error) {
+func (n negotiator) encodeError(res http.ResponseWriter, req *http.Request, err error) {
 	code, se := StatusError(err)
-	contentType, codec, err := responseCodec(req)
-	if err != nil {
-		res.WriteHeader(http.StatusInternalServerError)
-		return
+	if !n.debug {
+		se = publicError(err, se)
 	}
-	data, err := codec.Marshal(se)
-	if err != nil {
-		res.WriteHeader(http.StatusInternalServerError)
-		return
+	se = localize(req, se)
+	var (
+		data []byte
+		v    interface{} = se
+	)
+	if n.debug {
+		v = newDebugError(err, se)
 	}
-	res.Header().Set("content-type", contentType)
+	ct, codec, cerr := n.responseCodec(req)
+	if cerr == nil {
+		if data, cerr = codec.Marshal(v); cerr != nil && n.debug {
+			// the codecs which are unaware of the debug fields still encode the error.
+			data, cerr = codec.Marshal(se)
+		}
+		if cerr != nil {
+			// the proto codecs encode the status proto of the error.
+			data, cerr = codec.Marshal(&kerrors.Status{Code: se.Code, Reason: se.Reason, Message: se.Message, Metadata: se.Metadata})
+		}
+	}
+	if cerr != nil {
+		// the error is never lost for the codecs unable to encode it.
+		codec = encoding.GetCodec("json")
+		ct = contentType(codec)
+		data, _ = codec.Marshal(se)
+	}
+	res.Header().Set("content-type", ct)
 	res.WriteHeader(code)
 	res.Write(data)
 }
@@ -1,59 +1,92 @@
 package http
 
 import (
+	stderrors "errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/locale"
+	"github.com/go-kratos/kratos/v2/transport/http/httpstatus"
+
+	"golang.org/x/text/language"
 )
 
-var (
-	// References: https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
-	codesMapping = map[int32]int{
-		0:  http.StatusOK,
-		1:  http.StatusInternalServerError,
-		2:  http.StatusInternalServerError,
-		3:  http.StatusBadRequest,
-		4:  http.StatusRequestTimeout,
-		5:  http.StatusNotFound,
-		6:  http.StatusConflict,
-		7:  http.StatusForbidden,
-		8:  http.StatusTooManyRequests,
-		9:  http.StatusPreconditionFailed,
-		10: http.StatusConflict,
-		11: http.StatusBadRequest,
-		12: http.StatusNotImplemented,
-		13: http.StatusInternalServerError,
-		14: http.StatusServiceUnavailable,
-		15: http.StatusInternalServerError,
-		16: http.StatusUnauthorized,
+// UnsupportedMediaTypeReason is the reason of the errors for the request bodies
+// which cannot be decoded into the target type, they are 415 responses.
+const UnsupportedMediaTypeReason = "UNSUPPORTED_MEDIA_TYPE"
+
+// RequestEntityTooLargeReason is the reason of the errors for the request bodies
+// or the multipart parts beyond MaxRequestBodySize, they are 413 responses.
+const RequestEntityTooLargeReason = "REQUEST_ENTITY_TOO_LARGE"
+
+// StatusError converts error to http error, the status is mapped by httpstatus.FromCode.
+func StatusError(err error) (int, *errors.Error) {
+	se := errors.FromError(err)
+	return httpstatus.FromCode(int(se.Code)), se
+}
+
+// publicError returns a copy of the error with the status text as the message, if the error
+// is converted from an error which is not an *errors.Error, whose message is internal.
+func publicError(err error, se *errors.Error) *errors.Error {
+	if target := new(errors.Error); stderrors.As(err, &target) {
+		return se
 	}
-	statusMapping = map[int]int32{
-		http.StatusOK:                  0,
-		http.StatusBadRequest:          3,
-		http.StatusRequestTimeout:      4,
-		http.StatusNotFound:            5,
-		http.StatusConflict:            6,
-		http.StatusForbidden:           7,
-		http.StatusUnauthorized:        16,
-		http.StatusPreconditionFailed:  9,
-		http.StatusNotImplemented:      12,
-		http.StatusInternalServerError: 13,
-		http.StatusServiceUnavailable:  14,
+	public := *se
+	public.Message = http.StatusText(httpstatus.FromCode(int(se.Code)))
+	return &public
+}
+
+// debugCause is a layer of the cause chain of an error.
+type debugCause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// debugError is an error with the cause chain and the stack trace, which are encoded
+// in debug mode only.
+type debugError struct {
+	*errors.Error
+	Debug []debugCause `json:"debug"`
+	Stack []string     `json:"stack,omitempty"`
+}
+
+func newDebugError(err error, se *errors.Error) *debugError {
+	de := &debugError{Error: se}
+	for _, f := range errors.StackTrace(err) {
+		de.Stack = append(de.Stack, f.String())
 	}
-)
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		cause := debugCause{Type: fmt.Sprintf("%T", err), Message: err.Error()}
+		if e, ok := err.(*errors.Error); ok {
+			cause.Message = e.Message
+		}
+		de.Debug = append(de.Debug, cause)
+	}
+	return de
+}
 
-// StatusError converts error to http error.
-func StatusError(err error) (int, *errors.StatusError) {
-	se, ok := errors.FromError(err)
+// localize returns a copy of the error with the message translated by the registered
+// translator into the locale of the request, the error is kept as is if not translated.
+// The locale is the one negotiated by the i18n middleware, or the most preferred one of
+// the Accept-Language header.
+func localize(req *http.Request, se *errors.Error) *errors.Error {
+	t := locale.GetTranslator()
+	if t == nil || se.Reason == errors.UnknownReason {
+		return se
+	}
+	tag, ok := locale.FromContext(req.Context())
 	if !ok {
-		se = &errors.StatusError{
-			Code:    2,
-			Reason:  "Unknown",
-			Message: "Unknown: " + err.Error(),
+		tags, _, _ := language.ParseAcceptLanguage(req.Header.Get("Accept-Language"))
+		if len(tags) > 0 {
+			tag = tags[0]
 		}
 	}
-	if status, ok := codesMapping[se.Code]; ok {
-		return status, se
+	msg, ok := t.Translate(tag, se.Reason)
+	if !ok {
+		return se
 	}
-	return http.StatusInternalServerError, se
+	err := *se
+	err.Message = msg
+	return &err
 }
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/locale"
+
+	"golang.org/x/text/language"
+)
+
+func TestErrorEncoder(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+		body string
+	}{
+		{fmt.Errorf("find: %w", errors.New(http.StatusNotFound, "USER_NOT_FOUND", "user not found")), 404, `"reason":"USER_NOT_FOUND"`},
+		{errors.New(10001, "BUSINESS", "custom code"), 500, `"code":10001`},
+		{stderrors.New("boom"), 500, `"message":"Internal Server Error"`},
+		{errors.InternalServer("DB", "database error").WithCause(stderrors.New("secret dsn")), 500, `{"code":500,"reason":"DB","message":"database error"}`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		res := httptest.NewRecorder()
+		DefaultErrorEncoder(res, req, test.err)
+		if res.Code != test.code || !strings.Contains(res.Body.String(), test.body) {
+			t.Errorf("expected %d %s, but got %d %s", test.code, test.body, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestDebugErrors(t *testing.T) {
+	errors.EnableStack(true)
+	defer errors.EnableStack(false)
+	err := fmt.Errorf("query: %w", errors.InternalServer("DB", "database error").WithCause(stderrors.New("secret dsn")))
+	for _, debug := range []bool{false, true} {
+		req := httptest.NewRequest("GET", "/", nil)
+		res := httptest.NewRecorder()
+		negotiator{debug: debug}.encodeError(res, req, err)
+		var body struct {
+			Message string `json:"message"`
+			Debug   []struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"debug"`
+			Stack []string `json:"stack"`
+		}
+		if e := json.Unmarshal(res.Body.Bytes(), &body); e != nil {
+			t.Fatal(e)
+		}
+		if !debug {
+			if body.Debug != nil || body.Stack != nil || strings.Contains(res.Body.String(), "secret") {
+				t.Errorf("expected nothing internal, but got %s", res.Body.String())
+			}
+			continue
+		}
+		if len(body.Debug) != 3 || body.Debug[1].Type != "*errors.Error" || body.Debug[2].Message != "secret dsn" || len(body.Stack) == 0 {
+			t.Errorf("unexpected debug response %s", res.Body.String())
+		}
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+	negotiator{debug: true}.encodeError(res, req, stderrors.New("boom"))
+	if !strings.Contains(res.Body.String(), `"message":"boom"`) {
+		t.Errorf("expected the internal message in debug mode, but got %s", res.Body.String())
+	}
+}
+
+func TestLocalizedError(t *testing.T) {
+	locale.RegisterTranslator(locale.NewTranslator(language.English, locale.Messages{
+		"zh": {"USER_NOT_FOUND": "用户不存在"},
+	}))
+	defer locale.RegisterTranslator(nil)
+	err := errors.NotFound("USER_NOT_FOUND", "user not found").WithMetadata(map[string]string{"id": "1"})
+	tests := []struct {
+		accept string
+		body   string
+	}{
+		{"zh-CN,zh;q=0.9", `{"code":404,"reason":"USER_NOT_FOUND","message":"用户不存在","metadata":{"id":"1"}}`},
+		{"fr", `{"code":404,"reason":"USER_NOT_FOUND","message":"user not found","metadata":{"id":"1"}}`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", test.accept)
+		res := httptest.NewRecorder()
+		DefaultErrorEncoder(res, req, err)
+		if res.Code != 404 || res.Body.String() != test.body {
+			t.Errorf("%s: expected %s, but got %d %s", test.accept, test.body, res.Code, res.Body.String())
+		}
+	}
+	// the locale negotiated by the middleware wins over the header.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	req = req.WithContext(locale.NewContext(req.Context(), language.Chinese))
+	res := httptest.NewRecorder()
+	DefaultErrorEncoder(res, req, err)
+	if !strings.Contains(res.Body.String(), "用户不存在") {
+		t.Errorf("expected the negotiated locale, but got %s", res.Body.String())
+	}
+	if err.Message != "user not found" {
+		t.Errorf("expected the error kept as is, but got %s", err.Message)
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		code    int32
+		reason  string
+	}{
+		{func(res http.ResponseWriter, req *http.Request) {
+			DefaultErrorEncoder(res, req, errors.New(10001, "BALANCE_NOT_ENOUGH", "balance not enough"))
+		}, 10001, "BALANCE_NOT_ENOUGH"},
+		{func(res http.ResponseWriter, req *http.Request) {
+			http.Error(res, "<html>bad gateway</html>", http.StatusBadGateway)
+		}, 502, errors.UnknownReason},
+		{func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusTeapot)
+		}, 400, errors.UnknownReason},
+	}
+	for _, test := range tests {
+		ts := httptest.NewServer(test.handler)
+		res, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := errors.FromError(CheckResponse(res))
+		if se.Code != test.code || se.Reason != test.reason {
+			t.Errorf("expected %d %s, but got %v", test.code, test.reason, se)
+		}
+		ts.Close()
+	}
+}
+
+func TestErrorMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		DefaultErrorEncoder(res, req, errors.TooManyRequests("RATE_LIMITED", "rate limited").WithMetadata(map[string]string{"retry_after": "5"}))
+	}))
+	defer ts.Close()
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckResponse(res)
+	if res.StatusCode != http.StatusTooManyRequests || errors.Metadata(err)["retry_after"] != "5" {
+		t.Errorf("expected the metadata restored, but got %d %v", res.StatusCode, err)
+	}
+}
+
+func TestProtoError(t *testing.T) {
+	srv := NewServer()
+	srv.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		srv.Error(res, req, errors.NotFound("USER_NOT_FOUND", "user not found"))
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	res := httptest.NewRecorder()
+	srv.ServeHTTP(res, req)
+	if res.Code != http.StatusNotFound || res.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("expected a proto 404, but got %d %s", res.Code, res.Header().Get("Content-Type"))
+	}
+	err := CheckResponse(res.Result())
+	if se := errors.FromError(err); se.Code != http.StatusNotFound || se.Reason != "USER_NOT_FOUND" || se.Message != "user not found" {
+		t.Errorf("expected the error decoded from the status proto, but got %v", err)
+	}
+}
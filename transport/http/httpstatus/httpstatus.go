@@ -0,0 +1,61 @@
+// Package httpstatus maps the error codes to the HTTP statuses and back.
+//
+// The error codes are HTTP statuses, the codes of the table below are kept as
+// they are, and the others are 500 server-side, while the custom business codes
+// still reach the clients in the error body:
+//
+//	400 Bad Request            409 Conflict                 499 Client Closed Request
+//	401 Unauthorized           412 Precondition Failed      500 Internal Server Error
+//	403 Forbidden              413 Request Entity Too Large 501 Not Implemented
+//	404 Not Found              415 Unsupported Media Type   502 Bad Gateway
+//	405 Method Not Allowed     422 Unprocessable Entity     503 Service Unavailable
+//	408 Request Timeout        429 Too Many Requests        504 Gateway Timeout
+package httpstatus
+
+import "net/http"
+
+// ClientClosed is the status of the requests cancelled by the clients.
+const ClientClosed = 499
+
+var statuses = map[int]bool{
+	http.StatusBadRequest:            true,
+	http.StatusUnauthorized:          true,
+	http.StatusForbidden:             true,
+	http.StatusNotFound:              true,
+	http.StatusMethodNotAllowed:      true,
+	http.StatusRequestTimeout:        true,
+	http.StatusConflict:              true,
+	http.StatusPreconditionFailed:    true,
+	http.StatusRequestEntityTooLarge: true,
+	http.StatusUnsupportedMediaType:  true,
+	http.StatusUnprocessableEntity:   true,
+	http.StatusTooManyRequests:       true,
+	ClientClosed:                     true,
+	http.StatusInternalServerError:   true,
+	http.StatusNotImplemented:        true,
+	http.StatusBadGateway:            true,
+	http.StatusServiceUnavailable:    true,
+	http.StatusGatewayTimeout:        true,
+}
+
+// FromCode returns the HTTP status of the error code, 500 for the codes out of the table.
+func FromCode(code int) int {
+	if code == http.StatusOK || statuses[code] {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// ToCode returns the default error code of the HTTP status, i.e., for the responses
+// without an error body, the statuses out of the table are 400 for 4xx and 500 for 5xx.
+func ToCode(status int) int {
+	switch {
+	case statuses[status]:
+		return status
+	case status >= 200 && status <= 299:
+		return http.StatusOK
+	case status >= 400 && status <= 499:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,29 @@
+package httpstatus
+
+import "testing"
+
+func TestMapping(t *testing.T) {
+	for _, code := range []int{400, 401, 403, 404, 405, 408, 409, 412, 413, 415, 422, 429, 499, 500, 501, 502, 503, 504} {
+		if FromCode(code) != code || ToCode(code) != code {
+			t.Errorf("expected %d kept, but got %d %d", code, FromCode(code), ToCode(code))
+		}
+	}
+	tests := []struct {
+		code, status int
+	}{
+		{200, 200},
+		{10001, 500},
+		{0, 500},
+		{418, 500},
+	}
+	for _, test := range tests {
+		if got := FromCode(test.code); got != test.status {
+			t.Errorf("FromCode(%d): expected %d, but got %d", test.code, test.status, got)
+		}
+	}
+	for status, code := range map[int]int{204: 200, 418: 400, 505: 500, 302: 500} {
+		if got := ToCode(status); got != code {
+			t.Errorf("ToCode(%d): expected %d, but got %d", status, code, got)
+		}
+	}
+}
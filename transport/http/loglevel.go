@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// LogLevelOption is log level handler option.
+type LogLevelOption func(*logLevelOptions)
+
+type logLevelOptions struct {
+	authorize func(req *http.Request) error
+}
+
+// LogLevelAuthorizer with the func authorizing the requests, i.e., checking an admin token,
+// whose error is encoded as the response, i.e., errors.Unauthorized. By default the requests
+// are not authorized, so the handler should be mounted on an internal port only.
+func LogLevelAuthorizer(fn func(req *http.Request) error) LogLevelOption {
+	return func(o *logLevelOptions) {
+		o.authorize = fn
+	}
+}
+
+// HandleLogLevel with an admin handler mounted on path, which GETs the level of
+// the filter and PUTs a new one, i.e., {"level": "DEBUG"}.
+func HandleLogLevel(path string, filter *log.Filter, opts ...LogLevelOption) ServerOption {
+	return func(o *serverOptions) {
+		o.handlers = append(o.handlers, route{path: path, handler: LogLevelHandler(filter, opts...)})
+	}
+}
+
+// LogLevelHandler returns a handler that GETs and PUTs the level of the filter,
+// the unknown levels are rejected.
+func LogLevelHandler(filter *log.Filter, opts ...LogLevelOption) http.Handler {
+	var options logLevelOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if options.authorize != nil {
+			if err := options.authorize(req); err != nil {
+				DefaultErrorEncoder(res, req, err)
+				return
+			}
+		}
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var v logLevel
+			if err := DefaultRequestDecoder(req, &v); err != nil {
+				DefaultErrorEncoder(res, req, errors.InvalidArgument("LOG_LEVEL_INVALID", "log level: %v", err))
+				return
+			}
+			level, ok := log.LookupLevel(v.Level)
+			if !ok {
+				DefaultErrorEncoder(res, req, errors.InvalidArgument("LOG_LEVEL_INVALID", "unknown log level: %q", v.Level))
+				return
+			}
+			filter.SetLevel(level)
+		default:
+			res.Header().Set("Allow", "GET, PUT")
+			res.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		DefaultResponseEncoder(res, req, &logLevel{Level: filter.Level().String()})
+	})
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	filter := log.NewFilter(log.NewRecorder(), log.FilterLevel(log.LevelInfo))
+	h := LogLevelHandler(filter)
+	tests := []struct {
+		method string
+		body   string
+		code   int
+		level  string
+	}{
+		{"GET", "", 200, "INFO"},
+		{"PUT", `{"level": "debug"}`, 200, "DEBUG"},
+		{"PUT", `{"level": "verbose"}`, 400, "DEBUG"},
+		{"PUT", `{"level": ""}`, 400, "DEBUG"},
+		{"PUT", `{"level": `, 400, "DEBUG"},
+		{"DELETE", "", 405, "DEBUG"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/debug/log/level", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Code != test.code {
+			t.Errorf("%s %s: expected %d, but got %d %s", test.method, test.body, test.code, res.Code, res.Body)
+		}
+		if level := filter.Level().String(); level != test.level {
+			t.Errorf("%s %s: expected the level %s, but got %s", test.method, test.body, test.level, level)
+		}
+		if res.Code == 200 {
+			var v logLevel
+			if err := json.Unmarshal(res.Body.Bytes(), &v); err != nil || v.Level != test.level {
+				t.Errorf("%s %s: unexpected reply %s", test.method, test.body, res.Body)
+			}
+		}
+	}
+}
+
+func TestLogLevelAuthorizer(t *testing.T) {
+	filter := log.NewFilter(log.NewRecorder(), log.FilterLevel(log.LevelInfo))
+	h := LogLevelHandler(filter, LogLevelAuthorizer(func(req *http.Request) error {
+		if req.Header.Get("Authorization") != "Bearer admin" {
+			return errors.Unauthorized("UNAUTHORIZED", "admin token required")
+		}
+		return nil
+	}))
+	req := httptest.NewRequest("PUT", "/debug/log/level", strings.NewReader(`{"level": "debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != 401 || filter.Level() != log.LevelInfo {
+		t.Errorf("expected 401 with the level kept, but got %d %v", res.Code, filter.Level())
+	}
+
+	req = httptest.NewRequest("PUT", "/debug/log/level", strings.NewReader(`{"level": "debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer admin")
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != 200 || filter.Level() != log.LevelDebug {
+		t.Errorf("expected the level changed, but got %d %v", res.Code, filter.Level())
+	}
+}
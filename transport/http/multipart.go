@@ -0,0 +1,283 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/encoding/form"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+const defaultMultipartMemory = 10 << 20
+
+var (
+	uploadedFileType  = reflect.TypeOf((*UploadedFile)(nil))
+	uploadedFilesType = reflect.TypeOf([]*UploadedFile(nil))
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// UploadedFile is a file part of a multipart request.
+type UploadedFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+
+	data []byte
+	path string
+}
+
+// Open opens the content of the file, which is held in memory or spilled to a
+// temp file removed after the handler returns.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	if f.path != "" {
+		return os.Open(f.path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// bodyLimits is the body limits of a request, and the temp files of its uploaded files.
+type bodyLimits struct {
+	maxSize   int64
+	maxMemory int64
+
+	mu    sync.Mutex
+	paths []string
+}
+
+type bodyLimitsKey struct{}
+
+func newBodyLimitsContext(ctx context.Context, l *bodyLimits) context.Context {
+	return context.WithValue(ctx, bodyLimitsKey{}, l)
+}
+
+// bodyLimitsFromContext returns the limits of the server, without a server the parts
+// are unlimited and the files are never spilled.
+func bodyLimitsFromContext(ctx context.Context) *bodyLimits {
+	if l, ok := ctx.Value(bodyLimitsKey{}).(*bodyLimits); ok {
+		return l
+	}
+	return &bodyLimits{maxMemory: -1}
+}
+
+// removeAll removes the temp files.
+func (l *bodyLimits) removeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, path := range l.paths {
+		os.Remove(path)
+	}
+	l.paths = nil
+}
+
+func errTooLarge(limit int64) error {
+	return errors.Newf(http.StatusRequestEntityTooLarge, RequestEntityTooLargeReason, "request body too large: limit %d bytes", limit)
+}
+
+// read reads the part up to the max size.
+func (l *bodyLimits) read(part io.Reader) ([]byte, error) {
+	if l.maxSize <= 0 {
+		return ioutil.ReadAll(part)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(part, l.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.maxSize {
+		return nil, errTooLarge(l.maxSize)
+	}
+	return data, nil
+}
+
+// readFile reads the file part, the content beyond the max memory is spilled to a temp file.
+func (l *bodyLimits) readFile(part *multipart.Part) (*UploadedFile, error) {
+	file := &UploadedFile{
+		Filename:    part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+		Header:      part.Header,
+	}
+	r := io.Reader(part)
+	if l.maxSize > 0 {
+		r = io.LimitReader(part, l.maxSize+1)
+	}
+	var buf bytes.Buffer
+	if l.maxMemory < 0 {
+		if _, err := buf.ReadFrom(r); err != nil {
+			return nil, err
+		}
+	} else if n, err := io.CopyN(&buf, r, l.maxMemory+1); err != nil && err != io.EOF {
+		return nil, err
+	} else if n > l.maxMemory {
+		tmp, err := ioutil.TempFile("", "kratos-multipart-")
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		l.paths = append(l.paths, tmp.Name())
+		l.mu.Unlock()
+		size, err := io.Copy(tmp, io.MultiReader(&buf, r))
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		file.path, file.Size = tmp.Name(), size
+	}
+	if file.path == "" {
+		file.data, file.Size = buf.Bytes(), int64(buf.Len())
+	}
+	if l.maxSize > 0 && file.Size > l.maxSize {
+		return nil, errTooLarge(l.maxSize)
+	}
+	return file, nil
+}
+
+// limitedReader fails the reads beyond the limit with a request entity too large error.
+type limitedReader struct {
+	io.ReadCloser
+	n     int64
+	limit int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		var b [1]byte
+		if n, _ := r.ReadCloser.Read(b[:]); n > 0 {
+			return 0, errTooLarge(r.limit)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.n -= int64(n)
+	return n, err
+}
+
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// decodeMultipart binds a multipart request into the struct v: the file parts into the
+// *UploadedFile and []*UploadedFile fields, the other parts into the struct and map fields
+// by the codec of the part, json by default, and the rest of the values by the form codec.
+// The fields are matched by the json tags, the file parts without a file field are discarded.
+func decodeMultipart(req *http.Request, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.Newf(http.StatusUnsupportedMediaType, UnsupportedMediaTypeReason, "unsupported media type: multipart to %T", v)
+	}
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return errors.InvalidArgument("Multipart", "invalid multipart request: %v", err)
+	}
+	limits := bodyLimitsFromContext(req.Context())
+	values := make(url.Values)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+		field, ok := multipartField(rv.Elem(), name)
+		if part.FileName() != "" {
+			// the file parts without a file field are discarded unread.
+			if !ok || (field.Type() != uploadedFileType && field.Type() != uploadedFilesType) {
+				part.Close()
+				continue
+			}
+			file, err := limits.readFile(part)
+			if err != nil {
+				return err
+			}
+			if field.Type() == uploadedFileType {
+				field.Set(reflect.ValueOf(file))
+			} else {
+				field.Set(reflect.Append(field, reflect.ValueOf(file)))
+			}
+			continue
+		}
+		data, err := limits.read(part)
+		if err != nil {
+			return err
+		}
+		if ok && isDocument(field.Type()) {
+			if err = unmarshalPart(part, data, field); err != nil {
+				return err
+			}
+			continue
+		}
+		values.Add(name, string(data))
+	}
+	return form.DecodeValues(v, values)
+}
+
+// isDocument reports whether the field is decoded from a document part, i.e., json metadata.
+func isDocument(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return (t.Kind() == reflect.Struct && t != timeType) || t.Kind() == reflect.Map
+}
+
+func unmarshalPart(part *multipart.Part, data []byte, field reflect.Value) error {
+	codec := encoding.GetCodec(ContentSubtype(part.Header.Get("Content-Type")))
+	if codec == nil {
+		codec = encoding.GetCodec("json")
+	}
+	if codec == nil {
+		return errors.InvalidArgument("Codec", "not found codec: json")
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return codec.Unmarshal(data, field.Interface())
+	}
+	return codec.Unmarshal(data, field.Addr().Interface())
+}
+
+// multipartField returns the exported field of the struct by its json name or field name.
+func multipartField(rv reflect.Value, name string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag == "-" {
+			continue
+		}
+		if tag == name || (tag == "" && strings.EqualFold(f.Name, name)) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
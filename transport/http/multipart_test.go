@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+)
+
+type upload struct {
+	Name     string `json:"name"`
+	Metadata *struct {
+		Tags []string `json:"tags"`
+	} `json:"metadata"`
+	Files []*UploadedFile `json:"files"`
+}
+
+func newUpload(t *testing.T, content string) (*bytes.Buffer, string) {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	w.WriteField("name", "kratos")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="metadata"`)
+	h.Set("Content-Type", "application/json")
+	part, _ := w.CreatePart(h)
+	part.Write([]byte(`{"tags":["a","b"]}`))
+	for _, filename := range []string{"small.txt", "large.txt"} {
+		part, _ := w.CreateFormFile("files", filename)
+		part.Write([]byte(content + filename))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, w.FormDataContentType()
+}
+
+func TestMultipart(t *testing.T) {
+	var spilled string
+	srv := NewServer(MaxRequestBodySize(1024), MultipartMemory(16))
+	srv.HandleFunc("/upload", func(res http.ResponseWriter, req *http.Request) {
+		var in upload
+		if err := srv.Decode(req, &in); err != nil {
+			srv.Error(res, req, err)
+			return
+		}
+		if in.Name != "kratos" || in.Metadata == nil || len(in.Metadata.Tags) != 2 || len(in.Files) != 2 {
+			t.Errorf("unexpected binding %+v", in)
+		}
+		for _, f := range in.Files {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := ioutil.ReadAll(r)
+			r.Close()
+			if !strings.HasSuffix(string(data), f.Filename) || f.Size != int64(len(data)) {
+				t.Errorf("unexpected file %s: %s", f.Filename, data)
+			}
+			if f.path != "" {
+				spilled = f.path
+			}
+		}
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	body, contentType := newUpload(t, "content of ")
+	res, err := http.Post(ts.URL+"/upload", contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, but got %d", res.StatusCode)
+	}
+	if spilled == "" {
+		t.Fatalf("expected the large file spilled to a temp file")
+	}
+	if _, err := os.Stat(spilled); !os.IsNotExist(err) {
+		t.Errorf("expected the temp file removed after the handler, but got %v", err)
+	}
+
+	body, contentType = newUpload(t, strings.Repeat("x", 2048))
+	res, err = http.Post(ts.URL+"/upload", contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, but got %d", res.StatusCode)
+	}
+}
+
+func TestMultipartUnknownFile(t *testing.T) {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	w.WriteField("name", "kratos")
+	for _, name := range []string{"avatar", "name"} {
+		part, _ := w.CreateFormFile(name, name+".txt")
+		part.Write([]byte(strings.Repeat("x", 1024)))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	limits := &bodyLimits{maxSize: 2048, maxMemory: 16}
+	req = req.WithContext(newBodyLimitsContext(req.Context(), limits))
+	var in upload
+	if err := decodeMultipart(req, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Name != "kratos" || len(in.Files) != 0 {
+		t.Errorf("unexpected binding %+v", in)
+	}
+	if len(limits.paths) != 0 {
+		t.Errorf("expected the unknown file parts discarded, but got the temp files %v", limits.paths)
+		limits.removeAll()
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// DiscoveryScheme is the scheme of the request URLs resolved by the discovery, whose host
+// is the service name, i.e., discovery://helloworld/v1/greeter.
+const DiscoveryScheme = "discovery"
+
+// resolver resolves the service names into the hosts of the instances, which are watched
+// in the background once the service is requested.
+type resolver struct {
+	discovery registry.Discovery
+	builder   selector.Builder
+
+	mu       sync.Mutex
+	services map[string]*service
+}
+
+func newResolver(d registry.Discovery, b selector.Builder) *resolver {
+	return &resolver{discovery: d, builder: b, services: make(map[string]*service)}
+}
+
+// service is the watched endpoints of a service, which are applied to its selector.
+type service struct {
+	ready chan struct{}
+	once  sync.Once
+	err   error
+
+	mu       sync.RWMutex
+	selector selector.Selector
+	applied  bool
+}
+
+// resolve returns the node of an instance of the service with its done func, it waits for
+// the first snapshot of the instances until ctx is done.
+func (r *resolver) resolve(ctx context.Context, name string, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	r.mu.Lock()
+	s, ok := r.services[name]
+	if !ok {
+		s = &service{ready: make(chan struct{}), selector: r.builder.Build()}
+		r.services[name] = s
+		go r.watch(name, s)
+	}
+	r.mu.Unlock()
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	s.mu.RLock()
+	applied, err := s.applied, s.err
+	s.mu.RUnlock()
+	if applied {
+		if n, done, err := s.selector.Select(ctx, opts...); err == nil {
+			return n, done, nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, errors.ServiceUnavailable("NO_INSTANCE", "no http endpoint found for service %s", name)
+}
+
+func (r *resolver) watch(name string, s *service) {
+	w, err := r.discovery.Watch(context.Background(), name)
+	if err != nil {
+		r.mu.Lock()
+		// the next request watches again.
+		delete(r.services, name)
+		r.mu.Unlock()
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		s.once.Do(func() { close(s.ready) })
+		return
+	}
+	for {
+		ins, err := w.Next()
+		if err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.once.Do(func() { close(s.ready) })
+			time.Sleep(time.Second)
+			continue
+		}
+		nodes := make([]selector.Node, 0, len(ins))
+		for _, in := range ins {
+			for _, scheme := range []string{"http", "https"} {
+				host, err := registry.ParseEndpoint(in.Endpoints, "http", scheme == "https")
+				if err == nil && host != "" {
+					nodes = append(nodes, selector.NewNode(scheme, host, in))
+					break
+				}
+			}
+		}
+		s.selector.Apply(nodes)
+		s.mu.Lock()
+		s.applied, s.err = true, nil
+		s.mu.Unlock()
+		s.once.Do(func() { close(s.ready) })
+	}
+}
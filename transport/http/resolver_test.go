@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/chash"
+	"github.com/go-kratos/kratos/v2/selector/filter"
+	"github.com/go-kratos/kratos/v2/selector/p2c"
+	"github.com/go-kratos/kratos/v2/selector/random"
+)
+
+func TestDiscovery(t *testing.T) {
+	hits := make(map[string]int)
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			hits[name]++
+			res.Write([]byte(req.URL.Path))
+		}))
+	}
+	a, b := newServer("a"), newServer("b")
+	defer a.Close()
+	defer b.Close()
+	r := memory.New()
+	for _, in := range []*registry.ServiceInstance{
+		{ID: "a", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000", a.URL}},
+		{ID: "b", Name: "helloworld", Endpoints: []string{b.URL}},
+		{ID: "c", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9001"}},
+	} {
+		r.Register(context.Background(), in)
+	}
+	client, _ := NewClient(WithDiscovery(r))
+	for i := 0; i < 4; i++ {
+		res, err := client.Get("discovery://helloworld/v1/greeter")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "/v1/greeter" {
+			t.Errorf("expected /v1/greeter, but got %s", body)
+		}
+	}
+	if hits["a"] != 2 || hits["b"] != 2 {
+		t.Errorf("expected the instances picked in round robin, but got %v", hits)
+	}
+	if _, err := client.Get("discovery://unknown/"); err == nil {
+		t.Error("expected error without http endpoints")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	hits := make(chan string, 20)
+	r := memory.New()
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits <- name }))
+		defer srv.Close()
+		r.Register(context.Background(), &registry.ServiceInstance{ID: name, Name: "helloworld", Endpoints: []string{srv.URL}})
+	}
+	client, _ := NewClient(WithDiscovery(r), WithSelector(chash.NewBuilder()))
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "discovery://helloworld/", nil)
+		res, err := client.Do(WithCallOptions(req, HashKey("user")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	close(hits)
+	first := <-hits
+	for name := range hits {
+		if name != first {
+			t.Fatalf("expected the requests of the key routed to %s, but got %s", first, name)
+		}
+	}
+}
+
+func TestClientFilter(t *testing.T) {
+	hits := make(chan string, 20)
+	r := memory.New()
+	for _, v := range []string{"v1.0.0", "v2.0.0", "v2.1.0"} {
+		v := v
+		srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits <- v }))
+		defer srv.Close()
+		r.Register(context.Background(), &registry.ServiceInstance{ID: v, Name: "helloworld", Version: v, Endpoints: []string{srv.URL}})
+	}
+	client, _ := NewClient(WithDiscovery(r), WithFilter(filter.Version("v2.*")))
+	ctx := selector.NewFilterContext(context.Background(), filter.Version(">=2.1"))
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "discovery://helloworld/", nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if v := <-hits; v == "v1.0.0" {
+			t.Fatalf("expected the nodes of the client filters, but got %s", v)
+		}
+		res, err = client.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if v := <-hits; v != "v2.1.0" {
+			t.Fatalf("expected the nodes of the request filters, but got %s", v)
+		}
+	}
+}
+
+// doneSelector records the done infos of the selector.
+type doneSelector struct {
+	selector.Selector
+	done chan selector.DoneInfo
+}
+
+func (s *doneSelector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	n, done, err := s.Selector.Select(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return n, func(ctx context.Context, di selector.DoneInfo) {
+		done(ctx, di)
+		s.done <- di
+	}, nil
+}
+
+func TestDoneError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		DefaultErrorEncoder(res, req, errors.ServiceUnavailable("Overloaded", "server overloaded"))
+	}))
+	defer srv.Close()
+	r := memory.New()
+	r.Register(context.Background(), &registry.ServiceInstance{ID: "a", Name: "helloworld", Endpoints: []string{srv.URL}})
+	s := &doneSelector{Selector: random.New(), done: make(chan selector.DoneInfo, 1)}
+	client, _ := NewClient(WithDiscovery(r), WithSelector(selector.BuilderFunc(func() selector.Selector { return s })))
+	res, err := client.Get("discovery://helloworld/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if di := <-s.done; errors.Reason(di.Err) != "Overloaded" || errors.Code(di.Err) != 503 {
+		t.Errorf("expected the decoded error reported to the selector, but got %v", di.Err)
+	}
+	if err := CheckResponse(res); errors.Reason(err) != "Overloaded" {
+		t.Errorf("expected the response still readable, but got %v", err)
+	}
+}
+
+func TestP2CFailures(t *testing.T) {
+	hits := make(map[string]int)
+	var mu sync.Mutex
+	r := memory.New()
+	for _, name := range []string{"failing", "slow"} {
+		name := name
+		srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			hits[name]++
+			mu.Unlock()
+			if name == "failing" {
+				// the failing node answers at once, it would win on the latency without the errors.
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}))
+		defer srv.Close()
+		r.Register(context.Background(), &registry.ServiceInstance{ID: name, Name: "helloworld", Endpoints: []string{srv.URL}})
+	}
+	client, _ := NewClient(WithDiscovery(r), WithSelector(p2c.NewBuilder()))
+	for i := 0; i < 40; i++ {
+		res, err := client.Get("discovery://helloworld/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if hits["failing"] > 1 {
+		t.Errorf("expected the failing node routed around after the 5xx, but got %v", hits)
+	}
+}
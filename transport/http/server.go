@@ -2,13 +2,19 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/log/stdlog"
+	"github.com/go-kratos/kratos/v2/metadata"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"github.com/gorilla/mux"
@@ -17,7 +23,10 @@ import (
 // SupportPackageIsVersion1 These constants should not be referenced from any other code.
 const SupportPackageIsVersion1 = true
 
-var _ transport.Server = (*Server)(nil)
+var (
+	_ transport.Server     = (*Server)(nil)
+	_ transport.Endpointer = (*Server)(nil)
+)
 
 // DecodeRequestFunc is decode request func.
 type DecodeRequestFunc func(req *http.Request, v interface{}) error
@@ -39,7 +48,17 @@ type serverOptions struct {
 	requestDecoder  DecodeRequestFunc
 	responseEncoder EncodeResponseFunc
 	errorEncoder    EncodeErrorFunc
+	negotiator      negotiator
+	maxBodySize     int64
+	maxMemory       int64
 	logger          log.Logger
+	mdPrefixes      []string
+	handlers        []route
+}
+
+type route struct {
+	path    string
+	handler http.Handler
 }
 
 // Network with server network.
@@ -84,41 +103,128 @@ func ErrorEncoder(fn EncodeErrorFunc) ServerOption {
 	}
 }
 
-// Logger with server logger.
+// DefaultCodec with the codec name pinned for the responses regardless of the accept header,
+// and for the requests of unknown content types.
+func DefaultCodec(name string) ServerOption {
+	return func(s *serverOptions) {
+		s.negotiator.codec = strings.ToLower(name)
+		s.negotiator.instance = nil
+	}
+}
+
+// Codec with the codec instance pinned as DefaultCodec, i.e., a json codec with options,
+// which is not required to be registered.
+func Codec(c encoding.Codec) ServerOption {
+	return func(s *serverOptions) {
+		s.negotiator.codec = strings.ToLower(c.Name())
+		s.negotiator.instance = c
+	}
+}
+
+// StrictContentType with the requests rejected by 415 unless the content type matches the
+// pinned codec, or any registered codec if not pinned. A request body without content type
+// is rejected in strict mode, a request without body is decoded by the pinned codec.
+// The multipart bodies are rejected by 415 too, since they aren't decoded by a codec.
+func StrictContentType(strict bool) ServerOption {
+	return func(s *serverOptions) {
+		s.negotiator.strict = strict
+	}
+}
+
+// DebugErrors with the cause chain and the stack trace of the errors encoded in the debug
+// field of the responses, and the internal messages of the unknown errors kept, which must
+// be disabled in production, disabled by default.
+func DebugErrors(debug bool) ServerOption {
+	return func(s *serverOptions) {
+		s.negotiator.debug = debug
+	}
+}
+
+// MaxRequestBodySize with the max size of the request bodies, and of each multipart part,
+// the requests beyond it fail with 413, unlimited by default.
+func MaxRequestBodySize(n int64) ServerOption {
+	return func(s *serverOptions) {
+		s.maxBodySize = n
+	}
+}
+
+// MultipartMemory with the max size of an uploaded file held in memory, the files beyond it
+// are spilled to temp files removed after the handler returns, 10MB by default.
+func MultipartMemory(n int64) ServerOption {
+	return func(s *serverOptions) {
+		s.maxMemory = n
+	}
+}
+
+// Logger with server logger, the global logger is used by default.
 func Logger(logger log.Logger) ServerOption {
 	return func(s *serverOptions) {
 		s.logger = logger
 	}
 }
 
+// MetadataPrefixes with the prefixes of the headers extracted into the server metadata, see
+// metadata.FromServerContext, x-md-global- and x-md-local- by default. None are extracted if
+// empty, i.e., the edge servers never trust the metadata of the external callers.
+func MetadataPrefixes(prefixes ...string) ServerOption {
+	return func(s *serverOptions) {
+		s.mdPrefixes = prefixes
+	}
+}
+
 // Server is a HTTP server wrapper.
 type Server struct {
 	*http.Server
-	router *mux.Router
-	opts   serverOptions
-	log    *log.Helper
+	router   *mux.Router
+	opts     serverOptions
+	log      *log.Helper
+	endpoint string
+
+	once      sync.Once
+	lis       net.Listener
+	err       error
+	inherited []net.Listener
+	// baseCtx is the context of Start, whose values the request contexts carry.
+	baseCtx context.Context
 }
 
 // NewServer creates a HTTP server by options.
 func NewServer(opts ...ServerOption) *Server {
 	options := serverOptions{
-		network:         "tcp",
-		address:         ":8000",
-		timeout:         time.Second,
-		requestDecoder:  DefaultRequestDecoder,
-		responseEncoder: DefaultResponseEncoder,
-		errorEncoder:    DefaultErrorEncoder,
-		logger:          stdlog.NewLogger(),
+		network:    "tcp",
+		address:    ":8000",
+		timeout:    time.Second,
+		maxMemory:  defaultMultipartMemory,
+		mdPrefixes: metadata.DefaultPrefixes(),
 	}
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.requestDecoder == nil {
+		options.requestDecoder = options.negotiator.decodeRequest
+	}
+	if options.responseEncoder == nil {
+		options.responseEncoder = options.negotiator.encodeResponse
+	}
+	if options.errorEncoder == nil {
+		options.errorEncoder = options.negotiator.encodeError
+	}
+	if options.logger == nil {
+		options.logger = log.GetLogger()
+	}
 	srv := &Server{
 		opts:   options,
 		router: mux.NewRouter(),
 		log:    log.NewHelper("http", options.logger),
 	}
-	srv.Server = &http.Server{Handler: srv}
+	for _, r := range options.handlers {
+		srv.router.Handle(r.path, r.handler)
+	}
+	srv.router.Use(srv.filter)
+	srv.Server = &http.Server{
+		Handler:  srv,
+		ErrorLog: log.NewStdlibAdapter(log.With(options.logger, "module", "http"), log.LevelError),
+	}
 	return srv
 }
 
@@ -150,6 +256,11 @@ func (s *Server) Decode(req *http.Request, v interface{}) error {
 // Encode .
 func (s *Server) Encode(res http.ResponseWriter, req *http.Request, v interface{}) {
 	if err := s.opts.responseEncoder(res, req, v); err != nil {
+		s.log.WithContext(req.Context()).Errorf("[HTTP] failed to encode response: %v", err)
+		if se := new(streamError); errors.As(err, &se) {
+			// the partially written stream just ends.
+			return
+		}
 		s.Error(res, req, err)
 	}
 }
@@ -164,25 +275,133 @@ func (s *Server) Invoke(ctx context.Context, req interface{}, h middleware.Handl
 
 // ServeHTTP should write reply headers and data to the ResponseWriter and then return.
 func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	ctx, cancel := context.WithTimeout(req.Context(), s.opts.timeout)
-	defer cancel()
-	ctx = transport.NewContext(ctx, transport.Transport{Kind: "HTTP"})
-	ctx = NewContext(ctx, ServerInfo{Request: req, Response: res})
-	s.router.ServeHTTP(res, req.WithContext(ctx))
+	s.router.ServeHTTP(res, req)
 }
 
-// Start start the HTTP server.
+// filter injects the transport context once the route has been matched,
+// so that the operation is the route template rather than the raw path.
+func (s *Server) filter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if s.baseCtx != nil {
+			ctx = valueContext{Context: ctx, values: s.baseCtx}
+		}
+		ctx = newStreamContext(ctx, ctx)
+		ctx, cancel := context.WithTimeout(ctx, s.opts.timeout)
+		defer cancel()
+		operation := req.URL.Path
+		if route := mux.CurrentRoute(req); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				operation = tpl
+			}
+		}
+		stats := new(transport.Stats)
+		defer func() {
+			if n := req.ContentLength - stats.RequestBytes(); n > 0 {
+				// count the unread payload as well, i.e., when the handler fails early.
+				stats.AddRequestBytes(n)
+			}
+			stats.Finish()
+		}()
+		limits := &bodyLimits{maxSize: s.opts.maxBodySize, maxMemory: s.opts.maxMemory}
+		defer limits.removeAll()
+		if req.Body != nil && req.Body != http.NoBody {
+			if limits.maxSize > 0 {
+				req.Body = &limitedReader{ReadCloser: req.Body, n: limits.maxSize, limit: limits.maxSize}
+			}
+			req.Body = &countingReader{ReadCloser: req.Body, stats: stats}
+		}
+		res = &countingWriter{ResponseWriter: res, stats: stats}
+		ctx = transport.NewContext(ctx, transport.Transport{
+			Kind:        "HTTP",
+			Endpoint:    s.endpoint,
+			Operation:   operation,
+			Header:      headerCarrier(req.Header),
+			ReplyHeader: headerCarrier(res.Header()),
+		})
+		ctx = metadata.NewServerContext(ctx, metadata.Extract(req.Header, s.opts.mdPrefixes))
+		ctx = NewContext(ctx, ServerInfo{Request: req, Response: res, Stats: stats})
+		ctx = newBodyLimitsContext(ctx, limits)
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// Listener returns the listener of the server, nil if not listening.
+func (s *Server) Listener() net.Listener {
+	return s.lis
+}
+
+// Inherit with the listeners of the servers replaced on a reload, the server listens on a
+// duplicate of the listener of its address if any.
+func (s *Server) Inherit(listeners []net.Listener) {
+	s.inherited = listeners
+}
+
+// Endpoint listens if not yet, and returns the endpoint of the HTTP server.
+func (s *Server) Endpoint() (string, error) {
+	s.once.Do(func() {
+		var ok bool
+		if s.lis, ok, s.err = transport.InheritListener(s.opts.network, s.opts.address, s.inherited); !ok && s.err == nil {
+			s.lis, s.err = net.Listen(s.opts.network, s.opts.address)
+		}
+		if s.err != nil {
+			return
+		}
+		var addr string
+		if addr, s.err = host.Extract(s.lis.Addr().String()); s.err != nil {
+			s.lis.Close()
+			return
+		}
+		s.endpoint = registry.NewEndpoint("http", addr, false)
+		s.log.Infof("[HTTP] server listening on: %s", s.lis.Addr().String())
+	})
+	return s.endpoint, s.err
+}
+
+// Start start the HTTP server, it returns nil once the server is stopped gracefully, and
+// the server is shut down gracefully once ctx is done. The request contexts carry the
+// values of ctx, i.e., the app info, but never its cancellation.
 func (s *Server) Start(ctx context.Context) error {
-	lis, err := net.Listen(s.opts.network, s.opts.address)
-	if err != nil {
+	if _, err := s.Endpoint(); err != nil {
+		return err
+	}
+	s.baseCtx = ctx
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.log.Info("[HTTP] server shutting down on the context done")
+			s.Shutdown(context.Background())
+		case <-done:
+		}
+	}()
+	if err := s.Serve(s.lis); err != http.ErrServerClosed {
 		return err
 	}
-	s.log.Infof("[HTTP] server listening on: %s", s.opts.address)
-	return s.Serve(lis)
+	return nil
 }
 
 // Stop stop the HTTP server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.log.Info("[HTTP] server stopping")
-	return s.Shutdown(ctx)
+	err := s.Shutdown(ctx)
+	if s.lis != nil {
+		// the listener is closed by Shutdown only if served.
+		s.lis.Close()
+	}
+	return err
+}
+
+// valueContext is the request context, which falls back to the values of the base context.
+type valueContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valueContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.values.Value(key)
 }
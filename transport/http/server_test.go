@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metadata"
+)
+
+type testKey struct{}
+
+func TestServerStartContext(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"), Logger(log.NewRecorder()))
+	srv.HandleFunc("/value", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			w.Write([]byte("canceled"))
+		default:
+			w.Write([]byte(r.Context().Value(testKey{}).(string)))
+		}
+	})
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), testKey{}, "base"))
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Start(ctx)
+	}()
+	res, err := http.Get(endpoint + "/value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "base" {
+		t.Errorf("expected the values of the start context, but got %s", body)
+	}
+
+	// the server is shut down gracefully once the context is done.
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the server shut down")
+	}
+}
+
+func TestServerMetadata(t *testing.T) {
+	for _, tc := range []struct {
+		opts []ServerOption
+		want string
+	}{
+		{nil, "用户/a"},
+		{[]ServerOption{MetadataPrefixes()}, "/"},
+	} {
+		srv := NewServer(append(tc.opts, Address("127.0.0.1:0"), Logger(log.NewRecorder()))...)
+		srv.HandleFunc("/md", func(w http.ResponseWriter, r *http.Request) {
+			md, _ := metadata.FromServerContext(r.Context())
+			w.Write([]byte(md.Get("x-md-global-caller") + "/" + md.Get("X-Md-Local-Tenant")))
+		})
+		endpoint, err := srv.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Start(context.Background())
+		req, _ := http.NewRequest(http.MethodGet, endpoint+"/md", nil)
+		req.Header.Set("X-Md-Global-Caller", "%E7%94%A8%E6%88%B7")
+		req.Header.Set("x-md-local-tenant", "a")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		srv.Stop(context.Background())
+		if string(body) != tc.want {
+			t.Errorf("expected %s, but got %s", tc.want, body)
+		}
+	}
+}
+
+func TestServerEndpointUnspecified(t *testing.T) {
+	srv := NewServer(Address(":0"), Logger(log.NewRecorder()))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Listener().Close()
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip == nil || ip.IsUnspecified() || u.Port() == "0" {
+		t.Errorf("expected the endpoint of a dialable host, but got %s", endpoint)
+	}
+}
+
+func TestServerHijack(t *testing.T) {
+	endpoint := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhijacked")
+		rw.Flush()
+	})
+	u, _ := url.Parse(endpoint)
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: " + u.Host + "\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n"))
+	data, _ := ioutil.ReadAll(conn)
+	if !strings.HasPrefix(string(data), "HTTP/1.1 101") || !strings.HasSuffix(string(data), "hijacked") {
+		t.Errorf("expected the hijacked connection, but got %q", data)
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type countingReader struct {
+	io.ReadCloser
+	stats *transport.Stats
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.stats.AddRequestBytes(int64(n))
+	return n, err
+}
+
+var (
+	_ http.Flusher  = (*countingWriter)(nil)
+	_ http.Hijacker = (*countingWriter)(nil)
+	_ http.Pusher   = (*countingWriter)(nil)
+)
+
+// countingWriter counts the response bytes, the optional interfaces of the underlying
+// writer are forwarded, i.e., for the streams and the websocket upgrades.
+type countingWriter struct {
+	http.ResponseWriter
+	stats *transport.Stats
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.stats.AddResponseBytes(int64(n))
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer supports it.
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer supports it,
+// the bytes of the hijacked connection aren't counted.
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Push implements http.Pusher if the underlying writer supports it.
+func (w *countingWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// StreamContentType is the content type of the streaming responses.
+const StreamContentType = "application/x-ndjson"
+
+type streamContextKey struct{}
+
+// newStreamContext returns a context carrying the context of the connection, which the
+// streams run in instead of the context bounded by the handler timeout.
+func newStreamContext(ctx, conn context.Context) context.Context {
+	return context.WithValue(ctx, streamContextKey{}, conn)
+}
+
+// streamContext returns the context of the connection, the one of req without a server.
+func streamContext(req *http.Request) context.Context {
+	if ctx, ok := req.Context().Value(streamContextKey{}).(context.Context); ok {
+		return ctx
+	}
+	return req.Context()
+}
+
+// streamError is the error of a stream which has been partially written, the response
+// can't carry the error any more, so that the stream just ends.
+type streamError struct {
+	err error
+}
+
+func (e *streamError) Error() string { return fmt.Sprintf("stream aborted: %v", e.err) }
+func (e *streamError) Unwrap() error { return e.err }
+
+// StreamFunc yields the items of a streaming response, it stops once yield returns an error.
+type StreamFunc func(yield func(v interface{}) error) error
+
+// isStream reports whether the response is a receive channel or a StreamFunc.
+func isStream(v interface{}) bool {
+	if _, ok := v.(StreamFunc); ok {
+		return true
+	}
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0
+}
+
+// encodeStream writes the items one JSON document per line, and flushes every item until
+// the stream ends or the connection is closed. The stream isn't bounded by the handler
+// timeout of the server, and ends without an error body once an item has been written.
+func encodeStream(res http.ResponseWriter, req *http.Request, v interface{}) error {
+	codec := encoding.GetCodec("json")
+	if codec == nil {
+		return errors.InvalidArgument("Codec", "not found codec: json")
+	}
+	ctx := streamContext(req)
+	res.Header().Set("content-type", StreamContentType)
+	flusher, _ := res.(http.Flusher)
+	var written bool
+	yield := func(item interface{}) error {
+		data, err := codec.Marshal(item)
+		if err != nil {
+			return err
+		}
+		written = true
+		if _, err = res.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	err := func() error {
+		if fn, ok := v.(StreamFunc); ok {
+			return fn(func(item interface{}) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return yield(item)
+			})
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(v)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := yield(item.Interface()); err != nil {
+				return err
+			}
+		}
+	}()
+	if err == nil || ctx.Err() != nil {
+		// the client has gone away.
+		return nil
+	}
+	if written {
+		return &streamError{err: err}
+	}
+	return err
+}
+
+// DecodeStream decodes the newline-delimited JSON items of res as they arrive,
+// fn is called per item with the decode func of the item. A truncated stream
+// ends with io.ErrUnexpectedEOF, and the body is closed if ctx is canceled.
+func DecodeStream(ctx context.Context, res *http.Response, fn func(decode func(v interface{}) error) error) error {
+	defer res.Body.Close()
+	codec := encoding.GetCodec("json")
+	if codec == nil {
+		return errors.InvalidArgument("Codec", "not found codec: json")
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			res.Body.Close()
+		case <-done:
+		}
+	}()
+	r := bufio.NewReader(res.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				if len(bytes.TrimSpace(line)) > 0 {
+					return fmt.Errorf("truncated stream item %q: %w", line, io.ErrUnexpectedEOF)
+				}
+				return nil
+			}
+			return err
+		}
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if err = fn(func(v interface{}) error {
+			return codec.Unmarshal(line, v)
+		}); err != nil {
+			return err
+		}
+	}
+}
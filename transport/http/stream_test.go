@@ -0,0 +1,191 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	srv := NewServer()
+	srv.HandleFunc("/users", func(res http.ResponseWriter, req *http.Request) {
+		users := make(chan *user)
+		go func() {
+			defer close(users)
+			for i := 0; i < 250; i++ {
+				users <- &user{Name: "kratos"}
+			}
+		}()
+		srv.Encode(res, req, users)
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Header.Get("Content-Type") != StreamContentType {
+		t.Errorf("expected %s, but got %s", StreamContentType, res.Header.Get("Content-Type"))
+	}
+	var n int
+	err = DecodeStream(context.Background(), res, func(decode func(interface{}) error) error {
+		var u user
+		if err := decode(&u); err != nil {
+			return err
+		}
+		if u.Name == "kratos" {
+			n++
+		}
+		return nil
+	})
+	if err != nil || n != 250 {
+		t.Errorf("expected 250 items, but got %d %v", n, err)
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", StreamContentType)
+		res.Write([]byte(`{"name":"kratos"}` + "\n" + `{"name":`))
+	}))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = DecodeStream(context.Background(), res, func(decode func(interface{}) error) error {
+		n++
+		return decode(&user{})
+	})
+	if n != 1 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected the truncated tail reported, but got %d items %v", n, err)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", StreamContentType)
+		res.Write([]byte(`{"name":"kratos"}` + "\n"))
+		res.(http.Flusher).Flush()
+		<-block
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	err = DecodeStream(ctx, res, func(decode func(interface{}) error) error {
+		cancel()
+		return decode(&user{})
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled, but got %v", err)
+	}
+}
+
+func TestStreamClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", StreamContentType)
+		for i := 0; i < 3; i++ {
+			res.Write([]byte(`{"name":"kratos"}` + "\n"))
+			res.(http.Flusher).Flush()
+			// the stream outlives the timeout of the client.
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := NewClient(WithTimeout(100 * time.Millisecond))
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = DecodeStream(context.Background(), res, func(decode func(interface{}) error) error {
+		n++
+		return decode(&user{})
+	})
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 items, but got %d %v", n, err)
+	}
+}
+
+func TestStreamServerTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.opts.timeout = 50 * time.Millisecond
+	next := make(chan struct{})
+	srv.HandleFunc("/users", func(res http.ResponseWriter, req *http.Request) {
+		srv.Encode(res, req, StreamFunc(func(yield func(v interface{}) error) error {
+			for i := 0; i < 3; i++ {
+				if err := yield(&user{Name: "kratos"}); err != nil {
+					return err
+				}
+				// the next item is produced after the client has received this one,
+				// so that the items must be flushed one by one.
+				select {
+				case <-next:
+				case <-time.After(time.Second):
+					return errors.New("item not received")
+				}
+				// the stream outlives the handler timeout.
+				time.Sleep(40 * time.Millisecond)
+			}
+			return nil
+		}))
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = DecodeStream(context.Background(), res, func(decode func(interface{}) error) error {
+		n++
+		next <- struct{}{}
+		return decode(&user{})
+	})
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 items, but got %d %v", n, err)
+	}
+}
+
+func TestStreamAborted(t *testing.T) {
+	srv := NewServer()
+	srv.HandleFunc("/users", func(res http.ResponseWriter, req *http.Request) {
+		srv.Encode(res, req, StreamFunc(func(yield func(v interface{}) error) error {
+			if err := yield(&user{Name: "kratos"}); err != nil {
+				return err
+			}
+			return errors.New("database down")
+		}))
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || string(data) != `{"name":"kratos"}`+"\n" {
+		t.Errorf("expected the stream ended after the written item, but got %d %q", res.StatusCode, data)
+	}
+}
@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// Inheritor is implemented by the servers which inherit the listeners of the servers they
+// replace on a reload, so that the address is never unbound in between, i.e., rotating the
+// certificates on the same address.
+type Inheritor interface {
+	// Listener returns the listener of the server, nil if not listening.
+	Listener() net.Listener
+	// Inherit with the listeners of the servers replaced, which is called before Endpoint,
+	// the server listens on a duplicate of the listener of its address if any, see
+	// InheritListener.
+	Inherit(listeners []net.Listener)
+}
+
+// InheritListener returns a duplicate of the listener of the address among the listeners,
+// which accepts the connections of the same socket, and is closed independently, so that
+// the replaced server closes its listener as usual. It returns false if there is none, the
+// addresses of the port 0 never match, and the listeners unable to be duplicated are skipped,
+// i.e., on windows.
+func InheritListener(network, address string, listeners []net.Listener) (net.Listener, bool, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || port == "0" {
+		return nil, false, nil
+	}
+	for _, lis := range listeners {
+		addr := lis.Addr()
+		// tcp4 and tcp6 listen as tcp.
+		if strings.TrimRight(addr.Network(), "46") != strings.TrimRight(network, "46") {
+			continue
+		}
+		h, p, err := net.SplitHostPort(addr.String())
+		if err != nil || p != port || !sameHost(host, h) {
+			continue
+		}
+		f, ok := lis.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		file, err := f.File()
+		if err != nil {
+			continue
+		}
+		dup, err := net.FileListener(file)
+		file.Close()
+		return dup, err == nil, err
+	}
+	return nil, false, nil
+}
+
+// sameHost reports whether the host of an address is the host of a listener, the empty and
+// the unspecified hosts listen on all the addresses.
+func sameHost(host, listened string) bool {
+	if host == listened {
+		return true
+	}
+	ip, lip := net.ParseIP(host), net.ParseIP(listened)
+	if host == "" || (ip != nil && ip.IsUnspecified()) {
+		return lip != nil && lip.IsUnspecified()
+	}
+	return ip != nil && lip != nil && ip.Equal(lip)
+}
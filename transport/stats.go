@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is the byte counts of a server request, which are updated by the transport.
+type Stats struct {
+	requestBytes  int64
+	responseBytes int64
+
+	mu       sync.Mutex
+	finished bool
+	hooks    []func(*Stats)
+}
+
+// RequestBytes returns the bytes read from the request payload.
+func (s *Stats) RequestBytes() int64 {
+	return atomic.LoadInt64(&s.requestBytes)
+}
+
+// ResponseBytes returns the bytes written to the response payload.
+func (s *Stats) ResponseBytes() int64 {
+	return atomic.LoadInt64(&s.responseBytes)
+}
+
+// AddRequestBytes adds n to the request bytes.
+func (s *Stats) AddRequestBytes(n int64) {
+	atomic.AddInt64(&s.requestBytes, n)
+}
+
+// AddResponseBytes adds n to the response bytes.
+func (s *Stats) AddResponseBytes(n int64) {
+	atomic.AddInt64(&s.responseBytes, n)
+}
+
+// OnFinish registers fn to be called once the response has been written,
+// fn is called immediately if the request has already finished.
+func (s *Stats) OnFinish(fn func(*Stats)) {
+	s.mu.Lock()
+	if !s.finished {
+		s.hooks = append(s.hooks, fn)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	fn(s)
+}
+
+// Finish marks the request as finished and calls the registered hooks.
+func (s *Stats) Finish() {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+	for _, fn := range hooks {
+		fn(s)
+	}
+}
@@ -3,10 +3,16 @@ package transport
 import (
 	"context"
 
+	// init form codec
+	_ "github.com/go-kratos/kratos/v2/encoding/form"
 	// init json codec
 	_ "github.com/go-kratos/kratos/v2/encoding/json"
 	// init proto codec
 	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	// init xml codec
+	_ "github.com/go-kratos/kratos/v2/encoding/xml"
+	// init yaml codec
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
 )
 
 // Server is transport server.
@@ -15,9 +21,33 @@ type Server interface {
 	Stop(context.Context) error
 }
 
+// Endpointer is implemented by the servers which listen before serving, so that the app
+// registers the instance only after its servers are listening.
+type Endpointer interface {
+	// Endpoint listens if not yet, and returns the endpoint of the server.
+	Endpoint() (string, error)
+}
+
+// Header is the uniform carrier of HTTP headers and gRPC metadata.
+type Header interface {
+	Get(key string) string
+	Set(key string, value string)
+	Keys() []string
+}
+
 // Transport is transport context value.
 type Transport struct {
+	// Kind is the transport kind, i.e., HTTP or GRPC.
 	Kind string
+	// Endpoint is the endpoint of the server, i.e., http://127.0.0.1:8000.
+	Endpoint string
+	// Operation is the matched route template of HTTP requests,
+	// or the full method of gRPC requests, i.e., /package.service/method.
+	Operation string
+	// Header is the request header.
+	Header Header
+	// ReplyHeader is the reply header.
+	ReplyHeader Header
 }
 
 type transportKey struct{}
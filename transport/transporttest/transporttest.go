@@ -0,0 +1,28 @@
+// Package transporttest provides the helpers to test the code using transport.
+package transporttest
+
+import (
+	"net/http"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Header = Header{}
+
+// Header is a transport.Header backed by an http.Header.
+type Header http.Header
+
+// Get returns the value associated with the passed key.
+func (h Header) Get(key string) string { return http.Header(h).Get(key) }
+
+// Set stores the key-value pair.
+func (h Header) Set(key string, value string) { http.Header(h).Set(key, value) }
+
+// Keys lists the keys stored in this header.
+func (h Header) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}